@@ -0,0 +1,95 @@
+package ahoy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Version is the version of Ahoy the generated C names. Release builds set
+// it with -ldflags "-X ahoy.Version=v1.2.3".
+var Version = "devel"
+
+// ProvenancePrefix starts the comment line that records, as JSON, how a C
+// file was generated; see Provenance
+const ProvenancePrefix = "// ahoy-provenance: "
+
+// Provenance is what the banner of a C file records about how it was made,
+// so a generated file checked in somewhere else can be traced back
+type Provenance struct {
+	Version   string            `json:"version"`
+	Source    string            `json:"source"`              // the file built, as it was named to the build
+	Files     map[string]string `json:"files"`               // sha256 of each .ahoy file the C came from, by path relative to Source's directory
+	Options   []string          `json:"options,omitempty"`   // the flags the C was generated with
+	Generated string            `json:"generated,omitempty"` // when, in RFC 3339, with BuildOptions.BannerTime
+}
+
+// banner returns the comment a C file starts with under BuildOptions.Banner:
+// the license text, a line saying what generated the file and from what,
+// and the provenance line. The runtime files get no provenance, as the same
+// runtime serves every program.
+func banner(opts BuildOptions, pkg *Package, imports map[string]*Package, runtime bool) string {
+	var text strings.Builder
+	if opts.License != "" {
+		for _, line := range strings.Split(strings.TrimRight(opts.License, "\n"), "\n") {
+			text.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+		}
+		text.WriteString("\n")
+	}
+	if runtime {
+		text.WriteString("// Generated by ahoy " + Version + ". Do not edit.\n\n")
+		return text.String()
+	}
+	source := filepath.Base(opts.Source)
+	text.WriteString("// Generated by ahoy " + Version + " from " + source + ". Do not edit.\n")
+
+	provenance := Provenance{Version: Version, Source: source, Files: map[string]string{}, Options: buildFlags(opts)}
+	dir, _ := filepath.Abs(filepath.Dir(opts.Source))
+	for _, p := range append([]*Package{pkg}, slices.Collect(maps.Values(imports))...) {
+		for _, file := range p.Files {
+			name, err := filepath.Rel(dir, file.Path)
+			if err != nil {
+				name = file.Path
+			}
+			sum := sha256.Sum256([]byte(file.Content))
+			provenance.Files[filepath.ToSlash(name)] = hex.EncodeToString(sum[:])
+		}
+	}
+	if opts.BannerTime {
+		provenance.Generated = time.Now().UTC().Format(time.RFC3339)
+	}
+	// Maps marshal with sorted keys, so the same sources give the same line
+	encoded, _ := json.Marshal(provenance)
+	text.WriteString(ProvenancePrefix + string(encoded) + "\n\n")
+	return text.String()
+}
+
+// buildFlags returns the command-line flags that give the C opts generates
+func buildFlags(opts BuildOptions) []string {
+	var flags []string
+	for _, option := range []struct {
+		set  bool
+		flag string
+	}{
+		{opts.Release, "-release"}, {opts.SoftAssert, "-soft-assert"}, {opts.Safe, "-safe"},
+		{opts.GrowArrays, "-grow-arrays"}, {opts.StrictCalls, "-strict-calls"}, {opts.Deterministic, "-deterministic"},
+		{opts.Optimize, "-O"}, {opts.Cover, "-cover"}, {opts.Test, "-test"}, {opts.ReadableC, "-readable-c"},
+		{opts.SplitRuntime || opts.RuntimeCache != "", "-split-runtime"}, {opts.MinimalRuntime, "-minimal-runtime"},
+		{opts.Library, "-lib"}, {opts.Prune, "-prune"},
+	} {
+		if option.set {
+			flags = append(flags, option.flag)
+		}
+	}
+	for _, name := range BuildConstants {
+		if value, ok := opts.Set[name]; ok {
+			flags = append(flags, "-set "+name+"="+value)
+		}
+	}
+	return flags
+}
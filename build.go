@@ -0,0 +1,460 @@
+package ahoy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Diagnostic is a problem found in Ahoy source: a syntax error, a code
+// generation error or a lint finding
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string // SeverityError or SeverityWarning
+	Message  string
+	Rule     string // lint rule, empty for syntax and code generation errors
+}
+
+// BuildOptions configures Build
+type BuildOptions struct {
+	Source         string            // main .ahoy file; files sharing its program name and its imports are built with it
+	OutputDir      string            // where the C file and executable go; see DefaultOutputDir when empty
+	Compile        bool              // also compile the C code with gcc
+	SoftAssert     bool              // failed asserts report and carry on; the program then exits with status 1
+	Release        bool              // leave log.debug|...| out of the program
+	Report         bool              // also write build-report.json (see BuildReport) to the output directory
+	Test           bool              // build the program's test blocks into a runner instead of the program
+	Safe           bool              // loops stop the program if their array or dict is modified while they run
+	StrictCalls    bool              // calls to unknown functions are errors rather than guessed PascalCase C names
+	Deterministic  bool              // random numbers come from a generator seeded with AHOY_SEED, time from a virtual clock, and input can be recorded to AHOY_RECORD and replayed from AHOY_REPLAY
+	Optimize       bool              // switch expressions mapping an int enum to constants become table lookups
+	Cover          bool              // the program counts the statements run on each line and writes CoverageFile when it exits
+	ReadableC      bool              // comment the C with the Ahoy line of each statement and split lines longer than CLineWidth
+	CLineWidth     int               // with ReadableC, the longest line left whole; 0 means DefaultCLineWidth
+	SplitRuntime   bool              // write the runtime helpers the program uses to RuntimeFile and RuntimeHeader
+	MinimalRuntime bool              // include only the C headers and runtime types the program uses, and leave out the crash handler
+	GrowArrays     bool              // arr[i]: value past the end grows the array with zeros up to i instead of stopping the program
+	Library        bool              // build a shared library of the program's @export functions instead of a program, with a C header for them
+	Bindings       []string          // with Library, also write bindings for these languages: "go" (cgo) and "python" (ctypes)
+	RuntimeCache   string            // directory compiled runtimes are kept in and reused from, see DefaultRuntimeCache; implies SplitRuntime
+	Debug          bool              // compile with -g, so debuggers and valgrind can name the C lines
+	WerrorC        bool              // compile with CWarningFlags, failing the build on any warning gcc gives for the generated C
+	Set            map[string]string // values of the program's build.version, build.commit and build.date; see BuildConstants
+	Banner         bool              // start the C files with a comment naming the Ahoy version and source, and a Provenance line
+	BannerTime     bool              // with Banner, also record when the C was generated
+	License        string            // text the C files start with, such as the license of the generated code; implies Banner
+	Prune          bool              // leave out the functions nothing reachable from main, or from a library's exports, uses; see CallGraph
+	Log            io.Writer         // progress and error messages; nil discards them
+}
+
+// Artifacts describes what Build produced
+type Artifacts struct {
+	Package    string   // package name, or the file name for a standalone script
+	Files      []string // .ahoy files in the package
+	CFile      string
+	CCode      string
+	Runtime    string   // RuntimeFile, set when BuildOptions.SplitRuntime or RuntimeCache is set; RuntimeHeader is beside it
+	Executable string   // set when BuildOptions.Compile is true
+	Report     string   // build-report.json, set when BuildOptions.Report is true
+	Coverage   string   // profile the program writes when it exits, set when BuildOptions.Cover is true
+	Header     string   // C header of a library's exports, set when BuildOptions.Library is true
+	Library    string   // the shared library, set when BuildOptions.Library and Compile are true
+	Bindings   []string // the binding files BuildOptions.Bindings asked for
+	Pruned     []string // the functions BuildOptions.Prune left out, sorted
+}
+
+// ErrCodeGeneration is returned by Build when the program has errors; the
+// diagnostics say what they are
+var ErrCodeGeneration = errors.New("code generation failed")
+
+// Build compiles an Ahoy program to C and, with Compile set, to an
+// executable. Problems in the source come back as diagnostics; the error is
+// set whenever no artifacts were produced.
+func Build(opts BuildOptions) (Artifacts, []Diagnostic, error) {
+	var artifacts Artifacts
+	log := opts.Log
+	if log == nil {
+		log = io.Discard
+	}
+
+	if err := checkBuildSettings(opts.Set); err != nil {
+		return artifacts, nil, err
+	}
+	if err := checkBindings(opts); err != nil {
+		return artifacts, nil, err
+	}
+
+	absPath, err := filepath.Abs(opts.Source)
+	if err != nil {
+		return artifacts, nil, fmt.Errorf("resolving file path: %v", err)
+	}
+
+	pm := NewPackageManager(filepath.Dir(absPath))
+	pm.Log = log
+	pkg, err := pm.LoadPackageFromFile(absPath)
+	if err != nil {
+		return artifacts, nil, fmt.Errorf("loading package: %v", err)
+	}
+	artifacts.Package = pkg.Name
+	for _, file := range pkg.Files {
+		artifacts.Files = append(artifacts.Files, file.Path)
+	}
+
+	imports, err := resolveImports(pkg, pm)
+	if err != nil {
+		return artifacts, nil, fmt.Errorf("resolving imports: %v", err)
+	}
+	if err := checkImportScope(pkg, pm, imports); err != nil {
+		return artifacts, nil, fmt.Errorf("resolving imports: %v", err)
+	}
+	pm.BindHeaders(pkg)
+	for _, importedPkg := range imports {
+		pm.BindHeaders(importedPkg)
+	}
+	stringTable, err := loadStringTables(filepath.Dir(absPath))
+	if err != nil {
+		return artifacts, nil, fmt.Errorf("reading string tables: %v", err)
+	}
+
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = DefaultOutputDir(opts.Source)
+	}
+	baseName := strings.TrimSuffix(filepath.Base(opts.Source), filepath.Ext(opts.Source))
+
+	// The report is written however far the build gets from here
+	var report *BuildReport
+	if opts.Report {
+		report = newBuildReport(pkg, imports)
+		artifacts.Report = filepath.Join(outputDir, ReportFile)
+		defer func() {
+			os.MkdirAll(outputDir, 0755)
+			if err := report.write(artifacts.Report); err != nil {
+				fmt.Fprintf(log, "Warning: couldn't write %s: %v\n", artifacts.Report, err)
+			}
+		}()
+	}
+
+	// Generate C code with source filename for better error messages
+	ast := MergeWithImports(pkg, imports)
+	if opts.Prune {
+		artifacts.Pruned = BuildCallGraph(ast, opts.Library).prune(ast)
+		if len(artifacts.Pruned) > 0 {
+			fmt.Fprintf(log, "✓ Left out %d unreachable function(s): %s\n", len(artifacts.Pruned), strings.Join(artifacts.Pruned, ", "))
+		}
+	}
+	codegenOpts := codegenOptions{
+		softAssert:    opts.SoftAssert,
+		release:       opts.Release,
+		test:          opts.Test,
+		safe:          opts.Safe,
+		growArrays:    opts.GrowArrays,
+		strictCalls:   opts.StrictCalls,
+		deterministic: opts.Deterministic,
+		optimize:      opts.Optimize,
+		splitRuntime:  opts.SplitRuntime || opts.RuntimeCache != "",
+		minimal:       opts.MinimalRuntime,
+		build:         opts.Set,
+		strings:       stringTable,
+	}
+	if opts.Library {
+		codegenOpts.library = libraryName(baseName)
+	}
+	if opts.Cover {
+		// The program may run from anywhere, so the profile path is absolute
+		artifacts.Coverage, _ = filepath.Abs(filepath.Join(outputDir, CoverageFile))
+		codegenOpts.cover = artifacts.Coverage
+		codegenOpts.files = sourceFiles(pkg, imports)
+	}
+	if opts.ReadableC {
+		codegenOpts.readable = true
+		codegenOpts.width = opts.CLineWidth
+		codegenOpts.files = sourceFiles(pkg, imports)
+		codegenOpts.sources = sourceLines(pkg, imports)
+	}
+	start := time.Now()
+	program, diagnostics := generateCode(ast, opts.Source, codegenOpts, log)
+	if report != nil {
+		report.CodegenMS = milliseconds(time.Since(start))
+		report.addDiagnostics(diagnostics)
+	}
+	if program.code == "" {
+		return artifacts, diagnostics, ErrCodeGeneration
+	}
+	if opts.Banner || opts.License != "" {
+		program.code = banner(opts, pkg, imports, false) + program.code
+		if program.runtime != "" {
+			program.header = banner(opts, pkg, imports, true) + program.header
+			program.runtime = banner(opts, pkg, imports, true) + program.runtime
+		}
+	}
+	artifacts.CCode = program.code
+	artifacts.CFile = filepath.Join(outputDir, baseName+".c")
+	if report != nil {
+		report.CFile = artifacts.CFile
+		report.addCode(program.code+program.runtime, ast)
+	}
+
+	os.MkdirAll(outputDir, 0755)
+	if err := os.WriteFile(artifacts.CFile, []byte(program.code), 0644); err != nil {
+		return artifacts, diagnostics, fmt.Errorf("writing C file: %v", err)
+	}
+	if codegenOpts.splitRuntime {
+		// Each build rewrites the runtime with the helpers its program uses
+		artifacts.Runtime = filepath.Join(outputDir, RuntimeFile)
+		if err := os.WriteFile(filepath.Join(outputDir, RuntimeHeader), []byte(program.header), 0644); err != nil {
+			return artifacts, diagnostics, fmt.Errorf("writing runtime header: %v", err)
+		}
+		if err := os.WriteFile(artifacts.Runtime, []byte(program.runtime), 0644); err != nil {
+			return artifacts, diagnostics, fmt.Errorf("writing runtime: %v", err)
+		}
+	}
+
+	if opts.Library {
+		if err := writeLibraryFiles(&artifacts, program, outputDir, codegenOpts.library, opts.Bindings); err != nil {
+			return artifacts, diagnostics, err
+		}
+	}
+
+	if len(pkg.Files) > 1 {
+		fmt.Fprintf(log, "✓ Compiled package '%s' (%d files) to %s\n", pkg.Name, len(pkg.Files), artifacts.CFile)
+	} else {
+		fmt.Fprintf(log, "✓ Compiled %s to %s\n", opts.Source, artifacts.CFile)
+	}
+
+	if opts.Compile {
+		fmt.Fprintln(log, "Compiling C code...")
+		executable := filepath.Join(outputDir, baseName)
+		object := executable + ".o"
+		defer os.Remove(object)
+
+		// Compile and link separately so the report can time each
+		start := time.Now()
+		compileFlags := []string{"-c"}
+		if opts.Library {
+			// Only the exports are visible outside the library
+			compileFlags = append(compileFlags, "-fPIC", "-fvisibility=hidden")
+		}
+		if opts.Debug {
+			compileFlags = append(compileFlags, "-g")
+		}
+		if opts.WerrorC {
+			compileFlags = append(compileFlags, CWarningFlags...)
+		}
+		output, err := exec.Command("gcc", append(slices.Clone(compileFlags), "-o", object, artifacts.CFile)...).CombinedOutput()
+		objects := []string{object}
+		if err == nil && artifacts.Runtime != "" {
+			var runtimeObject string
+			var runtimeOutput []byte
+			if opts.RuntimeCache != "" {
+				var cached bool
+				runtimeObject, cached, runtimeOutput, err = cachedRuntime(opts.RuntimeCache, compileFlags, artifacts.Runtime, program)
+				if cached {
+					fmt.Fprintln(log, "Reusing the compiled runtime")
+				}
+				if report != nil {
+					report.RuntimeCached = cached
+				}
+			} else {
+				runtimeObject = strings.TrimSuffix(artifacts.Runtime, ".c") + ".o"
+				defer os.Remove(runtimeObject)
+				runtimeOutput, err = exec.Command("gcc", runtimeCompileArgs(compileFlags, runtimeObject, artifacts.Runtime)...).CombinedOutput()
+			}
+			output = append(output, runtimeOutput...)
+			objects = append(objects, runtimeObject)
+		}
+		if report != nil {
+			report.CompileMS = milliseconds(time.Since(start))
+			report.addCompilerOutput(output)
+		}
+		if err != nil {
+			return artifacts, diagnostics, fmt.Errorf("compiling C code:\n%s", output)
+		}
+		start = time.Now()
+		if opts.Library {
+			executable = filepath.Join(outputDir, "lib"+codegenOpts.library+".so")
+		}
+		args := append([]string{"-o", executable}, objects...)
+		if opts.Library {
+			args = append([]string{"-shared"}, args...)
+		}
+		output, err = exec.Command("gcc", append(args, linkFlags(pkg, imports)...)...).CombinedOutput()
+		if report != nil {
+			report.LinkMS = milliseconds(time.Since(start))
+			report.addCompilerOutput(output)
+		}
+		if err != nil {
+			return artifacts, diagnostics, fmt.Errorf("linking C code:\n%s", output)
+		}
+		if opts.Library {
+			artifacts.Library = executable
+		} else {
+			artifacts.Executable = executable
+		}
+		fmt.Fprintf(log, "✓ Compiled C code to %s\n", executable)
+	}
+
+	return artifacts, diagnostics, nil
+}
+
+// sourceFiles maps the top-level nodes of a package and its imports to the
+// files they are in
+func sourceFiles(pkg *Package, imports map[string]*Package) map[*ASTNode]string {
+	files := make(map[*ASTNode]string)
+	for _, p := range append([]*Package{pkg}, slices.Collect(maps.Values(imports))...) {
+		for _, file := range p.Files {
+			if file.AST == nil {
+				continue
+			}
+			for _, child := range file.AST.Children {
+				files[child] = file.Path
+			}
+		}
+	}
+	return files
+}
+
+// sourceLines returns the lines of each file of the program, by path
+func sourceLines(pkg *Package, imports map[string]*Package) map[string][]string {
+	lines := make(map[string][]string)
+	for _, p := range append([]*Package{pkg}, slices.Collect(maps.Values(imports))...) {
+		for _, file := range p.Files {
+			lines[file.Path] = strings.Split(file.Content, "\n")
+		}
+	}
+	return lines
+}
+
+// CWarningFlags are the gcc flags BuildOptions.WerrorC compiles with. The
+// generated C is meant to build without a warning under them.
+var CWarningFlags = []string{"-Wall", "-Wextra", "-Wstrict-prototypes", "-Wold-style-definition", "-Werror"}
+
+// DefaultOutputDir is "output" next to the working directory, except that
+// sources under test/input build into test/output
+func DefaultOutputDir(sourceFile string) string {
+	sourceDir := filepath.Dir(sourceFile)
+	if strings.Contains(sourceDir, "test/input") || strings.Contains(sourceDir, "test\\input") {
+		return filepath.Join(filepath.Dir(filepath.Dir(sourceDir)), "test", "output")
+	}
+	return "output"
+}
+
+// DefaultRuntimeCache is where the CLI keeps compiled runtimes: ahoy/runtime
+// in the user's cache directory, or the temporary directory without one
+func DefaultRuntimeCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ahoy", "runtime")
+}
+
+// linkFlags returns the gcc libraries a package and the packages it imports,
+// directly or not, need; raylib imports and game.run link raylib and its
+// system dependencies, externs link the libraries they name, and panic
+// exports the program's symbols for its stack trace
+func linkFlags(pkg *Package, imports map[string]*Package) []string {
+	raylibLibs := []string{"-lraylib", "-lm", "-lpthread", "-ldl", "-lrt", "-lX11"}
+	usesGame, usesPanic := false, false
+	var flags, externLibs []string
+	files := slices.Clone(pkg.Files)
+	seen := map[*Package]bool{pkg: true}
+	for _, namespace := range slices.Sorted(maps.Keys(imports)) {
+		if imported := imports[namespace]; !seen[imported] {
+			seen[imported] = true
+			files = append(files, imported.Files...)
+		}
+	}
+	for _, file := range files {
+		if file.AST == nil {
+			continue
+		}
+		for _, child := range file.AST.Children {
+			if child.Type == NODE_IMPORT_STATEMENT && strings.Contains(child.Value, "raylib.h") && flags == nil {
+				if raylibPath := filepath.Dir(child.Value); raylibPath != "" {
+					flags = append(flags, "-L"+raylibPath)
+				}
+				flags = append(flags, raylibLibs...)
+			}
+			if child.Type == NODE_EXTERN {
+				if library := child.Children[2].Value; library != "" && !slices.Contains(externLibs, "-l"+library) {
+					externLibs = append(externLibs, "-l"+library)
+				}
+			}
+		}
+		usesGame = usesGame || callsGameRun(file.AST)
+		usesPanic = usesPanic || callsPanic(file.AST)
+	}
+	if flags == nil {
+		if usesGame {
+			flags = raylibLibs
+		} else {
+			flags = []string{"-lm"}
+		}
+	}
+	if usesPanic {
+		// The stack a panic prints names the program's functions
+		flags = append(flags, "-rdynamic")
+	}
+	return append(externLibs, flags...)
+}
+
+// callsGameRun reports whether node contains a game.run|...| call
+func callsGameRun(node *ASTNode) bool {
+	if node == nil {
+		return false
+	}
+	if node.Type == NODE_METHOD_CALL && node.Value == "run" && len(node.Children) > 0 &&
+		node.Children[0].Type == NODE_IDENTIFIER && node.Children[0].Value == "game" {
+		return true
+	}
+	for _, child := range node.Children {
+		if callsGameRun(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check parses source and runs the default lint rules without generating
+// code. Syntax errors stop the check before linting.
+func Check(source string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	ast, parseErrors := ParseLint(Tokenize(source))
+	for _, parseError := range parseErrors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Line:     parseError.Line,
+			Column:   parseError.Column,
+			Severity: SeverityError,
+			Message:  parseError.Message,
+		})
+	}
+	if len(parseErrors) > 0 {
+		return diagnostics
+	}
+
+	for _, finding := range Lint(ast, source, DefaultLintConfig()) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Line:     finding.Line,
+			Column:   finding.Column,
+			Severity: finding.Severity,
+			Message:  finding.Message,
+			Rule:     finding.Rule,
+		})
+	}
+	return diagnostics
+}
+
+// Format returns source in the standard layout used by -format
+func Format(source string) string {
+	return formatSource(source)
+}
@@ -0,0 +1,2941 @@
+package ahoy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func writeSource(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.ahoy")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildWritesC(t *testing.T) {
+	path := writeSource(t, "x: 5\nprint|x|\n")
+	outputDir := t.TempDir()
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: outputDir})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if artifacts.CFile != filepath.Join(outputDir, "main.c") {
+		t.Errorf("unexpected C file %s", artifacts.CFile)
+	}
+	written, err := os.ReadFile(artifacts.CFile)
+	if err != nil || string(written) != artifacts.CCode || !strings.Contains(artifacts.CCode, "int main(void)") {
+		t.Errorf("C file doesn't hold the generated program: %v", err)
+	}
+}
+
+func TestBuildReportsDiagnostics(t *testing.T) {
+	path := writeSource(t, "x: 3\nif 2 in x then print|x| $\n")
+
+	_, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 || diagnostics[0].Severity != SeverityError {
+		t.Errorf("expected one error on line 2, got %+v", diagnostics)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if diagnostics := Check("x: [1, 2\n"); len(diagnostics) == 0 || diagnostics[0].Severity != SeverityError {
+		t.Errorf("expected a syntax error, got %+v", diagnostics)
+	}
+	diagnostics := Check("myVar: 1\n")
+	if len(diagnostics) != 1 || diagnostics[0].Rule != "naming" {
+		t.Errorf("expected a naming warning, got %+v", diagnostics)
+	}
+}
+
+func TestBuildAssertMessages(t *testing.T) {
+	path := writeSource(t, "x: 5\nname: \"bob\"\nassert name is \"alice\" or x < 3\n")
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		`ahoy_assert_failed("` + path + `", 3, "name is \"alice\" or x < 3");`,
+		`fprintf(stderr, "  name = \"%s\"\n", name);`,
+		`fprintf(stderr, "  x = %d\n", x);`,
+		"abort();",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), SoftAssert: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "abort();") || !strings.Contains(artifacts.CCode, "atexit(ahoy_assert_report)") {
+		t.Error("expected soft asserts to report at exit instead of aborting")
+	}
+}
+
+func TestBuildReleaseStripsDebugLogs(t *testing.T) {
+	path := writeSource(t, "x: 5\nlog.debug|\"x is %d\", x|\nlog.warn|\"x is %d\", x|\n")
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "ahoy_log_enabled(0)") || !strings.Contains(artifacts.CCode, "ahoy_log_enabled(2)") {
+		t.Error("expected a debug and a warn log")
+	}
+
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Release: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "ahoy_log_enabled(0)") || !strings.Contains(artifacts.CCode, "ahoy_log_enabled(2)") {
+		t.Error("expected release builds to keep only the warn log")
+	}
+}
+
+func TestBuildStructJSONTags(t *testing.T) {
+	source := `struct player:
+    health: int json:"hp"
+    speed: float json:",omitempty"
+    secret: string json:"-"
+$
+p: player{health: 90, speed: 0.0, secret: "x"}
+text: json_encode|p|
+ok: json_decode|text, p|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		`ahoy_json_buffer_append(b, "\"hp\":");`,
+		`if (!(v.speed == 0)) {`,
+		`member = ahoy_json_get(json, "hp");`,
+		"ahoy_json_encode_player(p)",
+		"ahoy_json_decode_player(text, &p)",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+	if strings.Contains(artifacts.CCode, `"secret"`) {
+		t.Error("expected the skipped field to stay out of the JSON helpers")
+	}
+}
+
+func TestBuildDictLambdas(t *testing.T) {
+	source := `prices: dict<string,float> = <"tea": 1.5, "cake": 3.25>
+taxed: prices.map_values|p: p * 2.0|
+cheap: prices.filter|(name, price): price lesser_than 2.0|
+names: prices.to_array|(name, price): name|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"HashMap* taxed;",
+		"double p = ahoy_value_from_raw((intptr_t)__e->value, __e->valueType).as.f;",
+		"ahoy_box_float((p * 2.0))), AHOY_TYPE_FLOAT);",
+		"if ((price < 2.0)) hashMapPutTyped(__result, __e->key, __e->value, __e->valueType);",
+		"__result->types[__result->length] = AHOY_TYPE_STRING;",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+}
+
+func TestBuildStructSlots(t *testing.T) {
+	source := `struct point:
+    x: int
+    y: int
+$
+p: point{x: 1, y: 2}
+items: [1, "two", true]
+items.push|p|
+pts: array[point] = [point{x: 3, y: 4}]
+loop pt in pts do
+    print|pt.x|
+$
+print|pts[0].y|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"AHOY_TYPE_STRUCT // struct slots are tagged AHOY_TYPE_STRUCT + struct id",
+		"ahoy_array_push(items, ({ Point __slot = p; ahoy_box_struct(&__slot, sizeof(Point)); }), (AHOY_TYPE_STRUCT + 0));",
+		"Point pt = *(Point*)pts->data[",
+		"(*(Point*)__arr->data[__idx])",
+		`{"point", sizeof(Point), ahoy_print_slot_point},`,
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+}
+
+func TestBuildRanges(t *testing.T) {
+	source := `tens: range|0, 100, 10|
+loop i in tens do
+    print|i|
+$
+found: tens.has|40|
+nums: tens.to_array||
+teens: range|13, 20|
+age: 15
+switch age:
+    on 0 to 12: print|"child"|
+    on teens: print|"teen"|
+    _: print|"adult"|
+$
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"tens = ahoy_range(0, 100, 10);",
+		"for (int i = __range_",
+		"found = ahoy_range_has(tens, 40);",
+		"nums = ahoy_range_to_array(tens);",
+		"if (ahoy_range_has(teens, age)) {",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+	main := artifacts.CCode[strings.Index(artifacts.CCode, "int main("):]
+	if strings.Count(main, "default:") != 1 {
+		t.Errorf("expected the range and _ cases to share one default label")
+	}
+}
+
+func TestBuildEnumMemberResolution(t *testing.T) {
+	enums := `enum paint:
+	red
+	blue
+$
+enum light:
+	red
+	amber
+$
+`
+	source := enums + `shade: paint.blue
+switch shade:
+	on red: print|"red"|
+	on blue: print|"blue"|
+$
+blue: 7
+print|blue|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"case paint_red:", "case paint_blue:", "int blue;", "blue = 7;", `printf("%d\n", blue);`} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	// Without a switch subject to go by, a shared member must be qualified
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, enums+"x: red\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 9 || !strings.Contains(diagnostics[0].Message, "light, paint") {
+		t.Errorf("expected an ambiguity error listing both enums on line 9, got %+v", diagnostics)
+	}
+}
+
+func TestBuildEnumValueChecks(t *testing.T) {
+	source := `enum:int temp
+	-2 cold
+	cool
+	5 hot
+	2 mild
+$
+switch temp.cool:
+	on cold: print|"cold"|
+	on hot, cold: print|"hot"|
+$
+`
+	_, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected a code generation error, got %v", err)
+	}
+	want := []struct {
+		line     int
+		severity string
+		message  string
+	}{
+		{5, SeverityWarning, "enum temp: mild is 2, lower than hot (5) before it"},
+		{9, SeverityError, "switch case cold is -2, the same as case cold on line 8"},
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %+v", len(want), diagnostics)
+	}
+	for i, w := range want {
+		d := diagnostics[i]
+		if d.Line != w.line || d.Severity != w.severity || !strings.HasPrefix(d.Message, w.message) {
+			t.Errorf("expected %s on line %d: %s, got %+v", w.severity, w.line, w.message, d)
+		}
+	}
+
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "enum:int dir\n\t3 up\n\t1 down\n\tleft\n\tright\n$\nenum:flags f\n\t-1 all\n$\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 3 ||
+		diagnostics[1].Line != 5 || diagnostics[1].Message != "enum dir: right is 3, the same as up" ||
+		diagnostics[2].Line != 8 || !strings.Contains(diagnostics[2].Message, "can't be negative") {
+		t.Errorf("expected errors for right repeating up and a negative flag, got %v %+v", err, diagnostics)
+	}
+
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "enum:flags perms\n\tread\n\twrite\n\texec\n\trw: read plus write\n$\n"), OutputDir: t.TempDir()})
+	if err != nil || len(diagnostics) != 0 {
+		t.Errorf("expected a combined flags mask after the single flags to pass, got %v %+v", err, diagnostics)
+	}
+
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "enum:flags perms\n\tread\n\t6 write\n$\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 1 || diagnostics[0].Line != 3 ||
+		diagnostics[0].Message != "Flags enum member 'write' must be a power of two, got 6" {
+		t.Errorf("expected a build error for a flag that isn't a single bit, got %v %+v", err, diagnostics)
+	}
+}
+
+func TestBuildScriptTopLevel(t *testing.T) {
+	source := `@ report || void:
+	print|total|
+$
+enum shade:
+	light
+	dark
+$
+total: 3
+grid: int[4]
+report||
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	// Top-level declarations come before the functions that use them
+	code := artifacts.CCode
+	report := strings.Index(code, "void report(void) {")
+	for _, want := range []string{"shade_dark = 1,", "int total;", "int grid[4];"} {
+		if at := strings.Index(code, want); at < 0 || at > report {
+			t.Errorf("expected %s to be declared before report", want)
+		}
+	}
+	main := code[strings.Index(code, "int main("):]
+	if !strings.Contains(main, "total = 3;\nreport();") || strings.Contains(main, "ahoy_main") {
+		t.Errorf("expected top-level statements to run in order in main, got:\n%s", main)
+	}
+
+	// With a main function, top-level statements initialize globals first
+	source = "total: 3\n@ main || void:\n\tprint|total|\n$\n"
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "total = 3;\n    ahoy_main();") {
+		t.Errorf("expected globals to be initialized before ahoy_main")
+	}
+}
+
+func TestBuildPrintErr(t *testing.T) {
+	path := writeSource(t, "n: 3\nprint_err|\"bad value: %d\", n|\nprint_err|n|\npanic|\"stop\"|\n")
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		`fprintf(stderr, "bad value: %d\n", n);`,
+		`fprintf(stderr, "%d\n", n);`,
+		`ahoy_panic("stop", `,
+		"exit(128 + sig);",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+}
+
+func TestBuildGameRun(t *testing.T) {
+	source := "@ update || void:\n\tprint|\"tick\"|\n$\n@ draw || void:\n\tclear_background|BLACK|\n$\n" +
+		"game.run|800, 450, \"Hello\", update, draw|\n"
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"#include <raylib.h>",
+		`ahoy_game_run(800, 450, "Hello", update, draw);`,
+		"while (!WindowShouldClose()) {",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	// Callbacks are called with no arguments, so they can't take any
+	source = "@ update || void:\n\tprint|\"tick\"|\n$\n@ draw |n:int| void:\n\tprint|n|\n$\n" +
+		"game.run|800, 450, \"Hello\", update, draw|\n"
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 7 || !strings.Contains(diagnostics[0].Message, "'draw'") {
+		t.Errorf("expected an error about draw on line 7, got %+v", diagnostics)
+	}
+}
+
+func TestBuildEmbed(t *testing.T) {
+	path := writeSource(t, "embed \"assets/logo.bin\" as logo\nprint|logo.length||\n")
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(path), "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "assets", "logo.bin"), []byte{0x89, 'P', 'N', 'G'}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"static unsigned char ahoy_embed_logo_data[4] = {\n    0x89, 0x50, 0x4E, 0x47,\n};",
+		"AhoyBytes* logo = &ahoy_embed_logo;",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	// A missing file is an error on the embed line
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "x: 1\nembed \"missing.bin\" as data\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 || !strings.Contains(diagnostics[0].Message, "missing.bin") {
+		t.Errorf("expected an error about missing.bin on line 2, got %+v", diagnostics)
+	}
+}
+
+func TestBuildVectorMath(t *testing.T) {
+	vector := "struct vector2:\n  x: float,\n  y: float\n$\nstruct color:\n  r: int,\n  g: int,\n  b: int,\n  a: int\n$\n"
+	source := vector + `a: vector2{x: 3.0, y: 4.0}
+b: a plus a
+c: 2.0 * a - b / 4.0
+n: vector2_length|c|
+red: color{r: 255, g: 0, b: 0, a: 255}
+faded: color_fade|red, 0.5|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"b = ahoy_vector2_add(a, a);",
+		"c = ahoy_vector2_sub(ahoy_vector2_scale(a, 2.0), ahoy_vector2_scale(b, 1.0f / (4.0)));",
+		"n = ahoy_vector2_length(c);",
+		"faded = ahoy_color_fade(red, 0.5);",
+		"#ifndef RAYLIB_H",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	// Colors have helpers instead of operators
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, vector+"red: color{r: 255, g: 0, b: 0, a: 255}\nx: red plus red\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 12 || !strings.Contains(diagnostics[0].Message, "colors") {
+		t.Errorf("expected an error about colors on line 12, got %+v", diagnostics)
+	}
+}
+
+func TestBuildBuiltinStructs(t *testing.T) {
+	source := "r: rectangle{x: 10.0, width: 30.0}\nprint|r|\nm: matrix{m0: 1.0, m15: 1.0}\nprint|m.m15|\n"
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"#ifndef RAYLIB_H\ntypedef struct Matrix {",
+		"typedef struct Rectangle { float x; float y; float width; float height; } Rectangle;\n#endif",
+		"r = (Rectangle){.x = 10.0, .y = 0.0, .width = 30.0, .height = 0.0};",
+		"char* print_struct_helper_rectangle(Rectangle obj)",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	if diagnostics := Check("r: rectangle{x: 1.0}\nprint|r.depth|\n"); len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "'depth'") {
+		t.Errorf("expected an error about depth, got %+v", diagnostics)
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	path := writeSource(t, "@ twice |n:int| int:\n\treturn n * 2\n$\nx: twice|4|\nprint|x|\n")
+	outputDir := t.TempDir()
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: outputDir, Report: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if artifacts.Report != filepath.Join(outputDir, ReportFile) {
+		t.Errorf("unexpected report path %s", artifacts.Report)
+	}
+	data, err := os.ReadFile(artifacts.Report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report BuildReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report isn't valid JSON: %v", err)
+	}
+	if len(report.Files) != 1 || report.Files[0].Lines != 5 {
+		t.Errorf("expected one 5-line file, got %+v", report.Files)
+	}
+	if report.CBytes != len(artifacts.CCode) || report.CLines == 0 {
+		t.Errorf("expected the C code size, got %d bytes and %d lines", report.CBytes, report.CLines)
+	}
+	helpers := strings.Join(report.Helpers, " ")
+	if !strings.Contains(helpers, "ahoy_setup_signal_handlers") || strings.Contains(helpers, "twice") {
+		t.Errorf("expected runtime helpers without user functions, got %s", helpers)
+	}
+	if strings.Contains(helpers, "createHashMap") {
+		t.Errorf("expected the unused dict helpers to be left out, got %s", helpers)
+	}
+}
+
+func TestBuildInlineTests(t *testing.T) {
+	source := `@ add |a:int, b:int| int:
+    return a + b
+$
+total: add|1, 2|
+print|"%d", total|
+test "adds numbers": assert add|1, 2| is 3
+test "uses globals":
+    assert total is 3
+$
+`
+	path := writeSource(t, source)
+
+	// A normal build leaves the tests out
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "ahoy_test_1") || !strings.Contains(artifacts.CCode, "printf(\"%d\\n\", total);") {
+		t.Errorf("expected the program without its tests, got:\n%s", artifacts.CCode)
+	}
+
+	// A test build runs each test instead of the program
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Test: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"void ahoy_test_1(void) {",
+		"failed += ahoy_run_test(\"adds numbers\", ahoy_test_1);",
+		"failed += ahoy_run_test(\"uses globals\", ahoy_test_2);",
+		"ahoy_test_failed();",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+	main := artifacts.CCode[strings.Index(artifacts.CCode, "int main(void) {"):]
+	if strings.Contains(main, "printf(\"%d\\n\", total);") || !strings.Contains(main, "total = add(1, 2);") {
+		t.Errorf("expected the test run to keep the declarations and leave out the rest, got:\n%s", main)
+	}
+}
+
+func TestBuildSafeLoops(t *testing.T) {
+	path := writeSource(t, "nums: [1, 2, 3]\nloop n in nums do\n    nums.push|n|\n$\nd: {\"a\": 1}\nloop k, v in d do\n    print|k|\n$\n")
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "ahoy_check_iteration") {
+		t.Errorf("expected no modification checks without Safe")
+	}
+
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Safe: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"unsigned int __mods_2 = nums->mods;",
+		"ahoy_check_iteration(nums->mods, __mods_2, ",
+		"ahoy_check_iteration(d->mods, ",
+		"    arr->mods++;",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+}
+
+func TestBuildStrictCalls(t *testing.T) {
+	path := writeSource(t, "@ my_helper |x:int| int:\n    return x plus 1\n$\nv: my_helpr|2|\nw: my_helper|3|\nprint|w|\n")
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "MyHelpr(2)") {
+		t.Errorf("expected the unknown call to be guessed as MyHelpr without StrictCalls")
+	}
+
+	_, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), StrictCalls: true})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 4 || diagnostics[0].Message != "unknown function 'my_helpr'" {
+		t.Errorf("expected an unknown function error on line 4, got %+v", diagnostics)
+	}
+}
+
+func TestBuildReservedNames(t *testing.T) {
+	path := writeSource(t, "@ exit |code:int| int:\n    return code\n$\nenum malloc:\n    small\n$\n@ ahoy_tr |x:int| int:\n    return x\n$\n")
+	_, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	want := []string{
+		"can't name a function 'exit': it's a C library function",
+		"can't name an enum 'malloc': it's a C library function",
+		"can't name a function 'ahoy_tr': it's a name the Ahoy runtime uses",
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %+v", len(want), diagnostics)
+	}
+	for i, message := range want {
+		if diagnostics[i].Message != message {
+			t.Errorf("expected %q, got %q", message, diagnostics[i].Message)
+		}
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	// Functions named after builtins are called in their place
+	source := `@ range |n:int| int:
+    return n * 10
+$
+@ flush |message:string|:
+    print|message|
+$
+@ parse_int |text:string| int:
+    return text.length|| + 6
+$
+r: range|2|
+print|r|
+flush|"mine"|
+n: parse_int|"3"|
+print|n|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "20\nmine\n7\n" {
+		t.Errorf("expected the program's own functions to run, got %q: %v", output, err)
+	}
+}
+
+func TestBuildCallbacks(t *testing.T) {
+	header := filepath.Join(t.TempDir(), "visit.h")
+	if err := os.WriteFile(header, []byte(`typedef int (*Visitor)(int value, void *context);
+int visit_all(int count, Visitor visit, void *context);
+void sort_items(int *items, int count, int (*compare)(const void *a, const void *b));
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := fmt.Sprintf(`import "%s"
+@ show |value:int, context:generic| int:
+    return value
+$
+@ same |a:generic, b:generic| int:
+    return 0
+$
+total: visit_all|3, show, 0|
+again: visit_all|1, show, 0|
+sort_items|0, 0, same|
+`, header)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"static int ahoy_callback_show(int arg0, void* arg1) {\n    return show(arg0, (intptr_t)arg1);\n}",
+		"static int ahoy_callback_same(const void* arg0, const void* arg1) {\n    return same((intptr_t)arg0, (intptr_t)arg1);\n}",
+		"total = visit_all(3, ahoy_callback_show, 0);",
+		"sort_items(0, 0, ahoy_callback_same);",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+	if strings.Count(artifacts.CCode, "static int ahoy_callback_show(") != 2 {
+		t.Errorf("expected one wrapper for show, declared and defined once")
+	}
+
+	source = fmt.Sprintf("import \"%s\"\n@ one |value:int| int:\n    return value\n$\nvisit_all|1, one, 0|\n", header)
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 5 || !strings.Contains(diagnostics[0].Message, "C calls it with 2") {
+		t.Errorf("expected an argument count error on line 5, got %+v", diagnostics)
+	}
+
+	// A pointer doesn't fit in an int
+	source = fmt.Sprintf("import \"%s\"\n@ show |value:int, context:int| int:\n    return value\n$\nvisit_all|1, show, 0|\n", header)
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if want := "parameter 2 of show is int, but C passes a void*"; len(diagnostics) != 1 || diagnostics[0].Line != 5 || diagnostics[0].Message != want {
+		t.Errorf("expected %q on line 5, got %+v", want, diagnostics)
+	}
+}
+
+func TestBuildImportedPackageHeader(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+	files := map[string]string{
+		"lib/mathx.h":     "int ScaleUp(int a);\n",
+		"lib/shapes.ahoy": "import mx \"./mathx.h\"\n@ doubled |n:int| int:\n    return mx.scale_up|n|\n$\n",
+		"main.ahoy":       "import \"./lib/shapes.ahoy\"\nx: doubled|4|\nprint|x|\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	artifacts, diagnostics, err := Build(BuildOptions{Source: filepath.Join(dir, "main.ahoy"), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "return ScaleUp(n);") {
+		t.Errorf("expected mx.scale_up to call ScaleUp from the package's header")
+	}
+
+	pm := NewPackageManager(dir)
+	first, err := pm.LoadHeader("./mathx.h", filepath.Join(dir, "lib", "shapes.ahoy"))
+	if err != nil {
+		t.Fatalf("LoadHeader: %v", err)
+	}
+	if again, _ := pm.LoadHeader("mathx.h", filepath.Join(dir, "lib", "other.ahoy")); again != first {
+		t.Errorf("expected the header to be parsed once")
+	}
+}
+
+func TestBuildFromImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"geo.ahoy":     "@ distance |a:float, b:float| float:\n    return b - a\n$\n",
+		"utils.ahoy":   "import geo \"./geo.ahoy\"\npub use geo.distance\n@ clamp |x:int, hi:int| int:\n    if x > hi then return hi $\n    return x\n$\n@ secret |x:int| int:\n    return x * 2\n$\n",
+		"main.ahoy":    "from utils import clamp, distance\nc: clamp|15, 10|\nd: distance|1.0, 4.0|\n",
+		"hidden.ahoy":  "from utils import clamp\ns: secret|3|\n",
+		"missing.ahoy": "from utils import lerp\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	artifacts, diagnostics, err := Build(BuildOptions{Source: filepath.Join(dir, "main.ahoy"), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"c = clamp(15, 10);", "d = distance(1.0, 4.0);", "int secret(int x) {"} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	for source, want := range map[string]string{
+		"hidden.ahoy":  "hidden.ahoy:2: secret isn't imported; add it to from utils import",
+		"missing.ahoy": "missing.ahoy:1: utils has no lerp to import",
+	} {
+		_, _, err := Build(BuildOptions{Source: filepath.Join(dir, source), OutputDir: t.TempDir()})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %s to fail with %q, got %v", source, want, err)
+		}
+	}
+}
+
+func TestBuildCEnums(t *testing.T) {
+	header := filepath.Join(t.TempDir(), "keys.h")
+	if err := os.WriteFile(header, []byte(`typedef enum {
+    KEY_SPACE = 32,
+    KEY_A = 65,
+    KEY_B,
+    KEY_FLAG = 1 << 4, // a bit
+    KEY_A_FLAG = (KEY_A | KEY_FLAG),
+    KEY_C = KEY_B + 1
+} KeyboardKey;
+typedef enum GamepadButton {
+    GAMEPAD_BUTTON_UNKNOWN = 0,
+    GAMEPAD_BUTTON_LEFT_FACE_UP
+} GamepadButton;
+typedef enum Dir { DIR_UP = 1, DIR_DOWN } Direction;
+typedef enum { ODD_SIZE = sizeof(int), ODD_NEXT } Odd;
+int key_code(KeyboardKey key);
+int dir_code(Direction dir);
+int odd_code(Odd odd);
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := fmt.Sprintf(`import "%s"
+@ describe |key:KeyboardKey| int:
+    return key
+$
+k: KeyboardKey.KEY_B
+total: describe|KEY_A|
+code: key_code|k|
+flags: describe|16| + describe|81| + describe|67|
+dir: dir_code|2|
+odd: odd_code|99| + odd_code|ODD_NEXT|
+`, header)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"int describe(KeyboardKey key) {",
+		"KeyboardKey k;",
+		"k = KEY_B;",
+		"total = describe(KEY_A);",
+		"code = key_code(k);",
+		"dir = dir_code(2);",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	source = fmt.Sprintf(`import "%s"
+@ describe |key:KeyboardKey| int:
+    return key
+$
+a: key_code|GAMEPAD_BUTTON_LEFT_FACE_UP|
+b: describe|7|
+c: KeyboardKey.KEY_Z
+e: dir_code|3|
+d: describe|66|
+`, header)
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	want := []string{
+		"key_code|...| takes a KeyboardKey, got a GamepadButton",
+		"7 is not a value of KeyboardKey, which describe|...| takes",
+		"KeyboardKey has no member KEY_Z",
+		"3 is not a value of Direction, which dir_code|...| takes",
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("expected %d errors, got %+v", len(want), diagnostics)
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Line != i+5 || diagnostic.Message != want[i] {
+			t.Errorf("expected %q on line %d, got %+v", want[i], i+5, diagnostic)
+		}
+	}
+}
+
+func TestBuildEnumConversions(t *testing.T) {
+	source := `enum direction:
+	north
+	east
+	south
+$
+enum:flags perms
+	read
+	write
+$
+d: direction.south
+n: int|d|
+e: direction|1|
+f: direction|n|
+p: perms|n|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"n = ((int)(d));",
+		"e = 1;",
+		"f = ahoy_enum_direction(n, ",
+		"        case 2:\n            return value;",
+		"    if ((value & ~3) == 0) return value;",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	for _, test := range []struct {
+		source string
+		line   int
+		want   string
+	}{
+		{"x: direction|3|", 7, "3 is not a value of direction"},
+		{"x: direction|paint.red|", 7, "direction|...| is given a paint value"},
+		{"x: direction.north is paint.red", 7, "'is' mixes direction and paint values"},
+		{"x: direction.north\nx: paint.red", 8, "x holds direction values, it can't be given a paint value"},
+		{"x: direction.north\ny: x\nz: y is paint.red", 9, "'is' mixes direction and paint values"},
+	} {
+		source := "enum direction:\n\tnorth\n$\nenum paint:\n\tred\n$\n" + test.source + "\n"
+		_, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+		if err != ErrCodeGeneration {
+			t.Fatalf("%s: expected ErrCodeGeneration, got %v", test.source, err)
+		}
+		if len(diagnostics) != 1 || diagnostics[0].Line != test.line || diagnostics[0].Message != test.want {
+			t.Errorf("%s: expected %q on line %d, got %+v", test.source, test.want, test.line, diagnostics)
+		}
+	}
+}
+
+func TestBuildEnumVariablesPrintNames(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `enum direction:
+	north
+	east
+$
+@ facing |d:direction| string:
+	return format|"facing {}", d|
+$
+d: direction.east
+n:int = direction.east
+print|d, n|
+shown: facing|d|
+print|shown|
+print|f"now {d}"|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if want := "east 1\nfacing east\nnow east\n"; err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildDeferRunsOnce(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `@ early |n:int| int:
+	defer print|"early"|
+	if n > 2 then
+		return 1
+	$
+	return 0
+$
+@ looped || int:
+	count: 0
+	loop i till 5 do
+		if i is 2 then
+			defer print|"looped"|
+			halt
+		$
+		count: count + 1
+	$
+	return count
+$
+@ maybe |n:int| void:
+	if n > 0 then
+		defer print|"maybe"|
+	$
+$
+a: early|5|
+b: early|1|
+c: looped||
+maybe|0|
+maybe|1|
+print|a, b, c|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if want := "early\nearly\nlooped\nmaybe\n1 0 2\n"; err != nil || string(output) != want {
+		t.Errorf("expected each reached defer to run once, got %q: %v", output, err)
+	}
+}
+
+func TestBuildCover(t *testing.T) {
+	source := `@ sign |n:int| int:
+    if n lesser_than 0 then
+        return 0
+    $
+    return 1
+$
+test "positive":
+    assert sign|5| is 1
+$
+`
+	outputDir := t.TempDir()
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: outputDir, Test: true, Cover: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if want, _ := filepath.Abs(filepath.Join(outputDir, CoverageFile)); artifacts.Coverage != want {
+		t.Errorf("expected the profile at %s, got %s", want, artifacts.Coverage)
+	}
+	for _, want := range []string{
+		"static unsigned long ahoy_cover_hits[3];",
+		"static const int ahoy_cover_lines[3] = {2, 3, 5};",
+		"    atexit(ahoy_cover_write);\n",
+		"ahoy_cover_hits[1]++;\n        return 0;",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Test: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if artifacts.Coverage != "" || strings.Contains(artifacts.CCode, "ahoy_cover") {
+		t.Errorf("expected no coverage without Cover")
+	}
+}
+
+func TestBuildSwitchExpressionTypes(t *testing.T) {
+	source := `struct point:
+  x: int,
+  y: int
+$
+n: 2
+label: switch n:
+    on 1: "one"
+    _: "many"
+$
+ratio: switch n:
+    on 1: 1
+    _: 2.5
+$
+p: switch n:
+    on 1: point{x: 1, y: 1}
+    _: point{x: 2, y: 3}
+$
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"char* label;", "double ratio;", "Point p;"} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	// Cases that give different types are an error on the first that differs
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "n: 2\nlabel: switch n:\n    on 1: 7\n    _: \"many\"\n$\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 4 || !strings.Contains(diagnostics[0].Message, "'string'") {
+		t.Errorf("expected an error about the string case on line 4, got %+v", diagnostics)
+	}
+}
+
+func TestBuildCollectionConstants(t *testing.T) {
+	source := `COLORS:: ["red", "green"]
+LIMITS:: {"max": 10}
+@ pick |i:int| string:
+    return COLORS[i]
+$
+name: pick|1|
+print|name|
+print|LIMITS{"max"}|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"AhoyArray* COLORS;", "static void ahoy_init_constants(void) {", "    ahoy_init_constants();"} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+	if strings.Contains(artifacts.CCode, "const AhoyArray* COLORS =") {
+		t.Error("expected COLORS to be set by ahoy_init_constants, not at file scope")
+	}
+}
+
+func TestBuildDivision(t *testing.T) {
+	source := `a: 7
+x: 7.5
+half: a / 2
+whole: x div 2
+rest: x mod 2
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"double half;", "((double)a / 2)", "int whole;", "((int)(x / 2))", "fmod(x, 2)"} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "x: 7.5\nrest: x % 2\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 || !strings.Contains(diagnostics[0].Message, "'%'") {
+		t.Errorf("expected an error about '%%' on line 2, got %+v", diagnostics)
+	}
+}
+
+func TestBuildSizeOf(t *testing.T) {
+	source := `struct player:
+  x: int
+  hp: float
+$
+PLAYER_SIZE:: size_of|player|
+p: player{x: 1, hp: 2.0}
+align: align_of|int|
+size: size_of|p|
+info: p.dump_struct||
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"const int PLAYER_SIZE = ((int)sizeof(Player));",
+		"((int)_Alignof(int))",
+		"((int)sizeof(p))",
+		"offsetof(Player, hp), sizeof(((Player*)0)->hp)",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "n: size_of|nothing|\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "'nothing'") {
+		t.Errorf("expected an error about 'nothing', got %+v", diagnostics)
+	}
+}
+
+func TestBuildExtern(t *testing.T) {
+	source := `extern c_puts |s:string| int from "puts"
+extern root |x:float| float from "sqrt" link "m"
+c_puts|"ahoy"|
+r: root|2.0|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		`int ahoy_extern_c_puts(char* s) __asm__(AHOY_EXTERN_NAME("puts"));`,
+		`double ahoy_extern_root(double x) __asm__(AHOY_EXTERN_NAME("sqrt"));`,
+		`ahoy_extern_c_puts("ahoy");`,
+		"double r;",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "extern c_abs |n:int| int from \"abs\"\nx: c_abs|1, 2|\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 || !strings.Contains(diagnostics[0].Message, "takes 1 argument") {
+		t.Errorf("expected an argument count error on line 2, got %+v", diagnostics)
+	}
+}
+
+func TestBuildImportedExternLinks(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	if _, err := exec.LookPath("ar"); err != nil {
+		t.Skip("ar not installed")
+	}
+	// The extern is two imports away from the program and links a library
+	// gcc only finds through LIBRARY_PATH
+	dir := t.TempDir()
+	files := map[string]string{
+		"bump.c":       "int bump(int n) { return n + 1; }\n",
+		"counter.ahoy": "extern bump |n:int| int link \"bump\"\n@ step |n:int| int:\n    return bump|n|\n$\n",
+		"steps.ahoy":   "import \"./counter.ahoy\"\n@ twice |n:int| int:\n    once: step|n|\n    return step|once|\n$\n",
+		"main.ahoy":    "import \"./steps.ahoy\"\nx: twice|1|\nprint|x|\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	object := filepath.Join(dir, "bump.o")
+	if output, err := exec.Command("gcc", "-c", "-o", object, filepath.Join(dir, "bump.c")).CombinedOutput(); err != nil {
+		t.Fatalf("gcc: %v %s", err, output)
+	}
+	if output, err := exec.Command("ar", "rcs", filepath.Join(dir, "libbump.a"), object).CombinedOutput(); err != nil {
+		t.Fatalf("ar: %v %s", err, output)
+	}
+	t.Setenv("LIBRARY_PATH", dir)
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: filepath.Join(dir, "main.ahoy"), OutputDir: t.TempDir(), Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "3\n" {
+		t.Errorf("expected 3, got %q: %v", output, err)
+	}
+}
+
+func TestBuildStringHashSwitch(t *testing.T) {
+	// "ae" and "bD" have the same djb2 hash
+	cases := ""
+	for _, word := range []string{"if", "else", "loop", "do", "on", "switch", "return", "halt"} {
+		cases += fmt.Sprintf("    on \"%s\": print|\"%s\"|\n", word, word)
+	}
+	source := "word: \"ae\"\nswitch word:\n" + cases + "    on \"ae\", \"bD\": print|\"pair\"|\n    _: print|\"other\"|\n$\n"
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "switch (ahoy_switch_hash(__switch_0)) {") {
+		t.Fatalf("expected a switch on the hash of the word, got:\n%s", artifacts.CCode)
+	}
+	collision := strings.Index(artifacts.CCode, fmt.Sprintf("case %du:\n", stringSwitchHash("ae")))
+	if collision < 0 {
+		t.Fatalf("expected a case for the hash of \"ae\"")
+	}
+	bucket := artifacts.CCode[collision:]
+	bucket = bucket[:strings.Index(bucket, "break;")]
+	for _, want := range []string{
+		"if (strcmp(__switch_0, \"ae\") == 0) goto __switch_0_case_8;",
+		"if (strcmp(__switch_0, \"bD\") == 0) goto __switch_0_case_8;",
+	} {
+		if !strings.Contains(bucket, want) {
+			t.Errorf("expected the colliding case to contain %s, got:\n%s", want, bucket)
+		}
+	}
+
+	// Switches with few cases keep comparing in order
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, "word: \"a\"\nswitch word:\n    on \"a\": print|1|\n    _: print|2|\n$\n"), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "ahoy_switch_hash") {
+		t.Errorf("expected a switch with one case to use strcmp")
+	}
+
+	// A repeated case could never match, whichever way the switch compares
+	for _, source := range []string{
+		"word: \"ae\"\nswitch word:\n" + cases + "    on \"do\", \"ae\": print|\"again\"|\n$\n",
+		"word: \"a\"\nswitch word:\n    on \"a\": print|1|\n    on \"b\": print|2|\n    on \"do\": print|3|\n    on \"c\", \"do\": print|4|\n$\n",
+	} {
+		_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+		if err != ErrCodeGeneration || len(diagnostics) != 1 || !strings.HasPrefix(diagnostics[0].Message, "switch case \"do\" repeats the case on line ") {
+			t.Errorf("expected an error for the repeated \"do\" in %q, got %v %+v", source, err, diagnostics)
+		}
+	}
+}
+
+func TestBuildReadableC(t *testing.T) {
+	path := writeSource(t, "nums: [1, 2, 3]\nfirst: nums[0] ? bounds checked\nprint|first|\n")
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), ReadableC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"// main.ahoy:2: first: nums[0] ? bounds checked\nfirst = ({\n    int __idx = 0;\n",
+		"        exit(1);\n    }\n    __arr->data[__idx];\n});\n",
+		"// main.ahoy:3: print|first|\n",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected readable C to contain %q", want)
+		}
+	}
+	for _, line := range strings.Split(artifacts.CCode, "\n") {
+		if strings.Contains(line, "({") && len(line) > DefaultCLineWidth {
+			t.Errorf("expected long statement expressions to be split, got %s", line)
+		}
+	}
+
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "// main.ahoy:") {
+		t.Error("expected no source comments without ReadableC")
+	}
+}
+
+func TestEmitFunction(t *testing.T) {
+	path := writeSource(t, `@ shout |name:string| string:
+    return name.upper||
+$
+@ twice |x:int| int:
+    return x * 2
+$
+words: ["a", "b"]
+words.push|"c"|
+loud: shout|"ahoy"|
+print|loud|
+`)
+	code, diagnostics, err := EmitFunction(BuildOptions{Source: path}, "shout")
+	if err != nil {
+		t.Fatalf("EmitFunction: %v %v", err, diagnostics)
+	}
+	if !strings.HasPrefix(code, "char* shout(char* name) {") {
+		t.Errorf("expected the function first, got:\n%s", code)
+	}
+	// upper calls ahoy_string_dup, which the function doesn't name itself
+	for _, helper := range []string{"char* ahoy_string_upper(", "char* ahoy_string_dup("} {
+		if !strings.Contains(code, helper) {
+			t.Errorf("expected the helper %s, got:\n%s", helper, code)
+		}
+	}
+	for _, other := range []string{"twice(", "ahoy_array_push", "int main("} {
+		if strings.Contains(code, other) {
+			t.Errorf("expected %s to be left out, got:\n%s", other, code)
+		}
+	}
+
+	if _, _, err := EmitFunction(BuildOptions{Source: path}, "missing"); err == nil || !strings.Contains(err.Error(), "no function 'missing'") {
+		t.Errorf("expected an error for a missing function, got %v", err)
+	}
+}
+
+func TestBuildSplitRuntime(t *testing.T) {
+	path := writeSource(t, "words: [\"a\", \"b\"]\nwords.push|\"c\"|\nprint|words|\n")
+	outputDir := t.TempDir()
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: outputDir, SplitRuntime: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if artifacts.Runtime != filepath.Join(outputDir, RuntimeFile) {
+		t.Fatalf("unexpected runtime path %s", artifacts.Runtime)
+	}
+	runtime, err := os.ReadFile(artifacts.Runtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := os.ReadFile(filepath.Join(outputDir, RuntimeHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(artifacts.CCode, "#include \""+RuntimeHeader+"\"\n") || strings.Contains(artifacts.CCode, "AhoyArray* ahoy_array_push(") {
+		t.Error("expected the program to include the runtime header instead of defining the helpers")
+	}
+	if !strings.Contains(string(runtime), "AhoyArray* ahoy_array_push(AhoyArray* arr, intptr_t value, AhoyValueType type) {") {
+		t.Error("expected the runtime to define the helpers the program calls")
+	}
+	if !strings.Contains(string(header), "} AhoyArray;") || !strings.Contains(string(header), "char* print_array_helper(AhoyArray* arr);") {
+		t.Error("expected the header to declare the runtime's types and functions")
+	}
+	for _, unused := range []string{"ahoy_array_pop", "createArray", "// ahoy:runtime"} {
+		if strings.Contains(string(runtime)+string(header)+artifacts.CCode, unused) {
+			t.Errorf("expected %s to be left out", unused)
+		}
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: outputDir, SplitRuntime: true, Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "[\"a\", \"b\", \"c\"]\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildDictCapacity(t *testing.T) {
+	var source strings.Builder
+	source.WriteString("d: dict[8]\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&source, "d{\"k%d\"}: %d\n", i, i)
+	}
+	source.WriteString("print|d.size||\nprint|d{\"k57\"}|\n")
+	path := writeSource(t, source.String())
+
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "createHashMap(8);") {
+		t.Error("expected dict[8] to size the map for 8 keys")
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	// The map grows well past its hint and still finds every key
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "100\n57\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildComptime(t *testing.T) {
+	source := `@ fib |n:int| int:
+	if n < 2 then return n $
+	return fib|n - 1| + fib|n - 2|
+$
+@ squares |n:int| array[int]:
+	result: array[int] = []
+	loop i:0 to n do
+		result.push|i * i|
+	$
+	return result
+$
+@ doubled |n:int, count:int| int:
+	x: n
+	loop i:0 to count do
+		x: x * 2
+	$
+	return x div 4
+$
+FIB :: comptime fib|20|
+table: comptime squares|5|
+print|FIB|
+print|table|
+print|comptime fib|10| - 100|
+wrapped: comptime doubled|3, 31|
+again: doubled|3, 31|
+print|wrapped, again|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"const int FIB = 6765;", "(55 - 100)"} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	// Calls reaching outside the function are reported where they're made
+	source = "count: 5\n@ noisy |n:int| int:\n\tprint|n|\n\treturn n + count\n$\nx: comptime noisy|1|\nprint|x|\n"
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration {
+		t.Fatalf("expected ErrCodeGeneration, got %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 6 || !strings.Contains(diagnostics[0].Message, "print") {
+		t.Errorf("expected an error about print on line 6, got %+v", diagnostics)
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	// An int overflowing while compiling wraps as it does at run time
+	if err != nil || string(output) != "6765\n[0, 1, 4, 9, 16]\n-45\n-536870912 -536870912\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildOptimizeLookup(t *testing.T) {
+	source := `enum tile
+	grass
+	water
+	sand
+	rock
+$
+@ texture_of |t:tile| int:
+	slot:int= switch t:
+		on grass: 4
+		on water: 7
+		on sand, rock: -1
+	$
+	return slot
+$
+@ walkable |t:tile| string:
+	ok:string= switch t:
+		on grass: "yes"
+		on sand: "yes"
+		_: "no"
+	$
+	return ok
+$
+loop i:0 to 4 do
+	t: tile|i|
+	slot: texture_of|t|
+	ok: walkable|t|
+	print|slot, ok|
+$
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Optimize: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "static int const __lookup_0[4] = {4, 7, -1, -1};") {
+		t.Error("expected the switch over every tile to become a lookup table")
+	}
+	// A switch leaving members to its _ case stays a switch
+	if strings.Contains(artifacts.CCode, "__lookup_1") {
+		t.Error("expected the switch over some tiles to stay a switch")
+	}
+
+	plain, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(plain.CCode, "__lookup_") {
+		t.Error("expected lookup tables only when optimizing")
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Optimize: true, Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "4 yes\n7 no\n-1 yes\n-1 no\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildOptimizeInline(t *testing.T) {
+	source := `SCALE :: 2
+@ scaled |x:int| int:
+	return x * SCALE
+$
+@ quad |x:int| int:
+	return scaled|x| + scaled|x|
+$
+@ clamp |x:int, low:int = 0, high:int = 10| int:
+	return (x < low ?? low : (x > high ?? high : x))
+$
+@noinline twice |x:int| int:
+	return x * 2
+$
+@ fact |n:int| int:
+	if n < 2 then return 1 $
+	return n * fact|n - 1|
+$
+@ main || int:
+	x: 3
+	q: quad|x|
+	c: clamp|x * 9|
+	low: clamp|-4, high: 3|
+	d: twice|x|
+	f: fact|5|
+	print|q, c, low, d, f|
+	return 0
+$
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Optimize: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, call := range []string{"quad(x)", "clamp((x * 9))", "scaled(__inline"} {
+		if strings.Contains(artifacts.CCode, call) {
+			t.Errorf("expected %s to be inlined", call)
+		}
+	}
+	// @noinline and recursion keep the calls
+	for _, call := range []string{"twice(x)", "fact(5)", "fact((n - 1))"} {
+		if !strings.Contains(artifacts.CCode, call) {
+			t.Errorf("expected %s to stay a call", call)
+		}
+	}
+
+	plain, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(plain.CCode, "__inline_") {
+		t.Error("expected inlining only when optimizing")
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Optimize: true, Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "12 10 0 6 120\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildTailCalls(t *testing.T) {
+	source := `@ steps |n:int, count:int| int:
+	if n is 0 then return count $
+	return steps|n - 1, count + 1|
+$
+@ countdown |n:int|:
+	if n > 0 then
+		countdown|n - 1|
+	$
+$
+@ fact |n:int| int:
+	if n < 2 then return 1 $
+	return n * fact|n - 1|
+$
+@ main || int:
+	s: steps|1000000, 0|
+	countdown|1000000|
+	f: fact|10|
+	print|s, f|
+	return 0
+$
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"int __tail_0_n = (n - 1);", "count = __tail_0_count;", "goto __tail_call;", "int __tail_1_n = (n - 1);"} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 12 || diagnostics[0].Severity != SeverityWarning ||
+		!strings.Contains(diagnostics[0].Message, "fact") {
+		t.Errorf("expected a warning about fact's recursion on line 12, got %+v", diagnostics)
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "1000000 3628800\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildMultiReturnOutParam(t *testing.T) {
+	source := `struct particle:
+	x: float
+	y: float
+	vx: float
+	vy: float
+	life: float
+	alive: bool
+$
+@ spawn |n:int| particle, particle, int:
+	a: particle{x: 1.0, y: 2.0, vx: 0.5, vy: 0.5, life: 3.0, alive: true}
+	b: particle{x: 4.0, y: 5.0, vx: 0.5, vy: 0.5, life: 2.0, alive: false}
+	return a, b, n * 2
+$
+@ divmod |a:int, b:int| int, int:
+	return a div b, a % b
+$
+@ main || int:
+	p, q, c: spawn|3|
+	print|p.x, q.life, c|
+	d, m: divmod|17, 5|
+	print|d, m|
+	return 0
+$
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"void spawn(int n, spawn_return* __out) {",
+		"*__out = (spawn_return){.ret0 = a, .ret1 = b, .ret2 = (n * 2)};",
+		"spawn(3, &__multi_ret_0);",
+		// Small results are still returned
+		"divmod_return __multi_ret_1 = divmod(17, 5);",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "1 2 6\n3 2\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildTupleFromMethodCall(t *testing.T) {
+	source := `struct point:
+	x: float,
+	y: float
+$
+@ unpack |p:point| float, float:
+	return p.x, p.y
+$
+@ scaled |p:point, by:float| float, float:
+	return p.x * by, p.y * by
+$
+pos: point{x: 1.5, y: 2.5}
+x, y: pos.unpack||
+w, h: pos.scaled|2.0|
+u, v: pos.unpack
+print|x, y, w, h, u, v|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{
+		"unpack_return __multi_ret_0 = unpack(pos);",
+		"scaled_return __multi_ret_1 = scaled(pos, 2.0);",
+		"unpack_return __multi_ret_2 = unpack(pos);",
+		"double w;",
+	} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "1.5 2.5 3 5 1.5 2.5\n" {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildPrune(t *testing.T) {
+	source := `@ square |n:int| int:
+	return n * n
+$
+@ cube |n:int| int:
+	return n * square|n|
+$
+@ helper || int:
+	return 1
+$
+@ main || int:
+	x: square|3|
+	print|x|
+	return 0
+$
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Prune: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if got := strings.Join(artifacts.Pruned, " "); got != "cube helper" {
+		t.Errorf("expected cube and helper to be pruned, got %s", got)
+	}
+	if strings.Contains(artifacts.CCode, "cube(") || !strings.Contains(artifacts.CCode, "int square(int n) {") {
+		t.Errorf("expected square and not cube in the C code")
+	}
+
+	// A library keeps its exports and what they use
+	source = "@ square |n:int| int:\n    return n * n\n$\n@ helper || int:\n    return 1\n$\n@export area |n:int| int:\n    return square|n|\n$\n"
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Library: true, Prune: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if got := strings.Join(artifacts.Pruned, " "); got != "helper" {
+		t.Errorf("expected helper to be pruned from the library, got %s", got)
+	}
+}
+
+func TestBuildRuntimeCache(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	path := writeSource(t, "names: [\"a\", \"b\"]\nprint|names|\n")
+	cache := t.TempDir()
+	for i, wantCached := range []bool{false, true} {
+		outputDir := t.TempDir()
+		artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: outputDir, Compile: true, Report: true, RuntimeCache: cache})
+		if err != nil {
+			t.Fatalf("Build: %v %v", err, diagnostics)
+		}
+		if artifacts.Runtime != filepath.Join(outputDir, RuntimeFile) {
+			t.Errorf("expected the runtime to be split out, got %q", artifacts.Runtime)
+		}
+		data, err := os.ReadFile(artifacts.Report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report BuildReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.RuntimeCached != wantCached {
+			t.Errorf("build %d: expected runtime_cached to be %v", i+1, wantCached)
+		}
+		output, err := exec.Command(artifacts.Executable).CombinedOutput()
+		if err != nil || string(output) != `["a", "b"]`+"\n" {
+			t.Errorf("unexpected output %q: %v", output, err)
+		}
+	}
+	objects, _ := filepath.Glob(filepath.Join(cache, "*"))
+	if len(objects) != 1 || !strings.HasSuffix(objects[0], ".o") {
+		t.Errorf("expected one cached runtime object, got %v", objects)
+	}
+}
+
+func TestBuildWerrorC(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `enum:string names
+	"jared" jared
+	"bob" bob
+$
+
+struct point:
+  x: int,
+  y: int
+$
+
+struct line:
+  head: point,
+  tail: point
+$
+
+@ answer || int:
+	unused: 7
+	return 42
+$
+
+@ count |lookup:dict| int:
+	loop key, value in lookup do
+		print|"entry"|
+	$
+	return lookup.size||
+$
+
+n: names.bob
+print|n|
+pairs: [[1, 2], [3, 4]]
+firsts: pairs.map|(a, b): a|
+print|firsts|
+lower: "BOB".lower||
+entries: count|{"a": 1}|
+print|lower, answer||, entries|
+l: line{head: point{x: 1, y: 2}, tail: point{x: 3, y: 4}}
+print|l|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, want := range []string{"int answer(void) {", "(void)unused;", "(void)key;", "(void)value;", "(void)b;", "(char*)names.bob"} {
+		if !strings.Contains(artifacts.CCode, want) {
+			t.Errorf("expected the C code to contain %s", want)
+		}
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "bob\n[1, 3]\nentry\nbob 42 1\nline{head:point{x:1, y:2}, tail:point{x:3, y:4}}\n"
+	if err != nil || string(output) != want {
+		t.Errorf("unexpected output %q: %v", output, err)
+	}
+}
+
+func TestBuildOnExit(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `@ save || void:
+	print|"saving"|
+$
+
+@ close || void:
+	print|"closing"|
+$
+
+@ main || void:
+	on_exit|save|
+	on_exit|close|
+	print|"running"|
+	n: 0
+	loop till n >= 0 do
+		n: 1
+	$
+$
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+
+	// The program runs until it's stopped, and the hooks run last first
+	var output bytes.Buffer
+	cmd := exec.Command(artifacts.Executable)
+	cmd.Stdout = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	cmd.Process.Signal(syscall.SIGTERM)
+	err = cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 128+int(syscall.SIGTERM) {
+		t.Errorf("expected exit status %d, got %v", 128+int(syscall.SIGTERM), err)
+	}
+	if want := "running\nclosing\nsaving\n"; output.String() != want {
+		t.Errorf("expected %q, got %q", want, output.String())
+	}
+}
+
+func TestBuildOnExitNeedsPlainFunction(t *testing.T) {
+	source := `@ show |x:int| void:
+	print|x|
+$
+
+@ total || int:
+	return 1
+$
+
+on_exit|show|
+on_exit|total|
+on_exit|missing|
+`
+	path := writeSource(t, source)
+	_, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 3 {
+		t.Fatalf("expected 3 errors, got %v %v", err, diagnostics)
+	}
+	for i, want := range []string{"can't take parameters", "can't return a value", "needs the name of the function"} {
+		if !strings.Contains(diagnostics[i].Message, want) {
+			t.Errorf("expected %q in %q", want, diagnostics[i].Message)
+		}
+	}
+}
+
+func TestBuildCliModule(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `cli.flag_int|"width", 800, "window width"|
+cli.flag_float|"scale", 1.5, "zoom"|
+cli.flag_string|"title", "Ahoy", "window title"|
+cli.flag_bool|"fullscreen", false, "start fullscreen"|
+cli.parse||
+width: cli.get_int|"width"|
+scale: cli.get_float|"scale"|
+title: cli.get_string|"title"|
+count: cli.arg_count||
+print|width, scale, title, count|
+loop i:0 to count do
+	arg: cli.arg|i|
+	print|arg|
+$
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "int main(int argc, char** argv) {") {
+		t.Errorf("expected main to take the command line")
+	}
+
+	output, err := exec.Command(artifacts.Executable, "--width", "1024", "-scale=2", "level1", "--", "--raw").CombinedOutput()
+	if want := "1024 2 Ahoy 2\nlevel1\n--raw\n"; err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+	output, err = exec.Command(artifacts.Executable, "--help").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "--width int") || !strings.Contains(string(output), "window title (default \"Ahoy\")") {
+		t.Errorf("unexpected help %q: %v", output, err)
+	}
+	output, err = exec.Command(artifacts.Executable, "--width", "wide").CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 || !strings.Contains(string(output), "Flag --width takes an int, got 'wide'") {
+		t.Errorf("expected a usage error, got %q: %v", output, err)
+	}
+}
+
+func TestBuildCliModuleChecksFlags(t *testing.T) {
+	source := `cli.flag_int|"width", 800, "window width"|
+cli.flag_int|"width", 640, "again"|
+title: cli.get_string|"width"|
+cli.flags||
+`
+	path := writeSource(t, source)
+	_, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 3 {
+		t.Fatalf("expected 3 errors, got %v %v", err, diagnostics)
+	}
+	for i, want := range []string{"already declared as int", "read it with cli.get_int", "has no 'flags'"} {
+		if !strings.Contains(diagnostics[i].Message, want) {
+			t.Errorf("expected %q in %q", want, diagnostics[i].Message)
+		}
+	}
+}
+
+func TestBuildFrameModule(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `elapsed: 0.0
+loop i:0 to 11 do
+	frame.limit|100|
+	elapsed: elapsed + frame.delta||
+$
+steady: elapsed >= 0.095 and elapsed < 1.0
+print|steady|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	// Ten frames at 100 per second take at least a tenth of a second
+	start := time.Now()
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if took := time.Since(start); took < 95*time.Millisecond {
+		t.Errorf("expected the frames to be limited, took %v", took)
+	}
+	if err != nil || string(output) != "1\n" {
+		t.Errorf("expected the deltas to add up to the time taken, got %q: %v", output, err)
+	}
+}
+
+func TestBuildFormat(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `score: 42
+name: "bob"
+nums: [1, 2]
+label: format|"{} scored {}", name, score|
+pair: format|name, score|
+listed: format|"nums=%v", nums|
+line: format|"kept\n"|
+wide: sprintf|"%0300d", score|
+print|label|
+print|pair|
+print|listed|
+printf|line|
+print|wide.length||
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "malloc(256)") {
+		t.Errorf("expected strings to be sized to fit")
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "bob scored 42\nbob 42\nnums=[1, 2]\nkept\n300\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildRawPrint(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `rate: "100%d"
+count: 3
+nums: [1, 2]
+print_raw|rate, count|
+println||
+println|rate, count, nums|
+print_err|"to stderr"|
+print_raw|"no newline"|
+flush||
+print_err|"after the flush"|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	// stdout and stderr share a pipe here, so their order shows the buffering
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "100%d3\n100%d 3 [1, 2]\nto stderr\nno newlineafter the flush\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildPlaceholderSpecs(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `score: 1234567
+debt: -4500
+pi: 3.14159
+total: 9876543.215
+name: "Ahoy sailor"
+print|f"Score: {score:,} pi: {pi:.2}"|
+print|"{:,} {:,.1} {:.3} {:.4} {:.1}", debt, total, pi, name, score|
+label: format|"{:,} pts", score|
+print|label|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "Score: 1,234,567 pi: 3.14\n-4,500 9,876,543.2 3.142 Ahoy 1234567.0\n1,234,567 pts\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildDictStructFields(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `struct player:
+  name: string,
+  health: int
+$
+
+@ heal |team:dict<string, player>, who:string| void:
+	team{who}.health += 5
+$
+
+bob: player{name: "bob", health: 10}
+team:dict<string, player> = {"bob": bob}
+team{"bob"}.health: 3
+heal|team, "bob"|
+print|team{"bob"}.health|
+print|team|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "(*(Player*)hashMapGet(team, who)).health") {
+		t.Errorf("expected the field to be reached through the slot's pointer with the key's value")
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "8\n{\"bob\": player{name:\"bob\", health:8}}\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildDictStructFieldsNeedTypedDict(t *testing.T) {
+	source := `struct player:
+  name: string,
+  health: int
+$
+
+bob: player{name: "bob", health: 10}
+team: {"bob": bob}
+team{"bob"}.health: 3
+counts:dict<string, int> = {"bob": 1}
+print|counts{"bob"}.health|
+`
+	path := writeSource(t, source)
+	_, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 2 {
+		t.Fatalf("expected 2 errors, got %v %v", err, diagnostics)
+	}
+	if !strings.Contains(diagnostics[0].Message, "a dict without a value type") || !strings.Contains(diagnostics[1].Message, "holds int values") {
+		t.Errorf("unexpected errors %v", diagnostics)
+	}
+}
+
+func TestBuildMethodChains(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `name: "  ahoy sailor  "
+shout: name.strip||.upper||.replace|"A", "B"|
+print|shout|
+words: name.strip||.split|" "|.length||
+print|words|
+parts: "pear,apple,fig".split|","|.reverse||
+print|parts|
+fields: "a,b,c".split|","|
+first: fields[0]
+print|first.upper||, fields[2]|
+nums: [3, 1, 2]
+top: nums.sort||.reverse||.length||
+print|top|
+doubled: nums.map|x: x * 2|.sum||
+print|doubled|
+scores: dict<string, int> {"b": 2, "a": 1}
+ranked: scores.sort||.keys||.length||
+print|ranked|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "BHOY SBILOR\n2\n[\"fig\", \"apple\", \"pear\"]\nA c\n3\n12\n2\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+	if c, _ := os.ReadFile(artifacts.CFile); !strings.Contains(string(c), "char* first;") {
+		t.Errorf("expected the indexed split result declared as char*:\n%s", c)
+	}
+}
+
+func TestBuildDictKeyOrder(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `stock: dict<string, int> {"pear": 3, "apple": 5, "kiwi": 1, "fig": 2}
+print|stock|
+loop name, count in stock.items_sorted|| do
+	print|"{}={}", name, count|
+$
+pairs: stock.items_sorted||
+print|pairs|
+nested: {"z": {"b": 1, "a": 2}, "m": "s"}
+print|nested|
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := `{"apple": 5, "fig": 2, "kiwi": 1, "pear": 3}
+apple=5
+fig=2
+kiwi=1
+pear=3
+[["apple", 5], ["fig", 2], ["kiwi", 1], ["pear", 3]]
+{"m": "s", "z": {"a": 2, "b": 1}}
+`
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildDictGet(t *testing.T) {
+	_, diagnostics, err := Build(BuildOptions{Source: writeSource(t, "d: {\"a\": 1}\nx, found: d.get_or|\"a\", 2|\ny: d.get||\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 2 ||
+		diagnostics[0].Message != "get_or gives back one value, not 2" ||
+		diagnostics[1].Message != "get takes 1 argument(s), got 0" {
+		t.Fatalf("expected errors for get_or with two targets and get without a key, got %v %+v", err, diagnostics)
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `struct point:
+    x: int
+    y: int
+$
+@ score_of |scores:dict<string, float>, name:string| float:
+    score, ok: scores.get|name|
+    if ok then
+        return score
+    $
+    return -1.0
+$
+@ main ||:
+    counts: dict<string, int> {"apples": 0}
+    n, found: counts.get|"apples"|
+    m, there: counts.get|"plums"|
+    print|"{} {} {} {}", n, found, m, there|
+    p: counts.get_or|"plums", 9|
+    print|p|
+    roles: dict<string, string> {"ann": "admin"}
+    role: roles.get_or|"bo", "guest"|
+    print|role|
+    scores: dict<string, float> {"ann": 2.5}
+    a: score_of|scores, "ann"|
+    print|a|
+    points: dict<string, point> {"origin": point{x: 1, y: 2}}
+    origin, has_origin: points.get|"origin"|
+    print|"{} {}", origin.y, has_origin|
+    mixed: {"x": 2, "y": 1.5}
+    x: mixed.get|"x"|
+    print|x|
+$
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "0 1 0 0\n9\nguest\n2.5\n2 1\n2\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildArraySet(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `@ main ||:
+    names: ["a", 2]
+    names[1]: "b"
+    print|names|
+    fs: array[float] [1.5]
+    fs[0]: 3
+    fs[2]: 2.5
+    print|fs|
+    ss: array[string] ["x"]
+    ss[2]: "z"
+    print|ss|
+    print|ss.length||
+$
+`
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != 1 ||
+		!strings.Contains(string(output), "RUNTIME ERROR: Array bounds violation") ||
+		!strings.Contains(string(output), "  Line: 7\n") || !strings.Contains(string(output), "  Index: 2\n") {
+		t.Errorf("expected a bounds error for fs[2], got %v %q", err, output)
+	}
+
+	artifacts, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true, GrowArrays: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err = exec.Command(artifacts.Executable).CombinedOutput()
+	want := "[\"a\", \"b\"]\n[3, 0, 2.5]\n[\"x\", \"\", \"z\"]\n3\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildStructIterator(t *testing.T) {
+	iterator := `struct countdown:
+    from: int
+$
+@ countdown_iter_begin |c:countdown| int:
+    return c.from
+$
+`
+	_, diagnostics, err := Build(BuildOptions{Source: writeSource(t, iterator+"loop tick in countdown{from: 3} do\n    print|tick|\n$\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 1 || diagnostics[0].Message != "countdown is looped over but has no countdown_iter_next" {
+		t.Fatalf("expected an error for a missing countdown_iter_next, got %v %+v", err, diagnostics)
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := iterator + `@ countdown_iter_next |c:countdown, at:int| string, int, bool:
+    if at <= 0 or at > c.from then
+        return "", 0, false
+    $
+    return format|"t-{}", at|, at - 1, true
+$
+@ main ||:
+    launch: countdown{from: 4}
+    loop tick in launch do
+        if tick is "t-3" then
+            next
+        $
+        print|tick|
+    $
+    loop tick in countdown{from: 1} do
+        print|tick|
+    $
+$
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "t-4\nt-2\nt-1\nt-1\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildLibrary(t *testing.T) {
+	_, diagnostics, err := Build(BuildOptions{Source: writeSource(t, "@export total |xs:array[int]| int:\n    return 0\n$\n"), OutputDir: t.TempDir(), Library: true})
+	if err != ErrCodeGeneration || len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "exported function total can't take") {
+		t.Fatalf("expected an error for an array parameter, got %v %+v", err, diagnostics)
+	}
+	if _, _, err := Build(BuildOptions{Source: writeSource(t, "x: 1\n"), OutputDir: t.TempDir(), Bindings: []string{"go"}}); err == nil {
+		t.Fatal("expected an error for bindings without Library")
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `struct point:
+    x: float
+    y: float
+$
+offset: 10
+@export shift |p:point, by:int| point, bool:
+    return point{x: p.x + by + offset, y: p.y}, by > 0
+$
+@export echo |s:string| string:
+    return s
+$
+`
+	outputDir := t.TempDir()
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: outputDir, Compile: true, WerrorC: true, Library: true, Bindings: []string{"go", "python"}})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if filepath.Base(artifacts.Library) != "libmain.so" || filepath.Base(artifacts.Header) != "main.h" || len(artifacts.Bindings) != 2 {
+		t.Fatalf("unexpected artifacts %+v", artifacts)
+	}
+
+	caller := filepath.Join(outputDir, "caller.c")
+	os.WriteFile(caller, []byte(`#include <stdio.h>
+#include "main.h"
+int main(void) {
+    main_init();
+    shift_return r = shift((Point){1.5, 2.0}, 3);
+    printf("%g %g %d %s\n", r.ret0.x, r.ret0.y, r.ret1, echo("hi"));
+    return 0;
+}
+`), 0644)
+	executable := filepath.Join(outputDir, "caller")
+	if output, err := exec.Command("gcc", caller, "-I", outputDir, "-L", outputDir, "-lmain", "-Wl,-rpath,"+outputDir, "-o", executable).CombinedOutput(); err != nil {
+		t.Fatalf("gcc: %v\n%s", err, output)
+	}
+	output, err := exec.Command(executable).CombinedOutput()
+	want := "14.5 2 1 hi\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+}
+
+func TestBuildSetConstants(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `print|"v{} ({}) built {}", build.version, build.commit, build.date|
+stamp: build.version
+print|stamp.length||
+`
+	path := writeSource(t, source)
+	set := map[string]string{"version": "1.2.3", "commit": `ab"c`}
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true, Set: set})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := "v1.2.3 (ab\"c) built \n5\n"
+	if err != nil || string(output) != want {
+		t.Errorf("expected %q, got %q: %v", want, output, err)
+	}
+
+	if _, _, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Set: map[string]string{"tag": "x"}}); err == nil || !strings.Contains(err.Error(), "'tag'") {
+		t.Errorf("expected an error setting an unknown constant, got %v", err)
+	}
+	path = writeSource(t, "print|build.tag|\n")
+	_, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) == 0 || !strings.Contains(diagnostics[0].Message, "no 'tag'") {
+		t.Errorf("expected an error reading an unknown constant, got %v %v", err, diagnostics)
+	}
+}
+
+func TestBuildStringTables(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `start: tr|"start"|
+quit: tr|"quit"|
+key: "unknown"
+other: tr|key|
+print|start|
+print|quit|
+print|other|
+`
+	path := writeSource(t, source)
+	table := `? Menu text
+[en]
+start: "Start game"
+quit: "Quit"
+
+[fr]
+start: "Commencer"
+`
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "menu"+StringsExt), []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	// A language without a string falls back to the default one
+	for env, want := range map[string]string{
+		"":                     "Start game\nQuit\nunknown\n",
+		LanguageEnv + "=fr":    "Commencer\nQuit\nunknown\n",
+		"LANG=fr_FR.UTF-8":     "Commencer\nQuit\nunknown\n",
+		LanguageEnv + "=de_DE": "Start game\nQuit\nunknown\n",
+	} {
+		cmd := exec.Command(artifacts.Executable)
+		cmd.Env = []string{env}
+		output, err := cmd.CombinedOutput()
+		if err != nil || string(output) != want {
+			t.Errorf("with %q expected %q, got %q: %v", env, want, output, err)
+		}
+	}
+
+	path = writeSource(t, "title: tr|\"title\"|\n")
+	os.WriteFile(filepath.Join(filepath.Dir(path), "menu"+StringsExt), []byte(table), 0644)
+	_, diagnostics, err = Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) == 0 || !strings.Contains(diagnostics[0].Message, "no string 'title'") {
+		t.Errorf("expected an error for a key the table doesn't have, got %v %v", err, diagnostics)
+	}
+
+	path = writeSource(t, "title: tr|\"title\"|\n")
+	os.WriteFile(filepath.Join(filepath.Dir(path), "menu"+StringsExt), []byte("[en]\ntitle: Ahoy\n"), 0644)
+	if _, _, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "menu.ahoy-strings:2") {
+		t.Errorf("expected an error for an unquoted string, got %v", err)
+	}
+}
+
+func TestBuildRegisteredBuiltins(t *testing.T) {
+	registered := builtins
+	t.Cleanup(func() { builtins = registered })
+
+	// A telemetry set: two builtins sharing a counter table
+	prototypes := "int telemetry_count(const char* name);\n"
+	helpers := `static const char* telemetry_names[16];
+static int telemetry_counts[16];
+
+int telemetry_count(const char* name) {
+    int i = 0;
+    while (i < 16 && telemetry_names[i] != NULL && strcmp(telemetry_names[i], name) != 0) i++;
+    if (i == 16) return -1;
+    telemetry_names[i] = name;
+    return ++telemetry_counts[i];
+}
+`
+	RegisterBuiltin(Builtin{Name: "telemetry_count", ReturnType: "int", Arguments: 1, Prototypes: prototypes, Helpers: helpers})
+	RegisterBuiltin(Builtin{Name: "telemetry_event", ReturnType: "int", Arguments: 2, Prototypes: prototypes, Helpers: helpers,
+		Generate: func(call *BuiltinCall) string {
+			if call.Types[1] != "int" {
+				call.Errorf("telemetry_event takes an int weight, got %s", call.Types[1])
+			}
+			return fmt.Sprintf("(telemetry_count(%s) * %s)", call.Arguments[0], call.Arguments[1])
+		}})
+	RegisterBuiltin(Builtin{Name: "telemetry_count", ReturnType: "int", Arguments: 1, Prototypes: prototypes, Helpers: helpers, Description: "counts"})
+	if names := Builtins(); len(names) != 2 || names[0].Description != "counts" {
+		t.Errorf("expected the second telemetry_count to replace the first, got %+v", names)
+	}
+
+	_, diagnostics, err := Build(BuildOptions{Source: writeSource(t, "x: telemetry_event|\"a\", \"b\"|\ny: telemetry_count||\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 2 ||
+		diagnostics[0].Message != "telemetry_event takes an int weight, got string" ||
+		diagnostics[1].Message != "telemetry_count takes 1 argument(s), got 0" {
+		t.Fatalf("expected argument errors, got %v %+v", err, diagnostics)
+	}
+
+	source := `a: telemetry_count|"start"|
+b: telemetry_count|"start"|
+c: telemetry_event|"start", 10|
+print|"{} {} {}", a, b, c|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if count := strings.Count(artifacts.CCode, "int telemetry_count(const char* name) {"); count != 1 {
+		t.Errorf("expected the shared helpers once, got %d copies", count)
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "1 2 30\n" {
+		t.Errorf("expected 1 2 30, got %q: %v", output, err)
+	}
+}
+
+func TestBuildMinimalRuntime(t *testing.T) {
+	math := "@ square |n:int| int:\n    return n * n\n$\nx: square|7|\n"
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, math), OutputDir: t.TempDir(), MinimalRuntime: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, unused := range []string{"#include", "HashMap", "AhoyValue", "ahoy_signal_handler", "setvbuf"} {
+		if strings.Contains(artifacts.CCode, unused) {
+			t.Errorf("expected no %s in a program that only does math:\n%s", unused, artifacts.CCode)
+		}
+	}
+
+	printing := "names: [\"ann\", \"bo\"]\nprint|names|\n"
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, printing), OutputDir: t.TempDir(), MinimalRuntime: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	for _, used := range []string{"#include <stdio.h>", "setvbuf(stdout", "print_string_array_helper"} {
+		if !strings.Contains(artifacts.CCode, used) {
+			t.Errorf("expected %s in a program that prints an array", used)
+		}
+	}
+	if strings.Contains(artifacts.CCode, "HashMap") {
+		t.Errorf("expected no hash map in a program without dicts")
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	for _, source := range []string{math + "print|\"{}\", x|\n", printing} {
+		artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), MinimalRuntime: true, Compile: true, WerrorC: true})
+		if err != nil {
+			t.Fatalf("Build: %v %v", err, diagnostics)
+		}
+		if output, err := exec.Command(artifacts.Executable).CombinedOutput(); err != nil {
+			t.Errorf("running %q: %v %s", source, err, output)
+		}
+	}
+}
+
+func TestBuildPanicRecover(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `@ deep |n:int| int:
+    if n is 0 then
+        panic|"bottom"|
+    $
+    return deep|n - 1|
+$
+@ safe |n:int| int:
+    recover:
+        return deep|n|
+    $
+    return -1
+$
+recover err:
+    d: deep|5|
+    print|"not reached"|
+$
+print|err|
+s: safe|3|
+print|s|
+loop i:0 to 3 do
+    recover:
+        if i is 1 then
+            next
+        $
+        print|i|
+    $
+$
+recover outer:
+    recover inner:
+        panic|"inner {}", 1|
+    $
+    print|inner|
+    panic|"outer"|
+$
+print|outer|
+deep|2|
+print|"not reached"|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	command := exec.Command(artifacts.Executable)
+	var stderr strings.Builder
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1, got %v", err)
+	}
+	if want := "bottom\n-1\n0\n2\ninner 1\nouter\n"; string(output) != want {
+		t.Errorf("expected %q, got %q", want, output)
+	}
+	if !strings.Contains(stderr.String(), "PANIC: bottom\n  at ") || !strings.Contains(stderr.String(), "stack:") {
+		t.Errorf("expected the panic and a stack on stderr, got %q", stderr.String())
+	}
+}
+
+func TestBuildRecoverLocals(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `@ tally || int:
+    total: 0
+    recover:
+        loop i to 4 do
+            total: total + 1
+        $
+        panic|"stop"|
+    $
+    return total
+$
+t: tally||
+print|f"total {t}"|
+recover:
+    print|"leaving"|
+    return
+$
+print|"not reached"|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.Contains(artifacts.CCode, "int volatile total = 0;") {
+		t.Errorf("expected total to be volatile, got:\n%s", artifacts.CCode)
+	}
+	// Optimized, a total kept in a register would lose the loop's additions
+	optimized := filepath.Join(t.TempDir(), "optimized")
+	args := append([]string{"-O2", "-o", optimized, artifacts.CFile}, CWarningFlags...)
+	if output, err := exec.Command("gcc", append(args, "-lm", "-rdynamic")...).CombinedOutput(); err != nil {
+		t.Fatalf("gcc -O2: %v %s", err, output)
+	}
+	for _, executable := range []string{artifacts.Executable, optimized} {
+		output, err := exec.Command(executable).CombinedOutput()
+		if want := "total 4\nleaving\n"; err != nil || string(output) != want {
+			t.Errorf("%s: expected %q, got %q: %v", filepath.Base(executable), want, output, err)
+		}
+	}
+}
+
+func TestBuildDeterministic(t *testing.T) {
+	source := "nums: [1, 2, 3, 4, 5, 6]\nnums.shuffle||\nprint|nums|\nr: rand||\nprint|r|\nloop i:0 to 2 do\n    frame.limit|1000|\n    d: frame.delta||\n    print|d|\n$\n"
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Deterministic: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if strings.Contains(artifacts.CCode, "srand(time(NULL))") || !strings.Contains(artifacts.CCode, "r = ahoy_det_rand();") {
+		t.Errorf("expected rand and shuffle to use the seeded generator, got:\n%s", artifacts.CCode)
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	artifacts, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true, Deterministic: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	run := func(seed string) string {
+		command := exec.Command(artifacts.Executable)
+		command.Env = append(os.Environ(), "AHOY_SEED="+seed)
+		output, err := command.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", artifacts.Executable, err, output)
+		}
+		return string(output)
+	}
+	first := run("7")
+	if again := run("7"); again != first {
+		t.Errorf("expected the same output for the same seed, got %q and %q", first, again)
+	}
+	if other := run("8"); other == first {
+		t.Errorf("expected another seed to give other numbers, got %q for both", first)
+	}
+	if !strings.HasSuffix(first, "0.001\n0.001\n") {
+		t.Errorf("expected frames of 1/1000s on the virtual clock, got %q", first)
+	}
+}
+
+func TestBuildMemModule(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `before: mem.live_objects||
+nums: [1, 2, 3]
+nums.push|4|
+ages: {"bob": 3, "amy": 4}
+arrays: mem.live_objects|"arrays"|
+dicts: mem.live_objects|"dicts"| > 0
+grew: mem.allocated|| > 0 and mem.live_objects|| > before
+print|arrays|
+print|dicts|
+print|grew|
+mem.report||
+`
+	for _, split := range []bool{false, true} {
+		artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true, SplitRuntime: split})
+		if err != nil {
+			t.Fatalf("Build: %v %v", err, diagnostics)
+		}
+		output, err := exec.Command(artifacts.Executable).CombinedOutput()
+		if err != nil || !strings.HasPrefix(string(output), "3\n1\n1\ncategory") || !strings.Contains(string(output), "\narrays ") {
+			t.Errorf("expected an array's three blocks counted and a report, got %q: %v", output, err)
+		}
+	}
+
+	_, diagnostics, err := Build(BuildOptions{Source: writeSource(t, "n: mem.allocated|\"sprites\"|\n"), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 1 || diagnostics[0].Message != "mem has no category 'sprites'" {
+		t.Errorf("expected an unknown category reported, got %v", diagnostics)
+	}
+}
+
+func TestBuildSwitchHas(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `@ describe |config:dict|:
+	switch config has:
+		on "width", "depth": print|"solid"|
+		on "width" int, "height" int:
+			area: width * height
+			print|"{} by {} is {}", width, height, area|
+		on "title" as name string: print|"called {}", name|
+		_: print|"empty"|
+	$
+$
+
+describe|{"width": 8, "height": 6}|
+describe|{"title": "Ahoy", "width": 1}|
+describe|{}|
+ages: dict<string,int>{"bob": 3}
+switch ages has:
+	on "bob": print|bob + 1|
+$
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	if err != nil || string(output) != "8 by 6 is 48\ncalled Ahoy\nempty\n4\n" {
+		t.Errorf("expected the first case with all its keys to run, got %q: %v", output, err)
+	}
+
+	source = "ages: dict<string,int>{\"bob\": 3}\nswitch ages has:\n\ton \"bob\" string: print|bob|\n$\n"
+	_, diagnostics, err = Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir()})
+	if err != ErrCodeGeneration || len(diagnostics) != 1 || diagnostics[0].Message != `switch has key "bob" can't be a string; the dict holds int values` {
+		t.Errorf("expected the binding's type checked against the dict's, got %+v", diagnostics)
+	}
+}
+
+func TestBuildBanner(t *testing.T) {
+	source := "x: 5\nprint|x|\n"
+	path := writeSource(t, source)
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Safe: true, SplitRuntime: true, License: "Copyright 2026 Example\nSPDX-License-Identifier: MIT\n"})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	if !strings.HasPrefix(artifacts.CCode, "// Copyright 2026 Example\n// SPDX-License-Identifier: MIT\n\n// Generated by ahoy "+Version+" from main.ahoy. Do not edit.\n") {
+		t.Errorf("expected the C to start with the license and banner, got %q", artifacts.CCode[:200])
+	}
+	_, line, _ := strings.Cut(artifacts.CCode, ProvenancePrefix)
+	line, _, _ = strings.Cut(line, "\n")
+	var provenance Provenance
+	if err := json.Unmarshal([]byte(line), &provenance); err != nil {
+		t.Fatalf("expected a JSON provenance line, got %q: %v", line, err)
+	}
+	sum := sha256.Sum256([]byte(source))
+	if provenance.Source != "main.ahoy" || provenance.Files["main.ahoy"] != hex.EncodeToString(sum[:]) ||
+		!slices.Equal(provenance.Options, []string{"-safe", "-split-runtime"}) || provenance.Generated != "" {
+		t.Errorf("expected the source's hash and the flags recorded, got %+v", provenance)
+	}
+	runtime, err := os.ReadFile(artifacts.Runtime)
+	if err != nil || !strings.HasPrefix(string(runtime), "// Copyright 2026 Example\n") || strings.Contains(string(runtime), ProvenancePrefix) {
+		t.Errorf("expected the runtime to carry the license but no provenance, got %q: %v", runtime[:min(len(runtime), 200)], err)
+	}
+
+	artifacts, _, _ = Build(BuildOptions{Source: path, OutputDir: t.TempDir(), Banner: true, BannerTime: true})
+	if !strings.Contains(artifacts.CCode, `"generated":"`) {
+		t.Errorf("expected BannerTime to record when the C was generated")
+	}
+	artifacts, _, _ = Build(BuildOptions{Source: path, OutputDir: t.TempDir()})
+	if strings.Contains(artifacts.CCode, ProvenancePrefix) {
+		t.Errorf("expected no banner unless asked for")
+	}
+}
+
+func TestBuildValuesFormatAlike(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	source := `nums: [1, 2]
+ratio: 0.5
+print|"nums", nums, ratio|
+shown: format|"nums", nums, ratio|
+print|shown|
+print_err|"nums", nums, ratio|
+recover message:
+	panic|"nums", nums, ratio|
+$
+print|message|
+`
+	artifacts, diagnostics, err := Build(BuildOptions{Source: writeSource(t, source), OutputDir: t.TempDir(), Compile: true, WerrorC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	output, err := exec.Command(artifacts.Executable).CombinedOutput()
+	want := strings.Repeat("nums [1, 2] 0.5\n", 4)
+	if err != nil || string(output) != want {
+		t.Errorf("expected print, format, print_err and panic to show values alike, got %q: %v", output, err)
+	}
+}
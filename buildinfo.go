@@ -0,0 +1,46 @@
+package ahoy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// BuildConstants are the strings a build can set for the program to read as
+// build.version, build.commit and build.date
+var BuildConstants = []string{"version", "commit", "date"}
+
+// checkBuildSettings reports a setting that isn't one of BuildConstants
+func checkBuildSettings(settings map[string]string) error {
+	for name := range settings {
+		if !slices.Contains(BuildConstants, name) {
+			return fmt.Errorf("there's no build constant '%s' to set; the build module has %s", name, strings.Join(BuildConstants, ", "))
+		}
+	}
+	return nil
+}
+
+// generateBuildConstant generates build.name, one of the strings set for the
+// build, which is empty when it wasn't set
+func (gen *CodeGenerator) generateBuildConstant(node *ASTNode) {
+	if !slices.Contains(BuildConstants, node.Value) {
+		gen.reportError(node.Line, fmt.Sprintf("the build module has no '%s'", node.Value),
+			"It has "+strings.Join(BuildConstants, ", ")+", set with -set name=value")
+		return
+	}
+	gen.useBuildConstants = true
+	// Ahoy strings are char*; the const only stops the constant being assigned
+	gen.output.WriteString("(char*)ahoy_build_" + node.Value)
+}
+
+// writeBuildConstants generates the strings behind the build module
+func (gen *CodeGenerator) writeBuildConstants() {
+	if !gen.useBuildConstants {
+		return
+	}
+	gen.funcReturnStructs.WriteString("// build module\n")
+	for _, name := range BuildConstants {
+		gen.funcReturnStructs.WriteString(fmt.Sprintf("static const char ahoy_build_%s[] = \"%s\";\n", name, escapeC(gen.buildSettings[name])))
+	}
+	gen.funcReturnStructs.WriteString("\n")
+}
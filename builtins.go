@@ -0,0 +1,132 @@
+package ahoy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Builtin is a function compiled into Ahoy by a Go program that uses the
+// compiler, added with RegisterBuiltin. Ahoy code calls it like any other
+// function, and a function the program declares with the same name is
+// called in its place.
+type Builtin struct {
+	Name        string
+	Description string
+	ReturnType  string   // Ahoy type the call evaluates to, like "int" or "string"; empty when it returns nothing
+	Arguments   int      // how many arguments it takes, -1 for any number
+	Includes    []string // C headers the helpers or the generated call need, like "time.h"
+	Prototypes  string   // C declarations of the helpers, written before the program's functions
+	Helpers     string   // C definitions of the helpers
+	// Generate returns the C expression a call becomes. When it's nil the
+	// call becomes a call to the C function named Name with the same
+	// arguments.
+	Generate func(call *BuiltinCall) string
+}
+
+// BuiltinCall is a call to a registered builtin being generated
+type BuiltinCall struct {
+	Name      string
+	Line      int
+	Arguments []string // the C of each argument
+	Types     []string // the Ahoy type of each argument, as far as it's known
+	gen       *CodeGenerator
+}
+
+// Errorf reports an error at the call; the program then fails to build
+func (call *BuiltinCall) Errorf(format string, args ...interface{}) {
+	call.gen.reportError(call.Line, fmt.Sprintf(format, args...))
+}
+
+var builtins = []*Builtin{}
+
+// RegisterBuiltin adds a builtin to every program built afterwards,
+// replacing a registered one with the same name. A registered builtin takes
+// the place of one of Ahoy's own with that name.
+//
+// Prototypes and Helpers are written once however many calls a program
+// makes, and only when it makes one. Builtins in a set that share helpers
+// can each carry the same text: it's written once for all of them. Name the
+// helpers with a prefix of their own, as the program's functions share the C
+// namespace with them.
+func RegisterBuiltin(builtin Builtin) {
+	for i, registered := range builtins {
+		if registered.Name == builtin.Name {
+			builtins[i] = &builtin
+			return
+		}
+	}
+	builtins = append(builtins, &builtin)
+}
+
+// Builtins returns the registered builtins in the order they were added
+func Builtins() []Builtin {
+	registered := make([]Builtin, len(builtins))
+	for i, builtin := range builtins {
+		registered[i] = *builtin
+	}
+	return registered
+}
+
+// registeredBuiltin returns the registered builtin a call to name reaches,
+// or nil when the program declares name itself or nothing is registered
+func (gen *CodeGenerator) registeredBuiltin(name string) *Builtin {
+	if gen.userFunctions[name] {
+		return nil
+	}
+	for _, builtin := range builtins {
+		if builtin.Name == name {
+			return builtin
+		}
+	}
+	return nil
+}
+
+// generateRegisteredBuiltin generates a call to a registered builtin
+func (gen *CodeGenerator) generateRegisteredBuiltin(builtin *Builtin, node *ASTNode) {
+	if builtin.Arguments >= 0 && len(node.Children) != builtin.Arguments {
+		gen.reportError(node.Line, fmt.Sprintf("%s takes %d argument(s), got %d", builtin.Name, builtin.Arguments, len(node.Children)))
+		return
+	}
+	if gen.builtinsUsed == nil {
+		gen.builtinsUsed = map[string]bool{}
+	}
+	gen.builtinsUsed[builtin.Name] = true
+	for _, header := range builtin.Includes {
+		gen.addInclude(header)
+	}
+
+	call := &BuiltinCall{Name: builtin.Name, Line: node.Line, gen: gen}
+	for _, arg := range node.Children {
+		savedOutput := gen.output
+		gen.output = strings.Builder{}
+		gen.generateNode(arg)
+		call.Arguments = append(call.Arguments, gen.output.String())
+		gen.output = savedOutput
+		call.Types = append(call.Types, gen.inferType(arg))
+	}
+
+	if builtin.Generate == nil {
+		gen.output.WriteString(builtin.Name + "(" + strings.Join(call.Arguments, ", ") + ")")
+		return
+	}
+	gen.output.WriteString(builtin.Generate(call))
+}
+
+// writeRegisteredBuiltinHelpers generates the helpers of the registered
+// builtins the program called, each distinct text once
+func (gen *CodeGenerator) writeRegisteredBuiltinHelpers() {
+	written := map[string]bool{}
+	for _, builtin := range builtins {
+		if !gen.builtinsUsed[builtin.Name] {
+			continue
+		}
+		if prototypes := builtin.Prototypes; prototypes != "" && !written[prototypes] {
+			written[prototypes] = true
+			gen.funcReturnStructs.WriteString(strings.TrimRight(prototypes, "\n") + "\n\n")
+		}
+		if helpers := builtin.Helpers; helpers != "" && !written[helpers] {
+			written[helpers] = true
+			gen.funcDecls.WriteString("\n" + strings.TrimRight(helpers, "\n") + "\n")
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ahoy
 
 import (
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -14,6 +15,7 @@ func ParseCHeader(path string) (*CHeaderInfo, error) {
 		Enums:     make(map[string]*CEnum),
 		Defines:   make(map[string]*CDefine),
 		Structs:   make(map[string]*CStruct),
+		Callbacks: make(map[string]*CFunction),
 	}
 	
 	// Read the header file
@@ -56,13 +58,43 @@ func ParseCHeader(path string) (*CHeaderInfo, error) {
 		
 		// Parse simple typedef aliases (e.g., typedef Texture Texture2D;)
 		if strings.HasPrefix(line, "typedef ") && !strings.Contains(line, "{") && strings.HasSuffix(line, ";") {
-			parseTypedefAlias(line, info)
+			if !parseCallbackTypedef(line, info) {
+				parseTypedefAlias(line, info)
+			}
+		}
+	}
+	
+	// Parameters typed with a callback typedef take its signature
+	for _, function := range info.Functions {
+		for i, param := range function.Parameters {
+			if callback, exists := info.Callbacks[param.Type]; exists && param.Callback == nil {
+				function.Parameters[i].Callback = callback
+			}
 		}
 	}
 	
 	return info, nil
 }
 
+// functionPointer matches a function pointer declarator such as
+// void (*callback)(void *data, int size), with or without the name
+var functionPointer = regexp.MustCompile(`^(.+?)\s*\(\s*\*\s*(\w*)\s*\)\s*\((.*)\)$`)
+
+// parseCallbackTypedef parses a function pointer typedef like:
+// typedef void (*AudioCallback)(void *bufferData, unsigned int frames);
+func parseCallbackTypedef(line string, info *CHeaderInfo) bool {
+	match := functionPointer.FindStringSubmatch(strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "typedef"), ";")))
+	if match == nil || match[2] == "" {
+		return false
+	}
+	info.Callbacks[match[2]] = &CFunction{
+		Name:       match[2],
+		ReturnType: strings.TrimSpace(match[1]),
+		Parameters: parseParameters(match[3]),
+	}
+	return true
+}
+
 // parseRLAPIFunction parses a function declaration like: RLAPI void InitWindow(int width, int height, const char *title);
 func parseRLAPIFunction(line string, lineNum int, info *CHeaderInfo) {
 	// Remove RLAPI/RMAPI prefix and comments
@@ -88,8 +120,8 @@ func parseRLAPIFunction(line string, lineNum int, info *CHeaderInfo) {
 	funcName := parts[len(parts)-1]
 	returnType := strings.Join(parts[:len(parts)-1], " ")
 	
-	// Extract parameters
-	endParen := strings.Index(line, ")")
+	// Extract parameters, which may hold function pointers' own parentheses
+	endParen := matchingParen(line, parenIdx)
 	if endParen == -1 {
 		return
 	}
@@ -227,14 +259,42 @@ func parseParameters(paramStr string) []CParameter {
 		return params
 	}
 	
-	// Split by comma
-	parts := strings.Split(paramStr, ",")
+	// Split by the commas outside function pointer parameter lists
+	var parts []string
+	depth, start := 0, 0
+	for i, ch := range paramStr {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, paramStr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, paramStr[start:])
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
 			continue
 		}
 		
+		if match := functionPointer.FindStringSubmatch(part); match != nil {
+			callback := &CFunction{
+				ReturnType: strings.TrimSpace(match[1]),
+				Parameters: parseParameters(match[3]),
+			}
+			params = append(params, CParameter{
+				Name:     match[2],
+				Type:     callback.ReturnType + " (*)(" + match[3] + ")",
+				Callback: callback,
+			})
+			continue
+		}
+		
 		// Extract parameter name and type
 		tokens := strings.Fields(part)
 		if len(tokens) == 0 {
@@ -247,8 +307,12 @@ func parseParameters(paramStr string) []CParameter {
 			paramName = ""
 		} else {
 			paramName = tokens[len(tokens)-1]
-			paramName = strings.TrimPrefix(paramName, "*")
 			paramType = strings.Join(tokens[:len(tokens)-1], " ")
+			// The pointer belongs to the type: const char *title is a const char*
+			for strings.HasPrefix(paramName, "*") {
+				paramName = strings.TrimPrefix(paramName, "*")
+				paramType += "*"
+			}
 		}
 		
 		params = append(params, CParameter{
@@ -260,6 +324,24 @@ func parseParameters(paramStr string) []CParameter {
 	return params
 }
 
+// matchingParen returns the index of the parenthesis closing the one at
+// open, or -1 when it isn't closed
+func matchingParen(line string, open int) int {
+	depth := 0
+	for i := open; i < len(line); i++ {
+		switch line[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
 // parseDefine parses #define constants
 func parseDefine(line string, lineNum int, info *CHeaderInfo) {
 	if strings.Contains(line, "RAYLIB_VERSION") || strings.Contains(line, "__declspec") {
@@ -341,65 +423,200 @@ func parseStructField(line string, fields *[]CStructField) {
 	}
 }
 
-// parseEnum parses typedef enum definitions
+// parseEnum parses typedef enum definitions, tagged or not, written on one
+// line or across several
 func parseEnum(lines []string, startIdx int, info *CHeaderInfo) {
 	var enumName string
+	var members []cEnumMember
+	member := cEnumMember{}
+	opened, closed, inComment := false, false, false
+	var tail strings.Builder
+
+	for i := startIdx; i < len(lines) && enumName == ""; i++ {
+		line := lines[i]
+		for pos := 0; pos < len(line); pos++ {
+			if inComment {
+				if strings.HasPrefix(line[pos:], "*/") {
+					inComment = false
+					pos++
+				}
+				continue
+			}
+			if strings.HasPrefix(line[pos:], "//") {
+				break
+			}
+			if strings.HasPrefix(line[pos:], "/*") {
+				inComment = true
+				pos++
+				continue
+			}
+			c := line[pos]
+			switch {
+			case !opened:
+				opened = c == '{'
+			case closed:
+				if c == ';' {
+					enumName = strings.TrimSpace(tail.String())
+				} else {
+					tail.WriteByte(c)
+				}
+			case c == ',' || c == '}':
+				if member.text != "" {
+					members = append(members, member)
+				}
+				member = cEnumMember{}
+				closed = c == '}'
+			default:
+				if member.text == "" && (c == ' ' || c == '\t') {
+					continue
+				}
+				if member.text == "" {
+					member.line = i + 1
+				}
+				member.text += string(c)
+			}
+		}
+		if closed && enumName == "" {
+			tail.WriteByte(' ')
+		}
+	}
+	if enumName == "" || strings.ContainsAny(enumName, " *,") {
+		return
+	}
+
+	// Members without an initializer count on from the one before; those
+	// with one are worked out from numbers, C operators and earlier members
 	values := make(map[string]int)
 	valueLines := make(map[string]int)
-	currentValue := 0
-	
-	for i := startIdx + 1; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
-		
-		if strings.HasPrefix(line, "}") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				enumName = strings.TrimSuffix(parts[1], ";")
+	partial := false
+	next, known := int64(0), true
+	lookup := func(name string) (int64, bool) {
+		if value, ok := values[name]; ok {
+			return int64(value), true
+		}
+		for _, other := range info.Enums {
+			if value, ok := other.Values[name]; ok {
+				return int64(value), true
 			}
-			break
 		}
-		
-		if line != "" && !strings.HasPrefix(line, "//") {
-			parseEnumValue(line, &values, &valueLines, &currentValue, i+1)
+		return 0, false
+	}
+	for _, m := range members {
+		name, initializer, hasInitializer := strings.Cut(m.text, "=")
+		name = strings.TrimSpace(name)
+		valueLines[name] = m.line
+		if hasInitializer {
+			known = false
+			if node := parseCConstant(initializer); node != nil {
+				next, known = foldConstInt(node, lookup)
+			}
 		}
-	}
-	
-	if enumName != "" {
-		info.Enums[enumName] = &CEnum{
-			Name:       enumName,
-			Values:     values,
-			ValueLines: valueLines,
-			Line:       startIdx + 1,
+		if !known {
+			partial = true
+			continue
 		}
+		values[name] = int(next)
+		next++
 	}
-}
 
-// parseEnumValue parses an enum value line
-func parseEnumValue(line string, values *map[string]int, valueLines *map[string]int, currentValue *int, lineNum int) {
-	if idx := strings.Index(line, "//"); idx != -1 {
-		line = line[:idx]
+	info.Enums[enumName] = &CEnum{
+		Name:       enumName,
+		Values:     values,
+		ValueLines: valueLines,
+		Partial:    partial,
+		Line:       startIdx + 1,
 	}
-	line = strings.TrimSpace(line)
-	line = strings.TrimSuffix(line, ",")
-	
-	parts := strings.Split(line, "=")
-	name := strings.TrimSpace(parts[0])
-	
-	if name != "" {
-		(*valueLines)[name] = lineNum
-		
-		if len(parts) > 1 {
-			valueStr := strings.TrimSpace(parts[1])
-			// Try parsing as int (supports both decimal and hex with 0x prefix)
-			if val, err := strconv.ParseInt(valueStr, 0, 64); err == nil {
-				(*values)[name] = int(val)
-				*currentValue = int(val) + 1
+}
+
+// cEnumMember is the text of an enum member, NAME or NAME = value, and the
+// line it starts on
+type cEnumMember struct {
+	text string
+	line int
+}
+
+// cConstantToken matches the tokens of a C integer constant expression
+var cConstantToken = regexp.MustCompile(`^\s*(0[xX][0-9a-fA-F]+[uUlL]*|[0-9]+[uUlL]*|[A-Za-z_][A-Za-z0-9_]*|<<|>>|[-+*/%|&^~()])`)
+
+// cOperatorPrecedence ranks C's binary operators from | up to *
+var cOperatorPrecedence = map[string]int{
+	"|": 1, "^": 2, "&": 3, "<<": 4, ">>": 4, "+": 5, "-": 5, "*": 6, "/": 6, "%": 6,
+}
+
+// parseCConstant parses a C integer constant expression, like 1 << 4 or
+// KEY_A + 1, into nodes foldConstInt folds. Returns nil for anything else,
+// such as casts and character literals.
+func parseCConstant(text string) *ASTNode {
+	var tokens []string
+	text = strings.TrimSpace(text)
+	for text != "" {
+		match := cConstantToken.FindStringSubmatch(text)
+		if match == nil {
+			return nil
+		}
+		tokens = append(tokens, match[1])
+		text = strings.TrimSpace(text[len(match[0]):])
+	}
+	pos := 0
+	var operand func() *ASTNode
+	var binary func(minPrecedence int) *ASTNode
+	operand = func() *ASTNode {
+		if pos >= len(tokens) {
+			return nil
+		}
+		token := tokens[pos]
+		pos++
+		switch {
+		case token == "(":
+			inner := binary(1)
+			if inner == nil || pos >= len(tokens) || tokens[pos] != ")" {
+				return nil
 			}
-		} else {
-			(*values)[name] = *currentValue
-			*currentValue++
+			pos++
+			return inner
+		case token == "-" || token == "~" || token == "+":
+			inner := operand()
+			if inner == nil || token == "+" {
+				return inner
+			}
+			return &ASTNode{Type: NODE_UNARY_OP, Value: token, Children: []*ASTNode{inner}}
+		case token[0] >= '0' && token[0] <= '9':
+			value, err := strconv.ParseInt(strings.TrimRight(token, "uUlL"), 0, 64)
+			if err != nil {
+				return nil
+			}
+			return &ASTNode{Type: NODE_NUMBER, Value: strconv.FormatInt(value, 10)}
+		case cOperatorPrecedence[token] == 0 && token != ")":
+			return &ASTNode{Type: NODE_IDENTIFIER, Value: token}
+		}
+		return nil
+	}
+	binary = func(minPrecedence int) *ASTNode {
+		left := operand()
+		for left != nil && pos < len(tokens) {
+			op := tokens[pos]
+			precedence := cOperatorPrecedence[op]
+			if precedence == 0 || precedence < minPrecedence {
+				break
+			}
+			pos++
+			right := binary(precedence + 1)
+			if right == nil {
+				return nil
+			}
+			// C's / on ints is Ahoy's div
+			if op == "/" {
+				op = "div"
+			}
+			left = &ASTNode{Type: NODE_BINARY_OP, Value: op, Children: []*ASTNode{left, right}}
 		}
+		return left
+	}
+	node := binary(1)
+	if pos != len(tokens) {
+		return nil
 	}
+	return node
 }
 
 // Helper functions for case conversion
@@ -448,18 +665,23 @@ func PascalToSnake(s string) string {
 	return string(result)
 }
 
-// parseTypedefAlias parses simple typedef aliases like: typedef Texture Texture2D;
+// parseTypedefAlias parses typedef aliases like: typedef Texture Texture2D;
+// or typedef unsigned int Flags;
 func parseTypedefAlias(line string, info *CHeaderInfo) {
 	// Remove typedef and semicolon
 	line = strings.TrimPrefix(line, "typedef")
 	line = strings.TrimSuffix(line, ";")
 	line = strings.TrimSpace(line)
 	
-	// Split into base type and alias name
+	// Split into base type and alias name; the base may take several words,
+	// like unsigned int or struct Texture
 	parts := strings.Fields(line)
-	if len(parts) == 2 {
-		baseType := parts[0]
-		aliasName := parts[1]
+	if len(parts) >= 2 {
+		baseType := parts[len(parts)-2]
+		aliasName := strings.TrimLeft(parts[len(parts)-1], "*")
+		if aliasName == "" || strings.ContainsAny(aliasName, "[(") {
+			return
+		}
 		
 		// Store as a struct entry so it's treated as a known C type
 		// We don't need the full struct definition, just the name
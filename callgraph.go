@@ -0,0 +1,197 @@
+package ahoy
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CallGraph is which of a program's functions use which, worked out from
+// the program merged with its imports
+type CallGraph struct {
+	Functions []string            // every function, sorted
+	Calls     map[string][]string // the functions each function calls or passes on by name, sorted
+	Roots     []string            // the functions the program starts from, sorted; see BuildCallGraph
+	Reachable map[string]bool     // the roots and everything they use
+}
+
+// LoadCallGraph builds the call graph of the program that source belongs
+// to, with its imports. With library, the roots are the program's exports
+// rather than main.
+func LoadCallGraph(source string, library bool) (*CallGraph, error) {
+	absPath, err := filepath.Abs(source)
+	if err != nil {
+		return nil, fmt.Errorf("resolving file path: %v", err)
+	}
+	pm := NewPackageManager(filepath.Dir(absPath))
+	pm.Log = io.Discard
+	pkg, err := pm.LoadPackageFromFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading package: %v", err)
+	}
+	imports, err := resolveImports(pkg, pm)
+	if err != nil {
+		return nil, fmt.Errorf("resolving imports: %v", err)
+	}
+	return BuildCallGraph(MergeWithImports(pkg, imports), library), nil
+}
+
+// BuildCallGraph builds the call graph of a merged program. The roots are
+// main, the functions the top-level statements and tests use, and the
+// iterator functions loops call by their names; a library's exports take
+// the place of main. A function named anywhere counts as used, so one
+// passed as a callback is kept.
+func BuildCallGraph(ast *ASTNode, library bool) *CallGraph {
+	graph := &CallGraph{Calls: map[string][]string{}, Reachable: map[string]bool{}}
+	functions := map[string]*ASTNode{}
+	for _, child := range ast.Children {
+		if child.Type == NODE_FUNCTION {
+			functions[child.Value] = child
+		}
+	}
+	graph.Functions = slices.Sorted(maps.Keys(functions))
+
+	roots := map[string]bool{}
+	for _, child := range ast.Children {
+		if child.Type != NODE_FUNCTION {
+			for name := range usedFunctions(child, functions) {
+				roots[name] = true
+			}
+		}
+	}
+	for name, fn := range functions {
+		switch {
+		case library && fn.Tag == "export",
+			!library && name == "main",
+			strings.HasSuffix(name, "_iter_begin") || strings.HasSuffix(name, "_iter_next"):
+			roots[name] = true
+		}
+		graph.Calls[name] = slices.Sorted(maps.Keys(usedFunctions(fn, functions)))
+	}
+	graph.Roots = slices.Sorted(maps.Keys(roots))
+
+	var visit func(name string)
+	visit = func(name string) {
+		if graph.Reachable[name] {
+			return
+		}
+		graph.Reachable[name] = true
+		for _, callee := range graph.Calls[name] {
+			visit(callee)
+		}
+	}
+	for _, root := range graph.Roots {
+		visit(root)
+	}
+	return graph
+}
+
+// usedFunctions returns the functions node calls or names, default
+// parameter values included
+func usedFunctions(node *ASTNode, functions map[string]*ASTNode) map[string]bool {
+	used := map[string]bool{}
+	var walk func(node *ASTNode)
+	walk = func(node *ASTNode) {
+		if node == nil {
+			return
+		}
+		switch node.Type {
+		case NODE_CALL, NODE_IDENTIFIER, NODE_METHOD_CALL, NODE_MEMBER_ACCESS:
+			if functions[node.Value] != nil {
+				used[node.Value] = true
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+		walk(node.DefaultValue)
+		walk(node.Capacity)
+	}
+	walk(node)
+	return used
+}
+
+// Unreachable returns the functions nothing the program runs uses, sorted
+func (graph *CallGraph) Unreachable() []string {
+	var names []string
+	for _, name := range graph.Functions {
+		if !graph.Reachable[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// prune removes the functions graph finds unreachable from ast, returning
+// their names
+func (graph *CallGraph) prune(ast *ASTNode) []string {
+	unreachable := graph.Unreachable()
+	ast.Children = slices.DeleteFunc(ast.Children, func(child *ASTNode) bool {
+		return child.Type == NODE_FUNCTION && !graph.Reachable[child.Value]
+	})
+	return unreachable
+}
+
+// WriteTree prints the graph as an indented tree from each root. A function
+// reached a second time isn't expanded again; the unreachable functions are
+// listed at the end.
+func (graph *CallGraph) WriteTree(w io.Writer) {
+	expanded := map[string]bool{}
+	var writeCalls func(name string, prefix string)
+	writeCalls = func(name string, prefix string) {
+		calls := graph.Calls[name]
+		for i, callee := range calls {
+			branch, indent := "├── ", "│   "
+			if i == len(calls)-1 {
+				branch, indent = "└── ", "    "
+			}
+			if expanded[callee] && len(graph.Calls[callee]) > 0 {
+				fmt.Fprintf(w, "%s%s%s (see above)\n", prefix, branch, callee)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, callee)
+			expanded[callee] = true
+			writeCalls(callee, prefix+indent)
+		}
+	}
+	for _, root := range graph.Roots {
+		if expanded[root] && len(graph.Calls[root]) > 0 {
+			fmt.Fprintf(w, "%s (see above)\n", root)
+			continue
+		}
+		fmt.Fprintln(w, root)
+		expanded[root] = true
+		writeCalls(root, "")
+	}
+
+	if unreachable := graph.Unreachable(); len(unreachable) > 0 {
+		fmt.Fprintf(w, "\nUnreachable: %s\n", strings.Join(unreachable, ", "))
+	}
+}
+
+// WriteDOT prints the graph in Graphviz DOT format. Roots are boxes and
+// unreachable functions are gray.
+func (graph *CallGraph) WriteDOT(w io.Writer) {
+	ids := map[string]string{}
+	fmt.Fprintln(w, "digraph calls {")
+	for i, name := range graph.Functions {
+		ids[name] = fmt.Sprintf("f%d", i)
+		var attrs []string
+		if slices.Contains(graph.Roots, name) {
+			attrs = append(attrs, "shape=box")
+		}
+		if !graph.Reachable[name] {
+			attrs = append(attrs, "color=gray", "fontcolor=gray")
+		}
+		fmt.Fprintf(w, "    f%d [%s];\n", i, strings.Join(append([]string{fmt.Sprintf("label=%q", name)}, attrs...), ", "))
+	}
+	for _, name := range graph.Functions {
+		for _, callee := range graph.Calls[name] {
+			fmt.Fprintf(w, "    %s -> %s;\n", ids[name], ids[callee])
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
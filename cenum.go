@@ -0,0 +1,113 @@
+package ahoy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// registerCEnums makes the enums of an imported C header types Ahoy code
+// can declare variables and parameters with. Their members are constants,
+// written bare or qualified like KeyboardKey.KEY_A.
+func (gen *CodeGenerator) registerCEnums(headerInfo *CHeaderInfo) {
+	for name, cEnum := range headerInfo.Enums {
+		gen.cEnums[name] = cEnum
+		gen.cTypeDefinitions[name] = true
+		gen.cTypeDefinitions[strings.ToLower(name)] = true
+		for member := range cEnum.ValueLines {
+			gen.enumMemberTypes[name+"."+member] = name
+		}
+		for member, value := range cEnum.Values {
+			gen.constValues[name+"."+member] = int64(value)
+			if _, exists := gen.constValues[member]; !exists {
+				gen.constValues[member] = int64(value)
+			}
+		}
+	}
+}
+
+// cEnumType returns the C enum an Ahoy or C type names, or "" if it isn't one
+func (gen *CodeGenerator) cEnumType(typeName string) string {
+	typeName = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(typeName), "const "))
+	if gen.cEnums[typeName] != nil {
+		return typeName
+	}
+	for name := range gen.cEnums {
+		if strings.EqualFold(name, typeName) {
+			return name
+		}
+	}
+	return ""
+}
+
+// cEnumsWithMember returns the C enums that have a member by this name
+func (gen *CodeGenerator) cEnumsWithMember(member string) []string {
+	var names []string
+	for name, cEnum := range gen.cEnums {
+		if _, exists := cEnum.ValueLines[member]; exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// valueCEnum returns the C enum an expression's value belongs to: a member,
+// qualified or bare, or a variable typed with the enum. Returns "" for
+// anything else.
+func (gen *CodeGenerator) valueCEnum(expr *ASTNode) string {
+	switch expr.Type {
+	case NODE_MEMBER_ACCESS:
+		if len(expr.Children) > 0 && expr.Children[0].Type == NODE_IDENTIFIER && !gen.isVariable(expr.Children[0].Value) {
+			return gen.cEnumType(expr.Children[0].Value)
+		}
+	case NODE_IDENTIFIER:
+		if varType, exists := gen.functionVars[expr.Value]; exists {
+			return gen.cEnumType(varType)
+		}
+		if varType, exists := gen.variables[expr.Value]; exists {
+			return gen.cEnumType(varType)
+		}
+		if names := gen.cEnumsWithMember(expr.Value); len(names) == 1 {
+			return names[0]
+		}
+	}
+	return ""
+}
+
+// checkCEnumArgument reports an argument given for a parameter typed with a
+// C enum that holds another enum's member or a number that isn't one of the
+// enum's values. Numbers aren't checked against an enum whose values weren't
+// all worked out.
+func (gen *CodeGenerator) checkCEnumArgument(arg *ASTNode, enumName string, funcName string) {
+	if arg.Type == NODE_BINARY_OP && arg.Value == "named_arg" {
+		arg = arg.Children[1]
+	}
+	if arg.Type == NODE_IDENTIFIER && !gen.isVariable(arg.Value) {
+		if _, exists := gen.cEnums[enumName].ValueLines[arg.Value]; exists {
+			return
+		}
+	}
+	if other := gen.valueCEnum(arg); other != "" && other != enumName {
+		gen.reportError(arg.Line, fmt.Sprintf("%s|...| takes a %s, got a %s", funcName, enumName, other))
+		return
+	}
+	if value, ok := gen.evalConstInt(arg, nil); ok && gen.valueCEnum(arg) == "" && !gen.cEnums[enumName].Partial {
+		for _, member := range gen.cEnums[enumName].Values {
+			if int64(member) == value {
+				return
+			}
+		}
+		gen.reportError(arg.Line, fmt.Sprintf("%d is not a value of %s, which %s|...| takes", value, enumName, funcName))
+	}
+}
+
+// generateCEnumMember writes a C enum member accessed through its enum,
+// like KeyboardKey.KEY_A, which in C is just the member
+func (gen *CodeGenerator) generateCEnumMember(enumName string, member string, line int) {
+	if _, exists := gen.cEnums[enumName].ValueLines[member]; !exists {
+		gen.reportError(line, fmt.Sprintf("%s has no member %s", enumName, member))
+		return
+	}
+	gen.output.WriteString(member)
+}
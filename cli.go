@@ -0,0 +1,325 @@
+package ahoy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCliFlags is how many flags a program can declare with the cli module
+const maxCliFlags = 64
+
+// cliFlagTypes are the flag kinds of the cli module, by the Ahoy type each
+// reads as; the C runtime tells them apart by the letter
+var cliFlagTypes = map[string]string{"int": "i", "float": "f", "string": "s", "bool": "b"}
+
+// cliMethodTypes are the types the cli module's methods return
+var cliMethodTypes = map[string]string{
+	"get_int": "int", "get_float": "float", "get_string": "string", "get_bool": "bool",
+	"arg_count": "int", "arg": "string",
+}
+
+// generateCliCall generates a call to the built-in cli module: flags are
+// declared with flag_int|name, default, usage| and friends, read from the
+// command line by parse||, which also answers -h and --help, and read back
+// with get_int|name| and friends. arg_count|| and arg|i| give the arguments
+// that aren't flags.
+func (gen *CodeGenerator) generateCliCall(node *ASTNode) {
+	method := node.Value
+	args := node.Children[1].Children
+	expect := func(count int, usage string) bool {
+		if len(args) != count {
+			gen.reportError(node.Line, fmt.Sprintf("cli.%s takes %s, got %d", method, usage, len(args)))
+			return false
+		}
+		return true
+	}
+
+	switch {
+	case strings.HasPrefix(method, "flag_") && cliFlagTypes[strings.TrimPrefix(method, "flag_")] != "":
+		if !expect(3, "a name, a default value and a usage string") {
+			return
+		}
+		if args[0].Type != NODE_STRING {
+			gen.reportError(node.Line, fmt.Sprintf("cli.%s needs the flag's name as a string literal", method))
+			return
+		}
+		kind := strings.TrimPrefix(method, "flag_")
+		if declared, ok := gen.cliFlags[args[0].Value]; ok {
+			gen.reportError(node.Line, fmt.Sprintf("flag '%s' is already declared as %s", args[0].Value, declared))
+			return
+		}
+		gen.cliFlags[args[0].Value] = kind
+
+	case strings.HasPrefix(method, "get_") && cliMethodTypes[method] != "":
+		if !expect(1, "the flag's name") {
+			return
+		}
+		kind := strings.TrimPrefix(method, "get_")
+		if args[0].Type == NODE_STRING {
+			if declared, ok := gen.cliFlags[args[0].Value]; ok && declared != kind {
+				gen.reportError(node.Line, fmt.Sprintf("flag '%s' is %s, so read it with cli.get_%s", args[0].Value, declared, declared))
+				return
+			}
+		}
+
+	case method == "parse", method == "arg_count":
+		if !expect(0, "no arguments") {
+			return
+		}
+
+	case method == "arg":
+		if !expect(1, "the argument's index") {
+			return
+		}
+
+	default:
+		gen.reportError(node.Line, fmt.Sprintf("the cli module has no '%s'", method),
+			"Declare flags with cli.flag_int, flag_float, flag_string or flag_bool, call cli.parse||, then read them with cli.get_int and friends")
+		return
+	}
+	gen.useCli = true
+
+	gen.output.WriteString(fmt.Sprintf("ahoy_cli_%s(", method))
+	for i, arg := range args {
+		if i > 0 {
+			gen.output.WriteString(", ")
+		}
+		gen.generateNode(arg)
+	}
+	gen.output.WriteString(")")
+}
+
+// writeCliHelperFunctions generates the command-line parser behind the cli
+// module. Flags are written --name value, --name=value or with a single
+// dash; a bool flag on its own is true. Anything else stops the program
+// with the usage and exit status 2.
+func (gen *CodeGenerator) writeCliHelperFunctions() {
+	if !gen.useCli {
+		return
+	}
+	gen.funcReturnStructs.WriteString("void ahoy_cli_init(int argc, char** argv);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_cli_flag_int(const char* name, int value, const char* usage);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_cli_flag_float(const char* name, double value, const char* usage);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_cli_flag_string(const char* name, char* value, const char* usage);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_cli_flag_bool(const char* name, bool value, const char* usage);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_cli_parse(void);\n")
+	gen.funcReturnStructs.WriteString("int ahoy_cli_get_int(const char* name);\n")
+	gen.funcReturnStructs.WriteString("double ahoy_cli_get_float(const char* name);\n")
+	gen.funcReturnStructs.WriteString("char* ahoy_cli_get_string(const char* name);\n")
+	gen.funcReturnStructs.WriteString("bool ahoy_cli_get_bool(const char* name);\n")
+	gen.funcReturnStructs.WriteString("int ahoy_cli_arg_count(void);\n")
+	gen.funcReturnStructs.WriteString("char* ahoy_cli_arg(int index);\n\n")
+
+	gen.funcDecls.WriteString("\n// cli module\n")
+	gen.funcDecls.WriteString(fmt.Sprintf("#define AHOY_MAX_CLI_FLAGS %d\n", maxCliFlags))
+	gen.funcDecls.WriteString(`typedef struct {
+    const char* name;
+    char kind;  // 'i', 'f', 's' or 'b'
+    const char* usage;
+    int int_value;
+    double float_value;
+    char* string_value;
+    bool bool_value;
+} AhoyCliFlag;
+
+static AhoyCliFlag ahoy_cli_flags[AHOY_MAX_CLI_FLAGS];
+static int ahoy_cli_flag_count = 0;
+static int ahoy_cli_argc = 0;
+static char** ahoy_cli_argv = NULL;
+static char** ahoy_cli_args = NULL;
+static int ahoy_cli_args_count = 0;
+
+void ahoy_cli_init(int argc, char** argv) {
+    ahoy_cli_argc = argc;
+    ahoy_cli_argv = argv;
+}
+
+static const char* ahoy_cli_kind_name(char kind) {
+    switch (kind) {
+    case 'i': return "an int";
+    case 'f': return "a float";
+    case 's': return "a string";
+    default: return "a bool";
+    }
+}
+
+static AhoyCliFlag* ahoy_cli_add(const char* name, char kind, const char* usage) {
+    if (ahoy_cli_flag_count == AHOY_MAX_CLI_FLAGS) {
+        fprintf(stderr, "cli: more than %d flags declared\n", AHOY_MAX_CLI_FLAGS);
+        exit(1);
+    }
+    AhoyCliFlag* flag = &ahoy_cli_flags[ahoy_cli_flag_count++];
+    memset(flag, 0, sizeof(*flag));
+    flag->name = name;
+    flag->kind = kind;
+    flag->usage = usage;
+    return flag;
+}
+
+void ahoy_cli_flag_int(const char* name, int value, const char* usage) {
+    ahoy_cli_add(name, 'i', usage)->int_value = value;
+}
+
+void ahoy_cli_flag_float(const char* name, double value, const char* usage) {
+    ahoy_cli_add(name, 'f', usage)->float_value = value;
+}
+
+void ahoy_cli_flag_string(const char* name, char* value, const char* usage) {
+    ahoy_cli_add(name, 's', usage)->string_value = value;
+}
+
+void ahoy_cli_flag_bool(const char* name, bool value, const char* usage) {
+    ahoy_cli_add(name, 'b', usage)->bool_value = value;
+}
+
+static void ahoy_cli_usage(FILE* out) {
+    fprintf(out, "Usage: %s [flags] [args]\n\nFlags:\n", ahoy_cli_argc > 0 ? ahoy_cli_argv[0] : "program");
+    for (int i = 0; i < ahoy_cli_flag_count; i++) {
+        AhoyCliFlag* flag = &ahoy_cli_flags[i];
+        char left[64];
+        if (flag->kind == 'b') {
+            snprintf(left, sizeof(left), "--%s", flag->name);
+        } else {
+            snprintf(left, sizeof(left), "--%s %s", flag->name, strchr(ahoy_cli_kind_name(flag->kind), ' ') + 1);
+        }
+        fprintf(out, "  %-24s %s", left, flag->usage);
+        switch (flag->kind) {
+        case 'i': fprintf(out, " (default %d)", flag->int_value); break;
+        case 'f': fprintf(out, " (default %g)", flag->float_value); break;
+        case 's':
+            if (flag->string_value != NULL && flag->string_value[0] != '\0') {
+                fprintf(out, " (default \"%s\")", flag->string_value);
+            }
+            break;
+        default:
+            if (flag->bool_value) fprintf(out, " (default true)");
+        }
+        fprintf(out, "\n");
+    }
+    fprintf(out, "  %-24s %s\n", "-h, --help", "show this help");
+}
+
+static void ahoy_cli_fail(void) {
+    fprintf(stderr, "\n");
+    ahoy_cli_usage(stderr);
+    exit(2);
+}
+
+void ahoy_cli_parse(void) {
+    ahoy_cli_args = malloc(sizeof(char*) * (ahoy_cli_argc > 0 ? ahoy_cli_argc : 1));
+    ahoy_cli_args_count = 0;
+    for (int i = 1; i < ahoy_cli_argc; i++) {
+        char* arg = ahoy_cli_argv[i];
+        if (strcmp(arg, "--") == 0) {
+            // Everything after -- is an argument, even when it starts with a dash
+            while (++i < ahoy_cli_argc) ahoy_cli_args[ahoy_cli_args_count++] = ahoy_cli_argv[i];
+            break;
+        }
+        if (arg[0] != '-' || arg[1] == '\0') {
+            ahoy_cli_args[ahoy_cli_args_count++] = arg;
+            continue;
+        }
+        char* name = arg[1] == '-' ? arg + 2 : arg + 1;
+        if (strcmp(name, "h") == 0 || strcmp(name, "help") == 0) {
+            ahoy_cli_usage(stdout);
+            exit(0);
+        }
+        char* value = strchr(name, '=');
+        size_t length = value != NULL ? (size_t)(value - name) : strlen(name);
+        if (value != NULL) value++;
+        AhoyCliFlag* flag = NULL;
+        for (int j = 0; j < ahoy_cli_flag_count; j++) {
+            if (strlen(ahoy_cli_flags[j].name) == length && strncmp(ahoy_cli_flags[j].name, name, length) == 0) {
+                flag = &ahoy_cli_flags[j];
+                break;
+            }
+        }
+        if (flag == NULL) {
+            fprintf(stderr, "Unknown flag: %s\n", arg);
+            ahoy_cli_fail();
+        }
+        if (flag->kind == 'b') {
+            if (value == NULL || strcmp(value, "true") == 0 || strcmp(value, "1") == 0) {
+                flag->bool_value = true;
+            } else if (strcmp(value, "false") == 0 || strcmp(value, "0") == 0) {
+                flag->bool_value = false;
+            } else {
+                fprintf(stderr, "Flag --%s takes true or false, got '%s'\n", flag->name, value);
+                ahoy_cli_fail();
+            }
+            continue;
+        }
+        if (value == NULL) {
+            if (i + 1 >= ahoy_cli_argc) {
+                fprintf(stderr, "Flag --%s needs a value\n", flag->name);
+                ahoy_cli_fail();
+            }
+            value = ahoy_cli_argv[++i];
+        }
+        if (flag->kind == 's') {
+            flag->string_value = value;
+            continue;
+        }
+        char* end = value;
+        long int_value = 0;
+        double float_value = 0;
+        if (flag->kind == 'i') {
+            int_value = strtol(value, &end, 10);
+        } else {
+            float_value = strtod(value, &end);
+        }
+        if (end == value || *end != '\0') {
+            fprintf(stderr, "Flag --%s takes %s, got '%s'\n", flag->name, ahoy_cli_kind_name(flag->kind), value);
+            ahoy_cli_fail();
+        }
+        if (flag->kind == 'i') {
+            flag->int_value = (int)int_value;
+        } else {
+            flag->float_value = float_value;
+        }
+    }
+}
+
+static AhoyCliFlag* ahoy_cli_lookup(const char* name, char kind) {
+    for (int i = 0; i < ahoy_cli_flag_count; i++) {
+        AhoyCliFlag* flag = &ahoy_cli_flags[i];
+        if (strcmp(flag->name, name) != 0) continue;
+        if (flag->kind != kind) {
+            fprintf(stderr, "cli: flag '%s' is %s, not %s\n", name, ahoy_cli_kind_name(flag->kind), ahoy_cli_kind_name(kind));
+            exit(1);
+        }
+        return flag;
+    }
+    fprintf(stderr, "cli: no flag named '%s' was declared\n", name);
+    exit(1);
+}
+
+int ahoy_cli_get_int(const char* name) {
+    return ahoy_cli_lookup(name, 'i')->int_value;
+}
+
+double ahoy_cli_get_float(const char* name) {
+    return ahoy_cli_lookup(name, 'f')->float_value;
+}
+
+char* ahoy_cli_get_string(const char* name) {
+    return ahoy_cli_lookup(name, 's')->string_value;
+}
+
+bool ahoy_cli_get_bool(const char* name) {
+    return ahoy_cli_lookup(name, 'b')->bool_value;
+}
+
+int ahoy_cli_arg_count(void) {
+    return ahoy_cli_args_count;
+}
+
+char* ahoy_cli_arg(int index) {
+    if (index < 0 || index >= ahoy_cli_args_count) {
+        fprintf(stderr, "cli: argument %d is out of range, %d were given\n", index, ahoy_cli_args_count);
+        exit(1);
+    }
+    return ahoy_cli_args[index];
+}
+`)
+}
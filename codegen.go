@@ -0,0 +1,11762 @@
+package ahoy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func snakeToPascal(s string) string {
+	// If there are no underscores, return as-is (it's already in the correct format)
+	if !strings.Contains(s, "_") {
+		return s
+	}
+
+	// Common acronyms that should be uppercase
+	acronyms := map[string]string{
+		"fps":   "FPS",
+		"api":   "API",
+		"url":   "URL",
+		"http":  "HTTP",
+		"https": "HTTPS",
+		"rgb":   "RGB",
+		"rgba":  "RGBA",
+		"gpu":   "GPU",
+		"cpu":   "CPU",
+		"ui":    "UI",
+		"id":    "ID",
+		"uuid":  "UUID",
+	}
+
+	parts := strings.Split(s, "_")
+	for i, part := range parts {
+		if len(part) > 0 {
+			// Check if this part is a known acronym
+			if acronymUpper, ok := acronyms[strings.ToLower(part)]; ok {
+				parts[i] = acronymUpper
+			} else {
+				// Normal word - capitalize first letter
+				parts[i] = strings.ToUpper(string(part[0])) + part[1:]
+			}
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+type StructFieldInfo struct {
+	Name         string
+	Type         string
+	DefaultValue string  // C code for default value (if any)
+	JSON         JSONTag // Key and options for json_encode/json_decode
+}
+
+type StructInfo struct {
+	Name   string
+	Fields []StructFieldInfo
+}
+
+type CodeGenerator struct {
+	output                        strings.Builder
+	indent                        int
+	varCounter                    int
+	dictCounter                   int             // Counter for inline dict/array literals
+	funcReturnStructs             strings.Builder // Struct definitions for multi-return functions
+	funcForwardDecls              strings.Builder // Forward declarations for user functions
+	funcDecls                     strings.Builder
+	structDecls                   strings.Builder
+	globalDecls                   strings.Builder // File-scope declarations of top-level enums, constants and variables
+	constInits                    strings.Builder // Top-level constants C can't initialize at file scope, set by ahoy_init_constants
+	includes                      map[string]bool
+	orderedIncludes               []string                     // Keep track of include order
+	variables                     map[string]string            // variable name -> type (global scope)
+	functionVars                  map[string]string            // variable name -> type (function scope)
+	nestedScopeVars               map[string]bool              // variables declared in nested scopes (loops/ifs)
+	constants                     map[string]bool              // constant name -> declared
+	constValues                   map[string]int64             // const or "enumName.memberName" -> folded int value
+	enums                         map[string]map[string]bool   // enum name -> {member names}
+	enumMemberTypes               map[string]string            // "enumName.memberName" -> type
+	constEnumMembers              map[string]bool              // "enumName.memberName" of immutable string members, declared const char*
+	enumVars                      map[string]string            // variable name -> enum of the member it was declared with
+	enumTypes                     map[string]string            // enum name -> enum type (int, string, etc.)
+	userFunctions                 map[string]bool              // user-defined function names (keep snake_case)
+	hasError                      bool                         // Track if error occurred
+	arrayImpls                    bool                         // Track if we've added array implementation
+	arrayMethods                  map[string]bool              // Track which array methods are used
+	stringMethods                 map[string]bool              // Track which string methods are used
+	dictMethods                   map[string]bool              // Track which dict methods are used
+	useJSON                       bool                         // Track if JSON functions are used
+	useBytes                      bool                         // Track if byte buffers are used
+	useNumberParsing              bool                         // Track if parse_int/parse_float are used
+	useRange                      bool                         // Track if range values are used
+	useExitHooks                  bool                         // Track if on_exit is used
+	useCli                        bool                         // Track if the cli module is used
+	useFrame                      bool                         // Track if the frame module is used
+	useMem                        bool                         // Track if the mem module is used
+	useBuildConstants             bool                         // Track if the build module is used
+	buildSettings                 map[string]string            // values of the build module's constants
+	stringTable                   *stringTable                 // text tr|key| looks up, nil when the program has none
+	useTr                         bool                         // Track if tr is used
+	useFormat                     bool                         // Track if format or sprintf is used
+	builtinsUsed                  map[string]bool              // Registered builtins the program calls
+	useThousands                  bool                         // Track if a {:,} placeholder is used
+	cliFlags                      map[string]string            // Type of each flag declared with the cli module
+	useClone                      bool                         // Track if .clone|| is used
+	jsonCodecStructs              map[string]bool              // Structs that need json_encode/json_decode helpers
+	slotStructIDs                 map[string]int               // Struct id of each struct stored in an array or dict slot
+	slotStructs                   []string                     // Slot structs in struct id order
+	useAssert                     bool                         // Track if assert is used
+	usePanic                      bool                         // panic or recover is used, so the recover stack is written
+	recoverExits                  map[*ASTNode]string          // return, halt or next -> the outermost recover block it leaves
+	useLogLevels                  bool                         // Track if log.debug|...| and friends are used
+	useGame                       bool                         // Track if game.run|...| is used
+	typeHelpersUsed               map[string]bool              // vector2 and color helpers that are called
+	builtinStructsUsed            map[string]bool              // built-in struct types that need a typedef
+	release                       bool                         // Release build: debug logging is stripped
+	softAssert                    bool                         // Failed asserts report and carry on instead of aborting
+	test                          bool                         // ahoy test: main runs the test blocks instead of the program
+	safe                          bool                         // Loops check their array or dict isn't modified while they run
+	growArrays                    bool                         // Writing past the end of an array grows it instead of stopping the program
+	library                       string                       // Name of the library being built, whose init function replaces main; "" for a program
+	exports                       []*exportedFunction          // A library's @export functions
+	strictCalls                   bool                         // Calls to unknown functions are errors instead of guessed C names
+	deterministic                 bool                         // Random numbers, clocks and input go through the -deterministic helpers
+	deterministicUsed             []string                     // C functions the program calls that -deterministic replaces
+	optimize                      bool                         // Enum switches of constants become table lookups and small functions are inlined
+	coverProfile                  string                       // Where -cover programs write their line hits, "" without -cover
+	nodeFiles                     map[*ASTNode]string          // Source file of each top-level node, for coverage
+	currentFile                   string                       // Source file of the top-level node being generated
+	coverLines                    []coverLine                  // Lines with a hit counter, in counter order
+	coverIndex                    map[coverLine]int            // coverLines positions
+	sourceLines                   map[string][]string          // Lines of each source file when statements are commented with them
+	useIterationCheck             bool                         // Track if a loop checks for modification
+	useSwitchHash                 bool                         // Track if a string switch dispatches on a hash
+	dumpStructs                   map[string]*StructInfo       // C struct name -> fields, for dump_struct helpers
+	dumpStructOrder               []string                     // dumpStructs keys in the order they were used
+	enumConversions               []string                     // enums converted from ints at runtime, in the order used
+	externs                       map[string]*ASTNode          // extern declarations by Ahoy name
+	externNameDefined             bool                         // AHOY_EXTERN_NAME has been written
+	callbacks                     []callbackWrapper            // Ahoy functions passed to C as function pointers
+	tests                         []inlineTest                 // test blocks, in source order, when testing
+	clonedStructs                 map[string]bool              // Struct types that need a clone helper
+	jsonVariables                 map[string]bool              // Track which variables hold JSON data
+	jsonStructs                   map[string]bool              // Track which structs are JSON schemas (not real C structs)
+	loopCounters                  []string                     // Stack of loop counter variable names
+	lambdaParams                  map[string]string            // lambda parameter -> type while a dict lambda is generated
+	currentFunction               string                       // Current function being generated
+	currentFunctionReturnType     string                       // Return type of current function
+	currentFunctionHasMultiReturn bool                         // Whether current function has multiple returns
+	outParams                     map[string]bool              // Multi-return functions that write their results through a pointer
+	callOut                       string                       // Struct the call being generated writes its results to
+	tailCalls                     map[*ASTNode][]*ASTNode      // Calls the current function makes to itself that jump back to its start -> their arguments
+	unusedVars                    map[string]bool              // Variables the current function declares and never uses, marked with (void)
+	recoverVars                   map[string]bool              // Variables the current function assigns in recover blocks, declared volatile
+	hasMainFunc                   bool                         // Whether there's an Ahoy main function
+	arrayElementTypes             map[string]string            // array variable name -> element type
+	dictValueTypes                map[string]string            // untyped dict variable name -> the type its literal's values share
+	structs                       map[string]*StructInfo       // struct name -> struct info
+	currentTypeContext            string                       // Current type annotation context (e.g., "array[int]")
+	functionReturnTypes           map[string][]string          // function name -> return types (for inferred functions)
+	deferredStatements            []string                     // Stack of deferred statements for current function
+	functionParamTypes            map[string][]string          // function name -> parameter types
+	functionParamNames            map[string][]string          // function name -> parameter names
+	functionParamDefaults         map[string][]*ASTNode        // function name -> parameter default values
+	dictSourcedVars               map[string]string            // variable name -> dict name (for dict-accessed vars)
+	dictSourcedKeys               map[string]string            // variable name -> key (for dict-accessed vars)
+	cFunctionNames                map[string]string            // snake_case name -> actual C name
+	cNamespaces                   map[string]map[string]string // namespace -> (snake_case name -> actual C name)
+	cFunctionReturnTypes          map[string]string            // C function name (snake_case) -> return type
+	cFunctionParams               map[string][]CParameter      // C function name (snake_case) -> parameters
+	cNamespaceReturnTypes         map[string]map[string]string // namespace -> (snake_case name -> return type)
+	headers                       *PackageManager              // Parses the headers of imports the package manager didn't bind
+	cTypeDefinitions              map[string]bool              // Track known C types from headers
+	cEnums                        map[string]*CEnum            // C enum name -> its members, from imported headers
+	declaredGlobalVars            map[string]bool              // Track global variables that have been declared in C code
+	hoistedGlobals                map[string]bool              // Top-level variables already declared at file scope
+	declaredFunctionVars          map[string]bool              // Track function-local variables that have been declared in C code
+	enableBoundsChecking          bool                         // Enable runtime array bounds checking
+	enableSignalHandler           bool                         // Enable signal handler for crash reporting
+	skipBoundsCheck               bool                         // Temporarily skip bounds check (for lvalue contexts)
+	sourceFilename                string                       // Source filename for error messages
+	log                           io.Writer                    // Where error messages are printed
+	diagnostics                   []Diagnostic                 // Errors reported during generation
+}
+
+// GenerateC generates C code from an AST (exported for testing)
+func GenerateC(ast *ASTNode) string {
+	return generateC(ast, "<source>")
+}
+
+// GenerateCWithFilename generates C code from an AST with a source filename
+func GenerateCWithFilename(ast *ASTNode, filename string) string {
+	return generateC(ast, filename)
+}
+
+func generateC(ast *ASTNode, filename string) string {
+	program, _ := generateCode(ast, filename, codegenOptions{}, os.Stdout)
+	return program.code
+}
+
+// codegenOptions are the BuildOptions that change the generated code
+type codegenOptions struct {
+	softAssert    bool
+	release       bool
+	test          bool
+	safe          bool
+	growArrays    bool
+	library       string // build a library of this name: no main, and a header and bindings of its @export functions
+	strictCalls   bool
+	optimize      bool
+	deterministic bool                // seeded random numbers, a virtual clock and recorded input, for replays
+	cover         string              // coverage profile the program writes when it exits; "" leaves coverage out
+	files         map[*ASTNode]string // source file of each top-level node
+	readable      bool                // comment statements with their Ahoy source and split long lines
+	width         int                 // with readable, the longest line left unsplit; 0 means DefaultCLineWidth
+	sources       map[string][]string // lines of each source file, for readable's comments
+	splitRuntime  bool                // write the runtime helpers to their own header and source
+	minimal       bool                // include only the headers and runtime types the program uses, without the crash handler
+	build         map[string]string   // values of the build module's constants, see BuildConstants
+	strings       *stringTable        // the program's string tables, for tr
+}
+
+// generateCode returns the C code for ast, or no code with the errors that
+// stopped it. Error messages are also printed to log.
+func generateCode(ast *ASTNode, filename string, opts codegenOptions, log io.Writer) (cProgram, []Diagnostic) {
+	gen := &CodeGenerator{
+		includes:              make(map[string]bool),
+		orderedIncludes:       make([]string, 0),
+		cliFlags:              make(map[string]string),
+		variables:             make(map[string]string),
+		constants:             make(map[string]bool),
+		constValues:           make(map[string]int64),
+		enums:                 make(map[string]map[string]bool),
+		enumMemberTypes:       make(map[string]string),
+		constEnumMembers:      make(map[string]bool),
+		enumVars:              make(map[string]string),
+		enumTypes:             make(map[string]string),
+		userFunctions:         make(map[string]bool),
+		hasError:              false,
+		arrayImpls:            false,
+		arrayMethods:          make(map[string]bool),
+		stringMethods:         make(map[string]bool),
+		dictMethods:           make(map[string]bool),
+		hasMainFunc:           false,
+		arrayElementTypes:     make(map[string]string),
+		dictValueTypes:        make(map[string]string),
+		structs:               make(map[string]*StructInfo),
+		functionReturnTypes:   make(map[string][]string),
+		outParams:             make(map[string]bool),
+		functionParamTypes:    make(map[string][]string),
+		functionParamNames:    make(map[string][]string),
+		functionParamDefaults: make(map[string][]*ASTNode),
+		dictSourcedVars:       make(map[string]string),
+		dictSourcedKeys:       make(map[string]string),
+		nestedScopeVars:       make(map[string]bool),
+		cFunctionNames:        make(map[string]string),
+		cNamespaces:           make(map[string]map[string]string),
+		typeHelpersUsed:       make(map[string]bool),
+		builtinStructsUsed:    make(map[string]bool),
+		cFunctionReturnTypes:  make(map[string]string),
+		cFunctionParams:       make(map[string][]CParameter),
+		cNamespaceReturnTypes: make(map[string]map[string]string),
+		headers:               NewPackageManager("."),
+		recoverExits:          make(map[*ASTNode]string),
+		cTypeDefinitions:      make(map[string]bool),
+		cEnums:                make(map[string]*CEnum),
+		declaredGlobalVars:    make(map[string]bool),
+		hoistedGlobals:        make(map[string]bool),
+		declaredFunctionVars:  make(map[string]bool),
+		jsonVariables:         make(map[string]bool),
+		jsonStructs:           make(map[string]bool),
+		clonedStructs:         make(map[string]bool),
+		jsonCodecStructs:      make(map[string]bool),
+		slotStructIDs:         make(map[string]int),
+		dumpStructs:           make(map[string]*StructInfo),
+		externs:               make(map[string]*ASTNode),
+		enableBoundsChecking:  true,          // Re-enabled with lvalue context handling
+		enableSignalHandler:   !opts.minimal, // Crash reports, unless the runtime is kept minimal
+		skipBoundsCheck:       false,
+		sourceFilename:        filename, // Source file for error messages
+		softAssert:            opts.softAssert,
+		release:               opts.release,
+		test:                  opts.test,
+		safe:                  opts.safe,
+		growArrays:            opts.growArrays,
+		library:               opts.library,
+		strictCalls:           opts.strictCalls,
+		deterministic:         opts.deterministic,
+		optimize:              opts.optimize,
+		coverProfile:          opts.cover,
+		nodeFiles:             opts.files,
+		currentFile:           filename,
+		coverIndex:            make(map[coverLine]int),
+		sourceLines:           opts.sources,
+		buildSettings:         opts.build,
+		stringTable:           opts.strings,
+		log:                   log,
+	}
+
+	// Add standard includes
+	gen.includes["stdio.h"] = true
+	gen.orderedIncludes = append(gen.orderedIncludes, "stdio.h")
+	gen.includes["stdlib.h"] = true
+	gen.orderedIncludes = append(gen.orderedIncludes, "stdlib.h")
+	gen.includes["string.h"] = true
+	gen.orderedIncludes = append(gen.orderedIncludes, "string.h")
+	gen.includes["stdbool.h"] = true
+	gen.orderedIncludes = append(gen.orderedIncludes, "stdbool.h")
+	gen.includes["stdint.h"] = true
+	gen.orderedIncludes = append(gen.orderedIncludes, "stdint.h")
+
+	// Generate hash map implementation
+	gen.writeRuntime(gen.writeHashMapImplementation)
+
+	// Test blocks become functions under ahoy test and are left out otherwise
+	gen.tests = prepareInlineTests(ast, gen.test)
+
+	// Comptime calls become the literals they evaluate to before any pass
+	// looks at their types
+	gen.evaluateComptime(ast)
+
+	// First pass: scan imports to populate C type definitions BEFORE code generation
+	gen.scanImports(ast)
+
+	// Second pass: check if there's a main function and collect function signatures
+	gen.checkForMainFunction(ast)
+
+	// Third pass: scan variable declarations to populate type information
+	gen.scanVariableTypes(ast)
+
+	// Fourth pass: infer parameter types from function call sites
+	gen.inferParameterTypesFromCalls(ast)
+
+	// Fifth pass: infer return types for all functions with infer keyword
+	gen.inferAllFunctionReturnTypes(ast)
+
+	// Sixth pass: scan for method calls to determine which helper functions we need
+	gen.scanForMethodCalls(ast)
+
+	// Multi-return functions with big results take a pointer to write them to
+	gen.scanOutParams(ast)
+
+	// Seventh pass: with -O, substitute small functions at their call sites
+	if gen.optimize {
+		gen.inlineFunctions(ast)
+	}
+
+	// Generate main code
+	gen.generateNode(ast)
+
+	// Wrap Ahoy functions passed as C callbacks, now every signature is known
+	gen.writeCallbackWrappers()
+
+	if gen.library != "" {
+		gen.collectExports(ast)
+	}
+
+	// Check if there were any errors
+	if gen.hasError {
+		return cProgram{}, gen.diagnostics // Return no code to indicate error
+	}
+
+	// Generate boxed value helpers shared by container print/format helpers
+	gen.writeValueImplementation()
+
+	// Generate type helper function if needed
+	gen.writeTypeEnumToStringHelper()
+
+	// Generate built-in type helpers (color, vector2)
+	gen.writeBuiltinTypeHelpers()
+
+	// Generate array helper functions if any array methods were used
+	gen.writeRuntime(gen.writeArrayHelperFunctions)
+
+	// Generate dict helper functions if any dict methods were used
+	gen.writeRuntime(gen.writeDictHelperFunctions)
+
+	// Generate string helper functions if any string methods were used
+	gen.writeRuntime(gen.writeStringHelperFunctions)
+
+	// Generate struct print helper functions
+	gen.writeStructHelperFunctions()
+
+	// Generate vector2 and color constructors
+	gen.writeTypeConstructors()
+
+	// Generate JSON helper functions if JSON is used
+	gen.writeRuntime(gen.writeJSONHelperFunctions)
+
+	// Generate byte buffer helper functions if bytes are used
+	gen.writeRuntime(gen.writeBytesHelperFunctions)
+
+	// Generate number parsing helpers if parse_int/parse_float are used
+	gen.writeRuntime(gen.writeNumberParsingHelperFunctions)
+
+	// Generate the string formatter if format|...| or sprintf|...| is used
+	gen.writeRuntime(gen.writeFormatHelperFunctions)
+
+	// Generate thousands grouping if a {:,} placeholder is used
+	gen.writeRuntime(gen.writeThousandsHelperFunctions)
+
+	// Generate range helpers if range|...| is used
+	gen.writeRuntime(gen.writeRangeHelperFunctions)
+
+	// Generate the on_exit hooks and their exit and signal handlers
+	gen.writeRuntime(gen.writeExitHookFunctions)
+
+	// Generate the command-line parser if the cli module is used
+	gen.writeRuntime(gen.writeCliHelperFunctions)
+
+	// Generate the seeded random numbers, virtual clock and input recording
+	// of a -deterministic build, which the frame module then runs on
+	gen.writeRuntime(gen.writeDeterministicFunctions)
+
+	// Generate the frame limiter and delta time if the frame module is used
+	gen.writeRuntime(gen.writeFrameHelperFunctions)
+
+	// Generate the strings set for the build if the build module is used
+	gen.writeBuildConstants()
+
+	// Generate the helpers of the registered builtins the program calls
+	gen.writeRuntime(gen.writeRegisteredBuiltinHelpers)
+
+	// Generate the string table and its lookup if tr is used
+	gen.writeTrHelperFunctions()
+
+	// Generate deep copy helpers if .clone|| is used
+	gen.writeCloneHelperFunctions()
+
+	// Generate struct encoders and decoders if json_encode/json_decode are used
+	gen.writeStructJSONHelperFunctions()
+
+	// Generate assert failure reporting if assert is used
+	gen.writeAssertHelperFunctions()
+
+	// Generate level filtering and prefixes if leveled logging is used
+	gen.writeRuntime(gen.writeLogHelperFunctions)
+
+	// Generate the raylib window loop if game.run is used
+	gen.writeGameHelperFunctions()
+
+	// Generate the test runner under ahoy test
+	gen.writeTestHelperFunctions()
+	gen.writePanicHelperFunctions()
+
+	// Generate the check -safe loops make before each next element
+	gen.writeRuntime(gen.writeIterationCheckFunction)
+
+	// Generate the string hash large string switches dispatch on
+	gen.writeRuntime(gen.writeSwitchHashFunction)
+	gen.writeDumpStructFunctions()
+	gen.writeEnumConversionFunctions()
+	gen.writeCoverageFunctions()
+
+	// Build final output. The runtime's includes, types and helpers are
+	// fenced so they can be pruned and split out.
+	var result strings.Builder
+	result.WriteString(runtimeBegin)
+
+	// Write includes
+	for _, include := range gen.orderedIncludes {
+		// Use angle brackets for system includes, quotes for local .h files
+		if strings.HasSuffix(include, ".h") && (strings.HasPrefix(include, "/") || strings.HasPrefix(include, ".")) {
+			result.WriteString(fmt.Sprintf("#include \"%s\"\n", include))
+		} else {
+			result.WriteString(fmt.Sprintf("#include <%s>\n", include))
+		}
+	}
+	result.WriteString("\n")
+
+	// Write the counting allocator if the mem module is used, ahead of
+	// everything that allocates
+	result.WriteString(gen.getMemAllocator())
+
+	// Write signal handler if enabled
+	if gen.enableSignalHandler {
+		result.WriteString(gen.getSignalHandler())
+		result.WriteString("\n")
+	}
+
+	// Write array implementation if needed
+	if gen.arrayImpls {
+		result.WriteString(gen.getArrayImplementation())
+		result.WriteString("\n")
+	}
+
+	// Write hash map declarations
+	result.WriteString(gen.getHashMapDeclarations())
+	result.WriteString("\n")
+
+	// Write AhoyValueType enum (needed by both HashMap and AhoyArray)
+	result.WriteString("\n// Value type tracking\n")
+	result.WriteString("typedef enum {\n")
+	result.WriteString("    AHOY_TYPE_INT,\n")
+	result.WriteString("    AHOY_TYPE_STRING,\n")
+	result.WriteString("    AHOY_TYPE_FLOAT,\n")
+	result.WriteString("    AHOY_TYPE_CHAR,\n")
+	result.WriteString("    AHOY_TYPE_BOOL,\n")
+	result.WriteString("    AHOY_TYPE_ARRAY,\n")
+	result.WriteString("    AHOY_TYPE_DICT,\n")
+	result.WriteString("    AHOY_TYPE_JSON,\n")
+	result.WriteString("    AHOY_TYPE_NULL,\n")
+	result.WriteString("    AHOY_TYPE_STRUCT // struct slots are tagged AHOY_TYPE_STRUCT + struct id\n")
+	result.WriteString("} AhoyValueType;\n\n")
+
+	// Write AhoyValue (the decoded form of a container slot)
+	result.WriteString(gen.getValueDeclarations())
+
+	// Write AhoyArray struct definition if arrays are used (must come after AhoyValueType)
+	if gen.usesAhoyArrays() {
+		result.WriteString("// Array Helper Structure\n")
+		result.WriteString("typedef struct {\n")
+		result.WriteString("    intptr_t* data;\n")
+		result.WriteString("    AhoyValueType* types;  // Type for each element\n")
+		result.WriteString("    int length;\n")
+		result.WriteString("    int capacity;\n")
+		result.WriteString("    int is_typed;  // 0 = mixed types allowed, 1 = single type enforced\n")
+		result.WriteString("    AhoyValueType element_type;  // If is_typed=1, this is the enforced type\n")
+		result.WriteString("    unsigned int mods;  // Bumped by push, pop and fill; -safe loops check it\n")
+		result.WriteString("} AhoyArray;\n\n")
+
+		// Add forward declarations for array helper functions
+		if gen.arrayMethods["push"] {
+			result.WriteString("AhoyArray* ahoy_array_push(AhoyArray* arr, intptr_t value, AhoyValueType type);\n")
+		}
+		if gen.arrayMethods["pop"] {
+			result.WriteString("intptr_t ahoy_array_pop(AhoyArray* arr);\n")
+		}
+		if gen.arrayMethods["length"] {
+			result.WriteString("int ahoy_array_length(AhoyArray* arr);\n")
+		}
+		if gen.arrayMethods["fill"] {
+			result.WriteString("AhoyArray* ahoy_array_fill(AhoyArray* arr, intptr_t value, AhoyValueType type, int count);\n")
+		}
+		if gen.arrayMethods["set"] {
+			result.WriteString("void ahoy_array_set(AhoyArray* arr, int index, intptr_t value, AhoyValueType type, const char* file, int line, const char* name);\n")
+		}
+		result.WriteString("char* print_array_helper(AhoyArray* arr);\n")
+		result.WriteString("AhoyValue ahoy_array_get_value(AhoyArray* arr, int index);\n")
+		result.WriteString("\n")
+	}
+
+	// Add forward declarations for dict helper functions if needed
+	if gen.dictMethods["print_dict"] || gen.dictMethods["items_sorted"] || gen.dictMethods["sorted_entries"] {
+		result.WriteString("HashMapEntry** ahoy_dict_sorted_entries(HashMap* dict);\n")
+	}
+	if gen.dictMethods["print_dict"] {
+		result.WriteString("char* print_dict_helper(HashMap* dict);\n")
+		result.WriteString("char* format_hashmap_value(HashMap* dict, const char* key);\n")
+	}
+	result.WriteString(runtimeEnd)
+
+	// Write struct declarations (typedefs)
+	result.WriteString(gen.structDecls.String())
+	result.WriteString("\n")
+
+	// Write function return struct definitions (for multi-return functions)
+	if gen.funcReturnStructs.Len() > 0 {
+		result.WriteString("// Return type structs for multi-return functions\n")
+		result.WriteString(gen.funcReturnStructs.String())
+		result.WriteString("\n")
+	}
+
+	// Write function forward declarations
+	if gen.funcForwardDecls.Len() > 0 {
+		result.WriteString("// User function forward declarations\n")
+		result.WriteString(gen.funcForwardDecls.String())
+		result.WriteString("\n")
+	}
+
+	// Write top-level enums, constants and variables ahead of every function
+	if gen.globalDecls.Len() > 0 {
+		result.WriteString("// Top-level declarations\n")
+		result.WriteString(gen.globalDecls.String())
+		result.WriteString("\n")
+	}
+
+	// Write function implementations
+	result.WriteString(gen.funcDecls.String())
+	result.WriteString("\n")
+
+	if gen.constInits.Len() > 0 {
+		result.WriteString("// Top-level constants set before the program runs\n")
+		result.WriteString("static void ahoy_init_constants(void) {\n")
+		result.WriteString(gen.constInits.String())
+		result.WriteString("}\n\n")
+	}
+
+	// Write main program: top-level statements run in order, then the Ahoy
+	// main function if there is one. A library runs its top-level
+	// statements from its init function instead.
+	if gen.library != "" {
+		result.WriteString(fmt.Sprintf("%svoid %s_init(void) {\n", exportVisibility, gen.library))
+		if gen.constInits.Len() > 0 {
+			result.WriteString("    ahoy_init_constants();\n")
+		}
+		result.WriteString(gen.output.String())
+		result.WriteString("}\n")
+	} else {
+		if gen.useCli {
+			result.WriteString("int main(int argc, char** argv) {\n")
+			result.WriteString("    ahoy_cli_init(argc, argv);\n")
+		} else {
+			result.WriteString("int main(void) {\n")
+		}
+		// stdout is line-buffered even into a pipe or file, so prints come out
+		// in order with raylib's log and with what print_err and runtime errors
+		// write to stderr
+		result.WriteString("    setvbuf(stdout, NULL, _IOLBF, BUFSIZ);\n")
+		if gen.enableSignalHandler {
+			result.WriteString("    ahoy_setup_signal_handlers();\n")
+		}
+		if gen.constInits.Len() > 0 {
+			result.WriteString("    ahoy_init_constants();\n")
+		}
+		if len(gen.coverLines) > 0 {
+			result.WriteString("    atexit(ahoy_cover_write);\n")
+		}
+		result.WriteString(gen.output.String())
+		if gen.test {
+			result.WriteString("    int failed = 0;\n")
+			for _, test := range gen.tests {
+				result.WriteString(fmt.Sprintf("    failed += ahoy_run_test(\"%s\", %s);\n", test.name, test.function))
+			}
+			result.WriteString(fmt.Sprintf("    printf(\"\\n%%d passed, %%d failed\\n\", %d - failed, failed);\n", len(gen.tests)))
+			result.WriteString("    return failed > 0;\n")
+		} else {
+			if gen.hasMainFunc {
+				result.WriteString("    ahoy_main();\n")
+			}
+			result.WriteString("    return 0;\n")
+		}
+		result.WriteString("}\n")
+	}
+
+	code := result.String()
+	if opts.minimal {
+		code = minimizeRuntime(code)
+	}
+	program := splitRuntime(code, opts.splitRuntime)
+	if opts.readable {
+		program.code = readableC(program.code, opts.width)
+		program.header = readableC(program.header, opts.width)
+		program.runtime = readableC(program.runtime, opts.width)
+	}
+	if gen.library != "" {
+		program.libraryHeader = gen.libraryHeader()
+		program.goBindings = gen.goBindings()
+		program.pythonBindings = gen.pythonBindings()
+	}
+	return program, gen.diagnostics
+}
+
+// reportError prints a code generation error, with optional detail lines,
+// and records it as a diagnostic
+func (gen *CodeGenerator) reportError(line int, message string, details ...string) {
+	fmt.Fprintf(gen.log, "\n❌ Error at line %d: %s\n", line, message)
+	for _, detail := range details {
+		fmt.Fprintf(gen.log, "   %s\n", detail)
+	}
+	fmt.Fprintln(gen.log)
+	gen.diagnostics = append(gen.diagnostics, Diagnostic{
+		File:     gen.sourceFilename,
+		Line:     line,
+		Severity: SeverityError,
+		Message:  message,
+	})
+	gen.hasError = true
+}
+
+// reportWarning prints a code generation warning and records it as a
+// diagnostic; the program is still generated
+func (gen *CodeGenerator) reportWarning(line int, message string) {
+	fmt.Fprintf(gen.log, "\n⚠️ Warning at line %d: %s\n\n", line, message)
+	gen.diagnostics = append(gen.diagnostics, Diagnostic{
+		File:     gen.sourceFilename,
+		Line:     line,
+		Severity: SeverityWarning,
+		Message:  message,
+	})
+}
+
+func (gen *CodeGenerator) getArrayImplementation() string {
+	return `
+// Dynamic Array Implementation
+typedef struct {
+    void** data;
+    int size;
+    int capacity;
+} DynamicArray;
+
+DynamicArray* createArray(int initialCapacity) {
+    DynamicArray* arr = malloc(sizeof(DynamicArray));
+    arr->data = malloc(sizeof(void*) * initialCapacity);
+    arr->size = 0;
+    arr->capacity = initialCapacity;
+    return arr;
+}
+
+void arrayPush(DynamicArray* arr, void* value) {
+    if (arr->size >= arr->capacity) {
+        arr->capacity *= 2;
+        arr->data = realloc(arr->data, sizeof(void*) * arr->capacity);
+    }
+    arr->data[arr->size++] = value;
+}
+
+void* arrayGet(DynamicArray* arr, int index) {
+    if (index >= 0 && index < arr->size) {
+        return arr->data[index];
+    }
+    return NULL;
+}
+
+void arraySet(DynamicArray* arr, int index, void* value) {
+    if (index >= 0 && index < arr->size) {
+        arr->data[index] = value;
+    }
+}
+
+void freeArray(DynamicArray* arr) {
+    free(arr->data);
+    free(arr);
+}
+`
+}
+
+func (gen *CodeGenerator) getValueDeclarations() string {
+	return `// Boxed runtime value - the decoded form of an array, dict or JSON slot
+typedef struct {
+    AhoyValueType type;
+    union {
+        intptr_t i;
+        double f;
+        const char* s;
+        char c;
+        bool b;
+        void* p;  // AhoyArray*, HashMap*, AhoyJSON* or a boxed struct
+    } as;
+} AhoyValue;
+
+// What the runtime knows about a struct stored in a slot, indexed by struct id
+typedef struct {
+    const char* name;
+    size_t size;
+    char* (*print)(const void* value);
+} AhoySlotStruct;
+
+AhoyValue ahoy_value_from_raw(intptr_t raw, AhoyValueType type);
+intptr_t ahoy_value_to_raw(AhoyValue value);
+intptr_t ahoy_box_float(double value);
+double ahoy_slot_float(intptr_t raw, AhoyValueType type);
+intptr_t ahoy_box_struct(const void* value, size_t size);
+int ahoy_value_format(char* buffer, size_t size, AhoyValue value, bool quote_strings);
+bool ahoy_value_equals(AhoyValue a, AhoyValue b);
+AhoyValue hashMapGetValue(HashMap* map, const char* key);
+void hashMapPutValue(HashMap* map, const char* key, AhoyValue value);
+
+`
+}
+
+// usesAhoyArrays reports whether the program needs the AhoyArray runtime;
+// JSON arrays are stored as AhoyArrays of JSON slots
+func (gen *CodeGenerator) usesAhoyArrays() bool {
+	return gen.arrayImpls || len(gen.arrayMethods) > 0 || gen.useJSON
+}
+
+// writeValueImplementation emits the single place that knows how container
+// slots store each type (floats are boxed as double*) and how to print them.
+// It runs after generation so nested containers can use their print helpers.
+func (gen *CodeGenerator) writeValueImplementation() {
+	if gen.useJSON {
+		// JSON objects and arrays convert to dicts and arrays of JSON slots
+		gen.arrayMethods["print_array"] = true
+		gen.dictMethods["print_dict"] = true
+	}
+
+	var code strings.Builder
+	gen.writeSlotStructTable(&code)
+	code.WriteString(`
+// Boxed value helpers
+AhoyValue ahoy_value_from_raw(intptr_t raw, AhoyValueType type) {
+    AhoyValue value;
+    value.type = type;
+    if (type >= AHOY_TYPE_STRUCT) {
+        value.as.p = (void*)raw;
+        return value;
+    }
+    switch (type) {
+        case AHOY_TYPE_FLOAT:
+            value.as.f = raw ? *(double*)raw : 0.0;
+            break;
+        case AHOY_TYPE_STRING:
+            value.as.s = (const char*)raw;
+            break;
+        case AHOY_TYPE_CHAR:
+            value.as.c = (char)raw;
+            break;
+        case AHOY_TYPE_BOOL:
+            value.as.b = raw != 0;
+            break;
+        case AHOY_TYPE_ARRAY:
+        case AHOY_TYPE_DICT:
+        case AHOY_TYPE_JSON:
+        case AHOY_TYPE_NULL:
+            value.as.p = (void*)raw;
+            break;
+        default:
+            value.as.i = raw;
+            break;
+    }
+    return value;
+}
+
+intptr_t ahoy_box_float(double value) {
+    double* boxed = malloc(sizeof(double));
+    *boxed = value;
+    return (intptr_t)boxed;
+}
+
+// Reads a float element, which may also be an int in an untyped array
+double ahoy_slot_float(intptr_t raw, AhoyValueType type) {
+    return type == AHOY_TYPE_FLOAT ? ahoy_value_from_raw(raw, type).as.f : (double)raw;
+}
+
+// Structs don't fit in a slot, so slots hold a copy on the heap
+intptr_t ahoy_box_struct(const void* value, size_t size) {
+    void* boxed = malloc(size);
+    memcpy(boxed, value, size);
+    return (intptr_t)boxed;
+}
+
+intptr_t ahoy_value_to_raw(AhoyValue value) {
+    if (value.type >= AHOY_TYPE_STRUCT) return (intptr_t)value.as.p;
+    switch (value.type) {
+        case AHOY_TYPE_FLOAT:
+            return ahoy_box_float(value.as.f);
+        case AHOY_TYPE_STRING:
+            return (intptr_t)value.as.s;
+        case AHOY_TYPE_CHAR:
+            return (intptr_t)value.as.c;
+        case AHOY_TYPE_BOOL:
+            return (intptr_t)value.as.b;
+        case AHOY_TYPE_ARRAY:
+        case AHOY_TYPE_DICT:
+        case AHOY_TYPE_JSON:
+        case AHOY_TYPE_NULL:
+            return (intptr_t)value.as.p;
+        default:
+            return value.as.i;
+    }
+}
+
+// Strings compare by content, ints compare equal to the same float and
+// structs compare by their bytes; everything else compares by identity
+bool ahoy_value_equals(AhoyValue a, AhoyValue b) {
+    if (a.type == AHOY_TYPE_STRING && b.type == AHOY_TYPE_STRING) {
+        if (a.as.s == NULL || b.as.s == NULL) return a.as.s == b.as.s;
+        return strcmp(a.as.s, b.as.s) == 0;
+    }
+    if (a.type == AHOY_TYPE_FLOAT || b.type == AHOY_TYPE_FLOAT) {
+        double x = a.type == AHOY_TYPE_FLOAT ? a.as.f : (double)a.as.i;
+        double y = b.type == AHOY_TYPE_FLOAT ? b.as.f : (double)b.as.i;
+        return (a.type == AHOY_TYPE_FLOAT || a.type == AHOY_TYPE_INT) &&
+               (b.type == AHOY_TYPE_FLOAT || b.type == AHOY_TYPE_INT) && x == y;
+    }
+`)
+	if len(gen.slotStructs) > 0 {
+		code.WriteString(`    if (a.type >= AHOY_TYPE_STRUCT && a.type == b.type) {
+        return memcmp(a.as.p, b.as.p, ahoy_slot_structs[a.type - AHOY_TYPE_STRUCT].size) == 0;
+    }
+`)
+	}
+	code.WriteString(`    return ahoy_value_to_raw(a) == ahoy_value_to_raw(b);
+}
+
+// Formats into buffer and returns the number of characters actually written
+int ahoy_value_format(char* buffer, size_t size, AhoyValue value, bool quote_strings) {
+    int written;
+    if (size == 0) return 0;
+    switch (value.type) {
+        case AHOY_TYPE_FLOAT:
+            written = snprintf(buffer, size, "%g", value.as.f);
+            break;
+        case AHOY_TYPE_STRING:
+            if (value.as.s == NULL) {
+                written = snprintf(buffer, size, "null");
+            } else {
+                written = snprintf(buffer, size, quote_strings ? "\"%s\"" : "%s", value.as.s);
+            }
+            break;
+        case AHOY_TYPE_CHAR:
+            written = snprintf(buffer, size, quote_strings ? "'%c'" : "%c", value.as.c);
+            break;
+        case AHOY_TYPE_BOOL:
+            written = snprintf(buffer, size, "%s", value.as.b ? "true" : "false");
+            break;
+        case AHOY_TYPE_NULL:
+            written = snprintf(buffer, size, "null");
+            break;
+`)
+	if gen.arrayMethods["print_array"] {
+		code.WriteString(`        case AHOY_TYPE_ARRAY:
+            written = snprintf(buffer, size, "%s", print_array_helper((AhoyArray*)value.as.p));
+            break;
+`)
+	}
+	if gen.dictMethods["print_dict"] {
+		code.WriteString(`        case AHOY_TYPE_DICT:
+            written = snprintf(buffer, size, "%s", print_dict_helper((HashMap*)value.as.p));
+            break;
+`)
+	}
+	if gen.useJSON {
+		code.WriteString(`        case AHOY_TYPE_JSON:
+            written = snprintf(buffer, size, "%s", ahoy_json_stringify((AhoyJSON*)value.as.p));
+            break;
+`)
+	}
+	code.WriteString(`        default:
+`)
+	if len(gen.slotStructs) > 0 {
+		code.WriteString(`            if (value.type >= AHOY_TYPE_STRUCT) {
+                written = snprintf(buffer, size, "%s", ahoy_slot_structs[value.type - AHOY_TYPE_STRUCT].print(value.as.p));
+                break;
+            }
+`)
+	}
+	code.WriteString(`            written = snprintf(buffer, size, "%ld", (long)value.as.i);
+            break;
+    }
+    if (written < 0) return 0;
+    return (size_t)written < size ? written : (int)size - 1;
+}
+
+AhoyValue hashMapGetValue(HashMap* map, const char* key) {
+    unsigned int index = hash(key) % map->capacity;
+    HashMapEntry* entry = map->buckets[index];
+    while (entry != NULL) {
+        if (strcmp(entry->key, key) == 0) {
+            return ahoy_value_from_raw((intptr_t)entry->value, entry->valueType);
+        }
+        entry = entry->next;
+    }
+    return ahoy_value_from_raw(0, AHOY_TYPE_NULL);
+}
+
+void hashMapPutValue(HashMap* map, const char* key, AhoyValue value) {
+    hashMapPutTyped(map, key, (void*)ahoy_value_to_raw(value), value.type);
+}
+`)
+	if gen.usesAhoyArrays() {
+		code.WriteString(`
+AhoyValue ahoy_array_get_value(AhoyArray* arr, int index) {
+    if (arr == NULL || index < 0 || index >= arr->length) return ahoy_value_from_raw(0, AHOY_TYPE_NULL);
+    return ahoy_value_from_raw(arr->data[index], arr->types[index]);
+}
+`)
+	}
+	gen.funcDecls.WriteString(code.String())
+}
+
+func (gen *CodeGenerator) writeHashMapImplementation() {
+	hashMapCode := `
+// Hash Map Implementation with type tracking
+
+typedef struct HashMapEntry {
+    char* key;
+    void* value;
+    AhoyValueType valueType;
+    struct HashMapEntry* next;
+} HashMapEntry;
+
+typedef struct HashMap {
+    HashMapEntry** buckets;
+    int size;
+    int capacity;
+    unsigned int mods; // Bumped when keys are added or cleared; -safe loops check it
+} HashMap;
+
+// FNV-1a, which spreads similar keys across the buckets
+unsigned int hash(const char* key) {
+    uint32_t hash = 2166136261u;
+    while (*key) {
+        hash ^= (unsigned char)*key++;
+        hash *= 16777619u;
+    }
+    return hash;
+}
+
+// capacity is how many keys to make room for before the map grows
+HashMap* createHashMap(int capacity) {
+    int buckets = 16;
+    while (buckets * 3 < capacity * 4) {
+        buckets *= 2;
+    }
+    HashMap* map = malloc(sizeof(HashMap));
+    map->capacity = buckets;
+    map->size = 0;
+    map->mods = 0;
+    map->buckets = calloc(buckets, sizeof(HashMapEntry*));
+    return map;
+}
+
+// Move every entry to a bucket array of the new size
+void hashMapResize(HashMap* map, int capacity) {
+    HashMapEntry** buckets = calloc(capacity, sizeof(HashMapEntry*));
+    for (int i = 0; i < map->capacity; i++) {
+        HashMapEntry* entry = map->buckets[i];
+        while (entry != NULL) {
+            HashMapEntry* next = entry->next;
+            unsigned int index = hash(entry->key) % capacity;
+            entry->next = buckets[index];
+            buckets[index] = entry;
+            entry = next;
+        }
+    }
+    free(map->buckets);
+    map->buckets = buckets;
+    map->capacity = capacity;
+}
+
+void hashMapPutTyped(HashMap* map, const char* key, void* value, AhoyValueType valueType) {
+    unsigned int index = hash(key) % map->capacity;
+    HashMapEntry* entry = map->buckets[index];
+
+    while (entry != NULL) {
+        if (strcmp(entry->key, key) == 0) {
+            entry->value = value;
+            entry->valueType = valueType;
+            return;
+        }
+        entry = entry->next;
+    }
+
+    HashMapEntry* newEntry = malloc(sizeof(HashMapEntry));
+    newEntry->key = strdup(key);
+    newEntry->value = value;
+    newEntry->valueType = valueType;
+    newEntry->next = map->buckets[index];
+    map->buckets[index] = newEntry;
+    map->size++;
+    map->mods++;
+
+    // Grow past a load factor of 3/4 so chains stay short
+    if (map->size * 4 > map->capacity * 3) {
+        hashMapResize(map, map->capacity * 2);
+    }
+}
+
+void hashMapPut(HashMap* map, const char* key, void* value) {
+    hashMapPutTyped(map, key, value, AHOY_TYPE_STRING);
+}
+
+void* hashMapGet(HashMap* map, const char* key) {
+    unsigned int index = hash(key) % map->capacity;
+    HashMapEntry* entry = map->buckets[index];
+
+    while (entry != NULL) {
+        if (strcmp(entry->key, key) == 0) {
+            return entry->value;
+        }
+        entry = entry->next;
+    }
+    return NULL;
+}
+
+// Get value with automatic type conversion - dereferences floats to actual double bits
+intptr_t hashMapGetTyped(HashMap* map, const char* key) {
+    unsigned int index = hash(key) % map->capacity;
+    HashMapEntry* entry = map->buckets[index];
+
+    while (entry != NULL) {
+        if (strcmp(entry->key, key) == 0) {
+            // For floats, dereference the pointer and return as bits in intptr_t
+            if (entry->valueType == AHOY_TYPE_FLOAT) {
+                union { double d; intptr_t i; } u;
+                u.d = ahoy_value_from_raw((intptr_t)entry->value, AHOY_TYPE_FLOAT).as.f;
+                return u.i;
+            }
+            // For other types, return the value as-is
+            return (intptr_t)(entry->value);
+        }
+        entry = entry->next;
+    }
+    return 0;
+}
+
+// Get value as double (for arithmetic operations and generic access)
+double hashMapGetDouble(HashMap* map, const char* key) {
+    unsigned int index = hash(key) % map->capacity;
+    HashMapEntry* entry = map->buckets[index];
+
+    while (entry != NULL) {
+        if (strcmp(entry->key, key) == 0) {
+            switch (entry->valueType) {
+                case AHOY_TYPE_INT:
+                    return (double)(intptr_t)entry->value;
+                case AHOY_TYPE_FLOAT:
+                    return ahoy_value_from_raw((intptr_t)entry->value, AHOY_TYPE_FLOAT).as.f;
+                case AHOY_TYPE_STRING:
+                    // For strings, return the pointer cast to double (for later casting back)
+                    return (double)(intptr_t)entry->value;
+                default:
+                    return (double)(intptr_t)entry->value;
+            }
+        }
+        entry = entry->next;
+    }
+    return 0.0;
+}
+
+// Helper to print dict values with proper type handling
+char* format_dict_value(HashMap* map, const char* key) {
+    unsigned int index = hash(key) % map->capacity;
+    HashMapEntry* entry = map->buckets[index];
+    // Rotate buffers so several values can be formatted in one printf
+    static char buffers[8][1024];
+    static int next_buffer = 0;
+    char* buffer = buffers[next_buffer];
+    next_buffer = (next_buffer + 1) % 8;
+
+    while (entry != NULL) {
+        if (strcmp(entry->key, key) == 0) {
+            ahoy_value_format(buffer, sizeof(buffers[0]), ahoy_value_from_raw((intptr_t)entry->value, entry->valueType), false);
+            return buffer;
+        }
+        entry = entry->next;
+    }
+    return "";
+}
+
+void freeHashMap(HashMap* map) {
+    for (int i = 0; i < map->capacity; i++) {
+        HashMapEntry* entry = map->buckets[i];
+        while (entry != NULL) {
+            HashMapEntry* temp = entry;
+            entry = entry->next;
+            free(temp->key);
+            free(temp);
+        }
+    }
+    free(map->buckets);
+    free(map);
+}
+`
+	gen.funcDecls.WriteString(hashMapCode)
+}
+
+func (gen *CodeGenerator) getHashMapDeclarations() string {
+	var decls strings.Builder
+	decls.WriteString("\n// Forward declarations\n")
+	decls.WriteString("typedef struct HashMapEntry HashMapEntry;\n")
+	decls.WriteString("typedef struct HashMap HashMap;\n")
+	decls.WriteString("HashMap* createHashMap(int capacity);\n")
+	decls.WriteString("void hashMapPut(HashMap* map, const char* key, void* value);\n")
+	decls.WriteString("void* hashMapGet(HashMap* map, const char* key);\n")
+	decls.WriteString("intptr_t hashMapGetTyped(HashMap* map, const char* key);\n")
+	decls.WriteString("double hashMapGetDouble(HashMap* map, const char* key);\n")
+	decls.WriteString("char* format_dict_value(HashMap* map, const char* key);\n")
+	decls.WriteString("void freeHashMap(HashMap* map);\n")
+
+	return decls.String()
+}
+
+// checkForMainFunction scans the AST for a main function, registers all user
+// functions and checks their names and enum names don't clash in C
+func (gen *CodeGenerator) checkForMainFunction(node *ASTNode) {
+	if node == nil {
+		return
+	}
+
+	if node.Type == NODE_ENUM_DECLARATION {
+		gen.checkDeclaredName(node, "an enum")
+	}
+	if node.Type == NODE_FUNCTION {
+		// Register this as a user-defined function
+		funcName := node.Value
+		gen.userFunctions[funcName] = true
+		gen.checkDeclaredName(node, "a function")
+
+		// Check if it's the main function
+		if funcName == "main" {
+			gen.hasMainFunc = true
+		}
+	}
+
+	for _, child := range node.Children {
+		gen.checkForMainFunction(child)
+	}
+}
+
+// scanVariableTypes scans all variable declarations to populate type information
+func (gen *CodeGenerator) scanVariableTypes(node *ASTNode) {
+	if node == nil {
+		return
+	}
+
+	// Scan for variable declarations and track their types
+	if node.Type == NODE_VARIABLE_DECLARATION || node.Type == NODE_ASSIGNMENT || node.Type == NODE_CONSTANT_DECLARATION {
+		varName := node.Value
+		if len(node.Children) > 0 {
+			// Check for explicit type annotation
+			if node.DataType != "" && node.DataType != "generic" {
+				gen.variables[varName] = node.DataType
+			} else {
+				// Try to infer from value
+				valueType := gen.inferType(node.Children[0])
+				if valueType != "unknown" && valueType != "generic" {
+					gen.variables[varName] = valueType
+				}
+			}
+		}
+	}
+	// recover name: holds the message of the panic it catches
+	if node.Type == NODE_RECOVER && node.Value != "" {
+		gen.variables[node.Value] = "string"
+	}
+
+	// Recursively scan children
+	for _, child := range node.Children {
+		gen.scanVariableTypes(child)
+	}
+}
+
+// inferParameterTypesFromCalls analyzes function calls to infer parameter types
+func (gen *CodeGenerator) inferParameterTypesFromCalls(node *ASTNode) {
+	if node == nil {
+		return
+	}
+
+	// First collect all function definitions and their parameter names
+	functionParams := make(map[string][]string) // function name -> parameter names
+	var collectFuncs func(*ASTNode)
+	collectFuncs = func(n *ASTNode) {
+		if n == nil {
+			return
+		}
+		if n.Type == NODE_FUNCTION {
+			funcName := n.Value
+			if len(n.Children) > 0 && n.Children[0].Type == NODE_BLOCK {
+				params := n.Children[0]
+				paramNames := []string{}
+				for _, param := range params.Children {
+					if param.Type == NODE_IDENTIFIER {
+						paramNames = append(paramNames, param.Value)
+					}
+				}
+				functionParams[funcName] = paramNames
+			}
+		}
+		for _, child := range n.Children {
+			collectFuncs(child)
+		}
+	}
+	collectFuncs(node)
+
+	// Now scan for function calls and infer parameter types from arguments
+	paramTypeInferences := make(map[string]map[int]string) // function name -> param index -> inferred type
+	var analyzeCalls func(*ASTNode)
+	analyzeCalls = func(n *ASTNode) {
+		if n == nil {
+			return
+		}
+		if n.Type == NODE_CALL {
+			funcName := n.Value
+			if paramNames, exists := functionParams[funcName]; exists {
+				// This is a user-defined function
+				if _, ok := paramTypeInferences[funcName]; !ok {
+					paramTypeInferences[funcName] = make(map[int]string)
+				}
+
+				// Infer types from arguments
+				for i, arg := range n.Children {
+					if i < len(paramNames) {
+						argType := gen.inferType(arg)
+
+						// If we already have an inferred type for this parameter, check consistency
+						if existingType, hasType := paramTypeInferences[funcName][i]; hasType {
+							// If types differ and one is more specific (not generic), prefer the specific one
+							if existingType != argType {
+								if existingType == "int" || existingType == "generic" {
+									paramTypeInferences[funcName][i] = argType
+								}
+								// Otherwise keep existing type if it's more specific
+							}
+						} else {
+							paramTypeInferences[funcName][i] = argType
+						}
+					}
+				}
+			}
+		}
+		for _, child := range n.Children {
+			analyzeCalls(child)
+		}
+	}
+	analyzeCalls(node)
+
+	// Apply inferred types to function parameters in the AST
+	var applyTypes func(*ASTNode)
+	applyTypes = func(n *ASTNode) {
+		if n == nil {
+			return
+		}
+		if n.Type == NODE_FUNCTION {
+			funcName := n.Value
+			if inferences, exists := paramTypeInferences[funcName]; exists {
+				if len(n.Children) > 0 && n.Children[0].Type == NODE_BLOCK {
+					params := n.Children[0]
+					for i, param := range params.Children {
+						if param.Type == NODE_IDENTIFIER {
+							// Only apply inferred type if parameter doesn't already have an explicit type
+							if param.DataType == "" || param.DataType == "generic" {
+								if inferredType, hasInference := inferences[i]; hasInference {
+									// Don't override with generic/int if we have something better
+									if inferredType != "generic" && inferredType != "int" {
+										param.DataType = inferredType
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		for _, child := range n.Children {
+			applyTypes(child)
+		}
+	}
+	applyTypes(node)
+}
+
+// importedHeader returns what the C header an import names declares. The
+// package manager binds it to imports when building; an AST generated on its
+// own has its headers found from the source file.
+func (gen *CodeGenerator) importedHeader(node *ASTNode) *CHeaderInfo {
+	if node.Header == nil {
+		node.Header, _ = gen.headers.LoadHeader(node.Value, gen.sourceFilename)
+	}
+	return node.Header
+}
+
+// bindHeader makes a header's types known and its functions callable by
+// their snake_case names, under namespace when the import gave one
+func (gen *CodeGenerator) bindHeader(headerInfo *CHeaderInfo, namespace string) {
+	// Track struct/typedef names as known C types
+	for typeName := range headerInfo.Structs {
+		gen.cTypeDefinitions[typeName] = true
+		// Also register lowercase version for easier matching
+		gen.cTypeDefinitions[strings.ToLower(typeName)] = true
+	}
+	gen.registerCEnums(headerInfo)
+
+	if namespace != "" && gen.cNamespaces[namespace] == nil {
+		gen.cNamespaces[namespace] = make(map[string]string)
+		gen.cNamespaceReturnTypes[namespace] = make(map[string]string)
+	}
+	for cFuncName, funcInfo := range headerInfo.Functions {
+		snakeName := PascalToSnake(cFuncName)
+		if namespace != "" {
+			gen.cNamespaces[namespace][snakeName] = cFuncName
+			gen.cNamespaceReturnTypes[namespace][snakeName] = funcInfo.ReturnType
+		} else {
+			gen.cFunctionNames[snakeName] = cFuncName
+			gen.cFunctionReturnTypes[snakeName] = funcInfo.ReturnType
+			gen.cFunctionParams[snakeName] = funcInfo.Parameters
+		}
+
+		// Register return type as a known C type if it's a struct
+		if funcInfo.ReturnType != "" && funcInfo.ReturnType != "void" && funcInfo.ReturnType != "int" &&
+			funcInfo.ReturnType != "float" && funcInfo.ReturnType != "double" && funcInfo.ReturnType != "char*" {
+			gen.cTypeDefinitions[funcInfo.ReturnType] = true
+			gen.cTypeDefinitions[strings.ToLower(funcInfo.ReturnType)] = true
+		}
+	}
+}
+
+// scanImports scans imports to populate C type definitions before code generation
+func (gen *CodeGenerator) scanImports(node *ASTNode) {
+	if node == nil {
+		return
+	}
+
+	// Process import statements to populate C type definitions
+	if node.Type == NODE_IMPORT_STATEMENT && strings.HasSuffix(node.Value, ".h") {
+		if headerInfo := gen.importedHeader(node); headerInfo != nil {
+			gen.bindHeader(headerInfo, node.DataType)
+		}
+	}
+
+	// extern declarations name a C function and its signature directly
+	if node.Type == NODE_EXTERN {
+		params := node.Children[0].Children
+		paramTypes := make([]string, len(params))
+		paramNames := make([]string, len(params))
+		for i, param := range params {
+			paramTypes[i] = param.DataType
+			paramNames[i] = param.Value
+		}
+		gen.externs[node.Value] = node
+		gen.cFunctionNames[node.Value] = "ahoy_extern_" + node.Value
+		gen.functionReturnTypes[node.Value] = []string{node.DataType}
+		gen.functionParamTypes[node.Value] = paramTypes
+		gen.functionParamNames[node.Value] = paramNames
+		return
+	}
+
+	// Recursively scan children
+	for _, child := range node.Children {
+		gen.scanImports(child)
+	}
+}
+
+func (gen *CodeGenerator) scanForMethodCalls(node *ASTNode) {
+	if node == nil {
+		return
+	}
+	// A call to a function the program declares isn't one to the builtin
+	// of the same name
+	builtinCall := node.Type == NODE_CALL && !gen.userFunctions[node.Value]
+
+	// Check for read_json or write_json calls
+	if builtinCall && (node.Value == "read_json" || node.Value == "write_json") {
+		if !gen.useJSON {
+			gen.useJSON = true
+			gen.registerJSONFunctionTypes()
+		}
+	}
+
+	// Struct JSON encoding builds on the JSON runtime
+	if builtinCall && (node.Value == "json_encode" || node.Value == "json_decode") {
+		if !gen.useJSON {
+			gen.useJSON = true
+			gen.registerJSONFunctionTypes()
+		}
+		gen.functionReturnTypes["json_encode"] = []string{"string"}
+		gen.functionReturnTypes["json_decode"] = []string{"bool"}
+	}
+
+	// Check for byte buffer literals and binary file I/O
+	if node.Type == NODE_BYTES_LITERAL || node.Type == NODE_EMBED_STATEMENT ||
+		(builtinCall && (node.Value == "read_bytes" || node.Value == "write_bytes")) {
+		gen.markBytesUsed()
+	}
+
+	// Vector and color helpers return their own types
+	if helper, exists := builtinTypeHelpers[node.Value]; exists && builtinCall {
+		gen.functionReturnTypes[node.Value] = []string{helper.result}
+	}
+
+	// Check for string to number parsing
+	if builtinCall && (node.Value == "parse_int" || node.Value == "parse_float") {
+		gen.markNumberParsingUsed()
+	}
+
+	// Check for range values
+	if builtinCall && node.Value == "range" {
+		gen.markRangeUsed()
+	}
+
+	if node.Type == NODE_METHOD_CALL && len(node.Children) > 0 {
+		// Extract method name
+		methodName := node.Value
+
+		// Check the object type to determine if it's array, dict, or string method
+		objectType := ""
+		if len(node.Children) > 0 {
+			objectType = gen.inferType(node.Children[0])
+		}
+
+		// Mark the method as used
+		if objectType == "array" || strings.HasPrefix(objectType, "array[") {
+			gen.arrayMethods[methodName] = true
+		} else if objectType == "dict" || strings.HasPrefix(objectType, "dict[") {
+			gen.dictMethods[methodName] = true
+		} else if objectType == "string" {
+			gen.stringMethods[methodName] = true
+		}
+	}
+
+	for _, child := range node.Children {
+		gen.scanForMethodCalls(child)
+	}
+}
+
+// inferAllFunctionReturnTypes pre-processes all functions with infer return type
+func (gen *CodeGenerator) inferAllFunctionReturnTypes(node *ASTNode) {
+	if node == nil {
+		return
+	}
+
+	if node.Type == NODE_FUNCTION {
+		funcName := node.Value
+		// Check if this function has infer return type
+		if node.DataType == "infer" {
+			inferredTypes := gen.inferReturnTypes(node)
+			if len(inferredTypes) > 0 {
+				gen.functionReturnTypes[funcName] = inferredTypes
+			}
+		} else if node.DataType != "" && node.DataType != "void" {
+			// For explicitly typed functions, store the return types
+			if strings.Contains(node.DataType, ",") {
+				gen.functionReturnTypes[funcName] = splitReturnTypes(node.DataType)
+			} else {
+				gen.functionReturnTypes[funcName] = []string{node.DataType}
+			}
+		}
+	}
+
+	for _, child := range node.Children {
+		gen.inferAllFunctionReturnTypes(child)
+	}
+}
+
+func (gen *CodeGenerator) writeIndent() {
+	for i := 0; i < gen.indent; i++ {
+		gen.output.WriteString("    ")
+	}
+}
+
+// writeVariableType starts a variable declaration with its C type. Top-level
+// variables are hoisted to file scope instead, so every function can use
+// them, which leaves the statement a plain assignment.
+func (gen *CodeGenerator) writeVariableType(cType string, name string) {
+	if gen.currentFunction != "" {
+		gen.output.WriteString(gen.localType(cType, name) + " ")
+		return
+	}
+	if !gen.hoistedGlobals[name] {
+		gen.hoistedGlobals[name] = true
+		gen.globalDecls.WriteString(fmt.Sprintf("%s %s;\n", cType, name))
+	}
+}
+
+// declareVariable declares a variable that is assigned afterwards, hoisting
+// top-level variables the same way as writeVariableType
+func (gen *CodeGenerator) declareVariable(cType string, name string) {
+	if gen.currentFunction != "" {
+		gen.output.WriteString(fmt.Sprintf("%s %s;\n", gen.localType(cType, name), name))
+		return
+	}
+	gen.writeVariableType(cType, name)
+}
+
+func (gen *CodeGenerator) generate(node *ASTNode) {
+	gen.generateNodeInternal(node, false)
+}
+
+func (gen *CodeGenerator) generateNode(node *ASTNode) {
+	gen.generateNodeInternal(node, false)
+}
+
+func (gen *CodeGenerator) generateNodeInternal(node *ASTNode, isStatement bool) {
+	if node == nil {
+		return
+	}
+
+	switch node.Type {
+	case NODE_PROGRAM:
+		for _, child := range node.Children {
+			if file, exists := gen.nodeFiles[child]; exists {
+				gen.currentFile = file
+			}
+			gen.commentStatement(child)
+			gen.coverStatement(child)
+			gen.generateNodeInternal(child, true)
+		}
+
+	case NODE_FUNCTION:
+		gen.generateFunction(node)
+
+	case NODE_ASSIGNMENT:
+		gen.generateAssignment(node)
+
+	case NODE_IF_STATEMENT:
+		gen.generateIfStatement(node)
+
+	case NODE_SWITCH_STATEMENT:
+		gen.generateSwitchStatement(node)
+
+	case NODE_WHILE_LOOP:
+		gen.generateWhileLoop(node)
+
+	case NODE_FOR_LOOP:
+		gen.generateForLoop(node)
+
+	case NODE_FOR_RANGE_LOOP:
+		gen.generateForRangeLoop(node)
+
+	case NODE_FOR_COUNT_LOOP:
+		gen.generateForCountLoop(node)
+
+	case NODE_FOR_IN_ARRAY_LOOP:
+		gen.generateForInArrayLoop(node)
+
+	case NODE_FOR_IN_DICT_LOOP:
+		gen.generateForInDictLoop(node)
+
+	case NODE_WHEN_STATEMENT:
+		gen.generateWhenStatement(node)
+
+	case NODE_RETURN_STATEMENT:
+		gen.generateReturnStatement(node)
+
+	case NODE_IMPORT_STATEMENT:
+		gen.generateImportStatement(node)
+
+	case NODE_EMBED_STATEMENT:
+		gen.generateEmbedStatement(node)
+
+	case NODE_EXTERN:
+		gen.generateExtern(node)
+
+	case NODE_PROGRAM_DECLARATION:
+		// Skip program declarations in code generation
+		return
+
+	case NODE_CALL:
+		if isStatement && gen.tailCalls[node] != nil {
+			gen.generateTailCall(node)
+			return
+		}
+		if isStatement {
+			gen.writeIndent()
+		}
+		gen.generateCall(node)
+		if isStatement {
+			gen.output.WriteString(";\n")
+		}
+
+	case NODE_INLINE:
+		gen.generateInline(node)
+
+	case NODE_BINARY_OP:
+		gen.generateBinaryOp(node)
+
+	case NODE_UNARY_OP:
+		gen.generateUnaryOp(node)
+
+	case NODE_TERNARY:
+		if isStatement {
+			gen.writeIndent()
+		}
+		gen.generateTernary(node)
+		if isStatement {
+			gen.output.WriteString(";\n")
+		}
+
+	case NODE_IDENTIFIER:
+		// Check if it's the loop counter variable
+		if node.Value == "__loop_counter" && len(gen.loopCounters) > 0 {
+			gen.output.WriteString(gen.loopCounters[len(gen.loopCounters)-1])
+		} else {
+			// A bare enum member resolves to its fully qualified name
+			if resolvedName := gen.resolveEnumMember(node); resolvedName != "" {
+				gen.output.WriteString(resolvedName)
+			} else {
+				// Check if it's a known constant/macro from raylib or other C libraries
+				// These will be passed through directly to C
+				// Don't convert variable names, only function names are converted
+				gen.output.WriteString(node.Value)
+			}
+		}
+
+	case NODE_NUMBER:
+		gen.output.WriteString(node.Value)
+
+	case NODE_STRING:
+		gen.output.WriteString(fmt.Sprintf("\"%s\"", node.Value))
+
+	case NODE_F_STRING:
+		gen.generateFString(node)
+
+	case NODE_BYTES_LITERAL:
+		gen.generateBytesLiteral(node)
+
+	case NODE_CHAR:
+		gen.output.WriteString(fmt.Sprintf("'%s'", node.Value))
+
+	case NODE_BOOLEAN:
+		if node.Value == "true" {
+			gen.output.WriteString("true")
+		} else {
+			gen.output.WriteString("false")
+		}
+
+	case NODE_DICT_LITERAL:
+		gen.generateDictLiteral(node)
+
+	case NODE_ARRAY_LITERAL:
+		gen.generateArrayLiteral(node)
+
+	case NODE_OBJECT_LITERAL:
+		gen.generateObjectLiteral(node)
+
+	case NODE_ARRAY_ACCESS:
+		gen.generateArrayAccess(node)
+
+	case NODE_DICT_ACCESS:
+		gen.generateDictAccess(node)
+
+	case NODE_OBJECT_ACCESS:
+		gen.generateObjectAccess(node)
+
+	case NODE_BLOCK:
+		for _, child := range node.Children {
+			gen.commentStatement(child)
+			gen.coverStatement(child)
+			gen.generateNodeInternal(child, true)
+			gen.markUnusedDeclarations(child)
+		}
+	case NODE_ENUM_DECLARATION:
+		// Top-level enums are declared at file scope for every function
+		if gen.currentFunction == "" {
+			savedOutput := gen.output
+			gen.output = strings.Builder{}
+			gen.generateEnum(node)
+			gen.globalDecls.WriteString(gen.output.String())
+			gen.output = savedOutput
+		} else {
+			gen.generateEnum(node)
+		}
+	case NODE_CONSTANT_DECLARATION:
+		gen.generateConstant(node)
+	case NODE_TUPLE_ASSIGNMENT:
+		gen.generateTupleAssignment(node)
+	case NODE_STRUCT_DECLARATION:
+		gen.generateStruct(node)
+	case NODE_ALIAS_DECLARATION:
+		// Type aliases are compile-time only, no C code needed
+		return
+	case NODE_UNION_DECLARATION:
+		// Union types are compile-time only, no C code needed
+		return
+	case NODE_METHOD_CALL:
+		if isStatement {
+			gen.writeIndent()
+		}
+		gen.generateMethodCall(node)
+		if isStatement {
+			gen.output.WriteString(";\n")
+		}
+	case NODE_TYPE_PROPERTY:
+		gen.generateTypeProperty(node)
+	case NODE_MEMBER_ACCESS:
+		gen.generateMemberAccess(node)
+	case NODE_HALT:
+		gen.leaveRecover(node)
+		gen.writeIndent()
+		gen.output.WriteString("break;\n")
+	case NODE_NEXT:
+		gen.leaveRecover(node)
+		gen.writeIndent()
+		gen.output.WriteString("continue;\n")
+	case NODE_RECOVER:
+		gen.generateRecover(node)
+	case NODE_ASSERT_STATEMENT:
+		gen.generateAssertStatement(node)
+	case NODE_DEFER_STATEMENT:
+		gen.generateDeferStatement(node)
+	}
+}
+
+func (gen *CodeGenerator) generateFunction(node *ASTNode) {
+	funcName := node.Value
+
+	// Rename main to ahoy_main to avoid conflict with C's main
+	cFuncName := funcName
+	if funcName == "main" {
+		cFuncName = "ahoy_main"
+	}
+
+	// Track this as a user-defined function (keep snake_case)
+	gen.userFunctions[funcName] = true
+
+	returnType := "void"
+	returnTypes := []string{}
+
+	// Check if we have multiple return types (comma-separated in DataType)
+	if node.DataType != "" {
+		if node.DataType == "infer" {
+			// Need to infer return types from the return statement
+			inferredTypes := gen.inferReturnTypes(node)
+
+			if len(inferredTypes) > 1 {
+				// Multiple inferred return types
+				returnTypes = inferredTypes
+
+				// Generate struct definition for multi-return
+				structName := fmt.Sprintf("%s_return", funcName)
+				gen.funcReturnStructs.WriteString(fmt.Sprintf("typedef struct {\n"))
+				for i, rType := range returnTypes {
+					// Use intptr_t for generic types (will be cast at call site)
+					var mappedType string
+					if rType == "generic" {
+						mappedType = "intptr_t"
+					} else {
+						mappedType = gen.mapType(rType)
+					}
+					gen.funcReturnStructs.WriteString(fmt.Sprintf("    %s ret%d;\n", mappedType, i))
+				}
+				gen.funcReturnStructs.WriteString(fmt.Sprintf("} %s;\n\n", structName))
+				returnType = structName
+			} else if len(inferredTypes) == 1 {
+				// Single inferred return type
+				returnTypes = inferredTypes // Store for lookup
+				returnType = gen.mapType(inferredTypes[0])
+			} else {
+				// No return statement found, default to void
+				returnType = "void"
+			}
+		} else if strings.Contains(node.DataType, ",") {
+			// Multiple return types - create a struct
+			// Use smart split that handles nested commas in dict<k,v>
+			returnTypes = splitReturnTypes(node.DataType)
+
+			// Generate struct definition for multi-return
+			structName := fmt.Sprintf("%s_return", funcName)
+			gen.funcReturnStructs.WriteString(fmt.Sprintf("typedef struct {\n"))
+			for i, rType := range returnTypes {
+				mappedType := gen.mapType(rType)
+				gen.funcReturnStructs.WriteString(fmt.Sprintf("    %s ret%d;\n", mappedType, i))
+			}
+			gen.funcReturnStructs.WriteString(fmt.Sprintf("} %s;\n\n", structName))
+			returnType = structName
+		} else {
+			returnType = gen.mapType(node.DataType)
+		}
+	}
+
+	// Build parameter list for both declaration and forward declaration
+	params := node.Children[0]
+	paramList := ""
+	for i, param := range params.Children {
+		if i > 0 {
+			paramList += ", "
+		}
+		paramType := "intptr_t" // default for untyped/generic parameters
+		if param.DataType != "" {
+			if param.DataType == "generic" {
+				paramType = "intptr_t" // Use intptr_t for generic parameters
+			} else {
+				paramType = gen.mapType(param.DataType)
+			}
+		}
+		paramList += fmt.Sprintf("%s %s", paramType, param.Value)
+	}
+
+	// Big results are written to the caller's struct rather than returned
+	cReturnType := returnType
+	if gen.outParams[funcName] {
+		cReturnType = "void"
+		if paramList != "" {
+			paramList += ", "
+		}
+		paramList += fmt.Sprintf("%s* %s", returnType, outParamName)
+	}
+	if paramList == "" {
+		paramList = "void"
+	}
+
+	// Store return types and parameter types for this function (for later lookup)
+	if len(returnTypes) > 0 {
+		gen.functionReturnTypes[funcName] = returnTypes
+	}
+
+	// Store parameter types, names, and default values
+	paramTypes := []string{}
+	paramNames := []string{}
+	paramDefaults := []*ASTNode{}
+	for _, param := range params.Children {
+		paramNames = append(paramNames, param.Value)
+		paramDefaults = append(paramDefaults, param.DefaultValue)
+		if param.DataType != "" {
+			paramTypes = append(paramTypes, param.DataType)
+		} else {
+			paramTypes = append(paramTypes, "generic")
+		}
+	}
+	gen.functionParamTypes[funcName] = paramTypes
+	gen.functionParamNames[funcName] = paramNames
+	gen.functionParamDefaults[funcName] = paramDefaults
+
+	// Write forward declaration
+	gen.funcForwardDecls.WriteString(fmt.Sprintf("%s %s(%s);\n", cReturnType, cFuncName, paramList))
+	// Write function implementation; a library's exports are the only
+	// functions other code can call
+	if gen.library != "" && node.Tag == "export" {
+		gen.funcDecls.WriteString(exportVisibility)
+	}
+	gen.funcDecls.WriteString(fmt.Sprintf("%s %s(%s) {\n", cReturnType, cFuncName, paramList))
+
+	// Function body
+	body := node.Children[1]
+	oldOutput := gen.output
+	gen.output = strings.Builder{}
+	gen.indent++
+
+	// Store current function info for return statement generation
+	gen.currentFunction = cFuncName
+	gen.currentFunctionReturnType = returnType
+	gen.currentFunctionHasMultiReturn = len(returnTypes) > 1
+
+	// Initialize function-local variable scope and add parameters
+	gen.functionVars = make(map[string]string)
+	gen.dictSourcedVars = make(map[string]string)
+	gen.dictSourcedKeys = make(map[string]string)
+	gen.nestedScopeVars = make(map[string]bool)
+
+	// Clear function-local declared variables for this new function
+	gen.declaredFunctionVars = make(map[string]bool)
+
+	for _, param := range params.Children {
+		if param.DataType != "" {
+			gen.functionVars[param.Value] = param.DataType
+
+			// Track array element types for typed array parameters
+			if strings.HasPrefix(param.DataType, "array[") {
+				elemType := strings.TrimSuffix(strings.TrimPrefix(param.DataType, "array["), "]")
+				gen.arrayElementTypes[param.Value] = elemType
+			}
+		} else {
+			// Parameters without explicit type are generic
+			gen.functionVars[param.Value] = "generic"
+		}
+	}
+
+	// Initialize deferred statements stack for this function
+	gen.deferredStatements = []string{}
+
+	// Tail calls to itself start the body over
+	gen.tailCalls = gen.findTailCalls(node, returnType == "void")
+	if len(gen.tailCalls) > 0 {
+		gen.writeIndent()
+		gen.output.WriteString(tailCallLabel + ":;\n")
+	}
+
+	gen.unusedVars = unusedLocals(body)
+	gen.recoverVars = recoverAssigned(body)
+	gen.generateNodeInternal(body, false)
+
+	// Run the deferred statements at the end of the body, unless it ends
+	// by returning and so has already run them
+	if len(body.Children) == 0 || body.Children[len(body.Children)-1].Type != NODE_RETURN_STATEMENT {
+		gen.writeDeferred()
+	}
+
+	// Each defer sets a flag when it is reached, declared ahead of the body
+	for i := range gen.deferredStatements {
+		gen.funcDecls.WriteString(fmt.Sprintf("    int %s = 0;\n", deferFlag(i)))
+	}
+	gen.funcDecls.WriteString(gen.output.String())
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.indent--
+	gen.output = oldOutput
+	gen.currentFunction = ""
+	gen.currentFunctionReturnType = ""
+	gen.currentFunctionHasMultiReturn = false
+	gen.tailCalls = nil
+	gen.unusedVars = nil
+	gen.recoverVars = nil
+	gen.functionVars = nil                           // Clear function scope
+	gen.deferredStatements = nil                     // Clear deferred statements
+	gen.declaredFunctionVars = make(map[string]bool) // Clear function-local declarations
+}
+
+func (gen *CodeGenerator) generateAssignment(node *ASTNode) {
+	gen.writeIndent()
+
+	// Check if this is a property/element/pointer assignment (obj<'prop'>: value or dict{"key"}: value or obj.prop: value or ^ptr: value)
+	// In this case, Children[0] is the access node, Children[1] is the value
+	if len(node.Children) == 2 &&
+		(node.Children[0].Type == NODE_OBJECT_ACCESS ||
+			node.Children[0].Type == NODE_DICT_ACCESS ||
+			node.Children[0].Type == NODE_ARRAY_ACCESS ||
+			node.Children[0].Type == NODE_MEMBER_ACCESS ||
+			node.Children[0].Type == NODE_UNARY_OP) {
+
+		// Special handling for array assignment with bounds checking
+		if node.Children[0].Type == NODE_ARRAY_ACCESS && gen.enableBoundsChecking {
+			arrayName := node.Children[0].Value
+			indexNode := node.Children[0].Children[0]
+			valueNode := node.Children[1]
+
+			// Fixed-size arrays check against their compile-time length
+			if _, length, isFixed := gen.fixedArrayType(arrayName); isFixed {
+				gen.output.WriteString("{ int __idx = ")
+				gen.generateNode(indexNode)
+				gen.output.WriteString("; ")
+				gen.writeIndexBoundsCheck(arrayName, strconv.FormatInt(length, 10), node.Children[0].Line)
+				gen.output.WriteString(fmt.Sprintf("%s[__idx] = ", arrayName))
+				gen.generateNode(valueNode)
+				gen.output.WriteString("; }\n")
+				return
+			}
+
+			// Byte buffers check against their runtime length
+			if gen.isBytesVar(arrayName) {
+				gen.output.WriteString("{ int __idx = ")
+				gen.generateNode(indexNode)
+				gen.output.WriteString("; ")
+				gen.writeIndexBoundsCheck(arrayName, arrayName+"->length", node.Children[0].Line)
+				gen.output.WriteString(fmt.Sprintf("%s->data[__idx] = (unsigned char)(", arrayName))
+				gen.generateNode(valueNode)
+				gen.output.WriteString("); }\n")
+				return
+			}
+
+			// Check if the variable type is intptr_t, void*, or generic (might need casting to AhoyArray*)
+			needsArrayCast := false
+			arrayType := ""
+			if varType, exists := gen.variables[arrayName]; exists {
+				arrayType = varType
+			}
+			if varType, exists := gen.functionVars[arrayName]; exists {
+				arrayType = varType
+			}
+			if arrayType == "intptr_t" || arrayType == "void*" || arrayType == "generic" {
+				needsArrayCast = true
+			}
+
+			// The slot takes the value and its type tag; a typed array
+			// stores the value as its element type
+			valueType := gen.getValueType(valueNode)
+			if strings.HasPrefix(arrayType, "array[") {
+				valueType = strings.TrimSuffix(strings.TrimPrefix(arrayType, "array["), "]")
+			}
+			gen.arrayMethods["set"] = true
+			gen.output.WriteString("ahoy_array_set(")
+			if needsArrayCast {
+				gen.output.WriteString("(AhoyArray*)")
+			}
+			gen.output.WriteString(arrayName + ", ")
+			gen.generateNode(indexNode)
+			gen.output.WriteString(", ")
+			gen.generateSlotValue(valueNode, valueType)
+			gen.output.WriteString(fmt.Sprintf(", %s, \"%s\", %d, \"%s\");\n",
+				gen.getAhoyTypeEnum(valueType), gen.sourceFilename, node.Children[0].Line, arrayName))
+			return
+		}
+
+		// Special handling for dict assignment - use hashMapPut
+		if node.Children[0].Type == NODE_DICT_ACCESS {
+			dictName := node.Children[0].Value
+			keyNode := node.Children[0].Children[0]
+			valueNode := node.Children[1]
+
+			valueType := gen.getValueType(valueNode)
+			gen.output.WriteString(fmt.Sprintf("hashMapPutTyped(%s, ", dictName))
+			gen.generateNode(keyNode)
+			gen.output.WriteString(", (void*)")
+			gen.generateSlotValue(valueNode, valueType)
+			gen.output.WriteString(fmt.Sprintf(", %s);\n", gen.getAhoyTypeEnum(valueType)))
+			return
+		}
+
+		// Special handling for object access assignment - use hashMapPut if it's a HashMap/dict/generic
+		if node.Children[0].Type == NODE_OBJECT_ACCESS {
+			objectName := node.Children[0].Value
+			propertyName := ""
+			if len(node.Children[0].Children) > 0 && node.Children[0].Children[0].Type == NODE_STRING {
+				propertyName = node.Children[0].Children[0].Value
+			}
+
+			// Check if this is a HashMap/dict or generic parameter
+			objectType := ""
+			if varType, exists := gen.variables[objectName]; exists {
+				objectType = varType
+			} else if varType, exists := gen.functionVars[objectName]; exists {
+				objectType = varType
+			}
+
+			// If object is dict, HashMap*, generic, or intptr_t, use hashMapPut
+			if objectType == "dict" || objectType == "HashMap*" || objectType == "generic" || objectType == "intptr_t" ||
+				strings.HasPrefix(objectType, "dict[") || strings.HasPrefix(objectType, "dict<") {
+				valueType := gen.getValueType(node.Children[1])
+				gen.output.WriteString("hashMapPutTyped(")
+				// Cast generic/intptr_t to HashMap*
+				if objectType == "generic" || objectType == "intptr_t" {
+					gen.output.WriteString("(HashMap*)")
+				}
+				gen.output.WriteString(objectName)
+				gen.output.WriteString(fmt.Sprintf(", \"%s\", (void*)", propertyName))
+				gen.generateSlotValue(node.Children[1], valueType)
+				gen.output.WriteString(fmt.Sprintf(", %s);\n", gen.getAhoyTypeEnum(valueType)))
+				return
+			}
+		}
+
+		// For struct field/array/pointer access, direct assignment works
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" = ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(";\n")
+		return
+	}
+
+	// Check if variable has been actually declared in C code
+	// Check both global and function-local scopes
+	inFunction := gen.currentFunction != ""
+	_, isDeclaredGlobal := gen.declaredGlobalVars[node.Value]
+	_, isDeclaredLocal := gen.declaredFunctionVars[node.Value]
+	isDeclared := isDeclaredGlobal || isDeclaredLocal
+	isNestedScope := gen.nestedScopeVars[node.Value]
+
+	valueNode := node.Children[0]
+
+	// Special case: Variables from nested scopes or array/dict access can be redeclared
+	isLoopLocalPattern := valueNode.Type == NODE_ARRAY_ACCESS || valueNode.Type == NODE_DICT_ACCESS
+	canRedeclare := isLoopLocalPattern || (isNestedScope && gen.indent > 1)
+
+	if isDeclared && !canRedeclare {
+		// Just assignment
+		if valueNode.Type == NODE_SWITCH_STATEMENT {
+			// Generate switch as expression (assign in each case)
+			gen.generateSwitchExpression(valueNode, node.Value)
+		} else {
+			target := gen.valueEnum(&ASTNode{Type: NODE_IDENTIFIER, Value: node.Value})
+			if other := gen.valueEnum(valueNode); target != "" && other != "" && other != target {
+				gen.reportError(node.Line, fmt.Sprintf("%s holds %s values, it can't be given a %s value", node.Value, target, other),
+					"convert it with int|...| first if that's intended")
+			}
+			gen.output.WriteString(fmt.Sprintf("%s = ", node.Value))
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(";\n")
+		}
+	} else {
+		// Type inference and declaration
+		valueNode := node.Children[0]
+
+		// Fixed-size arrays are plain C arrays on the stack
+		if valueNode.Type == NODE_FIXED_ARRAY {
+			gen.generateFixedArrayDeclaration(node.Value, valueNode)
+			return
+		}
+
+		// Check if we have an explicit type annotation
+		explicitType := node.DataType
+
+		// Special handling for object literals - they define their own type inline
+		if valueNode.Type == NODE_OBJECT_LITERAL {
+			// Check if this is a typed struct literal (e.g., rectangle<...>)
+			if valueNode.Value != "" {
+				// Use the C struct type name (capitalize first letter)
+				structName := capitalizeFirst(valueNode.Value)
+				gen.writeVariableType(structName, node.Value)
+				gen.output.WriteString(fmt.Sprintf("%s = ", node.Value))
+				gen.generateNode(valueNode)
+				gen.output.WriteString(";\n")
+
+				// Track variable in appropriate scope
+				if gen.currentFunction != "" && gen.functionVars != nil {
+					gen.functionVars[node.Value] = valueNode.Value
+				} else {
+					gen.variables[node.Value] = valueNode.Value
+				}
+				// Mark as declared
+				if inFunction {
+					gen.declaredFunctionVars[node.Value] = true
+				} else {
+					gen.declaredGlobalVars[node.Value] = true
+				}
+			} else {
+				// Anonymous struct - create a named struct for it
+				anonStructName := fmt.Sprintf("__anon_struct_%s", node.Value)
+
+				// Generate typedef struct with the other struct declarations
+				gen.structDecls.WriteString(fmt.Sprintf("typedef struct {\n"))
+
+				// Track struct fields
+				structInfo := &StructInfo{
+					Name:   anonStructName,
+					Fields: make([]StructFieldInfo, 0),
+				}
+
+				for _, prop := range valueNode.Children {
+					if prop.Type == NODE_OBJECT_PROPERTY {
+						propType := gen.inferType(prop.Children[0])
+						cType := gen.mapType(propType)
+						gen.structDecls.WriteString(fmt.Sprintf("    %s %s;\n", cType, prop.Value))
+
+						structInfo.Fields = append(structInfo.Fields, StructFieldInfo{
+							Name: prop.Value,
+							Type: cType,
+						})
+					}
+				}
+				gen.structDecls.WriteString(fmt.Sprintf("} %s;\n\n", anonStructName))
+				gen.structs[anonStructName] = structInfo
+
+				// Generate variable declaration
+				gen.writeVariableType(anonStructName, node.Value)
+				gen.output.WriteString(fmt.Sprintf("%s = ", node.Value))
+				gen.generateNode(valueNode)
+				gen.output.WriteString(";\n")
+
+				// Track variable in appropriate scope
+				if gen.currentFunction != "" && gen.functionVars != nil {
+					gen.functionVars[node.Value] = anonStructName
+				} else {
+					gen.variables[node.Value] = anonStructName
+				}
+				// Mark as declared
+				if inFunction {
+					gen.declaredFunctionVars[node.Value] = true
+				} else {
+					gen.declaredGlobalVars[node.Value] = true
+				}
+			}
+		} else {
+			varType := gen.inferType(valueNode)
+
+			// Use explicit type if provided, otherwise infer
+			if explicitType != "" {
+				varType = explicitType
+			}
+
+			// Track variable in appropriate scope
+			if gen.currentFunction != "" && gen.functionVars != nil {
+				// Inside a function - use function scope
+				gen.functionVars[node.Value] = varType
+				// Mark as nested scope if indent > 1 (inside loops/ifs)
+				if gen.indent > 1 {
+					gen.nestedScopeVars[node.Value] = true
+				}
+			} else {
+				// Global scope
+				gen.variables[node.Value] = varType
+			}
+			// Remember the enum of variables declared with a member, an enum
+			// conversion or another enum variable, so switches on them can
+			// use bare member names, other enums' values can't be mixed in
+			// and print shows them by name
+			if enumName := gen.valueEnum(valueNode); explicitType == "" && enumName != "" {
+				gen.enumVars[node.Value] = enumName
+			} else {
+				delete(gen.enumVars, node.Value)
+			}
+			// Mark as declared
+			if gen.currentFunction != "" {
+				gen.declaredFunctionVars[node.Value] = true
+			} else {
+				gen.declaredGlobalVars[node.Value] = true
+			}
+
+			// Untyped dict literals whose values share a type pass it on to values||
+			if valueType := gen.dictLiteralValueType(valueNode); explicitType == "" && valueType != "" {
+				gen.dictValueTypes[node.Value] = valueType
+			} else {
+				delete(gen.dictValueTypes, node.Value)
+			}
+
+			// If this is an array literal with typed annotation, track the element type
+			if valueNode.Type == NODE_ARRAY_LITERAL {
+				if explicitType != "" && strings.HasPrefix(explicitType, "array[") {
+					// Extract element type from array[type]
+					elemType := strings.TrimSuffix(strings.TrimPrefix(explicitType, "array["), "]")
+					gen.arrayElementTypes[node.Value] = elemType
+					// Set context for array literal generation
+					gen.currentTypeContext = explicitType
+				} else if len(valueNode.Children) > 0 {
+					elemType := gen.inferType(valueNode.Children[0])
+					gen.arrayElementTypes[node.Value] = elemType
+				}
+			}
+
+			// A clone holds the same elements and values as its source
+			if valueNode.Type == NODE_METHOD_CALL && valueNode.Value == "clone" && len(valueNode.Children) > 0 && valueNode.Children[0].Type == NODE_IDENTIFIER {
+				source := valueNode.Children[0].Value
+				if elemType, ok := gen.arrayElementTypes[source]; ok {
+					gen.arrayElementTypes[node.Value] = elemType
+				}
+				if valueType := gen.dictValueTypes[source]; explicitType == "" && valueType != "" {
+					gen.dictValueTypes[node.Value] = valueType
+				}
+			}
+
+			cType := gen.mapType(varType)
+
+			// Check if value is a switch expression
+			if valueNode.Type == NODE_SWITCH_STATEMENT {
+				// Generate switch as expression (assign in each case)
+				gen.declareVariable(cType, node.Value)
+				gen.generateSwitchExpression(valueNode, node.Value)
+			} else {
+				gen.writeVariableType(cType, node.Value)
+				gen.output.WriteString(fmt.Sprintf("%s = ", node.Value))
+				gen.generateNode(valueNode)
+				gen.output.WriteString(";\n")
+			}
+
+			// Track if this variable came from dict access
+			if valueNode.Type == NODE_DICT_ACCESS {
+				gen.dictSourcedVars[node.Value] = valueNode.Value // dict name
+				if len(valueNode.Children) > 0 && valueNode.Children[0].Type == NODE_STRING {
+					gen.dictSourcedKeys[node.Value] = valueNode.Children[0].Value // key
+				}
+			}
+
+			// Clear type context after generation
+			gen.currentTypeContext = ""
+		}
+	}
+}
+
+func (gen *CodeGenerator) generateIfStatement(node *ASTNode) {
+	gen.writeIndent()
+	gen.output.WriteString("if (")
+	gen.generateNode(node.Children[0])
+	gen.output.WriteString(") {\n")
+
+	gen.indent++
+	gen.generateNodeInternal(node.Children[1], false)
+	gen.indent--
+
+	gen.writeIndent()
+	gen.output.WriteString("}")
+
+	// Handle elseif and else
+	i := 2
+	for i < len(node.Children) {
+		// Check if this is an else (no condition)
+		if i == len(node.Children)-1 {
+			// Last child is else body
+			gen.output.WriteString(" else {\n")
+			gen.indent++
+			gen.generateNodeInternal(node.Children[i], false)
+			gen.indent--
+			gen.writeIndent()
+			gen.output.WriteString("}")
+			break
+		} else {
+			// Elseif: condition and body pair
+			gen.output.WriteString(" else if (")
+			gen.generateNode(node.Children[i])
+			gen.output.WriteString(") {\n")
+			gen.indent++
+			gen.generateNodeInternal(node.Children[i+1], false)
+			gen.indent--
+			gen.writeIndent()
+			gen.output.WriteString("}")
+			i += 2
+		}
+	}
+
+	gen.output.WriteString("\n")
+}
+
+// generateSwitchExpression generates a switch that assigns to a variable (expression context)
+func (gen *CodeGenerator) generateSwitchExpression(node *ASTNode, targetVar string) {
+	if node.Value == "has" {
+		gen.reportError(node.Line, "switch has runs statements; it doesn't give back a value",
+			"assign to "+targetVar+" inside each case instead")
+		return
+	}
+	gen.checkSwitchExpressionType(node, targetVar)
+	switchExpr := node.Children[0]
+	switchExprType := gen.inferType(switchExpr)
+
+	// Check if this is a string switch - need to use if-else with strcmp
+	if switchExprType == "char*" || switchExprType == "string" || gen.isStringEnumSwitch(switchExpr) {
+		gen.generateStringSwitchExpression(node, targetVar)
+		return
+	}
+
+	if gen.generateLookupSwitch(node, targetVar) {
+		return
+	}
+
+	// Generate normal switch with assignments in each case
+	enumName := gen.valueEnum(switchExpr)
+	gen.checkSwitchCases(node, enumName)
+	gen.writeIndent()
+	gen.output.WriteString("switch (")
+	gen.generateNode(switchExpr)
+	gen.output.WriteString(") {\n")
+
+	// Generate cases
+	var deferredCases []*ASTNode
+	for i := 1; i < len(node.Children); i++ {
+		caseNode := node.Children[i]
+		if caseNode.Type == NODE_SWITCH_CASE {
+			caseValue := caseNode.Children[0]
+			caseBody := caseNode.Children[1]
+
+			// Check if it's a list of cases or range
+			if caseValue.Type == NODE_SWITCH_CASE_LIST {
+				// Multiple cases
+				for _, val := range caseValue.Children {
+					gen.indent++
+					gen.writeIndent()
+					gen.output.WriteString("case ")
+					gen.generateSwitchCaseLabel(val, enumName)
+					gen.output.WriteString(":\n")
+					gen.indent--
+				}
+				gen.indent++
+				gen.indent++
+				gen.generateSwitchCaseAssignment(caseBody, targetVar)
+				gen.writeIndent()
+				gen.output.WriteString("break;\n")
+				gen.indent--
+				gen.indent--
+			} else if gen.isRangeCase(caseValue) || isDefaultCase(caseValue) {
+				// Range and default cases share the default label, after the loop
+				deferredCases = append(deferredCases, caseNode)
+			} else {
+				// Single case
+				gen.indent++
+				gen.writeIndent()
+				gen.output.WriteString("case ")
+				gen.generateSwitchCaseLabel(caseValue, enumName)
+				gen.output.WriteString(":\n")
+
+				gen.indent++
+				gen.generateSwitchCaseAssignment(caseBody, targetVar)
+				gen.writeIndent()
+				gen.output.WriteString("break;\n")
+				gen.indent--
+				gen.indent--
+			}
+		}
+	}
+	gen.generateSwitchDefault(switchExpr, deferredCases, func(body *ASTNode) {
+		gen.generateSwitchCaseAssignment(body, targetVar)
+	})
+
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
+
+// isDefaultCase reports whether a switch case value is the _ default
+func isDefaultCase(caseValue *ASTNode) bool {
+	return caseValue.Type == NODE_IDENTIFIER && caseValue.Value == "_"
+}
+
+// isRangeCase reports whether a switch case value is start to end or a
+// range value
+func (gen *CodeGenerator) isRangeCase(caseValue *ASTNode) bool {
+	return caseValue.Type == NODE_SWITCH_CASE_RANGE || gen.inferType(caseValue) == "range"
+}
+
+// generateSwitchDefault writes the single default label of a C switch. Range
+// cases can't be case labels, so they are tested here in order before the
+// body of the _ case, if any.
+func (gen *CodeGenerator) generateSwitchDefault(switchExpr *ASTNode, cases []*ASTNode, generateBody func(body *ASTNode)) {
+	if len(cases) == 0 {
+		return
+	}
+	gen.indent++
+	gen.writeIndent()
+	gen.output.WriteString("default:\n")
+	gen.indent++
+	var defaultCase *ASTNode
+	for _, caseNode := range cases {
+		if isDefaultCase(caseNode.Children[0]) {
+			defaultCase = caseNode
+			continue
+		}
+		gen.writeIndent()
+		gen.output.WriteString("if (")
+		gen.generateRangeCaseCondition(switchExpr, caseNode.Children[0])
+		gen.output.WriteString(") {\n")
+		gen.indent++
+		generateBody(caseNode.Children[1])
+		gen.writeIndent()
+		gen.output.WriteString("break;\n")
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+	if defaultCase != nil {
+		generateBody(defaultCase.Children[1])
+	}
+	gen.writeIndent()
+	gen.output.WriteString("break;\n")
+	gen.indent--
+	gen.indent--
+}
+
+// generateRangeCaseCondition writes the test for a range case: either
+// start to end, which includes the end, or a range value
+func (gen *CodeGenerator) generateRangeCaseCondition(switchExpr *ASTNode, caseValue *ASTNode) {
+	if caseValue.Type != NODE_SWITCH_CASE_RANGE {
+		gen.output.WriteString("ahoy_range_has(")
+		gen.generateNode(caseValue)
+		gen.output.WriteString(", ")
+		gen.generateNode(switchExpr)
+		gen.output.WriteString(")")
+		return
+	}
+	gen.generateNode(switchExpr)
+	gen.output.WriteString(" >= ")
+	gen.generateNode(caseValue.Children[0]) // Start
+	gen.output.WriteString(" && ")
+	gen.generateNode(switchExpr)
+	gen.output.WriteString(" <= ")
+	gen.generateNode(caseValue.Children[1]) // End
+}
+
+// generateSwitchCaseAssignment generates an assignment for a case body
+func (gen *CodeGenerator) generateSwitchCaseAssignment(caseBody *ASTNode, targetVar string) {
+	// Check if body is a block with multiple statements
+	if caseBody.Type == NODE_BLOCK && len(caseBody.Children) > 0 {
+		// Execute all statements except last, then assign last
+		for i := 0; i < len(caseBody.Children)-1; i++ {
+			gen.generateNodeInternal(caseBody.Children[i], true)
+		}
+		// Last statement is the return value
+		lastStmt := caseBody.Children[len(caseBody.Children)-1]
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s = ", targetVar))
+		gen.generateNode(lastStmt)
+		gen.output.WriteString(";\n")
+	} else {
+		// Single expression
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s = ", targetVar))
+		gen.generateNode(caseBody)
+		gen.output.WriteString(";\n")
+	}
+}
+
+// generateStringSwitchExpression generates if-else chain for string switches
+func (gen *CodeGenerator) generateStringSwitchExpression(node *ASTNode, targetVar string) {
+	gen.checkStringSwitchCases(node)
+	if gen.generateStringHashSwitch(node, func(body *ASTNode) { gen.generateSwitchCaseAssignment(body, targetVar) }) {
+		return
+	}
+	switchExpr := node.Children[0]
+	enumName := gen.valueEnum(switchExpr)
+
+	first := true
+	hasDefault := false
+	var defaultBody *ASTNode
+
+	for i := 1; i < len(node.Children); i++ {
+		caseNode := node.Children[i]
+		if caseNode.Type == NODE_SWITCH_CASE {
+			caseValue := caseNode.Children[0]
+			caseBody := caseNode.Children[1]
+
+			// Check for default case
+			if caseValue.Type == NODE_IDENTIFIER && caseValue.Value == "_" {
+				hasDefault = true
+				defaultBody = caseBody
+				continue
+			}
+
+			gen.writeIndent()
+			if first {
+				gen.output.WriteString("if (")
+				first = false
+			} else {
+				gen.output.WriteString("else if (")
+			}
+
+			// Handle multiple cases
+			if caseValue.Type == NODE_SWITCH_CASE_LIST {
+				for j, val := range caseValue.Children {
+					if j > 0 {
+						gen.output.WriteString(" || ")
+					}
+					gen.output.WriteString("strcmp(")
+					gen.generateNode(switchExpr)
+					gen.output.WriteString(", ")
+					gen.generateSwitchCaseLabel(val, enumName)
+					gen.output.WriteString(") == 0")
+				}
+			} else {
+				gen.output.WriteString("strcmp(")
+				gen.generateNode(switchExpr)
+				gen.output.WriteString(", ")
+				gen.generateSwitchCaseLabel(caseValue, enumName)
+				gen.output.WriteString(") == 0")
+			}
+
+			gen.output.WriteString(") {\n")
+			gen.indent++
+			gen.generateSwitchCaseAssignment(caseBody, targetVar)
+			gen.indent--
+			gen.writeIndent()
+			gen.output.WriteString("}")
+		}
+	}
+
+	// Handle default case
+	if hasDefault {
+		if !first {
+			gen.output.WriteString(" else {\n")
+		} else {
+			gen.writeIndent()
+			gen.output.WriteString("{\n")
+		}
+		gen.indent++
+		gen.generateSwitchCaseAssignment(defaultBody, targetVar)
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else if !first {
+		gen.output.WriteString("\n")
+	}
+}
+
+// generateStringSwitchStatement generates if-else chain for string/char switches in statement context
+func (gen *CodeGenerator) generateStringSwitchStatement(node *ASTNode) {
+	gen.checkStringSwitchCases(node)
+	switchExpr := node.Children[0]
+	switchExprType := gen.inferType(switchExpr)
+	if switchExprType != "char" && gen.generateStringHashSwitch(node, func(body *ASTNode) { gen.generateNodeInternal(body, true) }) {
+		return
+	}
+	enumName := gen.valueEnum(switchExpr)
+
+	first := true
+	hasDefault := false
+	var defaultBody *ASTNode
+
+	for i := 1; i < len(node.Children); i++ {
+		caseNode := node.Children[i]
+		if caseNode.Type == NODE_SWITCH_CASE {
+			caseValue := caseNode.Children[0]
+			caseBody := caseNode.Children[1]
+
+			// Check for default case
+			if caseValue.Type == NODE_IDENTIFIER && caseValue.Value == "_" {
+				hasDefault = true
+				defaultBody = caseBody
+				continue
+			}
+
+			gen.writeIndent()
+			if first {
+				gen.output.WriteString("if (")
+				first = false
+			} else {
+				gen.output.WriteString("else if (")
+			}
+
+			// Handle multiple cases
+			if caseValue.Type == NODE_SWITCH_CASE_LIST {
+				for j, val := range caseValue.Children {
+					if j > 0 {
+						gen.output.WriteString(" || ")
+					}
+					// For char type, use direct comparison
+					if switchExprType == "char" {
+						gen.generateNode(switchExpr)
+						gen.output.WriteString(" == ")
+						gen.generateNode(val)
+					} else {
+						// For string type, use strcmp
+						gen.output.WriteString("strcmp(")
+						gen.generateNode(switchExpr)
+						gen.output.WriteString(", ")
+						gen.generateSwitchCaseLabel(val, enumName)
+						gen.output.WriteString(") == 0")
+					}
+				}
+			} else {
+				// Single case value
+				if switchExprType == "char" {
+					gen.generateNode(switchExpr)
+					gen.output.WriteString(" == ")
+					gen.generateNode(caseValue)
+				} else {
+					gen.output.WriteString("strcmp(")
+					gen.generateNode(switchExpr)
+					gen.output.WriteString(", ")
+					gen.generateSwitchCaseLabel(caseValue, enumName)
+					gen.output.WriteString(") == 0")
+				}
+			}
+
+			gen.output.WriteString(") {\n")
+			gen.indent++
+			gen.generateNodeInternal(caseBody, true) // Case body
+			gen.indent--
+			gen.writeIndent()
+			gen.output.WriteString("}")
+		}
+	}
+
+	// Handle default case
+	if hasDefault {
+		if !first {
+			gen.output.WriteString(" else {\n")
+		} else {
+			gen.writeIndent()
+			gen.output.WriteString("{\n")
+		}
+		gen.indent++
+		gen.generateNodeInternal(defaultBody, true) // Default body
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else if !first {
+		gen.output.WriteString("\n")
+	}
+}
+
+// hashSwitchCases is how many string cases a switch needs before it
+// dispatches on a hash of the subject instead of comparing it with each case
+const hashSwitchCases = 8
+
+// stringSwitchHash is djb2, as ahoy_switch_hash computes it at runtime
+func stringSwitchHash(s string) uint32 {
+	hash := uint32(5381)
+	for i := 0; i < len(s); i++ {
+		hash = hash*33 + uint32(s[i])
+	}
+	return hash
+}
+
+// generateStringHashSwitch generates a string switch with more than
+// hashSwitchCases string literal cases as a C switch on the subject's hash.
+// Cases whose hashes collide are told apart with strcmp in source order.
+// The bodies follow the C switch and are reached with goto, so halt and next
+// in them still act on the enclosing loop. Returns false, generating
+// nothing, for other switches.
+func (gen *CodeGenerator) generateStringHashSwitch(node *ASTNode, generateBody func(body *ASTNode)) bool {
+	type hashCase struct {
+		label string
+		body  int
+	}
+	var hashes []uint32
+	buckets := map[uint32][]hashCase{}
+	var bodies []*ASTNode
+	var defaultBody *ASTNode
+	count := 0
+	for _, caseNode := range node.Children[1:] {
+		if caseNode.Type != NODE_SWITCH_CASE {
+			continue
+		}
+		caseValue, caseBody := caseNode.Children[0], caseNode.Children[1]
+		if isDefaultCase(caseValue) {
+			if defaultBody == nil {
+				defaultBody = caseBody
+			}
+			continue
+		}
+		values := []*ASTNode{caseValue}
+		if caseValue.Type == NODE_SWITCH_CASE_LIST {
+			values = caseValue.Children
+		}
+		for _, value := range values {
+			if value.Type != NODE_STRING {
+				return false
+			}
+			hash := stringSwitchHash(unescapeC(value.Value))
+			if _, seen := buckets[hash]; !seen {
+				hashes = append(hashes, hash)
+			}
+			buckets[hash] = append(buckets[hash], hashCase{label: value.Value, body: len(bodies)})
+			count++
+		}
+		bodies = append(bodies, caseBody)
+	}
+	if count <= hashSwitchCases {
+		return false
+	}
+	gen.useSwitchHash = true
+
+	id := gen.varCounter
+	gen.varCounter++
+	subject := fmt.Sprintf("__switch_%d", id)
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("const char* %s = ", subject))
+	gen.generateNode(node.Children[0])
+	gen.output.WriteString(";\n")
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("switch (ahoy_switch_hash(%s)) {\n", subject))
+	gen.indent++
+	for _, hash := range hashes {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("case %du:\n", hash))
+		gen.indent++
+		for _, c := range buckets[hash] {
+			gen.writeIndent()
+			gen.output.WriteString(fmt.Sprintf("if (strcmp(%s, \"%s\") == 0) goto %s_case_%d;\n", subject, c.label, subject, c.body))
+		}
+		gen.writeIndent()
+		gen.output.WriteString("break;\n")
+		gen.indent--
+	}
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+
+	// No case matched if the switch falls through to here
+	if defaultBody != nil {
+		gen.writeIndent()
+		gen.output.WriteString("{\n")
+		gen.indent++
+		generateBody(defaultBody)
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+	for i, body := range bodies {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("goto %s_end;\n", subject))
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s_case_%d: {\n", subject, i))
+		gen.indent++
+		generateBody(body)
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s_end:;\n", subject))
+	return true
+}
+
+// valueEnum returns the enum a value such as a switch subject belongs to,
+// either through member access (enum.member), a bare member name, an enum
+// conversion (enum|n|) or a variable declared with the enum type. Returns ""
+// when the value is not an enum value.
+func (gen *CodeGenerator) valueEnum(expr *ASTNode) string {
+	if expr.Type == NODE_MEMBER_ACCESS && len(expr.Children) > 0 {
+		object := expr.Children[0]
+		if object.Type == NODE_IDENTIFIER && gen.isEnumType(object.Value) {
+			return object.Value
+		}
+		return ""
+	}
+	if expr.Type == NODE_CALL && gen.isEnumType(expr.Value) && !gen.userFunctions[expr.Value] {
+		return expr.Value
+	}
+	if expr.Type == NODE_IDENTIFIER {
+		if varType, exists := gen.functionVars[expr.Value]; exists && gen.isEnumType(varType) {
+			return varType
+		}
+		if varType, exists := gen.variables[expr.Value]; exists && gen.isEnumType(varType) {
+			return varType
+		}
+		if enumName := gen.enumVars[expr.Value]; enumName != "" || gen.isVariable(expr.Value) || gen.constants[expr.Value] {
+			return enumName
+		}
+		if candidates := gen.enumsWithMember(expr.Value); len(candidates) == 1 {
+			return candidates[0]
+		}
+	}
+	return ""
+}
+
+// isStringEnumSwitch reports whether the switch subject is a member of a
+// string enum, which must be compared with strcmp rather than a C switch.
+func (gen *CodeGenerator) isStringEnumSwitch(expr *ASTNode) bool {
+	enumName := gen.valueEnum(expr)
+	return enumName != "" && gen.enumTypes[enumName] == "string"
+}
+
+// generateSwitchCaseLabel generates a case value, qualifying bare member
+// names (on jared:) with the enum of the switch subject. That enum decides,
+// so members other enums share aren't ambiguous here.
+func (gen *CodeGenerator) generateSwitchCaseLabel(val *ASTNode, enumName string) {
+	if enumName != "" && val.Type == NODE_IDENTIFIER && gen.enums[enumName][val.Value] {
+		if gen.enumTypes[enumName] == "int" || gen.enumTypes[enumName] == "flags" {
+			gen.output.WriteString(enumName + "_" + val.Value)
+			return
+		}
+		gen.output.WriteString(enumName)
+		gen.output.WriteString(".")
+		gen.output.WriteString(val.Value)
+		return
+	}
+	gen.generateNode(val)
+}
+
+func (gen *CodeGenerator) generateSwitchStatement(node *ASTNode) {
+	if node.Value == "has" {
+		gen.generateSwitchHas(node)
+		return
+	}
+	switchExpr := node.Children[0]
+	switchExprType := gen.inferType(switchExpr)
+
+	// Check if this is a string or char switch - need to use if-else
+	if switchExprType == "char*" || switchExprType == "string" || switchExprType == "char" || gen.isStringEnumSwitch(switchExpr) {
+		gen.generateStringSwitchStatement(node)
+		return
+	}
+
+	// Generate normal C switch statement for integers
+	enumName := gen.valueEnum(switchExpr)
+	gen.checkSwitchCases(node, enumName)
+	gen.writeIndent()
+	gen.output.WriteString("switch (")
+	gen.generateNode(node.Children[0]) // Generate switch expression
+	gen.output.WriteString(") {\n")
+
+	// Generate cases (skip first child which is the switch expression)
+	var deferredCases []*ASTNode
+	for i := 1; i < len(node.Children); i++ {
+		caseNode := node.Children[i]
+		if caseNode.Type == NODE_SWITCH_CASE {
+			caseValue := caseNode.Children[0]
+
+			// Check if it's a list of cases or a range
+			if caseValue.Type == NODE_SWITCH_CASE_LIST {
+				// Multiple cases - generate multiple case labels
+				for _, val := range caseValue.Children {
+					gen.indent++
+					gen.writeIndent()
+					gen.output.WriteString("case ")
+					gen.generateSwitchCaseLabel(val, enumName)
+					gen.output.WriteString(":\n")
+					gen.indent--
+				}
+				// Generate body after all case labels
+				gen.indent++
+				gen.indent++
+				gen.generateNodeInternal(caseNode.Children[1], true) // Case body
+				gen.writeIndent()
+				gen.output.WriteString("break;\n")
+				gen.indent--
+				gen.indent--
+			} else if gen.isRangeCase(caseValue) || isDefaultCase(caseValue) {
+				// Range and default cases share the default label, after the loop
+				deferredCases = append(deferredCases, caseNode)
+			} else {
+				// Single case value
+				gen.indent++
+				gen.writeIndent()
+				gen.output.WriteString("case ")
+				gen.generateSwitchCaseLabel(caseValue, enumName)
+				gen.output.WriteString(":\n")
+
+				gen.indent++
+				gen.generateNodeInternal(caseNode.Children[1], true) // Case body
+				gen.writeIndent()
+				gen.output.WriteString("break;\n")
+				gen.indent--
+				gen.indent--
+			}
+		}
+	}
+	gen.generateSwitchDefault(switchExpr, deferredCases, func(body *ASTNode) {
+		gen.generateNodeInternal(body, true)
+	})
+
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
+
+func (gen *CodeGenerator) generateWhenStatement(node *ASTNode) {
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("#ifdef %s\n", node.Value))
+
+	gen.indent++
+	gen.generateNodeInternal(node.Children[0], false)
+	gen.indent--
+
+	gen.writeIndent()
+	gen.output.WriteString("#endif\n")
+}
+
+func (gen *CodeGenerator) generateWhileLoop(node *ASTNode) {
+	gen.writeIndent()
+
+	// Check if we have an explicit loop variable with initialization
+	// Pattern 1: Children[0] is loop var, Children[1] is start, Children[2] is condition, Children[3] is body (loop i:start till condition)
+	// Pattern 2: Children[0] is loop var, Children[1] is start (0), Children[2] is condition, Children[3] is body (loop i till condition)
+	// Pattern 3: Children[0] is condition, Children[1] is body (loop till condition)
+	var loopVar string
+	var conditionNode *ASTNode
+	var bodyNode *ASTNode
+
+	if len(node.Children) == 4 && node.Children[0].Type == NODE_IDENTIFIER {
+		// Pattern 1 or 2: loop i:start till condition or loop i till condition
+		loopVar = node.Children[0].Value
+		startNode := node.Children[1]
+		conditionNode = node.Children[2]
+		bodyNode = node.Children[3]
+
+		// Create block scope for loop variable
+		gen.output.WriteString("{\n")
+		gen.indent++
+		gen.writeIndent()
+
+		// Initialize loop variable with start value
+		gen.output.WriteString(fmt.Sprintf("int %s = ", loopVar))
+		gen.generateNode(startNode)
+		gen.output.WriteString(";\n")
+		gen.writeIndent()
+	} else if len(node.Children) == 3 && node.Children[0].Type == NODE_IDENTIFIER {
+		// Old syntax: loop i till condition (without start value)
+		loopVar = node.Children[0].Value
+		conditionNode = node.Children[1]
+		bodyNode = node.Children[2]
+
+		// Create block scope for loop variable
+		gen.output.WriteString("{\n")
+		gen.indent++
+		gen.writeIndent()
+
+		// Initialize loop variable to 0
+		gen.output.WriteString(fmt.Sprintf("int %s = 0;\n", loopVar))
+		gen.writeIndent()
+	} else {
+		// Pattern 3: loop till condition (no loop variable)
+		conditionNode = node.Children[0]
+		bodyNode = node.Children[1]
+	}
+
+	gen.output.WriteString("while (")
+	gen.generateNode(conditionNode)
+	gen.output.WriteString(") {\n")
+
+	gen.indent++
+	gen.generateNodeInternal(bodyNode, false)
+
+	// Increment loop variable if present
+	if loopVar != "" {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s++;\n", loopVar))
+	}
+
+	gen.indent--
+
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+
+	// Close block scope if we created one
+	if loopVar != "" {
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+}
+
+func (gen *CodeGenerator) generateForRangeLoop(node *ASTNode) {
+	gen.writeIndent()
+
+	var loopVar string
+
+	// Multiple patterns:
+	// 1. Constant range: Value has start, DataType has end, Children[0] is body (old syntax)
+	// 2. Variable range: Children[0] is start, Children[1] is end, Children[2] is body (old syntax)
+	// 3. New syntax: Children[0] is loop var, Children[1] is start, Children[2] is end, Children[3] is body
+
+	if len(node.Children) == 4 && node.Children[0].Type == NODE_IDENTIFIER {
+		// Pattern 3: New syntax (loop i from 1 to 5 or loop i to 5)
+		loopVar = node.Children[0].Value
+
+		gen.output.WriteString(fmt.Sprintf("for (int %s = ", loopVar))
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(fmt.Sprintf("; %s < ", loopVar))
+		gen.generateNode(node.Children[2])
+		gen.output.WriteString(fmt.Sprintf("; %s++) {\n", loopVar))
+
+		gen.indent++
+		gen.generateNodeInternal(node.Children[3], false)
+		gen.indent--
+	} else {
+		// Old patterns - generate anonymous loop variable
+		loopVar = fmt.Sprintf("__loop_i_%d", gen.varCounter)
+		gen.varCounter++
+
+		// Push loop counter onto stack
+		gen.loopCounters = append(gen.loopCounters, loopVar)
+
+		if len(node.Children) == 1 {
+			// Pattern 1: Constant range (loop:0 to 10)
+			startVal := node.Value
+			endVal := node.DataType
+
+			gen.output.WriteString(fmt.Sprintf("for (int %s = %s; %s <= %s; %s++) {\n",
+				loopVar, startVal, loopVar, endVal, loopVar))
+
+			gen.indent++
+			gen.generateNodeInternal(node.Children[0], false)
+			gen.indent--
+		} else {
+			// Pattern 2: Variable range (loop:start to end)
+			gen.output.WriteString(fmt.Sprintf("for (int %s = ", loopVar))
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(fmt.Sprintf("; %s <= ", loopVar))
+			gen.generateNode(node.Children[1])
+			gen.output.WriteString(fmt.Sprintf("; %s++) {\n", loopVar))
+
+			gen.indent++
+			gen.generateNodeInternal(node.Children[2], false)
+			gen.indent--
+		}
+
+		// Pop loop counter from stack
+		gen.loopCounters = gen.loopCounters[:len(gen.loopCounters)-1]
+	}
+
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
+
+func (gen *CodeGenerator) generateForLoop(node *ASTNode) {
+	// For now, treat it like ForCountLoop
+	gen.generateForCountLoop(node)
+}
+
+func (gen *CodeGenerator) generateForCountLoop(node *ASTNode) {
+	gen.writeIndent()
+
+	// Check patterns:
+	// Pattern 1: Children[0] is identifier, Children[1] is start value, Children[2] is body (loop i:start:)
+	// Pattern 2: Children[0] is identifier, Children[1] is start (0), Children[2] is body (loop i: or loop i do)
+	// Pattern 3: Children[0] is body only (loop: or loop do - infinite loop without variable)
+
+	if len(node.Children) == 3 && node.Children[0].Type == NODE_IDENTIFIER {
+		// Pattern 1 or 2: loop i:start: (forever loop with explicit variable and start value)
+		loopVar := node.Children[0].Value
+
+		// Use block scope to avoid variable redeclaration
+		gen.output.WriteString("{\n")
+		gen.indent++
+		gen.writeIndent()
+
+		gen.output.WriteString(fmt.Sprintf("int %s = ", loopVar))
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(";\n")
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("for (; ; %s++) {\n", loopVar))
+
+		gen.indent++
+		gen.generateNodeInternal(node.Children[2], false)
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else if len(node.Children) == 2 && node.Children[0].Type == NODE_IDENTIFIER {
+		// Old pattern: loop i do (forever loop with explicit variable starting at 0)
+		loopVar := node.Children[0].Value
+
+		// Use block scope to avoid variable redeclaration
+		gen.output.WriteString("{\n")
+		gen.indent++
+		gen.writeIndent()
+
+		gen.output.WriteString(fmt.Sprintf("int %s = 0;\n", loopVar))
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("for (; ; %s++) {\n", loopVar))
+
+		gen.indent++
+		gen.generateNodeInternal(node.Children[1], false)
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else if len(node.Children) == 1 || node.Value == "0" {
+		// Pattern 3: Forever loop without explicit variable (loop: or loop do)
+		gen.output.WriteString("for (;;) {\n")
+
+		gen.indent++
+		gen.generateNodeInternal(node.Children[0], false)
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else {
+		// Old syntax: standard for loop with init/condition/update
+		gen.output.WriteString("for (")
+
+		// Init - with variable declaration
+		gen.generateAssignmentForFor(node.Children[0])
+		gen.output.WriteString("; ")
+
+		// Condition
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString("; ")
+
+		// Update - without variable declaration, just assignment
+		gen.generateAssignmentForUpdate(node.Children[2])
+
+		gen.output.WriteString(") {\n")
+
+		gen.indent++
+		gen.generateNodeInternal(node.Children[3], false)
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+}
+
+func (gen *CodeGenerator) generateAssignmentForFor(node *ASTNode) {
+	if node.Type == NODE_ASSIGNMENT {
+		// Type inference
+		valueNode := node.Children[0]
+		varType := gen.inferType(valueNode)
+		gen.variables[node.Value] = varType
+
+		cType := gen.mapType(varType)
+		gen.output.WriteString(fmt.Sprintf("%s %s = ", cType, node.Value))
+		gen.generateNode(valueNode)
+	}
+}
+
+func (gen *CodeGenerator) generateAssignmentForUpdate(node *ASTNode) {
+	if node.Type == NODE_ASSIGNMENT {
+		// Just assignment, no declaration
+		gen.output.WriteString(fmt.Sprintf("%s = ", node.Value))
+		gen.generateNode(node.Children[0])
+	}
+}
+
+func (gen *CodeGenerator) generateForInArrayLoop(node *ASTNode) {
+	gen.writeIndent()
+
+	// node.Children[0] is element variable name
+	// node.Children[1] is array/string expression
+	// node.Children[2] is body
+
+	elementVar := node.Children[0].Value
+	iterableExpr := node.Children[1]
+
+	// Check if we're iterating over a string
+	iterableType := gen.inferType(iterableExpr)
+
+	if iterableType == "char*" || iterableType == "string" {
+		// String iteration - iterate over characters
+		iterableName := gen.nodeToString(iterableExpr)
+		loopVar := fmt.Sprintf("__loop_i_%d", gen.varCounter)
+		gen.varCounter++
+
+		gen.output.WriteString(fmt.Sprintf("for (int %s = 0; %s[%s] != '\\0'; %s++) {\n",
+			loopVar, iterableName, loopVar, loopVar))
+
+		gen.indent++
+		gen.writeIndent()
+
+		gen.output.WriteString(fmt.Sprintf("char %s = %s[%s];\n",
+			elementVar, iterableName, loopVar))
+
+		// Register loop variable for type inference
+		oldType := gen.variables[elementVar]
+		gen.variables[elementVar] = "char"
+
+		gen.markUnused(elementVar)
+		gen.generateNodeInternal(node.Children[2], false)
+
+		// Restore old type
+		if oldType != "" {
+			gen.variables[elementVar] = oldType
+		} else {
+			delete(gen.variables, elementVar)
+		}
+
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else if iterableType == "range" {
+		// Range iteration - the range is evaluated once and stepped through
+		rangeName := fmt.Sprintf("__range_%d", gen.varCounter)
+		gen.varCounter++
+
+		gen.output.WriteString(fmt.Sprintf("AhoyRange %s = %s;\n", rangeName, gen.nodeToString(iterableExpr)))
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("for (int %s = %s.start; %s.step > 0 ? %s < %s.end : %s > %s.end; %s += %s.step) {\n",
+			elementVar, rangeName, rangeName, elementVar, rangeName, elementVar, rangeName, elementVar, rangeName))
+
+		// Register loop variable for type inference
+		oldType := gen.variables[elementVar]
+		gen.variables[elementVar] = "int"
+
+		gen.indent++
+		gen.markUnused(elementVar)
+		gen.generateNodeInternal(node.Children[2], false)
+		gen.indent--
+
+		// Restore old type
+		if oldType != "" {
+			gen.variables[elementVar] = oldType
+		} else {
+			delete(gen.variables, elementVar)
+		}
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else if iterableType == "bytes" {
+		// Byte buffer iteration - each byte as an int
+		loopVar := fmt.Sprintf("__loop_i_%d", gen.varCounter)
+		gen.varCounter++
+
+		bufName := gen.nodeToString(iterableExpr)
+		gen.output.WriteString(fmt.Sprintf("for (int %s = 0; %s < %s->length; %s++) {\n",
+			loopVar, loopVar, bufName, loopVar))
+
+		gen.indent++
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("int %s = %s->data[%s];\n",
+			elementVar, bufName, loopVar))
+
+		// Register loop variable for type inference
+		oldType := gen.variables[elementVar]
+		gen.variables[elementVar] = "int"
+
+		gen.markUnused(elementVar)
+		gen.generateNodeInternal(node.Children[2], false)
+
+		// Restore old type
+		if oldType != "" {
+			gen.variables[elementVar] = oldType
+		} else {
+			delete(gen.variables, elementVar)
+		}
+
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else if protocol := gen.structIterator(iterableType, nodeLine(node)); protocol != nil {
+		// Struct iteration - the program's iterator functions give the items
+		gen.generateForInStructLoop(node, protocol, iterableType)
+	} else if elemType, length, isFixed := gen.fixedArrayType(gen.nodeToString(iterableExpr)); isFixed {
+		// Fixed-size array iteration - length is known at compile time
+		loopVar := fmt.Sprintf("__loop_i_%d", gen.varCounter)
+		gen.varCounter++
+
+		arrayName := gen.nodeToString(iterableExpr)
+		gen.output.WriteString(fmt.Sprintf("for (int %s = 0; %s < %d; %s++) {\n",
+			loopVar, loopVar, length, loopVar))
+
+		gen.indent++
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s %s = %s[%s];\n",
+			gen.mapType(elemType), elementVar, arrayName, loopVar))
+
+		// Register loop variable for type inference
+		oldType := gen.variables[elementVar]
+		gen.variables[elementVar] = elemType
+
+		gen.markUnused(elementVar)
+		gen.generateNodeInternal(node.Children[2], false)
+
+		// Restore old type
+		if oldType != "" {
+			gen.variables[elementVar] = oldType
+		} else {
+			delete(gen.variables, elementVar)
+		}
+
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else {
+		// Array iteration
+		loopVar := fmt.Sprintf("__loop_i_%d", gen.varCounter)
+		gen.varCounter++
+
+		arrayName := gen.nodeToString(iterableExpr)
+
+		// Evaluate array-producing calls like d.keys|| once, not per iteration
+		if iterableExpr.Type != NODE_IDENTIFIER {
+			iterName := fmt.Sprintf("__iter_%d", gen.varCounter)
+			gen.varCounter++
+			gen.output.WriteString(fmt.Sprintf("AhoyArray* %s = %s;\n", iterName, arrayName))
+			gen.writeIndent()
+			arrayName = iterName
+		}
+
+		// AhoyArray uses 'length', not 'size'; -safe checks the array is
+		// unchanged before each next element
+		if mods := gen.iterationSnapshot(arrayName); mods != "" {
+			gen.output.WriteString(fmt.Sprintf("for (int %s = 0; %s < %s->length; %s, %s++) {\n",
+				loopVar, loopVar, arrayName, gen.iterationCheck(arrayName, mods, nodeLine(node)), loopVar))
+		} else {
+			gen.output.WriteString(fmt.Sprintf("for (int %s = 0; %s < %s->length; %s++) {\n",
+				loopVar, loopVar, arrayName, loopVar))
+		}
+
+		gen.indent++
+		gen.writeIndent()
+
+		// Typed arrays declare the element with its own type; others default to int
+		elemType := "int"
+		if declared := gen.declaredType(iterableExpr); strings.HasPrefix(declared, "array[") {
+			elemType = strings.TrimSuffix(strings.TrimPrefix(declared, "array["), "]")
+		} else if known := gen.arrayElementTypes[arrayName]; gen.slotStruct(known) != nil || known == "float" {
+			elemType = known
+		}
+		switch {
+		case gen.slotStruct(elemType) != nil:
+			// Struct slots hold a pointer to the boxed struct
+			cType := gen.mapType(elemType)
+			gen.output.WriteString(fmt.Sprintf("%s %s = *(%s*)%s->data[%s];\n",
+				cType, elementVar, cType, arrayName, loopVar))
+		case elemType == "int":
+			// Cast from void* through intptr_t to int (handles stored integers correctly)
+			gen.output.WriteString(fmt.Sprintf("int %s = (intptr_t)%s->data[%s];\n",
+				elementVar, arrayName, loopVar))
+		case elemType == "float":
+			gen.output.WriteString(fmt.Sprintf("double %s = ahoy_slot_float(%s->data[%s], %s->types[%s]);\n",
+				elementVar, arrayName, loopVar, arrayName, loopVar))
+		default:
+			cType := gen.mapType(elemType)
+			gen.output.WriteString(fmt.Sprintf("%s %s = (%s)%s->data[%s];\n",
+				cType, elementVar, cType, arrayName, loopVar))
+		}
+
+		// Register loop variable for type inference
+		oldType := gen.variables[elementVar]
+		gen.variables[elementVar] = elemType
+
+		gen.markUnused(elementVar)
+		gen.generateNodeInternal(node.Children[2], false)
+
+		// Restore old type
+		if oldType != "" {
+			gen.variables[elementVar] = oldType
+		} else {
+			delete(gen.variables, elementVar)
+		}
+
+		gen.indent--
+
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+}
+
+// iterationSnapshot starts a -safe loop over container by copying its
+// modification counter, returning the copy's name; without -safe it is ""
+func (gen *CodeGenerator) iterationSnapshot(container string) string {
+	if !gen.safe {
+		return ""
+	}
+	gen.useIterationCheck = true
+	mods := fmt.Sprintf("__mods_%d", gen.varCounter)
+	gen.varCounter++
+	gen.output.WriteString(fmt.Sprintf("unsigned int %s = %s->mods;\n", mods, container))
+	gen.writeIndent()
+	return mods
+}
+
+// iterationCheck returns the call that stops the program if container was
+// modified since iterationSnapshot
+func (gen *CodeGenerator) iterationCheck(container, mods string, line int) string {
+	return fmt.Sprintf("ahoy_check_iteration(%s->mods, %s, %s, %d)",
+		container, mods, strconv.Quote(gen.sourceFilename), line)
+}
+
+// dictValueType extracts the value type from dict<k,v> or dict[k,v]
+func dictValueType(dictType string) string {
+	if !strings.HasPrefix(dictType, "dict<") && !strings.HasPrefix(dictType, "dict[") {
+		return ""
+	}
+	startIdx := strings.IndexAny(dictType, "<[")
+	endIdx := strings.LastIndexAny(dictType, ">]")
+	if endIdx <= startIdx {
+		return ""
+	}
+	parts := strings.Split(dictType[startIdx+1:endIdx], ",")
+	if len(parts) != 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+func (gen *CodeGenerator) generateForInDictLoop(node *ASTNode) {
+	gen.writeIndent()
+
+	// node.Children[0] is key variable name
+	// node.Children[1] is value variable name
+	// node.Children[2] is dict expression
+	// node.Children[3] is body
+
+	keyVar := node.Children[0].Value
+	valueVar := node.Children[1].Value
+	dictExpr := node.Children[2]
+
+	// loop key, value in d.items_sorted|| walks the entries in key order
+	sorted := dictExpr.Type == NODE_METHOD_CALL && dictExpr.Value == "items_sorted" &&
+		len(dictExpr.Children) > 0 && len(dictExpr.Children[1].Children) == 0
+	if sorted {
+		dictExpr = dictExpr.Children[0]
+		gen.dictMethods["sorted_entries"] = true
+	}
+
+	// Generate unique loop counters
+	bucketVar := fmt.Sprintf("__bucket_%d", gen.varCounter)
+	entryVar := fmt.Sprintf("__entry_%d", gen.varCounter)
+	sortedVar := fmt.Sprintf("__sorted_%d", gen.varCounter)
+	gen.varCounter++
+
+	dictName := gen.nodeToString(dictExpr)
+
+	// Check if we need to cast (for generic parameters)
+	dictType := gen.inferType(dictExpr)
+	dictRef := dictName
+	if dictType == "generic" {
+		dictRef = "((HashMap*)" + dictName + ")"
+	}
+
+	// Iterate through hash map buckets, or a snapshot of the entries sorted
+	// by key
+	mods := gen.iterationSnapshot(dictRef)
+	if sorted {
+		gen.output.WriteString(fmt.Sprintf("HashMapEntry** %s = ahoy_dict_sorted_entries(%s);\n", sortedVar, dictRef))
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("for (int %s = 0; %s[%s] != NULL; %s++) {\n",
+			bucketVar, sortedVar, bucketVar, bucketVar))
+	} else {
+		gen.output.WriteString(fmt.Sprintf("for (int %s = 0; %s < %s->capacity; %s++) {\n",
+			bucketVar, bucketVar, dictRef, bucketVar))
+	}
+
+	gen.indent++
+	gen.writeIndent()
+	if sorted {
+		gen.output.WriteString(fmt.Sprintf("HashMapEntry* %s = %s[%s];\n", entryVar, sortedVar, bucketVar))
+		gen.writeIndent()
+		gen.output.WriteString("{\n")
+	} else {
+		gen.output.WriteString(fmt.Sprintf("HashMapEntry* %s = %s->buckets[%s];\n",
+			entryVar, dictRef, bucketVar))
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("while (%s != NULL) {\n", entryVar))
+	}
+
+	gen.indent++
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("const char* %s = %s->key;\n", keyVar, entryVar))
+
+	// Try to infer the dict value type from the dict variable
+	valueType := "" // Will determine based on dict type
+	valueCType := ""
+	hasKnownType := false
+
+	// Check if dict variable has a known type like dict<string,string>
+	if dictExpr.Type == NODE_IDENTIFIER {
+		dictVarName := dictExpr.Value
+		var dictVarType string
+		if varType, exists := gen.variables[dictVarName]; exists {
+			dictVarType = varType
+		} else if varType, exists := gen.functionVars[dictVarName]; exists {
+			dictVarType = varType
+		}
+
+		// Check if it's a typed dict
+		if dictValue := dictValueType(dictVarType); dictValue != "" {
+			valueType = dictValue
+			valueCType = gen.mapType(valueType)
+			hasKnownType = true
+		}
+	}
+
+	// Save old types before registering loop variables
+	oldKeyType, _ := gen.variables[keyVar]
+	oldValType, _ := gen.variables[valueVar]
+
+	// For typed dicts, use the specific type
+	// For untyped dicts (object literals), use intptr_t (can be cast to arrays/dicts/etc)
+	if hasKnownType {
+		gen.writeIndent()
+		switch valueCType {
+		case "double", "float":
+			// Floats are stored as their bits in the entry
+			gen.output.WriteString(fmt.Sprintf("%s %s = ahoy_value_from_raw((intptr_t)%s->value, %s->valueType).as.f;\n", valueCType, valueVar, entryVar, entryVar))
+		case "int", "bool", "char":
+			gen.output.WriteString(fmt.Sprintf("%s %s = (%s)(intptr_t)%s->value;\n", valueCType, valueVar, valueCType, entryVar))
+		default:
+			gen.output.WriteString(fmt.Sprintf("%s %s = (%s)%s->value;\n", valueCType, valueVar, valueCType, entryVar))
+		}
+
+		// Register loop variables
+		gen.variables[keyVar] = "char*"
+		gen.variables[valueVar] = valueType
+	} else {
+		// For untyped dicts, expose value as intptr_t which can be cast as needed
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("intptr_t %s = (intptr_t)%s->value;\n", valueVar, entryVar))
+
+		// Register loop variables
+		gen.variables[keyVar] = "char*"
+		gen.variables[valueVar] = "intptr_t"
+	}
+
+	gen.markUnused(keyVar)
+	gen.markUnused(valueVar)
+	gen.generateNodeInternal(node.Children[3], false)
+
+	// Restore old types (cleanup)
+	if oldKeyType != "" {
+		gen.variables[keyVar] = oldKeyType
+	} else {
+		delete(gen.variables, keyVar)
+	}
+	if oldValType != "" {
+		gen.variables[valueVar] = oldValType
+	} else {
+		delete(gen.variables, valueVar)
+	}
+
+	// -safe checks the dict is unchanged before moving to the next entry
+	if mods != "" {
+		gen.writeIndent()
+		gen.output.WriteString(gen.iterationCheck(dictRef, mods, nodeLine(node)) + ";\n")
+	}
+	if !sorted {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s = %s->next;\n", entryVar, entryVar))
+	}
+	gen.indent--
+
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+	gen.indent--
+
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+	if sorted {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("free(%s);\n", sortedVar))
+	}
+}
+
+func (gen *CodeGenerator) generateReturnStatement(node *ASTNode) {
+	if len(node.Children) == 1 && gen.tailCalls[node.Children[0]] != nil {
+		gen.generateTailCall(node.Children[0])
+		return
+	}
+
+	// The value is worked out before leaving any recover block the return
+	// is in, so a panic while working it out is still caught
+	value := ""
+	if len(node.Children) > 0 {
+		oldOutput := gen.output
+		gen.output = strings.Builder{}
+		gen.generateReturnValue(node)
+		value = gen.output.String()
+		gen.output = oldOutput
+		// A value worked out after the deferred statements ran could see
+		// what they changed
+		if gen.recoverExits[node] != "" || len(gen.deferredStatements) > 0 {
+			temp := fmt.Sprintf("__ret_%d", gen.varCounter)
+			gen.varCounter++
+			gen.writeIndent()
+			gen.output.WriteString(fmt.Sprintf("%s %s = %s;\n", gen.currentFunctionReturnType, temp, value))
+			value = temp
+		}
+	}
+	// A top-level return ends the program's int main
+	if value == "" && gen.currentFunction == "" && gen.library == "" {
+		value = "0"
+	}
+	gen.leaveRecover(node)
+	gen.writeDeferred()
+
+	gen.writeIndent()
+	out := gen.outParams[gen.currentFunction] && len(node.Children) > 0
+	if out {
+		gen.output.WriteString("*" + outParamName + " =")
+	} else {
+		gen.output.WriteString("return")
+	}
+	if value != "" {
+		gen.output.WriteString(" " + value)
+	}
+	gen.output.WriteString(";\n")
+	if out {
+		gen.writeIndent()
+		gen.output.WriteString("return;\n")
+	}
+}
+
+// generateReturnValue generates the value a return statement returns
+func (gen *CodeGenerator) generateReturnValue(node *ASTNode) {
+	// Handle multiple return values
+	if len(node.Children) > 1 && gen.currentFunctionHasMultiReturn {
+		// Multiple returns - return a struct literal with correct type
+		gen.output.WriteString("(")
+		gen.output.WriteString(gen.currentFunctionReturnType)
+		gen.output.WriteString("){")
+
+		// Get the return types for casting
+		returnTypes, hasReturnTypes := gen.functionReturnTypes[gen.currentFunction]
+
+		for i, child := range node.Children {
+			if i > 0 {
+				gen.output.WriteString(", ")
+			}
+			gen.output.WriteString(fmt.Sprintf(".ret%d = ", i))
+
+			// If this return type is generic (intptr_t) and value is string, cast
+			if hasReturnTypes && i < len(returnTypes) && returnTypes[i] == "generic" {
+				childType := gen.inferType(child)
+				if childType == "string" || childType == "char*" || childType == "const char*" {
+					gen.output.WriteString("(intptr_t)")
+				}
+			}
+
+			gen.generateNode(child)
+		}
+		gen.output.WriteString("}")
+	} else {
+		gen.generateNode(node.Children[0])
+	}
+}
+
+// generateAssertStatement reports a failed assert with the Ahoy condition,
+// the values of the operands it compares and where it is, then aborts, or
+// with -soft-assert carries on and fails the program at exit
+func (gen *CodeGenerator) generateAssertStatement(node *ASTNode) {
+	if len(node.Children) == 0 {
+		return
+	}
+	condition := node.Children[0]
+	gen.useAssert = true
+
+	gen.writeIndent()
+	gen.output.WriteString("if (!(")
+	gen.generateNode(condition)
+	gen.output.WriteString(")) {\n")
+	gen.indent++
+
+	text := strings.TrimSpace(node.Value)
+	if text == "" {
+		text = "condition"
+	}
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("ahoy_assert_failed(%s, %d, %s);\n",
+		strconv.Quote(gen.sourceFilename), node.Line, strconv.Quote(text)))
+
+	printed := map[string]bool{}
+	for _, operand := range gen.assertOperands(condition) {
+		operandText := assertOperandText(node.Value, condition, operand)
+		if operandText == "" || printed[operandText] {
+			continue
+		}
+		printed[operandText] = true
+		gen.writeAssertOperand(operandText, operand)
+	}
+
+	gen.writeIndent()
+	gen.output.WriteString("ahoy_assert_done();\n")
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
+
+// assertOperands returns the sides of the comparisons in an assert
+// condition whose values are worth showing: literals say nothing new, and
+// calls would run a second time
+func (gen *CodeGenerator) assertOperands(node *ASTNode) []*ASTNode {
+	switch node.Type {
+	case NODE_UNARY_OP:
+		if node.Value == "not" && len(node.Children) == 1 {
+			return gen.assertOperands(node.Children[0])
+		}
+	case NODE_BINARY_OP:
+		if len(node.Children) < 2 {
+			return nil
+		}
+		switch node.Value {
+		case "and", "or":
+			return append(gen.assertOperands(node.Children[0]), gen.assertOperands(node.Children[1])...)
+		case "is", "in", "greater_than", "lesser_than", "less_than", "==", "!=", "<", ">", "<=", ">=":
+			var operands []*ASTNode
+			for _, side := range node.Children[:2] {
+				if !isLiteralNode(side) && !containsCall(side) {
+					operands = append(operands, side)
+				}
+			}
+			return operands
+		}
+	}
+	return nil
+}
+
+func isLiteralNode(node *ASTNode) bool {
+	switch node.Type {
+	case NODE_NUMBER, NODE_STRING, NODE_CHAR, NODE_BOOLEAN:
+		return true
+	}
+	return false
+}
+
+func containsCall(node *ASTNode) bool {
+	if node == nil {
+		return false
+	}
+	if node.Type == NODE_CALL || node.Type == NODE_METHOD_CALL {
+		return true
+	}
+	for _, child := range node.Children {
+		if containsCall(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// assertOperandText cuts operand's source out of the condition text the
+// parser kept, which is laid out at the condition's offsets
+func assertOperandText(text string, condition, operand *ASTNode) string {
+	start := operand.Span.Start - condition.Span.Start
+	end := operand.Span.End - condition.Span.Start
+	if operand.Span.IsZero() || start < 0 || end > len(text) || start >= end {
+		return ""
+	}
+	return strings.TrimSpace(text[start:end])
+}
+
+// writeAssertOperand prints "  <text> = <value>" to stderr for the types
+// that have a readable value
+func (gen *CodeGenerator) writeAssertOperand(text string, operand *ASTNode) {
+	operandType := gen.inferType(operand)
+	label := strconv.Quote("  " + text + " = ")
+	label = label[1 : len(label)-1]
+	gen.writeIndent()
+	switch {
+	case operandType == "bool":
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"%s%%s\\n\", (", label))
+		gen.generateNode(operand)
+		gen.output.WriteString(") ? \"true\" : \"false\");\n")
+	case gen.isStringType(operandType):
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"%s\\\"%%s\\\"\\n\", ", label))
+		gen.generateNode(operand)
+		gen.output.WriteString(");\n")
+	case operandType == "char":
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"%s'%%c'\\n\", ", label))
+		gen.generateNode(operand)
+		gen.output.WriteString(");\n")
+	case operandType == "int" || operandType == "float":
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"%s%s\\n\", ", label, gen.getFormatSpec(operandType)))
+		gen.generateNode(operand)
+		gen.output.WriteString(");\n")
+	default:
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"%s<%s>\\n\");\n", label, operandType))
+	}
+}
+
+// generateDeferStatement collects a deferred statement, which runs when the
+// function returns, and sets its flag where the defer is, so it only runs
+// when the defer was reached
+func (gen *CodeGenerator) generateDeferStatement(node *ASTNode) {
+	if len(node.Children) > 0 {
+		// Generate the deferred statement into a temporary buffer
+		savedOutput := gen.output
+		gen.output = strings.Builder{}
+		savedIndent := gen.indent
+		gen.indent = 0
+
+		gen.generateNodeInternal(node.Children[0], true) // Generate as statement
+
+		deferredCode := gen.output.String()
+		gen.output = savedOutput
+		gen.indent = savedIndent
+
+		if gen.currentFunction != "" {
+			gen.writeIndent()
+			gen.output.WriteString(deferFlag(len(gen.deferredStatements)) + " = 1;\n")
+		}
+		gen.deferredStatements = append(gen.deferredStatements, deferredCode)
+	}
+}
+
+// deferFlag names the flag the i-th defer of a function sets when reached
+func deferFlag(i int) string {
+	return fmt.Sprintf("__defer_%d", i)
+}
+
+// writeDeferred runs the deferred statements of the current function, last
+// deferred first. Each one runs if its defer was reached and clears its
+// flag, so however the function is left, a statement runs at most once.
+func (gen *CodeGenerator) writeDeferred() {
+	for i := len(gen.deferredStatements) - 1; i >= 0; i-- {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("if (%s) {\n", deferFlag(i)))
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("    %s = 0;\n", deferFlag(i)))
+		for _, line := range strings.SplitAfter(gen.deferredStatements[i], "\n") {
+			if line != "" {
+				gen.writeIndent()
+				gen.output.WriteString("    " + line)
+			}
+		}
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+}
+
+func (gen *CodeGenerator) generateImportStatement(node *ASTNode) {
+	// Add include; the header's functions were bound to their C names when
+	// imports were scanned
+	headerName := node.Value
+	if !gen.includes[headerName] {
+		gen.includes[headerName] = true
+		gen.orderedIncludes = append(gen.orderedIncludes, headerName)
+	}
+}
+
+func (gen *CodeGenerator) generateCall(node *ASTNode) {
+	// Keep user-defined functions as snake_case
+	// Convert C library functions to their original names
+	funcName := node.Value
+	unresolved := false // -strict-calls found no function by this name
+	if gen.outParams[funcName] && gen.userFunctions[funcName] && gen.callOut == "" {
+		gen.generateOutParamCall(node)
+		return
+	}
+
+	// Special case: rename main to ahoy_main
+	if funcName == "main" {
+		funcName = "ahoy_main"
+	} else if gen.userFunctions[funcName] {
+		// Keep user-defined function names as-is (snake_case)
+		funcName = node.Value
+	} else if cFuncName, exists := gen.cFunctionNames[funcName]; exists {
+		// Use the actual C function name from the header
+		funcName = cFuncName
+	} else if strings.HasPrefix(funcName, "ahoy_json_") {
+		// Keep JSON helper functions as-is (they're built-in)
+		funcName = node.Value
+	} else if gen.strictCalls {
+		// Checked in the default case, after the builtins
+		unresolved = true
+	} else if strings.Contains(funcName, "_") {
+		// External C library function not in headers - convert to PascalCase as fallback
+		funcName = snakeToPascal(funcName)
+	}
+
+	if extern, exists := gen.externs[node.Value]; exists && !gen.userFunctions[node.Value] {
+		if params := extern.Children[0].Children; len(node.Children) != len(params) {
+			gen.reportError(node.Line, fmt.Sprintf("%s takes %d argument(s), got %d", node.Value, len(params), len(node.Children)))
+		}
+	}
+
+	// vector2 and color helpers, unless the program defines its own
+	if _, exists := builtinTypeHelpers[node.Value]; exists && !gen.userFunctions[node.Value] {
+		gen.generateBuiltinTypeCall(node)
+		return
+	}
+
+	// Builtins added with RegisterBuiltin
+	if builtin := gen.registeredBuiltin(node.Value); builtin != nil {
+		gen.generateRegisteredBuiltin(builtin, node)
+		return
+	}
+
+	// format|...| formats like print into a string, unless the program
+	// defines its own
+	if node.Value == "format" && !gen.userFunctions[node.Value] {
+		gen.generateFormat(node)
+		return
+	}
+
+	// tr|key| looks up translated text, unless the program defines its own
+	if node.Value == "tr" && !gen.userFunctions[node.Value] {
+		gen.generateTr(node)
+		return
+	}
+
+	// direction|n| makes a member of an enum from an int
+	if gen.isEnumType(node.Value) && !gen.userFunctions[node.Value] && !gen.isVariable(node.Value) {
+		gen.generateEnumConversion(node)
+		return
+	}
+
+	// Handle special functions; a function the program declares is called
+	// in place of the builtin it's named after
+	builtin := node.Value
+	if gen.userFunctions[builtin] {
+		builtin = ""
+	}
+	switch builtin {
+	case "print":
+		// Check if we have multiple arguments or if first arg is a format string
+		hasMultipleArgs := len(node.Children) > 1
+		firstIsString := len(node.Children) > 0 && node.Children[0].Type == NODE_STRING
+
+		// If first argument is a string AND it looks like a format string (has {} or %), treat it as one
+		if firstIsString && !hasMultipleArgs {
+			// Single string argument - just print it
+			gen.output.WriteString("printf(")
+			formatStr := node.Children[0].Value
+			if !strings.HasSuffix(formatStr, "\\n") {
+				formatStr += "\\n"
+			}
+			gen.output.WriteString(fmt.Sprintf("\"%s\"", formatStr))
+			gen.output.WriteString(")")
+			return
+		} else if firstIsString && hasFormatPlaceholders(node.Children[0].Value) {
+			// First arg is a format string with placeholders
+			gen.output.WriteString("printf(")
+			formatStr := node.Children[0].Value
+			args := node.Children[1:]
+
+			// Process %v and %t in format string
+			processedFormat, processedArgs := gen.processFormatString(formatStr, args)
+
+			// Auto-add newline if not present
+			if !strings.HasSuffix(processedFormat, "\\n") {
+				processedFormat += "\\n"
+			}
+
+			// Output processed format string
+			gen.output.WriteString(fmt.Sprintf("\"%s\"", processedFormat))
+
+			// Output processed arguments
+			for _, arg := range processedArgs {
+				gen.output.WriteString(", ")
+				gen.generateNode(arg)
+			}
+			gen.output.WriteString(")")
+			return
+		} else {
+			// Values without a format string, on one line separated by spaces
+			gen.output.WriteString("printf(")
+			if len(node.Children) > 0 {
+				gen.writeFormatPlan(gen.planFormat(node.Children), " ", "\\n")
+			}
+			gen.output.WriteString(")")
+			return
+		}
+
+	case "print_raw", "println":
+		gen.generateRawPrint(node)
+		return
+
+	case "flush":
+		// flush|| writes out what stdout has buffered
+		gen.output.WriteString("fflush(stdout)")
+		return
+
+	case "print_err":
+		// print_err|...| formats like print and writes to stderr
+		gen.output.WriteString("fprintf(stderr, " + gen.printArguments(node))
+		return
+
+	case "log.debug", "log.info", "log.warn", "log.error":
+		gen.generateLevelLog(node)
+		return
+
+	case "log":
+		// log|message, file_path| - logs to file with timestamp
+		gen.includes["time.h"] = true
+		if !contains(gen.orderedIncludes, "time.h") {
+			gen.orderedIncludes = append(gen.orderedIncludes, "time.h")
+		}
+
+		gen.output.WriteString("({ ")
+		gen.output.WriteString("FILE* __log_file = fopen(")
+		if len(node.Children) >= 2 {
+			gen.generateNode(node.Children[1]) // file_path
+		} else {
+			gen.output.WriteString("\"ahoy.log\"")
+		}
+		gen.output.WriteString(", \"a\"); ")
+		gen.output.WriteString("if (__log_file) { ")
+		gen.output.WriteString("time_t __now = time(NULL); ")
+		gen.output.WriteString("char __time_buf[26]; ")
+		gen.output.WriteString("struct tm* __tm_info = localtime(&__now); ")
+		gen.output.WriteString("strftime(__time_buf, 26, \"%Y-%m-%d %H:%M:%S\", __tm_info); ")
+		gen.output.WriteString("fprintf(__log_file, \"[%s] \", __time_buf); ")
+
+		// Handle message formatting similar to print
+		if len(node.Children) > 0 {
+			firstArg := node.Children[0]
+			if firstArg.Type == NODE_STRING {
+				formatStr := firstArg.Value
+				gen.output.WriteString(fmt.Sprintf("fprintf(__log_file, \"%s\\n\"", formatStr))
+				// Add additional arguments if any (before file_path)
+				for i := 1; i < len(node.Children)-1; i++ {
+					gen.output.WriteString(", ")
+					gen.generateNode(node.Children[i])
+				}
+				gen.output.WriteString("); ")
+			} else {
+				// Non-string message, convert to string representation
+				gen.output.WriteString("fprintf(__log_file, \"%s\\n\", ")
+				gen.generateNode(firstArg)
+				gen.output.WriteString("); ")
+			}
+		}
+
+		gen.output.WriteString("fclose(__log_file); } ")
+		gen.output.WriteString("})")
+		return
+
+	case "panic":
+		gen.generatePanic(node)
+		return
+
+	case "sprintf":
+		// sprintf returns a new string sized to fit
+		gen.markFormatUsed()
+		gen.output.WriteString("ahoy_format(")
+
+		// Process format string
+		if len(node.Children) > 0 && node.Children[0].Type == NODE_STRING {
+			formatStr := node.Children[0].Value
+			args := node.Children[1:]
+
+			processedFormat, processedArgs := gen.processFormatString(formatStr, args)
+
+			gen.output.WriteString(fmt.Sprintf("\"%s\"", processedFormat))
+
+			for _, arg := range processedArgs {
+				gen.output.WriteString(", ")
+				gen.generateNode(arg)
+			}
+		}
+		gen.output.WriteString(")")
+
+	case "__ahoy_thousands":
+		// Marks a value shown with a {:,} placeholder
+		gen.output.WriteString("ahoy_thousands((double)(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString("), " + node.Children[1].Value + ")")
+
+	case "__ahoy_double":
+		// Marks an int shown with a precision
+		gen.output.WriteString("((double)(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString("))")
+
+	case "__ahoy_enum_name":
+		// Marks an enum value shown by its member's name
+		gen.output.WriteString("ahoy_enum_" + node.Children[1].Value + "_name(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(")")
+
+	case "__print_array_helper":
+		// Special case for array printing - don't convert to PascalCase
+		gen.output.WriteString("print_array_helper(")
+		for i, arg := range node.Children {
+			if i > 0 {
+				gen.output.WriteString(", ")
+			}
+			gen.generateNode(arg)
+		}
+		gen.output.WriteString(")")
+
+	// Type casts
+	case "int":
+		gen.output.WriteString("((int)(")
+		if len(node.Children) > 0 {
+			gen.generateNode(node.Children[0])
+		}
+		gen.output.WriteString("))")
+
+	case "float":
+		gen.output.WriteString("((float)(")
+		if len(node.Children) > 0 {
+			gen.generateNode(node.Children[0])
+		}
+		gen.output.WriteString("))")
+
+	case "char":
+		gen.output.WriteString("((char)(")
+		if len(node.Children) > 0 {
+			gen.generateNode(node.Children[0])
+		}
+		gen.output.WriteString("))")
+
+	case "string":
+		// String cast - convert number to string
+		if len(node.Children) > 0 {
+			argType := gen.inferType(node.Children[0])
+			gen.output.WriteString("({ char* __cast_buf = malloc(32); ")
+
+			switch argType {
+			case "int":
+				gen.output.WriteString("sprintf(__cast_buf, \"%d\", ")
+				gen.generateNode(node.Children[0])
+				gen.output.WriteString("); __cast_buf; })")
+			case "float":
+				gen.output.WriteString("sprintf(__cast_buf, \"%f\", ")
+				gen.generateNode(node.Children[0])
+				gen.output.WriteString("); __cast_buf; })")
+			case "char":
+				gen.output.WriteString("sprintf(__cast_buf, \"%c\", ")
+				gen.generateNode(node.Children[0])
+				gen.output.WriteString("); __cast_buf; })")
+			case "bool":
+				gen.output.WriteString("sprintf(__cast_buf, \"%s\", ")
+				gen.generateNode(node.Children[0])
+				gen.output.WriteString(" ? \"true\" : \"false\"); __cast_buf; })")
+			default:
+				// Already a string or unknown - just pass through
+				gen.generateNode(node.Children[0])
+			}
+		}
+
+	// Bitflag operations (for flags enums)
+	case "has_flag":
+		// has_flag|set, flag| - true when every bit of flag is set
+		gen.output.WriteString("(((")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(") & (")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")) == (")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString("))")
+
+	case "set_flag":
+		gen.output.WriteString("((")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(") | (")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString("))")
+
+	case "clear_flag":
+		gen.output.WriteString("((")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(") & ~(")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString("))")
+
+	case "combine_flags":
+		// combine_flags|a, b, c| - bitwise or of every argument
+		gen.output.WriteString("(")
+		for i, arg := range node.Children {
+			if i > 0 {
+				gen.output.WriteString(" | ")
+			}
+			gen.generateNode(arg)
+		}
+		if len(node.Children) == 0 {
+			gen.output.WriteString("0")
+		}
+		gen.output.WriteString(")")
+
+	case "size_of", "align_of":
+		// size_of|T| and align_of|T| give the C size and alignment in bytes
+		// of a type, or of a value's type, as compile-time constants
+		if len(node.Children) != 1 {
+			gen.reportError(node.Line, fmt.Sprintf("%s|| takes one type or value", node.Value))
+			return
+		}
+		arg := node.Children[0]
+		operand := gen.layoutType(arg)
+		if operand == "" && arg.Type == NODE_IDENTIFIER && !gen.isVariable(arg.Value) {
+			gen.reportError(node.Line, fmt.Sprintf("%s|| of unknown type or variable '%s'", node.Value, arg.Value))
+			return
+		}
+		if node.Value == "size_of" {
+			gen.output.WriteString("((int)sizeof(")
+		} else {
+			gen.output.WriteString("((int)_Alignof(")
+		}
+		if operand != "" {
+			gen.output.WriteString(operand)
+		} else if node.Value == "size_of" {
+			gen.generateNode(arg)
+		} else {
+			gen.output.WriteString("__typeof__(")
+			gen.generateNode(arg)
+			gen.output.WriteString(")")
+		}
+		gen.output.WriteString("))")
+
+	case "parse_int", "parse_float":
+		// parse_int(s) / parse_float(s) return (value, ok)
+		gen.markNumberParsingUsed()
+		gen.output.WriteString(fmt.Sprintf("ahoy_%s(", node.Value))
+		if len(node.Children) > 0 {
+			gen.generateNode(node.Children[0])
+		}
+		gen.output.WriteString(")")
+
+	case "on_exit":
+		gen.generateOnExit(node)
+
+	case "range":
+		// range(end), range(start, end) or range(start, end, step); the end
+		// is left out
+		if len(node.Children) < 1 || len(node.Children) > 3 {
+			gen.reportError(node.Line, "range|| takes an end, or a start, an end and an optional step")
+			return
+		}
+		gen.markRangeUsed()
+		gen.output.WriteString("ahoy_range(")
+		if len(node.Children) == 1 {
+			gen.output.WriteString("0, ")
+		}
+		for i, arg := range node.Children {
+			if i > 0 {
+				gen.output.WriteString(", ")
+			}
+			gen.generateNode(arg)
+		}
+		if len(node.Children) < 3 {
+			gen.output.WriteString(", 1")
+		}
+		gen.output.WriteString(")")
+
+	case "read_bytes":
+		// read_bytes(path) returns (bytes, ok)
+		gen.markBytesUsed()
+		gen.output.WriteString("ahoy_read_bytes(")
+		if len(node.Children) > 0 {
+			gen.generateNode(node.Children[0])
+		}
+		gen.output.WriteString(")")
+
+	case "write_bytes":
+		// write_bytes(path, buf) returns true when every byte was written
+		gen.markBytesUsed()
+		gen.output.WriteString("ahoy_write_bytes(")
+		for i, arg := range node.Children {
+			if i > 0 {
+				gen.output.WriteString(", ")
+			}
+			gen.generateNode(arg)
+		}
+		gen.output.WriteString(")")
+
+	case "read_json":
+		// Mark that JSON is used
+		if !gen.useJSON {
+			gen.useJSON = true
+			// Register JSON function return types immediately
+			gen.registerJSONFunctionTypes()
+		}
+		// read_json returns (AhoyJSON*, char* error)
+		gen.output.WriteString("ahoy_json_read(")
+		if len(node.Children) > 0 {
+			gen.generateNode(node.Children[0])
+		}
+		gen.output.WriteString(")")
+
+	case "json_encode":
+		// json_encode(value) returns the JSON text of a struct
+		if len(node.Children) != 1 {
+			gen.reportError(node.Line, "json_encode|| takes one struct value")
+			return
+		}
+		if structName, ok := gen.jsonCodecStruct(node.Children[0], "json_encode"); ok {
+			gen.output.WriteString(fmt.Sprintf("ahoy_json_encode_%s(", structName))
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(")")
+		}
+
+	case "json_decode":
+		// json_decode(text or json, target) fills the fields of a struct
+		// variable and returns false unless the source is a JSON object
+		if len(node.Children) != 2 {
+			gen.reportError(node.Line, "json_decode|| takes JSON text or a JSON value, and a struct variable")
+			return
+		}
+		if structName, ok := gen.jsonCodecStruct(node.Children[1], "json_decode"); ok {
+			if gen.inferType(node.Children[0]) == "AhoyJSON*" {
+				gen.output.WriteString(fmt.Sprintf("ahoy_json_fill_%s(", structName))
+			} else {
+				gen.output.WriteString(fmt.Sprintf("ahoy_json_decode_%s(", structName))
+			}
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(", &")
+			gen.generateNode(node.Children[1])
+			gen.output.WriteString(")")
+		}
+
+	case "write_json":
+		// Mark that JSON is used
+		if !gen.useJSON {
+			gen.useJSON = true
+			// Register JSON function return types immediately
+			gen.registerJSONFunctionTypes()
+		}
+		// write_json(filename, json) returns char* error
+		gen.output.WriteString("ahoy_json_write(")
+		if len(node.Children) > 0 {
+			gen.generateNode(node.Children[0])
+		}
+		if len(node.Children) > 1 {
+			gen.output.WriteString(", ")
+			gen.generateNode(node.Children[1])
+		}
+		gen.output.WriteString(")")
+
+	default:
+		if unresolved && !gen.isKnownFunction(funcName) {
+			gen.reportError(node.Line, fmt.Sprintf("unknown function '%s'", funcName),
+				"declare it with @, import the C header that has it or declare it with extern")
+		}
+		if !gen.userFunctions[node.Value] {
+			funcName = gen.deterministicName(funcName)
+		}
+		gen.output.WriteString(fmt.Sprintf("%s(", funcName))
+		argsStart := gen.output.Len()
+		out := gen.callOut
+		gen.callOut = ""
+
+		// Check if we have parameter type information for this function
+		paramTypes, hasParamInfo := gen.functionParamTypes[node.Value]
+		cParams := gen.cFunctionParams[node.Value]
+		if gen.userFunctions[node.Value] {
+			cParams = nil
+		}
+
+		// Arguments for parameters typed with C enums must be their members
+		for i, arg := range node.Children {
+			if arg.Type == NODE_BINARY_OP && arg.Value == "named_arg" {
+				break
+			}
+			enumName := ""
+			if i < len(cParams) {
+				enumName = gen.cEnumType(cParams[i].Type)
+			} else if hasParamInfo && i < len(paramTypes) {
+				enumName = gen.cEnumType(paramTypes[i])
+			}
+			if enumName != "" {
+				gen.checkCEnumArgument(arg, enumName, node.Value)
+			}
+		}
+
+		// Check if any arguments are named (node.Value == "named_arg")
+		hasNamedArgs := false
+		for _, arg := range node.Children {
+			if arg.Type == NODE_BINARY_OP && arg.Value == "named_arg" {
+				hasNamedArgs = true
+				break
+			}
+		}
+
+		if hasNamedArgs {
+			// Handle named arguments by reordering based on function signature
+			paramNames, hasParamNames := gen.functionParamNames[node.Value]
+
+			if hasParamNames && hasParamInfo {
+				// Create a map to store arguments by name
+				namedArgs := make(map[string]*ASTNode)
+				positionalArgs := []*ASTNode{}
+				positionalIndex := 0
+
+				// Separate named and positional arguments
+				for _, arg := range node.Children {
+					if arg.Type == NODE_BINARY_OP && arg.Value == "named_arg" {
+						argName := arg.Children[0].Value
+						namedArgs[argName] = arg.Children[1]
+					} else {
+						positionalArgs = append(positionalArgs, arg)
+					}
+				}
+
+				// Generate arguments in the order defined by function signature
+				for i, paramName := range paramNames {
+					if i > 0 {
+						gen.output.WriteString(", ")
+					}
+
+					// Check if this parameter was provided as named argument
+					if argNode, exists := namedArgs[paramName]; exists {
+						if hasParamInfo && i < len(paramTypes) && paramTypes[i] == "generic" {
+							argType := gen.inferType(argNode)
+							// Cast all pointer types to intptr_t for generic parameters
+							if argType == "string" || argType == "char*" || argType == "const char*" ||
+								argType == "array" || strings.HasPrefix(argType, "array[") ||
+								argType == "dict" || strings.HasPrefix(argType, "dict[") || strings.HasPrefix(argType, "dict<") ||
+								argType == "HashMap*" || strings.HasSuffix(argType, "*") {
+								gen.output.WriteString("(intptr_t)")
+							}
+						}
+						gen.generateNode(argNode)
+					} else if positionalIndex < len(positionalArgs) {
+						// Use positional argument
+						argNode := positionalArgs[positionalIndex]
+						positionalIndex++
+						if hasParamInfo && i < len(paramTypes) && paramTypes[i] == "generic" {
+							argType := gen.inferType(argNode)
+							// Cast all pointer types to intptr_t for generic parameters
+							if argType == "string" || argType == "char*" || argType == "const char*" ||
+								argType == "array" || strings.HasPrefix(argType, "array[") ||
+								argType == "dict" || strings.HasPrefix(argType, "dict[") || strings.HasPrefix(argType, "dict<") ||
+								argType == "HashMap*" || strings.HasSuffix(argType, "*") {
+								gen.output.WriteString("(intptr_t)")
+							}
+						}
+						gen.generateNode(argNode)
+					} else {
+						// Parameter not provided - use default value
+						paramDefaults, hasDefaults := gen.functionParamDefaults[node.Value]
+						if hasDefaults && i < len(paramDefaults) && paramDefaults[i] != nil {
+							// Generate the default value
+							gen.generateNode(paramDefaults[i])
+						} else {
+							// No default value - error case
+							gen.output.WriteString("0 /* missing arg */")
+						}
+					}
+				}
+			} else {
+				// No parameter info - generate in order provided
+				for i, arg := range node.Children {
+					if i > 0 {
+						gen.output.WriteString(", ")
+					}
+
+					if arg.Type == NODE_BINARY_OP && arg.Value == "named_arg" {
+						gen.generateNode(arg.Children[1])
+					} else {
+						gen.generateNode(arg)
+					}
+				}
+			}
+		} else {
+			// Regular positional arguments
+			for i, arg := range node.Children {
+				if i > 0 {
+					gen.output.WriteString(", ")
+				}
+
+				// An Ahoy function passed for a C function pointer goes through
+				// a wrapper with the C signature
+				if i < len(cParams) && cParams[i].Callback != nil && arg.Type == NODE_IDENTIFIER &&
+					gen.userFunctions[arg.Value] && !gen.isVariable(arg.Value) {
+					gen.output.WriteString(gen.callbackWrapper(arg.Value, cParams[i].Callback, arg.Line))
+					continue
+				}
+
+				// Special case: DrawText first parameter expects char*, cast doubles from dict
+				if funcName == "DrawText" && i == 0 {
+					argType := gen.inferType(arg)
+					if argType == "float" {
+						// Dict access returns double, cast to char* for string values
+						gen.output.WriteString("(char*)(intptr_t)")
+					}
+				}
+
+				// If this parameter is generic, cast pointer types to intptr_t
+				if hasParamInfo && i < len(paramTypes) && paramTypes[i] == "generic" {
+					argType := gen.inferType(arg)
+					// Cast all pointer types (strings, arrays, dicts, structs) to intptr_t
+					if argType == "string" || argType == "char*" || argType == "const char*" ||
+						argType == "array" || strings.HasPrefix(argType, "array[") ||
+						argType == "dict" || strings.HasPrefix(argType, "dict[") || strings.HasPrefix(argType, "dict<") ||
+						argType == "HashMap*" || strings.HasSuffix(argType, "*") {
+						gen.output.WriteString("(intptr_t)")
+					}
+				}
+
+				gen.generateNode(arg)
+			}
+		}
+		if out != "" {
+			if gen.output.Len() > argsStart {
+				gen.output.WriteString(", ")
+			}
+			gen.output.WriteString("&" + out)
+		}
+		gen.output.WriteString(")")
+	}
+}
+
+// hasFloatOperand reports whether either side of a binary operation is a
+// float
+func (gen *CodeGenerator) hasFloatOperand(node *ASTNode) bool {
+	return gen.inferType(node.Children[0]) == "float" || gen.inferType(node.Children[1]) == "float"
+}
+
+func (gen *CodeGenerator) generateBinaryOp(node *ASTNode) {
+	if gen.generateVectorOp(node) {
+		return
+	}
+
+	// Members of different enums only mix after an explicit int|...|
+	switch node.Value {
+	case "and", "or", "&&", "||", "in", "named_arg":
+	default:
+		left, right := gen.valueEnum(node.Children[0]), gen.valueEnum(node.Children[1])
+		if left != "" && right != "" && left != right {
+			gen.reportError(nodeLine(node), fmt.Sprintf("'%s' mixes %s and %s values", node.Value, left, right),
+				"convert them with int|...| first if that's intended")
+			return
+		}
+	}
+
+	switch node.Value {
+	case "is":
+		// Strings compare by content, not by pointer
+		if gen.isStringType(gen.inferType(node.Children[0])) && gen.isStringType(gen.inferType(node.Children[1])) {
+			gen.output.WriteString("(strcmp(")
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(", ")
+			gen.generateNode(node.Children[1])
+			gen.output.WriteString(") == 0)")
+			return
+		}
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" == ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "in":
+		gen.generateMembership(node)
+	case "or":
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" || ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "and":
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" && ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "plus":
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" + ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "minus":
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" - ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "times":
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" * ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "/":
+		// Always float division; div is the integer one
+		gen.output.WriteString("(")
+		if !gen.hasFloatOperand(node) {
+			gen.output.WriteString("(double)")
+		}
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" / ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "div":
+		// Integer division, truncating toward zero like C's
+		if gen.hasFloatOperand(node) {
+			gen.output.WriteString("((int)(")
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(" / ")
+			gen.generateNode(node.Children[1])
+			gen.output.WriteString("))")
+			return
+		}
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" / ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "mod":
+		// The remainder takes the sign of the left operand for ints and
+		// floats alike
+		if gen.hasFloatOperand(node) {
+			gen.includes["math.h"] = true
+			if !contains(gen.orderedIncludes, "math.h") {
+				gen.orderedIncludes = append(gen.orderedIncludes, "math.h")
+			}
+			gen.output.WriteString("fmod(")
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(", ")
+			gen.generateNode(node.Children[1])
+			gen.output.WriteString(")")
+			return
+		}
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" % ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "%":
+		if gen.hasFloatOperand(node) {
+			gen.reportError(nodeLine(node), "'%' needs int operands",
+				"use mod for the remainder of floats")
+		}
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" % ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "greater_than":
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" > ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	case "lesser_than", "less_than":
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(" < ")
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	default:
+		gen.output.WriteString("(")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(fmt.Sprintf(" %s ", node.Value))
+		gen.generateNode(node.Children[1])
+		gen.output.WriteString(")")
+	}
+}
+
+func (gen *CodeGenerator) isStringType(typeName string) bool {
+	return typeName == "string" || typeName == "char*" || typeName == "const char*"
+}
+
+// generateMembership handles `value in array` and `key in dict` by routing to
+// the same runtime helpers as .has||
+func (gen *CodeGenerator) generateMembership(node *ASTNode) {
+	needle := node.Children[0]
+	container := node.Children[1]
+
+	switch gen.getValueType(container) {
+	case "dict":
+		gen.dictMethods["has"] = true
+		gen.output.WriteString("ahoy_dict_has(")
+		gen.generateNode(container)
+		gen.output.WriteString(", ")
+		gen.generateNode(needle)
+		gen.output.WriteString(")")
+	case "array":
+		gen.arrayMethods["has"] = true
+		gen.output.WriteString("ahoy_array_has(")
+		gen.generateNode(container)
+		gen.output.WriteString(", ")
+		gen.generateLookupValue(needle)
+		gen.output.WriteString(")")
+	default:
+		gen.reportError(node.Line, "'in' needs an array or dict on the right")
+	}
+}
+
+func (gen *CodeGenerator) generateConstant(node *ASTNode) {
+	constName := node.Value
+
+	// Check if constant already declared
+	if gen.constants[constName] {
+		gen.reportError(node.Line, fmt.Sprintf("Cannot redeclare constant '%s'", constName),
+			"Constants cannot be reassigned or redeclared.",
+			fmt.Sprintf("'%s' was already declared earlier in the code.", constName))
+		return
+	}
+
+	// Mark constant as declared
+	gen.constants[constName] = true
+
+	// Determine the constant type - use explicit type if provided, otherwise infer
+	var constType string
+	if node.DataType != "" {
+		constType = gen.mapType(node.DataType)
+	} else {
+		// Infer type from the value
+		inferredType := gen.inferType(node.Children[0])
+		constType = gen.mapType(inferredType)
+	}
+
+	// Fold integer constant expressions (SIZE: BASE times 2) so file-scope
+	// initializers stay valid C and later consts, enums and sizes can use them
+	if val, ok := gen.evalConstInt(node.Children[0], nil); ok {
+		gen.constValues[constName] = val
+		node.Children[0] = &ASTNode{
+			Type:     NODE_NUMBER,
+			Value:    strconv.FormatInt(val, 10),
+			DataType: "int",
+			Line:     node.Children[0].Line,
+		}
+	}
+
+	// Record the type so the constant is used like a variable of it
+	valueType := node.DataType
+	if valueType == "" {
+		valueType = gen.inferType(node.Children[0])
+	}
+	if gen.currentFunction != "" && gen.functionVars != nil {
+		gen.functionVars[constName] = valueType
+	} else {
+		gen.variables[constName] = valueType
+	}
+	if value := node.Children[0]; value.Type == NODE_ARRAY_LITERAL && len(value.Children) > 0 {
+		if strings.HasPrefix(node.DataType, "array[") {
+			gen.arrayElementTypes[constName] = strings.TrimSuffix(strings.TrimPrefix(node.DataType, "array["), "]")
+		} else {
+			gen.arrayElementTypes[constName] = gen.inferType(value.Children[0])
+		}
+	}
+
+	// Top-level arrays, dicts and other values C can't initialize at file
+	// scope are declared there and set by ahoy_init_constants, which main
+	// runs first, so functions can use them whatever the order
+	if gen.currentFunction == "" && !isStaticInitializer(node.Children[0]) {
+		gen.globalDecls.WriteString(fmt.Sprintf("%s %s;\n", constType, constName))
+
+		savedOutput := gen.output
+		savedIndent := gen.indent
+		gen.output = strings.Builder{}
+		gen.indent = 1
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s = ", constName))
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(";\n")
+		gen.constInits.WriteString(gen.output.String())
+		gen.output = savedOutput
+		gen.indent = savedIndent
+		return
+	}
+
+	// Constants at global scope (not in a function) go with the top-level
+	// declarations
+	if gen.currentFunction == "" {
+		savedOutput := gen.output
+		gen.output = strings.Builder{}
+
+		gen.output.WriteString(fmt.Sprintf("const %s %s = ", constType, constName))
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(";\n")
+
+		gen.globalDecls.WriteString(gen.output.String())
+		gen.output = savedOutput
+	} else {
+		// Local constants in functions
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("const %s %s = ", constType, constName))
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString(";\n")
+	}
+}
+
+// isStaticInitializer reports whether a constant's value is a literal C
+// accepts as a file-scope initializer
+func isStaticInitializer(value *ASTNode) bool {
+	switch value.Type {
+	case NODE_NUMBER, NODE_STRING, NODE_CHAR, NODE_BOOLEAN:
+		return true
+	case NODE_UNARY_OP:
+		return value.Value == "-" && len(value.Children) == 1 && value.Children[0].Type == NODE_NUMBER
+	case NODE_CALL:
+		return value.Value == "size_of" || value.Value == "align_of"
+	}
+	return false
+}
+
+// evalConstInt folds an integer constant expression built from literals,
+// previously declared consts and int enum members. Bare names are looked up
+// in scope first (may be nil). Returns false when the expression cannot be
+// evaluated at compile time.
+func (gen *CodeGenerator) evalConstInt(node *ASTNode, scope map[string]int64) (int64, bool) {
+	return foldConstInt(node, func(name string) (int64, bool) {
+		if val, ok := scope[name]; ok {
+			return val, true
+		}
+		val, ok := gen.constValues[name]
+		return val, ok
+	})
+}
+
+// foldConstInt folds an integer constant expression, looking up names, and
+// enum members as enum.member, with lookup. Besides Ahoy's operators it
+// folds C's bitwise ones, which C header enums are initialized with.
+func foldConstInt(node *ASTNode, lookup func(name string) (int64, bool)) (int64, bool) {
+	switch node.Type {
+	case NODE_NUMBER:
+		val, err := strconv.ParseInt(node.Value, 10, 64)
+		return val, err == nil
+	case NODE_IDENTIFIER:
+		return lookup(node.Value)
+	case NODE_MEMBER_ACCESS:
+		if len(node.Children) == 0 || node.Children[0].Type != NODE_IDENTIFIER {
+			return 0, false
+		}
+		return lookup(node.Children[0].Value + "." + node.Value)
+	case NODE_UNARY_OP:
+		if len(node.Children) == 0 {
+			return 0, false
+		}
+		val, ok := foldConstInt(node.Children[0], lookup)
+		switch node.Value {
+		case "-":
+			return -val, ok
+		case "~":
+			return ^val, ok
+		}
+	case NODE_BINARY_OP:
+		if len(node.Children) < 2 {
+			return 0, false
+		}
+		left, ok := foldConstInt(node.Children[0], lookup)
+		if !ok {
+			return 0, false
+		}
+		right, ok := foldConstInt(node.Children[1], lookup)
+		if !ok {
+			return 0, false
+		}
+		switch node.Value {
+		case "+", "plus":
+			return left + right, true
+		case "-", "minus":
+			return left - right, true
+		case "*", "times":
+			return left * right, true
+		case "div":
+			if right == 0 {
+				return 0, false
+			}
+			return left / right, true
+		case "%", "mod":
+			if right == 0 {
+				return 0, false
+			}
+			return left % right, true
+		case "<<", ">>":
+			if right < 0 || right > 63 {
+				return 0, false
+			}
+			if node.Value == "<<" {
+				return left << right, true
+			}
+			return left >> right, true
+		case "|":
+			return left | right, true
+		case "&":
+			return left & right, true
+		case "^":
+			return left ^ right, true
+		}
+	}
+	return 0, false
+}
+
+func (gen *CodeGenerator) generateMethodCall(node *ASTNode) {
+	object := node.Children[0]
+	args := node.Children[1]
+	methodName := node.Value
+
+	// Handle dump_struct - returns type information as a string constant
+	if methodName == "dump_struct" {
+		objectType := gen.inferType(object)
+		varName := ""
+		if object.Type == NODE_IDENTIFIER {
+			varName = object.Value
+		}
+
+		// Structs with C layouts are described, sizes and offsets included,
+		// by a helper that asks C for them
+		if structInfo, exists := gen.structs[objectType]; exists && !gen.jsonStructs[objectType] {
+			cName := gen.mapType(objectType)
+			if gen.dumpStructs[cName] == nil {
+				gen.dumpStructs[cName] = structInfo
+				gen.dumpStructOrder = append(gen.dumpStructOrder, cName)
+			}
+			gen.output.WriteString(fmt.Sprintf("ahoy_dump_struct_%s()", cName))
+			return
+		}
+
+		// Generate a string literal describing the type
+		structDesc := fmt.Sprintf("\"Type: %s", objectType)
+		if structInfo, exists := gen.structs[objectType]; exists {
+			structDesc += "\\nFields:"
+			for _, field := range structInfo.Fields {
+				structDesc += fmt.Sprintf("\\n  %s: %s", field.Name, field.Type)
+			}
+		} else if varName != "" {
+			structDesc += fmt.Sprintf(" (variable: %s)", varName)
+		}
+		structDesc += "\""
+		gen.output.WriteString(structDesc)
+		return
+	}
+
+	// Check if this is a namespaced C function call (e.g., math.lerp)
+	if object.Type == NODE_IDENTIFIER {
+		namespace := object.Value
+		if funcMap, exists := gen.cNamespaces[namespace]; exists {
+			// This is a namespaced C function call
+			if cFuncName, found := funcMap[methodName]; found {
+				// Generate the C function call
+				gen.output.WriteString(gen.deterministicName(cFuncName))
+				gen.output.WriteString("(")
+				for i, arg := range args.Children {
+					if i > 0 {
+						gen.output.WriteString(", ")
+					}
+					gen.generateNode(arg)
+				}
+				gen.output.WriteString(")")
+				return
+			}
+		}
+	}
+
+	// game.run|...| is the built-in raylib game loop, unless game is a variable
+	if methodName == "run" && gen.isModule(object, "game") {
+		gen.generateGameRun(node)
+		return
+	}
+
+	// cli.flag_int|...|, cli.parse|| and friends parse the command line
+	if gen.isModule(object, "cli") {
+		gen.generateCliCall(node)
+		return
+	}
+
+	// frame.limit|fps| and frame.delta|| time loops outside game.run
+	if gen.isModule(object, "frame") {
+		gen.generateFrameCall(node)
+		return
+	}
+
+	// mem.allocated||, mem.live_objects|| and mem.report|| count live memory
+	if gen.isModule(object, "mem") {
+		gen.generateMemCall(node)
+		return
+	}
+
+	// Dict transforms run their lambda over every entry
+	if methodName == "map_values" || methodName == "filter" || methodName == "to_array" {
+		if len(args.Children) > 0 && args.Children[0].Type == NODE_LAMBDA && gen.inferType(object) == "dict" {
+			gen.generateDictLambdaInline(object, methodName, args.Children[0])
+			return
+		}
+	}
+
+	// Handle map and filter with inline code generation
+	if methodName == "map" || methodName == "filter" {
+		if len(args.Children) > 0 && args.Children[0].Type == NODE_LAMBDA {
+			if methodName == "map" {
+				gen.generateMapInline(object, args.Children[0])
+			} else {
+				gen.generateFilterInline(object, args.Children[0])
+			}
+			return
+		}
+	}
+
+	// Fixed-size array length is a compile-time constant
+	if methodName == "length" && object.Type == NODE_IDENTIFIER {
+		if _, length, isFixed := gen.fixedArrayType(object.Value); isFixed {
+			gen.output.WriteString(strconv.FormatInt(length, 10))
+			return
+		}
+	}
+
+	// d.get|key| and d.get_or|key, default| tell a missing key from a zero
+	if isDictGet(node) {
+		gen.generateDictGet(node)
+		return
+	}
+
+	// Infer the object type to determine correct method routing
+	objectType := gen.inferType(object)
+
+	// clone|| deep copies arrays, dicts and structs holding them
+	if methodName == "clone" {
+		gen.generateClone(object, objectType)
+		return
+	}
+
+	// Range methods
+	if objectType == "range" && (methodName == "has" || methodName == "to_array") {
+		if methodName == "to_array" {
+			gen.arrayImpls = true
+		}
+		gen.output.WriteString(fmt.Sprintf("ahoy_range_%s(", methodName))
+		gen.generateNode(object)
+		for _, arg := range args.Children {
+			gen.output.WriteString(", ")
+			gen.generateNode(arg)
+		}
+		gen.output.WriteString(")")
+		return
+	}
+
+	// Byte buffer methods
+	if objectType == "bytes" && (methodName == "length" || methodName == "slice") {
+		if methodName == "length" {
+			gen.output.WriteString("(")
+			gen.generateNode(object)
+			gen.output.WriteString(")->length")
+			return
+		}
+		gen.output.WriteString("ahoy_bytes_slice(")
+		gen.generateNode(object)
+		for _, arg := range args.Children {
+			gen.output.WriteString(", ")
+			gen.generateNode(arg)
+		}
+		if len(args.Children) < 2 {
+			// slice|start| runs to the end of the buffer
+			gen.output.WriteString(", (")
+			gen.generateNode(object)
+			gen.output.WriteString(")->length")
+		}
+		gen.output.WriteString(")")
+		return
+	}
+
+	// List of string-only methods (not ambiguous)
+	stringOnlyMethods := []string{
+		"upper", "lower", "replace", "contains",
+		"camel_case", "snake_case", "pascal_case", "kebab_case",
+		"match", "split", "count", "lpad", "rpad", "pad",
+		"strip", "get_file",
+	}
+
+	// List of dictionary-only methods (not ambiguous)
+	dictMethodsList := []string{
+		"size", "clear", "has_all", "keys", "values",
+		"stable_sort", "merge", "items_sorted",
+	}
+
+	// Check if this is a string-only method
+	isStringMethod := false
+	for _, m := range stringOnlyMethods {
+		if methodName == m {
+			isStringMethod = true
+			break
+		}
+	}
+
+	// Check if this is a dictionary method
+	isDictMethod := false
+	for _, m := range dictMethodsList {
+		if methodName == m {
+			isDictMethod = true
+			break
+		}
+	}
+
+	// For "length" method, route based on object type
+	if methodName == "length" {
+		if objectType == "char*" || objectType == "string" {
+			isStringMethod = true
+		}
+		// Otherwise it's an array method (default)
+	}
+
+	// For ambiguous methods (sort, has), route based on object type
+	if methodName == "sort" || methodName == "has" || methodName == "reverse" {
+		if objectType == "dict" || objectType == "HashMap*" || dictValueType(objectType) != "" {
+			isDictMethod = true
+			isStringMethod = false
+		} else {
+			// Default to array method
+			isDictMethod = false
+		}
+	}
+
+	if isStringMethod || (objectType == "char*" && methodName == "length") {
+		// Track which string method is used
+		gen.stringMethods[methodName] = true
+		if methodName == "split" {
+			gen.arrayImpls = true
+		}
+
+		// Generate string method function call
+		gen.output.WriteString(fmt.Sprintf("ahoy_string_%s(", methodName))
+		gen.generateNodeInternal(object, false)
+
+		if len(args.Children) > 0 {
+			gen.output.WriteString(", ")
+			for i, arg := range args.Children {
+				if i > 0 {
+					gen.output.WriteString(", ")
+				}
+				gen.generateNodeInternal(arg, false)
+			}
+		}
+		gen.output.WriteString(")")
+	} else if isDictMethod || objectType == "dict" {
+		// Track which dict method is used
+		gen.dictMethods[methodName] = true
+
+		// Generate dict method function call
+		gen.output.WriteString(fmt.Sprintf("ahoy_dict_%s(", methodName))
+		// Cast generic parameters to HashMap*
+		if object.Type == NODE_IDENTIFIER {
+			objType := gen.inferType(object)
+			if objType == "generic" {
+				gen.output.WriteString("(HashMap*)")
+			}
+		}
+		gen.generateNodeInternal(object, false)
+
+		if len(args.Children) > 0 {
+			gen.output.WriteString(", ")
+			for i, arg := range args.Children {
+				if i > 0 {
+					gen.output.WriteString(", ")
+				}
+				gen.generateNodeInternal(arg, false)
+			}
+		}
+		gen.output.WriteString(")")
+	} else {
+		// Track which array method is used
+		gen.arrayMethods[methodName] = true
+
+		// Special handling for push with multiple arguments - generate multiple calls
+		if methodName == "push" && len(args.Children) > 1 {
+			for i, arg := range args.Children {
+				if i > 0 {
+					gen.output.WriteString("; ")
+				}
+				gen.output.WriteString("ahoy_array_push(")
+				gen.generateNodeInternal(object, false)
+				gen.output.WriteString(", ")
+				valueType := gen.getValueType(arg)
+				gen.generateSlotValue(arg, valueType)
+				gen.output.WriteString(fmt.Sprintf(", %s)", gen.getAhoyTypeEnum(valueType)))
+			}
+			return
+		}
+
+		// Generate array method function call
+		gen.output.WriteString(fmt.Sprintf("ahoy_array_%s(", methodName))
+		// Cast generic parameters to AhoyArray*
+		if object.Type == NODE_IDENTIFIER {
+			objType := gen.inferType(object)
+			if objType == "generic" {
+				gen.output.WriteString("(AhoyArray*)")
+			}
+		}
+		gen.generateNodeInternal(object, false)
+
+		if len(args.Children) > 0 {
+			gen.output.WriteString(", ")
+			for i, arg := range args.Children {
+				if i > 0 {
+					gen.output.WriteString(", ")
+				}
+				// For array methods like push, store the value in its slot form
+				if (methodName == "push" || methodName == "fill") && i == 0 {
+					gen.generateSlotValue(arg, gen.getValueType(arg))
+				} else if methodName == "has" && i == 0 {
+					gen.generateLookupValue(arg)
+				} else if methodName == "push" || methodName == "fill" {
+					gen.output.WriteString("(intptr_t)")
+					gen.generateNodeInternal(arg, false)
+				} else {
+					gen.generateNodeInternal(arg, false)
+				}
+				// For push and fill, also pass the type
+				if methodName == "push" && i == 0 {
+					valueType := gen.getValueType(arg)
+					gen.output.WriteString(fmt.Sprintf(", %s", gen.getAhoyTypeEnum(valueType)))
+				}
+				if methodName == "fill" && i == 0 {
+					valueType := gen.getValueType(arg)
+					gen.output.WriteString(fmt.Sprintf(", %s", gen.getAhoyTypeEnum(valueType)))
+				}
+			}
+		}
+		gen.output.WriteString(")")
+	}
+}
+
+func (gen *CodeGenerator) generateUnaryOp(node *ASTNode) {
+	switch node.Value {
+	case "not":
+		gen.output.WriteString("!")
+	case "^":
+		// Pointer dereference - convert ^ to *
+		gen.output.WriteString("*")
+	case "&":
+		// Address-of operator
+		gen.output.WriteString("&")
+	default:
+		gen.output.WriteString(node.Value)
+	}
+	gen.generateNode(node.Children[0])
+}
+
+func (gen *CodeGenerator) generateTernary(node *ASTNode) {
+	// C ternary: condition ? true_expr : false_expr
+	gen.output.WriteString("(")
+	gen.generateNode(node.Children[0]) // condition
+	gen.output.WriteString(" ? ")
+	gen.generateNode(node.Children[1]) // true branch
+	gen.output.WriteString(" : ")
+	gen.generateNode(node.Children[2]) // false branch
+	gen.output.WriteString(")")
+}
+
+func (gen *CodeGenerator) generateArrayLiteral(node *ASTNode) {
+	gen.arrayImpls = true
+
+	// Create array with initial capacity
+	arrName := fmt.Sprintf("arr_%d", gen.varCounter)
+	gen.varCounter++
+
+	// Check if we have an explicit type from context
+	var explicitElementType string
+	if gen.currentTypeContext != "" && strings.HasPrefix(gen.currentTypeContext, "array[") {
+		explicitElementType = strings.TrimSuffix(strings.TrimPrefix(gen.currentTypeContext, "array["), "]")
+	}
+
+	// Determine if this is a typed array (only if explicitly annotated)
+	isTyped := explicitElementType != ""
+	var elementType string
+	if explicitElementType != "" {
+		elementType = explicitElementType
+	}
+
+	// Use simple C array initialization
+	gen.output.WriteString("({ ")
+	gen.output.WriteString(fmt.Sprintf("AhoyArray* %s = malloc(sizeof(AhoyArray)); ", arrName))
+	gen.output.WriteString(fmt.Sprintf("%s->length = %d; ", arrName, len(node.Children)))
+	gen.output.WriteString(fmt.Sprintf("%s->capacity = %d; ", arrName, len(node.Children)))
+	gen.output.WriteString(fmt.Sprintf("%s->data = malloc(%d * sizeof(intptr_t)); ", arrName, len(node.Children)))
+	gen.output.WriteString(fmt.Sprintf("%s->types = malloc(%d * sizeof(AhoyValueType)); ", arrName, len(node.Children)))
+
+	// Set typed/mixed flag - only typed if explicitly annotated
+	if isTyped {
+		gen.output.WriteString(fmt.Sprintf("%s->is_typed = 1; ", arrName))
+		gen.output.WriteString(fmt.Sprintf("%s->element_type = %s; ", arrName, gen.getAhoyTypeEnum(elementType)))
+	} else {
+		gen.output.WriteString(fmt.Sprintf("%s->is_typed = 0; ", arrName))
+	}
+
+	// Add elements - cast to intptr_t for pointer safety and track types
+	for i, child := range node.Children {
+		valueType := gen.getValueType(child)
+		gen.output.WriteString(fmt.Sprintf("%s->types[%d] = %s; ", arrName, i, gen.getAhoyTypeEnum(valueType)))
+
+		gen.output.WriteString(fmt.Sprintf("%s->data[%d] = ", arrName, i))
+		gen.generateSlotValue(child, valueType)
+		gen.output.WriteString("; ")
+	}
+
+	gen.output.WriteString(fmt.Sprintf("%s; })", arrName))
+}
+
+// parseFixedArrayType splits a fixed-size array type like "int[64]" into its
+// element type and length
+func parseFixedArrayType(varType string) (string, int64, bool) {
+	open := strings.Index(varType, "[")
+	if open <= 0 || !strings.HasSuffix(varType, "]") {
+		return "", 0, false
+	}
+	elemType := varType[:open]
+	if elemType == "array" || elemType == "dict" {
+		return "", 0, false
+	}
+	length, err := strconv.ParseInt(varType[open+1:len(varType)-1], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return elemType, length, true
+}
+
+// declaredType is inferType without normalization, so variables keep
+// element types like array[string] and dict<string,int>
+func (gen *CodeGenerator) declaredType(node *ASTNode) string {
+	if node.Type == NODE_IDENTIFIER {
+		if varType, exists := gen.functionVars[node.Value]; exists {
+			return varType
+		}
+		if varType, exists := gen.variables[node.Value]; exists {
+			return varType
+		}
+	}
+	return gen.inferType(node)
+}
+
+// fixedArrayType looks up a variable declared as a fixed-size array
+func (gen *CodeGenerator) fixedArrayType(name string) (string, int64, bool) {
+	if varType, exists := gen.functionVars[name]; exists {
+		return parseFixedArrayType(varType)
+	}
+	if varType, exists := gen.variables[name]; exists {
+		return parseFixedArrayType(varType)
+	}
+	return "", 0, false
+}
+
+// generateFixedArrayDeclaration emits a zeroed C array (buf: int[64]) whose
+// length must fold to a positive compile-time constant
+func (gen *CodeGenerator) generateFixedArrayDeclaration(name string, node *ASTNode) {
+	length, ok := gen.evalConstInt(node.Children[0], nil)
+	if !ok || length <= 0 {
+		gen.reportError(node.Line, fmt.Sprintf("Fixed array '%s' needs a positive constant length", name))
+		return
+	}
+
+	elemType := node.Value
+	varType := fmt.Sprintf("%s[%d]", elemType, length)
+	if gen.currentFunction != "" && gen.functionVars != nil {
+		gen.functionVars[name] = varType
+		gen.declaredFunctionVars[name] = true
+	} else {
+		gen.variables[name] = varType
+		gen.declaredGlobalVars[name] = true
+	}
+
+	// Top-level arrays are hoisted to file scope, where C zeroes them; one
+	// declared in a block is zeroed again each time the block runs
+	if gen.currentFunction == "" {
+		if !gen.hoistedGlobals[name] {
+			gen.hoistedGlobals[name] = true
+			gen.globalDecls.WriteString(fmt.Sprintf("%s %s[%d];\n", gen.mapType(elemType), name, length))
+		}
+		if gen.indent > 0 {
+			gen.output.WriteString(fmt.Sprintf("memset(%s, 0, sizeof(%s));\n", name, name))
+		}
+		return
+	}
+	gen.output.WriteString(fmt.Sprintf("%s %s[%d] = {0};\n", gen.mapType(elemType), name, length))
+}
+
+// writeIndexBoundsCheck emits the runtime bounds check for __idx against a
+// C length expression
+func (gen *CodeGenerator) writeIndexBoundsCheck(arrayName string, length string, line int) {
+	gen.output.WriteString(fmt.Sprintf("if (__idx < 0 || __idx >= %s) { ", length))
+	gen.output.WriteString("fprintf(stderr, \"RUNTIME ERROR: Array bounds violation\\n\"); ")
+	gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"  File: %s\\n\"); ", gen.sourceFilename))
+	gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"  Line: %d\\n\"); ", line))
+	gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"  Array: %s\\n\"); ", arrayName))
+	gen.output.WriteString("fprintf(stderr, \"  Index: %d\\n\", __idx); ")
+	gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"  Valid range: 0 to %%d\\n\", %s - 1); ", length))
+	gen.output.WriteString("exit(1); ")
+	gen.output.WriteString("} ")
+}
+
+func (gen *CodeGenerator) generateArrayAccess(node *ASTNode) {
+	arrayName := node.Value
+
+	// Fixed-size arrays index the C array directly
+	if _, length, isFixed := gen.fixedArrayType(arrayName); isFixed {
+		if gen.enableBoundsChecking && !gen.skipBoundsCheck {
+			gen.output.WriteString("({ int __idx = ")
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString("; ")
+			gen.writeIndexBoundsCheck(arrayName, strconv.FormatInt(length, 10), node.Line)
+			gen.output.WriteString(fmt.Sprintf("%s[__idx]; })", arrayName))
+			return
+		}
+		gen.output.WriteString(fmt.Sprintf("%s[", arrayName))
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString("]")
+		return
+	}
+
+	// Byte buffers index their data directly
+	if gen.isBytesVar(arrayName) {
+		if gen.enableBoundsChecking && !gen.skipBoundsCheck {
+			gen.output.WriteString("({ int __idx = ")
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString("; ")
+			gen.writeIndexBoundsCheck(arrayName, arrayName+"->length", node.Line)
+			gen.output.WriteString(fmt.Sprintf("(int)%s->data[__idx]; })", arrayName))
+			return
+		}
+		gen.output.WriteString(fmt.Sprintf("%s->data[", arrayName))
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString("]")
+		return
+	}
+
+	// Check if the variable type is intptr_t, void*, or generic (might need casting to AhoyArray*)
+	needsArrayCast := false
+	if varType, exists := gen.variables[arrayName]; exists {
+		if varType == "intptr_t" || varType == "void*" || varType == "generic" {
+			needsArrayCast = true
+		}
+	}
+	if varType, exists := gen.functionVars[arrayName]; exists {
+		if varType == "intptr_t" || varType == "void*" || varType == "generic" {
+			needsArrayCast = true
+		}
+	}
+
+	// If bounds checking is enabled and not skipped (lvalue context handled separately)
+	if gen.enableBoundsChecking && !gen.skipBoundsCheck {
+		// For rvalue contexts, wrap in compound expression with bounds check
+		gen.output.WriteString("({ ")
+		gen.output.WriteString("int __idx = ")
+		gen.generateNode(node.Children[0])
+		gen.output.WriteString("; ")
+
+		if needsArrayCast {
+			gen.output.WriteString(fmt.Sprintf("AhoyArray* __arr = (AhoyArray*)%s; ", arrayName))
+		} else {
+			gen.output.WriteString(fmt.Sprintf("AhoyArray* __arr = %s; ", arrayName))
+		}
+
+		gen.output.WriteString("if (__idx < 0 || __idx >= __arr->length) { ")
+		gen.output.WriteString("fprintf(stderr, \"RUNTIME ERROR: Array bounds violation\\n\"); ")
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"  File: %s\\n\"); ", gen.sourceFilename))
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"  Line: %d\\n\"); ", node.Line))
+		gen.output.WriteString(fmt.Sprintf("fprintf(stderr, \"  Array: %s\\n\"); ", arrayName))
+		gen.output.WriteString("fprintf(stderr, \"  Index: %d\\n\", __idx); ")
+		gen.output.WriteString("fprintf(stderr, \"  Valid range: 0 to %d\\n\", __arr->length - 1); ")
+		gen.output.WriteString("exit(1); ")
+		gen.output.WriteString("} ")
+
+		// Check if we know the element type
+		if elemType, exists := gen.arrayElementType(arrayName); exists {
+			cType := gen.mapType(elemType)
+			if gen.slotStruct(elemType) != nil {
+				gen.output.WriteString(fmt.Sprintf("(*(%s*)__arr->data[__idx])", cType))
+			} else if elemType == "float" {
+				gen.output.WriteString("ahoy_slot_float(__arr->data[__idx], __arr->types[__idx])")
+			} else if cType != "int" {
+				gen.output.WriteString(fmt.Sprintf("((%s)(intptr_t)__arr->data[__idx])", cType))
+			} else {
+				gen.output.WriteString("__arr->data[__idx]")
+			}
+		} else {
+			gen.output.WriteString("__arr->data[__idx]")
+		}
+
+		gen.output.WriteString("; })")
+		return
+	}
+
+	// Check if we know the element type
+	if elemType, exists := gen.arrayElementType(arrayName); exists {
+		cType := gen.mapType(elemType)
+		// Struct slots hold a pointer to the boxed struct
+		if gen.slotStruct(elemType) != nil {
+			if needsArrayCast {
+				gen.output.WriteString(fmt.Sprintf("(*(%s*)((AhoyArray*)%s)->data[", cType, arrayName))
+			} else {
+				gen.output.WriteString(fmt.Sprintf("(*(%s*)%s->data[", cType, arrayName))
+			}
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString("])")
+			return
+		}
+		// Float slots hold a pointer to the boxed double
+		if elemType == "float" {
+			array := arrayName
+			if needsArrayCast {
+				array = fmt.Sprintf("((AhoyArray*)%s)", arrayName)
+			}
+			gen.output.WriteString("({ int __idx = ")
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString(fmt.Sprintf("; ahoy_slot_float(%s->data[__idx], %s->types[__idx]); })", array, array))
+			return
+		}
+		// Cast to the appropriate type for non-int types (need intptr_t intermediate for pointer safety)
+		if cType != "int" {
+			if needsArrayCast {
+				gen.output.WriteString(fmt.Sprintf("((%s)(intptr_t)((AhoyArray*)%s)->data[", cType, arrayName))
+			} else {
+				gen.output.WriteString(fmt.Sprintf("((%s)(intptr_t)%s->data[", cType, arrayName))
+			}
+			gen.generateNode(node.Children[0])
+			gen.output.WriteString("])")
+			return
+		}
+	}
+
+	// Default: no cast for int/intptr_t values to preserve lvalue for assignments
+	if needsArrayCast {
+		gen.output.WriteString(fmt.Sprintf("((AhoyArray*)%s)->data[", arrayName))
+	} else {
+		gen.output.WriteString(fmt.Sprintf("%s->data[", arrayName))
+	}
+	gen.generateNode(node.Children[0])
+	gen.output.WriteString("]")
+}
+
+// arrayElementType looks up the element type of an array variable, from its
+// literal or from an array[T] type such as split|| and keys|| give
+func (gen *CodeGenerator) arrayElementType(name string) (string, bool) {
+	if elemType, exists := gen.arrayElementTypes[name]; exists {
+		return elemType, true
+	}
+	varType, exists := gen.functionVars[name]
+	if !exists {
+		varType = gen.variables[name]
+	}
+	if strings.HasPrefix(varType, "array[") && strings.HasSuffix(varType, "]") {
+		return strings.TrimSuffix(strings.TrimPrefix(varType, "array["), "]"), true
+	}
+	return "", false
+}
+
+// dictLiteralValueType returns the type every value of a dict literal has,
+// or "" when they differ
+func (gen *CodeGenerator) dictLiteralValueType(node *ASTNode) string {
+	if node.Type != NODE_DICT_LITERAL || len(node.Children) < 2 {
+		return ""
+	}
+	valueType := gen.inferType(node.Children[1])
+	for i := 3; i < len(node.Children); i += 2 {
+		if gen.inferType(node.Children[i]) != valueType {
+			return ""
+		}
+	}
+	return valueType
+}
+
+func (gen *CodeGenerator) generateDictAccess(node *ASTNode) {
+	// Check if the dict variable is generic (intptr_t) and needs casting
+	dictName := node.Value
+	dictType := ""
+
+	// Check variable type
+	if varType, exists := gen.variables[dictName]; exists {
+		dictType = varType
+	} else if varType, exists := gen.functionVars[dictName]; exists {
+		dictType = varType
+	}
+
+	// Use hashMapGetDouble which converts values to double
+	// If generic, cast to HashMap*
+	if dictType == "generic" {
+		gen.output.WriteString("hashMapGetDouble((HashMap*)")
+		gen.output.WriteString(dictName)
+		gen.output.WriteString(", ")
+	} else {
+		gen.output.WriteString(fmt.Sprintf("hashMapGetDouble(%s, ", dictName))
+	}
+
+	gen.generateNode(node.Children[0])
+	gen.output.WriteString(")")
+}
+
+func (gen *CodeGenerator) generateDictLiteral(node *ASTNode) {
+	dictName := fmt.Sprintf("dict_%d", gen.varCounter)
+	gen.varCounter++
+
+	if node.Capacity != nil {
+		gen.output.WriteString(fmt.Sprintf("({ HashMap* %s = createHashMap(", dictName))
+		gen.generateNode(node.Capacity)
+		gen.output.WriteString("); ")
+	} else {
+		gen.output.WriteString(fmt.Sprintf("({ HashMap* %s = createHashMap(16); ", dictName))
+	}
+
+	// Add key-value pairs
+	for i := 0; i < len(node.Children); i += 2 {
+		key := node.Children[i]
+		value := node.Children[i+1]
+
+		// Determine value type
+		valueType := gen.getValueType(value)
+		ahoyTypeEnum := gen.getAhoyTypeEnum(valueType)
+
+		gen.output.WriteString(fmt.Sprintf("hashMapPutTyped(%s, ", dictName))
+
+		// If key is an identifier, convert to string literal
+		if key.Type == NODE_IDENTIFIER {
+			gen.output.WriteString(fmt.Sprintf("\"%s\"", key.Value))
+		} else {
+			gen.generateNode(key)
+		}
+
+		gen.output.WriteString(", (void*)")
+		gen.generateSlotValue(value, valueType)
+		gen.output.WriteString(fmt.Sprintf(", %s); ", ahoyTypeEnum))
+	}
+
+	gen.output.WriteString(fmt.Sprintf("%s; })", dictName))
+}
+
+func (gen *CodeGenerator) mapType(langType string) string {
+	// Check for typed collections first
+	if strings.HasPrefix(langType, "array[") {
+		return "AhoyArray*"
+	}
+	if strings.HasPrefix(langType, "dict[") || strings.HasPrefix(langType, "dict<") {
+		return "HashMap*"
+	}
+
+	// Handle known types first before pointer logic
+	switch langType {
+	case "generic":
+		return "intptr_t"
+	case "int":
+		return "int"
+	case "float":
+		return "double"
+	case "string", "char*", "char":
+		return "char*"
+	case "bool":
+		return "bool"
+	case "dict":
+		return "HashMap*"
+	case "array":
+		return "AhoyArray*"
+	case "AhoyJSON*", "json":
+		return "AhoyJSON*"
+	case "bytes":
+		return "AhoyBytes*"
+	case "range":
+		return "AhoyRange"
+	case "void":
+		return "void"
+	case "vector2", "color", "rectangle", "matrix":
+		gen.useBuiltinStruct(langType)
+		return builtinStructs[langType].cName
+	}
+
+	// Check for pointer types (e.g., "int*") but not already mapped types like "char*"
+	if strings.HasSuffix(langType, "*") {
+		baseType := strings.TrimSuffix(langType, "*")
+		// Recursively map the base type
+		mappedBase := gen.mapType(baseType)
+		return mappedBase + "*"
+	}
+
+	// Check if it's a struct type (capitalize first letter)
+	if _, exists := gen.structs[langType]; exists {
+		return capitalizeFirst(langType)
+	}
+
+	// Variables typed with a string enum hold the member's string value
+	if gen.isEnumType(langType) && gen.enumTypes[langType] == "string" {
+		return "char*"
+	}
+
+	// Check if any C type matches case-insensitively
+	// We need to find the properly-cased C type, not just accept any case
+	lowerLangType := strings.ToLower(langType)
+	for cType := range gen.cTypeDefinitions {
+		if strings.ToLower(cType) == lowerLangType {
+			// Return the properly-cased version from the type definitions
+			// Prefer PascalCase versions (e.g., Texture2D over texture2d)
+			if len(cType) > 0 && cType[0] >= 'A' && cType[0] <= 'Z' {
+				return cType
+			}
+		}
+	}
+
+	// If we only found a lowercase version, try capitalizing it
+	capitalizedType := capitalizeFirst(langType)
+	if gen.cTypeDefinitions[capitalizedType] {
+		return capitalizedType
+	}
+
+	// Last resort: if exact match exists and it's already properly cased, use it
+	if gen.cTypeDefinitions[langType] && len(langType) > 0 && langType[0] >= 'A' && langType[0] <= 'Z' {
+		return langType
+	}
+
+	return "int"
+}
+
+func (gen *CodeGenerator) inferType(node *ASTNode) string {
+	switch node.Type {
+	case NODE_TYPE_PROPERTY:
+		return "string" // .type property returns a string
+	case NODE_NUMBER:
+		if strings.Contains(node.Value, ".") {
+			return "float"
+		}
+		return "int"
+	case NODE_STRING:
+		return "string"
+	case NODE_F_STRING:
+		return "string"
+	case NODE_BYTES_LITERAL:
+		return "bytes"
+	case NODE_BOOLEAN:
+		return "bool"
+	case NODE_DICT_LITERAL:
+		return "dict"
+	case NODE_ARRAY_LITERAL:
+		// Don't infer element type from contents - only use explicit type annotations
+		// Untyped arrays are just "array"
+		return "array"
+	case NODE_FIXED_ARRAY:
+		if length, ok := gen.evalConstInt(node.Children[0], nil); ok {
+			return fmt.Sprintf("%s[%d]", node.Value, length)
+		}
+		return "unknown"
+	case NODE_OBJECT_LITERAL:
+		// Check if it's a typed object literal
+		if node.Value != "" {
+			return node.Value
+		}
+		return "struct"
+	case NODE_CALL:
+		// Infer return type of function calls; a function the program
+		// declares takes the place of the builtin of the same name
+		if returnTypes := gen.functionReturnTypes[node.Value]; gen.userFunctions[node.Value] && len(returnTypes) > 0 {
+			return returnTypes[0]
+		}
+		if builtin := gen.registeredBuiltin(node.Value); builtin != nil && builtin.ReturnType != "" {
+			return builtin.ReturnType
+		}
+		if node.Value == "sprintf" || ((node.Value == "format" || node.Value == "tr") && !gen.userFunctions[node.Value]) {
+			return "string"
+		}
+		// Type casts
+		if node.Value == "int" {
+			return "int"
+		}
+		if gen.isEnumType(node.Value) && !gen.userFunctions[node.Value] {
+			return "int"
+		}
+		if node.Value == "float" {
+			return "float"
+		}
+		if node.Value == "char" {
+			return "char"
+		}
+		if node.Value == "string" {
+			return "string"
+		}
+		if node.Value == "size_of" || node.Value == "align_of" {
+			return "int"
+		}
+		// Bitflag operations
+		if node.Value == "has_flag" {
+			return "bool"
+		}
+		if node.Value == "set_flag" || node.Value == "clear_flag" || node.Value == "combine_flags" {
+			return "int"
+		}
+		// Binary file I/O
+		if node.Value == "write_bytes" {
+			return "bool"
+		}
+		// Check if it's a C function and we know its return type
+		if returnType, exists := gen.cFunctionReturnTypes[node.Value]; exists {
+			return returnType
+		}
+		// Check if we know the function's return type (user-defined functions)
+		if returnTypes, exists := gen.functionReturnTypes[node.Value]; exists && len(returnTypes) > 0 {
+			// For single return, return that type
+			// For multiple returns, this will be used in tuple assignment context
+			return returnTypes[0]
+		}
+		return "int"
+	case NODE_METHOD_CALL:
+		// Check if this is a namespaced C function call
+		if len(node.Children) > 0 && node.Children[0].Type == NODE_IDENTIFIER {
+			namespace := node.Children[0].Value
+			methodName := node.Value
+			if returnTypeMap, exists := gen.cNamespaceReturnTypes[namespace]; exists {
+				if returnType, found := returnTypeMap[methodName]; found {
+					return returnType
+				}
+			}
+		}
+
+		// Check the object type to determine if it's a dict or array method
+		objectType := ""
+		if len(node.Children) > 0 {
+			objectType = gen.inferType(node.Children[0])
+		}
+
+		// The cli module's reads return the flag's type
+		if len(node.Children) > 0 && gen.isModule(node.Children[0], "cli") && cliMethodTypes[node.Value] != "" {
+			return cliMethodTypes[node.Value]
+		}
+		if len(node.Children) > 0 && gen.isModule(node.Children[0], "frame") && frameMethodTypes[node.Value] != "" {
+			return frameMethodTypes[node.Value]
+		}
+		if len(node.Children) > 0 && gen.isModule(node.Children[0], "mem") && memMethodTypes[node.Value] != "" {
+			return memMethodTypes[node.Value]
+		}
+
+		if isDictGet(node) && len(node.Children) > 1 {
+			return gen.dictGetType(node)
+		}
+
+		// dump_struct returns string
+		if node.Value == "dump_struct" {
+			return "string"
+		}
+
+		// clone returns a copy of the same type
+		if node.Value == "clone" && len(node.Children) > 0 {
+			return gen.declaredType(node.Children[0])
+		}
+
+		// Byte buffer methods
+		if objectType == "bytes" && node.Value == "slice" {
+			return "bytes"
+		}
+
+		// Range methods
+		if objectType == "range" && node.Value == "has" {
+			return "bool"
+		}
+		if objectType == "range" && node.Value == "to_array" {
+			return "array[int]"
+		}
+
+		// String methods that return string
+		if node.Value == "upper" || node.Value == "lower" ||
+			node.Value == "replace" || node.Value == "camel_case" ||
+			node.Value == "snake_case" || node.Value == "pascal_case" ||
+			node.Value == "kebab_case" || node.Value == "strip" ||
+			node.Value == "lpad" || node.Value == "rpad" ||
+			node.Value == "pad" || node.Value == "get_file" {
+			return "string"
+		}
+		// String methods that return int
+		if node.Value == "length" || node.Value == "count" {
+			return "int"
+		}
+		// String methods that return bool
+		if node.Value == "contains" || node.Value == "match" {
+			return "bool"
+		}
+		// String method split returns an array of the pieces
+		if node.Value == "split" {
+			return "array[string]"
+		}
+
+		// keys() and values() carry element types; typed dicts know their value type
+		if objectType == "dict" || dictValueType(objectType) != "" {
+			if node.Value == "keys" {
+				return "array[string]"
+			}
+			if node.Value == "values" {
+				if valueType := dictValueType(gen.declaredType(node.Children[0])); valueType != "" {
+					return "array[" + valueType + "]"
+				}
+				if valueType := gen.dictLiteralValueType(node.Children[0]); valueType != "" {
+					return "array[" + valueType + "]"
+				}
+				if node.Children[0].Type == NODE_IDENTIFIER && gen.dictValueTypes[node.Children[0].Value] != "" {
+					return "array[" + gen.dictValueTypes[node.Children[0].Value] + "]"
+				}
+				return "array"
+			}
+		}
+
+		// Dictionary-specific methods; the ones that give back a dict keep
+		// a typed dict's type so the next link in a chain knows it
+		if objectType == "dict" || dictValueType(objectType) != "" {
+			if node.Value == "size" {
+				return "int"
+			}
+			if node.Value == "has" || node.Value == "has_all" {
+				return "bool"
+			}
+			if node.Value == "sort" || node.Value == "stable_sort" || node.Value == "merge" ||
+				node.Value == "filter" {
+				return objectType
+			}
+			if node.Value == "map_values" {
+				return "dict"
+			}
+			if node.Value == "to_array" || node.Value == "items_sorted" {
+				return "array"
+			}
+		}
+
+		// Array methods that return the array they were called on keep its
+		// element type
+		if strings.HasPrefix(objectType, "array[") && (node.Value == "filter" ||
+			node.Value == "sort" || node.Value == "reverse" ||
+			node.Value == "shuffle" || node.Value == "push" ||
+			node.Value == "fill") {
+			return objectType
+		}
+		// Array methods that return arrays
+		if node.Value == "map" || node.Value == "filter" ||
+			node.Value == "sort" || node.Value == "reverse" ||
+			node.Value == "shuffle" || node.Value == "push" ||
+			node.Value == "fill" {
+			return "array"
+		}
+		// Array methods that return int
+		if node.Value == "sum" || node.Value == "pop" ||
+			node.Value == "pick" || node.Value == "has" {
+			return "int"
+		}
+		return "int"
+	case NODE_UNARY_OP:
+		// Handle unary operators
+		if node.Value == "&" {
+			// Address-of operator - return pointer to operand type
+			operandType := gen.inferType(node.Children[0])
+			return operandType + "*"
+		}
+		if node.Value == "^" || node.Value == "*" {
+			// Pointer dereference - return type pointed to
+			operandType := gen.inferType(node.Children[0])
+			// Remove trailing * if present
+			if strings.HasSuffix(operandType, "*") {
+				return strings.TrimSuffix(operandType, "*")
+			}
+			return "int" // fallback
+		}
+		// Other unary operators preserve type
+		return gen.inferType(node.Children[0])
+	case NODE_BINARY_OP:
+		if node.Value == "in" {
+			return "bool"
+		}
+		// Simple inference - could be more sophisticated
+		leftType := gen.inferType(node.Children[0])
+		rightType := gen.inferType(node.Children[1])
+		if vectorOperators[node.Value] != "" && (isVectorType(leftType) || isVectorType(rightType)) {
+			return "vector2"
+		}
+		switch node.Value {
+		case "/":
+			return "float"
+		case "div", "%":
+			return "int"
+		}
+		if leftType == "float" || rightType == "float" {
+			return "float"
+		}
+		return "int"
+	case NODE_INLINE:
+		return node.DataType
+	case NODE_TERNARY:
+		// Ternary returns the type of its branches (assume both branches have same type)
+		trueType := gen.inferType(node.Children[1])
+		falseType := gen.inferType(node.Children[2])
+		// If types differ, try to find common type
+		if trueType == "float" || falseType == "float" {
+			return "float"
+		}
+		if trueType == "string" || falseType == "string" {
+			return "string"
+		}
+		return trueType
+	case NODE_SWITCH_STATEMENT:
+		// The type every case body gives; conflicts are reported when the
+		// switch is generated
+		resultType, _, _ := gen.switchResultType(node)
+		return resultType
+	case NODE_IDENTIFIER:
+		if paramType, exists := gen.lambdaParams[node.Value]; exists {
+			return paramType
+		}
+		// Check if this is a JSON variable
+		if gen.jsonVariables[node.Value] {
+			return "AhoyJSON*"
+		}
+		// Locals shadow top-level variables of the same name
+		varType, exists := gen.functionVars[node.Value]
+		if !exists {
+			varType, exists = gen.variables[node.Value]
+		}
+		if exists {
+			// Normalize dict types
+			if strings.HasPrefix(varType, "dict<") || strings.HasPrefix(varType, "dict[") {
+				return "dict"
+			}
+			if strings.HasPrefix(varType, "array[") {
+				return "array"
+			}
+			return varType
+		}
+		return "int"
+	case NODE_ARRAY_ACCESS:
+		// Get the array variable name and look up its element type
+		arrayName := node.Value
+		if elemType, _, isFixed := gen.fixedArrayType(arrayName); isFixed {
+			return elemType
+		}
+		if gen.isBytesVar(arrayName) {
+			return "int"
+		}
+		if elemType, exists := gen.arrayElementType(arrayName); exists {
+			return elemType
+		}
+		// Check if the array itself is a generic parameter
+		arrayType := ""
+		if varType, exists := gen.variables[arrayName]; exists {
+			arrayType = varType
+		} else if varType, exists := gen.functionVars[arrayName]; exists {
+			arrayType = varType
+		}
+		// If array is generic, elements are also generic (intptr_t)
+		if arrayType == "generic" {
+			return "generic"
+		}
+		// Default to int if we don't know the element type
+		return "int"
+	case NODE_DICT_ACCESS:
+		// Dictionary values - use hashMapGetDouble which handles type conversion
+		return "float"
+	case NODE_OBJECT_ACCESS:
+		// Object property access with angle brackets - look up struct field type
+		if len(node.Children) > 0 {
+			objectName := node.Value
+			propertyName := node.Children[0].Value // String literal with property name
+
+			// Get the type of the object variable
+			objectType := ""
+			if varType, exists := gen.variables[objectName]; exists {
+				objectType = varType
+			} else if varType, exists := gen.functionVars[objectName]; exists {
+				objectType = varType
+			}
+
+			// Typed dicts of structs read back the boxed struct
+			if valueType := dictValueType(objectType); gen.slotStruct(valueType) != nil {
+				return valueType
+			}
+
+			// Look up the struct definition
+			if structInfo, exists := gen.structs[objectType]; exists {
+				// Find the field type
+				for _, field := range structInfo.Fields {
+					if field.Name == propertyName {
+						return field.Type
+					}
+				}
+			}
+		}
+		return "char*"
+	case NODE_MEMBER_ACCESS:
+		// Member access (dot notation) - look up struct field type
+		if len(node.Children) > 0 {
+			objectNode := node.Children[0]
+			memberName := node.Value
+
+			// The build module's constants are strings
+			if gen.isModule(objectNode, "build") {
+				return "string"
+			}
+
+			// Check if this is enum member access
+			if objectNode.Type == NODE_IDENTIFIER {
+				enumMemberKey := fmt.Sprintf("%s.%s", objectNode.Value, memberName)
+				if memberType, exists := gen.enumMemberTypes[enumMemberKey]; exists {
+					return memberType
+				}
+			}
+
+			// Get the type of the object
+			objectType := gen.inferType(objectNode)
+
+			// If object is JSON, member access returns AhoyJSON*
+			if objectType == "AhoyJSON*" {
+				return "AhoyJSON*"
+			}
+
+			// Look up the struct definition
+			if structInfo, exists := gen.structs[objectType]; exists {
+				// Find the field type
+				for _, field := range structInfo.Fields {
+					if field.Name == memberName {
+						return field.Type
+					}
+				}
+			}
+		}
+		return "char*"
+	default:
+		return "int"
+	}
+}
+
+// inferReturnTypes finds return statements in a function body and infers their types
+// It takes the full function node to have access to parameter information
+func (gen *CodeGenerator) inferReturnTypes(funcNode *ASTNode) []string {
+	if funcNode == nil || len(funcNode.Children) < 2 {
+		return []string{}
+	}
+
+	// Get parameters and body
+	params := funcNode.Children[0]
+	body := funcNode.Children[1]
+
+	// Temporarily set up functionVars with parameter types for inference
+	savedFunctionVars := gen.functionVars
+	gen.functionVars = make(map[string]string)
+	gen.dictSourcedVars = make(map[string]string)
+	gen.dictSourcedKeys = make(map[string]string)
+	gen.nestedScopeVars = make(map[string]bool)
+	for _, param := range params.Children {
+		if param.DataType != "" {
+			gen.functionVars[param.Value] = param.DataType
+		} else {
+			// Parameters without explicit type are generic (will be inferred at call site)
+			gen.functionVars[param.Value] = "generic"
+		}
+	}
+
+	// Scan function body for variable declarations to track their types
+	gen.scanVariableDeclarations(body)
+
+	// Find the first return statement
+	returnStmt := gen.findReturnStatement(body)
+
+	if returnStmt == nil {
+		// Restore functionVars
+		gen.functionVars = savedFunctionVars
+		return []string{}
+	}
+
+	// Infer types from each returned expression
+	types := []string{}
+	for _, child := range returnStmt.Children {
+		inferredType := gen.inferType(child)
+		types = append(types, inferredType)
+	}
+
+	// Restore functionVars
+	gen.functionVars = savedFunctionVars
+
+	return types
+}
+
+// scanVariableDeclarations scans a node tree and tracks variable declarations in functionVars
+func (gen *CodeGenerator) scanVariableDeclarations(node *ASTNode) {
+	if node == nil {
+		return
+	}
+
+	// Check if this is a variable declaration (assignment with no prior declaration)
+	if node.Type == NODE_VARIABLE_DECLARATION || node.Type == NODE_ASSIGNMENT {
+		varName := node.Value
+		if len(node.Children) > 0 {
+			valueNode := node.Children[0]
+			// Infer the type from the assigned value
+			varType := gen.inferType(valueNode)
+			gen.functionVars[varName] = varType
+		}
+	}
+
+	// Recursively scan children
+	for _, child := range node.Children {
+		gen.scanVariableDeclarations(child)
+	}
+}
+
+// findReturnStatement recursively finds the first return statement in a node tree
+func (gen *CodeGenerator) findReturnStatement(node *ASTNode) *ASTNode {
+	if node == nil {
+		return nil
+	}
+
+	if node.Type == NODE_RETURN_STATEMENT {
+		return node
+	}
+
+	// Recursively search children
+	for _, child := range node.Children {
+		if result := gen.findReturnStatement(child); result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// inferSwitchCaseType infers the type of a switch case body
+func (gen *CodeGenerator) inferSwitchCaseType(body *ASTNode) string {
+	if body == nil {
+		return "int"
+	}
+
+	// If it's a block, infer from last statement
+	if body.Type == NODE_BLOCK && len(body.Children) > 0 {
+		return gen.inferType(body.Children[len(body.Children)-1])
+	}
+
+	return gen.inferType(body)
+}
+
+// switchResultType unifies the types of a switch expression's case bodies.
+// int, char and float widen to the widest of them; other types must all be
+// the same. On a conflict it returns the case and the type it gives.
+func (gen *CodeGenerator) switchResultType(node *ASTNode) (string, *ASTNode, string) {
+	resultType := ""
+	for _, caseNode := range node.Children[1:] {
+		if caseNode.Type != NODE_SWITCH_CASE || len(caseNode.Children) < 2 {
+			continue
+		}
+		caseType := gen.inferSwitchCaseType(caseNode.Children[1])
+		if caseType == "char*" {
+			caseType = "string"
+		}
+		switch {
+		case caseType == "generic" || caseType == resultType:
+		case resultType == "" || resultType == "generic":
+			resultType = caseType
+		case isSwitchNumber(resultType) && isSwitchNumber(caseType):
+			if resultType == "float" || caseType == "float" {
+				resultType = "float"
+			} else {
+				resultType = "int"
+			}
+		default:
+			return resultType, caseNode, caseType
+		}
+	}
+	if resultType == "" {
+		resultType = "int"
+	}
+	return resultType, nil, ""
+}
+
+// isSwitchNumber reports whether switch cases of type t can be mixed with
+// other numbers
+func isSwitchNumber(t string) bool {
+	return t == "int" || t == "float" || t == "char"
+}
+
+// checkSwitchExpressionType reports case bodies of different types, and a
+// result that can't be stored in the variable it is assigned to
+func (gen *CodeGenerator) checkSwitchExpressionType(node *ASTNode, targetVar string) {
+	resultType, conflict, conflictType := gen.switchResultType(node)
+	if conflict != nil {
+		gen.reportError(nodeLine(conflict), fmt.Sprintf("switch case gives '%s' but earlier cases give '%s'", conflictType, resultType),
+			"every case of a switch expression must give the same type")
+		return
+	}
+
+	targetType, exists := gen.functionVars[targetVar]
+	if !exists {
+		targetType, exists = gen.variables[targetVar]
+	}
+	if targetType == "char*" {
+		targetType = "string"
+	}
+	if !exists || targetType == resultType || targetType == "generic" || resultType == "generic" ||
+		isSwitchNumber(targetType) && isSwitchNumber(resultType) {
+		return
+	}
+	if targetType == "string" || resultType == "string" || gen.structs[targetType] != nil || gen.structs[resultType] != nil {
+		gen.reportError(nodeLine(node), fmt.Sprintf("switch gives '%s' but '%s' is '%s'", resultType, targetVar, targetType))
+	}
+}
+
+// isEnumType checks if a name is an enum type
+func (gen *CodeGenerator) isEnumType(name string) bool {
+	_, exists := gen.enums[name]
+	return exists
+}
+
+func (gen *CodeGenerator) nodeToString(node *ASTNode) string {
+	oldOutput := gen.output
+	gen.output = strings.Builder{}
+	gen.generateNodeInternal(node, false)
+	result := gen.output.String()
+	gen.output = oldOutput
+	return result
+}
+
+func (gen *CodeGenerator) generateFString(node *ASTNode) {
+	// Parse f-string and extract variables
+	// Example: "hello{i}" -> format string "hello%d" and variables [i]
+	fstring := node.Value
+	var formatStr strings.Builder
+	var vars []string
+	// Values shown with a {name:spec} placeholder, by their index in vars
+	specArgs := map[int]*ASTNode{}
+
+	i := 0
+	for i < len(fstring) {
+		if fstring[i] == '{' {
+			// Find closing brace
+			j := i + 1
+			for j < len(fstring) && fstring[j] != '}' {
+				j++
+			}
+			if j < len(fstring) {
+				// Extract variable name
+				varName := fstring[i+1 : j]
+				if colon := strings.IndexByte(varName, ':'); colon > 0 {
+					if spec, ok := parsePlaceholderSpec(varName[colon+1:]); ok {
+						value := &ASTNode{Type: NODE_IDENTIFIER, Value: varName[:colon], Line: node.Line}
+						format, arg := gen.specFormat(spec, value)
+						specArgs[len(vars)] = arg
+						vars = append(vars, varName[:colon])
+						formatStr.WriteString(format)
+						i = j + 1
+						continue
+					}
+				}
+				value := &ASTNode{Type: NODE_IDENTIFIER, Value: varName, Line: node.Line}
+				if enumName := gen.printedEnum(value); enumName != "" {
+					specArgs[len(vars)] = &ASTNode{
+						Type:     NODE_CALL,
+						Value:    "__ahoy_enum_name",
+						Children: []*ASTNode{value, {Type: NODE_IDENTIFIER, Value: enumName}},
+					}
+					vars = append(vars, varName)
+					formatStr.WriteString("%s")
+					i = j + 1
+					continue
+				}
+				vars = append(vars, varName)
+
+				// Determine format specifier based on variable type
+				// For now, use %d for numbers, %s for strings
+				// We'll need to look up the variable type
+				varType := "int"
+				if knownType, exists := gen.variables[varName]; exists {
+					varType = knownType
+				}
+
+				formatSpec := "%d"
+				if varType == "string" || varType == "char*" || varType == "intptr_t" {
+					formatSpec = "%s"
+				} else if varType == "float" {
+					formatSpec = "%f"
+				} else if varType == "char" {
+					formatSpec = "%c"
+				}
+
+				formatStr.WriteString(formatSpec)
+				i = j + 1
+			} else {
+				formatStr.WriteByte(fstring[i])
+				i++
+			}
+		} else {
+			formatStr.WriteByte(fstring[i])
+			i++
+		}
+	}
+
+	// Generate sprintf call or simple string if no variables
+	if len(vars) == 0 {
+		gen.output.WriteString(fmt.Sprintf("\"%s\"", formatStr.String()))
+	} else {
+		// For now, we'll need to allocate a buffer
+		// Generate: (char[]){sprintf format, vars...}
+		// Actually, let's use a simpler approach with a static buffer
+		bufferVar := fmt.Sprintf("__fstr_buf_%d", gen.varCounter)
+		gen.varCounter++
+
+		// We need to emit this as a statement, not an expression
+		// For simplicity in expressions, we'll use a compound literal approach
+		// But C doesn't support that well for strings, so we'll generate a helper
+
+		// For now, emit inline sprintf - this works in some contexts
+		gen.output.WriteString("({\n")
+		gen.indent++
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("static char %s[256];\n", bufferVar))
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("sprintf(%s, \"%s\"", bufferVar, formatStr.String()))
+
+		for index, v := range vars {
+			gen.output.WriteString(", ")
+			if arg, ok := specArgs[index]; ok {
+				gen.generateNode(arg)
+				continue
+			}
+			// Cast intptr_t to char* for string formatting
+			varType := "int"
+			if knownType, exists := gen.variables[v]; exists {
+				varType = knownType
+			}
+			if varType == "intptr_t" {
+				gen.output.WriteString(fmt.Sprintf("(char*)%s", v))
+			} else {
+				gen.output.WriteString(v)
+			}
+		}
+
+		gen.output.WriteString(");\n")
+		gen.writeIndent()
+		gen.output.WriteString(bufferVar)
+		gen.indent--
+		gen.output.WriteString("; })")
+	}
+}
+
+// Generate enum declaration
+func (gen *CodeGenerator) generateEnum(node *ASTNode) {
+	enumName := node.Value
+	enumType := node.EnumType
+
+	// Track enum members for validation
+	if gen.enums[enumName] == nil {
+		gen.enums[enumName] = make(map[string]bool)
+	}
+
+	// Single-bit flags are checked before folding turns masks into numbers;
+	// checkEnumValues reports negative ones
+	if enumType == "flags" {
+		for _, member := range nonFlagMembers(node) {
+			if !strings.HasPrefix(member.Children[0].Value, "-") {
+				gen.reportError(member.Line, nonFlagMessage(member))
+			}
+		}
+	}
+
+	// Fold constant expression values before deciding how to generate
+	if enumType == "" || enumType == "int" || enumType == "flags" {
+		gen.foldEnumValues(node, enumType == "flags")
+		gen.checkEnumValues(node, enumType == "flags")
+	}
+
+	// Determine generation strategy based on type
+	// If no type specified AND no explicit type, analyze members to determine type
+	if enumType == "" || enumType == "int" {
+		// Check if all members are compatible with int enum
+		allInt := true
+		for _, member := range node.Children {
+			if len(member.Children) > 0 {
+				if member.Children[0].Type != NODE_NUMBER {
+					allInt = false
+					break
+				}
+				// Check if it's a float
+				if strings.Contains(member.Children[0].Value, ".") {
+					allInt = false
+					break
+				}
+			}
+		}
+
+		if allInt && enumType == "int" {
+			// Pure int enum - use C enum
+			gen.generateIntEnum(node)
+		} else if !allInt && enumType == "" {
+			// Mixed types - use flexible struct
+			gen.generateMixedEnum(node)
+		} else {
+			// enumType is explicitly "int" - use int enum
+			gen.generateIntEnum(node)
+		}
+	} else if enumType == "flags" {
+		// Bitflag enum - members are powers of two
+		gen.generateFlagsEnum(node)
+	} else if enumType == "string" {
+		// Use struct for string enums
+		gen.generateStringEnum(node)
+	} else if enumType == "array" || enumType == "dict" {
+		// Use struct for collection enums
+		gen.generateCollectionEnum(node, enumType)
+	} else if enumType == "float" {
+		// Use struct for float enums
+		gen.generateFloatEnum(node)
+	} else if enumType == "color" || enumType == "vector2" {
+		// Use struct for color/vector2 enums
+		gen.generateColorEnum(node, enumType)
+	} else {
+		// Custom types or explicitly mixed - use flexible struct
+		gen.generateMixedEnum(node)
+	}
+}
+
+// foldEnumValues replaces constant expression member values (two: one plus 1)
+// with number literals, resolving earlier members the same way the C enum
+// numbers them. Stops at the first value that is not an int constant.
+func (gen *CodeGenerator) foldEnumValues(node *ASTNode, flags bool) {
+	enumName := node.Value
+	members := make(map[string]int64)
+	next := int64(0)
+	if flags {
+		next = 1
+	}
+
+	for _, member := range node.Children {
+		val := next
+		if len(member.Children) > 0 {
+			folded, ok := gen.evalConstInt(member.Children[0], members)
+			if !ok {
+				return
+			}
+			if member.Children[0].Type != NODE_NUMBER {
+				member.Children[0] = &ASTNode{
+					Type:     NODE_NUMBER,
+					Value:    strconv.FormatInt(folded, 10),
+					DataType: "int",
+					Line:     member.Children[0].Line,
+				}
+			}
+			val = folded
+		}
+
+		// Later members may refer to this one bare; other code qualifies it
+		members[member.Value] = val
+		gen.constValues[enumName+"."+member.Value] = val
+
+		if flags {
+			next = 1
+			for next <= val {
+				next <<= 1
+			}
+		} else {
+			next = val + 1
+		}
+	}
+}
+
+// generateEnumConversion generates enum|n|, which is n once it is known to be
+// one of the enum's values. Constants are checked now, other ints when the
+// program runs.
+func (gen *CodeGenerator) generateEnumConversion(node *ASTNode) {
+	enumName := node.Value
+	if enumType := gen.enumTypes[enumName]; enumType != "int" && enumType != "flags" {
+		gen.reportError(node.Line, fmt.Sprintf("%s|...| needs an int or flags enum, %s is a %s enum", enumName, enumName, enumType))
+		return
+	}
+	if len(node.Children) != 1 {
+		gen.reportError(node.Line, fmt.Sprintf("%s|...| takes 1 argument, got %d", enumName, len(node.Children)))
+		return
+	}
+	arg := node.Children[0]
+	if other := gen.valueEnum(arg); other != "" && other != enumName {
+		gen.reportError(node.Line, fmt.Sprintf("%s|...| is given a %s value", enumName, other),
+			"convert it with int|...| first if that's intended")
+		return
+	}
+	if argType := gen.inferType(arg); argType == "float" || argType == "string" || argType == "bool" {
+		gen.reportError(node.Line, fmt.Sprintf("%s|...| takes an int, got a %s", enumName, argType))
+		return
+	}
+
+	if value, ok := gen.evalConstInt(arg, nil); ok {
+		if !gen.isEnumValue(enumName, value) {
+			gen.reportError(node.Line, fmt.Sprintf("%d is not a value of %s", value, enumName))
+			return
+		}
+		gen.output.WriteString(strconv.FormatInt(value, 10))
+		return
+	}
+
+	if !slices.Contains(gen.enumConversions, enumName) {
+		gen.enumConversions = append(gen.enumConversions, enumName)
+	}
+	gen.output.WriteString(fmt.Sprintf("ahoy_enum_%s(", enumName))
+	gen.generateNode(arg)
+	gen.output.WriteString(fmt.Sprintf(", %s, %d)", strconv.Quote(gen.sourceFilename), node.Line))
+}
+
+// enumValues returns the distinct values of an int or flags enum's members,
+// sorted
+func (gen *CodeGenerator) enumValues(enumName string) []int64 {
+	var values []int64
+	for member := range gen.enums[enumName] {
+		if value, ok := gen.constValues[enumName+"."+member]; ok && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	slices.Sort(values)
+	return values
+}
+
+// isEnumValue reports whether value is a member of an int enum, or a
+// combination of a flags enum's members
+func (gen *CodeGenerator) isEnumValue(enumName string, value int64) bool {
+	if gen.enumTypes[enumName] == "flags" {
+		var mask int64
+		for _, flag := range gen.enumValues(enumName) {
+			mask |= flag
+		}
+		return value&^mask == 0
+	}
+	return slices.Contains(gen.enumValues(enumName), value)
+}
+
+// Generate int enum using C typedef enum
+func (gen *CodeGenerator) generateIntEnum(node *ASTNode) {
+	gen.generateIntEnumAs(node, "int")
+}
+
+// Generate bitflag enum - members without explicit values get the next power of two
+func (gen *CodeGenerator) generateFlagsEnum(node *ASTNode) {
+	nextFlag := 1
+	for _, member := range node.Children {
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_NUMBER {
+			if val, err := strconv.Atoi(member.Children[0].Value); err == nil {
+				nextFlag = 1
+				for nextFlag <= val {
+					nextFlag <<= 1
+				}
+			}
+			continue
+		}
+		// Fill in the flag value so the access struct and print helper agree
+		member.Children = append(member.Children, &ASTNode{
+			Type:  NODE_NUMBER,
+			Value: strconv.Itoa(nextFlag),
+			Line:  member.Line,
+		})
+		nextFlag <<= 1
+	}
+
+	gen.generateIntEnumAs(node, "flags")
+}
+
+// generateIntEnumAs emits a C typedef enum, tracking it under the given enum type
+func (gen *CodeGenerator) generateIntEnumAs(node *ASTNode, enumType string) {
+	enumName := node.Value
+
+	// Track enum type
+	gen.enumTypes[enumName] = enumType
+
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("typedef enum {\n"))
+	gen.indent++
+
+	nextAutoValue := 0
+	for _, member := range node.Children {
+		gen.writeIndent()
+
+		// Track this member
+		gen.enums[enumName][member.Value] = true
+		// Track member type
+		gen.enumMemberTypes[fmt.Sprintf("%s.%s", enumName, member.Value)] = "int"
+
+		// Check if member has a custom value (in Children[0])
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_NUMBER {
+			value := member.Children[0].Value
+			gen.output.WriteString(fmt.Sprintf("%s_%s = %s,\n", enumName, member.Value, value))
+			// Parse the value to set nextAutoValue for next member
+			if val, err := strconv.Atoi(value); err == nil {
+				nextAutoValue = val + 1
+			}
+		} else {
+			// Auto-increment value
+			gen.output.WriteString(fmt.Sprintf("%s_%s = %d,\n", enumName, member.Value, nextAutoValue))
+			nextAutoValue++
+		}
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("} %s_enum;\n\n", enumName))
+
+	// Also generate a struct instance for member access (e.g., numbers.one)
+	gen.generateEnumAccessStruct(node, "int")
+
+	// Generate enum print helper
+	gen.generateEnumPrintHelper(node, enumName, enumType)
+	if enumType == "int" {
+		gen.generateEnumNameHelper(node, enumName)
+	}
+}
+
+// Generate string enum using struct
+func (gen *CodeGenerator) generateStringEnum(node *ASTNode) {
+	enumName := node.Value
+
+	// Track enum type
+	gen.enumTypes[enumName] = "string"
+
+	// Generate struct typedef
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("typedef struct {\n"))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		gen.enums[enumName][member.Value] = true
+		// Track member type
+		gen.enumMemberTypes[fmt.Sprintf("%s.%s", enumName, member.Value)] = "char*"
+
+		// Check if member is mutable
+		if member.IsMutable {
+			gen.output.WriteString(fmt.Sprintf("char* %s;\n", member.Value))
+		} else {
+			gen.output.WriteString(fmt.Sprintf("const char* %s;\n", member.Value))
+			gen.constEnumMembers[fmt.Sprintf("%s.%s", enumName, member.Value)] = true
+		}
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("} %s_struct;\n\n", enumName))
+
+	// Generate instance with initializer
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s_struct %s = {\n", enumName, enumName))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		var value string
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_STRING {
+			// String value - make sure it has quotes
+			rawValue := member.Children[0].Value
+			if !strings.HasPrefix(rawValue, "\"") {
+				value = fmt.Sprintf("\"%s\"", rawValue)
+			} else {
+				value = rawValue
+			}
+		} else {
+			// Default value for string is empty string
+			value = "\"\""
+		}
+		gen.output.WriteString(fmt.Sprintf(".%s = %s,\n", member.Value, value))
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("};\n\n")
+}
+
+// Generate float enum using struct
+func (gen *CodeGenerator) generateFloatEnum(node *ASTNode) {
+	enumName := node.Value
+
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("typedef struct {\n"))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		gen.enums[enumName][member.Value] = true
+
+		if member.IsMutable {
+			gen.output.WriteString(fmt.Sprintf("float %s;\n", member.Value))
+		} else {
+			gen.output.WriteString(fmt.Sprintf("const float %s;\n", member.Value))
+		}
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("} %s_struct;\n\n", enumName))
+
+	// Generate instance
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s_struct %s = {\n", enumName, enumName))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		var value string
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_NUMBER {
+			value = member.Children[0].Value
+		} else {
+			value = "0.0"
+		}
+		gen.output.WriteString(fmt.Sprintf(".%s = %s,\n", member.Value, value))
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("};\n\n")
+}
+
+// Generate color/vector2 enum using struct
+func (gen *CodeGenerator) generateColorEnum(node *ASTNode, enumType string) {
+	enumName := node.Value
+
+	// Track enum type
+	gen.enumTypes[enumName] = enumType
+
+	// Color and Vector2 types are defined globally
+
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("typedef struct {\n"))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		gen.enums[enumName][member.Value] = true
+		// Track member type
+		gen.enumMemberTypes[fmt.Sprintf("%s.%s", enumName, member.Value)] = enumType
+
+		// Check if member is mutable
+		if member.IsMutable {
+			gen.output.WriteString(fmt.Sprintf("%s %s;\n", enumType, member.Value))
+		} else {
+			gen.output.WriteString(fmt.Sprintf("const %s %s;\n", enumType, member.Value))
+		}
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("} %s_struct;\n\n", enumName))
+
+	// Generate instance with initializer
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s_struct %s = {\n", enumName, enumName))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		var value string
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_OBJECT_LITERAL {
+			// Has color/vector2 value
+			valueNode := member.Children[0]
+			if enumType == "color" && len(valueNode.Children) == 4 {
+				// Color<r,g,b,a>
+				r := valueNode.Children[0].Value
+				g := valueNode.Children[1].Value
+				b := valueNode.Children[2].Value
+				a := valueNode.Children[3].Value
+				value = fmt.Sprintf("(Color){%s, %s, %s, %s}", r, g, b, a)
+			} else if enumType == "vector2" && len(valueNode.Children) == 2 {
+				// Vector2<x,y>
+				x := valueNode.Children[0].Value
+				y := valueNode.Children[1].Value
+				value = fmt.Sprintf("(Vector2){%s, %s}", x, y)
+			} else {
+				// Default to zero
+				if enumType == "color" {
+					value = "(Color){0, 0, 0, 0}"
+				} else {
+					value = "(Vector2){0, 0}"
+				}
+			}
+		} else {
+			// Default to zero
+			if enumType == "color" {
+				value = "(Color){0, 0, 0, 0}"
+			} else {
+				value = "(Vector2){0, 0}"
+			}
+		}
+		gen.output.WriteString(fmt.Sprintf(".%s = %s,\n", member.Value, value))
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("};\n\n")
+}
+
+// Generate collection (array/dict) enum using struct
+func (gen *CodeGenerator) generateCollectionEnum(node *ASTNode, enumType string) {
+	enumName := node.Value
+	cType := "AhoyArray*"
+	if enumType == "dict" {
+		cType = "HashMap*"
+	}
+
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("typedef struct {\n"))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		gen.enums[enumName][member.Value] = true
+		// Collections are always mutable (pointer-based)
+		gen.output.WriteString(fmt.Sprintf("%s %s;\n", cType, member.Value))
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("} %s_struct;\n\n", enumName))
+
+	// Generate instance - initialized later or in init function
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s_struct %s;\n\n", enumName, enumName))
+
+	// TODO: Generate initialization function for arrays/dicts
+}
+
+// Generate mixed enum using struct with generic types
+func (gen *CodeGenerator) generateMixedEnum(node *ASTNode) {
+	enumName := node.Value
+
+	// Track enum type
+	gen.enumTypes[enumName] = "mixed"
+
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("typedef struct {\n"))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		gen.enums[enumName][member.Value] = true
+
+		// Infer type from value
+		var memberType string
+		if len(member.Children) > 0 {
+			switch member.Children[0].Type {
+			case NODE_NUMBER:
+				// Check if it's float or int
+				if strings.Contains(member.Children[0].Value, ".") {
+					memberType = "float"
+				} else {
+					memberType = "intptr_t"
+				}
+			case NODE_STRING:
+				memberType = "const char*"
+			case NODE_BOOLEAN:
+				memberType = "int"
+			case NODE_ARRAY_LITERAL:
+				memberType = "AhoyArray*"
+			case NODE_DICT_LITERAL:
+				memberType = "HashMap*"
+			default:
+				memberType = "intptr_t" // generic fallback
+			}
+		} else {
+			memberType = "intptr_t" // default
+		}
+
+		// Track member type for proper formatting in print
+		gen.enumMemberTypes[fmt.Sprintf("%s.%s", enumName, member.Value)] = memberType
+
+		// Make mutable if specified
+		if member.IsMutable || memberType == "AhoyArray*" || memberType == "HashMap*" {
+			gen.output.WriteString(fmt.Sprintf("%s %s;\n", memberType, member.Value))
+		} else {
+			// Add const for immutable non-pointer types
+			if !strings.Contains(memberType, "*") {
+				gen.output.WriteString(fmt.Sprintf("const %s %s;\n", memberType, member.Value))
+			} else {
+				gen.output.WriteString(fmt.Sprintf("%s %s;\n", memberType, member.Value))
+			}
+		}
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("} %s_struct;\n\n", enumName))
+
+	// Generate instance with initializers
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s_struct %s = {\n", enumName, enumName))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		var value string
+		if len(member.Children) > 0 {
+			switch member.Children[0].Type {
+			case NODE_NUMBER:
+				value = member.Children[0].Value
+			case NODE_STRING:
+				// Make sure string has quotes
+				rawValue := member.Children[0].Value
+				if !strings.HasPrefix(rawValue, "\"") {
+					value = fmt.Sprintf("\"%s\"", rawValue)
+				} else {
+					value = rawValue
+				}
+			case NODE_BOOLEAN:
+				if member.Children[0].Value == "true" {
+					value = "1"
+				} else {
+					value = "0"
+				}
+			case NODE_ARRAY_LITERAL:
+				// Generate array initialization inline
+				arrayNode := member.Children[0]
+				if len(arrayNode.Children) > 0 {
+					// Create array literal
+					tempBuf := &strings.Builder{}
+					tempBuf.WriteString("({ AhoyArray* arr = malloc(sizeof(AhoyArray)); ")
+					tempBuf.WriteString(fmt.Sprintf("arr->length = %d; ", len(arrayNode.Children)))
+					tempBuf.WriteString(fmt.Sprintf("arr->capacity = %d; ", len(arrayNode.Children)))
+					tempBuf.WriteString("arr->data = malloc(")
+					tempBuf.WriteString(fmt.Sprintf("%d * sizeof(intptr_t)); ", len(arrayNode.Children)))
+					tempBuf.WriteString("arr->types = malloc(")
+					tempBuf.WriteString(fmt.Sprintf("%d * sizeof(AhoyValueType)); ", len(arrayNode.Children)))
+					tempBuf.WriteString("arr->is_typed = 0; ")
+
+					// Initialize elements
+					for i, elem := range arrayNode.Children {
+						if elem.Type == NODE_NUMBER {
+							tempBuf.WriteString(fmt.Sprintf("arr->data[%d] = %s; ", i, elem.Value))
+							tempBuf.WriteString(fmt.Sprintf("arr->types[%d] = AHOY_TYPE_INT; ", i))
+						}
+					}
+
+					tempBuf.WriteString("arr; })")
+					value = tempBuf.String()
+				} else {
+					value = "NULL"
+				}
+			case NODE_DICT_LITERAL:
+				value = "NULL" // TODO: proper dict initialization
+			default:
+				value = "0"
+			}
+		} else {
+			value = "0"
+		}
+		gen.output.WriteString(fmt.Sprintf(".%s = %s,\n", member.Value, value))
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("};\n\n")
+}
+
+// Generate helper struct for enum member access (for int enums)
+func (gen *CodeGenerator) generateEnumAccessStruct(node *ASTNode, baseType string) {
+	enumName := node.Value
+
+	// Generate access struct
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("typedef struct {\n"))
+	gen.indent++
+
+	for _, member := range node.Children {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("const int %s;\n", member.Value))
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("} %s_struct;\n\n", enumName))
+
+	// Generate instance
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s_struct %s = {\n", enumName, enumName))
+	gen.indent++
+
+	nextAutoValue := 0
+	for _, member := range node.Children {
+		gen.writeIndent()
+		var value int
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_NUMBER {
+			if val, err := strconv.Atoi(member.Children[0].Value); err == nil {
+				value = val
+				nextAutoValue = val + 1
+			} else {
+				value = nextAutoValue
+				nextAutoValue++
+			}
+		} else {
+			value = nextAutoValue
+			nextAutoValue++
+		}
+		gen.output.WriteString(fmt.Sprintf(".%s = %d,\n", member.Value, value))
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("};\n\n")
+}
+
+// Generate enum print helper function
+func (gen *CodeGenerator) generateEnumPrintHelper(node *ASTNode, enumName string, enumType string) {
+	// Generate a helper function that returns a string representation of the enum
+	funcName := fmt.Sprintf("print_%s", enumName)
+
+	gen.funcDecls.WriteString(fmt.Sprintf("char* %s(void) {\n", funcName))
+	gen.funcDecls.WriteString("    char* buffer = malloc(512);\n")
+	gen.funcDecls.WriteString("    int offset = 0;\n")
+	gen.funcDecls.WriteString(fmt.Sprintf("    offset += sprintf(buffer + offset, \"enum:%s %s(\");\n", enumType, enumName))
+
+	nextAutoValue := 0
+	for i, member := range node.Children {
+		if i > 0 {
+			gen.funcDecls.WriteString("    offset += sprintf(buffer + offset, \", \");\n")
+		}
+
+		// Get member value, continuing from the last explicit value like the C enum
+		var valueStr string
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_NUMBER {
+			valueStr = member.Children[0].Value
+			if val, err := strconv.Atoi(valueStr); err == nil {
+				nextAutoValue = val + 1
+			}
+		} else {
+			valueStr = fmt.Sprintf("%d", nextAutoValue) // Auto-value
+			nextAutoValue++
+		}
+
+		gen.funcDecls.WriteString(fmt.Sprintf("    offset += sprintf(buffer + offset, \"%s:%s\");\n",
+			member.Value, valueStr))
+	}
+
+	gen.funcDecls.WriteString("    offset += sprintf(buffer + offset, \")\");\n")
+	gen.funcDecls.WriteString("    return buffer;\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// generateEnumNameHelper generates the function print shows a value of an
+// int enum with: the name of its member, or the number when it is no
+// member's
+func (gen *CodeGenerator) generateEnumNameHelper(node *ASTNode, enumName string) {
+	gen.funcDecls.WriteString(fmt.Sprintf("const char* ahoy_enum_%s_name(int value) {\n", enumName))
+	gen.funcDecls.WriteString("    switch (value) {\n")
+	nextAutoValue := 0
+	for _, member := range node.Children {
+		value := nextAutoValue
+		if len(member.Children) > 0 && member.Children[0].Type == NODE_NUMBER {
+			if val, err := strconv.Atoi(member.Children[0].Value); err == nil {
+				value = val
+			}
+		}
+		nextAutoValue = value + 1
+		gen.funcDecls.WriteString(fmt.Sprintf("        case %d: return \"%s\";\n", value, member.Value))
+	}
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    static char number[16];\n")
+	gen.funcDecls.WriteString("    snprintf(number, sizeof(number), \"%d\", value);\n")
+	gen.funcDecls.WriteString("    return number;\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// Generate constant declaration
+func (gen *CodeGenerator) generateEnumDeclaration(node *ASTNode) {
+	constantName := node.Value
+	value := node.Children[0]
+
+	// Generate as #define
+	gen.output.WriteString("#define ")
+	gen.output.WriteString(constantName)
+	gen.output.WriteString(" ")
+	gen.generateNodeInternal(value, false)
+	gen.output.WriteString("\n")
+}
+
+// Generate tuple assignment
+// generateTupleSwitchAssignment handles tuple assignment from switch expressions
+func (gen *CodeGenerator) generateTupleSwitchAssignment(leftSide *ASTNode, switchNode *ASTNode) {
+	// Declare all left-side variables first
+	for i, target := range leftSide.Children {
+		if _, exists := gen.variables[target.Value]; !exists {
+			// Infer type from first case of switch
+			if len(switchNode.Children) > 1 {
+				firstCase := switchNode.Children[1]
+				if firstCase.Type == NODE_SWITCH_CASE && len(firstCase.Children) > 1 {
+					caseBody := firstCase.Children[1]
+					// Case body should be a BLOCK node with tuple expressions
+					if caseBody.Type == NODE_BLOCK && i < len(caseBody.Children) {
+						exprType := gen.inferType(caseBody.Children[i])
+						cType := gen.mapType(exprType)
+						gen.writeIndent()
+						gen.declareVariable(cType, target.Value)
+						gen.variables[target.Value] = exprType
+						continue
+					}
+				}
+			}
+			// Fallback type
+			gen.writeIndent()
+			gen.declareVariable("int", target.Value)
+			gen.variables[target.Value] = "int"
+		}
+	}
+
+	// Generate switch with tuple assignments in each case
+	switchExpr := switchNode.Children[0]
+	switchExprType := gen.inferType(switchExpr)
+
+	// Check if this is a string switch
+	if switchExprType == "char*" || switchExprType == "string" {
+		gen.generateTupleStringSwitchExpression(switchNode, leftSide)
+		return
+	}
+
+	// Generate normal switch with tuple assignments
+	gen.writeIndent()
+	gen.output.WriteString("switch (")
+	gen.generateNode(switchExpr)
+	gen.output.WriteString(") {\n")
+
+	// Generate cases
+	for i := 1; i < len(switchNode.Children); i++ {
+		caseNode := switchNode.Children[i]
+		if caseNode.Type == NODE_SWITCH_CASE {
+			caseValue := caseNode.Children[0]
+			caseBody := caseNode.Children[1]
+
+			// Generate case label
+			gen.indent++
+			gen.writeIndent()
+			if caseValue.Type == NODE_IDENTIFIER && caseValue.Value == "_" {
+				gen.output.WriteString("default:\n")
+			} else {
+				gen.output.WriteString("case ")
+				gen.generateNode(caseValue)
+				gen.output.WriteString(":\n")
+			}
+
+			gen.indent++
+			// Generate tuple assignments
+			if caseBody.Type == NODE_BLOCK {
+				for j, expr := range caseBody.Children {
+					if j < len(leftSide.Children) {
+						gen.writeIndent()
+						gen.output.WriteString(fmt.Sprintf("%s = ", leftSide.Children[j].Value))
+						gen.generateNode(expr)
+						gen.output.WriteString(";\n")
+					}
+				}
+			}
+			gen.writeIndent()
+			gen.output.WriteString("break;\n")
+			gen.indent--
+			gen.indent--
+		}
+	}
+
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
+
+// generateTupleStringSwitchExpression handles tuple assignment from string switch
+func (gen *CodeGenerator) generateTupleStringSwitchExpression(switchNode *ASTNode, leftSide *ASTNode) {
+	switchExpr := switchNode.Children[0]
+
+	first := true
+	hasDefault := false
+	var defaultBody *ASTNode
+
+	for i := 1; i < len(switchNode.Children); i++ {
+		caseNode := switchNode.Children[i]
+		if caseNode.Type == NODE_SWITCH_CASE {
+			caseValue := caseNode.Children[0]
+			caseBody := caseNode.Children[1]
+
+			// Check for default case
+			if caseValue.Type == NODE_IDENTIFIER && caseValue.Value == "_" {
+				hasDefault = true
+				defaultBody = caseBody
+				continue
+			}
+
+			gen.writeIndent()
+			if first {
+				gen.output.WriteString("if (")
+				first = false
+			} else {
+				gen.output.WriteString("else if (")
+			}
+
+			gen.output.WriteString("strcmp(")
+			gen.generateNode(switchExpr)
+			gen.output.WriteString(", ")
+			gen.generateNode(caseValue)
+			gen.output.WriteString(") == 0) {\n")
+
+			gen.indent++
+			// Generate tuple assignments
+			if caseBody.Type == NODE_BLOCK {
+				for j, expr := range caseBody.Children {
+					if j < len(leftSide.Children) {
+						gen.writeIndent()
+						gen.output.WriteString(fmt.Sprintf("%s = ", leftSide.Children[j].Value))
+						gen.generateNode(expr)
+						gen.output.WriteString(";\n")
+					}
+				}
+			}
+			gen.indent--
+			gen.writeIndent()
+			gen.output.WriteString("}")
+		}
+	}
+
+	// Handle default case
+	if hasDefault {
+		gen.output.WriteString(" else {\n")
+		gen.indent++
+		if defaultBody.Type == NODE_BLOCK {
+			for j, expr := range defaultBody.Children {
+				if j < len(leftSide.Children) {
+					gen.writeIndent()
+					gen.output.WriteString(fmt.Sprintf("%s = ", leftSide.Children[j].Value))
+					gen.generateNode(expr)
+					gen.output.WriteString(";\n")
+				}
+			}
+		}
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	} else {
+		gen.output.WriteString("\n")
+	}
+}
+
+// tupleCall returns the call a tuple assignment takes its values from, or
+// nil when the right side isn't a single call. A method call or member
+// access naming a function of the program that returns several values,
+// pos.unpack|| or pos.unpack, calls it with the object first.
+func (gen *CodeGenerator) tupleCall(rightSide *ASTNode) *ASTNode {
+	if len(rightSide.Children) != 1 {
+		return nil
+	}
+	value := rightSide.Children[0]
+	switch value.Type {
+	case NODE_CALL:
+		return value
+	case NODE_METHOD_CALL, NODE_MEMBER_ACCESS:
+		if !gen.userFunctions[value.Value] || len(gen.functionReturnTypes[value.Value]) < 2 || len(value.Children) == 0 {
+			return nil
+		}
+		args := []*ASTNode{value.Children[0]}
+		if value.Type == NODE_METHOD_CALL && len(value.Children) > 1 {
+			args = append(args, value.Children[1].Children...)
+		}
+		return &ASTNode{Type: NODE_CALL, Value: value.Value, Line: value.Line, Children: args}
+	}
+	return nil
+}
+
+func (gen *CodeGenerator) generateTupleAssignment(node *ASTNode) {
+	leftSide := node.Children[0]
+	rightSide := node.Children[1]
+
+	// value, found: d.get|key|
+	if len(rightSide.Children) == 1 && isDictGet(rightSide.Children[0]) {
+		gen.generateDictGetTuple(leftSide.Children, rightSide.Children[0])
+		return
+	}
+
+	// Check if right side is a single function call that returns multiple values
+	if callNode := gen.tupleCall(rightSide); callNode != nil {
+		funcName := callNode.Value
+
+		// Generate the function call into a temp struct
+		tempVar := fmt.Sprintf("__multi_ret_%d", gen.varCounter)
+		gen.varCounter++
+
+		gen.writeIndent()
+		// Special case: read_json uses json_read_return struct
+		structName := funcName
+		if funcName == "read_json" {
+			structName = "json_read"
+		}
+		if gen.outParams[funcName] {
+			// The function writes its results straight into the struct
+			gen.output.WriteString(fmt.Sprintf("%s_return %s;\n", structName, tempVar))
+			gen.writeIndent()
+			gen.callOut = tempVar
+		} else {
+			gen.output.WriteString(fmt.Sprintf("%s_return %s = ", structName, tempVar))
+		}
+		gen.generateNode(callNode)
+		gen.output.WriteString(";\n")
+
+		// Special handling for read_json - track that first return value is AhoyJSON*
+		if funcName == "read_json" && len(leftSide.Children) >= 1 {
+			jsonVarName := leftSide.Children[0].Value
+			gen.jsonVariables[jsonVarName] = true // Track this as a JSON variable
+		}
+
+		// Assign struct fields to left side variables
+		for i, target := range leftSide.Children {
+			gen.writeIndent()
+			// Check if variable needs to be declared
+			existsInFunc := false
+			existsGlobal := false
+			if gen.functionVars != nil {
+				_, existsInFunc = gen.functionVars[target.Value]
+			}
+			_, existsGlobal = gen.variables[target.Value]
+
+			if !existsInFunc && !existsGlobal {
+				// Need to declare variable - look up function return types
+				cType := "int" // default
+				inferredType := "int"
+				needsCast := false
+
+				// Special case for read_json return values
+				if funcName == "read_json" {
+					if i == 0 {
+						cType = "AhoyJSON*"
+						inferredType = "AhoyJSON*"
+						// Track as JSON variable
+						gen.jsonVariables[target.Value] = true
+					} else if i == 1 {
+						cType = "char*"
+						inferredType = "char*"
+					}
+				} else if retTypes, ok := gen.functionReturnTypes[funcName]; ok && i < len(retTypes) {
+					// If return type is "generic", infer from actual call arguments
+					if retTypes[i] == "generic" && i < len(callNode.Children) {
+						inferredType = gen.inferType(callNode.Children[i])
+						needsCast = true // Need to cast from intptr_t
+					} else {
+						inferredType = retTypes[i]
+					}
+
+					cType = gen.mapType(inferredType)
+					if gen.functionVars != nil {
+						gen.functionVars[target.Value] = inferredType
+					} else {
+						gen.variables[target.Value] = inferredType
+					}
+					// Track JSON variables
+					if inferredType == "AhoyJSON*" {
+						gen.jsonVariables[target.Value] = true
+					}
+				} else {
+					if gen.functionVars != nil {
+						gen.functionVars[target.Value] = "int"
+					} else {
+						gen.variables[target.Value] = "int"
+					}
+				}
+				gen.writeVariableType(cType, target.Value)
+
+				// If we need to cast from intptr_t (for generic types), do it here
+				if needsCast {
+					if cType == "char*" {
+						gen.output.WriteString(fmt.Sprintf("%s = (char*)%s.ret%d;\n", target.Value, tempVar, i))
+					} else {
+						gen.output.WriteString(fmt.Sprintf("%s = (%s)%s.ret%d;\n", target.Value, cType, tempVar, i))
+					}
+				} else {
+					gen.output.WriteString(fmt.Sprintf("%s = %s.ret%d;\n", target.Value, tempVar, i))
+				}
+			} else {
+				gen.output.WriteString(fmt.Sprintf("%s = %s.ret%d;\n", target.Value, tempVar, i))
+			}
+		}
+		return
+	}
+
+	// Check if right side is a single switch statement returning a tuple
+	if len(rightSide.Children) == 1 && rightSide.Children[0].Type == NODE_SWITCH_STATEMENT {
+		gen.generateTupleSwitchAssignment(leftSide, rightSide.Children[0])
+		return
+	}
+
+	// Generate temporary variables for regular tuple assignment
+	temps := make([]string, len(rightSide.Children))
+	for i, expr := range rightSide.Children {
+		tempVar := fmt.Sprintf("__temp_%d", gen.varCounter)
+		gen.varCounter++
+		temps[i] = tempVar
+
+		// Infer type from the expression
+		exprType := gen.inferType(expr)
+		cType := gen.mapType(exprType)
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s %s = ", cType, tempVar))
+		gen.generateNodeInternal(expr, false)
+		gen.output.WriteString(";\n")
+	}
+
+	// Assign temps to left side variables
+	for i, target := range leftSide.Children {
+		if i < len(temps) {
+			gen.writeIndent()
+			// Check if variable needs to be declared
+			existsInFunc := false
+			existsGlobal := false
+			if gen.functionVars != nil {
+				_, existsInFunc = gen.functionVars[target.Value]
+			}
+			_, existsGlobal = gen.variables[target.Value]
+
+			if !existsInFunc && !existsGlobal {
+				// Need to declare variable - infer type from temp
+				tempType := gen.inferType(rightSide.Children[i])
+				cType := gen.mapType(tempType)
+				gen.writeVariableType(cType, target.Value)
+				if gen.functionVars != nil {
+					gen.functionVars[target.Value] = tempType
+				} else {
+					gen.variables[target.Value] = tempType
+				}
+			}
+			gen.output.WriteString(fmt.Sprintf("%s = %s;\n", target.Value, temps[i]))
+		}
+	}
+}
+
+// Generate struct declaration
+func (gen *CodeGenerator) generateStruct(node *ASTNode) {
+	structName := node.Value
+
+	// Handle JSON structs - just store schema, don't generate C code
+	if node.DataType == "json" {
+		structInfo := &StructInfo{
+			Name:   structName,
+			Fields: make([]StructFieldInfo, 0),
+		}
+
+		for _, field := range node.Children {
+			if field.Type != NODE_TYPE {
+				fieldType := field.DataType
+				if fieldType == "" {
+					fieldType = "string" // Default to string for JSON
+				}
+				structInfo.Fields = append(structInfo.Fields, StructFieldInfo{
+					Name: field.Value,
+					Type: fieldType,
+					JSON: parseJSONTag(field.Value, field.Tag),
+				})
+			}
+		}
+
+		// Store in structs map for type validation
+		gen.structs[structName] = structInfo
+		gen.structs["json_"+structName] = structInfo // Also store with json_ prefix
+		// Mark as JSON struct so we don't generate C code for it
+		gen.jsonStructs[structName] = true
+		gen.jsonStructs["json_"+structName] = true
+		return
+	}
+
+	// Skip vector2, color, rectangle and matrix - they're predefined
+	if _, builtin := builtinStructs[structName]; builtin {
+		// Still register struct info for type checking
+		cStructName := capitalizeFirst(structName)
+		structInfo := &StructInfo{
+			Name:   structName,
+			Fields: make([]StructFieldInfo, 0),
+		}
+
+		// Add fields for tracking
+		for _, field := range node.Children {
+			if field.Type != NODE_TYPE {
+				fieldType := gen.mapType(field.DataType)
+				defaultValue := gen.generateDefaultValue(field.DefaultValue)
+				structInfo.Fields = append(structInfo.Fields, StructFieldInfo{
+					Name:         field.Value,
+					Type:         fieldType,
+					DefaultValue: defaultValue,
+					JSON:         parseJSONTag(field.Value, field.Tag),
+				})
+			}
+		}
+
+		gen.structs[structName] = structInfo
+		gen.structs[cStructName] = structInfo
+		return
+	}
+
+	// Separate regular fields from nested types
+	var baseFields []*ASTNode
+	var nestedTypes []*ASTNode
+
+	for _, child := range node.Children {
+		if child.Type == NODE_TYPE {
+			nestedTypes = append(nestedTypes, child)
+		} else {
+			baseFields = append(baseFields, child)
+		}
+	}
+
+	// Generate nested types first (they inherit from base struct)
+	for _, nestedType := range nestedTypes {
+		gen.generateNestedStruct(nestedType, structName, baseFields)
+	}
+
+	// Generate base struct - write to structDecls instead of output
+	cStructName := capitalizeFirst(structName)
+	structInfo := &StructInfo{
+		Name:   structName,
+		Fields: make([]StructFieldInfo, 0),
+	}
+
+	gen.structDecls.WriteString(fmt.Sprintf("typedef struct {\n"))
+
+	for _, field := range baseFields {
+		fieldType := gen.mapType(field.DataType)
+		gen.structDecls.WriteString(fmt.Sprintf("    %s %s;\n", fieldType, field.Value))
+
+		// Track field info with default value
+		defaultValue := gen.generateDefaultValue(field.DefaultValue)
+		structInfo.Fields = append(structInfo.Fields, StructFieldInfo{
+			Name:         field.Value,
+			Type:         fieldType,
+			DefaultValue: defaultValue,
+			JSON:         parseJSONTag(field.Value, field.Tag),
+		})
+	}
+
+	gen.structDecls.WriteString(fmt.Sprintf("} %s;\n\n", cStructName))
+
+	// Store struct info with both lowercase and capitalized names
+	gen.structs[structName] = structInfo
+	gen.structs[cStructName] = structInfo
+}
+
+// Helper to generate C code for a default value
+func (gen *CodeGenerator) generateDefaultValue(node *ASTNode) string {
+	if node == nil {
+		return ""
+	}
+
+	switch node.Type {
+	case NODE_NUMBER:
+		return node.Value
+	case NODE_STRING:
+		return fmt.Sprintf("\"%s\"", node.Value)
+	case NODE_BOOLEAN:
+		if node.Value == "true" {
+			return "true"
+		}
+		return "false"
+	case NODE_CALL:
+		// Handle old-style function calls (backward compatibility)
+		if node.Value == "vector2" && len(node.Children) == 2 {
+			x := gen.generateDefaultValue(node.Children[0])
+			y := gen.generateDefaultValue(node.Children[1])
+			return fmt.Sprintf("(Vector2){.x = %s, .y = %s}", x, y)
+		}
+		if node.Value == "color" && len(node.Children) == 4 {
+			r := gen.generateDefaultValue(node.Children[0])
+			g := gen.generateDefaultValue(node.Children[1])
+			b := gen.generateDefaultValue(node.Children[2])
+			a := gen.generateDefaultValue(node.Children[3])
+			return fmt.Sprintf("(Color){.r = %s, .g = %s, .b = %s, .a = %s}", r, g, b, a)
+		}
+	case NODE_OBJECT_LITERAL:
+		// Handle object literal default values like vector2{x:10, y:20}
+		if node.Value != "" {
+			// Typed object literal
+			typeName := capitalizeFirst(node.Value)
+			var builder strings.Builder
+			builder.WriteString(fmt.Sprintf("(%s){", typeName))
+
+			first := true
+			for _, prop := range node.Children {
+				if prop.Type == NODE_OBJECT_PROPERTY {
+					if !first {
+						builder.WriteString(", ")
+					}
+					builder.WriteString(".")
+					builder.WriteString(prop.Value)
+					builder.WriteString(" = ")
+					if len(prop.Children) > 0 {
+						builder.WriteString(gen.generateDefaultValue(prop.Children[0]))
+					}
+					first = false
+				}
+			}
+			builder.WriteString("}")
+			return builder.String()
+		}
+	case NODE_ARRAY_LITERAL:
+		// Generate array literal inline
+		var builder strings.Builder
+		dictName := fmt.Sprintf("arr_%d", gen.dictCounter)
+		gen.dictCounter++
+		builder.WriteString("({ AhoyArray* ")
+		builder.WriteString(dictName)
+		builder.WriteString(" = malloc(sizeof(AhoyArray)); ")
+		builder.WriteString(dictName)
+		builder.WriteString("->length = 0; ")
+		builder.WriteString(dictName)
+		builder.WriteString("->capacity = 0; ")
+		builder.WriteString(dictName)
+		builder.WriteString("->data = malloc(0 * sizeof(intptr_t)); ")
+		builder.WriteString(dictName)
+		builder.WriteString("->types = malloc(0 * sizeof(AhoyValueType)); ")
+		builder.WriteString(dictName)
+		builder.WriteString("->is_typed = 0; ")
+		for _, elem := range node.Children {
+			builder.WriteString("ahoy_array_push(")
+			builder.WriteString(dictName)
+			valueType := gen.inferType(elem)
+			if valueType == "float" {
+				builder.WriteString(", ahoy_box_float(")
+				builder.WriteString(gen.generateDefaultValue(elem))
+				builder.WriteString(")")
+			} else {
+				builder.WriteString(", (intptr_t)")
+				builder.WriteString(gen.generateDefaultValue(elem))
+			}
+			builder.WriteString(fmt.Sprintf(", %s); ", gen.getAhoyTypeEnum(valueType)))
+		}
+		builder.WriteString(dictName)
+		builder.WriteString("; })")
+		return builder.String()
+	case NODE_DICT_LITERAL:
+		// Generate dict literal inline
+		var builder strings.Builder
+		dictName := fmt.Sprintf("dict_%d", gen.dictCounter)
+		gen.dictCounter++
+		builder.WriteString("({ HashMap* ")
+		builder.WriteString(dictName)
+		if node.Capacity != nil {
+			builder.WriteString(" = createHashMap(" + gen.generateDefaultValue(node.Capacity) + "); ")
+		} else {
+			builder.WriteString(" = createHashMap(16); ")
+		}
+		for i := 0; i < len(node.Children); i += 2 {
+			if i+1 < len(node.Children) {
+				key := node.Children[i]
+				value := node.Children[i+1]
+				builder.WriteString("hashMapPutTyped(")
+				builder.WriteString(dictName)
+				builder.WriteString(", ")
+				builder.WriteString(gen.generateDefaultValue(key))
+				valueType := gen.inferType(value)
+				if valueType == "float" {
+					builder.WriteString(", (void*)ahoy_box_float(")
+					builder.WriteString(gen.generateDefaultValue(value))
+					builder.WriteString(")")
+				} else {
+					builder.WriteString(", (void*)(intptr_t)")
+					builder.WriteString(gen.generateDefaultValue(value))
+				}
+				builder.WriteString(fmt.Sprintf(", %s); ", gen.getAhoyTypeEnum(valueType)))
+			}
+		}
+		builder.WriteString(dictName)
+		builder.WriteString("; })")
+		return builder.String()
+	}
+	return ""
+}
+
+// Get default value for a type
+func (gen *CodeGenerator) getTypeDefault(cType string) string {
+	switch cType {
+	case "int":
+		return "0"
+	case "double", "float":
+		return "0.0"
+	case "char*", "const char*":
+		return "\"\""
+	case "bool":
+		return "false"
+	case "Vector2":
+		return "(Vector2){.x = 0, .y = 0}"
+	case "Color":
+		return "(Color){.r = 0, .g = 0, .b = 0, .a = 0}"
+	case "Rectangle", "Matrix":
+		return fmt.Sprintf("(%s){0}", cType)
+	case "AhoyArray*":
+		return "({ AhoyArray* arr = malloc(sizeof(AhoyArray)); arr->length = 0; arr->capacity = 0; arr->data = malloc(0 * sizeof(intptr_t)); arr->types = malloc(0 * sizeof(AhoyValueType)); arr->is_typed = 0; arr; })"
+	case "HashMap*":
+		return "createHashMap(16)"
+	default:
+		return ""
+	}
+}
+
+// Generate a nested struct type that inherits fields from parent
+func (gen *CodeGenerator) generateNestedStruct(node *ASTNode, parentName string, parentFields []*ASTNode) {
+	typeName := node.Value
+	cTypeName := capitalizeFirst(typeName)
+
+	// Track struct info
+	structInfo := &StructInfo{
+		Name:   typeName,
+		Fields: make([]StructFieldInfo, 0),
+	}
+
+	gen.structDecls.WriteString(fmt.Sprintf("typedef struct {\n"))
+
+	// First, include parent fields
+	for _, field := range parentFields {
+		fieldType := gen.mapType(field.DataType)
+		gen.structDecls.WriteString(fmt.Sprintf("    %s %s;\n", fieldType, field.Value))
+
+		// Track field info with default value
+		defaultValue := ""
+		if field.DefaultValue != nil {
+			defaultValue = gen.generateDefaultValue(field.DefaultValue)
+		} else {
+			defaultValue = gen.getTypeDefault(fieldType)
+		}
+
+		structInfo.Fields = append(structInfo.Fields, StructFieldInfo{
+			Name:         field.Value,
+			Type:         fieldType,
+			DefaultValue: defaultValue,
+			JSON:         parseJSONTag(field.Value, field.Tag),
+		})
+	}
+
+	// Then, add nested type's own fields
+	for _, field := range node.Children {
+		fieldType := gen.mapType(field.DataType)
+		gen.structDecls.WriteString(fmt.Sprintf("    %s %s;\n", fieldType, field.Value))
+
+		// Track field info with default value if present
+		defaultValue := ""
+		if field.DefaultValue != nil {
+			defaultValue = gen.generateDefaultValue(field.DefaultValue)
+		} else {
+			// Apply type-specific defaults
+			defaultValue = gen.getTypeDefault(fieldType)
+		}
+
+		structInfo.Fields = append(structInfo.Fields, StructFieldInfo{
+			Name:         field.Value,
+			Type:         fieldType,
+			DefaultValue: defaultValue,
+			JSON:         parseJSONTag(field.Value, field.Tag),
+		})
+	}
+
+	gen.structDecls.WriteString(fmt.Sprintf("} %s;\n\n", cTypeName))
+
+	// Store struct info with both lowercase and capitalized names
+	gen.structs[typeName] = structInfo
+	gen.structs[cTypeName] = structInfo
+}
+
+// Generate method call
+
+// Generate member access
+func (gen *CodeGenerator) generateMemberAccess(node *ASTNode) {
+	object := node.Children[0]
+	memberName := node.Value
+
+	if gen.isModule(object, "build") {
+		gen.generateBuildConstant(node)
+		return
+	}
+
+	// Check if this is enum member access (enum_name.MEMBER)
+	if object.Type == NODE_IDENTIFIER {
+		// Check if the identifier is an enum name
+		if gen.isEnumType(object.Value) {
+			enumName := object.Value
+			enumType := gen.enumTypes[enumName]
+
+			// For int and flags enums, use the C enum format: enum_name_MEMBER
+			// This is needed for switch cases and constant expressions
+			if enumType == "int" || enumType == "flags" {
+				gen.output.WriteString(enumName)
+				gen.output.WriteString("_")
+				gen.output.WriteString(memberName)
+				return
+			}
+
+			// For other enum types (string, etc.), use struct member access: enum_name.member
+			if gen.constEnumMembers[enumName+"."+memberName] {
+				// Ahoy strings are char*; the const only stops the member being assigned
+				gen.output.WriteString("(char*)")
+			}
+			gen.output.WriteString(enumName)
+			gen.output.WriteString(".")
+			gen.output.WriteString(memberName)
+			return
+		}
+
+		// Members of C enums from headers
+		if enumName := gen.cEnumType(object.Value); enumName != "" && !gen.isVariable(object.Value) {
+			gen.generateCEnumMember(enumName, memberName, node.Line)
+			return
+		}
+	}
+
+	// A struct stored in a dict is reached through the pointer its slot
+	// holds, which only a dict typed with the struct as its values has
+	if object.Type == NODE_OBJECT_ACCESS {
+		dictType, isVar := gen.variables[object.Value]
+		if !isVar {
+			dictType = gen.functionVars[object.Value]
+		}
+		if valueType := dictValueType(dictType); gen.slotStruct(valueType) == nil {
+			if valueType != "" {
+				gen.reportError(node.Line, fmt.Sprintf("'%s' holds %s values, which have no field '%s'", object.Value, valueType, memberName))
+				return
+			}
+			if dictType == "dict" || dictType == "HashMap*" {
+				gen.reportError(node.Line, fmt.Sprintf("can't reach field '%s' of a value in '%s', a dict without a value type", memberName, object.Value),
+					fmt.Sprintf("Give it the struct as its value type: %s: dict<string, StructName> = ...", object.Value))
+				return
+			}
+		}
+	}
+
+	// Check if object is a HashMap (anonymous object) - need special handling
+	objectType := gen.inferType(object)
+
+	// Check if this is JSON object access
+	if objectType == "AhoyJSON*" || objectType == "json" {
+		// JSON object - use ahoy_json_get
+		gen.output.WriteString("ahoy_json_get(")
+		gen.generateNodeInternal(object, false)
+		gen.output.WriteString(fmt.Sprintf(", \"%s\")", memberName))
+		return
+	}
+
+	if objectType == "HashMap*" || objectType == "dict" {
+		// Anonymous object stored in HashMap - use hashMapGet
+		// Note: returns void*, caller needs to cast appropriately
+		gen.output.WriteString("hashMapGet(")
+		gen.generateNodeInternal(object, false)
+		gen.output.WriteString(fmt.Sprintf(", \"%s\")", memberName))
+		return
+	}
+
+	gen.generateNodeInternal(object, false)
+
+	// Check if object is a pointer type (array or struct pointer)
+	if objectType == "AhoyArray*" || objectType == "array" ||
+		strings.HasSuffix(objectType, "*") {
+		gen.output.WriteString("->")
+	} else {
+		gen.output.WriteString(".")
+	}
+	gen.output.WriteString(memberName)
+}
+
+func (gen *CodeGenerator) generateTypeProperty(node *ASTNode) {
+	// Generate code to return type string for .type property
+	object := node.Children[0]
+	objectName := ""
+
+	// Extract object name/identifier
+	if object.Type == NODE_IDENTIFIER {
+		objectName = object.Value
+	} else if object.Type == NODE_MEMBER_ACCESS {
+		// For enum.member.type
+		if len(object.Children) > 0 && object.Children[0].Type == NODE_IDENTIFIER {
+			enumName := object.Children[0].Value
+			memberName := object.Value
+			if gen.isEnumType(enumName) {
+				// Get the member type
+				memberKey := fmt.Sprintf("%s.%s", enumName, memberName)
+				if memberType, exists := gen.enumMemberTypes[memberKey]; exists {
+					ahoyType := gen.cTypeToAhoyType(memberType)
+					gen.output.WriteString(fmt.Sprintf("\"%s\"", ahoyType))
+					return
+				}
+			}
+		}
+	}
+
+	// Check if this is an enum type itself (enum.type)
+	if object.Type == NODE_IDENTIFIER && gen.isEnumType(objectName) {
+		if enumType, exists := gen.enumTypes[objectName]; exists {
+			// For mixed enums, just print "enum"
+			if enumType == "mixed" || enumType == "" {
+				gen.output.WriteString("\"enum\"")
+			} else {
+				gen.output.WriteString(fmt.Sprintf("\"enum:%s\"", enumType))
+			}
+			return
+		}
+	}
+
+	// Generate inline expression that returns type string
+	gen.output.WriteString("({")
+	gen.output.WriteString("char* __type_str = malloc(64); ")
+
+	// Check variable type to determine how to get type info
+	varType := gen.inferType(object)
+
+	if varType == "array" || varType == "AhoyArray*" || strings.HasPrefix(varType, "array[") {
+		// Array type - check if typed
+		gen.output.WriteString(fmt.Sprintf("if (%s != NULL && %s->is_typed) { ", objectName, objectName))
+		gen.output.WriteString(fmt.Sprintf("const char* elem_type = ahoy_type_enum_to_string(%s->element_type); ", objectName))
+		gen.output.WriteString("sprintf(__type_str, \"array[%s]\", elem_type); ")
+		gen.output.WriteString("} else { ")
+		gen.output.WriteString("strcpy(__type_str, \"array\"); ")
+		gen.output.WriteString("} ")
+	} else if varType == "dict" || varType == "HashMap*" {
+		// Dict type - for now just return "dict"
+		// TODO: Add typed dict support
+		gen.output.WriteString("strcpy(__type_str, \"dict\"); ")
+	} else {
+		// Other types - convert C type back to Ahoy type name
+		ahoyType := gen.cTypeToAhoyType(varType)
+		gen.output.WriteString(fmt.Sprintf("strcpy(__type_str, \"%s\"); ", ahoyType))
+	}
+
+	gen.output.WriteString("__type_str; ")
+	gen.output.WriteString("})")
+}
+
+// cTypeToAhoyType converts C type names back to Ahoy type names
+func (gen *CodeGenerator) cTypeToAhoyType(cType string) string {
+	switch cType {
+	case "char*":
+		return "string"
+	case "int":
+		return "int"
+	case "double", "float":
+		return "float"
+	case "bool":
+		return "bool"
+	case "AhoyArray*":
+		return "array"
+	case "HashMap*":
+		return "dict"
+	default:
+		if strings.HasPrefix(cType, "array[") {
+			return cType // Already in Ahoy format
+		}
+		return cType
+	}
+}
+
+// Helper function to convert AhoyValueType enum to string
+func (gen *CodeGenerator) writeTypeEnumToStringHelper() {
+	gen.funcDecls.WriteString("const char* ahoy_type_enum_to_string(AhoyValueType type) {\n")
+	gen.funcDecls.WriteString("    switch(type) {\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_INT: return \"int\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_STRING: return \"string\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_FLOAT: return \"float\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_CHAR: return \"char\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_BOOL: return \"bool\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_ARRAY: return \"array\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_DICT: return \"dict\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_JSON: return \"json\";\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_NULL: return \"null\";\n")
+	gen.funcDecls.WriteString("        default:\n")
+	if len(gen.slotStructs) > 0 {
+		gen.funcDecls.WriteString("            if (type >= AHOY_TYPE_STRUCT) return ahoy_slot_structs[type - AHOY_TYPE_STRUCT].name;\n")
+	}
+	gen.funcDecls.WriteString("            return \"unknown\";\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// Generate signal handler for better crash reporting
+func (gen *CodeGenerator) getSignalHandler() string {
+	return `// Signal handler for crash reporting
+#include <signal.h>
+
+void ahoy_signal_handler(int sig) {
+    fprintf(stderr, "\n");
+    fprintf(stderr, "========================================\n");
+    fprintf(stderr, "  Ahoy Program Crashed\n");
+    fprintf(stderr, "========================================\n");
+    fprintf(stderr, "\n");
+
+    switch(sig) {
+        case SIGSEGV:
+            fprintf(stderr, "Error: Memory access violation (segmentation fault)\n");
+            fprintf(stderr, "This usually happens when:\n");
+            fprintf(stderr, "  - Accessing memory that doesn't belong to your program\n");
+            fprintf(stderr, "  - Using a null pointer\n");
+            fprintf(stderr, "  - Accessing freed memory\n");
+            break;
+        case SIGABRT:
+            fprintf(stderr, "Error: Program aborted\n");
+            fprintf(stderr, "This usually happens when:\n");
+            fprintf(stderr, "  - An assertion failed\n");
+            fprintf(stderr, "  - A serious error was detected\n");
+            break;
+        case SIGFPE:
+            fprintf(stderr, "Error: Arithmetic error (floating point exception)\n");
+            fprintf(stderr, "This usually happens when:\n");
+            fprintf(stderr, "  - Dividing by zero\n");
+            fprintf(stderr, "  - Integer overflow\n");
+            break;
+        case SIGILL:
+            fprintf(stderr, "Error: Illegal instruction\n");
+            fprintf(stderr, "This usually happens when:\n");
+            fprintf(stderr, "  - Corrupted memory\n");
+            fprintf(stderr, "  - Invalid code execution\n");
+            break;
+        default:
+            fprintf(stderr, "Error: Program received signal %d\n", sig);
+            break;
+    }
+
+    fprintf(stderr, "\n");
+    fprintf(stderr, "Tips for debugging:\n");
+    fprintf(stderr, "  - Check array accesses are within bounds\n");
+    fprintf(stderr, "  - Ensure variables are initialized before use\n");
+    fprintf(stderr, "  - Verify pointers are not null\n");
+    fprintf(stderr, "\n");
+    fprintf(stderr, "========================================\n");
+
+    // Exit the way a shell reports a fatal signal
+    exit(128 + sig);
+}
+
+void ahoy_setup_signal_handlers(void) {
+    signal(SIGSEGV, ahoy_signal_handler);
+    signal(SIGABRT, ahoy_signal_handler);
+    signal(SIGFPE, ahoy_signal_handler);
+    signal(SIGILL, ahoy_signal_handler);
+}
+`
+}
+
+// Generate array helper functions
+func (gen *CodeGenerator) writeArrayHelperFunctions() {
+	// Note: AhoyArray structure is now defined in the header section
+
+	if len(gen.arrayMethods) == 0 {
+		return
+	}
+
+	gen.addInclude("time.h") // For shuffle
+
+	// length method
+	if gen.arrayMethods["length"] {
+		gen.funcDecls.WriteString("int ahoy_array_length(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    return arr->length;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// push method
+	if gen.arrayMethods["push"] {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_array_push(AhoyArray* arr, intptr_t value, AhoyValueType type) {\n")
+		gen.funcDecls.WriteString("    if (arr->length >= arr->capacity) {\n")
+		gen.funcDecls.WriteString("        arr->capacity = arr->capacity == 0 ? 4 : arr->capacity * 2;\n")
+		gen.funcDecls.WriteString("        arr->data = realloc(arr->data, arr->capacity * sizeof(intptr_t));\n")
+		gen.funcDecls.WriteString("        arr->types = realloc(arr->types, arr->capacity * sizeof(AhoyValueType));\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    arr->data[arr->length] = value;\n")
+		gen.funcDecls.WriteString("    arr->types[arr->length] = type;\n")
+		gen.funcDecls.WriteString("    arr->length++;\n")
+		gen.funcDecls.WriteString("    arr->mods++;\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// pop method
+	if gen.arrayMethods["pop"] {
+		gen.funcDecls.WriteString("intptr_t ahoy_array_pop(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    if (arr->length == 0) return 0;\n")
+		gen.funcDecls.WriteString("    arr->mods++;\n")
+		gen.funcDecls.WriteString("    return arr->data[--arr->length];\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// sum method
+	if gen.arrayMethods["sum"] {
+		gen.funcDecls.WriteString("int ahoy_array_sum(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    int total = 0;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < arr->length; i++) {\n")
+		gen.funcDecls.WriteString("        total += (int)arr->data[i];\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return total;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// has method
+	if gen.arrayMethods["has"] {
+		gen.funcDecls.WriteString("int ahoy_array_has(AhoyArray* arr, intptr_t value, AhoyValueType type) {\n")
+		gen.funcDecls.WriteString("    AhoyValue needle = ahoy_value_from_raw(value, type);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < arr->length; i++) {\n")
+		gen.funcDecls.WriteString("        if (ahoy_value_equals(ahoy_array_get_value(arr, i), needle)) return 1;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return 0;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// sort method
+	if gen.arrayMethods["sort"] {
+		gen.funcDecls.WriteString("int __ahoy_compare_ints(const void* a, const void* b) {\n")
+		gen.funcDecls.WriteString("    return (*(intptr_t*)a - *(intptr_t*)b);\n")
+		gen.funcDecls.WriteString("}\n\n")
+		gen.funcDecls.WriteString("AhoyArray* ahoy_array_sort(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    qsort(arr->data, arr->length, sizeof(intptr_t), __ahoy_compare_ints);\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// reverse method
+	if gen.arrayMethods["reverse"] {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_array_reverse(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < arr->length / 2; i++) {\n")
+		gen.funcDecls.WriteString("        intptr_t temp = arr->data[i];\n")
+		gen.funcDecls.WriteString("        arr->data[i] = arr->data[arr->length - 1 - i];\n")
+		gen.funcDecls.WriteString("        arr->data[arr->length - 1 - i] = temp;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// shuffle method
+	if gen.arrayMethods["shuffle"] {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_array_shuffle(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString(gen.seedRandom())
+		gen.funcDecls.WriteString("    for (int i = arr->length - 1; i > 0; i--) {\n")
+		gen.funcDecls.WriteString(fmt.Sprintf("        int j = %s() %% (i + 1);\n", gen.deterministicName("rand")))
+		gen.funcDecls.WriteString("        intptr_t temp = arr->data[i];\n")
+		gen.funcDecls.WriteString("        arr->data[i] = arr->data[j];\n")
+		gen.funcDecls.WriteString("        arr->data[j] = temp;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// pick method
+	if gen.arrayMethods["pick"] {
+		gen.funcDecls.WriteString("intptr_t ahoy_array_pick(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    if (arr->length == 0) return 0;\n")
+		gen.funcDecls.WriteString(gen.seedRandom())
+		gen.funcDecls.WriteString(fmt.Sprintf("    return arr->data[%s() %% arr->length];\n", gen.deterministicName("rand")))
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// fill method
+	if gen.arrayMethods["fill"] {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_array_fill(AhoyArray* arr, intptr_t value, AhoyValueType type, int count) {\n")
+		gen.funcDecls.WriteString("    if (count <= 0) return arr;\n")
+		gen.funcDecls.WriteString("    if (arr->capacity < count) {\n")
+		gen.funcDecls.WriteString("        arr->capacity = count;\n")
+		gen.funcDecls.WriteString("        arr->data = realloc(arr->data, arr->capacity * sizeof(intptr_t));\n")
+		gen.funcDecls.WriteString("        arr->types = realloc(arr->types, arr->capacity * sizeof(AhoyValueType));\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < count; i++) {\n")
+		gen.funcDecls.WriteString("        arr->data[i] = value;\n")
+		gen.funcDecls.WriteString("        arr->types[i] = type;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    arr->length = count;\n")
+		gen.funcDecls.WriteString("    arr->mods++;\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// set - arr[i]: value. Past the end, the write stops the program or,
+	// with growArrays, grows the array with zeros of its element type up to
+	// the index.
+	if gen.arrayMethods["set"] {
+		gen.funcDecls.WriteString("void ahoy_array_set(AhoyArray* arr, int index, intptr_t value, AhoyValueType type, const char* file, int line, const char* name) {\n")
+		if gen.growArrays {
+			gen.funcDecls.WriteString("    if (index < 0) {\n")
+		} else {
+			gen.funcDecls.WriteString("    if (index < 0 || index >= arr->length) {\n")
+		}
+		gen.funcDecls.WriteString("        fprintf(stderr, \"RUNTIME ERROR: Array bounds violation\\n\");\n")
+		gen.funcDecls.WriteString("        fprintf(stderr, \"  File: %s\\n\", file);\n")
+		gen.funcDecls.WriteString("        fprintf(stderr, \"  Line: %d\\n\", line);\n")
+		gen.funcDecls.WriteString("        fprintf(stderr, \"  Array: %s\\n\", name);\n")
+		gen.funcDecls.WriteString("        fprintf(stderr, \"  Index: %d\\n\", index);\n")
+		gen.funcDecls.WriteString("        fprintf(stderr, \"  Valid range: 0 to %d\\n\", arr->length - 1);\n")
+		gen.funcDecls.WriteString("        exit(1);\n")
+		gen.funcDecls.WriteString("    }\n")
+		if gen.growArrays {
+			gen.funcDecls.WriteString(`    if (index >= arr->length) {
+        if (index >= arr->capacity) {
+            int capacity = arr->capacity * 2 > index + 1 ? arr->capacity * 2 : index + 1;
+            arr->data = realloc(arr->data, capacity * sizeof(intptr_t));
+            arr->types = realloc(arr->types, capacity * sizeof(AhoyValueType));
+            arr->capacity = capacity;
+        }
+        AhoyValueType zero_type = arr->is_typed ? arr->element_type : AHOY_TYPE_INT;
+        for (int i = arr->length; i < index; i++) {
+            arr->types[i] = zero_type;
+            if (zero_type == AHOY_TYPE_FLOAT) arr->data[i] = ahoy_box_float(0.0);
+            else if (zero_type == AHOY_TYPE_STRING) arr->data[i] = (intptr_t)"";
+            else arr->data[i] = 0;
+        }
+        arr->length = index + 1;
+        arr->mods++;
+    }
+`)
+		}
+		gen.funcDecls.WriteString("    arr->data[index] = value;\n")
+		gen.funcDecls.WriteString("    arr->types[index] = type;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// print_array helper - formats array for printing with type support
+	if gen.arrayMethods["print_array"] {
+		gen.funcDecls.WriteString("char* print_array_helper(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    if (arr == NULL || arr->length == 0) return \"[]\";\n")
+		gen.funcDecls.WriteString("    char* buffer = malloc(4096);\n")
+		gen.funcDecls.WriteString("    int offset = 0;\n")
+		gen.funcDecls.WriteString("    offset += sprintf(buffer + offset, \"[\");\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < arr->length; i++) {\n")
+		gen.funcDecls.WriteString("        if (i > 0) offset += sprintf(buffer + offset, \", \");\n")
+		gen.funcDecls.WriteString("        offset += ahoy_value_format(buffer + offset, 4096 - offset, ahoy_value_from_raw(arr->data[i], arr->types[i]), true);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    offset += sprintf(buffer + offset, \"]\");\n")
+		gen.funcDecls.WriteString("    return buffer;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// print_string_array helper - formats string array for printing
+	if gen.arrayMethods["print_string_array"] {
+		gen.funcDecls.WriteString("char* print_string_array_helper(AhoyArray* arr) {\n")
+		gen.funcDecls.WriteString("    if (arr == NULL || arr->length == 0) return \"[]\";\n")
+		gen.funcDecls.WriteString("    char* buffer = malloc(4096);\n")
+		gen.funcDecls.WriteString("    int offset = 0;\n")
+		gen.funcDecls.WriteString("    offset += sprintf(buffer + offset, \"[\");\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < arr->length; i++) {\n")
+		gen.funcDecls.WriteString("        if (i > 0) offset += sprintf(buffer + offset, \", \");\n")
+		gen.funcDecls.WriteString("        char* str = (char*)(intptr_t)arr->data[i];\n")
+		gen.funcDecls.WriteString("        offset += sprintf(buffer + offset, \"\\\"%s\\\"\", str);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    offset += sprintf(buffer + offset, \"]\");\n")
+		gen.funcDecls.WriteString("    return buffer;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+}
+
+// builtinTypeHelper is a vector2 or color function: the Ahoy types of its
+// arguments, its result type and its C definition
+type builtinTypeHelper struct {
+	params []string
+	result string
+	body   string
+}
+
+var builtinTypeHelpers = map[string]builtinTypeHelper{
+	"vector2_add": {[]string{"vector2", "vector2"}, "vector2",
+		"Vector2 ahoy_vector2_add(Vector2 a, Vector2 b) {\n    return (Vector2){a.x + b.x, a.y + b.y};\n}\n"},
+	"vector2_sub": {[]string{"vector2", "vector2"}, "vector2",
+		"Vector2 ahoy_vector2_sub(Vector2 a, Vector2 b) {\n    return (Vector2){a.x - b.x, a.y - b.y};\n}\n"},
+	"vector2_scale": {[]string{"vector2", "float"}, "vector2",
+		"Vector2 ahoy_vector2_scale(Vector2 v, float s) {\n    return (Vector2){v.x * s, v.y * s};\n}\n"},
+	"vector2_dot": {[]string{"vector2", "vector2"}, "float",
+		"float ahoy_vector2_dot(Vector2 a, Vector2 b) {\n    return a.x * b.x + a.y * b.y;\n}\n"},
+	"vector2_length": {[]string{"vector2"}, "float",
+		"float ahoy_vector2_length(Vector2 v) {\n    return sqrtf(v.x * v.x + v.y * v.y);\n}\n"},
+	"vector2_normalize": {[]string{"vector2"}, "vector2",
+		"Vector2 ahoy_vector2_normalize(Vector2 v) {\n    float length = sqrtf(v.x * v.x + v.y * v.y);\n" +
+			"    if (length == 0) return v;\n    return (Vector2){v.x / length, v.y / length};\n}\n"},
+	"color_lerp": {[]string{"color", "color", "float"}, "color",
+		"Color ahoy_color_lerp(Color a, Color b, float t) {\n    if (t < 0) t = 0;\n    if (t > 1) t = 1;\n" +
+			"    return (Color){(unsigned char)(a.r + (b.r - a.r) * t), (unsigned char)(a.g + (b.g - a.g) * t),\n" +
+			"        (unsigned char)(a.b + (b.b - a.b) * t), (unsigned char)(a.a + (b.a - a.a) * t)};\n}\n"},
+	"color_fade": {[]string{"color", "float"}, "color",
+		"Color ahoy_color_fade(Color c, float alpha) {\n    if (alpha < 0) alpha = 0;\n    if (alpha > 1) alpha = 1;\n" +
+			"    c.a = (unsigned char)(255.0f * alpha);\n    return c;\n}\n"},
+	"color_with_alpha": {[]string{"color", "int"}, "color",
+		"Color ahoy_color_with_alpha(Color c, int alpha) {\n    c.a = (unsigned char)(alpha < 0 ? 0 : alpha > 255 ? 255 : alpha);\n    return c;\n}\n"},
+}
+
+// vectorOperators maps the operators vector2 values support to the helper
+// that implements them
+var vectorOperators = map[string]string{
+	"+": "vector2_add", "plus": "vector2_add",
+	"-": "vector2_sub", "minus": "vector2_sub",
+	"*": "vector2_scale", "times": "vector2_scale",
+	"/": "vector2_scale", "div": "vector2_scale",
+}
+
+func isVectorType(typeName string) bool {
+	return typeName == "vector2" || typeName == "Vector2"
+}
+
+func isColorType(typeName string) bool {
+	return typeName == "color" || typeName == "Color"
+}
+
+// generateBuiltinTypeCall generates a call to a vector2 or color helper
+func (gen *CodeGenerator) generateBuiltinTypeCall(node *ASTNode) {
+	helper := builtinTypeHelpers[node.Value]
+	if len(node.Children) != len(helper.params) {
+		gen.reportError(node.Line, fmt.Sprintf("%s takes %d argument(s) (%s), got %d",
+			node.Value, len(helper.params), strings.Join(helper.params, ", "), len(node.Children)))
+		return
+	}
+	gen.typeHelpersUsed[node.Value] = true
+	for _, typeName := range append(helper.params, helper.result) {
+		if _, builtin := builtinStructs[typeName]; builtin {
+			gen.useBuiltinStruct(typeName)
+		}
+	}
+	gen.output.WriteString("ahoy_" + node.Value + "(")
+	for i, arg := range node.Children {
+		if i > 0 {
+			gen.output.WriteString(", ")
+		}
+		gen.generateNode(arg)
+	}
+	gen.output.WriteString(")")
+}
+
+// generateVectorOp generates arithmetic on vector2 values: vectors add to and
+// subtract from each other, and multiply or divide by a number. It reports
+// false for operations on other types.
+func (gen *CodeGenerator) generateVectorOp(node *ASTNode) bool {
+	helperName := vectorOperators[node.Value]
+	if helperName == "" {
+		return false
+	}
+	left, right := node.Children[0], node.Children[1]
+	leftType, rightType := gen.inferType(left), gen.inferType(right)
+	if isColorType(leftType) || isColorType(rightType) {
+		gen.reportError(nodeLine(node), fmt.Sprintf("'%s' doesn't work on colors", node.Value),
+			"Use color_lerp, color_fade or color_with_alpha to make a new color")
+		return true
+	}
+	if !isVectorType(leftType) && !isVectorType(rightType) {
+		return false
+	}
+
+	if helperName == "vector2_scale" {
+		// The number can come first when multiplying
+		if !isVectorType(leftType) && node.Value != "/" && node.Value != "div" {
+			left, right = right, left
+			leftType, rightType = rightType, leftType
+		}
+		if !isVectorType(leftType) || isVectorType(rightType) {
+			gen.reportError(nodeLine(node), fmt.Sprintf("'%s' needs a vector2 and a number", node.Value),
+				"Use vector2_dot for the dot product of two vectors")
+			return true
+		}
+	} else if !isVectorType(leftType) || !isVectorType(rightType) {
+		gen.reportError(nodeLine(node), fmt.Sprintf("'%s' needs a vector2 on both sides", node.Value))
+		return true
+	}
+
+	gen.typeHelpersUsed[helperName] = true
+	gen.useBuiltinStruct("vector2")
+	gen.output.WriteString("ahoy_" + helperName + "(")
+	gen.generateNode(left)
+	gen.output.WriteString(", ")
+	if node.Value == "/" || node.Value == "div" {
+		gen.output.WriteString("1.0f / (")
+		gen.generateNode(right)
+		gen.output.WriteString(")")
+	} else {
+		gen.generateNode(right)
+	}
+	gen.output.WriteString(")")
+	return true
+}
+
+// builtinStruct is a struct type every program has, laid out like raylib's
+// so values pass straight to raylib functions
+type builtinStruct struct {
+	cName   string
+	fields  []StructFieldInfo
+	typedef string
+}
+
+var builtinStructs = map[string]builtinStruct{
+	"vector2":   {"Vector2", builtinFields("float", "x", "y"), "typedef struct Vector2 { float x; float y; } Vector2;"},
+	"color":     {"Color", builtinFields("int", "r", "g", "b", "a"), "typedef struct Color { unsigned char r; unsigned char g; unsigned char b; unsigned char a; } Color;"},
+	"rectangle": {"Rectangle", builtinFields("float", "x", "y", "width", "height"), "typedef struct Rectangle { float x; float y; float width; float height; } Rectangle;"},
+	"matrix": {"Matrix", builtinFields("float", "m0", "m4", "m8", "m12", "m1", "m5", "m9", "m13", "m2", "m6", "m10", "m14", "m3", "m7", "m11", "m15"),
+		"typedef struct Matrix {\n    float m0, m4, m8, m12;\n    float m1, m5, m9, m13;\n    float m2, m6, m10, m14;\n    float m3, m7, m11, m15;\n} Matrix;"},
+}
+
+func builtinFields(fieldType string, names ...string) []StructFieldInfo {
+	fields := make([]StructFieldInfo, len(names))
+	for i, name := range names {
+		fields[i] = StructFieldInfo{Name: name, Type: fieldType}
+	}
+	return fields
+}
+
+// useBuiltinStruct registers a built-in struct type the program uses, unless
+// the program declares it itself, and has its typedef written
+func (gen *CodeGenerator) useBuiltinStruct(name string) {
+	builtin := builtinStructs[name]
+	gen.builtinStructsUsed[name] = true
+	if _, declared := gen.structs[name]; declared {
+		return
+	}
+	structInfo := &StructInfo{Name: name, Fields: builtin.fields}
+	gen.structs[name] = structInfo
+	gen.structs[builtin.cName] = structInfo
+}
+
+// writeBuiltinTypeHelpers generates the built-in struct typedefs and the
+// vector2 and color helpers that were used. raylib.h has the same types, so
+// the typedefs are left out when it is included.
+func (gen *CodeGenerator) writeBuiltinTypeHelpers() {
+	if len(gen.builtinStructsUsed) > 0 {
+		names := make([]string, 0, len(gen.builtinStructsUsed))
+		for name := range gen.builtinStructsUsed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// Structs may have fields of these types, so they go first
+		var types strings.Builder
+		types.WriteString("#ifndef RAYLIB_H\n")
+		for _, name := range names {
+			types.WriteString(builtinStructs[name].typedef + "\n")
+		}
+		types.WriteString("#endif\n\n")
+		structDecls := gen.structDecls.String()
+		gen.structDecls.Reset()
+		gen.structDecls.WriteString(types.String() + structDecls)
+	}
+
+	if len(gen.typeHelpersUsed) == 0 {
+		return
+	}
+	if !gen.includes["math.h"] {
+		gen.includes["math.h"] = true
+		gen.orderedIncludes = append(gen.orderedIncludes, "math.h")
+	}
+
+	names := make([]string, 0, len(gen.typeHelpersUsed))
+	for name := range gen.typeHelpersUsed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	gen.funcDecls.WriteString("\n// vector2 and color helpers\n")
+	for _, name := range names {
+		body := builtinTypeHelpers[name].body
+		gen.funcReturnStructs.WriteString(strings.TrimSuffix(body[:strings.Index(body, " {")], " ") + ";\n")
+		gen.funcDecls.WriteString(body + "\n")
+	}
+	gen.funcReturnStructs.WriteString("\n")
+}
+
+// Generate dictionary helper functions
+func (gen *CodeGenerator) writeDictHelperFunctions() {
+	if len(gen.dictMethods) == 0 {
+		return
+	}
+
+	// HashMap structure (if not already defined - should be in stdlib)
+	gen.funcDecls.WriteString("\n// Dictionary Helper Methods\n")
+
+	// Check if we need array support for keys() or values() methods
+	if gen.dictMethods["keys"] || gen.dictMethods["values"] || gen.dictMethods["items_sorted"] {
+		// Ensure AhoyArray structure is defined
+		gen.arrayImpls = true
+	}
+
+	// Entries in key order, for printing and items_sorted. The array ends
+	// with NULL and the caller frees it.
+	if gen.dictMethods["print_dict"] || gen.dictMethods["items_sorted"] || gen.dictMethods["sorted_entries"] {
+		gen.funcDecls.WriteString(`static int __ahoy_compare_entries(const void* a, const void* b) {
+    return strcmp((*(HashMapEntry* const*)a)->key, (*(HashMapEntry* const*)b)->key);
+}
+
+HashMapEntry** ahoy_dict_sorted_entries(HashMap* dict) {
+    int size = dict == NULL ? 0 : dict->size;
+    HashMapEntry** entries = malloc((size + 1) * sizeof(HashMapEntry*));
+    int count = 0;
+    for (int i = 0; dict != NULL && i < dict->capacity; i++) {
+        for (HashMapEntry* entry = dict->buckets[i]; entry != NULL; entry = entry->next) {
+            entries[count++] = entry;
+        }
+    }
+    qsort(entries, count, sizeof(HashMapEntry*), __ahoy_compare_entries);
+    entries[count] = NULL;
+    return entries;
+}
+
+`)
+	}
+
+	// size method
+	if gen.dictMethods["size"] {
+		gen.funcDecls.WriteString("int ahoy_dict_size(HashMap* dict) {\n")
+		gen.funcDecls.WriteString("    if (dict == NULL) return 0;\n")
+		gen.funcDecls.WriteString("    return dict->size;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// clear method
+	if gen.dictMethods["clear"] {
+		gen.funcDecls.WriteString("void ahoy_dict_clear(HashMap* dict) {\n")
+		gen.funcDecls.WriteString("    if (dict == NULL) return;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < dict->capacity; i++) {\n")
+		gen.funcDecls.WriteString("        HashMapEntry* entry = dict->buckets[i];\n")
+		gen.funcDecls.WriteString("        while (entry != NULL) {\n")
+		gen.funcDecls.WriteString("            HashMapEntry* temp = entry;\n")
+		gen.funcDecls.WriteString("            entry = entry->next;\n")
+		gen.funcDecls.WriteString("            free(temp->key);\n")
+		gen.funcDecls.WriteString("            free(temp);\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("        dict->buckets[i] = NULL;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    dict->size = 0;\n")
+		gen.funcDecls.WriteString("    dict->mods++;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// has method
+	if gen.dictMethods["has"] {
+		gen.funcDecls.WriteString("int ahoy_dict_has(HashMap* dict, char* key) {\n")
+		gen.funcDecls.WriteString("    if (dict == NULL || key == NULL) return 0;\n")
+		gen.funcDecls.WriteString("    // Walk the bucket so keys holding 0 still count as present\n")
+		gen.funcDecls.WriteString("    for (HashMapEntry* entry = dict->buckets[hash(key) % dict->capacity]; entry != NULL; entry = entry->next) {\n")
+		gen.funcDecls.WriteString("        if (strcmp(entry->key, key) == 0) return 1;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return 0;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// get and get_or, which say whether the key was there
+	if gen.dictMethods["get"] {
+		gen.funcDecls.WriteString("bool ahoy_dict_get(HashMap* dict, const char* key, AhoyValue* value) {\n")
+		gen.funcDecls.WriteString("    if (dict != NULL && key != NULL) {\n")
+		gen.funcDecls.WriteString("        for (HashMapEntry* entry = dict->buckets[hash(key) % dict->capacity]; entry != NULL; entry = entry->next) {\n")
+		gen.funcDecls.WriteString("            if (strcmp(entry->key, key) == 0) {\n")
+		gen.funcDecls.WriteString("                *value = ahoy_value_from_raw((intptr_t)entry->value, entry->valueType);\n")
+		gen.funcDecls.WriteString("                return true;\n")
+		gen.funcDecls.WriteString("            }\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    *value = ahoy_value_from_raw(0, AHOY_TYPE_NULL);\n")
+		gen.funcDecls.WriteString("    return false;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// has_all method
+	if gen.dictMethods["has_all"] {
+		gen.funcDecls.WriteString("int ahoy_dict_has_all(HashMap* dict, AhoyArray* keys) {\n")
+		gen.funcDecls.WriteString("    if (dict == NULL || keys == NULL) return 0;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < keys->length; i++) {\n")
+		gen.funcDecls.WriteString("        char* key = (char*)(intptr_t)keys->data[i];\n")
+		gen.funcDecls.WriteString("        if (hashMapGet(dict, key) == NULL) return 0;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return 1;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// keys method - keys are strings owned by the dict
+	if gen.dictMethods["keys"] {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_dict_keys(HashMap* dict) {\n")
+		gen.funcDecls.WriteString("    AhoyArray* arr = malloc(sizeof(AhoyArray));\n")
+		gen.funcDecls.WriteString("    arr->length = 0;\n")
+		gen.funcDecls.WriteString("    arr->capacity = dict->size;\n")
+		gen.funcDecls.WriteString("    arr->data = malloc((arr->capacity > 0 ? arr->capacity : 1) * sizeof(intptr_t));\n")
+		gen.funcDecls.WriteString("    arr->types = malloc((arr->capacity > 0 ? arr->capacity : 1) * sizeof(AhoyValueType));\n")
+		gen.funcDecls.WriteString("    arr->is_typed = 1;\n")
+		gen.funcDecls.WriteString("    arr->element_type = AHOY_TYPE_STRING;\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < dict->capacity; i++) {\n")
+		gen.funcDecls.WriteString("        HashMapEntry* entry = dict->buckets[i];\n")
+		gen.funcDecls.WriteString("        while (entry != NULL) {\n")
+		gen.funcDecls.WriteString("            arr->types[arr->length] = AHOY_TYPE_STRING;\n")
+		gen.funcDecls.WriteString("            arr->data[arr->length++] = (intptr_t)entry->key;\n")
+		gen.funcDecls.WriteString("            entry = entry->next;\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// values method - each element keeps the type tag of its dict entry
+	if gen.dictMethods["values"] {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_dict_values(HashMap* dict) {\n")
+		gen.funcDecls.WriteString("    AhoyArray* arr = malloc(sizeof(AhoyArray));\n")
+		gen.funcDecls.WriteString("    arr->length = 0;\n")
+		gen.funcDecls.WriteString("    arr->capacity = dict->size;\n")
+		gen.funcDecls.WriteString("    arr->data = malloc((arr->capacity > 0 ? arr->capacity : 1) * sizeof(intptr_t));\n")
+		gen.funcDecls.WriteString("    arr->types = malloc((arr->capacity > 0 ? arr->capacity : 1) * sizeof(AhoyValueType));\n")
+		gen.funcDecls.WriteString("    arr->is_typed = 1;\n")
+		gen.funcDecls.WriteString("    arr->element_type = AHOY_TYPE_INT;\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < dict->capacity; i++) {\n")
+		gen.funcDecls.WriteString("        HashMapEntry* entry = dict->buckets[i];\n")
+		gen.funcDecls.WriteString("        while (entry != NULL) {\n")
+		gen.funcDecls.WriteString("            if (arr->length == 0) arr->element_type = entry->valueType;\n")
+		gen.funcDecls.WriteString("            else if (entry->valueType != arr->element_type) arr->is_typed = 0;\n")
+		gen.funcDecls.WriteString("            arr->types[arr->length] = entry->valueType;\n")
+		gen.funcDecls.WriteString("            arr->data[arr->length++] = (intptr_t)entry->value;\n")
+		gen.funcDecls.WriteString("            entry = entry->next;\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// sort method
+	if gen.dictMethods["sort"] {
+		gen.funcDecls.WriteString("int __ahoy_compare_keys(const void* a, const void* b) {\n")
+		gen.funcDecls.WriteString("    return strcmp((char*)a, (char*)b);\n")
+		gen.funcDecls.WriteString("}\n\n")
+		gen.funcDecls.WriteString("HashMap* ahoy_dict_sort(HashMap* dict) {\n")
+		gen.funcDecls.WriteString("    if (dict == NULL || dict->size == 0) return dict;\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    // Get all keys\n")
+		gen.funcDecls.WriteString("    char** keys = malloc(dict->size * sizeof(char*));\n")
+		gen.funcDecls.WriteString("    int idx = 0;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < dict->capacity; i++) {\n")
+		gen.funcDecls.WriteString("        HashMapEntry* entry = dict->buckets[i];\n")
+		gen.funcDecls.WriteString("        while (entry != NULL) {\n")
+		gen.funcDecls.WriteString("            keys[idx++] = entry->key;\n")
+		gen.funcDecls.WriteString("            entry = entry->next;\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    // Sort keys\n")
+		gen.funcDecls.WriteString("    qsort(keys, dict->size, sizeof(char*), __ahoy_compare_keys);\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    // Create new sorted dict\n")
+		gen.funcDecls.WriteString("    HashMap* sorted = createHashMap(dict->size);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < dict->size; i++) {\n")
+		gen.funcDecls.WriteString("        void* value = hashMapGet(dict, keys[i]);\n")
+		gen.funcDecls.WriteString("        hashMapPut(sorted, keys[i], value);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    free(keys);\n")
+		gen.funcDecls.WriteString("    return sorted;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// stable_sort method (same as sort for dictionaries)
+	if gen.dictMethods["stable_sort"] {
+		gen.funcDecls.WriteString("HashMap* ahoy_dict_stable_sort(HashMap* dict) {\n")
+		gen.funcDecls.WriteString("    return ahoy_dict_sort(dict);\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// merge method
+	if gen.dictMethods["merge"] {
+		gen.funcDecls.WriteString("HashMap* ahoy_dict_merge(HashMap* dict1, HashMap* dict2) {\n")
+		gen.funcDecls.WriteString("    if (dict1 == NULL) return dict2;\n")
+		gen.funcDecls.WriteString("    if (dict2 == NULL) return dict1;\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    HashMap* merged = createHashMap(dict1->size + dict2->size);\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    // Copy all from dict1\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < dict1->capacity; i++) {\n")
+		gen.funcDecls.WriteString("        HashMapEntry* entry = dict1->buckets[i];\n")
+		gen.funcDecls.WriteString("        while (entry != NULL) {\n")
+		gen.funcDecls.WriteString("            hashMapPut(merged, entry->key, entry->value);\n")
+		gen.funcDecls.WriteString("            entry = entry->next;\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    // Copy all from dict2 (overrides if keys exist)\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < dict2->capacity; i++) {\n")
+		gen.funcDecls.WriteString("        HashMapEntry* entry = dict2->buckets[i];\n")
+		gen.funcDecls.WriteString("        while (entry != NULL) {\n")
+		gen.funcDecls.WriteString("            hashMapPut(merged, entry->key, entry->value);\n")
+		gen.funcDecls.WriteString("            entry = entry->next;\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    \n")
+		gen.funcDecls.WriteString("    return merged;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// items_sorted method - [key, value] pairs in key order
+	if gen.dictMethods["items_sorted"] {
+		gen.funcDecls.WriteString(`AhoyArray* ahoy_dict_items_sorted(HashMap* dict) {
+    HashMapEntry** entries = ahoy_dict_sorted_entries(dict);
+    int size = dict == NULL ? 0 : dict->size;
+    AhoyArray* arr = malloc(sizeof(AhoyArray));
+    arr->length = 0;
+    arr->capacity = size;
+    arr->data = malloc((size > 0 ? size : 1) * sizeof(intptr_t));
+    arr->types = malloc((size > 0 ? size : 1) * sizeof(AhoyValueType));
+    arr->is_typed = 1;
+    arr->element_type = AHOY_TYPE_ARRAY;
+    arr->mods = 0;
+    for (int i = 0; entries[i] != NULL; i++) {
+        AhoyArray* pair = malloc(sizeof(AhoyArray));
+        pair->length = 2;
+        pair->capacity = 2;
+        pair->data = malloc(2 * sizeof(intptr_t));
+        pair->types = malloc(2 * sizeof(AhoyValueType));
+        pair->types[0] = AHOY_TYPE_STRING;
+        pair->data[0] = (intptr_t)entries[i]->key;
+        pair->types[1] = entries[i]->valueType;
+        pair->data[1] = (intptr_t)entries[i]->value;
+        pair->is_typed = entries[i]->valueType == AHOY_TYPE_STRING;
+        pair->element_type = AHOY_TYPE_STRING;
+        pair->mods = 0;
+        arr->types[arr->length] = AHOY_TYPE_ARRAY;
+        arr->data[arr->length++] = (intptr_t)pair;
+    }
+    free(entries);
+    return arr;
+}
+
+`)
+	}
+
+	// print_dict helper - formats dict for printing, in key order so the
+	// output doesn't depend on the hash
+	if gen.dictMethods["print_dict"] {
+		gen.funcDecls.WriteString(`char* print_dict_helper(HashMap* dict) {
+    if (dict == NULL || dict->size == 0) return "{}";
+    HashMapEntry** entries = ahoy_dict_sorted_entries(dict);
+    size_t capacity = 256;
+    size_t offset = 0;
+    char* buffer = malloc(capacity);
+    char value[4096];
+    buffer[offset++] = '{';
+    for (int i = 0; entries[i] != NULL; i++) {
+        ahoy_value_format(value, sizeof(value), ahoy_value_from_raw((intptr_t)entries[i]->value, entries[i]->valueType), true);
+        // Room for the separator, the quotes and colon, and the closing brace
+        size_t needed = offset + strlen(entries[i]->key) + strlen(value) + 8;
+        if (needed > capacity) {
+            while (needed > capacity) capacity *= 2;
+            buffer = realloc(buffer, capacity);
+        }
+        offset += sprintf(buffer + offset, "%s\"%s\": %s", i > 0 ? ", " : "", entries[i]->key, value);
+    }
+    strcpy(buffer + offset, "}");
+    free(entries);
+    return buffer;
+}
+
+`)
+
+		// Helper to format a single HashMap value as string
+		gen.funcDecls.WriteString("char* format_hashmap_value(HashMap* dict, const char* key) {\n")
+		gen.funcDecls.WriteString("    static char buffer[256];\n")
+		gen.funcDecls.WriteString("    // Find the entry\n")
+		gen.funcDecls.WriteString("    unsigned int index = hash(key) % dict->capacity;\n")
+		gen.funcDecls.WriteString("    HashMapEntry* entry = dict->buckets[index];\n")
+		gen.funcDecls.WriteString("    while (entry != NULL) {\n")
+		gen.funcDecls.WriteString("        if (strcmp(entry->key, key) == 0) {\n")
+		gen.funcDecls.WriteString("            if (entry->valueType == AHOY_TYPE_STRING) return (char*)entry->value;\n")
+		gen.funcDecls.WriteString("            ahoy_value_format(buffer, sizeof(buffer), ahoy_value_from_raw((intptr_t)entry->value, entry->valueType), false);\n")
+		gen.funcDecls.WriteString("            return buffer;\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("        entry = entry->next;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return \"(null)\";\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+}
+
+// registerJSONFunctionTypes registers return types for JSON functions
+func (gen *CodeGenerator) registerJSONFunctionTypes() {
+	// Mark all JSON functions as user functions so they don't get converted to PascalCase
+	gen.userFunctions["ahoy_json_read"] = true
+	gen.userFunctions["ahoy_json_write"] = true
+	gen.userFunctions["ahoy_json_get"] = true
+	gen.userFunctions["ahoy_json_get_index"] = true
+	gen.userFunctions["ahoy_json_string"] = true
+	gen.userFunctions["ahoy_json_number"] = true
+	gen.userFunctions["ahoy_json_int"] = true
+	gen.userFunctions["ahoy_json_bool"] = true
+
+	// Register return types for JSON helper functions
+	gen.functionReturnTypes["ahoy_json_string"] = []string{"string"}
+	gen.functionReturnTypes["ahoy_json_number"] = []string{"float"}
+	gen.functionReturnTypes["ahoy_json_int"] = []string{"int"}
+	gen.functionReturnTypes["ahoy_json_bool"] = []string{"bool"}
+	gen.functionReturnTypes["ahoy_json_get"] = []string{"AhoyJSON*"}
+	gen.functionReturnTypes["ahoy_json_get_index"] = []string{"AhoyJSON*"}
+}
+
+// markBytesUsed enables the byte buffer runtime and registers its helpers
+func (gen *CodeGenerator) markBytesUsed() {
+	if gen.useBytes {
+		return
+	}
+	gen.useBytes = true
+	gen.userFunctions["ahoy_read_bytes"] = true
+	gen.userFunctions["ahoy_write_bytes"] = true
+	gen.functionReturnTypes["read_bytes"] = []string{"bytes", "bool"}
+	gen.functionReturnTypes["write_bytes"] = []string{"bool"}
+}
+
+// isBytesVar reports whether a variable holds a byte buffer
+// isVariable reports whether name is a variable or parameter in scope
+func (gen *CodeGenerator) isVariable(name string) bool {
+	if _, exists := gen.functionVars[name]; exists {
+		return true
+	}
+	if _, exists := gen.lambdaParams[name]; exists {
+		return true
+	}
+	_, exists := gen.variables[name]
+	return exists
+}
+
+// isKnownFunction reports whether a call to name, which is neither a user
+// function nor in an imported C header, still has something to call: a
+// function value or a function whose return type is known
+func (gen *CodeGenerator) isKnownFunction(name string) bool {
+	if _, exists := gen.functionReturnTypes[name]; exists {
+		return true
+	}
+	return gen.isVariable(name)
+}
+
+// layoutType returns the C type that the operand of size_of or align_of
+// names, or "" when it is a value. Variables win over types of the same name.
+func (gen *CodeGenerator) layoutType(arg *ASTNode) string {
+	if arg.Type != NODE_IDENTIFIER || gen.isVariable(arg.Value) {
+		return ""
+	}
+	switch arg.Value {
+	case "char":
+		return "char"
+	case "int", "float", "string", "bool":
+		return gen.mapType(arg.Value)
+	}
+	if _, builtin := builtinStructs[arg.Value]; builtin {
+		return gen.mapType(arg.Value)
+	}
+	if _, exists := gen.structs[arg.Value]; exists && !gen.jsonStructs[arg.Value] {
+		return gen.mapType(arg.Value)
+	}
+	if _, exists := gen.cTypeDefinitions[arg.Value]; exists {
+		return arg.Value
+	}
+	return ""
+}
+
+func (gen *CodeGenerator) isBytesVar(name string) bool {
+	if varType, exists := gen.functionVars[name]; exists {
+		return varType == "bytes"
+	}
+	return gen.variables[name] == "bytes"
+}
+
+// generateBytesLiteral emits a heap byte buffer for b"..." with escapes decoded
+func (gen *CodeGenerator) generateBytesLiteral(node *ASTNode) {
+	gen.markBytesUsed()
+	data := decodeBytesLiteral(node.Value)
+	if len(data) == 0 {
+		gen.output.WriteString("ahoy_bytes_new(NULL, 0)")
+		return
+	}
+
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("0x%02X", b)
+	}
+	gen.output.WriteString(fmt.Sprintf("ahoy_bytes_new((const unsigned char[]){%s}, %d)",
+		strings.Join(parts, ", "), len(data)))
+}
+
+// generateEmbedStatement compiles the file of embed "path" as name into a
+// static byte array and makes name a bytes value over it. Relative paths are
+// resolved from the directory of the main source file.
+func (gen *CodeGenerator) generateEmbedStatement(node *ASTNode) {
+	if len(node.Children) == 0 {
+		return
+	}
+	name := node.Children[0].Value
+	if gen.currentFunction != "" {
+		gen.reportError(node.Line, "embed must be at the top level, not inside a function")
+		return
+	}
+
+	path := node.Value
+	if !filepath.IsAbs(path) && gen.sourceFilename != "" {
+		path = filepath.Join(filepath.Dir(gen.sourceFilename), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		gen.reportError(node.Line, fmt.Sprintf("can't embed '%s': %v", node.Value, err))
+		return
+	}
+	gen.markBytesUsed()
+
+	// Sixteen bytes to a line keeps large files readable in the C output
+	dataName := "ahoy_embed_" + name
+	gen.globalDecls.WriteString(fmt.Sprintf("// embed \"%s\" (%d bytes)\n", node.Value, len(data)))
+	gen.globalDecls.WriteString(fmt.Sprintf("static unsigned char %s_data[%d] = {", dataName, max(len(data), 1)))
+	for i, b := range data {
+		if i%16 == 0 {
+			gen.globalDecls.WriteString("\n    ")
+		} else {
+			gen.globalDecls.WriteString(" ")
+		}
+		gen.globalDecls.WriteString(fmt.Sprintf("0x%02X,", b))
+	}
+	if len(data) == 0 {
+		gen.globalDecls.WriteString("0")
+	}
+	gen.globalDecls.WriteString("\n};\n")
+	gen.globalDecls.WriteString(fmt.Sprintf("static AhoyBytes %s = {%s_data, %d};\n", dataName, dataName, len(data)))
+	gen.globalDecls.WriteString(fmt.Sprintf("AhoyBytes* %s = &%s;\n", name, dataName))
+
+	gen.variables[name] = "bytes"
+	gen.hoistedGlobals[name] = true
+}
+
+// decodeBytesLiteral resolves \xNN and the usual single-character escapes
+func decodeBytesLiteral(raw string) []byte {
+	data := []byte{}
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 >= len(raw) {
+			data = append(data, raw[i])
+			continue
+		}
+		i++
+		switch raw[i] {
+		case 'x':
+			// Exactly two hex digits; otherwise keep the 'x' literally
+			if i+2 < len(raw) {
+				if val, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+					data = append(data, byte(val))
+					i += 2
+					break
+				}
+			}
+			data = append(data, 'x')
+		case 'n':
+			data = append(data, '\n')
+		case 't':
+			data = append(data, '\t')
+		case 'r':
+			data = append(data, '\r')
+		case '0':
+			data = append(data, 0)
+		default:
+			data = append(data, raw[i])
+		}
+	}
+	return data
+}
+
+// markNumberParsingUsed enables the parse_int/parse_float runtime
+func (gen *CodeGenerator) markNumberParsingUsed() {
+	if gen.useNumberParsing {
+		return
+	}
+	gen.useNumberParsing = true
+	for _, header := range []string{"errno.h", "limits.h", "ctype.h"} {
+		if !gen.includes[header] {
+			gen.includes[header] = true
+			gen.orderedIncludes = append(gen.orderedIncludes, header)
+		}
+	}
+	gen.userFunctions["ahoy_parse_int"] = true
+	gen.userFunctions["ahoy_parse_float"] = true
+	gen.functionReturnTypes["parse_int"] = []string{"int", "bool"}
+	gen.functionReturnTypes["parse_float"] = []string{"float", "bool"}
+}
+
+// writeNumberParsingHelperFunctions generates strtol/strtod wrappers that
+// reject empty input, surrounding garbage and out-of-range values
+func (gen *CodeGenerator) writeNumberParsingHelperFunctions() {
+	if !gen.useNumberParsing {
+		return
+	}
+
+	gen.funcReturnStructs.WriteString("// Number parsing return types\n")
+	gen.funcReturnStructs.WriteString("typedef struct {\n")
+	gen.funcReturnStructs.WriteString("    int ret0;\n")
+	gen.funcReturnStructs.WriteString("    bool ret1;\n")
+	gen.funcReturnStructs.WriteString("} parse_int_return;\n\n")
+	gen.funcReturnStructs.WriteString("typedef struct {\n")
+	gen.funcReturnStructs.WriteString("    double ret0;\n")
+	gen.funcReturnStructs.WriteString("    bool ret1;\n")
+	gen.funcReturnStructs.WriteString("} parse_float_return;\n\n")
+	gen.funcReturnStructs.WriteString("parse_int_return ahoy_parse_int(const char* s);\n")
+	gen.funcReturnStructs.WriteString("parse_float_return ahoy_parse_float(const char* s);\n\n")
+
+	gen.funcDecls.WriteString("\n// Number parsing support\n")
+	gen.funcDecls.WriteString("parse_int_return ahoy_parse_int(const char* s) {\n")
+	gen.funcDecls.WriteString("    parse_int_return result = {0, false};\n")
+	gen.funcDecls.WriteString("    if (!s || *s == '\\0') return result;\n")
+	gen.funcDecls.WriteString("    char* end;\n")
+	gen.funcDecls.WriteString("    errno = 0;\n")
+	gen.funcDecls.WriteString("    long value = strtol(s, &end, 10);\n")
+	gen.funcDecls.WriteString("    if (*end != '\\0' || errno == ERANGE || value < INT_MIN || value > INT_MAX) return result;\n")
+	gen.funcDecls.WriteString("    result.ret0 = (int)value;\n")
+	gen.funcDecls.WriteString("    result.ret1 = true;\n")
+	gen.funcDecls.WriteString("    return result;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("parse_float_return ahoy_parse_float(const char* s) {\n")
+	gen.funcDecls.WriteString("    parse_float_return result = {0.0, false};\n")
+	gen.funcDecls.WriteString("    if (!s || *s == '\\0') return result;\n")
+	gen.funcDecls.WriteString("    char* end;\n")
+	gen.funcDecls.WriteString("    errno = 0;\n")
+	gen.funcDecls.WriteString("    double value = strtod(s, &end);\n")
+	gen.funcDecls.WriteString("    if (*end != '\\0' || errno == ERANGE) return result;\n")
+	gen.funcDecls.WriteString("    result.ret0 = value;\n")
+	gen.funcDecls.WriteString("    result.ret1 = true;\n")
+	gen.funcDecls.WriteString("    return result;\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// markRangeUsed enables the AhoyRange runtime
+func (gen *CodeGenerator) markRangeUsed() {
+	if gen.useRange {
+		return
+	}
+	gen.useRange = true
+	gen.functionReturnTypes["range"] = []string{"range"}
+}
+
+// writeRangeHelperFunctions generates AhoyRange, the small struct a range
+// value lowers to, and the helpers behind has||, to_array|| and printing
+func (gen *CodeGenerator) writeRangeHelperFunctions() {
+	if !gen.useRange {
+		return
+	}
+	hasArrays := gen.usesAhoyArrays()
+
+	gen.funcReturnStructs.WriteString("// Range values\n")
+	gen.funcReturnStructs.WriteString("typedef struct {\n")
+	gen.funcReturnStructs.WriteString("    int start;\n")
+	gen.funcReturnStructs.WriteString("    int end;\n")
+	gen.funcReturnStructs.WriteString("    int step;\n")
+	gen.funcReturnStructs.WriteString("} AhoyRange;\n\n")
+	gen.funcReturnStructs.WriteString("AhoyRange ahoy_range(int start, int end, int step);\n")
+	gen.funcReturnStructs.WriteString("bool ahoy_range_has(AhoyRange r, int value);\n")
+	gen.funcReturnStructs.WriteString("int ahoy_range_length(AhoyRange r);\n")
+	gen.funcReturnStructs.WriteString("char* ahoy_range_format(AhoyRange r);\n")
+	if hasArrays {
+		gen.funcReturnStructs.WriteString("AhoyArray* ahoy_range_to_array(AhoyRange r);\n")
+	}
+	gen.funcReturnStructs.WriteString("\n")
+
+	gen.funcDecls.WriteString("\n// Range support\n")
+	gen.funcDecls.WriteString("AhoyRange ahoy_range(int start, int end, int step) {\n")
+	gen.funcDecls.WriteString("    if (step == 0) {\n")
+	gen.funcDecls.WriteString("        fprintf(stderr, \"RUNTIME ERROR: range step can't be 0\\n\");\n")
+	gen.funcDecls.WriteString("        exit(1);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    return (AhoyRange){start, end, step};\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("bool ahoy_range_has(AhoyRange r, int value) {\n")
+	gen.funcDecls.WriteString("    if (r.step > 0 && (value < r.start || value >= r.end)) return false;\n")
+	gen.funcDecls.WriteString("    if (r.step < 0 && (value > r.start || value <= r.end)) return false;\n")
+	gen.funcDecls.WriteString("    return (value - r.start) % r.step == 0;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("int ahoy_range_length(AhoyRange r) {\n")
+	gen.funcDecls.WriteString("    if (r.step > 0) return r.start < r.end ? (r.end - r.start + r.step - 1) / r.step : 0;\n")
+	gen.funcDecls.WriteString("    return r.start > r.end ? (r.start - r.end - r.step - 1) / -r.step : 0;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("char* ahoy_range_format(AhoyRange r) {\n")
+	gen.funcDecls.WriteString("    static char buffer[64];\n")
+	gen.funcDecls.WriteString("    snprintf(buffer, sizeof(buffer), \"range|%d, %d, %d|\", r.start, r.end, r.step);\n")
+	gen.funcDecls.WriteString("    return buffer;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	if hasArrays {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_range_to_array(AhoyRange r) {\n")
+		gen.funcDecls.WriteString("    int length = ahoy_range_length(r);\n")
+		gen.funcDecls.WriteString("    AhoyArray* arr = malloc(sizeof(AhoyArray));\n")
+		gen.funcDecls.WriteString("    arr->length = length;\n")
+		gen.funcDecls.WriteString("    arr->capacity = length;\n")
+		gen.funcDecls.WriteString("    arr->data = malloc((length > 0 ? length : 1) * sizeof(intptr_t));\n")
+		gen.funcDecls.WriteString("    arr->types = malloc((length > 0 ? length : 1) * sizeof(AhoyValueType));\n")
+		gen.funcDecls.WriteString("    arr->is_typed = 1;\n")
+		gen.funcDecls.WriteString("    arr->element_type = AHOY_TYPE_INT;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < length; i++) {\n")
+		gen.funcDecls.WriteString("        arr->data[i] = r.start + i * r.step;\n")
+		gen.funcDecls.WriteString("        arr->types[i] = AHOY_TYPE_INT;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+}
+
+// generateClone emits a deep copy of an array, dict or struct value
+func (gen *CodeGenerator) generateClone(object *ASTNode, objectType string) {
+	gen.useClone = true
+	switch {
+	case objectType == "array" || strings.HasPrefix(objectType, "array["):
+		gen.arrayImpls = true
+		gen.output.WriteString("ahoy_array_clone(")
+	case objectType == "dict" || objectType == "generic":
+		if objectType == "generic" {
+			gen.output.WriteString("ahoy_dict_clone((HashMap*)")
+		} else {
+			gen.output.WriteString("ahoy_dict_clone(")
+		}
+	default:
+		structInfo, isStruct := gen.structs[objectType]
+		if !isStruct || gen.jsonStructs[objectType] {
+			gen.reportError(object.Line, fmt.Sprintf("clone|| works on arrays, dicts and structs, not '%s'", objectType))
+			return
+		}
+		gen.clonedStructs[structInfo.Name] = true
+		gen.output.WriteString(fmt.Sprintf("clone_struct_%s(", structInfo.Name))
+	}
+	gen.generateNode(object)
+	gen.output.WriteString(")")
+}
+
+// logLevelNames are the levels of log.<level>|...| from the most verbose
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+// generateLevelLog writes log.<level>|fmt, args...| to stderr behind a
+// timestamp and the level, formatting the message the way print does. Release
+// builds leave debug logs out altogether, arguments included.
+func (gen *CodeGenerator) generateLevelLog(node *ASTNode) {
+	levelName := strings.TrimPrefix(node.Value, "log.")
+	if gen.release && levelName == "debug" {
+		gen.output.WriteString("((void)0)")
+		return
+	}
+	level := 0
+	for i, name := range logLevelNames {
+		if name == levelName {
+			level = i
+		}
+	}
+	gen.useLogLevels = true
+
+	// Format through print and send it to stderr
+	gen.output.WriteString(fmt.Sprintf("({ if (ahoy_log_enabled(%d)) { ahoy_log_prefix(%d); fprintf(stderr, %s; } })",
+		level, level, gen.printArguments(node)))
+}
+
+// printArguments formats a call's arguments the way print does and returns
+// what follows "printf(" in print's output, closing parenthesis included
+func (gen *CodeGenerator) printArguments(node *ASTNode) string {
+	savedOutput := gen.output
+	gen.output = strings.Builder{}
+	gen.generateCall(&ASTNode{Type: NODE_CALL, Value: "print", Line: node.Line, Children: node.Children})
+	arguments := strings.TrimPrefix(gen.output.String(), "printf(")
+	gen.output = savedOutput
+	return arguments
+}
+
+// writeLogHelperFunctions generates the level check and line prefix of
+// log.<level>|...|. AHOY_LOG_LEVEL picks the quietest level shown, or "off".
+func (gen *CodeGenerator) writeLogHelperFunctions() {
+	if !gen.useLogLevels {
+		return
+	}
+	for _, header := range []string{"time.h", "strings.h"} {
+		if !gen.includes[header] {
+			gen.includes[header] = true
+			gen.orderedIncludes = append(gen.orderedIncludes, header)
+		}
+	}
+
+	gen.funcReturnStructs.WriteString("int ahoy_log_enabled(int level);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_log_prefix(int level);\n\n")
+
+	gen.funcDecls.WriteString("\n// Leveled logging support\n")
+	gen.funcDecls.WriteString("static const char* ahoy_log_level_names[] = {")
+	for i, name := range logLevelNames {
+		if i > 0 {
+			gen.funcDecls.WriteString(", ")
+		}
+		gen.funcDecls.WriteString(strconv.Quote(strings.ToUpper(name)))
+	}
+	gen.funcDecls.WriteString("};\n\n")
+	gen.funcDecls.WriteString("int ahoy_log_enabled(int level) {\n")
+	gen.funcDecls.WriteString("    static int threshold = -1;\n")
+	gen.funcDecls.WriteString("    if (threshold < 0) {\n")
+	gen.funcDecls.WriteString("        threshold = 0;\n")
+	gen.funcDecls.WriteString("        const char* env = getenv(\"AHOY_LOG_LEVEL\");\n")
+	gen.funcDecls.WriteString("        if (env && strcasecmp(env, \"off\") == 0) threshold = " + strconv.Itoa(len(logLevelNames)) + ";\n")
+	gen.funcDecls.WriteString("        for (int i = 0; env && i < " + strconv.Itoa(len(logLevelNames)) + "; i++) {\n")
+	gen.funcDecls.WriteString("            if (strcasecmp(env, ahoy_log_level_names[i]) == 0) threshold = i;\n")
+	gen.funcDecls.WriteString("        }\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    return level >= threshold;\n")
+	gen.funcDecls.WriteString("}\n\n")
+	gen.funcDecls.WriteString("void ahoy_log_prefix(int level) {\n")
+	gen.funcDecls.WriteString("    char stamp[32];\n")
+	gen.funcDecls.WriteString("    time_t now = time(NULL);\n")
+	gen.funcDecls.WriteString("    strftime(stamp, sizeof(stamp), \"%Y-%m-%d %H:%M:%S\", localtime(&now));\n")
+	gen.funcDecls.WriteString("    fflush(stdout);\n")
+	gen.funcDecls.WriteString("    fprintf(stderr, \"[%s] %-5s \", stamp, ahoy_log_level_names[level]);\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// isModule reports whether node names the built-in module called name,
+// like game or cli, rather than a variable with the same name
+func (gen *CodeGenerator) isModule(node *ASTNode, name string) bool {
+	if node.Type != NODE_IDENTIFIER || node.Value != name {
+		return false
+	}
+	if _, isVar := gen.variables[name]; isVar {
+		return false
+	}
+	_, isVar := gen.functionVars[name]
+	return !isVar
+}
+
+// generateGameRun generates game.run|width, height, title, update, draw|,
+// which opens a raylib window and calls update then draw every frame until
+// it is closed. Both callbacks are functions that take no arguments.
+func (gen *CodeGenerator) generateGameRun(node *ASTNode) {
+	args := node.Children[1].Children
+	if len(args) != 5 {
+		gen.reportError(node.Line, fmt.Sprintf("game.run takes 5 arguments (width, height, title, update, draw), got %d", len(args)))
+		return
+	}
+	for _, callback := range args[3:] {
+		if callback.Type != NODE_IDENTIFIER || !gen.userFunctions[callback.Value] {
+			gen.reportError(callback.Line, "game.run needs the names of its update and draw functions",
+				"Define them with @ name || void: ... $ and pass them without calling them")
+			return
+		}
+		if params := gen.functionParamTypes[callback.Value]; len(params) > 0 {
+			gen.reportError(callback.Line, fmt.Sprintf("'%s' is called every frame by game.run and can't take parameters", callback.Value))
+			return
+		}
+	}
+	gen.useGame = true
+
+	gen.output.WriteString("ahoy_game_run(")
+	for i, arg := range args {
+		if i > 0 {
+			gen.output.WriteString(", ")
+		}
+		gen.generateNode(arg)
+	}
+	gen.output.WriteString(")")
+}
+
+// writeIterationCheckFunction generates the runtime error for an array or
+// dict modified while a -safe loop iterates it
+func (gen *CodeGenerator) writeIterationCheckFunction() {
+	if !gen.useIterationCheck {
+		return
+	}
+	gen.funcReturnStructs.WriteString("void ahoy_check_iteration(unsigned int mods, unsigned int expected, const char* file, int line);\n\n")
+
+	gen.funcDecls.WriteString("\n// -safe loop modification check\n")
+	gen.funcDecls.WriteString("void ahoy_check_iteration(unsigned int mods, unsigned int expected, const char* file, int line) {\n")
+	gen.funcDecls.WriteString("    if (mods == expected) return;\n")
+	gen.funcDecls.WriteString("    fflush(stdout);\n")
+	gen.funcDecls.WriteString("    fprintf(stderr, \"RUNTIME ERROR: Container modified during iteration\\n\");\n")
+	gen.funcDecls.WriteString("    fprintf(stderr, \"  File: %s\\n\", file);\n")
+	gen.funcDecls.WriteString("    fprintf(stderr, \"  Line: %d\\n\", line);\n")
+	gen.funcDecls.WriteString("    fprintf(stderr, \"  The loop's array or dict was added to or removed from inside the loop\\n\");\n")
+	gen.funcDecls.WriteString("    exit(1);\n")
+	gen.funcDecls.WriteString("}\n")
+}
+
+// writeSwitchHashFunction generates the djb2 hash that string switches with
+// many cases dispatch on; stringSwitchHash computes the same at compile time
+func (gen *CodeGenerator) writeSwitchHashFunction() {
+	if !gen.useSwitchHash {
+		return
+	}
+	gen.funcReturnStructs.WriteString("unsigned int ahoy_switch_hash(const char* s);\n\n")
+
+	gen.funcDecls.WriteString("\n// String switch hash\n")
+	gen.funcDecls.WriteString("unsigned int ahoy_switch_hash(const char* s) {\n")
+	gen.funcDecls.WriteString("    unsigned int hash = 5381;\n")
+	gen.funcDecls.WriteString("    if (s == NULL) return hash;\n")
+	gen.funcDecls.WriteString("    for (const unsigned char* p = (const unsigned char*)s; *p; p++) {\n")
+	gen.funcDecls.WriteString("        hash = hash * 33 + *p;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    return hash;\n")
+	gen.funcDecls.WriteString("}\n")
+}
+
+// generateExtern declares the C function an extern names under its own C
+// identifier, bound to the C symbol with an asm label, so the declaration
+// can't clash with one from a header that is also included
+func (gen *CodeGenerator) generateExtern(node *ASTNode) {
+	if !gen.externNameDefined {
+		gen.externNameDefined = true
+		gen.funcForwardDecls.WriteString("#define AHOY_EXTERN_STR2(x) #x\n")
+		gen.funcForwardDecls.WriteString("#define AHOY_EXTERN_STR(x) AHOY_EXTERN_STR2(x)\n")
+		gen.funcForwardDecls.WriteString("#define AHOY_EXTERN_NAME(name) AHOY_EXTERN_STR(__USER_LABEL_PREFIX__) name\n")
+	}
+
+	params := []string{}
+	for _, param := range node.Children[0].Children {
+		params = append(params, fmt.Sprintf("%s %s", gen.mapType(param.DataType), param.Value))
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+	gen.funcForwardDecls.WriteString(fmt.Sprintf("%s ahoy_extern_%s(%s) __asm__(AHOY_EXTERN_NAME(\"%s\"));\n",
+		gen.mapType(node.DataType), node.Value, strings.Join(params, ", "), node.Children[1].Value))
+}
+
+// writeDumpStructFunctions generates a dump_struct helper for each struct it
+// is used on, listing the fields with the offsets and sizes C gives them
+func (gen *CodeGenerator) writeDumpStructFunctions() {
+	if len(gen.dumpStructOrder) == 0 {
+		return
+	}
+	if !gen.includes["stddef.h"] {
+		gen.includes["stddef.h"] = true
+		gen.orderedIncludes = append(gen.orderedIncludes, "stddef.h")
+	}
+	for _, cName := range gen.dumpStructOrder {
+		structInfo := gen.dumpStructs[cName]
+		format := fmt.Sprintf("Type: %s\\nSize: %%zu, align: %%zu\\nFields:", structInfo.Name)
+		args := fmt.Sprintf("sizeof(%s), _Alignof(%s)", cName, cName)
+		for _, field := range structInfo.Fields {
+			format += fmt.Sprintf("\\n  %s: %s (offset %%zu, size %%zu)", field.Name, field.Type)
+			args += fmt.Sprintf(", offsetof(%s, %s), sizeof(((%s*)0)->%s)", cName, field.Name, cName, field.Name)
+		}
+
+		gen.funcReturnStructs.WriteString(fmt.Sprintf("char* ahoy_dump_struct_%s(void);\n", cName))
+		gen.funcDecls.WriteString(fmt.Sprintf("\n// dump_struct of %s\n", structInfo.Name))
+		gen.funcDecls.WriteString(fmt.Sprintf("char* ahoy_dump_struct_%s(void) {\n", cName))
+		gen.funcDecls.WriteString("    static char text[1024];\n")
+		gen.funcDecls.WriteString("    if (text[0] == '\\0') {\n")
+		gen.funcDecls.WriteString(fmt.Sprintf("        snprintf(text, sizeof(text), \"%s\", %s);\n", format, args))
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return text;\n")
+		gen.funcDecls.WriteString("}\n")
+	}
+}
+
+// callbackWrapper is a C function with a callback's signature that calls
+// an Ahoy function
+type callbackWrapper struct {
+	name      string
+	function  string
+	signature *CFunction
+	line      int
+}
+
+// callbackWrapper returns the name of the wrapper that lets C call function
+// through a pointer with signature
+func (gen *CodeGenerator) callbackWrapper(function string, signature *CFunction, line int) string {
+	count := 0
+	for _, wrapper := range gen.callbacks {
+		if wrapper.function != function {
+			continue
+		}
+		if callbackSignature(wrapper.signature) == callbackSignature(signature) {
+			return wrapper.name
+		}
+		count++
+	}
+	name := "ahoy_callback_" + function
+	if count > 0 {
+		name = fmt.Sprintf("%s_%d", name, count+1)
+	}
+	gen.callbacks = append(gen.callbacks, callbackWrapper{name, function, signature, line})
+	return name
+}
+
+// callbackSignature is the C type of a pointer to a function with
+// signature
+func callbackSignature(signature *CFunction) string {
+	var params []string
+	for _, param := range signature.Parameters {
+		params = append(params, param.Type)
+	}
+	return fmt.Sprintf("%s (*)(%s)", signature.ReturnType, strings.Join(params, ", "))
+}
+
+// callbackValue converts value between the C types of a callback and the
+// Ahoy function behind it; numbers convert implicitly, and a pointer casts
+// to another pointer or to and from intptr_t, Ahoy's generic. Returns false
+// for a pointer on one side and any other type on the other.
+func callbackValue(value string, from string, to string) (string, bool) {
+	fromPointer, toPointer := strings.HasSuffix(from, "*"), strings.HasSuffix(to, "*")
+	switch {
+	case from == to:
+		return value, true
+	case fromPointer && toPointer, fromPointer && to == "intptr_t", toPointer && from == "intptr_t":
+		return fmt.Sprintf("(%s)%s", to, value), true
+	case fromPointer || toPointer:
+		return "", false
+	}
+	return value, true
+}
+
+func (gen *CodeGenerator) writeCallbackWrappers() {
+	for _, wrapper := range gen.callbacks {
+		signature := wrapper.signature
+		paramTypes := gen.functionParamTypes[wrapper.function]
+		if len(paramTypes) != len(signature.Parameters) {
+			gen.reportError(wrapper.line, fmt.Sprintf("%s takes %d argument(s), but C calls it with %d", wrapper.function, len(paramTypes), len(signature.Parameters)),
+				fmt.Sprintf("the callback is %s", callbackSignature(signature)))
+			continue
+		}
+		returnTypes := gen.functionReturnTypes[wrapper.function]
+		returnType := "void"
+		if len(returnTypes) > 1 {
+			gen.reportError(wrapper.line, fmt.Sprintf("%s returns %d values and can't be a C callback", wrapper.function, len(returnTypes)))
+			continue
+		} else if len(returnTypes) == 1 {
+			returnType = gen.mapType(returnTypes[0])
+		}
+		if returnType == "void" && signature.ReturnType != "void" {
+			gen.reportError(wrapper.line, fmt.Sprintf("%s returns nothing, but C expects a %s from it", wrapper.function, signature.ReturnType))
+			continue
+		}
+
+		var params, args []string
+		for i, param := range signature.Parameters {
+			argName := fmt.Sprintf("arg%d", i)
+			params = append(params, param.Type+" "+argName)
+			arg, ok := callbackValue(argName, param.Type, gen.mapType(paramTypes[i]))
+			if !ok {
+				gen.reportError(wrapper.line, fmt.Sprintf("parameter %d of %s is %s, but C passes a %s", i+1, wrapper.function, paramTypes[i], param.Type),
+					"declare the parameter generic to take the pointer")
+				break
+			}
+			args = append(args, arg)
+		}
+		if len(args) != len(signature.Parameters) {
+			continue
+		}
+		paramList := strings.Join(params, ", ")
+		if paramList == "" {
+			paramList = "void"
+		}
+		function := wrapper.function
+		if function == "main" {
+			function = "ahoy_main"
+		}
+		call := fmt.Sprintf("%s(%s)", function, strings.Join(args, ", "))
+		result, ok := callbackValue(call, returnType, signature.ReturnType)
+		if signature.ReturnType != "void" && !ok {
+			gen.reportError(wrapper.line, fmt.Sprintf("%s returns %s, but C expects a %s from it", wrapper.function, returnTypes[0], signature.ReturnType),
+				"return generic to give C a pointer")
+			continue
+		}
+
+		gen.funcReturnStructs.WriteString(fmt.Sprintf("static %s %s(%s);\n", signature.ReturnType, wrapper.name, paramList))
+		gen.funcDecls.WriteString(fmt.Sprintf("\n// %s, called from C as %s\n", wrapper.function, callbackSignature(signature)))
+		gen.funcDecls.WriteString(fmt.Sprintf("static %s %s(%s) {\n", signature.ReturnType, wrapper.name, paramList))
+		if signature.ReturnType == "void" {
+			gen.funcDecls.WriteString(fmt.Sprintf("    %s;\n", call))
+		} else {
+			gen.funcDecls.WriteString(fmt.Sprintf("    return %s;\n", result))
+		}
+		gen.funcDecls.WriteString("}\n")
+	}
+}
+
+// writeEnumConversionFunctions generates the checks behind enum|n| for ints
+// only known when the program runs
+func (gen *CodeGenerator) writeEnumConversionFunctions() {
+	for _, enumName := range gen.enumConversions {
+		values := gen.enumValues(enumName)
+		gen.funcReturnStructs.WriteString(fmt.Sprintf("int ahoy_enum_%s(int value, const char* file, int line);\n", enumName))
+		gen.funcDecls.WriteString(fmt.Sprintf("\n// %s|value| stops the program unless value is a %s\n", enumName, enumName))
+		gen.funcDecls.WriteString(fmt.Sprintf("int ahoy_enum_%s(int value, const char* file, int line) {\n", enumName))
+		if gen.enumTypes[enumName] == "flags" {
+			var mask int64
+			for _, flag := range values {
+				mask |= flag
+			}
+			gen.funcDecls.WriteString(fmt.Sprintf("    if ((value & ~%d) == 0) return value;\n", mask))
+		} else if len(values) > 0 {
+			gen.funcDecls.WriteString("    switch (value) {\n")
+			for _, value := range values {
+				gen.funcDecls.WriteString(fmt.Sprintf("        case %d:\n", value))
+			}
+			gen.funcDecls.WriteString("            return value;\n")
+			gen.funcDecls.WriteString("    }\n")
+		}
+		gen.funcDecls.WriteString("    fflush(stdout);\n")
+		gen.funcDecls.WriteString(fmt.Sprintf("    fprintf(stderr, \"RUNTIME ERROR: %%d is not a value of %s\\n\", value);\n", enumName))
+		gen.funcDecls.WriteString("    fprintf(stderr, \"  File: %s\\n\", file);\n")
+		gen.funcDecls.WriteString("    fprintf(stderr, \"  Line: %d\\n\", line);\n")
+		gen.funcDecls.WriteString("    exit(1);\n")
+		gen.funcDecls.WriteString("}\n")
+	}
+}
+
+// coverLine is a source line that counts how often its statements run
+type coverLine struct {
+	file string
+	line int
+}
+
+// coverStatement counts a run of statement under -cover. Declarations and
+// test blocks aren't counted.
+func (gen *CodeGenerator) coverStatement(statement *ASTNode) {
+	if gen.coverProfile == "" || strings.HasPrefix(gen.currentFunction, "ahoy_test_") {
+		return
+	}
+	line := gen.statementLine(statement)
+	if line == 0 {
+		return
+	}
+	key := coverLine{gen.currentFile, line}
+	index, exists := gen.coverIndex[key]
+	if !exists {
+		index = len(gen.coverLines)
+		gen.coverIndex[key] = index
+		gen.coverLines = append(gen.coverLines, key)
+	}
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("ahoy_cover_hits[%d]++;\n", index))
+}
+
+// statementLine is the source line of a statement that generates code in
+// place, or 0 for declarations
+func (gen *CodeGenerator) statementLine(statement *ASTNode) int {
+	switch statement.Type {
+	case NODE_FUNCTION, NODE_STRUCT_DECLARATION, NODE_ENUM_DECLARATION, NODE_UNION_DECLARATION,
+		NODE_ALIAS_DECLARATION, NODE_IMPORT_STATEMENT, NODE_EMBED_STATEMENT, NODE_EXTERN,
+		NODE_PROGRAM_DECLARATION, NODE_TEST_BLOCK:
+		return 0
+	case NODE_CONSTANT_DECLARATION:
+		if gen.currentFunction == "" {
+			return 0
+		}
+	}
+	return nodeLine(statement)
+}
+
+// writeCoverageFunctions generates the -cover hit counters and the function
+// that writes them to the profile when the program exits
+func (gen *CodeGenerator) writeCoverageFunctions() {
+	if len(gen.coverLines) == 0 {
+		return
+	}
+	var files, lines []string
+	for _, line := range gen.coverLines {
+		files = append(files, strconv.Quote(line.file))
+		lines = append(lines, strconv.Itoa(line.line))
+	}
+	gen.funcReturnStructs.WriteString("// -cover hit counts of the Ahoy lines with statements\n")
+	gen.funcReturnStructs.WriteString(fmt.Sprintf("static unsigned long ahoy_cover_hits[%d];\n", len(gen.coverLines)))
+	gen.funcReturnStructs.WriteString(fmt.Sprintf("static const char* ahoy_cover_files[%d] = {%s};\n", len(gen.coverLines), strings.Join(files, ", ")))
+	gen.funcReturnStructs.WriteString(fmt.Sprintf("static const int ahoy_cover_lines[%d] = {%s};\n", len(gen.coverLines), strings.Join(lines, ", ")))
+	gen.funcReturnStructs.WriteString("void ahoy_cover_write(void);\n\n")
+
+	gen.funcDecls.WriteString("\n// -cover profile, written when the program exits\n")
+	gen.funcDecls.WriteString("void ahoy_cover_write(void) {\n")
+	gen.funcDecls.WriteString(fmt.Sprintf("    FILE* profile = fopen(%s, \"w\");\n", strconv.Quote(gen.coverProfile)))
+	gen.funcDecls.WriteString("    if (profile == NULL) return;\n")
+	gen.funcDecls.WriteString("    fprintf(profile, \"mode: count\\n\");\n")
+	gen.funcDecls.WriteString(fmt.Sprintf("    for (int i = 0; i < %d; i++) {\n", len(gen.coverLines)))
+	gen.funcDecls.WriteString("        fprintf(profile, \"%s:%d %lu\\n\", ahoy_cover_files[i], ahoy_cover_lines[i], ahoy_cover_hits[i]);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    fclose(profile);\n")
+	gen.funcDecls.WriteString("}\n")
+}
+
+// inlineTest is a test block and the function generated for it
+type inlineTest struct {
+	name     string
+	function string
+}
+
+// prepareInlineTests drops the program's test blocks, or when testing turns
+// them into functions and drops the top-level statements that would run the
+// program, keeping its declarations
+func prepareInlineTests(ast *ASTNode, testing bool) []inlineTest {
+	var tests []inlineTest
+	children := []*ASTNode{}
+	for _, child := range ast.Children {
+		switch child.Type {
+		case NODE_TEST_BLOCK:
+			if !testing {
+				continue
+			}
+			function := fmt.Sprintf("ahoy_test_%d", len(tests)+1)
+			tests = append(tests, inlineTest{name: child.Value, function: function})
+			children = append(children, &ASTNode{
+				Type:     NODE_FUNCTION,
+				Value:    function,
+				Line:     child.Line,
+				Children: []*ASTNode{{Type: NODE_BLOCK}, child.Children[0]},
+			})
+		case NODE_PROGRAM_DECLARATION, NODE_IMPORT_STATEMENT, NODE_EMBED_STATEMENT, NODE_EXTERN,
+			NODE_FUNCTION, NODE_ASSIGNMENT, NODE_TUPLE_ASSIGNMENT, NODE_VARIABLE_DECLARATION,
+			NODE_CONSTANT_DECLARATION, NODE_ENUM_DECLARATION, NODE_STRUCT_DECLARATION,
+			NODE_ALIAS_DECLARATION, NODE_UNION_DECLARATION:
+			children = append(children, child)
+		default:
+			if !testing {
+				children = append(children, child)
+			}
+		}
+	}
+	ast.Children = children
+	return tests
+}
+
+// writeTestHelperFunctions generates the runner main uses under ahoy test. A
+// failed assert jumps back to it, so the remaining tests still run.
+func (gen *CodeGenerator) writeTestHelperFunctions() {
+	if !gen.test {
+		return
+	}
+	gen.includes["setjmp.h"] = true
+	gen.orderedIncludes = append(gen.orderedIncludes, "setjmp.h")
+
+	gen.funcReturnStructs.WriteString("void ahoy_test_failed(void);\n")
+	gen.funcReturnStructs.WriteString("int ahoy_run_test(const char* name, void (*test)(void));\n\n")
+
+	gen.funcDecls.WriteString("\n// ahoy test runner\n")
+	gen.funcDecls.WriteString("static jmp_buf ahoy_test_jump;\n\n")
+	gen.funcDecls.WriteString("void ahoy_test_failed(void) {\n")
+	gen.funcDecls.WriteString("    longjmp(ahoy_test_jump, 1);\n")
+	gen.funcDecls.WriteString("}\n\n")
+	gen.funcDecls.WriteString("int ahoy_run_test(const char* name, void (*test)(void)) {\n")
+	gen.funcDecls.WriteString("    if (setjmp(ahoy_test_jump) == 0) {\n")
+	gen.funcDecls.WriteString("        test();\n")
+	gen.funcDecls.WriteString("        printf(\"ok    %s\\n\", name);\n")
+	gen.funcDecls.WriteString("        return 0;\n")
+	gen.funcDecls.WriteString("    }\n")
+	if gen.usePanic {
+		// A test that fails inside recover blocks leaves them behind
+		gen.funcDecls.WriteString("    ahoy_recover_top = NULL;\n")
+	}
+	gen.funcDecls.WriteString("    printf(\"FAIL  %s\\n\", name);\n")
+	gen.funcDecls.WriteString("    return 1;\n")
+	gen.funcDecls.WriteString("}\n")
+}
+
+// writeGameHelperFunctions generates the window and frame loop behind
+// game.run|...|. raylib.h is included unless the program imports it itself.
+func (gen *CodeGenerator) writeGameHelperFunctions() {
+	if !gen.useGame {
+		return
+	}
+	hasRaylib := false
+	for _, include := range gen.orderedIncludes {
+		if strings.HasSuffix(include, "raylib.h") {
+			hasRaylib = true
+		}
+	}
+	if !hasRaylib {
+		gen.includes["raylib.h"] = true
+		gen.orderedIncludes = append(gen.orderedIncludes, "raylib.h")
+	}
+
+	gen.funcReturnStructs.WriteString("void ahoy_game_run(int width, int height, const char* title, void (*update)(void), void (*draw)(void));\n\n")
+
+	gen.funcDecls.WriteString("\n// game.run window and frame loop\n")
+	gen.funcDecls.WriteString("void ahoy_game_run(int width, int height, const char* title, void (*update)(void), void (*draw)(void)) {\n")
+	gen.funcDecls.WriteString("    InitWindow(width, height, title);\n")
+	gen.funcDecls.WriteString("    SetTargetFPS(60);\n")
+	gen.funcDecls.WriteString("    while (!WindowShouldClose()) {\n")
+	gen.funcDecls.WriteString("        update();\n")
+	gen.funcDecls.WriteString("        BeginDrawing();\n")
+	gen.funcDecls.WriteString("        ClearBackground(RAYWHITE);\n")
+	gen.funcDecls.WriteString("        draw();\n")
+	gen.funcDecls.WriteString("        EndDrawing();\n")
+	if gen.deterministic {
+		gen.funcDecls.WriteString("        ahoy_det_step();\n")
+	}
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    CloseWindow();\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// writeAssertHelperFunctions generates the failure path of assert. Soft
+// asserts count failures and exit with status 1 once the program is done;
+// under ahoy test a failure ends the test it is in.
+func (gen *CodeGenerator) writeAssertHelperFunctions() {
+	if !gen.useAssert {
+		return
+	}
+
+	gen.funcReturnStructs.WriteString("void ahoy_assert_failed(const char* file, int line, const char* expr);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_assert_done(void);\n\n")
+
+	gen.funcDecls.WriteString("\n// Assert support\n")
+	if gen.softAssert {
+		gen.funcDecls.WriteString("static int ahoy_assert_failures = 0;\n\n")
+		gen.funcDecls.WriteString("static void ahoy_assert_report(void) {\n")
+		gen.funcDecls.WriteString("    fprintf(stderr, \"%d assertion(s) failed\\n\", ahoy_assert_failures);\n")
+		gen.funcDecls.WriteString("    fflush(NULL);\n")
+		gen.funcDecls.WriteString("    _Exit(1);\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+	gen.funcDecls.WriteString("void ahoy_assert_failed(const char* file, int line, const char* expr) {\n")
+	gen.funcDecls.WriteString("    fflush(stdout);\n")
+	gen.funcDecls.WriteString("    fprintf(stderr, \"%s:%d: assertion failed: %s\\n\", file, line, expr);\n")
+	gen.funcDecls.WriteString("}\n\n")
+	gen.funcDecls.WriteString("void ahoy_assert_done(void) {\n")
+	if gen.test {
+		gen.funcDecls.WriteString("    ahoy_test_failed();\n")
+	} else if gen.softAssert {
+		gen.funcDecls.WriteString("    if (ahoy_assert_failures++ == 0) atexit(ahoy_assert_report);\n")
+	} else {
+		if gen.enableSignalHandler {
+			// The failure is already reported, skip the crash banner
+			gen.funcDecls.WriteString("    signal(SIGABRT, SIG_DFL);\n")
+		}
+		gen.funcDecls.WriteString("    abort();\n")
+	}
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// jsonCodecStruct returns the struct that json_encode or json_decode works on
+// for value and asks for its helpers
+func (gen *CodeGenerator) jsonCodecStruct(value *ASTNode, builtin string) (string, bool) {
+	valueType := gen.inferType(value)
+	structInfo, isStruct := gen.structs[valueType]
+	if !isStruct || gen.jsonStructs[valueType] {
+		gen.reportError(value.Line, fmt.Sprintf("%s|| works on structs, not '%s'", builtin, valueType))
+		return "", false
+	}
+	gen.jsonCodecStructs[structInfo.Name] = true
+	return structInfo.Name, true
+}
+
+// writeStructJSONHelperFunctions generates an encoder and a decoder for each
+// struct used with json_encode or json_decode, and for the structs in their
+// fields. Field tags pick the JSON keys, leave out empty fields and skip
+// fields altogether.
+func (gen *CodeGenerator) writeStructJSONHelperFunctions() {
+	if len(gen.jsonCodecStructs) == 0 {
+		return
+	}
+
+	gen.funcReturnStructs.WriteString("// Struct JSON encoding\n")
+	gen.funcReturnStructs.WriteString("typedef struct {\n")
+	gen.funcReturnStructs.WriteString("    char* data;\n")
+	gen.funcReturnStructs.WriteString("    int length;\n")
+	gen.funcReturnStructs.WriteString("    int capacity;\n")
+	gen.funcReturnStructs.WriteString("} AhoyJSONBuffer;\n\n")
+	gen.funcReturnStructs.WriteString("void ahoy_json_buffer_append(AhoyJSONBuffer* b, const char* s);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_json_buffer_quote(AhoyJSONBuffer* b, const char* s);\n")
+	gen.funcReturnStructs.WriteString("void ahoy_json_buffer_number(AhoyJSONBuffer* b, double n);\n\n")
+
+	gen.funcDecls.WriteString("\n// Struct JSON encoding support\n")
+	gen.funcDecls.WriteString("void ahoy_json_buffer_append(AhoyJSONBuffer* b, const char* s) {\n")
+	gen.funcDecls.WriteString("    int n = strlen(s);\n")
+	gen.funcDecls.WriteString("    if (b->length + n + 1 > b->capacity) {\n")
+	gen.funcDecls.WriteString("        b->capacity = (b->length + n + 1) * 2;\n")
+	gen.funcDecls.WriteString("        b->data = realloc(b->data, b->capacity);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    memcpy(b->data + b->length, s, n + 1);\n")
+	gen.funcDecls.WriteString("    b->length += n;\n")
+	gen.funcDecls.WriteString("}\n\n")
+	gen.funcDecls.WriteString("void ahoy_json_buffer_quote(AhoyJSONBuffer* b, const char* s) {\n")
+	gen.funcDecls.WriteString("    if (s == NULL) { ahoy_json_buffer_append(b, \"null\"); return; }\n")
+	gen.funcDecls.WriteString("    ahoy_json_buffer_append(b, \"\\\"\");\n")
+	gen.funcDecls.WriteString("    for (; *s; s++) {\n")
+	gen.funcDecls.WriteString("        char c[8] = {*s, 0};\n")
+	gen.funcDecls.WriteString("        if (*s == '\"' || *s == '\\\\') { c[0] = '\\\\'; c[1] = *s; }\n")
+	gen.funcDecls.WriteString("        else if (*s == '\\n') strcpy(c, \"\\\\n\");\n")
+	gen.funcDecls.WriteString("        else if (*s == '\\t') strcpy(c, \"\\\\t\");\n")
+	gen.funcDecls.WriteString("        else if ((unsigned char)*s < 0x20) snprintf(c, sizeof(c), \"\\\\u%04x\", *s);\n")
+	gen.funcDecls.WriteString("        ahoy_json_buffer_append(b, c);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    ahoy_json_buffer_append(b, \"\\\"\");\n")
+	gen.funcDecls.WriteString("}\n\n")
+	gen.funcDecls.WriteString("void ahoy_json_buffer_number(AhoyJSONBuffer* b, double n) {\n")
+	gen.funcDecls.WriteString("    char text[32];\n")
+	gen.funcDecls.WriteString("    snprintf(text, sizeof(text), \"%.15g\", n);\n")
+	gen.funcDecls.WriteString("    ahoy_json_buffer_append(b, text);\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	pending := []string{}
+	for name := range gen.jsonCodecStructs {
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		structInfo := gen.structs[name]
+		cStructName := capitalizeFirst(name)
+
+		var encode, decode strings.Builder
+		for _, field := range structInfo.Fields {
+			tag := field.JSON
+			if tag.Skip {
+				continue
+			}
+			if tag.Key == "" {
+				tag.Key = field.Name
+			}
+			value := "v." + field.Name
+			var write, read, empty string
+			switch field.Type {
+			case "int", "double", "float":
+				write = fmt.Sprintf("ahoy_json_buffer_number(b, %s);", value)
+				empty = value + " == 0"
+				if field.Type == "int" {
+					read = fmt.Sprintf("out->%s = ahoy_json_int(member);", field.Name)
+				} else {
+					read = fmt.Sprintf("out->%s = ahoy_json_number(member);", field.Name)
+				}
+			case "bool":
+				write = fmt.Sprintf("ahoy_json_buffer_append(b, %s ? \"true\" : \"false\");", value)
+				empty = "!" + value
+				read = fmt.Sprintf("out->%s = ahoy_json_bool(member);", field.Name)
+			case "char*":
+				write = fmt.Sprintf("ahoy_json_buffer_quote(b, %s);", value)
+				empty = fmt.Sprintf("%s == NULL || %s[0] == '\\0'", value, value)
+				read = fmt.Sprintf("if (member->type == JSON_STRING) out->%s = member->string_value;", field.Name)
+			case "AhoyArray*", "HashMap*":
+				valueType, jsonType, jsonData, count := "AHOY_TYPE_ARRAY", "JSON_ARRAY", "array_data", "length"
+				if field.Type == "HashMap*" {
+					valueType, jsonType, jsonData, count = "AHOY_TYPE_DICT", "JSON_OBJECT", "data", "size"
+				}
+				write = fmt.Sprintf("ahoy_json_buffer_append(b, ahoy_json_stringify(ahoy_json_from_value(ahoy_value_from_raw((intptr_t)%s, %s))));", value, valueType)
+				empty = fmt.Sprintf("%s == NULL || %s->%s == 0", value, value, count)
+				read = fmt.Sprintf("if (member->type == %s) out->%s = member->%s;", jsonType, field.Name, jsonData)
+			default:
+				nested, isStruct := gen.structs[field.Type]
+				if !isStruct || gen.jsonStructs[nested.Name] {
+					continue
+				}
+				if !gen.jsonCodecStructs[nested.Name] {
+					gen.jsonCodecStructs[nested.Name] = true
+					pending = append(pending, nested.Name)
+				}
+				write = fmt.Sprintf("ahoy_json_encode_%s_into(b, %s);", nested.Name, value)
+				read = fmt.Sprintf("ahoy_json_fill_%s(member, &out->%s);", nested.Name, field.Name)
+			}
+
+			key := strconv.Quote(strconv.Quote(tag.Key) + ":")
+			indent := "    "
+			if tag.OmitEmpty && empty != "" {
+				encode.WriteString(fmt.Sprintf("    if (!(%s)) {\n", empty))
+				indent = "        "
+			}
+			encode.WriteString(fmt.Sprintf("%sif (!first) ahoy_json_buffer_append(b, \",\");\n", indent))
+			encode.WriteString(fmt.Sprintf("%sfirst = false;\n", indent))
+			encode.WriteString(fmt.Sprintf("%sahoy_json_buffer_append(b, %s);\n", indent, key))
+			encode.WriteString(fmt.Sprintf("%s%s\n", indent, write))
+			if indent != "    " {
+				encode.WriteString("    }\n")
+			}
+			decode.WriteString(fmt.Sprintf("    member = ahoy_json_get(json, %s);\n", strconv.Quote(tag.Key)))
+			decode.WriteString(fmt.Sprintf("    if (member) { %s }\n", read))
+		}
+
+		gen.funcForwardDecls.WriteString(fmt.Sprintf("void ahoy_json_encode_%s_into(AhoyJSONBuffer* b, %s v);\n", name, cStructName))
+		gen.funcForwardDecls.WriteString(fmt.Sprintf("char* ahoy_json_encode_%s(%s v);\n", name, cStructName))
+		gen.funcForwardDecls.WriteString(fmt.Sprintf("bool ahoy_json_fill_%s(AhoyJSON* json, %s* out);\n", name, cStructName))
+		gen.funcForwardDecls.WriteString(fmt.Sprintf("bool ahoy_json_decode_%s(const char* text, %s* out);\n", name, cStructName))
+
+		gen.funcDecls.WriteString(fmt.Sprintf("void ahoy_json_encode_%s_into(AhoyJSONBuffer* b, %s v) {\n", name, cStructName))
+		gen.funcDecls.WriteString("    bool first = true;\n")
+		gen.funcDecls.WriteString("    ahoy_json_buffer_append(b, \"{\");\n")
+		gen.funcDecls.WriteString(encode.String())
+		gen.funcDecls.WriteString("    (void)first;\n")
+		gen.funcDecls.WriteString("    ahoy_json_buffer_append(b, \"}\");\n")
+		gen.funcDecls.WriteString("}\n\n")
+
+		gen.funcDecls.WriteString(fmt.Sprintf("char* ahoy_json_encode_%s(%s v) {\n", name, cStructName))
+		gen.funcDecls.WriteString("    AhoyJSONBuffer b = {NULL, 0, 0};\n")
+		gen.funcDecls.WriteString(fmt.Sprintf("    ahoy_json_encode_%s_into(&b, v);\n", name))
+		gen.funcDecls.WriteString("    return b.data;\n")
+		gen.funcDecls.WriteString("}\n\n")
+
+		gen.funcDecls.WriteString(fmt.Sprintf("bool ahoy_json_fill_%s(AhoyJSON* json, %s* out) {\n", name, cStructName))
+		gen.funcDecls.WriteString("    if (!json || json->type != JSON_OBJECT) return false;\n")
+		gen.funcDecls.WriteString("    AhoyJSON* member;\n")
+		gen.funcDecls.WriteString(decode.String())
+		gen.funcDecls.WriteString("    (void)member;\n")
+		gen.funcDecls.WriteString("    return true;\n")
+		gen.funcDecls.WriteString("}\n\n")
+
+		gen.funcDecls.WriteString(fmt.Sprintf("bool ahoy_json_decode_%s(const char* text, %s* out) {\n", name, cStructName))
+		gen.funcDecls.WriteString("    if (text == NULL) return false;\n")
+		gen.funcDecls.WriteString("    const char* p = text;\n")
+		gen.funcDecls.WriteString(fmt.Sprintf("    return ahoy_json_fill_%s(ahoy_json_parse_value(&p), out);\n", name))
+		gen.funcDecls.WriteString("}\n\n")
+	}
+}
+
+// writeCloneHelperFunctions generates recursive deep copy helpers. Strings
+// are duplicated and JSON values, which are read-only, are shared.
+func (gen *CodeGenerator) writeCloneHelperFunctions() {
+	if !gen.useClone {
+		return
+	}
+	hasArrays := gen.usesAhoyArrays()
+
+	gen.funcReturnStructs.WriteString("// Deep copy helpers\n")
+	gen.funcReturnStructs.WriteString("intptr_t ahoy_slot_clone(intptr_t raw, AhoyValueType type);\n")
+	gen.funcReturnStructs.WriteString("HashMap* ahoy_dict_clone(HashMap* src);\n")
+	if hasArrays {
+		gen.funcReturnStructs.WriteString("AhoyArray* ahoy_array_clone(AhoyArray* src);\n")
+	}
+	gen.funcReturnStructs.WriteString("\n")
+
+	gen.funcDecls.WriteString("\n// Deep copy support\n")
+	gen.funcDecls.WriteString("intptr_t ahoy_slot_clone(intptr_t raw, AhoyValueType type) {\n")
+	gen.funcDecls.WriteString("    switch (type) {\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_FLOAT: return ahoy_box_float(ahoy_value_from_raw(raw, type).as.f);\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_STRING: return raw ? (intptr_t)strdup((const char*)raw) : 0;\n")
+	if hasArrays {
+		gen.funcDecls.WriteString("        case AHOY_TYPE_ARRAY: return (intptr_t)ahoy_array_clone((AhoyArray*)raw);\n")
+	}
+	gen.funcDecls.WriteString("        case AHOY_TYPE_DICT: return (intptr_t)ahoy_dict_clone((HashMap*)raw);\n")
+	gen.funcDecls.WriteString("        default:\n")
+	if len(gen.slotStructs) > 0 {
+		gen.funcDecls.WriteString("            if (type >= AHOY_TYPE_STRUCT) return ahoy_box_struct((const void*)raw, ahoy_slot_structs[type - AHOY_TYPE_STRUCT].size);\n")
+	}
+	gen.funcDecls.WriteString("            return raw;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	if hasArrays {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_array_clone(AhoyArray* src) {\n")
+		gen.funcDecls.WriteString("    if (src == NULL) return NULL;\n")
+		gen.funcDecls.WriteString("    AhoyArray* arr = malloc(sizeof(AhoyArray));\n")
+		gen.funcDecls.WriteString("    *arr = *src;\n")
+		gen.funcDecls.WriteString("    arr->capacity = src->length > 0 ? src->length : 1;\n")
+		gen.funcDecls.WriteString("    arr->data = malloc(arr->capacity * sizeof(intptr_t));\n")
+		gen.funcDecls.WriteString("    arr->types = malloc(arr->capacity * sizeof(AhoyValueType));\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < src->length; i++) {\n")
+		gen.funcDecls.WriteString("        arr->types[i] = src->types[i];\n")
+		gen.funcDecls.WriteString("        arr->data[i] = ahoy_slot_clone(src->data[i], src->types[i]);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	gen.funcDecls.WriteString("HashMap* ahoy_dict_clone(HashMap* src) {\n")
+	gen.funcDecls.WriteString("    if (src == NULL) return NULL;\n")
+	gen.funcDecls.WriteString("    HashMap* map = createHashMap(src->size);\n")
+	gen.funcDecls.WriteString("    for (int i = 0; i < src->capacity; i++) {\n")
+	gen.funcDecls.WriteString("        for (HashMapEntry* entry = src->buckets[i]; entry != NULL; entry = entry->next) {\n")
+	gen.funcDecls.WriteString("            void* value = (void*)ahoy_slot_clone((intptr_t)entry->value, entry->valueType);\n")
+	gen.funcDecls.WriteString("            hashMapPutTyped(map, entry->key, value, entry->valueType);\n")
+	gen.funcDecls.WriteString("        }\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    return map;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Struct clones copy by value, then deep copy container and struct fields.
+	// Nested struct types are queued as they are found.
+	pending := make([]string, 0, len(gen.clonedStructs))
+	for name := range gen.clonedStructs {
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		structInfo := gen.structs[name]
+		cStructName := capitalizeFirst(name)
+
+		gen.funcForwardDecls.WriteString(fmt.Sprintf("%s clone_struct_%s(%s src);\n", cStructName, name, cStructName))
+		gen.funcDecls.WriteString(fmt.Sprintf("%s clone_struct_%s(%s src) {\n", cStructName, name, cStructName))
+		gen.funcDecls.WriteString(fmt.Sprintf("    %s copy = src;\n", cStructName))
+		for _, field := range structInfo.Fields {
+			switch field.Type {
+			case "AhoyArray*":
+				if hasArrays {
+					gen.funcDecls.WriteString(fmt.Sprintf("    copy.%s = ahoy_array_clone(src.%s);\n", field.Name, field.Name))
+				}
+			case "HashMap*":
+				gen.funcDecls.WriteString(fmt.Sprintf("    copy.%s = ahoy_dict_clone(src.%s);\n", field.Name, field.Name))
+			case "char*":
+				gen.funcDecls.WriteString(fmt.Sprintf("    copy.%s = src.%s ? strdup(src.%s) : NULL;\n", field.Name, field.Name, field.Name))
+			default:
+				nested, isStruct := gen.structs[field.Type]
+				if !isStruct || gen.jsonStructs[nested.Name] {
+					continue
+				}
+				if !gen.clonedStructs[nested.Name] {
+					gen.clonedStructs[nested.Name] = true
+					pending = append(pending, nested.Name)
+				}
+				gen.funcDecls.WriteString(fmt.Sprintf("    copy.%s = clone_struct_%s(src.%s);\n", field.Name, nested.Name, field.Name))
+			}
+		}
+		gen.funcDecls.WriteString("    return copy;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+}
+
+// writeBytesHelperFunctions generates the AhoyBytes runtime
+func (gen *CodeGenerator) writeBytesHelperFunctions() {
+	if !gen.useBytes {
+		return
+	}
+
+	// Type and prototypes go before user code so functions can take bytes
+	gen.funcReturnStructs.WriteString("// Byte buffer type\n")
+	gen.funcReturnStructs.WriteString("typedef struct {\n")
+	gen.funcReturnStructs.WriteString("    unsigned char* data;\n")
+	gen.funcReturnStructs.WriteString("    int length;\n")
+	gen.funcReturnStructs.WriteString("} AhoyBytes;\n\n")
+	gen.funcReturnStructs.WriteString("typedef struct {\n")
+	gen.funcReturnStructs.WriteString("    AhoyBytes* ret0;\n")
+	gen.funcReturnStructs.WriteString("    bool ret1;\n")
+	gen.funcReturnStructs.WriteString("} read_bytes_return;\n\n")
+	gen.funcReturnStructs.WriteString("AhoyBytes* ahoy_bytes_new(const unsigned char* src, int length);\n")
+	gen.funcReturnStructs.WriteString("AhoyBytes* ahoy_bytes_slice(AhoyBytes* b, int start, int end);\n")
+	gen.funcReturnStructs.WriteString("char* ahoy_bytes_format(AhoyBytes* b);\n")
+	gen.funcReturnStructs.WriteString("read_bytes_return ahoy_read_bytes(const char* path);\n")
+	gen.funcReturnStructs.WriteString("bool ahoy_write_bytes(const char* path, AhoyBytes* b);\n\n")
+
+	gen.funcDecls.WriteString("\n// Byte buffer support\n")
+	gen.funcDecls.WriteString("AhoyBytes* ahoy_bytes_new(const unsigned char* src, int length) {\n")
+	gen.funcDecls.WriteString("    AhoyBytes* b = malloc(sizeof(AhoyBytes));\n")
+	gen.funcDecls.WriteString("    b->data = calloc(length > 0 ? length : 1, 1);\n")
+	gen.funcDecls.WriteString("    b->length = length;\n")
+	gen.funcDecls.WriteString("    if (src && length > 0) memcpy(b->data, src, length);\n")
+	gen.funcDecls.WriteString("    return b;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("AhoyBytes* ahoy_bytes_slice(AhoyBytes* b, int start, int end) {\n")
+	gen.funcDecls.WriteString("    if (start < 0) start = 0;\n")
+	gen.funcDecls.WriteString("    if (end > b->length) end = b->length;\n")
+	gen.funcDecls.WriteString("    if (end < start) end = start;\n")
+	gen.funcDecls.WriteString("    return ahoy_bytes_new(b->data + start, end - start);\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("char* ahoy_bytes_format(AhoyBytes* b) {\n")
+	gen.funcDecls.WriteString("    char* out = malloc(b->length * 4 + 4);\n")
+	gen.funcDecls.WriteString("    int offset = sprintf(out, \"b\\\"\");\n")
+	gen.funcDecls.WriteString("    for (int i = 0; i < b->length; i++) {\n")
+	gen.funcDecls.WriteString("        offset += sprintf(out + offset, \"\\\\x%02X\", b->data[i]);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    sprintf(out + offset, \"\\\"\");\n")
+	gen.funcDecls.WriteString("    return out;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("read_bytes_return ahoy_read_bytes(const char* path) {\n")
+	gen.funcDecls.WriteString("    read_bytes_return result = {ahoy_bytes_new(NULL, 0), false};\n")
+	gen.funcDecls.WriteString("    FILE* f = fopen(path, \"rb\");\n")
+	gen.funcDecls.WriteString("    if (!f) return result;\n")
+	gen.funcDecls.WriteString("    fseek(f, 0, SEEK_END);\n")
+	gen.funcDecls.WriteString("    long size = ftell(f);\n")
+	gen.funcDecls.WriteString("    fseek(f, 0, SEEK_SET);\n")
+	gen.funcDecls.WriteString("    if (size < 0) { fclose(f); return result; }\n")
+	gen.funcDecls.WriteString("    AhoyBytes* b = ahoy_bytes_new(NULL, (int)size);\n")
+	gen.funcDecls.WriteString("    size_t read = fread(b->data, 1, size, f);\n")
+	gen.funcDecls.WriteString("    fclose(f);\n")
+	gen.funcDecls.WriteString("    if (read != (size_t)size) return result;\n")
+	gen.funcDecls.WriteString("    result.ret0 = b;\n")
+	gen.funcDecls.WriteString("    result.ret1 = true;\n")
+	gen.funcDecls.WriteString("    return result;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("bool ahoy_write_bytes(const char* path, AhoyBytes* b) {\n")
+	gen.funcDecls.WriteString("    FILE* f = fopen(path, \"wb\");\n")
+	gen.funcDecls.WriteString("    if (!f) return false;\n")
+	gen.funcDecls.WriteString("    size_t written = fwrite(b->data, 1, b->length, f);\n")
+	gen.funcDecls.WriteString("    fclose(f);\n")
+	gen.funcDecls.WriteString("    return written == (size_t)b->length;\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// writeJSONHelperFunctions generates JSON parsing and writing functions
+func (gen *CodeGenerator) writeJSONHelperFunctions() {
+	if !gen.useJSON {
+		return
+	}
+
+	// Add JSON type definition and functions
+	gen.funcDecls.WriteString("\n// JSON Support\n")
+	gen.funcDecls.WriteString("struct AhoyJSON {\n")
+	gen.funcDecls.WriteString("    HashMap* data;  // For objects, members stored as AHOY_TYPE_JSON slots\n")
+	gen.funcDecls.WriteString("    AhoyArray* array_data;  // For arrays, elements stored as AHOY_TYPE_JSON slots\n")
+	gen.funcDecls.WriteString("    char* string_value;  // For strings\n")
+	gen.funcDecls.WriteString("    double number_value;  // For numbers\n")
+	gen.funcDecls.WriteString("    int bool_value;  // For booleans\n")
+	gen.funcDecls.WriteString("    int is_null;\n")
+	gen.funcDecls.WriteString("    enum { JSON_OBJECT, JSON_ARRAY, JSON_STRING, JSON_NUMBER, JSON_BOOL, JSON_NULL } type;\n")
+	gen.funcDecls.WriteString("};\n\n")
+
+	// Forward declarations
+	gen.funcDecls.WriteString("AhoyJSON* ahoy_json_parse_value(const char** p);\n")
+	gen.funcDecls.WriteString("void ahoy_json_skip_whitespace(const char** p);\n\n")
+
+	// Skip whitespace
+	gen.funcDecls.WriteString("void ahoy_json_skip_whitespace(const char** p) {\n")
+	gen.funcDecls.WriteString("    while (**p == ' ' || **p == '\\t' || **p == '\\n' || **p == '\\r') (*p)++;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Parse string
+	gen.funcDecls.WriteString("char* ahoy_json_parse_string(const char** p) {\n")
+	gen.funcDecls.WriteString("    (*p)++;  // Skip opening quote\n")
+	gen.funcDecls.WriteString("    const char* start = *p;\n")
+	gen.funcDecls.WriteString("    while (**p && **p != '\"') {\n")
+	gen.funcDecls.WriteString("        if (**p == '\\\\') (*p)++;  // Skip escaped char\n")
+	gen.funcDecls.WriteString("        (*p)++;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    int len = *p - start;\n")
+	gen.funcDecls.WriteString("    char* result = malloc(len + 1);\n")
+	gen.funcDecls.WriteString("    strncpy(result, start, len);\n")
+	gen.funcDecls.WriteString("    result[len] = 0;\n")
+	gen.funcDecls.WriteString("    (*p)++;  // Skip closing quote\n")
+	gen.funcDecls.WriteString("    return result;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Parse number
+	gen.funcDecls.WriteString("double ahoy_json_parse_number(const char** p) {\n")
+	gen.funcDecls.WriteString("    return strtod(*p, (char**)p);\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Append a parsed element to a JSON array
+	gen.funcDecls.WriteString("void ahoy_json_array_append(AhoyArray* arr, AhoyJSON* value) {\n")
+	gen.funcDecls.WriteString("    if (arr->length >= arr->capacity) {\n")
+	gen.funcDecls.WriteString("        arr->capacity = arr->capacity == 0 ? 16 : arr->capacity * 2;\n")
+	gen.funcDecls.WriteString("        arr->data = realloc(arr->data, arr->capacity * sizeof(intptr_t));\n")
+	gen.funcDecls.WriteString("        arr->types = realloc(arr->types, arr->capacity * sizeof(AhoyValueType));\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    arr->data[arr->length] = (intptr_t)value;\n")
+	gen.funcDecls.WriteString("    arr->types[arr->length] = AHOY_TYPE_JSON;\n")
+	gen.funcDecls.WriteString("    arr->length++;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Parse object
+	gen.funcDecls.WriteString("AhoyJSON* ahoy_json_parse_object(const char** p) {\n")
+	gen.funcDecls.WriteString("    AhoyJSON* json = malloc(sizeof(AhoyJSON));\n")
+	gen.funcDecls.WriteString("    json->type = JSON_OBJECT;\n")
+	gen.funcDecls.WriteString("    json->data = createHashMap(16);\n")
+	gen.funcDecls.WriteString("    (*p)++;  // Skip '{'\n")
+	gen.funcDecls.WriteString("    ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("    if (**p == '}') { (*p)++; return json; }\n")
+	gen.funcDecls.WriteString("    while (1) {\n")
+	gen.funcDecls.WriteString("        ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("        if (**p != '\"') break;\n")
+	gen.funcDecls.WriteString("        char* key = ahoy_json_parse_string(p);\n")
+	gen.funcDecls.WriteString("        ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("        if (**p == ':') (*p)++;\n")
+	gen.funcDecls.WriteString("        ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("        AhoyJSON* value = ahoy_json_parse_value(p);\n")
+	gen.funcDecls.WriteString("        hashMapPutTyped(json->data, key, value, AHOY_TYPE_JSON);\n")
+	gen.funcDecls.WriteString("        ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("        if (**p == ',') { (*p)++; continue; }\n")
+	gen.funcDecls.WriteString("        if (**p == '}') break;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    if (**p == '}') (*p)++;\n")
+	gen.funcDecls.WriteString("    return json;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Parse array
+	gen.funcDecls.WriteString("AhoyJSON* ahoy_json_parse_array(const char** p) {\n")
+	gen.funcDecls.WriteString("    AhoyJSON* json = malloc(sizeof(AhoyJSON));\n")
+	gen.funcDecls.WriteString("    json->type = JSON_ARRAY;\n")
+	gen.funcDecls.WriteString("    json->array_data = calloc(1, sizeof(AhoyArray));\n")
+	gen.funcDecls.WriteString("    (*p)++;  // Skip '['\n")
+	gen.funcDecls.WriteString("    ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("    if (**p == ']') { (*p)++; return json; }\n")
+	gen.funcDecls.WriteString("    while (1) {\n")
+	gen.funcDecls.WriteString("        ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("        AhoyJSON* value = ahoy_json_parse_value(p);\n")
+	gen.funcDecls.WriteString("        ahoy_json_array_append(json->array_data, value);\n")
+	gen.funcDecls.WriteString("        ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("        if (**p == ',') { (*p)++; continue; }\n")
+	gen.funcDecls.WriteString("        if (**p == ']') break;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    if (**p == ']') (*p)++;\n")
+	gen.funcDecls.WriteString("    return json;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Parse value (main parser)
+	gen.funcDecls.WriteString("AhoyJSON* ahoy_json_parse_value(const char** p) {\n")
+	gen.funcDecls.WriteString("    ahoy_json_skip_whitespace(p);\n")
+	gen.funcDecls.WriteString("    AhoyJSON* json = malloc(sizeof(AhoyJSON));\n")
+	gen.funcDecls.WriteString("    if (**p == '{') return ahoy_json_parse_object(p);\n")
+	gen.funcDecls.WriteString("    if (**p == '[') return ahoy_json_parse_array(p);\n")
+	gen.funcDecls.WriteString("    if (**p == '\"') {\n")
+	gen.funcDecls.WriteString("        json->type = JSON_STRING;\n")
+	gen.funcDecls.WriteString("        json->string_value = ahoy_json_parse_string(p);\n")
+	gen.funcDecls.WriteString("        return json;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    if (strncmp(*p, \"true\", 4) == 0) {\n")
+	gen.funcDecls.WriteString("        json->type = JSON_BOOL; json->bool_value = 1; *p += 4; return json;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    if (strncmp(*p, \"false\", 5) == 0) {\n")
+	gen.funcDecls.WriteString("        json->type = JSON_BOOL; json->bool_value = 0; *p += 5; return json;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    if (strncmp(*p, \"null\", 4) == 0) {\n")
+	gen.funcDecls.WriteString("        json->type = JSON_NULL; json->is_null = 1; *p += 4; return json;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    // Number\n")
+	gen.funcDecls.WriteString("    json->type = JSON_NUMBER;\n")
+	gen.funcDecls.WriteString("    json->number_value = ahoy_json_parse_number(p);\n")
+	gen.funcDecls.WriteString("    return json;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// JSON type must be forward declared first, then define return struct
+	gen.funcReturnStructs.WriteString("// Forward declare JSON type\n")
+	gen.funcReturnStructs.WriteString("typedef struct AhoyJSON AhoyJSON;\n\n")
+
+	// json_read function - use multi-return struct naming convention
+	gen.funcReturnStructs.WriteString("// JSON read return type\n")
+	gen.funcReturnStructs.WriteString("typedef struct {\n")
+	gen.funcReturnStructs.WriteString("    AhoyJSON* ret0;\n")
+	gen.funcReturnStructs.WriteString("    char* ret1;\n")
+	gen.funcReturnStructs.WriteString("} json_read_return;\n\n")
+
+	// Forward declare the read_json function and helpers
+	gen.funcReturnStructs.WriteString("json_read_return ahoy_json_read(const char* filename);\n")
+	gen.funcReturnStructs.WriteString("char* ahoy_json_write(const char* filename, AhoyJSON* json);\n")
+	gen.funcReturnStructs.WriteString("AhoyJSON* ahoy_json_get(AhoyJSON* json, const char* key);\n")
+	gen.funcReturnStructs.WriteString("AhoyJSON* ahoy_json_get_index(AhoyJSON* json, int index);\n")
+	gen.funcReturnStructs.WriteString("char* ahoy_json_string(AhoyJSON* json);\n")
+	gen.funcReturnStructs.WriteString("double ahoy_json_number(AhoyJSON* json);\n")
+	gen.funcReturnStructs.WriteString("int ahoy_json_int(AhoyJSON* json);\n")
+	gen.funcReturnStructs.WriteString("int ahoy_json_bool(AhoyJSON* json);\n")
+	gen.funcReturnStructs.WriteString("char* ahoy_json_stringify(AhoyJSON* json);\n")
+	gen.funcReturnStructs.WriteString("AhoyValue ahoy_json_to_value(AhoyJSON* json);\n")
+	gen.funcReturnStructs.WriteString("AhoyJSON* ahoy_json_from_value(AhoyValue value);\n\n")
+
+	gen.funcDecls.WriteString("json_read_return ahoy_json_read(const char* filename) {\n")
+	gen.funcDecls.WriteString("    json_read_return result = {NULL, NULL};\n")
+	gen.funcDecls.WriteString("    FILE* f = fopen(filename, \"r\");\n")
+	gen.funcDecls.WriteString("    if (!f) {\n")
+	gen.funcDecls.WriteString("        result.ret1 = \"Failed to open file\";\n")
+	gen.funcDecls.WriteString("        return result;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    fseek(f, 0, SEEK_END);\n")
+	gen.funcDecls.WriteString("    long size = ftell(f);\n")
+	gen.funcDecls.WriteString("    fseek(f, 0, SEEK_SET);\n")
+	gen.funcDecls.WriteString("    char* content = malloc(size + 1);\n")
+	gen.funcDecls.WriteString("    fread(content, 1, size, f);\n")
+	gen.funcDecls.WriteString("    content[size] = 0;\n")
+	gen.funcDecls.WriteString("    fclose(f);\n")
+	gen.funcDecls.WriteString("    const char* p = content;\n")
+	gen.funcDecls.WriteString("    result.ret0 = ahoy_json_parse_value(&p);\n")
+	gen.funcDecls.WriteString("    return result;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// json_write function (simplified - just converts to string)
+	gen.funcDecls.WriteString("char* ahoy_json_write(const char* filename, AhoyJSON* json) {\n")
+	gen.funcDecls.WriteString("    // TODO: Implement JSON serialization\n")
+	gen.funcDecls.WriteString("    return \"Not implemented yet\";\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Helper to access JSON properties
+	gen.funcDecls.WriteString("AhoyJSON* ahoy_json_get(AhoyJSON* json, const char* key) {\n")
+	gen.funcDecls.WriteString("    if (!json || json->type != JSON_OBJECT) return NULL;\n")
+	gen.funcDecls.WriteString("    return (AhoyJSON*)hashMapGet(json->data, key);\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("AhoyJSON* ahoy_json_get_index(AhoyJSON* json, int index) {\n")
+	gen.funcDecls.WriteString("    if (!json || json->type != JSON_ARRAY) return NULL;\n")
+	gen.funcDecls.WriteString("    if (index < 0 || index >= json->array_data->length) return NULL;\n")
+	gen.funcDecls.WriteString("    return (AhoyJSON*)json->array_data->data[index];\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Add value extraction helpers
+	gen.funcDecls.WriteString("// Extract string value from JSON\n")
+	gen.funcDecls.WriteString("char* ahoy_json_string(AhoyJSON* json) {\n")
+	gen.funcDecls.WriteString("    if (!json) return \"\";\n")
+	gen.funcDecls.WriteString("    if (json->type == JSON_STRING) return json->string_value;\n")
+	gen.funcDecls.WriteString("    return \"\";\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("// Extract number value from JSON\n")
+	gen.funcDecls.WriteString("double ahoy_json_number(AhoyJSON* json) {\n")
+	gen.funcDecls.WriteString("    if (!json) return 0.0;\n")
+	gen.funcDecls.WriteString("    if (json->type == JSON_NUMBER) return json->number_value;\n")
+	gen.funcDecls.WriteString("    return 0.0;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("// Extract int value from JSON\n")
+	gen.funcDecls.WriteString("int ahoy_json_int(AhoyJSON* json) {\n")
+	gen.funcDecls.WriteString("    if (!json) return 0;\n")
+	gen.funcDecls.WriteString("    if (json->type == JSON_NUMBER) return (int)json->number_value;\n")
+	gen.funcDecls.WriteString("    return 0;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("// Extract bool value from JSON\n")
+	gen.funcDecls.WriteString("int ahoy_json_bool(AhoyJSON* json) {\n")
+	gen.funcDecls.WriteString("    if (!json) return 0;\n")
+	gen.funcDecls.WriteString("    if (json->type == JSON_BOOL) return json->bool_value;\n")
+	gen.funcDecls.WriteString("    return 0;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// JSON stringify function (forward declare helper)
+	gen.funcDecls.WriteString("// Forward declare recursive helper\n")
+	gen.funcDecls.WriteString("void ahoy_json_stringify_helper(AhoyJSON* json, char* buffer, int* pos, int max_size);\n\n")
+
+	gen.funcDecls.WriteString("// Recursive helper for stringify\n")
+	gen.funcDecls.WriteString("void ahoy_json_stringify_helper(AhoyJSON* json, char* buffer, int* pos, int max_size) {\n")
+	gen.funcDecls.WriteString("    if (!json || *pos >= max_size - 1) return;\n")
+	gen.funcDecls.WriteString("    \n")
+	gen.funcDecls.WriteString("    switch(json->type) {\n")
+	gen.funcDecls.WriteString("        case JSON_STRING:\n")
+	gen.funcDecls.WriteString("            *pos += snprintf(buffer + *pos, max_size - *pos, \"\\\"%s\\\"\", json->string_value ? json->string_value : \"\");\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        case JSON_NUMBER:\n")
+	gen.funcDecls.WriteString("            if (json->number_value == (int)json->number_value) {\n")
+	gen.funcDecls.WriteString("                *pos += snprintf(buffer + *pos, max_size - *pos, \"%d\", (int)json->number_value);\n")
+	gen.funcDecls.WriteString("            } else {\n")
+	gen.funcDecls.WriteString("                *pos += snprintf(buffer + *pos, max_size - *pos, \"%g\", json->number_value);\n")
+	gen.funcDecls.WriteString("            }\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        case JSON_BOOL:\n")
+	gen.funcDecls.WriteString("            *pos += snprintf(buffer + *pos, max_size - *pos, \"%s\", json->bool_value ? \"true\" : \"false\");\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        case JSON_NULL:\n")
+	gen.funcDecls.WriteString("            *pos += snprintf(buffer + *pos, max_size - *pos, \"null\");\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        case JSON_OBJECT: {\n")
+	gen.funcDecls.WriteString("            int count = 0;\n")
+	gen.funcDecls.WriteString("            *pos += snprintf(buffer + *pos, max_size - *pos, \"{\");\n")
+	gen.funcDecls.WriteString("            for (int i = 0; i < json->data->capacity && *pos < max_size - 1; i++) {\n")
+	gen.funcDecls.WriteString("                for (HashMapEntry* entry = json->data->buckets[i]; entry != NULL && *pos < max_size - 1; entry = entry->next) {\n")
+	gen.funcDecls.WriteString("                    if (count++ > 0) *pos += snprintf(buffer + *pos, max_size - *pos, \",\");\n")
+	gen.funcDecls.WriteString("                    *pos += snprintf(buffer + *pos, max_size - *pos, \"\\\"%s\\\":\", entry->key);\n")
+	gen.funcDecls.WriteString("                    ahoy_json_stringify_helper((AhoyJSON*)entry->value, buffer, pos, max_size);\n")
+	gen.funcDecls.WriteString("                }\n")
+	gen.funcDecls.WriteString("            }\n")
+	gen.funcDecls.WriteString("            *pos += snprintf(buffer + *pos, max_size - *pos, \"}\");\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        }\n")
+	gen.funcDecls.WriteString("        case JSON_ARRAY: {\n")
+	gen.funcDecls.WriteString("            *pos += snprintf(buffer + *pos, max_size - *pos, \"[\");\n")
+	gen.funcDecls.WriteString("            for (int i = 0; i < json->array_data->length && *pos < max_size - 1; i++) {\n")
+	gen.funcDecls.WriteString("                if (i > 0) *pos += snprintf(buffer + *pos, max_size - *pos, \",\");\n")
+	gen.funcDecls.WriteString("                ahoy_json_stringify_helper((AhoyJSON*)json->array_data->data[i], buffer, pos, max_size);\n")
+	gen.funcDecls.WriteString("            }\n")
+	gen.funcDecls.WriteString("            *pos += snprintf(buffer + *pos, max_size - *pos, \"]\");\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        }\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("// Stringify JSON object for printing\n")
+	gen.funcDecls.WriteString("char* ahoy_json_stringify(AhoyJSON* json) {\n")
+	gen.funcDecls.WriteString("    static char buffer[8192];\n")
+	gen.funcDecls.WriteString("    int pos = 0;\n")
+	gen.funcDecls.WriteString("    if (!json) return \"null\";\n")
+	gen.funcDecls.WriteString("    ahoy_json_stringify_helper(json, buffer, &pos, 8192);\n")
+	gen.funcDecls.WriteString("    buffer[pos] = '\\0';\n")
+	gen.funcDecls.WriteString("    return buffer;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// Conversions between JSON nodes and runtime values. Objects and arrays
+	// keep their JSON members, so nested values stay lazily typed.
+	gen.funcDecls.WriteString("// Convert a JSON node to a runtime value\n")
+	gen.funcDecls.WriteString("AhoyValue ahoy_json_to_value(AhoyJSON* json) {\n")
+	gen.funcDecls.WriteString("    AhoyValue value;\n")
+	gen.funcDecls.WriteString("    if (!json) return ahoy_value_from_raw(0, AHOY_TYPE_NULL);\n")
+	gen.funcDecls.WriteString("    switch (json->type) {\n")
+	gen.funcDecls.WriteString("        case JSON_OBJECT: return ahoy_value_from_raw((intptr_t)json->data, AHOY_TYPE_DICT);\n")
+	gen.funcDecls.WriteString("        case JSON_ARRAY: return ahoy_value_from_raw((intptr_t)json->array_data, AHOY_TYPE_ARRAY);\n")
+	gen.funcDecls.WriteString("        case JSON_STRING: return ahoy_value_from_raw((intptr_t)json->string_value, AHOY_TYPE_STRING);\n")
+	gen.funcDecls.WriteString("        case JSON_BOOL: return ahoy_value_from_raw(json->bool_value, AHOY_TYPE_BOOL);\n")
+	gen.funcDecls.WriteString("        case JSON_NUMBER:\n")
+	gen.funcDecls.WriteString("            if (json->number_value == (intptr_t)json->number_value) {\n")
+	gen.funcDecls.WriteString("                return ahoy_value_from_raw((intptr_t)json->number_value, AHOY_TYPE_INT);\n")
+	gen.funcDecls.WriteString("            }\n")
+	gen.funcDecls.WriteString("            value.type = AHOY_TYPE_FLOAT;\n")
+	gen.funcDecls.WriteString("            value.as.f = json->number_value;\n")
+	gen.funcDecls.WriteString("            return value;\n")
+	gen.funcDecls.WriteString("        default: return ahoy_value_from_raw(0, AHOY_TYPE_NULL);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	gen.funcDecls.WriteString("// Convert a runtime value to a JSON node, copying dicts and arrays\n")
+	gen.funcDecls.WriteString("AhoyJSON* ahoy_json_from_value(AhoyValue value) {\n")
+	gen.funcDecls.WriteString("    if (value.type == AHOY_TYPE_JSON) return (AhoyJSON*)value.as.p;\n")
+	gen.funcDecls.WriteString("    AhoyJSON* json = calloc(1, sizeof(AhoyJSON));\n")
+	gen.funcDecls.WriteString("    switch (value.type) {\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_INT: json->type = JSON_NUMBER; json->number_value = (double)value.as.i; break;\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_FLOAT: json->type = JSON_NUMBER; json->number_value = value.as.f; break;\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_BOOL: json->type = JSON_BOOL; json->bool_value = value.as.b; break;\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_STRING:\n")
+	gen.funcDecls.WriteString("            if (value.as.s == NULL) { json->type = JSON_NULL; json->is_null = 1; break; }\n")
+	gen.funcDecls.WriteString("            json->type = JSON_STRING; json->string_value = strdup(value.as.s); break;\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_CHAR:\n")
+	gen.funcDecls.WriteString("            json->type = JSON_STRING; json->string_value = calloc(2, 1); json->string_value[0] = value.as.c; break;\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_DICT: {\n")
+	gen.funcDecls.WriteString("            HashMap* map = (HashMap*)value.as.p;\n")
+	gen.funcDecls.WriteString("            json->type = JSON_OBJECT;\n")
+	gen.funcDecls.WriteString("            json->data = createHashMap(16);\n")
+	gen.funcDecls.WriteString("            for (int i = 0; map && i < map->capacity; i++) {\n")
+	gen.funcDecls.WriteString("                for (HashMapEntry* entry = map->buckets[i]; entry != NULL; entry = entry->next) {\n")
+	gen.funcDecls.WriteString("                    AhoyJSON* member = ahoy_json_from_value(ahoy_value_from_raw((intptr_t)entry->value, entry->valueType));\n")
+	gen.funcDecls.WriteString("                    hashMapPutTyped(json->data, entry->key, member, AHOY_TYPE_JSON);\n")
+	gen.funcDecls.WriteString("                }\n")
+	gen.funcDecls.WriteString("            }\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        }\n")
+	gen.funcDecls.WriteString("        case AHOY_TYPE_ARRAY: {\n")
+	gen.funcDecls.WriteString("            AhoyArray* arr = (AhoyArray*)value.as.p;\n")
+	gen.funcDecls.WriteString("            json->type = JSON_ARRAY;\n")
+	gen.funcDecls.WriteString("            json->array_data = calloc(1, sizeof(AhoyArray));\n")
+	gen.funcDecls.WriteString("            for (int i = 0; arr && i < arr->length; i++) {\n")
+	gen.funcDecls.WriteString("                ahoy_json_array_append(json->array_data, ahoy_json_from_value(ahoy_array_get_value(arr, i)));\n")
+	gen.funcDecls.WriteString("            }\n")
+	gen.funcDecls.WriteString("            break;\n")
+	gen.funcDecls.WriteString("        }\n")
+	gen.funcDecls.WriteString("        default: json->type = JSON_NULL; json->is_null = 1; break;\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    return json;\n")
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// Process format string to replace %v, {}, {:spec} and %t with appropriate C format specifiers
+func (gen *CodeGenerator) processFormatString(formatStr string, args []*ASTNode) (string, []*ASTNode) {
+	result := ""
+	newArgs := []*ASTNode{}
+	argIndex := 0
+	i := 0
+
+	for i < len(formatStr) {
+		// {:spec} shows the next value with a spec
+		if strings.HasPrefix(formatStr[i:], "{:") && argIndex < len(args) {
+			if end := strings.IndexByte(formatStr[i:], '}'); end > 0 {
+				if spec, ok := parsePlaceholderSpec(formatStr[i+2 : i+end]); ok {
+					format, arg := gen.specFormat(spec, args[argIndex])
+					result += format
+					newArgs = append(newArgs, arg)
+					argIndex++
+					i += end + 1
+					continue
+				}
+			}
+		}
+		// {} is a placeholder like %v
+		placeholder := strings.HasPrefix(formatStr[i:], "{}")
+		if (formatStr[i] == '%' || placeholder) && i+1 < len(formatStr) {
+			if formatStr[i+1] == 'v' || placeholder {
+				// %v - replace with appropriate format specifier based on argument type
+				if argIndex < len(args) {
+					argType := gen.getNodeType(args[argIndex])
+					if enumName := gen.printedEnum(args[argIndex]); enumName != "" {
+						result += "%s"
+						newArgs = append(newArgs, &ASTNode{
+							Type:     NODE_CALL,
+							Value:    "__ahoy_enum_name",
+							Children: []*ASTNode{args[argIndex], {Type: NODE_IDENTIFIER, Value: enumName}},
+						})
+					} else if argType == "array" {
+						// For arrays, we need to call a helper function
+						gen.arrayMethods["print_array"] = true
+						result += "%s"
+						// Mark this argument as needing array helper
+						arrayArg := &ASTNode{
+							Type:     NODE_CALL,
+							Value:    "__print_array_helper", // Special marker
+							Children: []*ASTNode{args[argIndex]},
+						}
+						newArgs = append(newArgs, arrayArg)
+					} else {
+						result += gen.getFormatSpec(argType)
+						newArgs = append(newArgs, args[argIndex])
+					}
+					argIndex++
+				} else {
+					result += "%v" // Keep if no argument
+				}
+				i += 2
+			} else if formatStr[i+1] == 't' {
+				// %t - replace with type name as string
+				if argIndex < len(args) {
+					argType := gen.getNodeType(args[argIndex])
+					result += "%s"
+					// Create a string literal node for the type name
+					typeNode := &ASTNode{
+						Type:  NODE_STRING,
+						Value: argType,
+					}
+					newArgs = append(newArgs, typeNode)
+					argIndex++
+				} else {
+					result += "%t" // Keep if no argument
+				}
+				i += 2
+			} else {
+				// Regular format specifier or escaped %
+				result += string(formatStr[i])
+				if i+1 < len(formatStr) {
+					result += string(formatStr[i+1])
+				}
+				// Add the corresponding argument
+				if argIndex < len(args) {
+					newArgs = append(newArgs, args[argIndex])
+					argIndex++
+				}
+				i += 2
+			}
+		} else {
+			result += string(formatStr[i])
+			i++
+		}
+	}
+
+	// Add any remaining arguments
+	for argIndex < len(args) {
+		newArgs = append(newArgs, args[argIndex])
+		argIndex++
+	}
+
+	return result, newArgs
+}
+
+// Get the type of a node
+func (gen *CodeGenerator) getNodeType(node *ASTNode) string {
+	if node.DataType != "" {
+		return node.DataType
+	}
+
+	switch node.Type {
+	case NODE_NUMBER:
+		if strings.Contains(node.Value, ".") {
+			return "float"
+		}
+		return "int"
+	case NODE_STRING:
+		return "string"
+	case NODE_F_STRING:
+		return "string"
+	case NODE_CHAR:
+		return "char"
+	case NODE_BOOLEAN:
+		return "bool"
+	case NODE_ARRAY_LITERAL:
+		return "array"
+	case NODE_DICT_LITERAL:
+		return "dict"
+	case NODE_IDENTIFIER:
+		// Look up in variables map
+		if varType, ok := gen.variables[node.Value]; ok {
+			return varType
+		}
+		return "int" // Default
+	case NODE_MEMBER_ACCESS:
+		if gen.isModule(node.Children[0], "build") {
+			return "string"
+		}
+		return "int" // Default
+	default:
+		return "int" // Default
+	}
+}
+
+// Get C format specifier for a type
+func (gen *CodeGenerator) getFormatSpec(typeName string) string {
+	switch typeName {
+	case "int":
+		return "%d"
+	case "float":
+		return "%f"
+	case "string", "char*":
+		return "%s"
+	case "char":
+		return "%c"
+	case "bool":
+		return "%d" // C prints bool as 0/1
+	case "array":
+		return "%p" // Pointer
+	case "dict":
+		return "%p" // Pointer
+	default:
+		return "%d" // Default to int
+	}
+}
+
+// Get value type for an AST node (simpler version of inferType)
+func (gen *CodeGenerator) getValueType(node *ASTNode) string {
+	switch node.Type {
+	case NODE_NUMBER:
+		// Check if it contains a decimal point
+		if strings.Contains(node.Value, ".") {
+			return "float"
+		}
+		return "int"
+	case NODE_STRING, NODE_F_STRING:
+		return "string"
+	case NODE_CHAR:
+		return "char"
+	case NODE_BOOLEAN:
+		return "bool"
+	case NODE_ARRAY_LITERAL:
+		return "array"
+	case NODE_DICT_LITERAL:
+		return "dict"
+	default:
+		// Variables and expressions carry their inferred type into the slot tag
+		inferred := gen.inferType(node)
+		switch inferred {
+		case "float", "string", "char", "bool", "array", "dict", "json":
+			return inferred
+		case "char*", "const char*":
+			return "string"
+		case "AhoyArray*":
+			return "array"
+		case "HashMap*":
+			return "dict"
+		case "AhoyJSON*":
+			return "json"
+		}
+		if strings.HasPrefix(inferred, "array[") {
+			return "array"
+		}
+		if strings.HasPrefix(inferred, "dict[") || strings.HasPrefix(inferred, "dict<") {
+			return "dict"
+		}
+		if gen.slotStruct(inferred) != nil {
+			return inferred
+		}
+		return "int"
+	}
+}
+
+// generateSlotValue writes a value in the intptr_t form stored in array and
+// dict slots; floats and structs are boxed so they survive the integer storage
+func (gen *CodeGenerator) generateSlotValue(node *ASTNode, valueType string) {
+	if valueType == "float" || valueType == "double" {
+		gen.output.WriteString("ahoy_box_float(")
+		gen.generateNodeInternal(node, false)
+		gen.output.WriteString(")")
+		return
+	}
+	if structInfo := gen.slotStruct(valueType); structInfo != nil {
+		cStructName := capitalizeFirst(structInfo.Name)
+		gen.output.WriteString(fmt.Sprintf("({ %s __slot = ", cStructName))
+		gen.generateNodeInternal(node, false)
+		gen.output.WriteString(fmt.Sprintf("; ahoy_box_struct(&__slot, sizeof(%s)); })", cStructName))
+		return
+	}
+	gen.output.WriteString("(intptr_t)")
+	gen.generateNodeInternal(node, false)
+}
+
+// generateLookupValue writes a value and its type tag for comparing against
+// container slots. Floats point at a compound literal so lookups don't allocate.
+func (gen *CodeGenerator) generateLookupValue(node *ASTNode) {
+	valueType := gen.getValueType(node)
+	if valueType == "float" || valueType == "double" {
+		gen.output.WriteString("(intptr_t)&(double){")
+		gen.generateNodeInternal(node, false)
+		gen.output.WriteString("}")
+	} else {
+		gen.generateSlotValue(node, valueType)
+	}
+	gen.output.WriteString(fmt.Sprintf(", %s", gen.getAhoyTypeEnum(valueType)))
+}
+
+// Get AhoyValueType enum for a type string
+func (gen *CodeGenerator) getAhoyTypeEnum(typeName string) string {
+	switch typeName {
+	case "int":
+		return "AHOY_TYPE_INT"
+	case "bool":
+		return "AHOY_TYPE_BOOL"
+	case "float":
+		return "AHOY_TYPE_FLOAT"
+	case "string":
+		return "AHOY_TYPE_STRING"
+	case "char":
+		return "AHOY_TYPE_CHAR"
+	case "array":
+		// Nested containers are formatted through their own print helpers
+		gen.arrayMethods["print_array"] = true
+		return "AHOY_TYPE_ARRAY"
+	case "dict":
+		gen.dictMethods["print_dict"] = true
+		return "AHOY_TYPE_DICT"
+	case "json":
+		return "AHOY_TYPE_JSON"
+	default:
+		if structInfo := gen.slotStruct(typeName); structInfo != nil {
+			return fmt.Sprintf("(AHOY_TYPE_STRUCT + %d)", gen.slotStructID(structInfo.Name))
+		}
+		return "AHOY_TYPE_INT"
+	}
+}
+
+// slotStruct returns the struct a value of typeName is, or nil when it isn't
+// one that can be boxed into a slot
+func (gen *CodeGenerator) slotStruct(typeName string) *StructInfo {
+	structInfo, isStruct := gen.structs[typeName]
+	if !isStruct || gen.jsonStructs[structInfo.Name] {
+		return nil
+	}
+	return structInfo
+}
+
+// slotStructID returns the struct id a slot holding the named struct is
+// tagged with, handing out the next id the first time
+func (gen *CodeGenerator) slotStructID(name string) int {
+	if id, exists := gen.slotStructIDs[name]; exists {
+		return id
+	}
+	id := len(gen.slotStructs)
+	gen.slotStructIDs[name] = id
+	gen.slotStructs = append(gen.slotStructs, name)
+	return id
+}
+
+// writeSlotStructTable emits ahoy_slot_structs, which lets the runtime
+// print and compare the structs stored in slots by their struct id
+func (gen *CodeGenerator) writeSlotStructTable(code *strings.Builder) {
+	if len(gen.slotStructs) == 0 {
+		return
+	}
+	code.WriteString("\n// Structs stored in array and dict slots\n")
+	for _, name := range gen.slotStructs {
+		code.WriteString(fmt.Sprintf("static char* ahoy_print_slot_%s(const void* value) {\n", name))
+		code.WriteString(fmt.Sprintf("    return print_struct_helper_%s(*(const %s*)value);\n", name, capitalizeFirst(name)))
+		code.WriteString("}\n")
+	}
+	code.WriteString("\nstatic const AhoySlotStruct ahoy_slot_structs[] = {\n")
+	for _, name := range gen.slotStructs {
+		code.WriteString(fmt.Sprintf("    {\"%s\", sizeof(%s), ahoy_print_slot_%s},\n", name, capitalizeFirst(name), name))
+	}
+	code.WriteString("};\n")
+}
+
+// Generate inline map code
+func (gen *CodeGenerator) generateMapInline(arrayNode *ASTNode, lambda *ASTNode) {
+	// Parse lambda structure: Value contains param count, first N children are params, last child is body
+	paramCount := 1
+	if lambda.Value != "" {
+		if count, err := strconv.Atoi(lambda.Value); err == nil {
+			paramCount = count
+		}
+	}
+
+	// Extract parameters and body
+	params := []string{}
+	var bodyExpr *ASTNode
+
+	if paramCount == 1 && len(lambda.Children) == 1 {
+		// Old format: single param in Value, body is first child
+		params = []string{lambda.Value}
+		bodyExpr = lambda.Children[0]
+	} else if len(lambda.Children) > paramCount {
+		// New format: first paramCount children are params, last is body
+		for i := 0; i < paramCount; i++ {
+			params = append(params, lambda.Children[i].Value)
+		}
+		bodyExpr = lambda.Children[paramCount]
+	} else {
+		// Fallback
+		params = []string{"x"}
+		bodyExpr = lambda.Children[0]
+	}
+
+	// Generate inline statement expression
+	gen.output.WriteString("({ ")
+	gen.output.WriteString("AhoyArray* __src = ")
+	gen.generateNodeInternal(arrayNode, false)
+	gen.output.WriteString("; ")
+	gen.output.WriteString("AhoyArray* __result = malloc(sizeof(AhoyArray)); ")
+	gen.output.WriteString("__result->length = __src->length; ")
+	gen.output.WriteString("__result->capacity = __src->length; ")
+	gen.output.WriteString("__result->data = malloc(__src->length * sizeof(intptr_t)); ")
+	gen.output.WriteString("__result->types = malloc(__src->length * sizeof(AhoyValueType)); ")
+	gen.output.WriteString("__result->is_typed = 0; ")
+	gen.output.WriteString("for (int __i = 0; __i < __src->length; __i++) { ")
+
+	// For multi-param lambdas, extract from nested array
+	if len(params) > 1 {
+		gen.output.WriteString("AhoyArray* __elem = (AhoyArray*)__src->data[__i]; ")
+		for i, paramName := range params {
+			// A lambda needn't use every parameter
+			gen.output.WriteString(fmt.Sprintf("int %s = __elem->data[%d]; (void)%s; ", paramName, i, paramName))
+		}
+	} else {
+		gen.output.WriteString(fmt.Sprintf("int %s = __src->data[__i]; (void)%s; ", params[0], params[0]))
+	}
+
+	gen.output.WriteString("__result->types[__i] = AHOY_TYPE_INT; ")
+	gen.output.WriteString("__result->data[__i] = (intptr_t)(")
+
+	// Generate lambda body expression
+	gen.generateNodeInternal(bodyExpr, false)
+
+	gen.output.WriteString("); } ")
+	gen.output.WriteString("__result; })")
+}
+
+// Generate inline filter code
+func (gen *CodeGenerator) generateFilterInline(arrayNode *ASTNode, lambda *ASTNode) {
+	// Parse lambda structure: Value contains param count, first N children are params, last child is body
+	paramCount := 1
+	if lambda.Value != "" {
+		if count, err := strconv.Atoi(lambda.Value); err == nil {
+			paramCount = count
+		}
+	}
+
+	// Extract parameters and condition
+	params := []string{}
+	var condExpr *ASTNode
+
+	if paramCount == 1 && len(lambda.Children) == 1 {
+		// Old format: single param in Value, body is first child
+		params = []string{lambda.Value}
+		condExpr = lambda.Children[0]
+	} else if len(lambda.Children) > paramCount {
+		// New format: first paramCount children are params, last is body
+		for i := 0; i < paramCount; i++ {
+			params = append(params, lambda.Children[i].Value)
+		}
+		condExpr = lambda.Children[paramCount]
+	} else {
+		// Fallback
+		params = []string{"x"}
+		condExpr = lambda.Children[0]
+	}
+
+	// Generate inline statement expression
+	gen.output.WriteString("({ ")
+	gen.output.WriteString("AhoyArray* __src = ")
+	gen.generateNodeInternal(arrayNode, false)
+	gen.output.WriteString("; ")
+	gen.output.WriteString("AhoyArray* __result = malloc(sizeof(AhoyArray)); ")
+	gen.output.WriteString("__result->capacity = __src->length; ")
+	gen.output.WriteString("__result->data = malloc(__src->length * sizeof(intptr_t)); ")
+	gen.output.WriteString("__result->types = malloc(__src->length * sizeof(AhoyValueType)); ")
+	gen.output.WriteString("__result->is_typed = 0; ")
+	gen.output.WriteString("__result->length = 0; ")
+	gen.output.WriteString("for (int __i = 0; __i < __src->length; __i++) { ")
+
+	// For multi-param lambdas, extract from nested array
+	if len(params) > 1 {
+		gen.output.WriteString("AhoyArray* __elem = (AhoyArray*)__src->data[__i]; ")
+		for i, paramName := range params {
+			// A lambda needn't use every parameter
+			gen.output.WriteString(fmt.Sprintf("int %s = __elem->data[%d]; (void)%s; ", paramName, i, paramName))
+		}
+	} else {
+		gen.output.WriteString(fmt.Sprintf("int %s = __src->data[__i]; (void)%s; ", params[0], params[0]))
+	}
+
+	gen.output.WriteString("if (")
+
+	// Generate lambda condition expression
+	gen.generateNodeInternal(condExpr, false)
+
+	gen.output.WriteString(") { ")
+	if len(params) > 1 {
+		gen.output.WriteString("__result->types[__result->length] = AHOY_TYPE_INT; ")
+		gen.output.WriteString("__result->data[__result->length++] = (intptr_t)__elem; ")
+	} else {
+		gen.output.WriteString("__result->types[__result->length] = AHOY_TYPE_INT; ")
+		gen.output.WriteString(fmt.Sprintf("__result->data[__result->length++] = (intptr_t)%s; ", params[0]))
+	}
+	gen.output.WriteString("} } ")
+	gen.output.WriteString("__result; })")
+}
+
+// lambdaParts splits a lambda into its parameter names and body
+func lambdaParts(lambda *ASTNode) ([]string, *ASTNode) {
+	paramCount := 1
+	if count, err := strconv.Atoi(lambda.Value); err == nil {
+		paramCount = count
+	}
+	if paramCount == 1 && len(lambda.Children) == 1 {
+		// Old format: single param in Value, body is first child
+		return []string{lambda.Value}, lambda.Children[0]
+	}
+	if len(lambda.Children) > paramCount {
+		params := []string{}
+		for _, param := range lambda.Children[:paramCount] {
+			params = append(params, param.Value)
+		}
+		return params, lambda.Children[paramCount]
+	}
+	return []string{"x"}, lambda.Children[0]
+}
+
+// generateDictLambdaInline generates map_values, filter and to_array on a
+// dict. One lambda parameter is the entry's value; two are its key and value.
+// map_values and filter keep the keys and return a dict, to_array collects
+// the lambda's results into an array.
+func (gen *CodeGenerator) generateDictLambdaInline(dictNode *ASTNode, methodName string, lambda *ASTNode) {
+	params, body := lambdaParts(lambda)
+	valueType := dictValueType(gen.declaredType(dictNode))
+	if valueType == "" {
+		valueType = "int"
+	}
+
+	// The body sees the parameters with the dict's types
+	savedParams := gen.lambdaParams
+	gen.lambdaParams = map[string]string{}
+	for name, paramType := range savedParams {
+		gen.lambdaParams[name] = paramType
+	}
+	valueParam := params[len(params)-1]
+	gen.lambdaParams[valueParam] = valueType
+	if len(params) > 1 {
+		gen.lambdaParams[params[0]] = "string"
+	}
+	defer func() { gen.lambdaParams = savedParams }()
+
+	gen.output.WriteString("({ ")
+	gen.output.WriteString("HashMap* __src = ")
+	gen.generateNodeInternal(dictNode, false)
+	gen.output.WriteString("; ")
+	switch methodName {
+	case "to_array":
+		gen.arrayImpls = true
+		gen.output.WriteString("AhoyArray* __result = malloc(sizeof(AhoyArray)); ")
+		gen.output.WriteString("__result->capacity = __src->size > 0 ? __src->size : 1; ")
+		gen.output.WriteString("__result->data = malloc(__result->capacity * sizeof(intptr_t)); ")
+		gen.output.WriteString("__result->types = malloc(__result->capacity * sizeof(AhoyValueType)); ")
+		gen.output.WriteString("__result->is_typed = 0; ")
+		gen.output.WriteString("__result->length = 0; ")
+	default:
+		gen.output.WriteString("HashMap* __result = createHashMap(__src->size); ")
+	}
+	gen.output.WriteString("for (int __b = 0; __b < __src->capacity; __b++) ")
+	gen.output.WriteString("for (HashMapEntry* __e = __src->buckets[__b]; __e != NULL; __e = __e->next) { ")
+
+	if len(params) > 1 {
+		gen.output.WriteString(fmt.Sprintf("char* %s = __e->key; ", params[0]))
+	}
+	switch valueType {
+	case "float":
+		gen.output.WriteString(fmt.Sprintf("double %s = ahoy_value_from_raw((intptr_t)__e->value, __e->valueType).as.f; ", valueParam))
+	case "string":
+		gen.output.WriteString(fmt.Sprintf("char* %s = (char*)__e->value; ", valueParam))
+	case "bool":
+		gen.output.WriteString(fmt.Sprintf("bool %s = (intptr_t)__e->value != 0; ", valueParam))
+	case "char":
+		gen.output.WriteString(fmt.Sprintf("char %s = (char)(intptr_t)__e->value; ", valueParam))
+	default:
+		gen.output.WriteString(fmt.Sprintf("int %s = (int)(intptr_t)__e->value; ", valueParam))
+	}
+
+	switch methodName {
+	case "map_values":
+		resultType := gen.getValueType(body)
+		gen.output.WriteString("hashMapPutTyped(__result, __e->key, (void*)(")
+		gen.generateSlotValue(body, resultType)
+		gen.output.WriteString(fmt.Sprintf("), %s); ", gen.getAhoyTypeEnum(resultType)))
+	case "filter":
+		gen.output.WriteString("if (")
+		gen.generateNodeInternal(body, false)
+		gen.output.WriteString(") hashMapPutTyped(__result, __e->key, __e->value, __e->valueType); ")
+	case "to_array":
+		resultType := gen.getValueType(body)
+		gen.output.WriteString(fmt.Sprintf("__result->types[__result->length] = %s; ", gen.getAhoyTypeEnum(resultType)))
+		gen.output.WriteString("__result->data[__result->length++] = ")
+		gen.generateSlotValue(body, resultType)
+		gen.output.WriteString("; ")
+	}
+	gen.output.WriteString("} ")
+	gen.output.WriteString("__result; })")
+}
+
+func (gen *CodeGenerator) writeTypeConstructors() {
+	// Note: Vector2 and Color constructors removed
+	// These types should be provided by imported libraries (e.g., raylib)
+	// If needed, users can define their own constructor functions
+}
+
+func (gen *CodeGenerator) writeStructHelperFunctions() {
+	// Generate print helper for each struct type
+	// Track which structs we've processed to avoid duplicates (since we store both lowercase and capitalized)
+	processed := make(map[string]bool)
+
+	// First pass: Add forward declarations
+	for _, structInfo := range gen.structs {
+		if processed[structInfo.Name] {
+			continue
+		}
+		// Skip JSON structs - they don't have C representations
+		if gen.jsonStructs[structInfo.Name] {
+			continue
+		}
+		processed[structInfo.Name] = true
+		cStructName := capitalizeFirst(structInfo.Name)
+		gen.funcForwardDecls.WriteString(fmt.Sprintf("char* print_struct_helper_%s(%s obj);\n", structInfo.Name, cStructName))
+	}
+
+	// Second pass: Add implementations
+	processed = make(map[string]bool)
+	for _, structInfo := range gen.structs {
+		// Skip if already processed (avoid duplicates from lowercase/capitalized pairs)
+		if processed[structInfo.Name] {
+			continue
+		}
+		// Skip JSON structs - they don't have C representations
+		if gen.jsonStructs[structInfo.Name] {
+			continue
+		}
+		processed[structInfo.Name] = true
+
+		cStructName := capitalizeFirst(structInfo.Name)
+		gen.funcDecls.WriteString(fmt.Sprintf("\n// Print helper for %s\n", structInfo.Name))
+		gen.funcDecls.WriteString(fmt.Sprintf("char* print_struct_helper_%s(%s obj) {\n", structInfo.Name, cStructName))
+		gen.funcDecls.WriteString("    static char buffer[512];\n")
+
+		// The format is written in pieces, each ending after a nested
+		// struct, as nested helpers share their buffer between calls
+		var pieces []string
+		var format strings.Builder
+		var values []string
+		endPiece := func() {
+			piece := fmt.Sprintf("\"%s\"", format.String())
+			if len(values) > 0 {
+				piece += ", " + strings.Join(values, ", ")
+			}
+			pieces = append(pieces, piece)
+			format.Reset()
+			values = nil
+		}
+
+		// Anonymous structs use {} format, named structs use name{} format
+		if strings.HasPrefix(structInfo.Name, "__anon_struct_") {
+			format.WriteString("{")
+		} else {
+			format.WriteString(structInfo.Name + "{")
+		}
+
+		for i, field := range structInfo.Fields {
+			if i > 0 {
+				format.WriteString(", ")
+			}
+			format.WriteString(field.Name)
+			// Anonymous structs use ": " (space), named structs use ":" (no space)
+			if strings.HasPrefix(structInfo.Name, "__anon_struct_") {
+				format.WriteString(": ")
+			} else {
+				format.WriteString(":")
+			}
+
+			// Add format specifier and value based on field type; arrays
+			// and dicts are shown empty
+			switch field.Type {
+			case "int":
+				format.WriteString("%d")
+				values = append(values, fmt.Sprintf("obj.%s", field.Name))
+			case "float", "double":
+				format.WriteString("%g")
+				values = append(values, fmt.Sprintf("obj.%s", field.Name))
+			case "char*", "const char*":
+				format.WriteString("\\\"%s\\\"")
+				values = append(values, fmt.Sprintf("(obj.%s ? obj.%s : \"\")", field.Name, field.Name))
+			case "char":
+				format.WriteString("%c")
+				values = append(values, fmt.Sprintf("obj.%s", field.Name))
+			case "bool":
+				format.WriteString("%s")
+				values = append(values, fmt.Sprintf("obj.%s ? \"true\" : \"false\"", field.Name))
+			case "AhoyArray*":
+				format.WriteString("[]")
+			case "HashMap*":
+				format.WriteString("<>")
+			default:
+				if nested := gen.printableStruct(field.Type); nested != "" {
+					format.WriteString("%s")
+					values = append(values, fmt.Sprintf("print_struct_helper_%s(obj.%s)", nested, field.Name))
+					endPiece()
+				} else if strings.HasSuffix(field.Type, "*") {
+					format.WriteString("%p")
+					values = append(values, fmt.Sprintf("(void*)obj.%s", field.Name))
+				} else {
+					format.WriteString("%p")
+					values = append(values, fmt.Sprintf("(void*)&obj.%s", field.Name))
+				}
+			}
+		}
+
+		// Close with } for all structs
+		format.WriteString("}")
+		endPiece()
+
+		if len(pieces) == 1 {
+			gen.funcDecls.WriteString(fmt.Sprintf("    sprintf(buffer, %s);\n", pieces[0]))
+		} else {
+			gen.funcDecls.WriteString("    int length = 0;\n")
+			for _, piece := range pieces {
+				gen.funcDecls.WriteString(fmt.Sprintf("    length += snprintf(buffer + length, sizeof(buffer) - length, %s);\n", piece))
+			}
+		}
+		gen.funcDecls.WriteString("    return buffer;\n")
+		gen.funcDecls.WriteString("}\n")
+	}
+}
+
+// printableStruct returns the name of the struct with a print helper that
+// a C type names, or "" if it names none
+func (gen *CodeGenerator) printableStruct(cType string) string {
+	for _, structInfo := range gen.structs {
+		if capitalizeFirst(structInfo.Name) == cType && !gen.jsonStructs[structInfo.Name] {
+			return structInfo.Name
+		}
+	}
+	return ""
+}
+
+// addInclude includes a system header once
+func (gen *CodeGenerator) addInclude(header string) {
+	if !gen.includes[header] {
+		gen.includes[header] = true
+		gen.orderedIncludes = append(gen.orderedIncludes, header)
+	}
+}
+
+func (gen *CodeGenerator) writeStringHelperFunctions() {
+	if len(gen.stringMethods) == 0 {
+		return
+	}
+
+	gen.addInclude("ctype.h") // For tolower/toupper
+	gen.addInclude("regex.h") // For regex matching
+
+	// Helper function to duplicate strings
+	gen.funcDecls.WriteString("\n// String Helper Functions\n")
+	gen.funcDecls.WriteString("char* ahoy_string_dup(const char* src) {\n")
+	gen.funcDecls.WriteString("    if (!src) return NULL;\n")
+	gen.funcDecls.WriteString("    char* dest = malloc(strlen(src) + 1);\n")
+	gen.funcDecls.WriteString("    strcpy(dest, src);\n")
+	gen.funcDecls.WriteString("    return dest;\n")
+	gen.funcDecls.WriteString("}\n\n")
+
+	// length method
+	if gen.stringMethods["length"] {
+		gen.funcDecls.WriteString("int ahoy_string_length(const char* str) {\n")
+		gen.funcDecls.WriteString("    return str ? strlen(str) : 0;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// upper method
+	if gen.stringMethods["upper"] {
+		gen.funcDecls.WriteString("char* ahoy_string_upper(const char* str) {\n")
+		gen.funcDecls.WriteString("    if (!str) return NULL;\n")
+		gen.funcDecls.WriteString("    char* result = ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; result[i]; i++) {\n")
+		gen.funcDecls.WriteString("        result[i] = toupper(result[i]);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// lower method
+	if gen.stringMethods["lower"] {
+		gen.funcDecls.WriteString("char* ahoy_string_lower(const char* str) {\n")
+		gen.funcDecls.WriteString("    if (!str) return NULL;\n")
+		gen.funcDecls.WriteString("    char* result = ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; result[i]; i++) {\n")
+		gen.funcDecls.WriteString("        result[i] = tolower(result[i]);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// replace method
+	if gen.stringMethods["replace"] {
+		gen.funcDecls.WriteString("char* ahoy_string_replace(const char* str, const char* old, const char* new_str) {\n")
+		gen.funcDecls.WriteString("    if (!str || !old || !new_str) return ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    int count = 0;\n")
+		gen.funcDecls.WriteString("    const char* tmp = str;\n")
+		gen.funcDecls.WriteString("    while ((tmp = strstr(tmp, old))) {\n")
+		gen.funcDecls.WriteString("        count++;\n")
+		gen.funcDecls.WriteString("        tmp += strlen(old);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    int old_len = strlen(old);\n")
+		gen.funcDecls.WriteString("    int new_len = strlen(new_str);\n")
+		gen.funcDecls.WriteString("    int result_len = strlen(str) + count * (new_len - old_len);\n")
+		gen.funcDecls.WriteString("    char* result = malloc(result_len + 1);\n")
+		gen.funcDecls.WriteString("    char* ptr = result;\n")
+		gen.funcDecls.WriteString("    while (*str) {\n")
+		gen.funcDecls.WriteString("        if (strstr(str, old) == str) {\n")
+		gen.funcDecls.WriteString("            strcpy(ptr, new_str);\n")
+		gen.funcDecls.WriteString("            ptr += new_len;\n")
+		gen.funcDecls.WriteString("            str += old_len;\n")
+		gen.funcDecls.WriteString("        } else {\n")
+		gen.funcDecls.WriteString("            *ptr++ = *str++;\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    *ptr = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// contains method
+	if gen.stringMethods["contains"] {
+		gen.funcDecls.WriteString("bool ahoy_string_contains(const char* str, const char* substr) {\n")
+		gen.funcDecls.WriteString("    if (!str || !substr) return false;\n")
+		gen.funcDecls.WriteString("    return strstr(str, substr) != NULL;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// strip method
+	if gen.stringMethods["strip"] {
+		gen.funcDecls.WriteString("char* ahoy_string_strip(const char* str) {\n")
+		gen.funcDecls.WriteString("    if (!str) return NULL;\n")
+		gen.funcDecls.WriteString("    while (*str && isspace(*str)) str++;\n")
+		gen.funcDecls.WriteString("    if (!*str) return ahoy_string_dup(\"\");\n")
+		gen.funcDecls.WriteString("    const char* end = str + strlen(str) - 1;\n")
+		gen.funcDecls.WriteString("    while (end > str && isspace(*end)) end--;\n")
+		gen.funcDecls.WriteString("    int len = end - str + 1;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(len + 1);\n")
+		gen.funcDecls.WriteString("    strncpy(result, str, len);\n")
+		gen.funcDecls.WriteString("    result[len] = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// count method
+	if gen.stringMethods["count"] {
+		gen.funcDecls.WriteString("int ahoy_string_count(const char* str, const char* substr) {\n")
+		gen.funcDecls.WriteString("    if (!str || !substr) return 0;\n")
+		gen.funcDecls.WriteString("    int count = 0;\n")
+		gen.funcDecls.WriteString("    const char* tmp = str;\n")
+		gen.funcDecls.WriteString("    while ((tmp = strstr(tmp, substr))) {\n")
+		gen.funcDecls.WriteString("        count++;\n")
+		gen.funcDecls.WriteString("        tmp += strlen(substr);\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return count;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// lpad method
+	if gen.stringMethods["lpad"] {
+		gen.funcDecls.WriteString("char* ahoy_string_lpad(const char* str, int length, const char* pad) {\n")
+		gen.funcDecls.WriteString("    if (!str || !pad) return ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    int str_len = strlen(str);\n")
+		gen.funcDecls.WriteString("    if (str_len >= length) return ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    int pad_len = length - str_len;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(length + 1);\n")
+		gen.funcDecls.WriteString("    int pad_char_len = strlen(pad);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < pad_len; i++) {\n")
+		gen.funcDecls.WriteString("        result[i] = pad[i % pad_char_len];\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    strcpy(result + pad_len, str);\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// rpad method
+	if gen.stringMethods["rpad"] {
+		gen.funcDecls.WriteString("char* ahoy_string_rpad(const char* str, int length, const char* pad) {\n")
+		gen.funcDecls.WriteString("    if (!str || !pad) return ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    int str_len = strlen(str);\n")
+		gen.funcDecls.WriteString("    if (str_len >= length) return ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    int pad_len = length - str_len;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(length + 1);\n")
+		gen.funcDecls.WriteString("    strcpy(result, str);\n")
+		gen.funcDecls.WriteString("    int pad_char_len = strlen(pad);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < pad_len; i++) {\n")
+		gen.funcDecls.WriteString("        result[str_len + i] = pad[i % pad_char_len];\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    result[length] = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// pad method
+	if gen.stringMethods["pad"] {
+		gen.funcDecls.WriteString("char* ahoy_string_pad(const char* str, int length, const char* pad) {\n")
+		gen.funcDecls.WriteString("    if (!str || !pad) return ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    int str_len = strlen(str);\n")
+		gen.funcDecls.WriteString("    if (str_len >= length) return ahoy_string_dup(str);\n")
+		gen.funcDecls.WriteString("    int total_pad = length - str_len;\n")
+		gen.funcDecls.WriteString("    int left_pad = total_pad / 2;\n")
+		gen.funcDecls.WriteString("    int right_pad = total_pad - left_pad;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(length + 1);\n")
+		gen.funcDecls.WriteString("    int pad_char_len = strlen(pad);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < left_pad; i++) {\n")
+		gen.funcDecls.WriteString("        result[i] = pad[i % pad_char_len];\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    strcpy(result + left_pad, str);\n")
+		gen.funcDecls.WriteString("    for (int i = 0; i < right_pad; i++) {\n")
+		gen.funcDecls.WriteString("        result[left_pad + str_len + i] = pad[i % pad_char_len];\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    result[length] = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// match method (regex)
+	if gen.stringMethods["match"] {
+		gen.funcDecls.WriteString("bool ahoy_string_match(const char* str, const char* pattern) {\n")
+		gen.funcDecls.WriteString("    if (!str || !pattern) return false;\n")
+		gen.funcDecls.WriteString("    regex_t regex;\n")
+		gen.funcDecls.WriteString("    int ret = regcomp(&regex, pattern, REG_EXTENDED | REG_NOSUB);\n")
+		gen.funcDecls.WriteString("    if (ret) return false;\n")
+		gen.funcDecls.WriteString("    ret = regexec(&regex, str, 0, NULL, 0);\n")
+		gen.funcDecls.WriteString("    regfree(&regex);\n")
+		gen.funcDecls.WriteString("    return ret == 0;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// get_file method
+	if gen.stringMethods["get_file"] {
+		gen.funcDecls.WriteString("char* ahoy_string_get_file(const char* path) {\n")
+		gen.funcDecls.WriteString("    if (!path) return NULL;\n")
+		gen.funcDecls.WriteString("    const char* filename = strrchr(path, '/');\n")
+		gen.funcDecls.WriteString("    if (!filename) filename = strrchr(path, '\\\\');\n")
+		gen.funcDecls.WriteString("    if (!filename) return ahoy_string_dup(path);\n")
+		gen.funcDecls.WriteString("    return ahoy_string_dup(filename + 1);\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// Case conversion methods - these are more complex, so provide simplified versions
+	if gen.stringMethods["camel_case"] {
+		gen.funcDecls.WriteString("char* ahoy_string_camel_case(const char* str) {\n")
+		gen.funcDecls.WriteString("    if (!str) return NULL;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(strlen(str) + 1);\n")
+		gen.funcDecls.WriteString("    int j = 0;\n")
+		gen.funcDecls.WriteString("    bool capitalize_next = false;\n")
+		gen.funcDecls.WriteString("    bool first = true;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; str[i]; i++) {\n")
+		gen.funcDecls.WriteString("        if (str[i] == ' ' || str[i] == '_' || str[i] == '-') {\n")
+		gen.funcDecls.WriteString("            capitalize_next = true;\n")
+		gen.funcDecls.WriteString("        } else if (capitalize_next) {\n")
+		gen.funcDecls.WriteString("            result[j++] = toupper(str[i]);\n")
+		gen.funcDecls.WriteString("            capitalize_next = false;\n")
+		gen.funcDecls.WriteString("        } else if (first) {\n")
+		gen.funcDecls.WriteString("            result[j++] = tolower(str[i]);\n")
+		gen.funcDecls.WriteString("            first = false;\n")
+		gen.funcDecls.WriteString("        } else {\n")
+		gen.funcDecls.WriteString("            result[j++] = str[i];\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    result[j] = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	if gen.stringMethods["snake_case"] {
+		gen.funcDecls.WriteString("char* ahoy_string_snake_case(const char* str) {\n")
+		gen.funcDecls.WriteString("    if (!str) return NULL;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(strlen(str) * 2 + 1);\n")
+		gen.funcDecls.WriteString("    int j = 0;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; str[i]; i++) {\n")
+		gen.funcDecls.WriteString("        if (str[i] == ' ' || str[i] == '-') {\n")
+		gen.funcDecls.WriteString("            result[j++] = '_';\n")
+		gen.funcDecls.WriteString("        } else if (isupper(str[i]) && i > 0) {\n")
+		gen.funcDecls.WriteString("            result[j++] = '_';\n")
+		gen.funcDecls.WriteString("            result[j++] = tolower(str[i]);\n")
+		gen.funcDecls.WriteString("        } else {\n")
+		gen.funcDecls.WriteString("            result[j++] = tolower(str[i]);\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    result[j] = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	if gen.stringMethods["pascal_case"] {
+		gen.funcDecls.WriteString("char* ahoy_string_pascal_case(const char* str) {\n")
+		gen.funcDecls.WriteString("    if (!str) return NULL;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(strlen(str) + 1);\n")
+		gen.funcDecls.WriteString("    int j = 0;\n")
+		gen.funcDecls.WriteString("    bool capitalize_next = true;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; str[i]; i++) {\n")
+		gen.funcDecls.WriteString("        if (str[i] == ' ' || str[i] == '_' || str[i] == '-') {\n")
+		gen.funcDecls.WriteString("            capitalize_next = true;\n")
+		gen.funcDecls.WriteString("        } else if (capitalize_next) {\n")
+		gen.funcDecls.WriteString("            result[j++] = toupper(str[i]);\n")
+		gen.funcDecls.WriteString("            capitalize_next = false;\n")
+		gen.funcDecls.WriteString("        } else {\n")
+		gen.funcDecls.WriteString("            result[j++] = tolower(str[i]);\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    result[j] = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	if gen.stringMethods["kebab_case"] {
+		gen.funcDecls.WriteString("char* ahoy_string_kebab_case(const char* str) {\n")
+		gen.funcDecls.WriteString("    if (!str) return NULL;\n")
+		gen.funcDecls.WriteString("    char* result = malloc(strlen(str) * 2 + 1);\n")
+		gen.funcDecls.WriteString("    int j = 0;\n")
+		gen.funcDecls.WriteString("    for (int i = 0; str[i]; i++) {\n")
+		gen.funcDecls.WriteString("        if (str[i] == ' ' || str[i] == '_') {\n")
+		gen.funcDecls.WriteString("            result[j++] = '-';\n")
+		gen.funcDecls.WriteString("        } else if (isupper(str[i]) && i > 0) {\n")
+		gen.funcDecls.WriteString("            result[j++] = '-';\n")
+		gen.funcDecls.WriteString("            result[j++] = tolower(str[i]);\n")
+		gen.funcDecls.WriteString("        } else {\n")
+		gen.funcDecls.WriteString("            result[j++] = tolower(str[i]);\n")
+		gen.funcDecls.WriteString("        }\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    result[j] = '\\0';\n")
+		gen.funcDecls.WriteString("    return result;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+
+	// split method - returns an array[string] of the pieces between delimiters
+	if gen.stringMethods["split"] {
+		gen.funcDecls.WriteString("AhoyArray* ahoy_string_split(const char* str, const char* delim) {\n")
+		gen.funcDecls.WriteString("    AhoyArray* arr = malloc(sizeof(AhoyArray));\n")
+		gen.funcDecls.WriteString("    arr->length = 0;\n")
+		gen.funcDecls.WriteString("    arr->capacity = 1;\n")
+		gen.funcDecls.WriteString("    arr->is_typed = 1;\n")
+		gen.funcDecls.WriteString("    arr->element_type = AHOY_TYPE_STRING;\n")
+		gen.funcDecls.WriteString("    arr->mods = 0;\n")
+		gen.funcDecls.WriteString("    size_t delim_len = delim ? strlen(delim) : 0;\n")
+		gen.funcDecls.WriteString("    if (str && delim_len > 0) {\n")
+		gen.funcDecls.WriteString("        for (const char* p = strstr(str, delim); p; p = strstr(p + delim_len, delim)) arr->capacity++;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    arr->data = malloc(arr->capacity * sizeof(intptr_t));\n")
+		gen.funcDecls.WriteString("    arr->types = malloc(arr->capacity * sizeof(AhoyValueType));\n")
+		gen.funcDecls.WriteString("    if (!str) return arr;\n")
+		gen.funcDecls.WriteString("    const char* start = str;\n")
+		gen.funcDecls.WriteString("    while (1) {\n")
+		gen.funcDecls.WriteString("        const char* end = delim_len > 0 ? strstr(start, delim) : NULL;\n")
+		gen.funcDecls.WriteString("        size_t len = end ? (size_t)(end - start) : strlen(start);\n")
+		gen.funcDecls.WriteString("        char* piece = malloc(len + 1);\n")
+		gen.funcDecls.WriteString("        memcpy(piece, start, len);\n")
+		gen.funcDecls.WriteString("        piece[len] = '\\0';\n")
+		gen.funcDecls.WriteString("        arr->types[arr->length] = AHOY_TYPE_STRING;\n")
+		gen.funcDecls.WriteString("        arr->data[arr->length++] = (intptr_t)piece;\n")
+		gen.funcDecls.WriteString("        if (!end) break;\n")
+		gen.funcDecls.WriteString("        start = end + delim_len;\n")
+		gen.funcDecls.WriteString("    }\n")
+		gen.funcDecls.WriteString("    return arr;\n")
+		gen.funcDecls.WriteString("}\n\n")
+	}
+}
+
+func (gen *CodeGenerator) generateObjectLiteral(node *ASTNode) {
+	// Generate compound literal initialization
+	// If node.Value is set, it's a typed literal (e.g., rectangle{...} or vector2{...})
+	// If node.Value is empty, it's an anonymous object - use HashMap
+
+	structName := ""
+	if node.Value != "" {
+		// Typed object literal - capitalize first letter for C struct name
+		structName = capitalizeFirst(node.Value)
+		if _, builtin := builtinStructs[node.Value]; builtin {
+			gen.useBuiltinStruct(node.Value)
+		}
+
+		// Check if this is a known Ahoy struct type
+		_, hasStructInfo := gen.structs[node.Value]
+		if !hasStructInfo {
+			_, hasStructInfo = gen.structs[structName]
+		}
+
+		// For typed object literals, generate C struct initialization even if we don't have
+		// the full struct definition (e.g., C structs from imported headers like Vector2)
+		// Trust that if node.Value is set, the parser validated it's a valid type
+		gen.output.WriteString(fmt.Sprintf("(%s)", structName))
+	} else {
+		// Anonymous object - use HashMap
+		gen.generateAnonymousObject(node)
+		return
+	}
+
+	gen.output.WriteString("{")
+
+	// Collect explicitly set properties
+	explicitProps := make(map[string]bool)
+	for _, prop := range node.Children {
+		if prop.Type == NODE_OBJECT_PROPERTY {
+			explicitProps[prop.Value] = true
+		}
+	}
+
+	// If this is a typed literal with a struct definition, apply defaults
+	structInfo, hasStructInfo := gen.structs[node.Value]
+	if !hasStructInfo && structName != "" {
+		structInfo, hasStructInfo = gen.structs[structName]
+	}
+
+	first := true
+	if hasStructInfo {
+		// Generate all fields with defaults or explicit values
+		for _, field := range structInfo.Fields {
+			if !first {
+				gen.output.WriteString(", ")
+			}
+			gen.output.WriteString(".")
+			gen.output.WriteString(field.Name)
+			gen.output.WriteString(" = ")
+
+			// Check if this field was explicitly set
+			fieldSet := false
+			for _, prop := range node.Children {
+				if prop.Type == NODE_OBJECT_PROPERTY && prop.Value == field.Name {
+					gen.generateNodeInternal(prop.Children[0], false)
+					fieldSet = true
+					break
+				}
+			}
+
+			// If not explicitly set, use default value or type default
+			if !fieldSet {
+				if field.DefaultValue != "" {
+					gen.output.WriteString(field.DefaultValue)
+				} else {
+					// Use type-specific zero value
+					gen.output.WriteString(gen.getTypeDefault(field.Type))
+				}
+			}
+			first = false
+		}
+	} else {
+		// No struct info, just output explicit properties
+		for _, prop := range node.Children {
+			if prop.Type == NODE_OBJECT_PROPERTY {
+				if !first {
+					gen.output.WriteString(", ")
+				}
+				gen.output.WriteString(".")
+				gen.output.WriteString(prop.Value)
+				gen.output.WriteString(" = ")
+				gen.generateNodeInternal(prop.Children[0], false)
+				first = false
+			}
+		}
+	}
+
+	gen.output.WriteString("}")
+}
+
+// generateAnonymousObject generates a HashMap for anonymous object literals
+func (gen *CodeGenerator) generateAnonymousObject(node *ASTNode) {
+	dictName := fmt.Sprintf("dict_%d", gen.varCounter)
+	gen.varCounter++
+
+	gen.output.WriteString(fmt.Sprintf("({ HashMap* %s = createHashMap(16); ", dictName))
+
+	// Add properties
+	for _, prop := range node.Children {
+		if prop.Type == NODE_OBJECT_PROPERTY {
+			// Determine value type
+			valueType := "string"
+			if len(prop.Children) > 0 {
+				valueType = gen.getValueType(prop.Children[0])
+			}
+			ahoyTypeEnum := gen.getAhoyTypeEnum(valueType)
+
+			gen.output.WriteString(fmt.Sprintf("hashMapPutTyped(%s, \"%s\", (void*)",
+				dictName, prop.Value))
+			if len(prop.Children) > 0 {
+				gen.generateSlotValue(prop.Children[0], valueType)
+			} else {
+				gen.output.WriteString("(intptr_t)0")
+			}
+			gen.output.WriteString(fmt.Sprintf(", %s); ", ahoyTypeEnum))
+		}
+	}
+
+	gen.output.WriteString(fmt.Sprintf("%s; })", dictName))
+}
+
+// capitalizeFirst capitalizes the first letter of a string
+func capitalizeFirst(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func (gen *CodeGenerator) generateObjectAccess(node *ASTNode) {
+	// Object property access: person<'name'>
+	// If the object is a HashMap (dict or generic), use hashMapGet
+	// Otherwise use struct field access (person.name)
+
+	objectName := node.Value
+	propertyName := ""
+	if len(node.Children) > 0 && node.Children[0].Type == NODE_STRING {
+		propertyName = node.Children[0].Value
+	}
+
+	// Check if this is a HashMap/dict or generic parameter
+	objectType := ""
+	if varType, exists := gen.variables[objectName]; exists {
+		objectType = varType
+	} else if varType, exists := gen.functionVars[objectName]; exists {
+		objectType = varType
+	}
+
+	// If object is dict, HashMap*, generic, or intptr_t, use hashMapGet
+	if objectType == "dict" || objectType == "HashMap*" || objectType == "generic" || objectType == "intptr_t" ||
+		strings.HasPrefix(objectType, "dict[") || strings.HasPrefix(objectType, "dict<") {
+		// A key that isn't a literal is looked up by its value
+		key := fmt.Sprintf("\"%s\"", propertyName)
+		if len(node.Children) > 0 && node.Children[0].Type != NODE_STRING {
+			savedOutput := gen.output
+			gen.output = strings.Builder{}
+			gen.generateNode(node.Children[0])
+			key = gen.output.String()
+			gen.output = savedOutput
+		}
+		if valueType := dictValueType(objectType); gen.slotStruct(valueType) != nil {
+			// Struct slots hold a pointer to the boxed struct
+			gen.output.WriteString(fmt.Sprintf("(*(%s*)hashMapGet(%s, %s))", gen.mapType(valueType), objectName, key))
+			return
+		}
+		gen.output.WriteString(fmt.Sprintf("((char*)hashMapGet("))
+		// Cast generic/intptr_t to HashMap*
+		if objectType == "generic" || objectType == "intptr_t" {
+			gen.output.WriteString("(HashMap*)")
+		}
+		gen.output.WriteString(objectName)
+		gen.output.WriteString(fmt.Sprintf(", %s))", key))
+	} else {
+		// Struct field access
+		gen.output.WriteString(objectName)
+		gen.output.WriteString(".")
+		gen.output.WriteString(propertyName)
+	}
+}
+
+// resolveEnumMember resolves a bare identifier to the C name of the int or
+// flags enum member it names. Variables and constants in scope shadow enum
+// members, and a member name shared by several enums is reported as an error
+// listing them, since only the enum name can tell them apart.
+func (gen *CodeGenerator) resolveEnumMember(node *ASTNode) string {
+	name := node.Value
+	if _, isVar := gen.variables[name]; isVar {
+		return ""
+	}
+	if _, isVar := gen.functionVars[name]; isVar {
+		return ""
+	}
+	if gen.constants[name] {
+		return ""
+	}
+
+	candidates := gen.enumsWithMember(name)
+	if len(candidates) > 1 {
+		gen.reportError(node.Line, fmt.Sprintf("Ambiguous enum member '%s' - found in multiple enums: %s. Please prefix with enum name (e.g., %s.%s)",
+			name, strings.Join(candidates, ", "), candidates[0], name))
+		return ""
+	}
+	if len(candidates) == 1 {
+		enumName := candidates[0]
+		if gen.enumTypes[enumName] == "int" || gen.enumTypes[enumName] == "flags" {
+			return enumName + "_" + name
+		}
+	}
+	return ""
+}
+
+// enumsWithMember returns the names of the enums with a member called name,
+// sorted
+func (gen *CodeGenerator) enumsWithMember(name string) []string {
+	var names []string
+	for enumName, members := range gen.enums {
+		if members[name] {
+			names = append(names, enumName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Helper function to check if a string slice contains a string
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
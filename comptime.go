@@ -0,0 +1,799 @@
+package ahoy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// comptimeStepLimit is how many statements and expressions a comptime call
+// may evaluate before it's taken to never finish
+const comptimeStepLimit = 100_000_000
+
+// comptimeDepthLimit is how deep comptime calls may recurse
+const comptimeDepthLimit = 10_000
+
+// comptimeArray is an array built while compiling. Arrays are shared by
+// reference, as they are at run time.
+type comptimeArray struct {
+	elements []any
+}
+
+// comptimeFlow is how a statement left the code around it
+type comptimeFlow int
+
+const (
+	comptimeNormal comptimeFlow = iota
+	comptimeReturn
+	comptimeHalt
+	comptimeNext
+)
+
+// comptimeError is why a comptime call couldn't be evaluated, with the line
+// of the code that stopped it
+type comptimeError struct {
+	line    int
+	message string
+}
+
+func (err *comptimeError) Error() string {
+	return err.message
+}
+
+// comptime evaluates calls of Ahoy functions while compiling, walking their
+// ASTs. Values are int64, float64, bool, string and *comptimeArray.
+// Anything with an effect outside the call, like printing or reading a
+// variable, stops the evaluation with an error.
+type comptime struct {
+	functions map[string]*ASTNode
+	constants map[string]*ASTNode
+	values    map[string]any  // constants already evaluated
+	resolving map[string]bool // constants being evaluated, to catch cycles
+	steps     int
+	depth     int
+}
+
+func newComptime(ast *ASTNode) *comptime {
+	c := &comptime{
+		functions: map[string]*ASTNode{},
+		constants: map[string]*ASTNode{},
+		values:    map[string]any{},
+		resolving: map[string]bool{},
+	}
+	for _, child := range ast.Children {
+		switch child.Type {
+		case NODE_FUNCTION:
+			c.functions[child.Value] = child
+		case NODE_CONSTANT_DECLARATION:
+			c.constants[child.Value] = child
+		}
+	}
+	return c
+}
+
+// evaluateComptime replaces each comptime call in the program with the
+// literal it evaluates to, reporting the calls that can't run while
+// compiling
+func (gen *CodeGenerator) evaluateComptime(ast *ASTNode) {
+	var calls []*ASTNode
+	var find func(node *ASTNode)
+	find = func(node *ASTNode) {
+		if node == nil {
+			return
+		}
+		if node.Type == NODE_COMPTIME {
+			calls = append(calls, node)
+			return
+		}
+		for _, child := range node.Children {
+			find(child)
+		}
+		find(node.DefaultValue)
+		find(node.Capacity)
+	}
+	find(ast)
+	if len(calls) == 0 {
+		return
+	}
+
+	c := newComptime(ast)
+	for _, node := range calls {
+		call := node.Children[0]
+		c.steps, c.depth = 0, 0
+		value, err := c.expression(call, nil)
+		var literal *ASTNode
+		if err == nil {
+			literal, err = comptimeLiteral(value, node.Line)
+		}
+		if err != nil {
+			var details []string
+			if at, ok := err.(*comptimeError); ok && at.line > 0 && at.line != node.Line {
+				details = append(details, fmt.Sprintf("stopped at line %d", at.line))
+			}
+			gen.reportError(node.Line, fmt.Sprintf("comptime %s|...| %s", call.Value, err), details...)
+			// Later passes see the call, so its errors are the only ones
+			*node = *call
+			continue
+		}
+		literal.Span = node.Span
+		*node = *literal
+	}
+}
+
+// comptimeLiteral is the AST of the literal giving a comptime value
+func comptimeLiteral(value any, line int) (*ASTNode, error) {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			number := &ASTNode{Type: NODE_NUMBER, Value: strconv.FormatInt(v, 10)[1:], DataType: "int", Line: line}
+			return &ASTNode{Type: NODE_UNARY_OP, Value: "-", Children: []*ASTNode{number}, Line: line}, nil
+		}
+		return &ASTNode{Type: NODE_NUMBER, Value: strconv.FormatInt(v, 10), DataType: "int", Line: line}, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, &comptimeError{message: fmt.Sprintf("gives %v, which has no literal", v)}
+		}
+		text := strconv.FormatFloat(math.Abs(v), 'g', -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		number := &ASTNode{Type: NODE_NUMBER, Value: text, DataType: "float", Line: line}
+		if v < 0 {
+			return &ASTNode{Type: NODE_UNARY_OP, Value: "-", Children: []*ASTNode{number}, Line: line}, nil
+		}
+		return number, nil
+	case bool:
+		return &ASTNode{Type: NODE_BOOLEAN, Value: strconv.FormatBool(v), DataType: "bool", Line: line}, nil
+	case string:
+		return &ASTNode{Type: NODE_STRING, Value: v, DataType: "string", Line: line}, nil
+	case *comptimeArray:
+		array := &ASTNode{Type: NODE_ARRAY_LITERAL, DataType: "array", Line: line}
+		for _, element := range v.elements {
+			literal, err := comptimeLiteral(element, line)
+			if err != nil {
+				return nil, err
+			}
+			array.Children = append(array.Children, literal)
+		}
+		return array, nil
+	}
+	return nil, &comptimeError{message: "gives no value"}
+}
+
+// comptimeTypeName names a comptime value's type for errors
+func comptimeTypeName(value any) string {
+	switch value.(type) {
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case *comptimeArray:
+		return "array"
+	}
+	return "nothing"
+}
+
+func (c *comptime) fail(node *ASTNode, format string, args ...any) error {
+	return &comptimeError{line: nodeLine(node), message: fmt.Sprintf(format, args...)}
+}
+
+// step counts one evaluation against the step limit
+func (c *comptime) step(node *ASTNode) error {
+	c.steps++
+	if c.steps > comptimeStepLimit {
+		return c.fail(node, "didn't finish within %d steps", comptimeStepLimit)
+	}
+	return nil
+}
+
+// call runs a function with the arguments of a call node
+func (c *comptime) call(node *ASTNode, env map[string]any) (any, error) {
+	fn, exists := c.functions[node.Value]
+	if !exists {
+		return nil, c.fail(node, "calls %s|...|, which can't run while compiling", node.Value)
+	}
+	if strings.Contains(fn.DataType, ",") {
+		return nil, c.fail(node, "calls %s|...|, which returns more than one value", node.Value)
+	}
+	var params []*ASTNode
+	if len(fn.Children) > 0 {
+		params = fn.Children[0].Children
+	}
+
+	locals := map[string]any{}
+	positional := 0
+	for _, arg := range node.Children {
+		name := ""
+		if arg.Type == NODE_BINARY_OP && arg.Value == "named_arg" {
+			name = arg.Children[0].Value
+			arg = arg.Children[1]
+		} else if positional < len(params) {
+			name = params[positional].Value
+			positional++
+		} else {
+			return nil, c.fail(node, "gives %s|...| %d argument(s), it takes %d", node.Value, len(node.Children), len(params))
+		}
+		value, err := c.expression(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		locals[name] = value
+	}
+	for _, param := range params {
+		if _, given := locals[param.Value]; given {
+			continue
+		}
+		if param.DefaultValue == nil {
+			return nil, c.fail(node, "gives %s|...| no %s", node.Value, param.Value)
+		}
+		value, err := c.expression(param.DefaultValue, nil)
+		if err != nil {
+			return nil, err
+		}
+		locals[param.Value] = value
+	}
+
+	c.depth++
+	defer func() { c.depth-- }()
+	if c.depth > comptimeDepthLimit {
+		return nil, c.fail(node, "recurses deeper than %d calls", comptimeDepthLimit)
+	}
+	_, value, err := c.block(fn.Children[1], locals)
+	return value, err
+}
+
+// block runs statements in order until one returns, halts or skips ahead
+func (c *comptime) block(block *ASTNode, env map[string]any) (comptimeFlow, any, error) {
+	for _, statement := range block.Children {
+		flow, value, err := c.statement(statement, env)
+		if err != nil || flow != comptimeNormal {
+			return flow, value, err
+		}
+	}
+	return comptimeNormal, nil, nil
+}
+
+// loopBody runs a loop's body, reporting whether the loop goes on
+func (c *comptime) loopBody(body *ASTNode, env map[string]any) (bool, comptimeFlow, any, error) {
+	flow, value, err := c.block(body, env)
+	switch {
+	case err != nil || flow == comptimeReturn:
+		return false, flow, value, err
+	case flow == comptimeHalt:
+		return false, comptimeNormal, nil, nil
+	}
+	return true, comptimeNormal, nil, nil
+}
+
+func (c *comptime) statement(node *ASTNode, env map[string]any) (comptimeFlow, any, error) {
+	if err := c.step(node); err != nil {
+		return comptimeNormal, nil, err
+	}
+
+	switch node.Type {
+	case NODE_VARIABLE_DECLARATION, NODE_ASSIGNMENT, NODE_CONSTANT_DECLARATION:
+		if node.Value == "" && len(node.Children) == 2 && node.Children[0].Type == NODE_ARRAY_ACCESS {
+			return comptimeNormal, nil, c.setElement(node.Children[0], node.Children[1], env)
+		}
+		if node.Value == "" || len(node.Children) == 0 {
+			return comptimeNormal, nil, c.fail(node, "declares a variable without a value, which isn't supported while compiling")
+		}
+		value, err := c.expression(node.Children[0], env)
+		if err != nil {
+			return comptimeNormal, nil, err
+		}
+		env[node.Value] = value
+		return comptimeNormal, nil, nil
+
+	case NODE_IF_STATEMENT:
+		// Condition and block pairs, then the else block if there is one
+		for i := 0; i+1 < len(node.Children); i += 2 {
+			condition, err := c.condition(node.Children[i], env)
+			if err != nil {
+				return comptimeNormal, nil, err
+			}
+			if condition {
+				return c.block(node.Children[i+1], env)
+			}
+		}
+		if len(node.Children)%2 == 1 {
+			return c.block(node.Children[len(node.Children)-1], env)
+		}
+		return comptimeNormal, nil, nil
+
+	case NODE_WHILE_LOOP:
+		loopVar, condition, body := "", node.Children[0], node.Children[len(node.Children)-1]
+		if len(node.Children) >= 3 && node.Children[0].Type == NODE_IDENTIFIER {
+			loopVar = node.Children[0].Value
+			env[loopVar] = int64(0)
+			condition = node.Children[len(node.Children)-2]
+			if len(node.Children) == 4 {
+				start, err := c.expression(node.Children[1], env)
+				if err != nil {
+					return comptimeNormal, nil, err
+				}
+				env[loopVar] = start
+			}
+		}
+		for {
+			holds, err := c.condition(condition, env)
+			if err != nil || !holds {
+				return comptimeNormal, nil, err
+			}
+			more, flow, value, err := c.loopBody(body, env)
+			if !more {
+				return flow, value, err
+			}
+			if loopVar != "" {
+				if err := c.increment(node, loopVar, env); err != nil {
+					return comptimeNormal, nil, err
+				}
+			}
+		}
+
+	case NODE_FOR_RANGE_LOOP:
+		// loop i:start to end stops before end; the older loop:start to end
+		// runs through it
+		var loopVar string
+		var start, end any
+		var body *ASTNode
+		var err error
+		switch len(node.Children) {
+		case 4:
+			loopVar, body = node.Children[0].Value, node.Children[3]
+			if start, err = c.expression(node.Children[1], env); err == nil {
+				end, err = c.expression(node.Children[2], env)
+			}
+		case 3:
+			body = node.Children[2]
+			if start, err = c.expression(node.Children[0], env); err == nil {
+				end, err = c.expression(node.Children[1], env)
+			}
+		default:
+			body = node.Children[0]
+			start, err = strconv.ParseInt(node.Value, 10, 64)
+			if err == nil {
+				end, err = strconv.ParseInt(node.DataType, 10, 64)
+			}
+		}
+		if err != nil {
+			return comptimeNormal, nil, err
+		}
+		first, firstOK := start.(int64)
+		last, lastOK := end.(int64)
+		if !firstOK || !lastOK {
+			return comptimeNormal, nil, c.fail(node, "loops over a range of %s to %s; ranges need ints", comptimeTypeName(start), comptimeTypeName(end))
+		}
+		if loopVar == "" {
+			last++
+		}
+		for i := first; i < last; i++ {
+			if loopVar != "" {
+				env[loopVar] = i
+			}
+			more, flow, value, err := c.loopBody(body, env)
+			if !more {
+				return flow, value, err
+			}
+		}
+		return comptimeNormal, nil, nil
+
+	case NODE_FOR_COUNT_LOOP, NODE_FOR_LOOP:
+		loopVar, body := "", node.Children[len(node.Children)-1]
+		if len(node.Children) >= 2 && node.Children[0].Type == NODE_IDENTIFIER {
+			loopVar = node.Children[0].Value
+			env[loopVar] = int64(0)
+			if len(node.Children) == 3 {
+				start, err := c.expression(node.Children[1], env)
+				if err != nil {
+					return comptimeNormal, nil, err
+				}
+				env[loopVar] = start
+			}
+		}
+		for {
+			if err := c.step(node); err != nil {
+				return comptimeNormal, nil, err
+			}
+			more, flow, value, err := c.loopBody(body, env)
+			if !more {
+				return flow, value, err
+			}
+			if loopVar != "" {
+				if err := c.increment(node, loopVar, env); err != nil {
+					return comptimeNormal, nil, err
+				}
+			}
+		}
+
+	case NODE_FOR_IN_ARRAY_LOOP, NODE_FOR_IN_DICT_LOOP:
+		// loop element in array, or loop index, element in array
+		indexVar, elementVar := "", node.Children[0].Value
+		iterable, body := node.Children[1], node.Children[2]
+		if node.Type == NODE_FOR_IN_DICT_LOOP {
+			indexVar, elementVar = node.Children[0].Value, node.Children[1].Value
+			iterable, body = node.Children[2], node.Children[3]
+		}
+		value, err := c.expression(iterable, env)
+		if err != nil {
+			return comptimeNormal, nil, err
+		}
+		array, ok := value.(*comptimeArray)
+		if !ok {
+			return comptimeNormal, nil, c.fail(node, "loops over a %s; only arrays can be looped over while compiling", comptimeTypeName(value))
+		}
+		for i := 0; i < len(array.elements); i++ {
+			if indexVar != "" {
+				env[indexVar] = int64(i)
+			}
+			env[elementVar] = array.elements[i]
+			more, flow, value, err := c.loopBody(body, env)
+			if !more {
+				return flow, value, err
+			}
+		}
+		return comptimeNormal, nil, nil
+
+	case NODE_RETURN_STATEMENT:
+		if len(node.Children) > 1 {
+			return comptimeNormal, nil, c.fail(node, "returns more than one value")
+		}
+		if len(node.Children) == 0 {
+			return comptimeReturn, nil, nil
+		}
+		value, err := c.expression(node.Children[0], env)
+		return comptimeReturn, value, err
+
+	case NODE_HALT:
+		return comptimeHalt, nil, nil
+
+	case NODE_NEXT:
+		return comptimeNext, nil, nil
+
+	case NODE_CALL, NODE_METHOD_CALL, NODE_COMPTIME:
+		_, err := c.expression(node, env)
+		return comptimeNormal, nil, err
+	}
+	return comptimeNormal, nil, c.fail(node, "runs a statement that isn't supported while compiling")
+}
+
+// increment steps an int loop variable
+func (c *comptime) increment(node *ASTNode, name string, env map[string]any) error {
+	i, ok := env[name].(int64)
+	if !ok {
+		return c.fail(node, "counts with a %s loop variable", comptimeTypeName(env[name]))
+	}
+	env[name] = i + 1
+	return nil
+}
+
+// setElement runs array[index]: value
+func (c *comptime) setElement(target, valueNode *ASTNode, env map[string]any) error {
+	array, index, err := c.element(target, env)
+	if err != nil {
+		return err
+	}
+	value, err := c.expression(valueNode, env)
+	if err != nil {
+		return err
+	}
+	array.elements[index] = value
+	return nil
+}
+
+// element looks up the array and checked index of array[index]
+func (c *comptime) element(node *ASTNode, env map[string]any) (*comptimeArray, int, error) {
+	value, err := c.identifier(node, node.Value, env)
+	if err != nil {
+		return nil, 0, err
+	}
+	array, ok := value.(*comptimeArray)
+	if !ok {
+		return nil, 0, c.fail(node, "indexes %s, a %s", node.Value, comptimeTypeName(value))
+	}
+	indexValue, err := c.expression(node.Children[0], env)
+	if err != nil {
+		return nil, 0, err
+	}
+	index, ok := indexValue.(int64)
+	if !ok {
+		return nil, 0, c.fail(node, "indexes %s with a %s", node.Value, comptimeTypeName(indexValue))
+	}
+	if index < 0 || index >= int64(len(array.elements)) {
+		return nil, 0, c.fail(node, "indexes %s out of bounds: %d of %d element(s)", node.Value, index, len(array.elements))
+	}
+	return array, int(index), nil
+}
+
+func (c *comptime) condition(node *ASTNode, env map[string]any) (bool, error) {
+	value, err := c.expression(node, env)
+	if err != nil {
+		return false, err
+	}
+	holds, ok := value.(bool)
+	if !ok {
+		return false, c.fail(node, "tests a %s as a condition", comptimeTypeName(value))
+	}
+	return holds, nil
+}
+
+// identifier looks a name up among the locals, then the constants
+func (c *comptime) identifier(node *ASTNode, name string, env map[string]any) (any, error) {
+	if value, exists := env[name]; exists {
+		return value, nil
+	}
+	if value, exists := c.values[name]; exists {
+		return value, nil
+	}
+	constant, exists := c.constants[name]
+	if !exists || len(constant.Children) == 0 {
+		return nil, c.fail(node, "reads %s, which is only known at run time", name)
+	}
+	if c.resolving[name] {
+		return nil, c.fail(node, "reads %s, whose value depends on itself", name)
+	}
+	c.resolving[name] = true
+	value, err := c.expression(constant.Children[0], nil)
+	delete(c.resolving, name)
+	if err != nil {
+		return nil, err
+	}
+	c.values[name] = value
+	return value, nil
+}
+
+func (c *comptime) expression(node *ASTNode, env map[string]any) (any, error) {
+	if err := c.step(node); err != nil {
+		return nil, err
+	}
+
+	switch node.Type {
+	case NODE_NUMBER:
+		if i, err := strconv.ParseInt(node.Value, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			return f, nil
+		}
+		return nil, c.fail(node, "uses the number %s, which can't be read while compiling", node.Value)
+	case NODE_STRING:
+		return node.Value, nil
+	case NODE_BOOLEAN:
+		return node.Value == "true", nil
+	case NODE_IDENTIFIER:
+		return c.identifier(node, node.Value, env)
+	case NODE_COMPTIME:
+		return c.expression(node.Children[0], env)
+	case NODE_CALL:
+		return c.call(node, env)
+	case NODE_TERNARY:
+		condition, err := c.condition(node.Children[0], env)
+		if err != nil {
+			return nil, err
+		}
+		if condition {
+			return c.expression(node.Children[1], env)
+		}
+		return c.expression(node.Children[2], env)
+	case NODE_ARRAY_LITERAL:
+		array := &comptimeArray{}
+		for _, child := range node.Children {
+			value, err := c.expression(child, env)
+			if err != nil {
+				return nil, err
+			}
+			array.elements = append(array.elements, value)
+		}
+		return array, nil
+	case NODE_ARRAY_ACCESS:
+		array, index, err := c.element(node, env)
+		if err != nil {
+			return nil, err
+		}
+		return array.elements[index], nil
+	case NODE_METHOD_CALL:
+		return c.method(node, env)
+	case NODE_UNARY_OP:
+		value, err := c.expression(node.Children[0], env)
+		if err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case int64:
+			if node.Value == "-" {
+				return comptimeInt(-v), nil
+			}
+		case float64:
+			if node.Value == "-" {
+				return -v, nil
+			}
+		case bool:
+			if node.Value == "not" {
+				return !v, nil
+			}
+		}
+		return nil, c.fail(node, "applies '%s' to a %s", node.Value, comptimeTypeName(value))
+	case NODE_BINARY_OP:
+		return c.binary(node, env)
+	}
+	return nil, c.fail(node, "uses an expression that isn't supported while compiling")
+}
+
+// method runs the array and string methods that don't reach outside the
+// call: push and length
+func (c *comptime) method(node *ASTNode, env map[string]any) (any, error) {
+	object, err := c.expression(node.Children[0], env)
+	if err != nil {
+		return nil, err
+	}
+	var args []any
+	if len(node.Children) > 1 {
+		for _, arg := range node.Children[1].Children {
+			value, err := c.expression(arg, env)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, value)
+		}
+	}
+
+	switch v := object.(type) {
+	case *comptimeArray:
+		switch node.Value {
+		case "push":
+			v.elements = append(v.elements, args...)
+			return nil, nil
+		case "length":
+			return int64(len(v.elements)), nil
+		}
+	case string:
+		if node.Value == "length" {
+			return int64(len(v)), nil
+		}
+	}
+	return nil, c.fail(node, "calls .%s|...| on a %s, which isn't supported while compiling", node.Value, comptimeTypeName(object))
+}
+
+func (c *comptime) binary(node *ASTNode, env map[string]any) (any, error) {
+	left, err := c.expression(node.Children[0], env)
+	if err != nil {
+		return nil, err
+	}
+
+	// and and or only evaluate their right side when it decides the result
+	switch node.Value {
+	case "and", "&&", "or", "||":
+		l, ok := left.(bool)
+		if !ok {
+			return nil, c.fail(node, "applies '%s' to a %s", node.Value, comptimeTypeName(left))
+		}
+		if l == (node.Value == "or" || node.Value == "||") {
+			return l, nil
+		}
+		return c.condition(node.Children[1], env)
+	}
+
+	right, err := c.expression(node.Children[1], env)
+	if err != nil {
+		return nil, err
+	}
+	mismatch := func() error {
+		return c.fail(node, "applies '%s' to a %s and a %s", node.Value, comptimeTypeName(left), comptimeTypeName(right))
+	}
+
+	switch node.Value {
+	case "is", "==":
+		return comptimeEqual(left, right), nil
+	case "!=":
+		return !comptimeEqual(left, right), nil
+	}
+
+	if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return nil, mismatch()
+		}
+		switch node.Value {
+		case "+", "plus":
+			return l + r, nil
+		case "<", "lesser_than", "less_than":
+			return l < r, nil
+		case ">", "greater_than":
+			return l > r, nil
+		case "<=":
+			return l <= r, nil
+		case ">=":
+			return l >= r, nil
+		}
+		return nil, mismatch()
+	}
+
+	l, lInt := left.(int64)
+	r, rInt := right.(int64)
+	if lInt && rInt {
+		switch node.Value {
+		case "+", "plus":
+			return comptimeInt(l + r), nil
+		case "-", "minus":
+			return comptimeInt(l - r), nil
+		case "*", "times":
+			return comptimeInt(l * r), nil
+		case "div", "%", "mod":
+			if r == 0 {
+				return nil, c.fail(node, "divides by zero")
+			}
+			if node.Value == "div" {
+				return comptimeInt(l / r), nil
+			}
+			return comptimeInt(l % r), nil
+		}
+	}
+
+	lf, lNumber := comptimeFloat(left)
+	rf, rNumber := comptimeFloat(right)
+	if !lNumber || !rNumber {
+		return nil, mismatch()
+	}
+	switch node.Value {
+	case "+", "plus":
+		return lf + rf, nil
+	case "-", "minus":
+		return lf - rf, nil
+	case "*", "times":
+		return lf * rf, nil
+	case "/":
+		// Always float division, as at run time
+		if rf == 0 {
+			return nil, c.fail(node, "divides by zero")
+		}
+		return lf / rf, nil
+	case "div":
+		if rf == 0 {
+			return nil, c.fail(node, "divides by zero")
+		}
+		return comptimeInt(int64(lf / rf)), nil
+	case "mod":
+		return math.Mod(lf, rf), nil
+	case "<", "lesser_than", "less_than":
+		return lf < rf, nil
+	case ">", "greater_than":
+		return lf > rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">=":
+		return lf >= rf, nil
+	}
+	return nil, mismatch()
+}
+
+// comptimeInt wraps an int result to 32 bits, as C's int does when the
+// function runs
+func comptimeInt(v int64) int64 {
+	return int64(int32(v))
+}
+
+// comptimeFloat widens a number to a float
+func comptimeFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// comptimeEqual compares values like is does: numbers by value, strings by
+// content and arrays by identity
+func comptimeEqual(left, right any) bool {
+	if l, ok := comptimeFloat(left); ok {
+		r, ok := comptimeFloat(right)
+		return ok && l == r
+	}
+	return left == right
+}
@@ -0,0 +1,141 @@
+package ahoy
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CoverageFile is the name of the profile a program built with
+// BuildOptions.Cover writes next to its C file
+const CoverageFile = "coverage.out"
+
+// Coverage is how many times the statements on each line ran, by file and
+// line. Lines without statements aren't in it.
+type Coverage map[string]map[int]int
+
+// FileCoverage sums up the coverage of one file
+type FileCoverage struct {
+	File    string
+	Lines   int // lines with statements
+	Covered int // lines whose statements ran at least once
+}
+
+// Percent is the share of lines that ran, 100 for a file without statements
+func (f FileCoverage) Percent() float64 {
+	if f.Lines == 0 {
+		return 100
+	}
+	return float64(f.Covered) * 100 / float64(f.Lines)
+}
+
+// ReadCoverage reads a profile written by a program built with
+// BuildOptions.Cover. Counts for a line that appears more than once are
+// added up, so profiles from several runs can be concatenated.
+func ReadCoverage(path string) (Coverage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	coverage := Coverage{}
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// file:line count, where the file may itself contain colons
+		position, count, found := strings.Cut(line, " ")
+		colon := strings.LastIndex(position, ":")
+		if !found || colon < 0 {
+			return nil, fmt.Errorf("%s:%d: expected file:line count", path, number)
+		}
+		lineNumber, err := strconv.Atoi(position[colon+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad line number %q", path, number, position[colon+1:])
+		}
+		hits, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad count %q", path, number, count)
+		}
+		source := position[:colon]
+		if coverage[source] == nil {
+			coverage[source] = map[int]int{}
+		}
+		coverage[source][lineNumber] += hits
+	}
+	return coverage, scanner.Err()
+}
+
+// Files sums up each file's coverage, sorted by file
+func (c Coverage) Files() []FileCoverage {
+	var files []FileCoverage
+	for _, file := range slices.Sorted(maps.Keys(c)) {
+		summary := FileCoverage{File: file, Lines: len(c[file])}
+		for _, hits := range c[file] {
+			if hits > 0 {
+				summary.Covered++
+			}
+		}
+		files = append(files, summary)
+	}
+	return files
+}
+
+// Total sums up the coverage of every file
+func (c Coverage) Total() FileCoverage {
+	var total FileCoverage
+	for _, file := range c.Files() {
+		total.Lines += file.Lines
+		total.Covered += file.Covered
+	}
+	return total
+}
+
+// WriteCoverageHTML writes a page showing the source of every covered file,
+// with lines that ran in green and lines that never ran in red
+func WriteCoverageHTML(w io.Writer, c Coverage) error {
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Ahoy coverage</title>\n<style>\n")
+	page.WriteString("body { font-family: sans-serif; }\n")
+	page.WriteString("pre { font-family: monospace; line-height: 1.3; }\n")
+	page.WriteString(".line { display: block; }\n")
+	page.WriteString(".number { color: #888; display: inline-block; width: 4em; }\n")
+	page.WriteString(".hit { background: #d9f2d9; }\n")
+	page.WriteString(".miss { background: #f7d4d4; }\n")
+	page.WriteString("</style>\n</head>\n<body>\n")
+	for _, file := range c.Files() {
+		source, err := os.ReadFile(file.File)
+		if err != nil {
+			return err
+		}
+		page.WriteString(fmt.Sprintf("<h2>%s: %.1f%% of %d lines</h2>\n<pre>\n",
+			html.EscapeString(file.File), file.Percent(), file.Lines))
+		for i, text := range strings.Split(strings.TrimSuffix(string(source), "\n"), "\n") {
+			class := "line"
+			title := ""
+			if hits, exists := c[file.File][i+1]; exists {
+				if hits > 0 {
+					class += " hit"
+				} else {
+					class += " miss"
+				}
+				title = fmt.Sprintf(" title=\"%d run(s)\"", hits)
+			}
+			page.WriteString(fmt.Sprintf("<span class=\"%s\"%s><span class=\"number\">%d</span>%s</span>",
+				class, title, i+1, html.EscapeString(text)))
+		}
+		page.WriteString("</pre>\n")
+	}
+	page.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, page.String())
+	return err
+}
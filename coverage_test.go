@@ -0,0 +1,56 @@
+package ahoy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCoverage(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "main.ahoy")
+	if err := os.WriteFile(source, []byte("x: 1\nif x is 2 then\n    print|x|\n$\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile := filepath.Join(dir, CoverageFile)
+	content := "mode: count\n" + source + ":1 1\n" + source + ":2 1\n" + source + ":3 0\n" + source + ":1 2\n"
+	if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	coverage, err := ReadCoverage(profile)
+	if err != nil {
+		t.Fatalf("ReadCoverage: %v", err)
+	}
+	if hits := coverage[source][1]; hits != 3 {
+		t.Errorf("expected the counts of line 1 to add up to 3, got %d", hits)
+	}
+	files := coverage.Files()
+	if len(files) != 1 || files[0].Lines != 3 || files[0].Covered != 2 {
+		t.Fatalf("expected 2 of 3 lines covered, got %+v", files)
+	}
+	if percent := coverage.Total().Percent(); percent < 66.6 || percent > 66.7 {
+		t.Errorf("expected 66.7%%, got %.1f%%", percent)
+	}
+
+	var page strings.Builder
+	if err := WriteCoverageHTML(&page, coverage); err != nil {
+		t.Fatalf("WriteCoverageHTML: %v", err)
+	}
+	for _, want := range []string{
+		`<span class="line miss" title="0 run(s)"><span class="number">3</span>    print|x|</span>`,
+		`<span class="line"><span class="number">4</span>$</span>`,
+	} {
+		if !strings.Contains(page.String(), want) {
+			t.Errorf("expected the page to contain %s", want)
+		}
+	}
+
+	if err := os.WriteFile(profile, []byte("main.ahoy 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCoverage(profile); err == nil {
+		t.Errorf("expected an error for a line without a line number")
+	}
+}
@@ -0,0 +1,196 @@
+package ahoy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// deterministicCall is a C function a -deterministic build replaces with a
+// helper: its prototype, and the helper's body
+type deterministicCall struct {
+	prototype string
+	body      string
+}
+
+// deterministicCalls are the C functions a -deterministic build replaces,
+// by name: the random numbers, the clocks and raylib's frame pacing
+var deterministicCalls = map[string]deterministicCall{
+	"rand":          {"int ahoy_det_rand(void)", "return (int)(ahoy_det_next() >> 33);"},
+	"srand":         {"void ahoy_det_srand(unsigned int seed)", "ahoy_det_seed(seed);"},
+	"SetRandomSeed": {"void ahoy_det_SetRandomSeed(unsigned int seed)", "ahoy_det_seed(seed);"},
+	"GetRandomValue": {"int ahoy_det_GetRandomValue(int min, int max)",
+		"if (min > max) { int swap = min; min = max; max = swap; }\n" +
+			"    return min + (int)(ahoy_det_next() % ((unsigned long long)max - min + 1));"},
+	"time": {"time_t ahoy_det_time(time_t* out)",
+		"time_t now = (time_t)ahoy_det_clock;\n    if (out) *out = now;\n    return now;"},
+	"GetTime":      {"double ahoy_det_GetTime(void)", "return ahoy_det_clock;"},
+	"GetFrameTime": {"float ahoy_det_GetFrameTime(void)", "return (float)ahoy_det_frame_length;"},
+	"SetTargetFPS": {"void ahoy_det_SetTargetFPS(int fps)",
+		"if (fps > 0) ahoy_det_frame_length = 1.0 / fps;\n    SetTargetFPS(fps);"},
+	"EndDrawing": {"void ahoy_det_EndDrawing(void)", "EndDrawing();\n    ahoy_det_step();"},
+}
+
+// deterministicInputs are the raylib input queries a -deterministic build
+// records and replays, with the C type each returns
+var deterministicInputs = map[string]string{
+	"IsKeyDown": "bool", "IsKeyPressed": "bool", "IsKeyPressedRepeat": "bool", "IsKeyReleased": "bool", "IsKeyUp": "bool",
+	"GetKeyPressed": "int", "GetCharPressed": "int",
+	"IsMouseButtonDown": "bool", "IsMouseButtonPressed": "bool", "IsMouseButtonReleased": "bool", "IsMouseButtonUp": "bool",
+	"GetMouseX": "int", "GetMouseY": "int", "GetMouseWheelMove": "float",
+	"IsGamepadButtonDown": "bool", "IsGamepadButtonPressed": "bool", "IsGamepadButtonReleased": "bool",
+}
+
+// deterministicName returns the C function a call to name makes: under
+// -deterministic the helper that replaces it, if there is one
+func (gen *CodeGenerator) deterministicName(name string) string {
+	if !gen.deterministic {
+		return name
+	}
+	_, replaced := deterministicCalls[name]
+	_, input := deterministicInputs[name]
+	if !replaced && !input && name != "GetMousePosition" {
+		return name
+	}
+	if !slices.Contains(gen.deterministicUsed, name) {
+		gen.deterministicUsed = append(gen.deterministicUsed, name)
+	}
+	return "ahoy_det_" + name
+}
+
+// writeDeterministicFunctions generates the helpers of a -deterministic
+// build. Random numbers come from a generator seeded with AHOY_SEED (1 when
+// it isn't set), time is a virtual clock that moves on by a frame at each
+// EndDrawing, game.run frame or frame.limit, and the input queries are
+// written to the file AHOY_RECORD names, or read back from AHOY_REPLAY's.
+func (gen *CodeGenerator) writeDeterministicFunctions() {
+	if !gen.deterministic {
+		return
+	}
+	gen.addInclude("time.h")
+
+	gen.funcReturnStructs.WriteString("void ahoy_det_step(void);\n")
+	gen.funcDecls.WriteString("\n// -deterministic: seeded random numbers, a virtual clock and recorded input\n")
+	gen.funcDecls.WriteString(`static unsigned long long ahoy_det_state = 1;
+static double ahoy_det_clock = 0;
+static double ahoy_det_frame_length = 1.0 / 60;
+static long ahoy_det_frame = 0;
+static FILE* ahoy_det_record = NULL;
+static FILE* ahoy_det_replay = NULL;
+
+static void ahoy_det_start(void) {
+    static bool started = false;
+    if (started) return;
+    started = true;
+    const char* seed = getenv("AHOY_SEED");
+    if (seed) ahoy_det_state = strtoull(seed, NULL, 10);
+    const char* replay = getenv("AHOY_REPLAY");
+    if (replay) {
+        ahoy_det_replay = fopen(replay, "r");
+        if (!ahoy_det_replay || fscanf(ahoy_det_replay, "ahoy-replay seed %llu\n", &ahoy_det_state) != 1) {
+            fprintf(stderr, "can't replay %s: not a recording\n", replay);
+            exit(1);
+        }
+    }
+    const char* record = getenv("AHOY_RECORD");
+    if (record) {
+        ahoy_det_record = fopen(record, "w");
+        if (!ahoy_det_record) {
+            perror(record);
+            exit(1);
+        }
+        fprintf(ahoy_det_record, "ahoy-replay seed %llu\n", ahoy_det_state);
+    }
+}
+
+static void ahoy_det_seed(unsigned long long seed) {
+    ahoy_det_start();
+    ahoy_det_state = seed;
+}
+
+static unsigned long long ahoy_det_next(void) {
+    ahoy_det_start();
+    unsigned long long z = (ahoy_det_state += 0x9E3779B97F4A7C15ULL);
+    z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9ULL;
+    z = (z ^ (z >> 27)) * 0x94D049BB133111EBULL;
+    return z ^ (z >> 31);
+}
+
+void ahoy_det_step(void) {
+    ahoy_det_clock += ahoy_det_frame_length;
+    ahoy_det_frame++;
+    // A crash still leaves the frames before it to replay
+    if (ahoy_det_record) fflush(ahoy_det_record);
+}
+
+// ahoy_det_input returns what an input query gave when it was recorded, or
+// the live value, and records the value it returns
+static double ahoy_det_input(const char* name, int arg, double live) {
+    ahoy_det_start();
+    if (ahoy_det_replay) {
+        char recorded[64];
+        long frame;
+        int recordedArg;
+        double value;
+        if (fscanf(ahoy_det_replay, "%ld %63s %d %lf\n", &frame, recorded, &recordedArg, &value) == 4) {
+            if (frame != ahoy_det_frame || strcmp(recorded, name) != 0 || recordedArg != arg) {
+                fflush(stdout);
+                fprintf(stderr, "replay diverged at frame %ld: the recording has %s(%d) at frame %ld, the program asked for %s(%d)\n",
+                    ahoy_det_frame, recorded, recordedArg, frame, name, arg);
+                exit(1);
+            }
+            live = value;
+        } else {
+            fflush(stdout);
+            fprintf(stderr, "replay finished at frame %ld; input is live from here\n", ahoy_det_frame);
+            fclose(ahoy_det_replay);
+            ahoy_det_replay = NULL;
+        }
+    }
+    if (ahoy_det_record) fprintf(ahoy_det_record, "%ld %s %d %.17g\n", ahoy_det_frame, name, arg, live);
+    return live;
+}
+
+`)
+
+	names := slices.Clone(gen.deterministicUsed)
+	slices.Sort(names)
+	for _, name := range names {
+		if call, replaced := deterministicCalls[name]; replaced {
+			gen.funcReturnStructs.WriteString(call.prototype + ";\n")
+			gen.funcDecls.WriteString(fmt.Sprintf("%s {\n    %s\n}\n\n", call.prototype, call.body))
+			continue
+		}
+		if name == "GetMousePosition" {
+			gen.funcReturnStructs.WriteString("Vector2 ahoy_det_GetMousePosition(void);\n")
+			gen.funcDecls.WriteString("Vector2 ahoy_det_GetMousePosition(void) {\n")
+			gen.funcDecls.WriteString("    Vector2 live = GetMousePosition();\n")
+			gen.funcDecls.WriteString("    float x = (float)ahoy_det_input(\"GetMouseX\", 0, live.x);\n")
+			gen.funcDecls.WriteString("    return (Vector2){x, (float)ahoy_det_input(\"GetMouseY\", 0, live.y)};\n")
+			gen.funcDecls.WriteString("}\n\n")
+			continue
+		}
+		// Queries of a key or button take it as their argument
+		returnType := deterministicInputs[name]
+		param, arg, liveArgs := "void", "0", ""
+		switch {
+		case strings.HasPrefix(name, "IsGamepad"):
+			param, arg, liveArgs = "int gamepad, int button", "gamepad * 1000 + button", "gamepad, button"
+		case strings.HasPrefix(name, "Is"):
+			param, arg, liveArgs = "int button", "button", "button"
+		}
+		prototype := fmt.Sprintf("%s ahoy_det_%s(%s)", returnType, name, param)
+		gen.funcReturnStructs.WriteString(prototype + ";\n")
+		gen.funcDecls.WriteString(fmt.Sprintf("%s {\n    return (%s)ahoy_det_input(%q, %s, %s(%s));\n}\n\n",
+			prototype, returnType, name, arg, name, liveArgs))
+	}
+}
+
+// seedRandom returns the C that seeds rand before shuffle or pick draws
+// from it; a -deterministic build's generator is seeded once, when it starts
+func (gen *CodeGenerator) seedRandom() string {
+	if gen.deterministic {
+		return ""
+	}
+	return "    srand(time(NULL));\n"
+}
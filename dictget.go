@@ -0,0 +1,148 @@
+package ahoy
+
+import (
+	"fmt"
+)
+
+// isDictGet reports whether node is d.get|key| or d.get_or|key, default|
+func isDictGet(node *ASTNode) bool {
+	return node.Type == NODE_METHOD_CALL && (node.Value == "get" || node.Value == "get_or")
+}
+
+// dictGetType returns the type d.get and d.get_or give back: the value type
+// of a typed dict, or for other dicts the type of get_or's default, and
+// float like d{key} when there's none
+func (gen *CodeGenerator) dictGetType(node *ASTNode) string {
+	if valueType := dictValueType(gen.declaredType(node.Children[0])); valueType != "" {
+		return valueType
+	}
+	if args := node.Children[1].Children; node.Value == "get_or" && len(args) == 2 {
+		return gen.inferType(args[1])
+	}
+	return "float"
+}
+
+// checkDictGet reports a get or get_or with the wrong arguments
+func (gen *CodeGenerator) checkDictGet(node *ASTNode) bool {
+	want := 1
+	if node.Value == "get_or" {
+		want = 2
+	}
+	if got := len(node.Children[1].Children); got != want {
+		gen.reportError(node.Line, fmt.Sprintf("%s takes %d argument(s), got %d", node.Value, want, got),
+			"d.get|key| and d.get_or|key, default|")
+		return false
+	}
+	return true
+}
+
+// generateDictGet generates d.get|key|, the value or the zero value of its
+// type when the key isn't there, and d.get_or|key, default|
+func (gen *CodeGenerator) generateDictGet(node *ASTNode) {
+	if !gen.checkDictGet(node) {
+		return
+	}
+	valueType := gen.dictGetType(node)
+	cType := gen.mapType(valueType)
+	lookup := fmt.Sprintf("__lookup_%d", gen.varCounter)
+	gen.varCounter++
+
+	gen.output.WriteString(fmt.Sprintf("({ AhoyValue %s; ", lookup))
+	gen.generateDictLookup(node, lookup)
+	gen.output.WriteString(fmt.Sprintf(" ? %s : ", gen.dictValueFrom(lookup, valueType)))
+	if node.Value == "get_or" {
+		gen.output.WriteString(fmt.Sprintf("(%s)(", cType))
+		gen.generateNode(node.Children[1].Children[1])
+		gen.output.WriteString(")")
+	} else {
+		gen.output.WriteString(fmt.Sprintf("(%s){0}", cType))
+	}
+	gen.output.WriteString("; })")
+}
+
+// generateDictGetTuple generates value, found: d.get|key|, which sets found
+// to whether the key is there and value to its value, or the zero value of
+// its type when it isn't
+func (gen *CodeGenerator) generateDictGetTuple(targets []*ASTNode, node *ASTNode) {
+	if node.Value != "get" {
+		gen.reportError(node.Line, fmt.Sprintf("%s gives back one value, not %d", node.Value, len(targets)),
+			"value, found: d.get|key| reports whether the key is there")
+		return
+	}
+	if len(targets) != 2 {
+		gen.reportError(node.Line, fmt.Sprintf("get gives back a value and whether it was found, not %d values", len(targets)))
+		return
+	}
+	if !gen.checkDictGet(node) {
+		return
+	}
+	valueType := gen.dictGetType(node)
+	cType := gen.mapType(valueType)
+	lookup := fmt.Sprintf("__lookup_%d", gen.varCounter)
+	gen.varCounter++
+	value, found := targets[0].Value, targets[1].Value
+
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("AhoyValue %s;\n", lookup))
+	gen.writeIndent()
+	gen.declareTupleTarget(found, "bool")
+	gen.generateDictLookup(node, lookup)
+	gen.output.WriteString(";\n")
+	gen.writeIndent()
+	gen.declareTupleTarget(value, valueType)
+	gen.output.WriteString(fmt.Sprintf("%s ? %s : (%s){0};\n", found, gen.dictValueFrom(lookup, valueType), cType))
+}
+
+// declareTupleTarget starts the assignment of one of a tuple's values,
+// declaring the variable with valueType when it's new
+func (gen *CodeGenerator) declareTupleTarget(name string, valueType string) {
+	_, existsInFunc := gen.functionVars[name]
+	_, existsGlobal := gen.variables[name]
+	if !existsInFunc && !existsGlobal {
+		gen.writeVariableType(gen.mapType(valueType), name)
+		if gen.functionVars != nil {
+			gen.functionVars[name] = valueType
+		} else {
+			gen.variables[name] = valueType
+		}
+	}
+	gen.output.WriteString(name + " = ")
+}
+
+// generateDictLookup generates the call that looks the key up into lookup
+// and reports whether it was found
+func (gen *CodeGenerator) generateDictLookup(node *ASTNode, lookup string) {
+	gen.dictMethods["get"] = true
+	object := node.Children[0]
+	gen.output.WriteString("ahoy_dict_get(")
+	if gen.inferType(object) == "generic" {
+		gen.output.WriteString("(HashMap*)")
+	}
+	gen.generateNode(object)
+	gen.output.WriteString(", ")
+	gen.generateNode(node.Children[1].Children[0])
+	gen.output.WriteString(fmt.Sprintf(", &%s)", lookup))
+}
+
+// dictValueFrom returns the C reading a value of valueType out of the
+// AhoyValue lookup. Ints and floats convert to each other, as untyped dicts
+// hold both.
+func (gen *CodeGenerator) dictValueFrom(lookup string, valueType string) string {
+	cType := gen.mapType(valueType)
+	if gen.slotStruct(valueType) != nil {
+		return fmt.Sprintf("*(%s*)%s.as.p", cType, lookup)
+	}
+	switch cType {
+	case "double", "float":
+		return fmt.Sprintf("(%s.type == AHOY_TYPE_FLOAT ? %s.as.f : (double)%s.as.i)", lookup, lookup, lookup)
+	case "int":
+		return fmt.Sprintf("(%s.type == AHOY_TYPE_FLOAT ? (int)%s.as.f : (int)%s.as.i)", lookup, lookup, lookup)
+	case "bool":
+		return lookup + ".as.b"
+	case "char":
+		return lookup + ".as.c"
+	case "char*", "const char*":
+		return fmt.Sprintf("(%s)%s.as.s", cType, lookup)
+	}
+	return fmt.Sprintf("(%s)%s.as.p", cType, lookup)
+}
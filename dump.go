@@ -0,0 +1,161 @@
+package ahoy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AST dump formats DumpAST takes
+const (
+	DumpJSON  = "json"
+	DumpSExpr = "sexp"
+)
+
+var nodeTypeNames = [...]string{
+	NODE_PROGRAM: "program", NODE_PROGRAM_DECLARATION: "program_declaration",
+	NODE_FUNCTION: "function", NODE_VARIABLE_DECLARATION: "variable_declaration",
+	NODE_ASSIGNMENT: "assignment", NODE_IF_STATEMENT: "if",
+	NODE_SWITCH_STATEMENT: "switch", NODE_SWITCH_CASE: "switch_case",
+	NODE_SWITCH_CASE_LIST: "switch_case_list", NODE_SWITCH_CASE_RANGE: "switch_case_range",
+	NODE_WHILE_LOOP: "while_loop", NODE_FOR_LOOP: "for_loop",
+	NODE_FOR_RANGE_LOOP: "for_range_loop", NODE_FOR_COUNT_LOOP: "for_count_loop",
+	NODE_FOR_IN_ARRAY_LOOP: "for_in_array_loop", NODE_FOR_IN_DICT_LOOP: "for_in_dict_loop",
+	NODE_RETURN_STATEMENT: "return", NODE_IMPORT_STATEMENT: "import",
+	NODE_WHEN_STATEMENT: "when", NODE_EXPRESSION: "expression",
+	NODE_BINARY_OP: "binary_op", NODE_UNARY_OP: "unary_op", NODE_CALL: "call",
+	NODE_IDENTIFIER: "identifier", NODE_NUMBER: "number", NODE_STRING: "string",
+	NODE_F_STRING: "f_string", NODE_CHAR: "char", NODE_BOOLEAN: "boolean",
+	NODE_DICT_LITERAL: "dict_literal", NODE_ARRAY_LITERAL: "array_literal",
+	NODE_ARRAY_ACCESS: "array_access", NODE_DICT_ACCESS: "dict_access",
+	NODE_BLOCK: "block", NODE_TYPE: "type", NODE_ENUM_DECLARATION: "enum",
+	NODE_CONSTANT_DECLARATION: "constant", NODE_TUPLE_ASSIGNMENT: "tuple_assignment",
+	NODE_STRUCT_DECLARATION: "struct", NODE_ALIAS_DECLARATION: "alias",
+	NODE_UNION_DECLARATION: "union", NODE_METHOD_CALL: "method_call",
+	NODE_MEMBER_ACCESS: "member_access", NODE_HALT: "halt", NODE_NEXT: "next",
+	NODE_LAMBDA: "lambda", NODE_TERNARY: "ternary", NODE_ASSERT_STATEMENT: "assert",
+	NODE_DEFER_STATEMENT: "defer", NODE_OBJECT_LITERAL: "object_literal",
+	NODE_OBJECT_PROPERTY: "object_property", NODE_OBJECT_ACCESS: "object_access",
+	NODE_TYPE_PROPERTY: "type_property", NODE_FIXED_ARRAY: "fixed_array",
+	NODE_BYTES_LITERAL: "bytes_literal", NODE_EMBED_STATEMENT: "embed",
+	NODE_TEST_BLOCK: "test", NODE_EXTERN: "extern", NODE_COMPTIME: "comptime",
+	NODE_INLINE: "inline", NODE_REEXPORT: "reexport",
+	NODE_RECOVER: "recover",
+}
+
+// nodeTypeName returns the name a node's type has in dumps
+func nodeTypeName(t NodeType) string {
+	if int(t) < len(nodeTypeNames) && nodeTypeNames[t] != "" {
+		return nodeTypeNames[t]
+	}
+	return fmt.Sprintf("node(%d)", t)
+}
+
+// dumpedToken is a token as DumpTokens writes it
+type dumpedToken struct {
+	Type    string `json:"type"`
+	Value   string `json:"value,omitempty"`
+	Span    Span   `json:"span"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// DumpTokens returns tokens as an indented JSON array, each with its type,
+// the text it holds and its span
+func DumpTokens(tokens []Token) string {
+	dumped := make([]dumpedToken, len(tokens))
+	for i, token := range tokens {
+		dumped[i] = dumpedToken{Type: tokenTypeName(token.Type), Value: token.Value, Span: token.Span, Comment: token.Comment}
+	}
+	output, _ := json.MarshalIndent(dumped, "", "  ")
+	return string(output) + "\n"
+}
+
+// dumpedNode is a node as DumpAST writes it in JSON
+type dumpedNode struct {
+	Type            string        `json:"type"`
+	Value           string        `json:"value,omitempty"`
+	DataType        string        `json:"data_type,omitempty"`
+	Span            *Span         `json:"span,omitempty"`
+	DefaultValue    *dumpedNode   `json:"default_value,omitempty"`
+	Capacity        *dumpedNode   `json:"capacity,omitempty"`
+	Tag             string        `json:"tag,omitempty"`
+	EnumType        string        `json:"enum_type,omitempty"`
+	Mutable         bool          `json:"mutable,omitempty"`
+	Comments        []string      `json:"comments,omitempty"`
+	TrailingComment string        `json:"trailing_comment,omitempty"`
+	Children        []*dumpedNode `json:"children,omitempty"`
+}
+
+func newDumpedNode(node *ASTNode) *dumpedNode {
+	if node == nil {
+		return nil
+	}
+	dumped := &dumpedNode{
+		Type: nodeTypeName(node.Type), Value: node.Value, DataType: node.DataType,
+		DefaultValue: newDumpedNode(node.DefaultValue), Capacity: newDumpedNode(node.Capacity), Tag: node.Tag, EnumType: node.EnumType,
+		Mutable: node.IsMutable, Comments: node.Comments, TrailingComment: node.TrailingComment,
+	}
+	if !node.Span.IsZero() {
+		span := node.Span
+		dumped.Span = &span
+	}
+	for _, child := range node.Children {
+		dumped.Children = append(dumped.Children, newDumpedNode(child))
+	}
+	return dumped
+}
+
+// DumpAST returns the tree under node in format, DumpJSON or DumpSExpr. An
+// s-expression is a node per line, like (call "print" @4:1-4:12 ...), with
+// a node's data type after its value, as in :int, and its default value
+// as a (default ...) child.
+func DumpAST(node *ASTNode, format string) (string, error) {
+	switch format {
+	case DumpJSON:
+		output, err := json.MarshalIndent(newDumpedNode(node), "", "  ")
+		return string(output) + "\n", err
+	case DumpSExpr:
+		var b strings.Builder
+		writeSExpr(&b, node, 0)
+		b.WriteString("\n")
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("can't dump a tree as %q; the formats are %s and %s", format, DumpJSON, DumpSExpr)
+}
+
+func writeSExpr(b *strings.Builder, node *ASTNode, depth int) {
+	b.WriteString(strings.Repeat("  ", depth) + "(")
+	if node == nil {
+		b.WriteString("nil)")
+		return
+	}
+	b.WriteString(nodeTypeName(node.Type))
+	if node.Value != "" {
+		b.WriteString(" " + strconv.Quote(node.Value))
+	}
+	if dataType := node.DataType; dataType != "" {
+		if strings.ContainsAny(dataType, " ()") {
+			dataType = strconv.Quote(dataType)
+		}
+		b.WriteString(" :" + dataType)
+	}
+	if span := node.Span; !span.IsZero() {
+		fmt.Fprintf(b, " @%d:%d-%d:%d", span.StartLine, span.StartColumn, span.EndLine, span.EndColumn)
+	}
+	if node.DefaultValue != nil {
+		b.WriteString("\n" + strings.Repeat("  ", depth+1) + "(default\n")
+		writeSExpr(b, node.DefaultValue, depth+2)
+		b.WriteString(")")
+	}
+	if node.Capacity != nil {
+		b.WriteString("\n" + strings.Repeat("  ", depth+1) + "(capacity\n")
+		writeSExpr(b, node.Capacity, depth+2)
+		b.WriteString(")")
+	}
+	for _, child := range node.Children {
+		b.WriteString("\n")
+		writeSExpr(b, child, depth+1)
+	}
+	b.WriteString(")")
+}
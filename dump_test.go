@@ -0,0 +1,90 @@
+package ahoy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDumpAST(t *testing.T) {
+	source := "@ add |a:int, b:int = 2| int:\n    return a + b\n$\nx: add|1|\n"
+	ast, errors := ParseLint(Tokenize(source))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+
+	sexp, err := DumpAST(ast, DumpSExpr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `(program @1:1-4:10
+  (function "add" :int @1:1-3:2
+    (block @1:8-1:24
+      (identifier "a" :int @1:8-1:9)
+      (identifier "b" :int @1:15-1:24
+        (default
+          (number "2" :int @1:23-1:24))))
+    (block @2:5-2:17
+      (return @2:5-2:17
+        (binary_op "+" @2:12-2:17
+          (identifier "a" @2:12-2:13)
+          (identifier "b" @2:16-2:17)))))
+  (assignment "x" @4:1-4:10
+    (call "add" @4:4-4:10
+      (number "1" :int @4:8-4:9))))
+`
+	if sexp != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, sexp)
+	}
+
+	dump, err := DumpAST(ast, DumpJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree struct {
+		Type     string
+		Children []struct {
+			Type     string
+			Value    string
+			DataType string `json:"data_type"`
+			Span     Span
+		}
+	}
+	if err := json.Unmarshal([]byte(dump), &tree); err != nil {
+		t.Fatalf("expected JSON, got %v:\n%s", err, dump)
+	}
+	function := tree.Children[0]
+	if tree.Type != "program" || function.Type != "function" || function.Value != "add" || function.DataType != "int" || function.Span.EndLine != 3 {
+		t.Errorf("unexpected tree %+v", tree)
+	}
+
+	if _, err := DumpAST(ast, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	for nodeType := NODE_PROGRAM; nodeType <= NODE_INLINE; nodeType++ {
+		if strings.HasPrefix(nodeTypeName(nodeType), "node(") {
+			t.Errorf("node type %d has no name", nodeType)
+		}
+	}
+}
+
+func TestDumpTokens(t *testing.T) {
+	var tokens []struct {
+		Type  string
+		Value string
+		Span  Span
+	}
+	if err := json.Unmarshal([]byte(DumpTokens(Tokenize("x: 12 ^ 3\n"))), &tokens); err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+	if got := strings.Join(types, " "); got != "identifier ':' number '^' number newline EOF" {
+		t.Errorf("unexpected token types %s", got)
+	}
+	if tokens[2].Value != "12" || tokens[2].Span.StartColumn != 4 || tokens[2].Span.EndColumn != 6 {
+		t.Errorf("unexpected number token %+v", tokens[2])
+	}
+}
@@ -0,0 +1,95 @@
+package ahoy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EmitFunction builds the program opts.Source names and returns the C its
+// function name became, followed by the runtime helpers that C calls and the
+// tables they read, in the order the runtime has them. The program's other
+// functions are left out. The program is generated but not compiled, and
+// nothing is written to opts.OutputDir.
+func EmitFunction(opts BuildOptions, name string) (string, []Diagnostic, error) {
+	outputDir, err := os.MkdirTemp("", "ahoy-emit")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(outputDir)
+	opts.OutputDir = outputDir
+	opts.Compile = false
+	opts.Report = false
+	opts.SplitRuntime = true
+	opts.RuntimeCache = ""
+
+	artifacts, diagnostics, err := Build(opts)
+	if err != nil {
+		return "", diagnostics, err
+	}
+	runtime, err := os.ReadFile(artifacts.Runtime)
+	if err != nil {
+		return "", diagnostics, fmt.Errorf("reading runtime: %v", err)
+	}
+
+	cName := name
+	if name == "main" {
+		cName = "ahoy_main"
+	}
+	var function *cItem
+	for _, item := range cItems(artifacts.CCode) {
+		if item.kind == cFunction && item.name == cName {
+			function = &item
+			break
+		}
+	}
+	if function == nil {
+		return "", diagnostics, fmt.Errorf("there's no function '%s' in %s", name, opts.Source)
+	}
+
+	// The helpers the function calls, then the ones they call in turn
+	helpers := cItems(string(runtime))
+	used := map[string]bool{}
+	addUses := func(text string) {
+		for _, identifier := range cIdentifier.FindAllString(text, -1) {
+			used[identifier] = true
+		}
+	}
+	addUses(function.text)
+	kept := map[int]bool{}
+	for changed := true; changed; {
+		changed = false
+		for i, item := range helpers {
+			if !kept[i] && (item.kind == cFunction || item.kind == cVariable) && used[definedName(item)] {
+				kept[i] = true
+				addUses(item.text)
+				changed = true
+			}
+		}
+	}
+
+	var code strings.Builder
+	code.WriteString(strings.TrimLeft(function.text, "\n"))
+	for i, item := range helpers {
+		if kept[i] {
+			code.WriteString(item.text)
+		}
+	}
+	return code.String(), diagnostics, nil
+}
+
+// definedName returns the name a function or variable item defines
+func definedName(item cItem) string {
+	if item.kind == cFunction {
+		return item.name
+	}
+	declaration := item.first
+	if end := strings.IndexAny(declaration, "=[;"); end >= 0 {
+		declaration = declaration[:end]
+	}
+	names := cIdentifier.FindAllString(declaration, -1)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[len(names)-1]
+}
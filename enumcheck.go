@@ -0,0 +1,137 @@
+package ahoy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// nonFlagMembers returns the members of a flags enum given a number that
+// isn't a single bit or zero. Constant expressions like read plus write
+// combine flags and aren't checked.
+func nonFlagMembers(node *ASTNode) []*ASTNode {
+	var members []*ASTNode
+	for _, member := range node.Children {
+		if len(member.Children) == 0 || member.Children[0].Type != NODE_NUMBER {
+			continue
+		}
+		val, err := strconv.ParseInt(member.Children[0].Value, 10, 64)
+		if err != nil || val < 0 || val&(val-1) != 0 {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// nonFlagMessage describes a member nonFlagMembers returned
+func nonFlagMessage(member *ASTNode) string {
+	return fmt.Sprintf("Flags enum member '%s' must be a power of two, got %s", member.Value, member.Children[0].Value)
+}
+
+// checkEnumValues reports members of an int or flags enum that share a
+// value, which a switch on the enum couldn't tell apart, and negative flags.
+// Members of an int enum numbered lower than the one before them are warned
+// about, since the members after them can run into later values; flags
+// enums are left alone, as their combined masks follow the single flags.
+func (gen *CodeGenerator) checkEnumValues(node *ASTNode, flags bool) {
+	enumName := node.Value
+	taken := map[int64]string{}
+	var previous *ASTNode
+	var previousValue int64
+	for _, member := range node.Children {
+		value, ok := gen.constValues[enumName+"."+member.Value]
+		if !ok {
+			return
+		}
+		switch other, duplicate := taken[value]; {
+		case duplicate:
+			gen.reportError(member.Line, fmt.Sprintf("enum %s: %s is %d, the same as %s", enumName, member.Value, value, other),
+				"a switch on "+enumName+" couldn't tell them apart; give each member its own value")
+		case flags && value < 0:
+			gen.reportError(member.Line, fmt.Sprintf("Flags enum member '%s' can't be negative, got %d", member.Value, value))
+		case !flags && previous != nil && value < previousValue:
+			gen.reportWarning(member.Line, fmt.Sprintf("enum %s: %s is %d, lower than %s (%d) before it; list members in increasing order",
+				enumName, member.Value, value, previous.Value, previousValue))
+		}
+		taken[value] = member.Value
+		previous, previousValue = member, value
+	}
+}
+
+// checkSwitchCases reports a switch whose cases repeat a value, which C
+// would reject as a duplicate case label. Bare member names are looked up
+// in enumName, the enum of the switch subject.
+func (gen *CodeGenerator) checkSwitchCases(node *ASTNode, enumName string) {
+	type switchCase struct {
+		label string
+		line  int
+	}
+	seen := map[int64]switchCase{}
+	for _, caseNode := range node.Children[1:] {
+		if caseNode.Type != NODE_SWITCH_CASE {
+			continue
+		}
+		values := []*ASTNode{caseNode.Children[0]}
+		if caseNode.Children[0].Type == NODE_SWITCH_CASE_LIST {
+			values = caseNode.Children[0].Children
+		}
+		for _, val := range values {
+			value, ok := gen.switchCaseValue(val, enumName)
+			if !ok {
+				continue
+			}
+			label := val.Value
+			if val.Type != NODE_IDENTIFIER {
+				label = gen.nodeToString(val)
+			}
+			if first, repeated := seen[value]; repeated {
+				gen.reportError(caseNode.Line, fmt.Sprintf("switch case %s is %d, the same as case %s on line %d", label, value, first.label, first.line))
+				continue
+			}
+			seen[value] = switchCase{label, caseNode.Line}
+		}
+	}
+}
+
+// checkStringSwitchCases reports a string or char switch whose cases repeat
+// a literal. The switch tests its cases in turn, so the repeat could never
+// match.
+func (gen *CodeGenerator) checkStringSwitchCases(node *ASTNode) {
+	seen := map[string]int{}
+	for _, caseNode := range node.Children[1:] {
+		if caseNode.Type != NODE_SWITCH_CASE {
+			continue
+		}
+		values := []*ASTNode{caseNode.Children[0]}
+		if caseNode.Children[0].Type == NODE_SWITCH_CASE_LIST {
+			values = caseNode.Children[0].Children
+		}
+		for _, val := range values {
+			if val.Type != NODE_STRING && val.Type != NODE_CHAR {
+				continue
+			}
+			label := "\"" + val.Value + "\""
+			if val.Type == NODE_CHAR {
+				label = "'" + val.Value + "'"
+			}
+			key := fmt.Sprintf("%d:%s", val.Type, unescapeC(val.Value))
+			if line, repeated := seen[key]; repeated {
+				gen.reportError(caseNode.Line, fmt.Sprintf("switch case %s repeats the case on line %d", label, line))
+				continue
+			}
+			seen[key] = caseNode.Line
+		}
+	}
+}
+
+// switchCaseValue returns the int a case label stands for, when it is a
+// constant
+func (gen *CodeGenerator) switchCaseValue(val *ASTNode, enumName string) (int64, bool) {
+	if isDefaultCase(val) || gen.isRangeCase(val) {
+		return 0, false
+	}
+	if enumName != "" && val.Type == NODE_IDENTIFIER && gen.enums[enumName][val.Value] {
+		value, ok := gen.constValues[enumName+"."+val.Value]
+		return value, ok
+	}
+	return gen.evalConstInt(val, nil)
+}
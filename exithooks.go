@@ -0,0 +1,70 @@
+package ahoy
+
+import "fmt"
+
+// maxExitHooks is how many functions on_exit can register
+const maxExitHooks = 64
+
+// generateOnExit writes on_exit|fn|, which registers fn to run when the
+// program exits, returns from main or is stopped with SIGINT or SIGTERM
+func (gen *CodeGenerator) generateOnExit(node *ASTNode) {
+	if len(node.Children) != 1 || node.Children[0].Type != NODE_IDENTIFIER || !gen.userFunctions[node.Children[0].Value] {
+		gen.reportError(node.Line, "on_exit needs the name of the function to run when the program exits",
+			"Define it with @ name || void: ... $ and pass it without calling it")
+		return
+	}
+	hook := node.Children[0].Value
+	if params := gen.functionParamTypes[hook]; len(params) > 0 {
+		gen.reportError(node.Line, "'"+hook+"' is run by on_exit and can't take parameters")
+		return
+	}
+	if len(gen.functionReturnTypes[hook]) > 0 {
+		gen.reportError(node.Line, "'"+hook+"' is run by on_exit and can't return a value")
+		return
+	}
+	gen.useExitHooks = true
+	gen.addInclude("signal.h")
+
+	gen.output.WriteString("ahoy_on_exit(")
+	gen.generateNode(node.Children[0])
+	gen.output.WriteString(")")
+}
+
+// writeExitHookFunctions generates the hooks on_exit registers. They run
+// once each, the last registered first like defer, from atexit or from the
+// SIGINT and SIGTERM handlers, which then exit as a shell reports the signal.
+func (gen *CodeGenerator) writeExitHookFunctions() {
+	if !gen.useExitHooks {
+		return
+	}
+	gen.funcDecls.WriteString("\n// on_exit hooks\n")
+	gen.funcReturnStructs.WriteString("void ahoy_on_exit(void (*hook)(void));\n")
+	gen.funcDecls.WriteString(fmt.Sprintf("#define AHOY_MAX_EXIT_HOOKS %d\n", maxExitHooks))
+	gen.funcDecls.WriteString("static void (*ahoy_exit_hooks[AHOY_MAX_EXIT_HOOKS])(void);\n")
+	gen.funcDecls.WriteString("static int ahoy_exit_hook_count = 0;\n\n")
+	gen.funcDecls.WriteString("static void ahoy_run_exit_hooks(void) {\n")
+	gen.funcDecls.WriteString("    // Taken off before running, so a hook that exits isn't run again\n")
+	gen.funcDecls.WriteString("    while (ahoy_exit_hook_count > 0) {\n")
+	gen.funcDecls.WriteString("        ahoy_exit_hooks[--ahoy_exit_hook_count]();\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    fflush(stdout);\n")
+	gen.funcDecls.WriteString("}\n\n")
+	gen.funcDecls.WriteString("static void ahoy_exit_signal(int sig) {\n")
+	gen.funcDecls.WriteString("    signal(sig, SIG_DFL);\n")
+	gen.funcDecls.WriteString("    exit(128 + sig);\n")
+	gen.funcDecls.WriteString("}\n\n")
+	gen.funcDecls.WriteString("void ahoy_on_exit(void (*hook)(void)) {\n")
+	gen.funcDecls.WriteString("    static int installed = 0;\n")
+	gen.funcDecls.WriteString("    if (!installed) {\n")
+	gen.funcDecls.WriteString("        installed = 1;\n")
+	gen.funcDecls.WriteString("        atexit(ahoy_run_exit_hooks);\n")
+	gen.funcDecls.WriteString("        signal(SIGINT, ahoy_exit_signal);\n")
+	gen.funcDecls.WriteString("        signal(SIGTERM, ahoy_exit_signal);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    if (ahoy_exit_hook_count == AHOY_MAX_EXIT_HOOKS) {\n")
+	gen.funcDecls.WriteString("        fprintf(stderr, \"on_exit: more than %d functions registered\\n\", AHOY_MAX_EXIT_HOOKS);\n")
+	gen.funcDecls.WriteString("        exit(1);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    ahoy_exit_hooks[ahoy_exit_hook_count++] = hook;\n")
+	gen.funcDecls.WriteString("}\n")
+}
@@ -0,0 +1,511 @@
+package ahoy
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// exportVisibility marks the functions a library exports; the rest of its
+// C is compiled hidden
+const exportVisibility = `__attribute__((visibility("default"))) `
+
+// exportedFunction is an @export function of a library, with the Ahoy types
+// of its parameters and results
+type exportedFunction struct {
+	name       string
+	paramNames []string
+	params     []string
+	results    []string
+}
+
+// bindingLanguages are the languages BuildOptions.Bindings takes
+var bindingLanguages = []string{"go", "python"}
+
+// checkBindings reports bindings a build can't write
+func checkBindings(opts BuildOptions) error {
+	if len(opts.Bindings) > 0 && !opts.Library {
+		return fmt.Errorf("bindings are written for libraries; build with Library set")
+	}
+	for _, language := range opts.Bindings {
+		if !slices.Contains(bindingLanguages, language) {
+			return fmt.Errorf("no bindings for %q; want %s", language, strings.Join(bindingLanguages, " or "))
+		}
+	}
+	return nil
+}
+
+// writeLibraryFiles writes a library's header to outputDir, beside the
+// library, with the bindings asked for. Go bindings are a package in a
+// directory of their own, as cgo would build the library's C file too.
+func writeLibraryFiles(artifacts *Artifacts, program cProgram, outputDir string, name string, bindings []string) error {
+	artifacts.Header = filepath.Join(outputDir, name+".h")
+	if err := os.WriteFile(artifacts.Header, []byte(program.libraryHeader), 0644); err != nil {
+		return fmt.Errorf("writing library header: %v", err)
+	}
+	for _, language := range bindings {
+		path := filepath.Join(outputDir, name+".py")
+		code := program.pythonBindings
+		if language == "go" {
+			path = filepath.Join(outputDir, name, name+".go")
+			code = program.goBindings
+			os.MkdirAll(filepath.Dir(path), 0755)
+		}
+		if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+			return fmt.Errorf("writing %s bindings: %v", language, err)
+		}
+		artifacts.Bindings = append(artifacts.Bindings, path)
+	}
+	return nil
+}
+
+// libraryName turns a source file's base name into the name of the library
+// built from it, which prefixes its init function and names its bindings
+func libraryName(baseName string) string {
+	name := []byte(baseName)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || name[0] >= '0' && name[0] <= '9' {
+		return "lib_" + string(name)
+	}
+	return string(name)
+}
+
+// collectExports records the @export functions of a library, reporting the
+// ones whose parameters or results C callers couldn't use. Only ints,
+// floats, bools, strings and structs of them cross the library's boundary.
+func (gen *CodeGenerator) collectExports(ast *ASTNode) {
+	for _, node := range ast.Children {
+		if node.Type != NODE_FUNCTION || node.Tag != "export" {
+			continue
+		}
+		if node.Value == "main" {
+			gen.reportError(node.Line, "main can't be exported", fmt.Sprintf("a library runs its top-level statements from %s_init instead", gen.library))
+			continue
+		}
+		export := &exportedFunction{name: node.Value, results: gen.functionReturnTypes[node.Value]}
+		for _, param := range node.Children[0].Children {
+			export.paramNames = append(export.paramNames, param.Value)
+			export.params = append(export.params, param.DataType)
+		}
+		valid := true
+		for i, paramType := range export.params {
+			if !gen.exportableType(paramType) {
+				gen.reportError(node.Line, fmt.Sprintf("exported function %s can't take %s as %s", node.Value, describeExportType(paramType), export.paramNames[i]),
+					"exported functions take and return ints, floats, bools, strings and structs of them")
+				valid = false
+			}
+		}
+		for _, result := range export.results {
+			if result != "void" && !gen.exportableType(result) {
+				gen.reportError(node.Line, fmt.Sprintf("exported function %s can't give back %s", node.Value, describeExportType(result)),
+					"exported functions take and return ints, floats, bools, strings and structs of them")
+				valid = false
+			}
+		}
+		if valid {
+			if len(export.results) == 1 && export.results[0] == "void" {
+				export.results = nil
+			}
+			gen.exports = append(gen.exports, export)
+		}
+	}
+	if len(gen.exports) == 0 && !gen.hasError {
+		gen.reportError(1, "a library needs at least one @export function", "@export add |a:int, b:int| int: makes add part of the library")
+	}
+}
+
+func describeExportType(langType string) string {
+	if langType == "" {
+		return "an untyped value"
+	}
+	return "a " + langType
+}
+
+// exportableType reports whether values of an Ahoy type can be passed to
+// and from C callers as they are
+func (gen *CodeGenerator) exportableType(langType string) bool {
+	switch langType {
+	case "int", "float", "bool", "string":
+		return true
+	}
+	info, isStruct := gen.structs[langType]
+	if !isStruct || gen.jsonStructs[langType] {
+		return false
+	}
+	if _, builtin := builtinStructs[langType]; builtin {
+		return false
+	}
+	for _, field := range info.Fields {
+		if !gen.exportableCType(field.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// exportableCType is exportableType for the C types struct fields hold
+func (gen *CodeGenerator) exportableCType(cType string) bool {
+	switch cType {
+	case "int", "double", "bool", "char*":
+		return true
+	}
+	info, isStruct := gen.structs[cType]
+	return isStruct && gen.exportableType(info.Name)
+}
+
+// exportedStructs returns the structs the exports use, each after the
+// structs its fields hold
+func (gen *CodeGenerator) exportedStructs() []*StructInfo {
+	var ordered []*StructInfo
+	seen := map[*StructInfo]bool{}
+	var visit func(info *StructInfo)
+	visit = func(info *StructInfo) {
+		if seen[info] {
+			return
+		}
+		seen[info] = true
+		for _, field := range info.Fields {
+			if nested, isStruct := gen.structs[field.Type]; isStruct {
+				visit(nested)
+			}
+		}
+		ordered = append(ordered, info)
+	}
+	for _, export := range gen.exports {
+		for _, langType := range append(append([]string{}, export.params...), export.results...) {
+			if info, isStruct := gen.structs[langType]; isStruct {
+				visit(info)
+			}
+		}
+	}
+	return ordered
+}
+
+// returnCType returns the C type an export returns
+func (gen *CodeGenerator) returnCType(export *exportedFunction) string {
+	switch len(export.results) {
+	case 0:
+		return "void"
+	case 1:
+		return gen.mapType(export.results[0])
+	}
+	return export.name + "_return"
+}
+
+// libraryHeader returns the C header of a library: the structs its exports
+// use, and the prototypes of its init function and exports
+func (gen *CodeGenerator) libraryHeader() string {
+	guard := strings.ToUpper(gen.library) + "_H"
+	var h strings.Builder
+	fmt.Fprintf(&h, "// %s.h: the C API of the Ahoy library lib%s. Call %s_init once\n", gen.library, gen.library, gen.library)
+	h.WriteString("// before the other functions; it runs the library's top-level statements.\n")
+	fmt.Fprintf(&h, "#ifndef %s\n#define %s\n\n#include <stdbool.h>\n\n", guard, guard)
+	h.WriteString("#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	for _, info := range gen.exportedStructs() {
+		h.WriteString("typedef struct {\n")
+		for _, field := range info.Fields {
+			fmt.Fprintf(&h, "    %s %s;\n", field.Type, field.Name)
+		}
+		fmt.Fprintf(&h, "} %s;\n\n", gen.mapType(info.Name))
+	}
+	for _, export := range gen.exports {
+		if len(export.results) < 2 {
+			continue
+		}
+		h.WriteString("typedef struct {\n")
+		for i, result := range export.results {
+			fmt.Fprintf(&h, "    %s ret%d;\n", gen.mapType(result), i)
+		}
+		fmt.Fprintf(&h, "} %s_return;\n\n", export.name)
+	}
+	fmt.Fprintf(&h, "void %s_init(void);\n", gen.library)
+	for _, export := range gen.exports {
+		params := make([]string, len(export.params))
+		for i, paramType := range export.params {
+			params[i] = gen.mapType(paramType) + " " + export.paramNames[i]
+		}
+		if len(params) == 0 {
+			params = []string{"void"}
+		}
+		fmt.Fprintf(&h, "%s %s(%s);\n", gen.returnCType(export), export.name, strings.Join(params, ", "))
+	}
+	h.WriteString("\n#ifdef __cplusplus\n}\n#endif\n\n")
+	fmt.Fprintf(&h, "#endif // %s\n", guard)
+	return h.String()
+}
+
+// goName turns an Ahoy name into an exported Go name: point_count becomes
+// PointCount
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// goType returns the Go type a binding uses for an Ahoy type
+func (gen *CodeGenerator) goType(langType string) string {
+	switch langType {
+	case "int":
+		return "int"
+	case "float":
+		return "float64"
+	case "bool":
+		return "bool"
+	case "string":
+		return "string"
+	}
+	return goName(gen.structs[langType].Name)
+}
+
+// goCType returns the Go type a binding uses for a struct field's C type
+func (gen *CodeGenerator) goCType(cType string) string {
+	switch cType {
+	case "double":
+		return "float64"
+	case "char*":
+		return "string"
+	case "int", "bool":
+		return cType
+	}
+	return goName(gen.structs[cType].Name)
+}
+
+// goToC returns the Go expression converting value to C. Strings are copied
+// into C memory that free releases.
+func (gen *CodeGenerator) goToC(value string, goType string) string {
+	switch goType {
+	case "int":
+		return fmt.Sprintf("C.int(%s)", value)
+	case "float64":
+		return fmt.Sprintf("C.double(%s)", value)
+	case "bool":
+		return fmt.Sprintf("C.bool(%s)", value)
+	case "string":
+		return fmt.Sprintf("keep(C.CString(%s))", value)
+	}
+	return fmt.Sprintf("%s.toC(keep)", value)
+}
+
+// goFromC returns the Go expression converting a C value to goType
+func (gen *CodeGenerator) goFromC(value string, goType string) string {
+	switch goType {
+	case "int", "float64", "bool":
+		return fmt.Sprintf("%s(%s)", goType, value)
+	case "string":
+		return fmt.Sprintf("C.GoString(%s)", value)
+	}
+	return fmt.Sprintf("%sFromC(%s)", lowerFirst(goType), value)
+}
+
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// goBindings returns a cgo package calling the library, with Go structs
+// and functions for the exports
+func (gen *CodeGenerator) goBindings() string {
+	var g strings.Builder
+	fmt.Fprintf(&g, "// Package %s calls the Ahoy library lib%s through cgo. Init runs the\n", gen.library, gen.library)
+	g.WriteString("// library's top-level statements and must be called before the other functions.\n")
+	fmt.Fprintf(&g, "package %s\n\n", gen.library)
+	fmt.Fprintf(&g, "/*\n#cgo CFLAGS: -I${SRCDIR}/..\n#cgo LDFLAGS: -L${SRCDIR}/.. -l%s -Wl,-rpath,${SRCDIR}/..\n#include <stdlib.h>\n#include \"%s.h\"\n*/\nimport \"C\"\n\n", gen.library, gen.library)
+	g.WriteString("import \"unsafe\"\n\n")
+	g.WriteString("// cStrings collects the C copies of a call's string arguments to free after it\n")
+	g.WriteString("type cStrings []*C.char\n\n")
+	g.WriteString("func (s *cStrings) keep(c *C.char) *C.char {\n\t*s = append(*s, c)\n\treturn c\n}\n\n")
+	g.WriteString("func (s cStrings) free() {\n\tfor _, c := range s {\n\t\tC.free(unsafe.Pointer(c))\n\t}\n}\n\n")
+
+	for _, info := range gen.exportedStructs() {
+		name := goName(info.Name)
+		cName := gen.mapType(info.Name)
+		fmt.Fprintf(&g, "type %s struct {\n", name)
+		for _, field := range info.Fields {
+			fmt.Fprintf(&g, "\t%s %s\n", goName(field.Name), gen.goCType(field.Type))
+		}
+		g.WriteString("}\n\n")
+		fmt.Fprintf(&g, "func (v %s) toC(keep func(*C.char) *C.char) C.%s {\n\treturn C.%s{\n", name, cName, cName)
+		for _, field := range info.Fields {
+			fmt.Fprintf(&g, "\t\t%s: %s,\n", field.Name, gen.goToC("v."+goName(field.Name), gen.goCType(field.Type)))
+		}
+		g.WriteString("\t}\n}\n\n")
+		fmt.Fprintf(&g, "func %sFromC(c C.%s) %s {\n\treturn %s{\n", lowerFirst(name), cName, name, name)
+		for _, field := range info.Fields {
+			fmt.Fprintf(&g, "\t\t%s: %s,\n", goName(field.Name), gen.goFromC("c."+field.Name, gen.goCType(field.Type)))
+		}
+		g.WriteString("\t}\n}\n\n")
+	}
+
+	fmt.Fprintf(&g, "// Init runs the library's top-level statements\nfunc Init() {\n\tC.%s_init()\n}\n", gen.library)
+	for _, export := range gen.exports {
+		params := make([]string, len(export.params))
+		args := make([]string, len(export.params))
+		for i, paramType := range export.params {
+			params[i] = goParam(export.paramNames[i]) + " " + gen.goType(paramType)
+			args[i] = gen.goToC(goParam(export.paramNames[i]), gen.goType(paramType))
+		}
+		results := make([]string, len(export.results))
+		for i, result := range export.results {
+			results[i] = gen.goType(result)
+		}
+		signature := fmt.Sprintf("func %s(%s)", goName(export.name), strings.Join(params, ", "))
+		switch len(results) {
+		case 0:
+		case 1:
+			signature += " " + results[0]
+		default:
+			signature += " (" + strings.Join(results, ", ") + ")"
+		}
+		fmt.Fprintf(&g, "\n%s {\n", signature)
+		for _, paramType := range export.params {
+			if paramType != "int" && paramType != "float" && paramType != "bool" {
+				g.WriteString("\tvar strs cStrings\n\tdefer strs.free()\n\tkeep := strs.keep\n")
+				break
+			}
+		}
+		call := fmt.Sprintf("C.%s(%s)", export.name, strings.Join(args, ", "))
+		switch len(results) {
+		case 0:
+			fmt.Fprintf(&g, "\t%s\n", call)
+		case 1:
+			fmt.Fprintf(&g, "\treturn %s\n", gen.goFromC(call, results[0]))
+		default:
+			fmt.Fprintf(&g, "\tresult := %s\n", call)
+			values := make([]string, len(results))
+			for i, result := range results {
+				values[i] = gen.goFromC(fmt.Sprintf("result.ret%d", i), result)
+			}
+			fmt.Fprintf(&g, "\treturn %s\n", strings.Join(values, ", "))
+		}
+		g.WriteString("}\n")
+	}
+	// gofmt lines up the struct fields
+	formatted, err := format.Source([]byte(g.String()))
+	if err != nil {
+		return g.String()
+	}
+	return string(formatted)
+}
+
+// goParam returns the name a wrapper's parameter has in Go, moved aside
+// when it's a Go keyword or a name the wrapper uses itself
+func goParam(name string) string {
+	switch name {
+	case "strs", "keep", "result", "C", "unsafe":
+		return name + "_"
+	}
+	if token.IsKeyword(name) {
+		return name + "_"
+	}
+	return name
+}
+
+// pythonCType returns the ctypes type of an Ahoy type or a struct field's
+// C type
+func (gen *CodeGenerator) pythonCType(langType string) string {
+	switch langType {
+	case "int":
+		return "ctypes.c_int"
+	case "float", "double":
+		return "ctypes.c_double"
+	case "bool":
+		return "ctypes.c_bool"
+	case "string", "char*":
+		return "ctypes.c_char_p"
+	}
+	return goName(gen.structs[langType].Name)
+}
+
+// pythonBindings returns a ctypes module calling the library, with a
+// Structure for each struct and a function for each export. Strings go in
+// and come back as str.
+func (gen *CodeGenerator) pythonBindings() string {
+	var p strings.Builder
+	fmt.Fprintf(&p, "\"\"\"Calls the Ahoy library lib%s through ctypes. init() runs the library's\n", gen.library)
+	p.WriteString("top-level statements and must be called before the other functions.\"\"\"\n\n")
+	p.WriteString("import ctypes\nimport os\n\n")
+	fmt.Fprintf(&p, "_lib = ctypes.CDLL(os.path.join(os.path.dirname(os.path.abspath(__file__)), \"lib%s.so\"))\n\n\n", gen.library)
+
+	for _, info := range gen.exportedStructs() {
+		fmt.Fprintf(&p, "class %s(ctypes.Structure):\n    _fields_ = [\n", goName(info.Name))
+		for _, field := range info.Fields {
+			fmt.Fprintf(&p, "        (\"%s\", %s),\n", field.Name, gen.pythonCType(field.Type))
+		}
+		p.WriteString("    ]\n\n\n")
+	}
+	for _, export := range gen.exports {
+		if len(export.results) < 2 {
+			continue
+		}
+		fmt.Fprintf(&p, "class _%sReturn(ctypes.Structure):\n    _fields_ = [\n", goName(export.name))
+		for i, result := range export.results {
+			fmt.Fprintf(&p, "        (\"ret%d\", %s),\n", i, gen.pythonCType(result))
+		}
+		p.WriteString("    ]\n\n\n")
+	}
+
+	fmt.Fprintf(&p, "_lib.%s_init.argtypes = []\n_lib.%s_init.restype = None\n", gen.library, gen.library)
+	for _, export := range gen.exports {
+		argtypes := make([]string, len(export.params))
+		for i, paramType := range export.params {
+			argtypes[i] = gen.pythonCType(paramType)
+		}
+		restype := "None"
+		switch len(export.results) {
+		case 1:
+			restype = gen.pythonCType(export.results[0])
+		case 0:
+		default:
+			restype = fmt.Sprintf("_%sReturn", goName(export.name))
+		}
+		fmt.Fprintf(&p, "_lib.%s.argtypes = [%s]\n_lib.%s.restype = %s\n", export.name, strings.Join(argtypes, ", "), export.name, restype)
+	}
+
+	fmt.Fprintf(&p, "\n\ndef init():\n    _lib.%s_init()\n", gen.library)
+	for _, export := range gen.exports {
+		args := make([]string, len(export.params))
+		for i, paramType := range export.params {
+			args[i] = export.paramNames[i]
+			if paramType == "string" {
+				args[i] += ".encode()"
+			}
+		}
+		call := fmt.Sprintf("_lib.%s(%s)", export.name, strings.Join(args, ", "))
+		fmt.Fprintf(&p, "\n\ndef %s(%s):\n", export.name, strings.Join(export.paramNames, ", "))
+		switch len(export.results) {
+		case 0:
+			fmt.Fprintf(&p, "    %s\n", call)
+		case 1:
+			fmt.Fprintf(&p, "    return %s\n", pythonFromC(call, export.results[0]))
+		default:
+			fmt.Fprintf(&p, "    result = %s\n", call)
+			values := make([]string, len(export.results))
+			for i, result := range export.results {
+				values[i] = pythonFromC(fmt.Sprintf("result.ret%d", i), result)
+			}
+			fmt.Fprintf(&p, "    return %s\n", strings.Join(values, ", "))
+		}
+	}
+	return p.String()
+}
+
+func pythonFromC(value string, langType string) string {
+	if langType == "string" {
+		return value + ".decode()"
+	}
+	return value
+}
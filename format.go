@@ -0,0 +1,216 @@
+package ahoy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// generateFormat generates format|...|, which formats its arguments the way
+// print does, without the newline print adds, into a new string sized to
+// fit
+func (gen *CodeGenerator) generateFormat(node *ASTNode) {
+	if len(node.Children) == 0 {
+		gen.reportError(node.Line, "format needs a format string or values to format")
+		return
+	}
+	gen.markFormatUsed()
+	gen.output.WriteString("ahoy_format(" + gen.formatArguments(node))
+}
+
+// formatArguments is printArguments without the newline print adds
+func (gen *CodeGenerator) formatArguments(node *ASTNode) string {
+	arguments := gen.printArguments(node)
+	// print ends its format with a newline unless the string already did
+	first := node.Children[0]
+	if first.Type != NODE_STRING || !strings.HasSuffix(first.Value, "\\n") {
+		if end := cStringLiteralEnd(arguments); end > 0 && strings.HasSuffix(arguments[:end], "\\n") {
+			arguments = arguments[:end-2] + arguments[end:]
+		}
+	}
+	return arguments
+}
+
+// generateRawPrint generates print_raw|...|, which writes its arguments
+// back to back, and println|...|, which separates them with spaces and ends
+// the line. Unlike print, neither treats a string as a format: strings are
+// written as they are and other values the way print shows them.
+func (gen *CodeGenerator) generateRawPrint(node *ASTNode) {
+	separator := ""
+	if node.Value == "println" {
+		separator = " "
+	}
+	gen.output.WriteString("({ ")
+	for i, arg := range node.Children {
+		if i > 0 && separator != "" {
+			gen.output.WriteString(fmt.Sprintf("fputs(\"%s\", stdout); ", separator))
+		}
+		switch gen.inferType(arg) {
+		case "string", "char*", "const char*":
+			gen.output.WriteString("fputs(")
+			gen.generateNode(arg)
+			gen.output.WriteString(", stdout); ")
+		default:
+			value := &ASTNode{Type: NODE_CALL, Value: "print", Line: node.Line, Children: []*ASTNode{arg}}
+			gen.output.WriteString("printf(" + gen.formatArguments(value) + "; ")
+		}
+	}
+	if node.Value == "println" {
+		gen.output.WriteString("fputs(\"\\n\", stdout); ")
+	}
+	gen.output.WriteString("})")
+}
+
+// cStringLiteralEnd returns the index of the quote that closes the C string
+// literal code starts with, or -1 when it doesn't start with one
+func cStringLiteralEnd(code string) int {
+	if !strings.HasPrefix(code, "\"") {
+		return -1
+	}
+	for i := 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func (gen *CodeGenerator) markFormatUsed() {
+	if gen.useFormat {
+		return
+	}
+	gen.useFormat = true
+	gen.addInclude("stdarg.h")
+}
+
+// writeFormatHelperFunctions generates the printf into a new string behind
+// format and sprintf
+func (gen *CodeGenerator) writeFormatHelperFunctions() {
+	if !gen.useFormat {
+		return
+	}
+	gen.funcReturnStructs.WriteString("char* ahoy_format(const char* format, ...) __attribute__((format(printf, 1, 2)));\n\n")
+
+	gen.funcDecls.WriteString("\n// String formatting\n")
+	gen.funcDecls.WriteString("char* ahoy_format(const char* format, ...) {\n")
+	gen.funcDecls.WriteString("    va_list args;\n")
+	gen.funcDecls.WriteString("    va_start(args, format);\n")
+	gen.funcDecls.WriteString("    int length = vsnprintf(NULL, 0, format, args);\n")
+	gen.funcDecls.WriteString("    va_end(args);\n")
+	gen.funcDecls.WriteString("    char* result = malloc(length + 1);\n")
+	gen.funcDecls.WriteString("    va_start(args, format);\n")
+	gen.funcDecls.WriteString("    vsnprintf(result, length + 1, format, args);\n")
+	gen.funcDecls.WriteString("    va_end(args);\n")
+	gen.funcDecls.WriteString("    return result;\n")
+	gen.funcDecls.WriteString("}\n")
+}
+
+// placeholderSpec is what follows the colon of a {:spec} or {name:spec}
+// placeholder: a comma groups the digits in thousands and .N sets how many
+// come after the point
+type placeholderSpec struct {
+	thousands bool
+	precision int // -1 when not given
+}
+
+var placeholderSpecPattern = regexp.MustCompile(`^(,)?(?:\.(\d+))?$`)
+
+// parsePlaceholderSpec reads the spec of a placeholder, reporting false
+// when spec isn't one
+func parsePlaceholderSpec(spec string) (placeholderSpec, bool) {
+	match := placeholderSpecPattern.FindStringSubmatch(spec)
+	if spec == "" || match == nil {
+		return placeholderSpec{}, false
+	}
+	result := placeholderSpec{thousands: match[1] != "", precision: -1}
+	if match[2] != "" {
+		result.precision, _ = strconv.Atoi(match[2])
+	}
+	return result, true
+}
+
+// hasFormatPlaceholders reports whether a string print is given has
+// placeholders for the values that follow it
+func hasFormatPlaceholders(format string) bool {
+	if strings.Contains(format, "{}") || strings.Contains(format, "%") {
+		return true
+	}
+	for start := strings.Index(format, "{:"); start >= 0; {
+		end := strings.IndexByte(format[start:], '}')
+		if end < 0 {
+			return false
+		}
+		if _, ok := parsePlaceholderSpec(format[start+2 : start+end]); ok {
+			return true
+		}
+		next := strings.Index(format[start+1:], "{:")
+		if next < 0 {
+			return false
+		}
+		start += 1 + next
+	}
+	return false
+}
+
+// specFormat returns the C format and the argument for a value shown with a
+// placeholder spec. Grouped numbers are formatted by ahoy_thousands; a
+// precision on an int shows it as a float, and on a string cuts it short.
+func (gen *CodeGenerator) specFormat(spec placeholderSpec, value *ASTNode) (string, *ASTNode) {
+	valueType := gen.inferType(value)
+	number := valueType == "int" || valueType == "float" || valueType == "double"
+	if spec.thousands {
+		if !number {
+			gen.reportError(value.Line, fmt.Sprintf("the ',' placeholder spec groups the digits of a number, not a %s", valueType))
+		}
+		gen.useThousands = true
+		precision := &ASTNode{Type: NODE_NUMBER, Value: strconv.Itoa(spec.precision), Line: value.Line}
+		if spec.precision < 0 && valueType == "int" {
+			precision.Value = "0"
+		}
+		return "%s", &ASTNode{Type: NODE_CALL, Value: "__ahoy_thousands", Line: value.Line, Children: []*ASTNode{value, precision}}
+	}
+	switch {
+	case valueType == "int":
+		return fmt.Sprintf("%%.%df", spec.precision), &ASTNode{Type: NODE_CALL, Value: "__ahoy_double", Line: value.Line, Children: []*ASTNode{value}}
+	case number:
+		return fmt.Sprintf("%%.%df", spec.precision), value
+	default:
+		return fmt.Sprintf("%%.%ds", spec.precision), value
+	}
+}
+
+// writeThousandsHelperFunctions generates ahoy_thousands, which formats a
+// number with a comma between each group of three digits before the point.
+// A precision below zero shows the number the shortest exact way.
+func (gen *CodeGenerator) writeThousandsHelperFunctions() {
+	if !gen.useThousands {
+		return
+	}
+	gen.funcReturnStructs.WriteString("char* ahoy_thousands(double value, int precision);\n\n")
+
+	gen.funcDecls.WriteString("\n// Thousands separators\n")
+	gen.funcDecls.WriteString(`char* ahoy_thousands(double value, int precision) {
+    char digits[512];
+    if (precision < 0) {
+        snprintf(digits, sizeof(digits), "%.15g", value);
+    } else {
+        snprintf(digits, sizeof(digits), "%.*f", precision, value);
+    }
+    const char* start = digits[0] == '-' ? digits + 1 : digits;
+    size_t whole = strspn(start, "0123456789");
+    char* result = malloc(strlen(digits) + whole / 3 + 1);
+    char* out = result;
+    if (start != digits) *out++ = '-';
+    for (size_t i = 0; i < whole; i++) {
+        if (i > 0 && (whole - i) % 3 == 0) *out++ = ',';
+        *out++ = start[i];
+    }
+    strcpy(out, start + whole);
+    return result;
+}
+`)
+}
@@ -1,4 +1,4 @@
-package main
+package ahoy
 
 import (
 	"regexp"
@@ -7,6 +7,10 @@ import (
 
 const INDENT_SIZE = 2
 
+// MAX_LINE_WIDTH is how long a line can get before the literal on it is laid
+// out one entry per line
+const MAX_LINE_WIDTH = 100
+
 // formatSource formats Ahoy source code with proper indentation
 func formatSource(source string) string {
 	// First, preprocess to split lines with $ at the end
@@ -16,8 +20,18 @@ func formatSource(source string) string {
 	var formatted []string
 	indentLevel := 0
 	structStack := []int{} // Stack to track struct indent levels
+	inRawString := false
+	open := 0        // brackets and call pipes left open by the lines so far
+	inPipes := false // whether one of them is the pipe of an argument list
 
 	for _, line := range lines {
+		// Lines inside a raw string are part of its text
+		if inRawString {
+			formatted = append(formatted, line)
+			inRawString = rawStringOpen(line, true)
+			continue
+		}
+
 		// Convert tabs to spaces initially for processing
 		line = strings.ReplaceAll(line, "\t", "    ")
 
@@ -32,6 +46,18 @@ func formatSource(source string) string {
 			continue
 		}
 
+		// A line inside a literal or argument list spanning lines is indented
+		// one level for each bracket still open, less the ones it closes
+		if open > 0 {
+			if !strings.HasPrefix(trimmed, "?") {
+				trimmed = formatLine(trimmed)
+			}
+			depth := indentLevel + open - leadingClosers(trimmed, open, inPipes)
+			formatted = append(formatted, strings.Repeat(" ", max(depth, 0)*INDENT_SIZE)+trimmed)
+			open, inPipes = bracketBalance(trimmed, open, inPipes)
+			continue
+		}
+
 		// Check if this line should decrease indent ($, ⚓, else, elseif)
 		shouldDedentBefore := false
 		if trimmed == "$" || trimmed == "⚓" || strings.HasPrefix(trimmed, "else ") ||
@@ -62,8 +88,13 @@ func formatSource(source string) string {
 		// Check if this is a single-line construct (should NOT be indented on next line)
 		isSingleLine := isSingleLineConstruct(trimmed)
 
-		// Format the line content (spacing, etc.)
-		trimmed = formatLine(trimmed)
+		// Format the line content (spacing, etc.), except for one opening a
+		// raw string, whose text runs on
+		if rawStringOpen(trimmed, false) {
+			inRawString = true
+		} else {
+			trimmed = formatLine(trimmed)
+		}
 
 		// Apply current indentation
 		// Use spaces (2 per level) or tab (for level 1 only in some cases)
@@ -72,8 +103,17 @@ func formatSource(source string) string {
 			// Use 2 spaces per indent level
 			indent = strings.Repeat(" ", indentLevel*INDENT_SIZE)
 		}
-		formattedLine := indent + trimmed
-		formatted = append(formatted, formattedLine)
+		if !inRawString && len(indent)+len(trimmed) > MAX_LINE_WIDTH {
+			for _, part := range splitLongLiteral(trimmed, MAX_LINE_WIDTH-len(indent)) {
+				formatted = append(formatted, indent+part)
+			}
+		} else {
+			formattedLine := indent + trimmed
+			formatted = append(formatted, formattedLine)
+		}
+		if !inRawString && !strings.HasPrefix(trimmed, "?") {
+			open, inPipes = bracketBalance(trimmed, 0, false)
+		}
 
 		// Check if we should increase indent after this line (skip for comments)
 		if !strings.HasPrefix(trimmed, "?") {
@@ -105,8 +145,15 @@ func formatSource(source string) string {
 func preprocessDollarSigns(source string) string {
 	lines := strings.Split(source, "\n")
 	var result []string
+	inRawString := false
 
 	for _, line := range lines {
+		open := rawStringOpen(line, inRawString)
+		if inRawString || open {
+			inRawString = open
+			result = append(result, line)
+			continue
+		}
 		trimmed := strings.TrimSpace(line)
 		// If line ends with $ and has content before it, split
 		if strings.HasSuffix(trimmed, " $") && trimmed != "$" {
@@ -336,7 +383,7 @@ func formatOperatorOutsideStrings(s string, op byte) string {
 					continue
 				}
 			}
-			
+
 			// Add spaces around operator
 			// Check if there's already a space before
 			if result.Len() > 0 {
@@ -560,3 +607,131 @@ func shouldIncreaseIndent(line string) bool {
 
 	return false
 }
+
+// scanCode calls visit with each byte of line that is code, outside string
+// and char literals, up to a trailing comment
+func scanCode(line string, visit func(i int, ch byte)) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '?' && i > 0 && line[i-1] == ' ' && i+1 < len(line) && line[i+1] == ' ':
+			return
+		default:
+			visit(i, ch)
+		}
+	}
+}
+
+// bracketBalance returns how many brackets and call pipes are open after
+// line, given how many were open before it. A line with an odd number of
+// pipes opens an argument list spanning lines, or closes the open one.
+func bracketBalance(line string, open int, inPipes bool) (int, bool) {
+	balance, pipes := open, 0
+	scanCode(line, func(i int, ch byte) {
+		switch ch {
+		case '[', '{', '(':
+			balance++
+		case ']', '}', ')':
+			balance--
+		case '|':
+			pipes++
+		}
+	})
+	if pipes%2 == 1 {
+		if inPipes {
+			balance--
+		} else {
+			balance++
+		}
+		inPipes = !inPipes
+	}
+	return max(balance, 0), inPipes
+}
+
+// leadingClosers is how many of the open brackets a line closes before its
+// first entry, which lines it up with the line that opened them
+func leadingClosers(line string, open int, inPipes bool) int {
+	closing := "]})"
+	if inPipes {
+		closing += "|"
+	}
+	closers := 0
+	for closers < len(line) && closers < open && strings.IndexByte(closing, line[closers]) >= 0 {
+		closers++
+	}
+	return closers
+}
+
+// splitLongLiteral lays out the first array, dict or object literal on a
+// line that is longer than width one entry per line, each ending with a
+// comma. Entries that are still too long are split in turn.
+func splitLongLiteral(line string, width int) []string {
+	if len(line) <= width || strings.HasPrefix(line, "?") {
+		return []string{line}
+	}
+	start, end := -1, -1
+	var commas []int
+	depth := 0
+	inCall := false
+	scanCode(line, func(i int, ch byte) {
+		if end >= 0 {
+			return
+		}
+		switch ch {
+		case '[', '{', '(':
+			if depth == 0 && start < 0 && ch != '(' {
+				start = i
+				commas = nil
+			}
+			if start >= 0 {
+				depth++
+			}
+		case ']', '}', ')':
+			if start < 0 {
+				return
+			}
+			depth--
+			if depth == 0 {
+				if len(commas) > 0 {
+					end = i
+				} else {
+					start = -1 // an index or a key, not a literal
+				}
+			}
+		case '|':
+			// The arguments of a call are part of one entry
+			inCall = !inCall
+		case ',':
+			if start >= 0 && depth == 1 && !inCall {
+				commas = append(commas, i)
+			}
+		}
+	})
+	if end < 0 {
+		return []string{line}
+	}
+
+	lines := []string{line[:start+1]}
+	indent := strings.Repeat(" ", INDENT_SIZE)
+	from := start + 1
+	for _, at := range append(commas, end) {
+		entry := strings.TrimSpace(line[from:at])
+		from = at + 1
+		if entry == "" {
+			continue // the trailing comma of a literal already split
+		}
+		for _, part := range splitLongLiteral(entry+",", width-INDENT_SIZE) {
+			lines = append(lines, indent+part)
+		}
+	}
+	return append(lines, line[end:])
+}
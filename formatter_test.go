@@ -1,4 +1,4 @@
-package main
+package ahoy
 
 import (
 	"os"
@@ -362,3 +362,61 @@ end
 		t.Errorf("Tab conversion failed.\nExpected:\n%s\nGot:\n%s", expected, result)
 	}
 }
+
+func TestFormatterRawString(t *testing.T) {
+	input := "help: \"\"\"usage:\n\t-v  verbose $\n  done\"\"\"\nprint|help|\n"
+
+	// The text of a raw string is left as written
+	result := formatSource(input)
+	if result != input {
+		t.Errorf("Raw string was changed.\nExpected:\n%s\nGot:\n%s", input, result)
+	}
+}
+
+func TestFormatterLongLiterals(t *testing.T) {
+	input := `names: ["alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliet", "kilo"]
+short: [1, 2, 3]
+scores: {"alpha": [1, 2, 3], "bravo": [4, 5, 6], "charlie": [7, 8, 9], "delta": [10, 11, 12], "echo": [13]}
+total: add|
+1,
+2,
+|
+`
+
+	// Past the width each entry goes on its own line; lines already split
+	// are indented inside their brackets
+	expected := `names: [
+  "alpha",
+  "bravo",
+  "charlie",
+  "delta",
+  "echo",
+  "foxtrot",
+  "golf",
+  "hotel",
+  "india",
+  "juliet",
+  "kilo",
+]
+short: [1, 2, 3]
+scores: {
+  "alpha": [1, 2, 3],
+  "bravo": [4, 5, 6],
+  "charlie": [7, 8, 9],
+  "delta": [10, 11, 12],
+  "echo": [13],
+}
+total : add|
+  1,
+  2,
+|
+`
+
+	result := formatSource(input)
+	if result != expected {
+		t.Errorf("Long literal formatting failed.\nExpected:\n%s\nGot:\n%s", expected, result)
+	}
+	if again := formatSource(result); again != result {
+		t.Errorf("Formatting split literals again changed them:\n%s", again)
+	}
+}
@@ -0,0 +1,109 @@
+package ahoy
+
+import "fmt"
+
+// frameMethodTypes are the types the frame module's methods return;
+// frame.limit returns nothing
+var frameMethodTypes = map[string]string{"delta": "float"}
+
+// generateFrameCall generates a call to the built-in frame module, the
+// timing of loops that don't run under game.run. frame.limit|fps| ends a
+// frame, sleeping until 1/fps seconds after the previous one ended, and
+// frame.delta|| is how long the last frame took in seconds.
+func (gen *CodeGenerator) generateFrameCall(node *ASTNode) {
+	method := node.Value
+	args := node.Children[1].Children
+	switch method {
+	case "limit":
+		if len(args) != 1 {
+			gen.reportError(node.Line, fmt.Sprintf("frame.limit takes the frames per second, got %d arguments", len(args)))
+			return
+		}
+	case "delta":
+		if len(args) != 0 {
+			gen.reportError(node.Line, fmt.Sprintf("frame.delta takes no arguments, got %d", len(args)))
+			return
+		}
+	default:
+		gen.reportError(node.Line, fmt.Sprintf("the frame module has no '%s'", method),
+			"End each frame with frame.limit|fps| and read its length with frame.delta||")
+		return
+	}
+	gen.useFrame = true
+	gen.addInclude("time.h")
+	gen.addInclude("errno.h")
+
+	gen.output.WriteString(fmt.Sprintf("ahoy_frame_%s(", method))
+	if len(args) == 1 {
+		gen.generateNode(args[0])
+	}
+	gen.output.WriteString(")")
+}
+
+// writeFrameHelperFunctions generates the frame timing behind the frame
+// module on the monotonic clock. A loop that never calls frame.limit still
+// gets deltas: each frame.delta call then ends the frame.
+func (gen *CodeGenerator) writeFrameHelperFunctions() {
+	if !gen.useFrame {
+		return
+	}
+	gen.funcReturnStructs.WriteString("void ahoy_frame_limit(int fps);\n")
+	gen.funcReturnStructs.WriteString("double ahoy_frame_delta(void);\n\n")
+
+	gen.funcDecls.WriteString("\n// frame module\n")
+	gen.funcDecls.WriteString(`static double ahoy_frame_last = -1;
+static double ahoy_frame_length = 0;
+static bool ahoy_frame_limited = false;
+
+static double ahoy_frame_now(void) {
+    struct timespec now;
+    clock_gettime(CLOCK_MONOTONIC, &now);
+    return (double)now.tv_sec + (double)now.tv_nsec / 1e9;
+}
+
+`)
+	if gen.deterministic {
+		// Frames still take their time, but are a fixed length to the program
+		gen.funcDecls.WriteString(`static void ahoy_frame_end(double now) {
+    ahoy_frame_length = ahoy_det_frame_length;
+    ahoy_frame_last = now;
+    ahoy_det_step();
+}
+
+void ahoy_frame_limit(int fps) {
+    ahoy_frame_limited = true;
+    if (fps > 0) ahoy_det_frame_length = 1.0 / fps;
+`)
+	} else {
+		gen.funcDecls.WriteString(`static void ahoy_frame_end(double now) {
+    ahoy_frame_length = ahoy_frame_last < 0 ? 0 : now - ahoy_frame_last;
+    ahoy_frame_last = now;
+}
+
+void ahoy_frame_limit(int fps) {
+    ahoy_frame_limited = true;
+`)
+	}
+	gen.funcDecls.WriteString(`    double now = ahoy_frame_now();
+    if (fps > 0 && ahoy_frame_last >= 0) {
+        double wait = ahoy_frame_last + 1.0 / fps - now;
+        if (wait > 0) {
+            struct timespec pause;
+            pause.tv_sec = (time_t)wait;
+            pause.tv_nsec = (long)((wait - (double)pause.tv_sec) * 1e9);
+            // A signal can wake the sleep early; it carries on for the rest
+            while (nanosleep(&pause, &pause) == -1 && errno == EINTR) {}
+            now = ahoy_frame_now();
+        }
+    }
+    ahoy_frame_end(now);
+}
+
+double ahoy_frame_delta(void) {
+    if (!ahoy_frame_limited) {
+        ahoy_frame_end(ahoy_frame_now());
+    }
+    return ahoy_frame_length;
+}
+`)
+}
@@ -0,0 +1,63 @@
+package ahoy
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// addFuzzSeeds seeds a fuzz target with the example programs
+func addFuzzSeeds(f *testing.F) {
+	paths, _ := filepath.Glob(filepath.Join("test", "input", "*.ahoy"))
+	for _, path := range paths {
+		if source, err := os.ReadFile(path); err == nil {
+			f.Add(string(source))
+		}
+	}
+	f.Add("x: 1\nprint|x|\n")
+}
+
+func FuzzTokenize(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, source string) {
+		tokens := Tokenize(source)
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != TOKEN_EOF {
+			t.Fatalf("expected the tokens to end with EOF")
+		}
+	})
+}
+
+// FuzzParse checks that Parse only stops with its own syntax errors, never
+// with a runtime error such as an index out of range
+func FuzzParse(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, source string) {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, isRuntime := r.(runtime.Error); isRuntime {
+					t.Fatalf("Parse crashed: %v", r)
+				}
+			}
+		}()
+		Parse(Tokenize(source))
+	})
+}
+
+// FuzzParseLint checks that lint mode records every syntax error rather than
+// reaching its recover backstop
+func FuzzParseLint(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, source string) {
+		ast, errors := ParseLint(Tokenize(source))
+		if ast == nil {
+			t.Fatalf("expected an AST")
+		}
+		for _, err := range errors {
+			if strings.HasPrefix(err.Message, "internal parser error") {
+				t.Fatalf("%s at line %d", err.Message, err.Line)
+			}
+		}
+	})
+}
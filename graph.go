@@ -0,0 +1,219 @@
+package ahoy
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ImportGraph is how a program's packages import each other and the C
+// headers they use
+type ImportGraph struct {
+	Root   *GraphNode
+	Nodes  []*GraphNode // in the order they were reached
+	Cycles [][]string   // each cycle's package names, starting and ending with the same one
+}
+
+// GraphNode is a package or a C header in an import graph
+type GraphNode struct {
+	Name    string
+	Path    string // resolved file or directory; a header's path as imported
+	Header  bool   // a C header rather than an Ahoy package
+	Imports []*GraphEdge
+}
+
+// GraphEdge is an import of one node by another
+type GraphEdge struct {
+	To        *GraphNode
+	Namespace string // empty when the import has no namespace
+	Cycle     bool   // the import closes a cycle
+}
+
+// LoadImportGraph follows the imports of the program that source belongs to.
+// Import cycles are recorded rather than followed; an Ahoy import that cannot
+// be loaded is an error.
+func LoadImportGraph(source string) (*ImportGraph, error) {
+	absPath, err := filepath.Abs(source)
+	if err != nil {
+		return nil, fmt.Errorf("resolving file path: %v", err)
+	}
+	pm := NewPackageManager(filepath.Dir(absPath))
+	pm.Log = io.Discard
+	pkg, err := pm.LoadPackageFromFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading package: %v", err)
+	}
+
+	graph := &ImportGraph{}
+	nodes := map[string]*GraphNode{}
+	graph.Root = graph.addNode(nodes, pkg.Name, absPath, false)
+
+	// Depth-first, so an import of a package still being visited is a cycle
+	visiting := map[*GraphNode]bool{}
+	var stack []*GraphNode
+	var visit func(node *GraphNode, pkg *Package) error
+	visit = func(node *GraphNode, pkg *Package) error {
+		visiting[node] = true
+		stack = append(stack, node)
+		defer func() {
+			visiting[node] = false
+			stack = stack[:len(stack)-1]
+		}()
+
+		for _, file := range pkg.Files {
+			if file.AST == nil {
+				continue
+			}
+			for _, child := range file.AST.Children {
+				if child.Type != NODE_IMPORT_STATEMENT {
+					continue
+				}
+				if strings.HasSuffix(child.Value, ".h") {
+					header, exists := nodes[child.Value]
+					if !exists {
+						header = graph.addNode(nodes, filepath.Base(child.Value), child.Value, true)
+					}
+					node.addEdge(header, child.DataType)
+					continue
+				}
+
+				path := filepath.Clean(pm.resolveImportPath(child.Value, file.Path))
+				if target, exists := nodes[path]; exists {
+					edge := node.addEdge(target, child.DataType)
+					if visiting[target] && edge != nil {
+						edge.Cycle = true
+						graph.addCycle(stack, target)
+					}
+					continue
+				}
+				imported, err := pm.ResolveImport(path, file.Path)
+				if err != nil {
+					return fmt.Errorf("failed to resolve import '%s': %v", child.Value, err)
+				}
+				target := graph.addNode(nodes, imported.Name, path, false)
+				node.addEdge(target, child.DataType)
+				if err := visit(target, imported); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(graph.Root, pkg); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+func (graph *ImportGraph) addNode(nodes map[string]*GraphNode, name, path string, header bool) *GraphNode {
+	node := &GraphNode{Name: name, Path: path, Header: header}
+	nodes[path] = node
+	graph.Nodes = append(graph.Nodes, node)
+	return node
+}
+
+// addCycle records the cycle from target, which is on the stack, back to it
+func (graph *ImportGraph) addCycle(stack []*GraphNode, target *GraphNode) {
+	var cycle []string
+	for i, node := range stack {
+		if node == target {
+			for _, member := range stack[i:] {
+				cycle = append(cycle, member.Name)
+			}
+			break
+		}
+	}
+	graph.Cycles = append(graph.Cycles, append(cycle, target.Name))
+}
+
+// addEdge adds an import of to, returning nil when a file of the package
+// already imports it the same way
+func (node *GraphNode) addEdge(to *GraphNode, namespace string) *GraphEdge {
+	for _, edge := range node.Imports {
+		if edge.To == to && edge.Namespace == namespace {
+			return nil
+		}
+	}
+	edge := &GraphEdge{To: to, Namespace: namespace}
+	node.Imports = append(node.Imports, edge)
+	return edge
+}
+
+// label names an import the way the program refers to it
+func (edge *GraphEdge) label() string {
+	label := edge.To.Name
+	if edge.Namespace != "" {
+		label = edge.Namespace + ": " + label
+	}
+	if edge.To.Header {
+		label += " [C header]"
+	}
+	return label
+}
+
+// WriteTree prints the graph as an indented tree from the root. A package
+// reached a second time isn't expanded again; cycles are listed at the end.
+func (graph *ImportGraph) WriteTree(w io.Writer) {
+	fmt.Fprintln(w, graph.Root.Name)
+	expanded := map[*GraphNode]bool{graph.Root: true}
+	var writeImports func(node *GraphNode, prefix string)
+	writeImports = func(node *GraphNode, prefix string) {
+		for i, edge := range node.Imports {
+			branch, indent := "├── ", "│   "
+			if i == len(node.Imports)-1 {
+				branch, indent = "└── ", "    "
+			}
+			switch {
+			case edge.Cycle:
+				fmt.Fprintf(w, "%s%s%s (cycle)\n", prefix, branch, edge.label())
+			case expanded[edge.To] && len(edge.To.Imports) > 0:
+				fmt.Fprintf(w, "%s%s%s (see above)\n", prefix, branch, edge.label())
+			default:
+				fmt.Fprintf(w, "%s%s%s\n", prefix, branch, edge.label())
+				expanded[edge.To] = true
+				writeImports(edge.To, prefix+indent)
+			}
+		}
+	}
+	writeImports(graph.Root, "")
+
+	for _, cycle := range graph.Cycles {
+		fmt.Fprintf(w, "\nImport cycle: %s\n", strings.Join(cycle, " -> "))
+	}
+}
+
+// WriteDOT prints the graph in Graphviz DOT format. Headers are boxes,
+// namespaces label their imports and imports that close a cycle are red.
+func (graph *ImportGraph) WriteDOT(w io.Writer) {
+	ids := map[*GraphNode]string{}
+	fmt.Fprintln(w, "digraph imports {")
+	for i, node := range graph.Nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+		shape := "ellipse"
+		if node.Header {
+			shape = "box"
+		}
+		fmt.Fprintf(w, "    n%d [label=%q, shape=%s];\n", i, node.Name, shape)
+	}
+	for _, node := range graph.Nodes {
+		for _, edge := range node.Imports {
+			var attrs []string
+			if edge.Namespace != "" {
+				attrs = append(attrs, fmt.Sprintf("label=%q", edge.Namespace))
+			}
+			if edge.Cycle {
+				attrs = append(attrs, "color=red")
+			}
+			if len(attrs) > 0 {
+				fmt.Fprintf(w, "    %s -> %s [%s];\n", ids[node], ids[edge.To], strings.Join(attrs, ", "))
+			} else {
+				fmt.Fprintf(w, "    %s -> %s;\n", ids[node], ids[edge.To])
+			}
+		}
+	}
+	for _, cycle := range graph.Cycles {
+		fmt.Fprintf(w, "    // import cycle: %s\n", strings.Join(cycle, " -> "))
+	}
+	fmt.Fprintln(w, "}")
+}
@@ -0,0 +1,105 @@
+package ahoy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadImportGraph(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.ahoy":       "import geo \"./lib/shapes.ahoy\"\nimport \"./util.ahoy\"\nprint|1|\n",
+		"util.ahoy":       "import \"./lib/shapes.ahoy\"\nimport \"widgets.h\"\n@ helper || int:\n    return 1\n$\n",
+		"lib/shapes.ahoy": "import \"../util.ahoy\"\n@ area || int:\n    return 2\n$\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	graph, err := LoadImportGraph(filepath.Join(dir, "main.ahoy"))
+	if err != nil {
+		t.Fatalf("LoadImportGraph: %v", err)
+	}
+	if len(graph.Nodes) != 4 {
+		t.Errorf("expected 4 nodes, got %d", len(graph.Nodes))
+	}
+	if len(graph.Cycles) != 1 || strings.Join(graph.Cycles[0], " ") != "shapes.ahoy util.ahoy shapes.ahoy" {
+		t.Errorf("expected the shapes/util cycle, got %v", graph.Cycles)
+	}
+
+	var tree strings.Builder
+	graph.WriteTree(&tree)
+	expected := `main.ahoy
+├── geo: shapes.ahoy
+│   └── util.ahoy
+│       ├── shapes.ahoy (cycle)
+│       └── widgets.h [C header]
+└── util.ahoy (see above)
+
+Import cycle: shapes.ahoy -> util.ahoy -> shapes.ahoy
+`
+	if tree.String() != expected {
+		t.Errorf("tree mismatch.\nExpected:\n%s\nGot:\n%s", expected, tree.String())
+	}
+
+	var dot strings.Builder
+	graph.WriteDOT(&dot)
+	for _, want := range []string{`n0 -> n1 [label="geo"];`, "n2 -> n1 [color=red];", `n3 [label="widgets.h", shape=box];`} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("expected the DOT output to contain %s, got:\n%s", want, dot.String())
+		}
+	}
+}
+
+func TestLoadCallGraph(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.ahoy": "import \"./util.ahoy\"\n@ twice |n:int| int:\n    return n * 2\n$\n" +
+			"@ fact |n:int| int:\n    if n < 2 then\n        return 1\n    $\n    return n * fact|n - 1|\n$\n" +
+			"nums: [1, 2]\ndoubled: nums.map|twice|\nsix: fact|3|\nfour: square|2|\nprint|six, four, doubled|\n",
+		"util.ahoy": "@ square |n:int| int:\n    return n * n\n$\n@ cube |n:int| int:\n    return n * square|n|\n$\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	graph, err := LoadCallGraph(filepath.Join(dir, "main.ahoy"), false)
+	if err != nil {
+		t.Fatalf("LoadCallGraph: %v", err)
+	}
+	if got := strings.Join(graph.Roots, " "); got != "fact square twice" {
+		t.Errorf("expected the top-level statements' functions as roots, got %s", got)
+	}
+	if got := strings.Join(graph.Unreachable(), " "); got != "cube" {
+		t.Errorf("expected only cube to be unreachable, got %s", got)
+	}
+
+	var tree strings.Builder
+	graph.WriteTree(&tree)
+	expected := `fact
+└── fact (see above)
+square
+twice
+
+Unreachable: cube
+`
+	if tree.String() != expected {
+		t.Errorf("tree mismatch.\nExpected:\n%s\nGot:\n%s", expected, tree.String())
+	}
+
+	var dot strings.Builder
+	graph.WriteDOT(&dot)
+	for _, want := range []string{`f0 [label="cube", color=gray, fontcolor=gray];`, `f1 [label="fact", shape=box];`, "f0 -> f2;"} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("expected the DOT output to contain %s, got:\n%s", want, dot.String())
+		}
+	}
+}
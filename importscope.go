@@ -0,0 +1,229 @@
+package ahoy
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// importScope checks the names packages take from each other. Every
+// package is compiled in full, but from ... import brings only the names it
+// lists into scope, and a package offers importers its own top-level names
+// and those it re-exports with pub use.
+type importScope struct {
+	pm       *PackageManager
+	offers   map[*Package]map[string]bool
+	visiting map[*Package]bool
+}
+
+// checkImportScope reports, for the package and the packages it imports,
+// names a from ... import lists that the package doesn't offer, pub use of
+// names the imported package doesn't have, and uses of names that only a
+// from ... import brings in without listing them
+func checkImportScope(pkg *Package, pm *PackageManager, imports map[string]*Package) error {
+	scope := &importScope{pm: pm, offers: map[*Package]map[string]bool{}, visiting: map[*Package]bool{}}
+	packages := []*Package{pkg}
+	for _, namespace := range slices.Sorted(maps.Keys(imports)) {
+		packages = append(packages, imports[namespace])
+	}
+	for _, p := range packages {
+		if err := scope.checkPackage(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// offered returns the names pkg offers importers
+func (scope *importScope) offered(pkg *Package) (map[string]bool, error) {
+	if names, done := scope.offers[pkg]; done {
+		return names, nil
+	}
+	names := map[string]bool{}
+	for _, symbol := range pkg.Symbols() {
+		names[symbol.Name] = true
+	}
+	// A package re-exporting from one that re-exports from it offers its own
+	if scope.visiting[pkg] {
+		return names, nil
+	}
+	scope.visiting[pkg] = true
+	defer delete(scope.visiting, pkg)
+
+	for _, file := range pkg.Files {
+		if file.AST == nil {
+			continue
+		}
+		for _, child := range file.AST.Children {
+			if child.Type != NODE_REEXPORT {
+				continue
+			}
+			source, err := scope.importNamed(pkg, child.DataType)
+			if err != nil {
+				return nil, err
+			}
+			if source == nil {
+				return nil, fmt.Errorf("%s:%d: pub use %s.%s: nothing is imported as %s", file.Path, child.Line, child.DataType, child.Value, child.DataType)
+			}
+			sourceNames, err := scope.offered(source)
+			if err != nil {
+				return nil, err
+			}
+			if !sourceNames[child.Value] {
+				return nil, fmt.Errorf("%s:%d: pub use %s.%s: %s has no %s", file.Path, child.Line, child.DataType, child.Value, child.DataType, child.Value)
+			}
+			names[child.Value] = true
+		}
+	}
+	scope.offers[pkg] = names
+	return names, nil
+}
+
+// importNamed returns the package one of pkg's files imports as namespace:
+// by the namespace the import gives, the file or directory name, or the
+// imported program's name
+func (scope *importScope) importNamed(pkg *Package, namespace string) (*Package, error) {
+	for _, file := range pkg.Files {
+		if file.AST == nil {
+			continue
+		}
+		for _, child := range file.AST.Children {
+			if !isPackageImport(child) {
+				continue
+			}
+			imported, err := scope.pm.ResolveImport(child.Value, file.Path)
+			if err != nil {
+				return nil, err
+			}
+			if child.DataType == namespace || importName(child) == namespace || imported.Name == namespace {
+				return imported, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// checkPackage reports the names pkg's files take from other packages that
+// aren't in their scope
+func (scope *importScope) checkPackage(pkg *Package) error {
+	visible, err := scope.offered(pkg)
+	if err != nil {
+		return err
+	}
+	visible = maps.Clone(visible)
+	hidden := map[string]string{} // name -> the package a from ... import left it in
+	for _, file := range pkg.Files {
+		if file.AST == nil {
+			continue
+		}
+		for _, child := range file.AST.Children {
+			if !isPackageImport(child) {
+				continue
+			}
+			imported, err := scope.pm.ResolveImport(child.Value, file.Path)
+			if err != nil {
+				return err
+			}
+			names, err := scope.offered(imported)
+			if err != nil {
+				return err
+			}
+			if len(child.Children) == 0 {
+				for name := range names {
+					visible[name] = true
+				}
+				continue
+			}
+			for _, selected := range child.Children {
+				if !names[selected.Value] {
+					return fmt.Errorf("%s:%d: %s has no %s to import", file.Path, selected.Line, importName(child), selected.Value)
+				}
+				visible[selected.Value] = true
+			}
+			for name := range names {
+				hidden[name] = importName(child)
+			}
+		}
+	}
+	for name := range visible {
+		delete(hidden, name)
+	}
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	for _, file := range pkg.Files {
+		if file.AST == nil {
+			continue
+		}
+		locals := localNames(file.AST)
+		var outOfScope error
+		walkAST(file.AST, func(node *ASTNode) bool {
+			if outOfScope != nil {
+				return false
+			}
+			switch node.Type {
+			case NODE_STRUCT_DECLARATION, NODE_ENUM_DECLARATION, NODE_IMPORT_STATEMENT, NODE_REEXPORT:
+				return false
+			case NODE_CALL, NODE_OBJECT_LITERAL:
+			case NODE_IDENTIFIER:
+				if locals[node.Value] {
+					return true
+				}
+			default:
+				return true
+			}
+			if from, isHidden := hidden[node.Value]; isHidden {
+				outOfScope = fmt.Errorf("%s:%d: %s isn't imported; add it to from %s import", file.Path, node.Line, node.Value, from)
+			}
+			return true
+		})
+		if outOfScope != nil {
+			return outOfScope
+		}
+	}
+	return nil
+}
+
+// isPackageImport reports whether node imports an Ahoy package rather than
+// a C header
+func isPackageImport(node *ASTNode) bool {
+	return node.Type == NODE_IMPORT_STATEMENT && !strings.HasSuffix(node.Value, ".h")
+}
+
+// importName is the name an import's package goes by in messages: the file
+// or directory it names, without .ahoy
+func importName(node *ASTNode) string {
+	return strings.TrimSuffix(filepath.Base(node.Value), ".ahoy")
+}
+
+// localNames returns the names a file assigns, and the parameters and loop
+// variables it declares, which shadow the names of other packages
+func localNames(ast *ASTNode) map[string]bool {
+	locals := map[string]bool{}
+	walkAST(ast, func(node *ASTNode) bool {
+		switch node.Type {
+		case NODE_ASSIGNMENT, NODE_VARIABLE_DECLARATION, NODE_CONSTANT_DECLARATION:
+			locals[node.Value] = true
+		case NODE_TUPLE_ASSIGNMENT, NODE_FUNCTION, NODE_LAMBDA:
+			if len(node.Children) > 0 {
+				for _, target := range node.Children[0].Children {
+					if target.Type == NODE_IDENTIFIER {
+						locals[target.Value] = true
+					}
+				}
+			}
+		case NODE_FOR_IN_ARRAY_LOOP, NODE_FOR_IN_DICT_LOOP, NODE_FOR_RANGE_LOOP, NODE_FOR_COUNT_LOOP:
+			for _, child := range node.Children {
+				if child.Type != NODE_IDENTIFIER {
+					break
+				}
+				locals[child.Value] = true
+			}
+		}
+		return true
+	})
+	return locals
+}
@@ -0,0 +1,575 @@
+package ahoy
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// SourceEdit describes one change to a source file by the lines it touched,
+// the way an editor reports it: lines StartLine through OldEndLine of the
+// previous text became lines StartLine through NewEndLine. Lines are 1-based
+// and each edit is relative to the text left by the edits before it.
+type SourceEdit struct {
+	StartLine  int
+	OldEndLine int
+	NewEndLine int
+}
+
+// NewSourceEdit describes replacing the text from startLine to endLine with
+// newText
+func NewSourceEdit(startLine, endLine int, newText string) SourceEdit {
+	return SourceEdit{
+		StartLine:  startLine,
+		OldEndLine: endLine,
+		NewEndLine: startLine + strings.Count(newText, "\n"),
+	}
+}
+
+// ParseTree is a parsed source file that ParseIncremental can bring up to
+// date after edits. The AST and errors match what ParseLintWithPath returns
+// for Source.
+type ParseTree struct {
+	AST    *ASTNode
+	Errors []ParseError
+	Source string
+
+	path   string
+	chunks []parseChunk
+	final  *parserState // parser state after the last chunk
+}
+
+// parseChunk is a run of source lines that starts with a top-level statement
+// at the left margin and runs up to the next one
+type parseChunk struct {
+	start  int // first line
+	end    int // last line
+	nodes  []*ASTNode
+	errors []ParseError
+	before *parserState // parser state at the start of the chunk
+}
+
+// NewParseTree parses source in lint mode, keeping what ParseIncremental
+// needs to reuse the result
+func NewParseTree(source string, sourceFilePath string) *ParseTree {
+	b := newTreeBuilder(source, sourceFilePath)
+	b.parseLines(1, len(b.lines))
+	return b.finish()
+}
+
+// ParseIncremental returns the parse tree for newSource, the text of
+// oldTree after edits. Top-level statements outside the edited lines are
+// reused when the declarations before them are unchanged; only the edited
+// region is tokenized and parsed again. Reused nodes are moved to their new
+// lines in place, so oldTree must not be used afterwards. Edits that don't
+// account for newSource fall back to a full parse.
+func ParseIncremental(oldTree *ParseTree, edits []SourceEdit, newSource string) *ParseTree {
+	if oldTree == nil || len(oldTree.chunks) == 0 {
+		return NewParseTree(newSource, "")
+	}
+
+	b := newTreeBuilder(newSource, oldTree.path)
+	oldLines := strings.Split(oldTree.Source, "\n")
+	places, ok := placeChunks(oldTree.chunks, edits, oldLines, b.lines)
+	if !ok {
+		return NewParseTree(newSource, oldTree.path)
+	}
+	oldOffsets := lineOffsets(oldLines)
+
+	regionEdited := false
+	for i := range oldTree.chunks {
+		chunk := &oldTree.chunks[i]
+		place := places[i]
+		if !place.dirty && b.next <= place.start {
+			if b.next < place.start {
+				mark := len(b.tree.chunks)
+				if b.parseLines(b.next, place.start-1) && regionEdited {
+					// The edit may have opened a block that now runs into
+					// the following statements, so parse the rest as a whole
+					b.rollback(mark)
+					b.parseLines(b.next, len(b.lines))
+					return b.finish()
+				}
+				regionEdited = false
+			}
+			if len(chunk.errors) == 0 && b.parser.currentState().key() == chunk.before.key() {
+				after := oldTree.final
+				if i+1 < len(oldTree.chunks) {
+					after = oldTree.chunks[i+1].before
+				}
+				b.reuse(chunk, place, after, oldOffsets)
+				continue
+			}
+		}
+		regionEdited = regionEdited || place.dirty
+	}
+	if b.next <= len(b.lines) {
+		b.parseLines(b.next, len(b.lines))
+	}
+	return b.finish()
+}
+
+// chunkPlace is where an old chunk sits in the new source
+type chunkPlace struct {
+	start, end int
+	dirty      bool // touched by an edit
+}
+
+// placeChunks follows each chunk through the edits. A chunk is dirty when an
+// edit touched it, its text changed anyway or a comment was added right above
+// it, and the chunk before a dirty one is dirty too since its last statement
+// may have looked ahead.
+func placeChunks(chunks []parseChunk, edits []SourceEdit, oldLines, newLines []string) ([]chunkPlace, bool) {
+	places := make([]chunkPlace, len(chunks))
+	for i, chunk := range chunks {
+		places[i] = chunkPlace{start: chunk.start, end: chunk.end}
+	}
+	places[len(places)-1].end = math.MaxInt
+
+	lineDelta := 0
+	for _, edit := range edits {
+		delta := edit.NewEndLine - edit.OldEndLine
+		lineDelta += delta
+		for i := range places {
+			place := &places[i]
+			switch {
+			case place.end < edit.StartLine:
+			case place.start > edit.OldEndLine:
+				place.start += delta
+				if place.end != math.MaxInt {
+					place.end += delta
+				}
+			default:
+				place.dirty = true
+				if place.end != math.MaxInt && place.end >= edit.OldEndLine {
+					place.end += delta
+				}
+			}
+		}
+	}
+	if len(newLines) != len(oldLines)+lineDelta {
+		return nil, false
+	}
+	places[len(places)-1].end = len(newLines)
+
+	previousEnd := 0
+	for i, chunk := range chunks {
+		place := &places[i]
+		if place.dirty {
+			continue
+		}
+		// A comment right above the chunk would belong to its first statement
+		commented := place.start > 1 && place.start <= len(newLines) && strings.HasPrefix(newLines[place.start-2], "?")
+		if commented || place.start <= previousEnd || place.end > len(newLines) ||
+			!slices.Equal(oldLines[chunk.start-1:chunk.end], newLines[place.start-1:place.end]) {
+			place.dirty = true
+			continue
+		}
+		previousEnd = place.end
+	}
+
+	for i := 0; i+1 < len(places); i++ {
+		if places[i+1].dirty {
+			places[i].dirty = true
+		}
+	}
+	return places, true
+}
+
+// treeBuilder assembles a ParseTree from parsed regions and reused chunks
+type treeBuilder struct {
+	parser  *Parser
+	lines   []string
+	offsets []int // where each line starts
+	tree    *ParseTree
+	next    int // first line not yet covered by a chunk
+
+	// AST and error counts when the open chunk started
+	nodeStart  int
+	errorStart int
+}
+
+func newTreeBuilder(source string, sourceFilePath string) *treeBuilder {
+	lines := strings.Split(source, "\n")
+	return &treeBuilder{
+		parser:  newParser(nil, true, sourceFilePath),
+		lines:   lines,
+		offsets: lineOffsets(lines),
+		tree: &ParseTree{
+			AST:    &ASTNode{Type: NODE_PROGRAM},
+			Source: source,
+			path:   sourceFilePath,
+		},
+		next: 1,
+	}
+}
+
+// parseLines tokenizes and parses lines first through last as top-level
+// statements, starting a chunk at each statement on the left margin. It
+// reports whether parsing produced errors.
+func (b *treeBuilder) parseLines(first, last int) bool {
+	p := b.parser
+	p.tokens = Tokenize(strings.Join(b.lines[first-1:last], "\n"))
+	p.pos = 0
+	for i := range p.tokens {
+		token := &p.tokens[i]
+		if token.Line > 0 {
+			token.Line += first - 1
+		} else if last < len(b.lines) {
+			// Closing dedents and the end of the region stand in for the
+			// next statement, which starts the line after it
+			token.Line = last + 1
+			if token.Type == TOKEN_EOF {
+				token.Column = 1
+			}
+			start := b.offsets[last]
+			token.Span = Span{Start: start, End: start, StartLine: last + 1, StartColumn: 1, EndLine: last + 1, EndColumn: 1}
+			continue
+		}
+		token.Span.Start += b.offsets[first-1]
+		token.Span.End += b.offsets[first-1]
+		token.Span.StartLine += first - 1
+		token.Span.EndLine += first - 1
+	}
+
+	errorCount := len(p.Errors)
+	b.openChunk(first)
+	for p.current().Type != TOKEN_EOF {
+		if p.current().Type == TOKEN_NEWLINE || p.current().Type == TOKEN_SEMICOLON || p.current().Type == TOKEN_DEDENT {
+			p.advance()
+			continue
+		}
+		if b.startsChunk() {
+			// Comments directly above the statement belong to it
+			line := p.current().Line
+			for line-1 > b.tree.chunks[len(b.tree.chunks)-1].start && strings.HasPrefix(b.lines[line-2], "?") {
+				line--
+			}
+			b.closeChunk(line - 1)
+			b.openChunk(line)
+		}
+		p.parseTopLevelStatement(b.tree.AST)
+	}
+	b.closeChunk(last)
+	return len(p.Errors) > errorCount
+}
+
+// startsChunk reports whether the current token begins a statement on the
+// left margin that follows what the open chunk already holds
+func (b *treeBuilder) startsChunk() bool {
+	p := b.parser
+	if len(b.tree.AST.Children) == b.nodeStart && len(p.Errors) == b.errorStart {
+		return false
+	}
+	line := p.current().Line
+	if line <= b.tree.chunks[len(b.tree.chunks)-1].start || line > len(b.lines) {
+		return false
+	}
+	if text := b.lines[line-1]; text == "" || text[0] == ' ' || text[0] == '\t' {
+		return false
+	}
+	for i := p.pos - 1; i >= 0; i-- {
+		switch p.tokens[i].Type {
+		case TOKEN_INDENT, TOKEN_DEDENT:
+			continue
+		case TOKEN_NEWLINE:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func (b *treeBuilder) openChunk(line int) {
+	b.nodeStart = len(b.tree.AST.Children)
+	b.errorStart = len(b.parser.Errors)
+	b.tree.chunks = append(b.tree.chunks, parseChunk{
+		start:  line,
+		before: b.parser.currentState().clone(),
+	})
+}
+
+func (b *treeBuilder) closeChunk(end int) {
+	chunk := &b.tree.chunks[len(b.tree.chunks)-1]
+	chunk.end = end
+	chunk.nodes = slices.Clip(b.tree.AST.Children[b.nodeStart:])
+	chunk.errors = slices.Clip(b.parser.Errors[b.errorStart:])
+	b.next = end + 1
+}
+
+// rollback drops the chunks from index mark on and puts the parser back in
+// the state it had at their start
+func (b *treeBuilder) rollback(mark int) {
+	chunk := b.tree.chunks[mark]
+	nodes, errors := 0, 0
+	for _, kept := range b.tree.chunks[:mark] {
+		nodes += len(kept.nodes)
+		errors += len(kept.errors)
+	}
+	b.tree.AST.Children = b.tree.AST.Children[:nodes]
+	b.parser.Errors = b.parser.Errors[:errors]
+	b.parser.setState(chunk.before.clone())
+	b.parser.trailingComment = 0
+	b.tree.chunks = b.tree.chunks[:mark]
+	b.next = chunk.start
+}
+
+// reuse adds an unchanged chunk from the old tree at its new place. The
+// parser continues from the state the chunk left behind, with the
+// declarations it made moved to their new lines.
+func (b *treeBuilder) reuse(chunk *parseChunk, place chunkPlace, after *parserState, oldOffsets []int) {
+	delta := place.start - chunk.start
+	byteDelta := b.offsets[place.start-1] - oldOffsets[chunk.start-1]
+	if delta != 0 || byteDelta != 0 {
+		seen := map[*ASTNode]bool{}
+		for _, node := range chunk.nodes {
+			moveNode(node, delta, byteDelta, seen)
+		}
+	}
+
+	live := b.parser.currentState()
+	b.tree.chunks = append(b.tree.chunks, parseChunk{
+		start:  place.start,
+		end:    place.end,
+		nodes:  chunk.nodes,
+		before: live.clone(),
+	})
+	b.tree.AST.Children = append(b.tree.AST.Children, chunk.nodes...)
+	b.parser.setState(after.moved(chunk.before, live, delta))
+	b.next = place.end + 1
+}
+
+func (b *treeBuilder) finish() *ParseTree {
+	for _, child := range b.tree.AST.Children {
+		b.tree.AST.Span = joinSpans(b.tree.AST.Span, child.Span)
+	}
+	b.tree.Errors = b.parser.Errors
+	b.tree.final = b.parser.currentState().clone()
+	return b.tree
+}
+
+// moveNode moves node and everything below it by delta lines and
+// byteDelta bytes
+func moveNode(node *ASTNode, delta int, byteDelta int, seen map[*ASTNode]bool) {
+	if node == nil || seen[node] {
+		return
+	}
+	seen[node] = true
+	if node.Line > 0 {
+		node.Line += delta
+	}
+	if !node.Span.IsZero() {
+		node.Span.Start += byteDelta
+		node.Span.End += byteDelta
+		node.Span.StartLine += delta
+		node.Span.EndLine += delta
+	}
+	for _, child := range node.Children {
+		moveNode(child, delta, byteDelta, seen)
+	}
+	moveNode(node.DefaultValue, delta, byteDelta, seen)
+	moveNode(node.Capacity, delta, byteDelta, seen)
+}
+
+// lineOffsets returns the byte offset at which each line starts
+func lineOffsets(lines []string) []int {
+	offsets := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		offsets[i] = offset
+		offset += len(line) + 1
+	}
+	return offsets
+}
+
+// parserState is the part of a Parser that carries over from one top-level
+// statement to the next
+type parserState struct {
+	variableTypes      map[string]string
+	constants          map[string]int
+	structs            map[string]*StructDefinition
+	enums              map[string]*EnumDefinition
+	typeAliases        map[string]string
+	unionTypes         map[string][]string
+	objectLiterals     map[string]map[string]bool
+	functionScope      map[string]string
+	functions          map[string]*FunctionSignature
+	arrayLengths       map[string]ArrayInfo
+	cHeaders           map[string]*CHeaderInfo
+	cHeaderGlobal      *CHeaderInfo
+	loopVarScopes      []map[string]string
+	currentFunctionRet string
+	seenNonImport      bool
+	hasProgramDecl     bool
+	inFunctionBody     bool
+	blockDepth         int
+	functionDepth      int
+}
+
+// currentState returns the parser's state without copying it
+func (p *Parser) currentState() *parserState {
+	return &parserState{
+		variableTypes:      p.variableTypes,
+		constants:          p.constants,
+		structs:            p.structs,
+		enums:              p.enums,
+		typeAliases:        p.typeAliases,
+		unionTypes:         p.unionTypes,
+		objectLiterals:     p.objectLiterals,
+		functionScope:      p.functionScope,
+		functions:          p.functions,
+		arrayLengths:       p.arrayLengths,
+		cHeaders:           p.cHeaders,
+		cHeaderGlobal:      p.cHeaderGlobal,
+		loopVarScopes:      p.loopVarScopes,
+		currentFunctionRet: p.currentFunctionRet,
+		seenNonImport:      p.seenNonImport,
+		hasProgramDecl:     p.hasProgramDecl,
+		inFunctionBody:     p.inFunctionBody,
+		blockDepth:         p.blockDepth,
+		functionDepth:      p.functionDepth,
+	}
+}
+
+// setState makes s the parser's state; the parser owns it from then on
+func (p *Parser) setState(s *parserState) {
+	p.variableTypes = s.variableTypes
+	p.constants = s.constants
+	p.structs = s.structs
+	p.enums = s.enums
+	p.typeAliases = s.typeAliases
+	p.unionTypes = s.unionTypes
+	p.objectLiterals = s.objectLiterals
+	p.functionScope = s.functionScope
+	p.functions = s.functions
+	p.arrayLengths = s.arrayLengths
+	p.cHeaders = s.cHeaders
+	p.cHeaderGlobal = s.cHeaderGlobal
+	p.loopVarScopes = s.loopVarScopes
+	p.currentFunctionRet = s.currentFunctionRet
+	p.seenNonImport = s.seenNonImport
+	p.hasProgramDecl = s.hasProgramDecl
+	p.inFunctionBody = s.inFunctionBody
+	p.blockDepth = s.blockDepth
+	p.functionDepth = s.functionDepth
+}
+
+func (s *parserState) clone() *parserState {
+	c := *s
+	c.variableTypes = maps.Clone(s.variableTypes)
+	c.constants = maps.Clone(s.constants)
+	c.structs = maps.Clone(s.structs)
+	c.enums = maps.Clone(s.enums)
+	c.typeAliases = maps.Clone(s.typeAliases)
+	c.unionTypes = maps.Clone(s.unionTypes)
+	c.objectLiterals = make(map[string]map[string]bool, len(s.objectLiterals))
+	for name, properties := range s.objectLiterals {
+		c.objectLiterals[name] = maps.Clone(properties)
+	}
+	c.functionScope = maps.Clone(s.functionScope)
+	c.functions = maps.Clone(s.functions)
+	c.arrayLengths = maps.Clone(s.arrayLengths)
+	c.cHeaders = maps.Clone(s.cHeaders)
+	c.cHeaderGlobal = &CHeaderInfo{
+		Functions: maps.Clone(s.cHeaderGlobal.Functions),
+		Enums:     maps.Clone(s.cHeaderGlobal.Enums),
+		Defines:   maps.Clone(s.cHeaderGlobal.Defines),
+		Structs:   maps.Clone(s.cHeaderGlobal.Structs),
+	}
+	c.loopVarScopes = make([]map[string]string, len(s.loopVarScopes))
+	for i, scope := range s.loopVarScopes {
+		c.loopVarScopes[i] = maps.Clone(scope)
+	}
+	return &c
+}
+
+// moved returns a copy of s, the state after a chunk that started in state
+// before, for the chunk moved by delta lines and started in live instead.
+// live and before have the same key, so declarations s shares with before
+// come from live and only the chunk's own declarations are moved.
+func (s *parserState) moved(before, live *parserState, delta int) *parserState {
+	c := s.clone()
+	for name, line := range c.constants {
+		if old, ok := before.constants[name]; ok && old == line {
+			c.constants[name] = live.constants[name]
+		} else {
+			c.constants[name] = line + delta
+		}
+	}
+	for name, def := range c.structs {
+		if before.structs[name] == def {
+			c.structs[name] = live.structs[name]
+		} else {
+			def.Line += delta
+		}
+	}
+	for name, def := range c.enums {
+		if before.enums[name] == def {
+			c.enums[name] = live.enums[name]
+		} else {
+			def.Line += delta
+		}
+	}
+	for name, sig := range c.functions {
+		if before.functions[name] == sig {
+			c.functions[name] = live.functions[name]
+		} else {
+			sig.Line += delta
+		}
+	}
+	return c
+}
+
+// key describes everything in s that can change how later statements parse.
+// Line numbers are left out so that moved declarations still match.
+func (s *parserState) key() string {
+	var b strings.Builder
+	fmt.Fprint(&b, s.variableTypes, s.typeAliases, s.unionTypes, s.objectLiterals,
+		s.functionScope, s.arrayLengths, s.loopVarScopes, slices.Sorted(maps.Keys(s.constants)))
+	fmt.Fprint(&b, s.currentFunctionRet, s.seenNonImport, s.hasProgramDecl, s.inFunctionBody, s.blockDepth, s.functionDepth)
+
+	for _, name := range slices.Sorted(maps.Keys(s.structs)) {
+		def := s.structs[name]
+		fmt.Fprintf(&b, "struct %s<%s>{", name, def.Parent)
+		for _, field := range def.Fields {
+			fmt.Fprintf(&b, "%s:%s=", field.Name, field.Type)
+			writeNodeKey(&b, field.DefaultValue)
+		}
+		b.WriteString("}")
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.enums)) {
+		fmt.Fprintf(&b, "enum %s{", name)
+		for _, member := range s.enums[name].Members {
+			writeNodeKey(&b, member)
+		}
+		b.WriteString("}")
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.functions)) {
+		sig := s.functions[name]
+		fmt.Fprintf(&b, "func %s%v%v", name, sig.Parameters, sig.ReturnTypes)
+		if sig.IsInfer {
+			// Callers infer their types from the body
+			writeNodeKey(&b, sig.FunctionNode)
+		}
+	}
+	fmt.Fprint(&b, slices.Sorted(maps.Keys(s.cHeaders)),
+		slices.Sorted(maps.Keys(s.cHeaderGlobal.Functions)), slices.Sorted(maps.Keys(s.cHeaderGlobal.Enums)),
+		slices.Sorted(maps.Keys(s.cHeaderGlobal.Defines)), slices.Sorted(maps.Keys(s.cHeaderGlobal.Structs)))
+	return b.String()
+}
+
+func writeNodeKey(b *strings.Builder, node *ASTNode) {
+	if node == nil {
+		b.WriteString("()")
+		return
+	}
+	fmt.Fprintf(b, "(%d %q %q %q %t", node.Type, node.Value, node.DataType, node.EnumType, node.IsMutable)
+	writeNodeKey(b, node.DefaultValue)
+	writeNodeKey(b, node.Capacity)
+	for _, child := range node.Children {
+		writeNodeKey(b, child)
+	}
+	b.WriteString(")")
+}
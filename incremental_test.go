@@ -0,0 +1,119 @@
+package ahoy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const incrementalSource = `LIMIT :: 10
+struct point:
+    x: int
+    y: int
+$
+
+@ scale |p:point, factor:int| point:
+    return point{x: p.x * factor, y: p.y * factor}
+$
+
+? helpers
+@ clamp |value:int| int:
+    if value greater_than LIMIT then return LIMIT $
+    return value
+$
+
+origin: point{x: 0, y: 0}
+total: clamp|42|
+print|f"{total}"|
+`
+
+// editLines replaces lines first through last of source with text, which
+// may be empty or end in a newline
+func editLines(source string, first, last int, text string) (string, SourceEdit) {
+	lines := strings.SplitAfter(source, "\n")
+	edited := strings.Join(lines[:first-1], "") + text + strings.Join(lines[last:], "")
+	return edited, SourceEdit{
+		StartLine:  first,
+		OldEndLine: last,
+		NewEndLine: first + strings.Count(text, "\n") - 1,
+	}
+}
+
+func dumpNode(b *strings.Builder, node *ASTNode, depth int) {
+	if node == nil {
+		return
+	}
+	fmt.Fprintf(b, "%s%d %q %q line %d %+v %q %q\n", strings.Repeat("  ", depth), node.Type, node.Value, node.DataType,
+		node.Line, node.Span, node.Comments, node.TrailingComment)
+	for _, child := range node.Children {
+		dumpNode(b, child, depth+1)
+	}
+	dumpNode(b, node.DefaultValue, depth+1)
+}
+
+// checkMatchesFullParse fails unless tree is what a full parse of its source gives
+func checkMatchesFullParse(t *testing.T, tree *ParseTree) {
+	t.Helper()
+	ast, errors := ParseLint(Tokenize(tree.Source))
+	var want, got strings.Builder
+	dumpNode(&want, ast, 0)
+	dumpNode(&got, tree.AST, 0)
+	if got.String() != want.String() {
+		t.Errorf("incremental AST differs from a full parse.\nExpected:\n%s\nGot:\n%s", want.String(), got.String())
+	}
+	if fmt.Sprint(tree.Errors) != fmt.Sprint(errors) {
+		t.Errorf("incremental errors differ from a full parse.\nExpected: %v\nGot: %v", errors, tree.Errors)
+	}
+}
+
+func TestParseIncrementalMatchesFullParse(t *testing.T) {
+	edits := []struct {
+		name        string
+		first, last int
+		text        string
+	}{
+		{"edit function body", 13, 13, "    if value greater_than LIMIT then return 0 $\n"},
+		{"insert function", 10, 9, "@ twice |n:int| int:\n    return n * 2\n$\n\n"},
+		{"delete function", 11, 15, ""},
+		{"rename struct field", 3, 3, "    left: int\n"},
+		{"drop closing dollar", 14, 15, "    return value\n"},
+		{"append statement", 20, 19, "print|\"done\"|\n"},
+		{"remove constant", 1, 1, ""},
+	}
+	for _, edit := range edits {
+		t.Run(edit.name, func(t *testing.T) {
+			tree := NewParseTree(incrementalSource, "")
+			checkMatchesFullParse(t, tree)
+			source, sourceEdit := editLines(incrementalSource, edit.first, edit.last, edit.text)
+			checkMatchesFullParse(t, ParseIncremental(tree, []SourceEdit{sourceEdit}, source))
+		})
+	}
+}
+
+func TestParseIncrementalReusesUnchangedStatements(t *testing.T) {
+	tree := NewParseTree(incrementalSource, "")
+	last := tree.AST.Children[len(tree.AST.Children)-1]
+
+	source, edit := editLines(incrementalSource, 10, 9, "? one more comment\n")
+	tree = ParseIncremental(tree, []SourceEdit{edit}, source)
+	checkMatchesFullParse(t, tree)
+	if tree.AST.Children[len(tree.AST.Children)-1] != last {
+		t.Error("expected the last statement to be reused")
+	}
+	if last.Line != 20 {
+		t.Errorf("expected the reused statement to move to line 20, got %d", last.Line)
+	}
+
+	// A second round of edits starts from the updated tree
+	source, edit = editLines(source, 13, 13, "@ clamp |value:int, limit:int| int:\n")
+	tree = ParseIncremental(tree, []SourceEdit{edit}, source)
+	checkMatchesFullParse(t, tree)
+}
+
+func TestParseIncrementalWithWrongEdits(t *testing.T) {
+	tree := NewParseTree(incrementalSource, "")
+	source, _ := editLines(incrementalSource, 18, 18, "total: clamp|7|\nextra: 1\n")
+	// The edit claims a different line than the one that changed
+	tree = ParseIncremental(tree, []SourceEdit{{StartLine: 2, OldEndLine: 2, NewEndLine: 3}}, source)
+	checkMatchesFullParse(t, tree)
+}
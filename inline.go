@@ -0,0 +1,254 @@
+package ahoy
+
+import "fmt"
+
+// inlineSizeLimit is the most AST nodes a function's returned expression may
+// have to be inlined without @inline
+const inlineSizeLimit = 16
+
+// inlineTypes are the parameter and return types inlined functions may have
+var inlineTypes = map[string]bool{"int": true, "float": true, "bool": true, "char": true, "string": true}
+
+// inlineCandidate is a function whose body is one returned expression
+type inlineCandidate struct {
+	params     []*ASTNode
+	returnType string
+	body       *ASTNode
+}
+
+// inliner substitutes the bodies of small functions at their call sites
+type inliner struct {
+	gen        *CodeGenerator
+	candidates map[string]*inlineCandidate
+	globals    map[string]bool // constants, enums and enum members, which no local can shadow
+	active     map[string]bool // functions being inlined, so recursion stops
+}
+
+// inlineFunctions replaces calls of small functions in ast with their
+// bodies. A function is inlined when it only returns an expression of its
+// parameters, constants and enums, no bigger than inlineSizeLimit unless it
+// is marked @inline, and takes and returns scalars. @noinline keeps a
+// function called. Arguments are bound to fresh locals at the call site, so
+// each is still evaluated once, in order.
+func (gen *CodeGenerator) inlineFunctions(ast *ASTNode) {
+	in := &inliner{
+		gen:        gen,
+		candidates: map[string]*inlineCandidate{},
+		globals:    map[string]bool{},
+		active:     map[string]bool{},
+	}
+	for _, child := range ast.Children {
+		switch child.Type {
+		case NODE_CONSTANT_DECLARATION:
+			in.globals[child.Value] = true
+		case NODE_ENUM_DECLARATION:
+			in.globals[child.Value] = true
+			for _, member := range child.Children {
+				in.globals[member.Value] = true
+			}
+		}
+	}
+	for _, child := range ast.Children {
+		if child.Type == NODE_FUNCTION {
+			if candidate := in.candidate(child); candidate != nil {
+				in.candidates[child.Value] = candidate
+			}
+		}
+	}
+	if len(in.candidates) > 0 {
+		in.inlineCalls(ast, true)
+	}
+}
+
+// candidate returns how to inline fn, or nil if it can't be
+func (in *inliner) candidate(fn *ASTNode) *inlineCandidate {
+	if fn.Tag == "noinline" || len(fn.Children) < 2 {
+		return nil
+	}
+	returnTypes := in.gen.functionReturnTypes[fn.Value]
+	if len(returnTypes) != 1 || !inlineTypes[returnTypes[0]] {
+		return nil
+	}
+	body := fn.Children[1]
+	if len(body.Children) != 1 || body.Children[0].Type != NODE_RETURN_STATEMENT || len(body.Children[0].Children) != 1 {
+		return nil
+	}
+	params := fn.Children[0].Children
+	names := map[string]bool{}
+	for _, param := range params {
+		if !inlineTypes[param.DataType] {
+			return nil
+		}
+		names[param.Value] = true
+	}
+
+	expr := body.Children[0].Children[0]
+	size := 0
+	ok := true
+	walkAST(expr, func(node *ASTNode) bool {
+		size++
+		switch node.Type {
+		case NODE_NUMBER, NODE_STRING, NODE_CHAR, NODE_BOOLEAN, NODE_BINARY_OP, NODE_UNARY_OP,
+			NODE_TERNARY, NODE_MEMBER_ACCESS, NODE_METHOD_CALL, NODE_BLOCK:
+		case NODE_CALL:
+			ok = ok && node.Value != fn.Value
+		case NODE_IDENTIFIER:
+			ok = ok && (names[node.Value] || in.globals[node.Value])
+		case NODE_ARRAY_ACCESS:
+			ok = ok && names[node.Value]
+		default:
+			ok = false
+		}
+		return ok
+	})
+	if !ok || (size > inlineSizeLimit && fn.Tag != "inline") {
+		return nil
+	}
+	// A copy, as the calls in the function itself are inlined too
+	return &inlineCandidate{params: params, returnType: returnTypes[0], body: cloneRenamed(expr, nil, expr.Line)}
+}
+
+// inlineCalls inlines the calls under node. The calls that are statements
+// of a block are left alone, as their results aren't used.
+func (in *inliner) inlineCalls(node *ASTNode, statements bool) {
+	for i, child := range node.Children {
+		if child == nil {
+			continue
+		}
+		arguments := node.Type == NODE_METHOD_CALL && i == 1
+		in.inlineCalls(child, child.Type == NODE_PROGRAM || (child.Type == NODE_BLOCK && !arguments))
+		if child.Type == NODE_CALL && !statements {
+			if inlined := in.inline(child); inlined != nil {
+				node.Children[i] = inlined
+			}
+		}
+	}
+}
+
+// inline returns a call's function body bound to its arguments, or nil if
+// the call can't be inlined
+func (in *inliner) inline(call *ASTNode) *ASTNode {
+	candidate := in.candidates[call.Value]
+	if candidate == nil || in.active[call.Value] {
+		return nil
+	}
+	args := bindArguments(call, candidate.params)
+	if args == nil {
+		return nil
+	}
+
+	prefix := fmt.Sprintf("__inline_%d_", in.gen.varCounter)
+	in.gen.varCounter++
+	bindings := &ASTNode{Type: NODE_BLOCK}
+	renamed := map[string]string{}
+	for i, param := range candidate.params {
+		renamed[param.Value] = prefix + param.Value
+		bindings.Children = append(bindings.Children, &ASTNode{
+			Type:     NODE_VARIABLE_DECLARATION,
+			Value:    prefix + param.Value,
+			DataType: param.DataType,
+			Children: []*ASTNode{args[i]},
+			Line:     call.Line,
+		})
+	}
+	inlined := &ASTNode{
+		Type:     NODE_INLINE,
+		Value:    call.Value,
+		DataType: candidate.returnType,
+		Children: []*ASTNode{bindings, cloneRenamed(candidate.body, renamed, call.Line)},
+		Line:     call.Line,
+		Span:     call.Span,
+	}
+
+	// The body's own calls are inlined in turn
+	in.active[call.Value] = true
+	in.inlineCalls(inlined.Children[1], false)
+	if inlined.Children[1].Type == NODE_CALL {
+		if body := in.inline(inlined.Children[1]); body != nil {
+			inlined.Children[1] = body
+		}
+	}
+	delete(in.active, call.Value)
+	return inlined
+}
+
+// bindArguments returns the argument of a call for each of params: named
+// arguments go to their parameters, the rest in order, and parameters left
+// out take their defaults. Returns nil if the arguments don't fit.
+func bindArguments(call *ASTNode, params []*ASTNode) []*ASTNode {
+	args := make([]*ASTNode, len(params))
+	positional := 0
+	for _, arg := range call.Children {
+		if arg.Type == NODE_BINARY_OP && arg.Value == "named_arg" {
+			found := false
+			for i, param := range params {
+				if param.Value == arg.Children[0].Value && args[i] == nil {
+					args[i], found = arg.Children[1], true
+				}
+			}
+			if !found {
+				return nil
+			}
+			continue
+		}
+		for positional < len(args) && args[positional] != nil {
+			positional++
+		}
+		if positional == len(args) {
+			return nil
+		}
+		args[positional] = arg
+	}
+	for i, param := range params {
+		if args[i] == nil {
+			if param.DefaultValue == nil {
+				return nil
+			}
+			args[i] = param.DefaultValue
+		}
+	}
+	return args
+}
+
+// cloneRenamed copies an expression for a call site, renaming parameters
+func cloneRenamed(node *ASTNode, renamed map[string]string, line int) *ASTNode {
+	if node == nil {
+		return nil
+	}
+	clone := *node
+	clone.Line = line
+	if name, ok := renamed[node.Value]; ok && (node.Type == NODE_IDENTIFIER || node.Type == NODE_ARRAY_ACCESS) {
+		clone.Value = name
+	}
+	clone.Children = make([]*ASTNode, len(node.Children))
+	for i, child := range node.Children {
+		if node.Type == NODE_BINARY_OP && node.Value == "named_arg" && i == 0 {
+			// The name of the callee's parameter
+			clone.Children[i] = child
+			continue
+		}
+		clone.Children[i] = cloneRenamed(child, renamed, line)
+	}
+	return &clone
+}
+
+// generateInline writes an inlined call as a statement expression binding
+// the arguments to the parameters, then giving the body's value
+func (gen *CodeGenerator) generateInline(node *ASTNode) {
+	scope := gen.functionVars
+	if scope == nil {
+		scope = gen.variables
+	}
+	gen.output.WriteString("({ ")
+	for _, binding := range node.Children[0].Children {
+		gen.output.WriteString(fmt.Sprintf("%s %s = ", gen.mapType(binding.DataType), binding.Value))
+		gen.generateNode(binding.Children[0])
+		gen.output.WriteString("; ")
+		scope[binding.Value] = binding.DataType
+	}
+	gen.generateNode(node.Children[1])
+	gen.output.WriteString("; })")
+	for _, binding := range node.Children[0].Children {
+		delete(scope, binding.Value)
+	}
+}
@@ -0,0 +1,139 @@
+package ahoy
+
+import (
+	"fmt"
+)
+
+// iterProtocol is a struct's iterator: functions named after the struct
+// that loop item in container calls instead of reading an array or dict
+//
+//	@ list_iter_begin |l:list| C:              the cursor at the first item
+//	@ list_iter_next |l:list, at:C| E, C, bool: the item at the cursor, the
+//	                                             cursor after it, and false
+//	                                             once there are no more
+type iterProtocol struct {
+	begin, next string
+	cursorType  string
+	itemType    string
+}
+
+// structIterator returns the iterator the program declares for the struct
+// type structType, or nil when it declares none. Functions that don't fit
+// the protocol are reported.
+func (gen *CodeGenerator) structIterator(structType string, line int) *iterProtocol {
+	if _, isStruct := gen.structs[structType]; !isStruct {
+		return nil
+	}
+	protocol := &iterProtocol{begin: structType + "_iter_begin", next: structType + "_iter_next"}
+	hasBegin, hasNext := gen.userFunctions[protocol.begin], gen.userFunctions[protocol.next]
+	if !hasBegin && !hasNext {
+		return nil
+	}
+	hint := fmt.Sprintf("@ %s |c:%s| cursor: and @ %s |c:%s, at:cursor| item, cursor, bool:",
+		protocol.begin, structType, protocol.next, structType)
+	if !hasBegin {
+		gen.reportError(line, fmt.Sprintf("%s is looped over but has no %s", structType, protocol.begin), hint)
+		return nil
+	}
+	if !hasNext {
+		gen.reportError(line, fmt.Sprintf("%s is looped over but has no %s", structType, protocol.next), hint)
+		return nil
+	}
+
+	beginTypes := gen.functionReturnTypes[protocol.begin]
+	nextTypes := gen.functionReturnTypes[protocol.next]
+	// Parameter types are only known once a function is generated
+	beginParams, beginKnown := gen.functionParamTypes[protocol.begin]
+	nextParams, nextKnown := gen.functionParamTypes[protocol.next]
+	if len(beginTypes) != 1 || beginKnown && len(beginParams) != 1 {
+		gen.reportError(line, fmt.Sprintf("%s must take the %s and give back one cursor", protocol.begin, structType), hint)
+		return nil
+	}
+	if len(nextTypes) != 3 || nextTypes[2] != "bool" || nextTypes[1] != beginTypes[0] || nextKnown && len(nextParams) != 2 {
+		gen.reportError(line, fmt.Sprintf("%s must take the %s and a cursor and give back the item, the next cursor and a bool", protocol.next, structType), hint)
+		return nil
+	}
+	protocol.cursorType = beginTypes[0]
+	protocol.itemType = nextTypes[0]
+	return protocol
+}
+
+// generateForInStructLoop generates loop item in container for a struct
+// with an iterator, calling its functions for each item
+func (gen *CodeGenerator) generateForInStructLoop(node *ASTNode, protocol *iterProtocol, structType string) {
+	elementVar := node.Children[0].Value
+	iterableExpr := node.Children[1]
+	line := nodeLine(node)
+
+	// The container is evaluated once, and the call nodes refer to it by name
+	container := iterableExpr
+	gen.output.WriteString("{\n")
+	gen.indent++
+	if iterableExpr.Type != NODE_IDENTIFIER {
+		name := fmt.Sprintf("__iter_%d", gen.varCounter)
+		gen.varCounter++
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s %s = ", gen.mapType(structType), name))
+		gen.generateNode(iterableExpr)
+		gen.output.WriteString(";\n")
+		gen.variables[name] = structType
+		defer delete(gen.variables, name)
+		container = &ASTNode{Type: NODE_IDENTIFIER, Value: name, Line: line}
+	}
+
+	cursor := fmt.Sprintf("__cursor_%d", gen.varCounter)
+	next := fmt.Sprintf("__next_%d", gen.varCounter)
+	gen.varCounter++
+	gen.variables[cursor] = protocol.cursorType
+	defer delete(gen.variables, cursor)
+
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s %s = ", gen.mapType(protocol.cursorType), cursor))
+	gen.generateNode(&ASTNode{Type: NODE_CALL, Value: protocol.begin, Line: line, Children: []*ASTNode{container}})
+	gen.output.WriteString(";\n")
+	gen.writeIndent()
+	gen.output.WriteString("for (;;) {\n")
+	gen.indent++
+
+	// The cursor moves on before the body runs, so skip goes to the next item
+	gen.writeIndent()
+	call := &ASTNode{Type: NODE_CALL, Value: protocol.next, Line: line, Children: []*ASTNode{
+		container, {Type: NODE_IDENTIFIER, Value: cursor, Line: line},
+	}}
+	if gen.outParams[protocol.next] {
+		gen.output.WriteString(fmt.Sprintf("%s_return %s;\n", protocol.next, next))
+		gen.writeIndent()
+		gen.callOut = next
+	} else {
+		gen.output.WriteString(fmt.Sprintf("%s_return %s = ", protocol.next, next))
+	}
+	gen.generateNode(call)
+	gen.output.WriteString(";\n")
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("if (!%s.ret2) break;\n", next))
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s = %s.ret1;\n", cursor, next))
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s %s = %s.ret0;\n", gen.mapType(protocol.itemType), elementVar, next))
+
+	// Register loop variable for type inference
+	oldType := gen.variables[elementVar]
+	gen.variables[elementVar] = protocol.itemType
+
+	gen.markUnused(elementVar)
+	gen.generateNodeInternal(node.Children[2], false)
+
+	// Restore old type
+	if oldType != "" {
+		gen.variables[elementVar] = oldType
+	} else {
+		delete(gen.variables, elementVar)
+	}
+
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
@@ -0,0 +1,203 @@
+package ahoy
+
+import (
+	"bufio"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LeakFrame is one call in the stack of a leaked allocation
+type LeakFrame struct {
+	Function string
+	File     string // base name of the C file, empty when valgrind only knows the library
+	Line     int
+}
+
+// LeakRecord is one loss record of a valgrind leak report
+type LeakRecord struct {
+	Kind   string // "definitely lost", "indirectly lost", "possibly lost" or "still reachable"
+	Bytes  int
+	Blocks int
+	Stack  []LeakFrame // innermost call first
+}
+
+// Leak is a loss record traced back to the Ahoy code that allocated it
+type Leak struct {
+	LeakRecord
+	Helper    string // the runtime helper that allocated, or the C function for generated code
+	Construct string // what the allocation is in Ahoy terms, like "array literal"
+	File      string // Ahoy file and line of the statement, when the C was built readable
+	Line      int
+}
+
+// leakHelpers are the runtime helpers that allocate, with what they build
+var leakHelpers = map[string]string{
+	"createArray":               "array",
+	"arrayPush":                 "array push",
+	"ahoy_array_push":           "array push",
+	"ahoy_array_fill":           "array fill",
+	"ahoy_array_clone":          "array copy",
+	"ahoy_range_to_array":       "range to array",
+	"createHashMap":             "dict",
+	"hashMapPut":                "dict assignment",
+	"hashMapPutTyped":           "dict assignment",
+	"ahoy_dict_keys":            "dict keys",
+	"ahoy_dict_values":          "dict values",
+	"ahoy_dict_sort":            "dict sort",
+	"ahoy_dict_clone":           "dict copy",
+	"ahoy_box_float":            "float in an array or dict",
+	"ahoy_box_struct":           "struct in an array or dict",
+	"ahoy_slot_clone":           "array or dict element copy",
+	"ahoy_bytes_new":            "bytes",
+	"ahoy_bytes_format":         "bytes formatting",
+	"ahoy_json_buffer_append":   "JSON encoding",
+	"ahoy_format":               "string formatting",
+	"ahoy_thousands":            "thousands formatting",
+	"print_array_helper":        "array formatting",
+	"print_string_array_helper": "array formatting",
+	"print_dict_helper":         "dict formatting",
+}
+
+// leakPatterns name the allocations generated code makes inline, by the C
+// they are written with
+var leakPatterns = []struct {
+	code      string
+	construct string
+}{
+	{"malloc(sizeof(AhoyArray))", "array literal"},
+	{"__cast_buf", "conversion to string"},
+	{"__type_str", "type name"},
+}
+
+var (
+	lossRecordPattern = regexp.MustCompile(`^([\d,]+) (?:\(([\d,]+) direct, ([\d,]+) indirect\) )?bytes in ([\d,]+) blocks are (.+?) in loss record`)
+	leakFramePattern  = regexp.MustCompile(`^(?:at|by) 0x[0-9A-Fa-f]+: (.+?) \((.+)\)$`)
+	leakSourcePattern = regexp.MustCompile(`^(.+):(\d+)$`)
+	ahoyLinePattern   = regexp.MustCompile(`^\s*// (\S+\.ahoy):(\d+):`)
+)
+
+// ParseLeakReport reads the loss records from valgrind's output. Lines
+// that aren't part of a record are skipped, so the whole of valgrind's
+// stderr can be passed.
+func ParseLeakReport(report string) []LeakRecord {
+	var records []LeakRecord
+	var current *LeakRecord
+	scanner := bufio.NewScanner(strings.NewReader(report))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Valgrind starts each line with ==pid==
+		if strings.HasPrefix(line, "==") {
+			if end := strings.Index(line[2:], "=="); end >= 0 {
+				line = line[end+4:]
+			}
+		}
+		line = strings.TrimSpace(line)
+		if match := lossRecordPattern.FindStringSubmatch(line); match != nil {
+			records = append(records, LeakRecord{
+				Kind:   match[5],
+				Bytes:  leakNumber(match[1]),
+				Blocks: leakNumber(match[4]),
+			})
+			current = &records[len(records)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		match := leakFramePattern.FindStringSubmatch(line)
+		if match == nil {
+			current = nil
+			continue
+		}
+		frame := LeakFrame{Function: match[1]}
+		if source := leakSourcePattern.FindStringSubmatch(match[2]); source != nil && !strings.HasPrefix(match[2], "in ") {
+			frame.File = filepath.Base(source[1])
+			frame.Line, _ = strconv.Atoi(source[2])
+		}
+		current.Stack = append(current.Stack, frame)
+	}
+	return records
+}
+
+func leakNumber(text string) int {
+	n, _ := strconv.Atoi(strings.ReplaceAll(text, ",", ""))
+	return n
+}
+
+// SummarizeLeaks keeps the records allocated by the program's C file or its
+// runtime and names what each is. cCode is the C that was compiled; when it
+// was built with ReadableC, its comments map each leak to the Ahoy statement
+// that made it.
+func SummarizeLeaks(records []LeakRecord, cFile string, cCode string) []Leak {
+	base := filepath.Base(cFile)
+	cLines := strings.Split(cCode, "\n")
+	statements := ahoyStatements(cLines)
+
+	var leaks []Leak
+	for _, record := range records {
+		leak := Leak{LeakRecord: record}
+		found := false
+		for _, frame := range record.Stack {
+			if frame.File != base && frame.File != RuntimeFile {
+				continue
+			}
+			if !found {
+				found = true
+				leak.Helper = frame.Function
+				leak.Construct = leakConstruct(frame, cLines)
+			}
+			if frame.File == base && frame.Line > 0 && frame.Line <= len(statements) && statements[frame.Line-1].line > 0 {
+				leak.File = statements[frame.Line-1].file
+				leak.Line = statements[frame.Line-1].line
+				break
+			}
+		}
+		if found {
+			leaks = append(leaks, leak)
+		}
+	}
+	return leaks
+}
+
+// leakConstruct names the allocation a frame makes, from its helper or the
+// C on its line
+func leakConstruct(frame LeakFrame, cLines []string) string {
+	if construct, ok := leakHelpers[frame.Function]; ok {
+		return construct
+	}
+	if frame.File == RuntimeFile || frame.Line <= 0 || frame.Line > len(cLines) {
+		return ""
+	}
+	for _, pattern := range leakPatterns {
+		if strings.Contains(cLines[frame.Line-1], pattern.code) {
+			return pattern.construct
+		}
+	}
+	return ""
+}
+
+// ahoyLine is a line of an Ahoy file
+type ahoyLine struct {
+	file string
+	line int
+}
+
+// ahoyStatements gives each line of readable C the Ahoy statement it was
+// generated from: the nearest statement comment above it in its function
+func ahoyStatements(cLines []string) []ahoyLine {
+	statements := make([]ahoyLine, len(cLines))
+	var current ahoyLine
+	for i, line := range cLines {
+		if strings.HasPrefix(line, "}") {
+			current = ahoyLine{}
+		}
+		if match := ahoyLinePattern.FindStringSubmatch(line); match != nil {
+			current.file = match[1]
+			current.line, _ = strconv.Atoi(match[2])
+		}
+		statements[i] = current
+	}
+	return statements
+}
@@ -0,0 +1,78 @@
+package ahoy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseLeakReport(t *testing.T) {
+	report := `==4242== Memcheck, a memory error detector
+==4242== HEAP SUMMARY:
+==4242==     in use at exit: 1,136 bytes in 4 blocks
+==4242==
+==4242== 48 (24 direct, 24 indirect) bytes in 1 blocks are definitely lost in loss record 2 of 3
+==4242==    at 0x4848899: malloc (in /usr/libexec/valgrind/vgpreload_memcheck-amd64-linux.so)
+==4242==    by 0x1092A4: createArray (main.c:41)
+==4242==    by 0x10A1F0: main (main.c:310)
+==4242==
+==4242== 1,024 bytes in 1 blocks are still reachable in loss record 3 of 3
+==4242==    at 0x4848899: malloc (in /usr/libexec/valgrind/vgpreload_memcheck-amd64-linux.so)
+==4242==    by 0x48E0D23: _IO_file_doallocate (filedoalloc.c:101)
+==4242==
+==4242== LEAK SUMMARY:
+`
+	records := ParseLeakReport(report)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	first := records[0]
+	if first.Kind != "definitely lost" || first.Bytes != 48 || first.Blocks != 1 || len(first.Stack) != 3 {
+		t.Errorf("unexpected first record %+v", first)
+	}
+	if frame := first.Stack[1]; frame != (LeakFrame{Function: "createArray", File: "main.c", Line: 41}) {
+		t.Errorf("unexpected frame %+v", frame)
+	}
+	if frame := first.Stack[0]; frame.Function != "malloc" || frame.File != "" {
+		t.Errorf("expected a library frame without a file, got %+v", frame)
+	}
+	if records[1].Kind != "still reachable" || records[1].Bytes != 1024 {
+		t.Errorf("unexpected second record %+v", records[1])
+	}
+}
+
+func TestSummarizeLeaks(t *testing.T) {
+	path := writeSource(t, "@ main :: ||:\n    total: 0\n    nums: [1, 2, 3]\n    print|nums|\n$\n")
+	artifacts, diagnostics, err := Build(BuildOptions{Source: path, OutputDir: t.TempDir(), ReadableC: true})
+	if err != nil {
+		t.Fatalf("Build: %v %v", err, diagnostics)
+	}
+	literal := 0
+	for i, line := range strings.Split(artifacts.CCode, "\n") {
+		if strings.Contains(line, "malloc(sizeof(AhoyArray))") && literal == 0 {
+			literal = i + 1
+		}
+	}
+	if literal == 0 {
+		t.Fatalf("expected an array literal in the C:\n%s", artifacts.CCode)
+	}
+
+	report := fmt.Sprintf(`==1== 24 bytes in 1 blocks are definitely lost in loss record 1 of 2
+==1==    at 0x4848899: malloc (in /usr/libexec/valgrind/vgpreload_memcheck-amd64-linux.so)
+==1==    by 0x1092A4: ahoy_main (main.c:%d)
+==1==    by 0x10A1F0: main (main.c:1)
+==1==
+==1== 1,024 bytes in 1 blocks are possibly lost in loss record 2 of 2
+==1==    at 0x4848899: malloc (in /usr/libexec/valgrind/vgpreload_memcheck-amd64-linux.so)
+==1==    by 0x48E0D23: _IO_file_doallocate (filedoalloc.c:101)
+==1==
+`, literal)
+	leaks := SummarizeLeaks(ParseLeakReport(report), artifacts.CFile, artifacts.CCode)
+	if len(leaks) != 1 {
+		t.Fatalf("expected the library record to be left out, got %+v", leaks)
+	}
+	leak := leaks[0]
+	if leak.Construct != "array literal" || leak.File != "main.ahoy" || leak.Line != 3 || leak.Bytes != 24 {
+		t.Errorf("unexpected leak %+v", leak)
+	}
+}
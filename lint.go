@@ -0,0 +1,1071 @@
+package ahoy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lint severities. Rules set to off are skipped; errors make -lint fail.
+const (
+	SeverityOff     = "off"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// LintConfigFile is looked up from the source directory upwards
+const LintConfigFile = ".ahoylint"
+
+// LintWarning is a finding reported by -lint
+type LintWarning struct {
+	Rule     string
+	Severity string
+	Message  string
+	Line     int
+	Column   int
+	Edits    []LintEdit // applied by -lint -fix; empty when there is no safe fix
+}
+
+// LintEdit replaces Length bytes starting at Line:Column (both 1-based, the
+// column counted from the start of the line) with NewText
+type LintEdit struct {
+	Line    int
+	Column  int
+	Length  int
+	NewText string
+}
+
+// LintRule is one check run by the linter. Check reports findings through
+// the context; the severity is filled in from the config.
+type LintRule struct {
+	Name        string
+	Description string
+	Severity    string // default severity
+	Options     map[string]string
+	Check       func(ast *ASTNode, ctx *LintContext)
+}
+
+// LintContext is handed to a rule while it runs
+type LintContext struct {
+	rule     *LintRule
+	config   *LintConfig
+	input    *lintInput
+	findings []LintWarning
+}
+
+// lintInput is the source shared by every rule in one Lint call
+type lintInput struct {
+	source string
+	lines  []string
+	tokens []Token
+}
+
+// Report records a finding at node's line
+func (ctx *LintContext) Report(node *ASTNode, format string, args ...interface{}) {
+	ctx.ReportFix(node, nil, format, args...)
+}
+
+// ReportFix records a finding that -lint -fix can apply
+func (ctx *LintContext) ReportFix(node *ASTNode, edits []LintEdit, format string, args ...interface{}) {
+	ctx.findings = append(ctx.findings, LintWarning{
+		Rule:    ctx.rule.Name,
+		Message: fmt.Sprintf(format, args...),
+		Line:    nodeLine(node),
+		Column:  node.Column,
+		Edits:   edits,
+	})
+}
+
+// reportToken records a finding at a token, for rules that work on tokens
+func (ctx *LintContext) reportToken(token Token, edits []LintEdit, format string, args ...interface{}) {
+	ctx.ReportFix(&ASTNode{Line: token.Line, Column: token.Column}, edits, format, args...)
+}
+
+// Tokens returns the tokens of the linted source
+func (ctx *LintContext) Tokens() []Token {
+	if ctx.input.tokens == nil {
+		ctx.input.tokens = Tokenize(ctx.input.source)
+	}
+	return ctx.input.tokens
+}
+
+// tokenEdit replaces a token
+func (ctx *LintContext) tokenEdit(token Token, length int, newText string) LintEdit {
+	return LintEdit{Line: token.Line, Column: token.Span.StartColumn, Length: length, NewText: newText}
+}
+
+// Option returns the configured value of a rule option, or its default
+func (ctx *LintContext) Option(name string) string {
+	if value, ok := ctx.config.Options[ctx.rule.Name+"."+name]; ok {
+		return value
+	}
+	return ctx.rule.Options[name]
+}
+
+// IntOption is Option for numeric settings
+func (ctx *LintContext) IntOption(name string) int {
+	value, err := strconv.Atoi(ctx.Option(name))
+	if err != nil {
+		value, _ = strconv.Atoi(ctx.rule.Options[name])
+	}
+	return value
+}
+
+var lintRules = []*LintRule{}
+
+// RegisterLintRule adds a rule to the linter. Rules run in registration order.
+func RegisterLintRule(rule LintRule) {
+	for i, existing := range lintRules {
+		if existing.Name == rule.Name {
+			lintRules[i] = &rule
+			return
+		}
+	}
+	lintRules = append(lintRules, &rule)
+}
+
+// LintRules lists the registered rules
+func LintRules() []LintRule {
+	rules := make([]LintRule, len(lintRules))
+	for i, rule := range lintRules {
+		rules[i] = *rule
+	}
+	return rules
+}
+
+func findLintRule(name string) *LintRule {
+	for _, rule := range lintRules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// LintConfig overrides rule severities and options
+type LintConfig struct {
+	Severities map[string]string // rule -> severity
+	Options    map[string]string // "rule.option" -> value
+}
+
+// DefaultLintConfig runs every rule at its default severity
+func DefaultLintConfig() *LintConfig {
+	return &LintConfig{Severities: map[string]string{}, Options: map[string]string{}}
+}
+
+// FindLintConfig returns the nearest .ahoylint in dir or a parent, or ""
+func FindLintConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, LintConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadLintConfig reads a lint config. Each line is `rule: severity` or
+// `rule.option: value`; `?` starts a comment.
+func LoadLintConfig(path string) (*LintConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config := DefaultLintConfig()
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if idx := strings.Index(line, "?"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("%s:%d: expected 'rule: severity'", path, lineNum)
+		}
+		key := strings.TrimSpace(line[:colon])
+		value := strings.TrimSpace(line[colon+1:])
+
+		ruleName, option, isOption := strings.Cut(key, ".")
+		rule := findLintRule(ruleName)
+		if rule == nil {
+			return nil, fmt.Errorf("%s:%d: unknown lint rule '%s'", path, lineNum, ruleName)
+		}
+		if isOption {
+			if _, known := rule.Options[option]; !known {
+				return nil, fmt.Errorf("%s:%d: rule '%s' has no option '%s'", path, lineNum, ruleName, option)
+			}
+			config.Options[key] = value
+			continue
+		}
+		if value != SeverityOff && value != SeverityWarning && value != SeverityError {
+			return nil, fmt.Errorf("%s:%d: severity must be off, warning or error, got '%s'", path, lineNum, value)
+		}
+		config.Severities[ruleName] = value
+	}
+	return config, scanner.Err()
+}
+
+// Lint runs the enabled rules over a parsed program. source is the text the
+// AST was parsed from; it is scanned for `?ahoy:ignore` comments.
+func Lint(ast *ASTNode, source string, config *LintConfig) []LintWarning {
+	findings := []LintWarning{}
+	if ast == nil {
+		return findings
+	}
+	if config == nil {
+		config = DefaultLintConfig()
+	}
+
+	ignored := parseIgnoreComments(source)
+	input := &lintInput{source: source, lines: strings.Split(source, "\n")}
+	for _, rule := range lintRules {
+		severity := rule.Severity
+		if configured, ok := config.Severities[rule.Name]; ok {
+			severity = configured
+		}
+		if severity == SeverityOff {
+			continue
+		}
+
+		ctx := &LintContext{rule: rule, config: config, input: input}
+		rule.Check(ast, ctx)
+		for _, finding := range ctx.findings {
+			if ignored[finding.Line][rule.Name] || ignored[finding.Line][""] {
+				continue
+			}
+			finding.Severity = severity
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// ApplyLintEdits applies the fixes attached to findings and returns the new
+// source and how many findings were fixed. A fix that overlaps one already
+// taken is skipped; running -fix again picks it up.
+func ApplyLintEdits(source string, findings []LintWarning) (string, int) {
+	lineStarts := []int{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(edit LintEdit) int {
+		if edit.Line < 1 || edit.Line > len(lineStarts) {
+			return -1
+		}
+		return lineStarts[edit.Line-1] + edit.Column - 1
+	}
+
+	type span struct {
+		start, end int
+		text       string
+	}
+	var spans []span
+	overlaps := func(start, end int) bool {
+		for _, taken := range spans {
+			if start < taken.end && taken.start < end || start == taken.start {
+				return true
+			}
+		}
+		return false
+	}
+
+	fixed := 0
+	for _, finding := range findings {
+		if len(finding.Edits) == 0 {
+			continue
+		}
+		var pending []span
+		valid := true
+		for _, edit := range finding.Edits {
+			start := offset(edit)
+			end := start + edit.Length
+			if start < 0 || end > len(source) || overlaps(start, end) {
+				valid = false
+				break
+			}
+			pending = append(pending, span{start, end, edit.NewText})
+		}
+		if valid {
+			spans = append(spans, pending...)
+			fixed++
+		}
+	}
+
+	// Apply from the end so earlier offsets stay valid
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+	for _, edit := range spans {
+		source = source[:edit.start] + edit.text + source[edit.end:]
+	}
+	return source, fixed
+}
+
+var ignoreComment = regexp.MustCompile(`\?\s*ahoy:ignore\b([\w\-, ]*)`)
+
+// parseIgnoreComments maps line -> suppressed rules ("" suppresses all). A
+// comment on its own line applies to the next line, otherwise to its own.
+func parseIgnoreComments(source string) map[int]map[string]bool {
+	ignored := map[int]map[string]bool{}
+	for i, line := range strings.Split(source, "\n") {
+		match := ignoreComment.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		target := i + 1
+		if strings.HasPrefix(strings.TrimSpace(line), "?") {
+			target = i + 2
+		}
+		if ignored[target] == nil {
+			ignored[target] = map[string]bool{}
+		}
+		rules := strings.FieldsFunc(line[match[2]:match[3]], func(r rune) bool { return r == ',' || r == ' ' })
+		if len(rules) == 0 {
+			ignored[target][""] = true
+		}
+		for _, rule := range rules {
+			ignored[target][rule] = true
+		}
+	}
+	return ignored
+}
+
+// nodeLine finds a line for nodes the parser leaves at 0, such as operators
+func nodeLine(node *ASTNode) int {
+	if node == nil {
+		return 0
+	}
+	if node.Line > 0 {
+		return node.Line
+	}
+	if node.Span.StartLine > 0 {
+		return node.Span.StartLine
+	}
+	for _, child := range node.Children {
+		if line := nodeLine(child); line > 0 {
+			return line
+		}
+	}
+	return 0
+}
+
+// walkAST calls visit for node and every descendant; returning false skips
+// the node's children
+func walkAST(node *ASTNode, visit func(node *ASTNode) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	for _, child := range node.Children {
+		walkAST(child, visit)
+	}
+}
+
+func init() {
+	RegisterLintRule(LintRule{
+		Name:        "alias-mutation",
+		Description: "an array or dict copied by plain assignment is changed through the copy",
+		Severity:    SeverityWarning,
+		Check: func(ast *ASTNode, ctx *LintContext) {
+			checkAliasMutation(ast, newAliasScope(), ctx)
+		},
+	})
+	RegisterLintRule(LintRule{
+		Name:        "naming",
+		Description: "variables, parameters and functions use snake_case; constants use UPPER_CASE",
+		Severity:    SeverityWarning,
+		Check:       checkNaming,
+	})
+	RegisterLintRule(LintRule{
+		Name:        "unused-result",
+		Description: "the result of a call with no side effects is thrown away",
+		Severity:    SeverityWarning,
+		Check:       checkUnusedResult,
+	})
+	RegisterLintRule(LintRule{
+		Name:        "unused-import",
+		Description: "a namespaced import is never used, or a file is imported twice",
+		Severity:    SeverityWarning,
+		Check:       checkUnusedImports,
+	})
+	RegisterLintRule(LintRule{
+		Name:        "equality-style",
+		Description: "== and != are written instead of is and is not",
+		Severity:    SeverityWarning,
+		Check:       checkEqualityStyle,
+	})
+	RegisterLintRule(LintRule{
+		Name:        "magic-number",
+		Description: "a numeric literal appears in an expression instead of a named constant",
+		Severity:    SeverityOff,
+		Options:     map[string]string{"allowed": "0, 1, 2"},
+		Check:       checkMagicNumbers,
+	})
+	RegisterLintRule(LintRule{
+		Name:        "function-length",
+		Description: "a function body is longer than max lines",
+		Severity:    SeverityWarning,
+		Options:     map[string]string{"max": "50"},
+		Check:       checkFunctionLength,
+	})
+	RegisterLintRule(LintRule{
+		Name:        "raylib-unload",
+		Description: "a raylib texture, sound or other resource is loaded but never unloaded",
+		Severity:    SeverityWarning,
+		Check:       checkRaylibUnload,
+	})
+	RegisterLintRule(LintRule{
+		Name:        "raylib-drawing",
+		Description: "a raylib draw call is made outside begin_drawing||/end_drawing||",
+		Severity:    SeverityWarning,
+		Check:       checkRaylibDrawing,
+	})
+}
+
+// Methods that change an array or dict in place
+var mutatingMethods = map[string]bool{
+	"push": true, "pop": true, "fill": true, "sort": true,
+	"reverse": true, "shuffle": true, "clear": true,
+}
+
+// aliasScope tracks container variables and plain copies of them. Arrays and
+// dicts are references, so `b: a` makes b share a's storage.
+type aliasScope struct {
+	containers map[string]bool   // variables holding an array or dict
+	aliases    map[string]string // alias -> variable it was copied from
+	reported   map[string]bool
+}
+
+func newAliasScope() *aliasScope {
+	return &aliasScope{containers: map[string]bool{}, aliases: map[string]string{}, reported: map[string]bool{}}
+}
+
+func checkAliasMutation(node *ASTNode, scope *aliasScope, ctx *LintContext) {
+	if node == nil {
+		return
+	}
+
+	switch node.Type {
+	case NODE_FUNCTION:
+		// Functions get their own scope
+		inner := newAliasScope()
+		for _, child := range node.Children {
+			checkAliasMutation(child, inner, ctx)
+		}
+		return
+
+	case NODE_ASSIGNMENT:
+		if node.Value != "" && len(node.Children) > 0 {
+			name := node.Value
+			value := node.Children[0]
+			delete(scope.aliases, name)
+			delete(scope.containers, name)
+			switch {
+			case value.Type == NODE_IDENTIFIER && scope.containers[value.Value]:
+				scope.aliases[name] = value.Value
+				scope.containers[name] = true
+			case isContainerValue(value) || isContainerType(node.DataType):
+				scope.containers[name] = true
+			}
+		} else if len(node.Children) > 0 {
+			// Element assignment: b[0]: v, b<"k">: v, b{"k"}: v
+			target := node.Children[0]
+			if target.Type == NODE_ARRAY_ACCESS || target.Type == NODE_DICT_ACCESS || target.Type == NODE_OBJECT_ACCESS {
+				scope.reportAlias(target.Value, node, ctx)
+			}
+		}
+
+	case NODE_METHOD_CALL:
+		if mutatingMethods[node.Value] && len(node.Children) > 0 && node.Children[0].Type == NODE_IDENTIFIER {
+			scope.reportAlias(node.Children[0].Value, node, ctx)
+		}
+	}
+
+	for _, child := range node.Children {
+		checkAliasMutation(child, scope, ctx)
+	}
+}
+
+func (scope *aliasScope) reportAlias(name string, node *ASTNode, ctx *LintContext) {
+	source, isAlias := scope.aliases[name]
+	if !isAlias || scope.reported[name] {
+		return
+	}
+	scope.reported[name] = true
+	ctx.Report(node, "'%s' shares storage with '%s', so this also changes '%s'; use %s.clone|| for an independent copy",
+		name, source, source, source)
+}
+
+// isContainerValue reports whether an expression produces an array or dict
+func isContainerValue(node *ASTNode) bool {
+	switch node.Type {
+	case NODE_ARRAY_LITERAL, NODE_DICT_LITERAL:
+		return true
+	case NODE_METHOD_CALL:
+		return node.Value == "clone" || node.Value == "keys" || node.Value == "values"
+	}
+	return false
+}
+
+func isContainerType(dataType string) bool {
+	return dataType == "array" || dataType == "dict" || strings.HasPrefix(dataType, "array[") ||
+		strings.HasPrefix(dataType, "dict<") || strings.HasPrefix(dataType, "dict[")
+}
+
+var (
+	snakeCase = regexp.MustCompile(`^_*[a-z][a-z0-9_]*$`)
+	upperCase = regexp.MustCompile(`^_*[A-Z][A-Z0-9_]*$`)
+)
+
+func checkNaming(ast *ASTNode, ctx *LintContext) {
+	seen := map[string]bool{}
+	check := func(node *ASTNode, name, kind string, pattern *regexp.Regexp, style string) {
+		if name == "" || name == "_" || seen[name] || pattern.MatchString(name) {
+			return
+		}
+		seen[name] = true
+
+		renamed := toSnakeCase(name)
+		if pattern == upperCase {
+			renamed = strings.ToUpper(renamed)
+		}
+		ctx.ReportFix(node, renameEdits(ctx.input.lines, name, renamed), "%s '%s' should be %s (%s)", kind, name, style, renamed)
+	}
+
+	walkAST(ast, func(node *ASTNode) bool {
+		switch node.Type {
+		case NODE_ASSIGNMENT:
+			check(node, node.Value, "variable", snakeCase, "snake_case")
+		case NODE_TUPLE_ASSIGNMENT:
+			if len(node.Children) > 0 {
+				for _, target := range node.Children[0].Children {
+					if target.Type == NODE_IDENTIFIER {
+						check(target, target.Value, "variable", snakeCase, "snake_case")
+					}
+				}
+			}
+		case NODE_CONSTANT_DECLARATION:
+			check(node, node.Value, "constant", upperCase, "UPPER_CASE")
+		case NODE_FUNCTION:
+			check(node, node.Value, "function", snakeCase, "snake_case")
+			if len(node.Children) > 0 {
+				for _, param := range node.Children[0].Children {
+					check(param, param.Value, "parameter", snakeCase, "snake_case")
+				}
+			}
+		}
+		return true
+	})
+}
+
+// toSnakeCase splits words at case changes: myVar -> my_var, HTTPPort -> http_port
+func toSnakeCase(name string) string {
+	var result strings.Builder
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		if ch >= 'A' && ch <= 'Z' && i > 0 && name[i-1] != '_' {
+			prev := name[i-1]
+			nextIsLower := i+1 < len(name) && name[i+1] >= 'a' && name[i+1] <= 'z'
+			if (prev >= 'a' && prev <= 'z') || (prev >= '0' && prev <= '9') || (prev >= 'A' && prev <= 'Z' && nextIsLower) {
+				result.WriteByte('_')
+			}
+		}
+		result.WriteByte(ch)
+	}
+	return strings.ToLower(result.String())
+}
+
+// renameEdits renames every use of an identifier. It returns nil when the
+// rename isn't safe: the new name is taken, or the old one is also used as a
+// field or method name after a dot.
+func renameEdits(lines []string, oldName, newName string) []LintEdit {
+	if newName == oldName || newName == "" {
+		return nil
+	}
+	var edits []LintEdit
+	for i, line := range lines {
+		for _, word := range scanIdentifiers(line) {
+			switch {
+			case word.name == newName:
+				return nil
+			case word.name != oldName:
+				continue
+			case word.afterDot:
+				return nil
+			}
+			edits = append(edits, LintEdit{Line: i + 1, Column: word.column, Length: len(oldName), NewText: newName})
+		}
+	}
+	return edits
+}
+
+type identifierUse struct {
+	name     string
+	column   int // 1-based
+	afterDot bool
+}
+
+// scanIdentifiers lists the identifiers on a line, skipping comments, string
+// and char literals but looking inside f-string {} holes
+func scanIdentifiers(line string) []identifierUse {
+	var words []identifierUse
+	isWordStart := func(ch byte) bool { return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' }
+	isWordChar := func(ch byte) bool { return isWordStart(ch) || ch >= '0' && ch <= '9' }
+
+	inString, inFString, holeDepth := false, false, 0
+	lastCode := byte(0) // last non-space character outside literals
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		if inString || (inFString && holeDepth == 0) {
+			switch ch {
+			case '\\':
+				i++
+			case '{':
+				if inFString {
+					holeDepth = 1
+					lastCode = ch
+				}
+			case '"':
+				inString, inFString = false, false
+			}
+			continue
+		}
+
+		switch {
+		case ch == '?' && i+1 < len(line) && line[i+1] == '?':
+			i++
+		case ch == '?':
+			return words
+		case ch == 'f' && i+1 < len(line) && line[i+1] == '"' && (i == 0 || !isWordChar(line[i-1])):
+			inFString = true
+			i++
+		case ch == '"':
+			inString = true
+		case ch == '\'':
+			if end := strings.IndexByte(line[i+1:], '\''); end >= 0 {
+				i += end + 1
+			}
+		case inFString && ch == '{':
+			holeDepth++
+		case inFString && ch == '}':
+			holeDepth--
+		case isWordStart(ch) && (i == 0 || !isWordChar(line[i-1])):
+			start := i
+			for i+1 < len(line) && isWordChar(line[i+1]) {
+				i++
+			}
+			words = append(words, identifierUse{name: line[start : i+1], column: start + 1, afterDot: lastCode == '.'})
+		}
+		if ch != ' ' && ch != '\t' {
+			lastCode = ch
+		}
+	}
+	return words
+}
+
+func checkUnusedImports(ast *ASTNode, ctx *LintContext) {
+	tokens := ctx.Tokens()
+	used := map[string]bool{}
+	for i, token := range tokens {
+		// Skip the namespace in the import itself
+		if token.Type == TOKEN_IDENTIFIER && (i == 0 || tokens[i-1].Type != TOKEN_IMPORT) {
+			used[token.Value] = true
+		}
+	}
+
+	imported := map[string]bool{}
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Type != TOKEN_IMPORT {
+			continue
+		}
+		namespace := ""
+		pathToken := tokens[i+1]
+		if pathToken.Type == TOKEN_IDENTIFIER && i+2 < len(tokens) {
+			namespace = pathToken.Value
+			pathToken = tokens[i+2]
+		}
+		if pathToken.Type != TOKEN_STRING {
+			continue
+		}
+
+		// Only the import is on the line, so the fix drops the whole line
+		var edits []LintEdit
+		line := tokens[i].Line
+		if line-1 < len(ctx.input.lines) && line < len(ctx.input.lines) {
+			edits = []LintEdit{{Line: line, Column: 1, Length: len(ctx.input.lines[line-1]) + 1}}
+		}
+
+		switch {
+		case imported[pathToken.Value]:
+			ctx.reportToken(tokens[i], edits, "\"%s\" is already imported", pathToken.Value)
+		case namespace != "" && !used[namespace]:
+			ctx.reportToken(tokens[i], edits, "import %s \"%s\" is never used", namespace, pathToken.Value)
+		}
+		imported[pathToken.Value] = true
+	}
+}
+
+func checkEqualityStyle(ast *ASTNode, ctx *LintContext) {
+	for _, token := range ctx.Tokens() {
+		if token.Type != TOKEN_IS || (token.Value != "==" && token.Value != "!=") {
+			continue
+		}
+		word := "is"
+		if token.Value == "!=" {
+			word = "is not"
+		}
+		ctx.reportToken(token, []LintEdit{ctx.tokenEdit(token, 2, word)}, "use '%s' instead of '%s'", word, token.Value)
+	}
+}
+
+// Builtins and methods that only compute a value
+var pureFunctions = map[string]bool{
+	"parse_int": true, "parse_float": true, "json_encode": true, "range": true,
+}
+
+var pureMethods = map[string]bool{
+	"length": true, "upper": true, "lower": true, "replace": true, "contains": true,
+	"camel_case": true, "snake_case": true, "pascal_case": true, "kebab_case": true,
+	"match": true, "join": true, "split": true, "count": true, "lpad": true, "rpad": true,
+	"pad": true, "strip": true, "get_file": true, "clone": true, "keys": true,
+	"values": true, "has": true, "has_all": true, "sum": true, "map": true,
+	"filter": true, "size": true, "map_values": true, "to_array": true, "get": true,
+	"get_or": true,
+}
+
+func checkUnusedResult(ast *ASTNode, ctx *LintContext) {
+	pure := pureUserFunctions(ast)
+
+	// Method arguments and tuple targets are also blocks, but not statements
+	argumentBlocks := map[*ASTNode]bool{}
+	walkAST(ast, func(node *ASTNode) bool {
+		if node.Type == NODE_METHOD_CALL || node.Type == NODE_TUPLE_ASSIGNMENT {
+			for _, child := range node.Children {
+				argumentBlocks[child] = true
+			}
+		}
+		return true
+	})
+
+	walkAST(ast, func(node *ASTNode) bool {
+		if (node.Type != NODE_PROGRAM && node.Type != NODE_BLOCK) || argumentBlocks[node] {
+			return true
+		}
+		// Children of a program or block are statements
+		for _, stmt := range node.Children {
+			switch {
+			case stmt.Type == NODE_CALL && (pureFunctions[stmt.Value] || pure[stmt.Value]):
+				ctx.Report(stmt, "result of %s|...| is not used", stmt.Value)
+			case stmt.Type == NODE_METHOD_CALL && pureMethods[stmt.Value]:
+				ctx.Report(stmt, "result of .%s|...| is not used", stmt.Value)
+			}
+		}
+		return true
+	})
+}
+
+// pureUserFunctions finds functions that return a value and only read their
+// inputs: no printing, no calls to impure functions, no writes to globals or
+// through element access, and no in-place container methods
+func pureUserFunctions(ast *ASTNode) map[string]bool {
+	functions := map[string]*ASTNode{}
+	globals := map[string]bool{}
+	for _, child := range ast.Children {
+		switch child.Type {
+		case NODE_FUNCTION:
+			if child.DataType != "" && child.DataType != "void" {
+				functions[child.Value] = child
+			}
+		case NODE_ASSIGNMENT:
+			globals[child.Value] = true
+		}
+	}
+
+	// Start by assuming every candidate is pure and drop any that call an
+	// impure function, until nothing changes
+	pure := map[string]bool{}
+	for name := range functions {
+		pure[name] = true
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, fn := range functions {
+			if pure[name] && !isPureBody(fn, pure, globals) {
+				pure[name] = false
+				changed = true
+			}
+		}
+	}
+	return pure
+}
+
+func isPureBody(fn *ASTNode, pure map[string]bool, globals map[string]bool) bool {
+	result := true
+	walkAST(fn, func(node *ASTNode) bool {
+		switch node.Type {
+		case NODE_CALL:
+			if !pureFunctions[node.Value] && !pure[node.Value] {
+				result = false
+			}
+		case NODE_METHOD_CALL:
+			if !pureMethods[node.Value] {
+				result = false
+			}
+		case NODE_ASSIGNMENT:
+			if node.Value == "" || globals[node.Value] {
+				result = false
+			}
+		}
+		return result
+	})
+	return result
+}
+
+func checkMagicNumbers(ast *ASTNode, ctx *LintContext) {
+	allowed := map[string]bool{}
+	for _, value := range strings.Split(ctx.Option("allowed"), ",") {
+		allowed[strings.TrimSpace(value)] = true
+	}
+
+	walkAST(ast, func(node *ASTNode) bool {
+		switch node.Type {
+		case NODE_CONSTANT_DECLARATION, NODE_ENUM_DECLARATION:
+			// These are how numbers get names
+			return false
+		case NODE_BINARY_OP:
+			for _, operand := range node.Children {
+				if operand.Type == NODE_NUMBER && !allowed[operand.Value] {
+					ctx.Report(operand, "magic number %s; give it a name with a constant", operand.Value)
+				}
+			}
+		}
+		return true
+	})
+}
+
+func checkFunctionLength(ast *ASTNode, ctx *LintContext) {
+	max := ctx.IntOption("max")
+	walkAST(ast, func(node *ASTNode) bool {
+		if node.Type != NODE_FUNCTION {
+			return true
+		}
+		last := node.Line
+		walkAST(node, func(child *ASTNode) bool {
+			if child.Line > last {
+				last = child.Line
+			}
+			return true
+		})
+		if length := last - node.Line; length > max {
+			ctx.Report(node, "function '%s' is %d lines long (max %d)", node.Value, length, max)
+		}
+		return true
+	})
+}
+
+// raylib loaders and the functions that release what they return
+var raylibUnloaders = map[string]string{
+	"load_texture":            "unload_texture",
+	"load_texture_from_image": "unload_texture",
+	"load_render_texture":     "unload_render_texture",
+	"load_image":              "unload_image",
+	"load_font":               "unload_font",
+	"load_sound":              "unload_sound",
+	"load_music_stream":       "unload_music_stream",
+	"load_wave":               "unload_wave",
+	"load_model":              "unload_model",
+	"load_shader":             "unload_shader",
+}
+
+// raylibNamespaces reports whether a program uses raylib, through an import
+// of raylib.h or game.run, and returns the namespaces raylib is imported as
+func raylibNamespaces(ast *ASTNode) (map[string]bool, bool) {
+	namespaces := map[string]bool{}
+	found := callsGameRun(ast)
+	for _, child := range ast.Children {
+		if child.Type == NODE_IMPORT_STATEMENT && strings.HasSuffix(child.Value, "raylib.h") {
+			found = true
+			if child.DataType != "" {
+				namespaces[child.DataType] = true
+			}
+		}
+	}
+	return namespaces, found
+}
+
+// raylibCall returns the snake_case name and arguments of a call to a raylib
+// function, written either way: load_texture|...|, LoadTexture|...| or
+// rl.load_texture|...| through a namespace
+func raylibCall(node *ASTNode, namespaces map[string]bool) (string, []*ASTNode, bool) {
+	switch node.Type {
+	case NODE_CALL:
+		return toSnakeCase(node.Value), node.Children, true
+	case NODE_METHOD_CALL:
+		if len(node.Children) == 2 && node.Children[0].Type == NODE_IDENTIFIER && namespaces[node.Children[0].Value] {
+			return toSnakeCase(node.Value), node.Children[1].Children, true
+		}
+	}
+	return "", nil, false
+}
+
+// raylibProcedures splits a program into the code that runs together: the
+// top-level statements, then each function
+func raylibProcedures(ast *ASTNode) [][]*ASTNode {
+	var topLevel []*ASTNode
+	procedures := [][]*ASTNode{nil}
+	for _, child := range ast.Children {
+		if child.Type == NODE_FUNCTION {
+			procedures = append(procedures, []*ASTNode{child})
+		} else {
+			topLevel = append(topLevel, child)
+		}
+	}
+	procedures[0] = topLevel
+	return procedures
+}
+
+// checkRaylibUnload follows each loaded resource through the code it was
+// loaded in. A local resource must be unloaded, returned or stored there; one
+// loaded at the top level is a global and may be unloaded by any function.
+func checkRaylibUnload(ast *ASTNode, ctx *LintContext) {
+	namespaces, usesRaylib := raylibNamespaces(ast)
+	if !usesRaylib {
+		return
+	}
+
+	// Block children are statements, so a load there is thrown away
+	statements := map[*ASTNode]bool{}
+	walkAST(ast, func(node *ASTNode) bool {
+		if node.Type == NODE_PROGRAM || node.Type == NODE_BLOCK {
+			for _, stmt := range node.Children {
+				statements[stmt] = true
+			}
+		}
+		return true
+	})
+
+	type load struct {
+		node   *ASTNode
+		name   string
+		loader string
+	}
+	unloadedAnywhere := map[string]bool{}
+	var leaks []load
+	for i, procedure := range raylibProcedures(ast) {
+		var loads []load
+		released := map[string]bool{}
+		for _, root := range procedure {
+			walkAST(root, func(node *ASTNode) bool {
+				switch node.Type {
+				case NODE_ASSIGNMENT:
+					value := node.Children[len(node.Children)-1]
+					if name, _, ok := raylibCall(value, namespaces); ok && raylibUnloaders[name] != "" && node.Value != "" {
+						loads = append(loads, load{value, node.Value, name})
+					} else if value.Type == NODE_IDENTIFIER {
+						// Stored somewhere else, which takes over unloading it
+						released[value.Value] = true
+					}
+				case NODE_RETURN_STATEMENT:
+					for _, value := range node.Children {
+						if value.Type == NODE_IDENTIFIER {
+							released[value.Value] = true
+						}
+					}
+				}
+				name, args, ok := raylibCall(node, namespaces)
+				if !ok {
+					return true
+				}
+				if raylibUnloaders[name] != "" && statements[node] {
+					ctx.Report(node, "the result of %s|...| is thrown away, so it can never be unloaded", name)
+				}
+				if strings.HasPrefix(name, "unload_") && len(args) > 0 && args[0].Type == NODE_IDENTIFIER {
+					released[args[0].Value] = true
+					unloadedAnywhere[args[0].Value] = true
+				}
+				return true
+			})
+		}
+		for _, l := range loads {
+			if released[l.name] {
+				continue
+			}
+			if i == 0 {
+				// Top-level resources are checked once every function is seen
+				leaks = append(leaks, l)
+			} else {
+				ctx.Report(l.node, "'%s' is loaded with %s|...| but never passed to %s|...|", l.name, l.loader, raylibUnloaders[l.loader])
+			}
+		}
+	}
+	for _, l := range leaks {
+		if !unloadedAnywhere[l.name] {
+			ctx.Report(l.node, "'%s' is loaded with %s|...| but never passed to %s|...|", l.name, l.loader, raylibUnloaders[l.loader])
+		}
+	}
+}
+
+// checkRaylibDrawing walks the code that runs a frame loop in source order
+// and reports draw calls made while no begin_drawing|| (or
+// begin_texture_mode|...|) is open. Code that never begins or ends drawing is
+// taken to be called from inside a frame, like the draw function of game.run.
+func checkRaylibDrawing(ast *ASTNode, ctx *LintContext) {
+	namespaces, usesRaylib := raylibNamespaces(ast)
+	if !usesRaylib {
+		return
+	}
+
+	for _, procedure := range raylibProcedures(ast) {
+		ownsFrame := false
+		for _, root := range procedure {
+			walkAST(root, func(node *ASTNode) bool {
+				switch name, _, _ := raylibCall(node, namespaces); name {
+				case "begin_drawing", "end_drawing", "begin_texture_mode", "end_texture_mode", "window_should_close":
+					ownsFrame = true
+				}
+				return !ownsFrame
+			})
+		}
+		if !ownsFrame {
+			continue
+		}
+
+		depth := 0
+		for _, root := range procedure {
+			walkAST(root, func(node *ASTNode) bool {
+				name, _, ok := raylibCall(node, namespaces)
+				switch {
+				case !ok:
+				case name == "begin_drawing" || name == "begin_texture_mode":
+					depth++
+				case name == "end_drawing" || name == "end_texture_mode":
+					if depth > 0 {
+						depth--
+					}
+				case depth == 0 && (strings.HasPrefix(name, "draw_") || name == "clear_background"):
+					ctx.Report(node, "%s|...| is called outside begin_drawing||/end_drawing||", name)
+				}
+				return true
+			})
+		}
+	}
+}
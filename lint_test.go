@@ -0,0 +1,159 @@
+package ahoy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func lintSource(t *testing.T, source string, config *LintConfig) []LintWarning {
+	t.Helper()
+	ast, errors := ParseLint(Tokenize(source))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+	return Lint(ast, source, config)
+}
+
+func rulesOf(findings []LintWarning) map[string]int {
+	counts := map[string]int{}
+	for _, finding := range findings {
+		counts[finding.Rule]++
+	}
+	return counts
+}
+
+func TestLintDefaultRules(t *testing.T) {
+	source := `myVar: 5
+@ add_one |x:int| int:
+    return x + 1
+$
+add_one|3|
+"abc".upper||
+total: add_one|myVar| * 60
+`
+	counts := rulesOf(lintSource(t, source, nil))
+	if counts["naming"] != 1 {
+		t.Errorf("expected 1 naming finding, got %d", counts["naming"])
+	}
+	if counts["unused-result"] != 2 {
+		t.Errorf("expected 2 unused-result findings, got %d", counts["unused-result"])
+	}
+	if counts["magic-number"] != 0 {
+		t.Errorf("magic-number should be off by default, got %d findings", counts["magic-number"])
+	}
+}
+
+func TestLintIgnoreComments(t *testing.T) {
+	source := `myVar: 5 ? ahoy:ignore naming
+? ahoy:ignore
+otherVar: 6
+thirdVar: 7 ? ahoy:ignore unused-result
+`
+	findings := lintSource(t, source, nil)
+	if len(findings) != 1 || findings[0].Line != 4 {
+		t.Errorf("expected only line 4 to be reported, got %+v", findings)
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LintConfigFile)
+	content := "? project rules\nnaming: error\nmagic-number: warning\nmagic-number.allowed: 0, 60\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadLintConfig(path)
+	if err != nil {
+		t.Fatalf("LoadLintConfig: %v", err)
+	}
+
+	findings := lintSource(t, "myVar: 5\ntotal: myVar * 60 + 7\n", config)
+	for _, finding := range findings {
+		if finding.Rule == "naming" && finding.Severity != SeverityError {
+			t.Errorf("naming should be an error, got %s", finding.Severity)
+		}
+	}
+	if counts := rulesOf(findings); counts["magic-number"] != 1 {
+		t.Errorf("expected only 7 to be a magic number, got %d findings", counts["magic-number"])
+	}
+
+	if err := os.WriteFile(path, []byte("naming: loud\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLintConfig(path); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+}
+
+func TestLintFixes(t *testing.T) {
+	source := `myVar: 5
+@ addOne |X:int| int:
+    return X + 1
+$
+total: addOne|myVar|
+if total == 6 then print|f"{myVar} is fine"| $
+print|"myVar"|
+`
+	fixed, count := ApplyLintEdits(source, lintSource(t, source, nil))
+	expected := `my_var: 5
+@ add_one |x:int| int:
+    return x + 1
+$
+total: add_one|my_var|
+if total is 6 then print|f"{my_var} is fine"| $
+print|"myVar"|
+`
+	if fixed != expected {
+		t.Errorf("fixed source mismatch.\nExpected:\n%s\nGot:\n%s", expected, fixed)
+	}
+	if count != 4 {
+		t.Errorf("expected 4 fixes, got %d", count)
+	}
+}
+
+func TestLintRenameSkipsFields(t *testing.T) {
+	// myField is also a property name, so renaming it isn't safe
+	source := "myField: 1\nprint|point.myField|\n"
+	for _, finding := range lintSource(t, source, nil) {
+		if finding.Rule == "naming" && len(finding.Edits) > 0 {
+			t.Errorf("expected no fix for a name used after a dot, got %+v", finding.Edits)
+		}
+	}
+}
+
+func TestLintRaylib(t *testing.T) {
+	header := filepath.Join(t.TempDir(), "raylib.h")
+	if err := os.WriteFile(header, []byte("typedef struct Texture { int id; } Texture;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := `import "` + header + `"
+hero: load_texture|"hero.png"|
+boom: LoadSound|"boom.wav"|
+@ load_level || Texture:
+    tiles: load_texture|"tiles.png"|
+    return tiles
+$
+@ flash || void:
+    tmp: load_texture|"flash.png"|
+    draw_texture|tmp, 0, 0, WHITE|
+$
+draw_text|"loading", 0, 0, 20, BLACK|
+loop till not window_should_close|| do
+    begin_drawing||
+    draw_texture|hero, 0, 0, WHITE|
+    flash||
+    end_drawing||
+$
+unload_texture|hero|
+`
+	var lines []int
+	for _, finding := range lintSource(t, source, nil) {
+		if finding.Rule == "raylib-unload" || finding.Rule == "raylib-drawing" {
+			lines = append(lines, finding.Line)
+		}
+	}
+	// boom and tmp are never unloaded; draw_text runs before drawing begins
+	if len(lines) != 3 || lines[0] != 3 || lines[1] != 9 || lines[2] != 12 {
+		t.Errorf("expected findings on lines 3, 9 and 12, got %v", lines)
+	}
+}
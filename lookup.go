@@ -0,0 +1,147 @@
+package ahoy
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// lookupTypes are the switch results a lookup table can hold
+var lookupTypes = map[string]bool{"int": true, "float": true, "char": true, "bool": true, "string": true}
+
+// generateLookupSwitch writes a switch expression as a read from a static
+// table, when optimizing and the switch maps every member of an int enum
+// whose values have no gaps to a constant. A _ case gives the value for
+// ints outside the enum. Returns false, writing nothing, for other switches.
+func (gen *CodeGenerator) generateLookupSwitch(node *ASTNode, targetVar string) bool {
+	if !gen.optimize {
+		return false
+	}
+	switchExpr := node.Children[0]
+	enumName := gen.valueEnum(switchExpr)
+	if enumName == "" || gen.enumTypes[enumName] != "int" {
+		return false
+	}
+	values := gen.enumValues(enumName)
+	for member := range gen.enums[enumName] {
+		if _, ok := gen.constValues[enumName+"."+member]; !ok {
+			return false
+		}
+	}
+	if len(values) == 0 || values[len(values)-1]-values[0]+1 != int64(len(values)) {
+		return false
+	}
+	resultType, conflict, _ := gen.switchResultType(node)
+	if conflict != nil || !lookupTypes[resultType] {
+		return false
+	}
+
+	first := values[0]
+	entries := make([]string, len(values))
+	fallback := ""
+	for _, caseNode := range node.Children[1:] {
+		if caseNode.Type != NODE_SWITCH_CASE || len(caseNode.Children) < 2 {
+			return false
+		}
+		value, ok := gen.lookupConstant(caseNode.Children[1])
+		if !ok {
+			return false
+		}
+		labels := []*ASTNode{caseNode.Children[0]}
+		if labels[0].Type == NODE_SWITCH_CASE_LIST {
+			labels = labels[0].Children
+		}
+		for _, label := range labels {
+			if isDefaultCase(label) {
+				fallback = value
+				continue
+			}
+			key, ok := gen.lookupKey(label, enumName)
+			if !ok || key < first || key-first >= int64(len(entries)) || entries[key-first] != "" {
+				return false
+			}
+			entries[key-first] = value
+		}
+	}
+	if slices.Contains(entries, "") {
+		return false
+	}
+
+	table := fmt.Sprintf("__lookup_%d", gen.varCounter)
+	key := fmt.Sprintf("__lookup_key_%d", gen.varCounter)
+	gen.varCounter++
+	gen.writeIndent()
+	gen.output.WriteString("{\n")
+	gen.indent++
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("static %s const %s[%d] = {%s};\n", gen.mapType(resultType), table, len(entries), strings.Join(entries, ", ")))
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("int %s = (int)(", key))
+	gen.generateNode(switchExpr)
+	gen.output.WriteString(")")
+	if first != 0 {
+		gen.output.WriteString(fmt.Sprintf(" - (%d)", first))
+	}
+	gen.output.WriteString(";\n")
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("if (%s >= 0 && %s < %d) {\n", key, key, len(entries)))
+	gen.indent++
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s = %s[%s];\n", targetVar, table, key))
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}")
+	if fallback != "" {
+		gen.output.WriteString(" else {\n")
+		gen.indent++
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s = %s;\n", targetVar, fallback))
+		gen.indent--
+		gen.writeIndent()
+		gen.output.WriteString("}")
+	}
+	gen.output.WriteString("\n")
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+	return true
+}
+
+// lookupKey is the enum value a case label stands for
+func (gen *CodeGenerator) lookupKey(label *ASTNode, enumName string) (int64, bool) {
+	if label.Type == NODE_IDENTIFIER && gen.enums[enumName][label.Value] {
+		value, ok := gen.constValues[enumName+"."+label.Value]
+		return value, ok
+	}
+	return gen.evalConstInt(label, nil)
+}
+
+// lookupConstant is the C for a case body that gives a constant, which a
+// static table can be initialized with
+func (gen *CodeGenerator) lookupConstant(body *ASTNode) (string, bool) {
+	if body.Type == NODE_BLOCK {
+		if len(body.Children) != 1 {
+			return "", false
+		}
+		body = body.Children[0]
+	}
+	if value, ok := gen.evalConstInt(body, nil); ok {
+		return strconv.FormatInt(value, 10), true
+	}
+	switch body.Type {
+	case NODE_NUMBER, NODE_STRING, NODE_CHAR, NODE_BOOLEAN:
+	case NODE_UNARY_OP:
+		if body.Value != "-" || len(body.Children) != 1 || body.Children[0].Type != NODE_NUMBER {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	savedOutput := gen.output
+	gen.output = strings.Builder{}
+	gen.generateNode(body)
+	value := gen.output.String()
+	gen.output = savedOutput
+	return value, true
+}
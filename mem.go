@@ -0,0 +1,192 @@
+package ahoy
+
+import "fmt"
+
+// memMethodTypes are the types the mem module's methods return; mem.report
+// returns nothing
+var memMethodTypes = map[string]string{"allocated": "int", "live_objects": "int"}
+
+// memCategories are the kinds of allocation the mem module counts, in the
+// order mem.report lists them
+var memCategories = []string{"arrays", "dicts", "strings", "json", "other"}
+
+// generateMemCall generates a call to the built-in mem module, which counts
+// what the program has allocated and not yet freed. mem.allocated|| is the
+// bytes live, mem.live_objects|| the allocations live, and either takes a
+// category to count only that one; mem.report|| prints them all.
+func (gen *CodeGenerator) generateMemCall(node *ASTNode) {
+	method := node.Value
+	args := node.Children[1].Children
+	switch method {
+	case "allocated", "live_objects":
+		if len(args) > 1 {
+			gen.reportError(node.Line, fmt.Sprintf("mem.%s takes at most a category, got %d arguments", method, len(args)))
+			return
+		}
+		if len(args) == 1 && args[0].Type == NODE_STRING && !contains(memCategories, args[0].Value) {
+			gen.reportError(node.Line, fmt.Sprintf("mem has no category '%s'", args[0].Value),
+				"The categories are arrays, dicts, strings, json and other")
+			return
+		}
+	case "report":
+		if len(args) != 0 {
+			gen.reportError(node.Line, fmt.Sprintf("mem.report takes no arguments, got %d", len(args)))
+			return
+		}
+	default:
+		gen.reportError(node.Line, fmt.Sprintf("the mem module has no '%s'", method),
+			"Count live memory with mem.allocated||, mem.live_objects|| and mem.report||")
+		return
+	}
+	gen.useMem = true
+
+	gen.output.WriteString(fmt.Sprintf("ahoy_mem_%s(", method))
+	switch {
+	case method == "report":
+	case len(args) == 1:
+		gen.generateNode(args[0])
+	default:
+		gen.output.WriteString("NULL")
+	}
+	gen.output.WriteString(")")
+}
+
+// getMemAllocator returns the counting allocator behind the mem module. It
+// comes straight after the includes, so the macros it ends with route every
+// malloc, calloc, realloc, strdup and free in the runtime and the program
+// through it. Each block carries a header with its size and category; the
+// category is read once per call site from the size expression's text, so
+// sizeof(AhoyArray) counts as an array and a byte count as a string. Memory
+// that libraries allocated is passed through to free untouched.
+func (gen *CodeGenerator) getMemAllocator() string {
+	if !gen.useMem {
+		return ""
+	}
+	return `// mem module: a counting allocator
+#define AHOY_MEM_MAGIC 0xA40717EDu
+#define AHOY_MEM_CATEGORIES 5
+
+typedef struct {
+    size_t size;
+    unsigned int magic;
+    int category;
+} AhoyMemHeader;
+
+const char* ahoy_mem_names[AHOY_MEM_CATEGORIES] = {"arrays", "dicts", "strings", "json", "other"};
+long ahoy_mem_bytes[AHOY_MEM_CATEGORIES];
+long ahoy_mem_objects[AHOY_MEM_CATEGORIES];
+long ahoy_mem_total[AHOY_MEM_CATEGORIES];
+
+int ahoy_mem_category(const char* site) {
+    if (strstr(site, "HashMap")) return 1;
+    if (strstr(site, "AhoyJSON")) return 3;
+    if (strstr(site, "AhoyArray") || strstr(site, "AhoyValueType") || strstr(site, "intptr_t") ||
+        strstr(site, "DynamicArray") || strstr(site, "void*")) return 0;
+    if (strstr(site, "sizeof") && !strstr(site, "sizeof(char)")) return 4;
+    return 2;
+}
+
+static AhoyMemHeader* ahoy_mem_header(void* ptr) {
+    if (ptr == NULL) return NULL;
+    AhoyMemHeader* header = (AhoyMemHeader*)ptr - 1;
+    return header->magic == AHOY_MEM_MAGIC ? header : NULL;
+}
+
+static void* ahoy_mem_track(AhoyMemHeader* header, size_t size, int category) {
+    if (header == NULL) return NULL;
+    header->size = size;
+    header->magic = AHOY_MEM_MAGIC;
+    header->category = category;
+    ahoy_mem_bytes[category] += (long)size;
+    ahoy_mem_objects[category]++;
+    ahoy_mem_total[category]++;
+    return header + 1;
+}
+
+void* ahoy_mem_malloc(size_t size, int category) {
+    return ahoy_mem_track((malloc)(sizeof(AhoyMemHeader) + size), size, category);
+}
+
+void* ahoy_mem_calloc(size_t count, size_t size, int category) {
+    return ahoy_mem_track((calloc)(1, sizeof(AhoyMemHeader) + count * size), count * size, category);
+}
+
+void ahoy_mem_free(void* ptr) {
+    AhoyMemHeader* header = ahoy_mem_header(ptr);
+    if (header == NULL) {
+        (free)(ptr);
+        return;
+    }
+    ahoy_mem_bytes[header->category] -= (long)header->size;
+    ahoy_mem_objects[header->category]--;
+    header->magic = 0;
+    (free)(header);
+}
+
+void* ahoy_mem_realloc(void* ptr, size_t size, int category) {
+    if (ptr == NULL) return ahoy_mem_malloc(size, category);
+    AhoyMemHeader* header = ahoy_mem_header(ptr);
+    if (header == NULL) return (realloc)(ptr, size);
+    // A block keeps the category it was first allocated as
+    category = header->category;
+    size_t old = header->size;
+    AhoyMemHeader* moved = (realloc)(header, sizeof(AhoyMemHeader) + size);
+    if (moved == NULL) return NULL;
+    moved->size = size;
+    ahoy_mem_bytes[category] += (long)size - (long)old;
+    return moved + 1;
+}
+
+char* ahoy_mem_strdup(const char* str) {
+    size_t size = strlen(str) + 1;
+    char* copy = ahoy_mem_malloc(size, 2);
+    if (copy != NULL) memcpy(copy, str, size);
+    return copy;
+}
+
+static int ahoy_mem_index(const char* category) {
+    for (int i = 0; i < AHOY_MEM_CATEGORIES; i++) {
+        if (strcmp(ahoy_mem_names[i], category) == 0) return i;
+    }
+    return -1;
+}
+
+int ahoy_mem_allocated(const char* category) {
+    long bytes = 0;
+    for (int i = 0; i < AHOY_MEM_CATEGORIES; i++) {
+        if (category == NULL || i == ahoy_mem_index(category)) bytes += ahoy_mem_bytes[i];
+    }
+    return (int)bytes;
+}
+
+int ahoy_mem_live_objects(const char* category) {
+    long objects = 0;
+    for (int i = 0; i < AHOY_MEM_CATEGORIES; i++) {
+        if (category == NULL || i == ahoy_mem_index(category)) objects += ahoy_mem_objects[i];
+    }
+    return (int)objects;
+}
+
+void ahoy_mem_report(void) {
+    long bytes = 0, objects = 0, total = 0;
+    printf("%-8s %12s %10s %12s\n", "category", "live bytes", "live", "allocations");
+    for (int i = 0; i < AHOY_MEM_CATEGORIES; i++) {
+        printf("%-8s %12ld %10ld %12ld\n", ahoy_mem_names[i], ahoy_mem_bytes[i], ahoy_mem_objects[i], ahoy_mem_total[i]);
+        bytes += ahoy_mem_bytes[i];
+        objects += ahoy_mem_objects[i];
+        total += ahoy_mem_total[i];
+    }
+    printf("%-8s %12ld %10ld %12ld\n", "total", bytes, objects, total);
+}
+
+// The category of each call site is worked out the first time it runs
+#define AHOY_MEM_SITE(text) ({ static int ahoy_mem_site = -1; if (ahoy_mem_site < 0) ahoy_mem_site = ahoy_mem_category(text); ahoy_mem_site; })
+#define malloc(size) ahoy_mem_malloc((size), AHOY_MEM_SITE(#size))
+#define calloc(count, size) ahoy_mem_calloc((count), (size), AHOY_MEM_SITE(#count "," #size))
+#define realloc(ptr, size) ahoy_mem_realloc((ptr), (size), AHOY_MEM_SITE(#size))
+#undef strdup
+#define strdup(str) ahoy_mem_strdup(str)
+#define free(ptr) ahoy_mem_free(ptr)
+
+`
+}
@@ -0,0 +1,229 @@
+package ahoy
+
+import (
+	"strings"
+)
+
+// lineBufferStdout is the first statement of main, which a minimal runtime
+// leaves out when the program doesn't use stdio
+const lineBufferStdout = "    setvbuf(stdout, NULL, _IOLBF, BUFSIZ);\n"
+
+// standardHeaderNames are the names each header every program includes
+// declares. With MinimalRuntime a header is only included when the C uses
+// one of them.
+var standardHeaderNames = map[string]map[string]bool{
+	"stdio.h": setOf(
+		"printf", "fprintf", "sprintf", "snprintf", "vprintf", "vfprintf", "vsnprintf",
+		"puts", "fputs", "putchar", "fputc", "getchar", "fgets", "getline",
+		"scanf", "sscanf", "fscanf", "fopen", "fclose", "fread", "fwrite",
+		"fflush", "fseek", "ftell", "rewind", "remove", "rename", "perror",
+		"setvbuf", "FILE", "stdin", "stdout", "stderr", "EOF", "BUFSIZ", "_IOLBF",
+	),
+	"stdlib.h": setOf(
+		"malloc", "calloc", "realloc", "free", "exit", "abort", "atexit",
+		"atoi", "atof", "atol", "strtol", "strtoll", "strtoul", "strtod",
+		"rand", "srand", "qsort", "bsearch", "getenv", "setenv", "system",
+		"abs", "labs", "EXIT_SUCCESS", "EXIT_FAILURE", "RAND_MAX",
+	),
+	"string.h": setOf(
+		"strlen", "strcmp", "strncmp", "strcpy", "strncpy", "strcat", "strncat",
+		"strdup", "strndup", "strchr", "strrchr", "strstr", "strtok", "strspn",
+		"strcspn", "strerror", "memcpy", "memmove", "memset", "memcmp", "memchr",
+	),
+	"stdbool.h": setOf("bool", "true", "false"),
+	"stdint.h": setOf(
+		"int8_t", "int16_t", "int32_t", "int64_t", "uint8_t", "uint16_t",
+		"uint32_t", "uint64_t", "intptr_t", "uintptr_t", "intmax_t", "uintmax_t",
+		"INT8_MIN", "INT8_MAX", "INT16_MIN", "INT16_MAX", "INT32_MIN", "INT32_MAX",
+		"INT64_MIN", "INT64_MAX", "UINT8_MAX", "UINT16_MAX", "UINT32_MAX",
+		"UINT64_MAX", "INTPTR_MIN", "INTPTR_MAX", "UINTPTR_MAX",
+	),
+}
+
+// stddefNames come with stdio.h, stdlib.h and string.h, and need stddef.h
+// when none of them is included
+var stddefNames = setOf("NULL", "size_t", "ptrdiff_t", "offsetof")
+
+// minimizeRuntime removes the runtime's types, tables and functions nothing
+// uses from fenced C, along with the standard headers and the line
+// buffering of stdout it has no use for
+func minimizeRuntime(code string) string {
+	items := cItems(code)
+
+	used := map[string]bool{}
+	addUses := func(text string) {
+		for _, name := range cIdentifier.FindAllString(stripCLiterals(text), -1) {
+			used[name] = true
+		}
+	}
+	kept := make([]bool, len(items))
+	for i, item := range items {
+		switch {
+		case !item.runtime && !isRuntimeHelper(item):
+			kept[i] = true
+			text := item.text
+			if item.kind == cFunction && item.name == "main" {
+				text = strings.Replace(text, lineBufferStdout, "", 1)
+			}
+			addUses(text)
+		case item.kind == cOther:
+			kept[i] = true
+		case item.kind == cDirective && !isStandardInclude(item.first):
+			kept[i] = true
+			addUses(item.text)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, item := range items {
+			if kept[i] || item.kind == cDirective {
+				continue
+			}
+			for _, name := range definedNames(item) {
+				if used[name] && !cKeywords[name] {
+					kept[i] = true
+					addUses(item.text)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	needsHeader := func(header string) bool {
+		for name := range standardHeaderNames[header] {
+			if used[name] {
+				return true
+			}
+		}
+		return false
+	}
+	stdio := needsHeader("stdio.h")
+	stddef := false
+	if !stdio && !needsHeader("stdlib.h") && !needsHeader("string.h") {
+		for name := range stddefNames {
+			stddef = stddef || used[name]
+		}
+	}
+
+	var result strings.Builder
+	runtime := false
+	for i, item := range items {
+		if item.kind == cDirective && isStandardInclude(item.first) {
+			header := standardInclude(item.first)
+			if needsHeader(header) {
+				kept[i] = true
+			} else if stddef {
+				// The first standard header dropped makes way for stddef.h
+				stddef = false
+				item.text = strings.Replace(item.text, header, "stddef.h", 1)
+				kept[i] = true
+			}
+		}
+		if !kept[i] {
+			continue
+		}
+		if item.runtime != runtime {
+			runtime = item.runtime
+			if runtime {
+				result.WriteString(runtimeBegin)
+			} else {
+				result.WriteString(runtimeEnd)
+			}
+		}
+		if !stdio && item.kind == cFunction && item.name == "main" {
+			item.text = strings.Replace(item.text, lineBufferStdout, "", 1)
+		}
+		result.WriteString(item.text)
+	}
+	if runtime {
+		result.WriteString(runtimeEnd)
+	}
+	return strings.TrimLeft(result.String(), "\n")
+}
+
+// isRuntimeHelper reports whether an item outside the runtime's fences is
+// one of its helpers all the same, going by the names programs can't use
+func isRuntimeHelper(item cItem) bool {
+	switch item.kind {
+	case cFunction, cPrototype, cVariable:
+		name := definedNames(item)[0]
+		return strings.HasPrefix(name, "ahoy_") || runtimeNames[name]
+	}
+	return false
+}
+
+// standardInclude returns the header an #include line names when it's one
+// of standardHeaderNames, or ""
+func standardInclude(line string) string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "#include <") || !strings.HasSuffix(line, ">") {
+		return ""
+	}
+	header := strings.TrimSuffix(strings.TrimPrefix(line, "#include <"), ">")
+	if _, ok := standardHeaderNames[header]; !ok {
+		return ""
+	}
+	return header
+}
+
+func isStandardInclude(line string) bool {
+	return standardInclude(line) != ""
+}
+
+// definedNames returns the names a top-level item of C defines. An enum
+// defines every name in it, its constants among them.
+func definedNames(item cItem) []string {
+	switch item.kind {
+	case cFunction, cPrototype:
+		return []string{item.name}
+	case cVariable:
+		return []string{definedName(item)}
+	case cType:
+		text := stripCLiterals(item.text)
+		if strings.Contains(item.first, "enum") {
+			return cIdentifier.FindAllString(text, -1)
+		}
+		// typedef struct Tag { ... } Name; or typedef struct Tag Name;
+		names := cIdentifier.FindAllString(item.first, -1)
+		if end := strings.LastIndex(text, "}"); end >= 0 {
+			names = append(names, cIdentifier.FindAllString(text[end:], -1)...)
+		} else {
+			names = append(names, cIdentifier.FindAllString(text, -1)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// stripCLiterals blanks out the comments and string and character literals
+// in C, leaving the names it uses
+func stripCLiterals(code string) string {
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; {
+		case strings.HasPrefix(code[i:], "//"):
+			for i < len(code) && code[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+		case c == '"' || c == '\'':
+			for i++; i < len(code) && code[i] != c; i++ {
+				if code[i] == '\\' {
+					i++
+				}
+			}
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package ahoy
+
+import "fmt"
+
+// outParamThreshold is the most bytes a multi-return function's results
+// may take to be returned as a struct. Bigger results are written through
+// a pointer to the caller's struct instead, so they aren't copied out.
+const outParamThreshold = 64
+
+// outParamName is the parameter a function writes its results through
+const outParamName = "__out"
+
+// scanOutParams marks the multi-return functions whose results are bigger
+// than outParamThreshold. It runs before code generation, as calls can come
+// before the function they call.
+func (gen *CodeGenerator) scanOutParams(ast *ASTNode) {
+	fields := map[string][]string{}
+	for _, child := range ast.Children {
+		if child.Type == NODE_STRUCT_DECLARATION {
+			for _, field := range child.Children {
+				if field.Type != NODE_TYPE {
+					fields[child.Value] = append(fields[child.Value], field.DataType)
+				}
+			}
+		}
+	}
+	for _, child := range ast.Children {
+		returnTypes := gen.functionReturnTypes[child.Value]
+		if child.Type != NODE_FUNCTION || len(returnTypes) < 2 {
+			continue
+		}
+		// A library's exports return their results the same way at any size
+		if gen.library != "" && child.Tag == "export" {
+			continue
+		}
+		size := 0
+		for _, returnType := range returnTypes {
+			size += typeSize(returnType, fields, 0)
+		}
+		if size > outParamThreshold {
+			gen.outParams[child.Value] = true
+		}
+	}
+}
+
+// typeSize estimates the bytes a value of an Ahoy type takes in C, leaving
+// out padding. Anything held by pointer takes 8.
+func typeSize(langType string, fields map[string][]string, depth int) int {
+	switch langType {
+	case "int":
+		return 4
+	case "bool":
+		return 1
+	case "float":
+		return 8
+	}
+	if builtin, ok := builtinStructs[langType]; ok && fields[langType] == nil {
+		if langType == "color" {
+			return 4
+		}
+		return 4 * len(builtin.fields)
+	}
+	if types, ok := fields[langType]; ok && depth < 8 {
+		size := 0
+		for _, fieldType := range types {
+			size += typeSize(fieldType, fields, depth+1)
+		}
+		return size
+	}
+	return 8
+}
+
+// generateOutParamCall writes a call of a function with an out-parameter
+// as a statement expression giving the results, for calls whose results
+// aren't unpacked straight into variables
+func (gen *CodeGenerator) generateOutParamCall(node *ASTNode) {
+	results := fmt.Sprintf("__out_%d", gen.varCounter)
+	gen.varCounter++
+	gen.output.WriteString(fmt.Sprintf("({ %s_return %s; ", node.Value, results))
+	gen.callOut = results
+	gen.generateCall(node)
+	gen.output.WriteString(fmt.Sprintf("; %s; })", results))
+}
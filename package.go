@@ -0,0 +1,566 @@
+package ahoy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PackageFile represents a single .ahoy file in a package
+type PackageFile struct {
+	Path        string
+	ProgramName string // Empty if standalone script
+	AST         *ASTNode
+	Content     string
+}
+
+// Package represents a collection of files with the same program name
+type Package struct {
+	Name  string
+	Files []PackageFile
+}
+
+// PackageManager handles package resolution and compilation
+type PackageManager struct {
+	Packages      map[string]*Package // program name -> Package
+	ImportedPaths map[string]*Package // file/dir path -> Package
+	CurrentDir    string
+	Log           io.Writer // Where warnings about skipped files are printed
+
+	headers map[string]parsedHeader // header path -> what parsing it gave
+}
+
+// parsedHeader is what a C header declares, or why it couldn't be parsed
+type parsedHeader struct {
+	info *CHeaderInfo
+	err  error
+}
+
+// headerDirs are searched for a header imported by name after the importing
+// file's directory and the current one
+var headerDirs = []string{"/usr/include", "/usr/local/include", "repos/raylib/src"}
+
+func NewPackageManager(currentDir string) *PackageManager {
+	return &PackageManager{
+		Packages:      make(map[string]*Package),
+		ImportedPaths: make(map[string]*Package),
+		CurrentDir:    currentDir,
+		Log:           os.Stdout,
+		headers:       make(map[string]parsedHeader),
+	}
+}
+
+// LoadHeader finds the C header that fromFile imports as headerName and
+// parses it. A header is parsed once however many files import it.
+func (pm *PackageManager) LoadHeader(headerName string, fromFile string) (*CHeaderInfo, error) {
+	locations := []string{headerName}
+	if !filepath.IsAbs(headerName) {
+		locations = nil
+		if fromFile != "" {
+			locations = append(locations, filepath.Join(filepath.Dir(fromFile), headerName))
+		}
+		locations = append(locations, headerName)
+		for _, dir := range headerDirs {
+			locations = append(locations, filepath.Join(dir, headerName))
+		}
+	}
+
+	var err error
+	for _, location := range locations {
+		parsed, exists := pm.headers[location]
+		if !exists {
+			parsed.info, parsed.err = ParseCHeader(location)
+			pm.headers[location] = parsed
+		}
+		if parsed.err == nil {
+			return parsed.info, nil
+		}
+		if err == nil {
+			err = parsed.err
+		}
+	}
+	return nil, err
+}
+
+// BindHeaders attaches to each C header import in the package's files the
+// header it names, so code generation doesn't look for headers again and
+// imports from other directories find theirs. Headers that can't be parsed
+// are left unbound.
+func (pm *PackageManager) BindHeaders(pkg *Package) {
+	for _, file := range pkg.Files {
+		if file.AST == nil {
+			continue
+		}
+		for _, child := range file.AST.Children {
+			if child.Type == NODE_IMPORT_STATEMENT && strings.HasSuffix(child.Value, ".h") && child.Header == nil {
+				child.Header, _ = pm.LoadHeader(child.Value, file.Path)
+			}
+		}
+	}
+}
+
+// LoadFile loads and parses a .ahoy file
+func (pm *PackageManager) LoadFile(filePath string) (*PackageFile, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %v", filePath, err)
+	}
+
+	// TEMP: Disable formatter for debugging
+	formattedContent := string(content) // formatSource(string(content))
+	tokens := Tokenize(formattedContent)
+
+	// Protect against parse panics when scanning directories
+	var ast *ASTNode
+	var parseErr error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				parseErr = fmt.Errorf("parse error in %s: %v", filePath, r)
+			}
+		}()
+		ast = Parse(tokens)
+	}()
+
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	pf := &PackageFile{
+		Path:    filePath,
+		AST:     ast,
+		Content: formattedContent,
+	}
+
+	// Check if first statement is a program declaration
+	if ast != nil && len(ast.Children) > 0 {
+		firstNode := ast.Children[0]
+		if firstNode.Type == NODE_PROGRAM_DECLARATION {
+			pf.ProgramName = firstNode.Value
+		}
+	}
+
+	return pf, nil
+}
+
+// LoadPackageFromFile loads a file and its associated package files
+func (pm *PackageManager) LoadPackageFromFile(mainFilePath string) (*Package, error) {
+	// Load the main file
+	mainFile, err := pm.LoadFile(mainFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// If no program declaration, return single-file package
+	if mainFile.ProgramName == "" {
+		pkg := &Package{
+			Name:  filepath.Base(mainFilePath),
+			Files: []PackageFile{*mainFile},
+		}
+		return pkg, nil
+	}
+
+	// Find all files in the same directory with the same program name
+	dir := filepath.Dir(mainFilePath)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %v", dir, err)
+	}
+
+	pkg := &Package{
+		Name:  mainFile.ProgramName,
+		Files: []PackageFile{},
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".ahoy") {
+			continue
+		}
+
+		filePath := filepath.Join(dir, file.Name())
+		pf, err := pm.LoadFile(filePath)
+		if err != nil {
+			// Skip files that fail to parse instead of failing the whole package
+			fmt.Fprintf(pm.Log, "Warning: Skipping file %s due to error: %v\n", file.Name(), err)
+			continue
+		}
+
+		// Only include files with matching program name
+		if pf.ProgramName == mainFile.ProgramName {
+			pkg.Files = append(pkg.Files, *pf)
+		}
+	}
+
+	pm.Packages[pkg.Name] = pkg
+	return pkg, nil
+}
+
+// ResolveImport resolves an import path to a Package
+func (pm *PackageManager) ResolveImport(importPath string, fromFile string) (*Package, error) {
+	// Check if already imported; the same path from files in different
+	// directories can name different packages
+	resolvedPath := pm.resolveImportPath(importPath, fromFile)
+	key := resolvedPath
+	if pkg, exists := pm.ImportedPaths[key]; exists {
+		return pkg, nil
+	}
+
+	// Check if path is a directory or file; from name import finds name.ahoy
+	info, err := os.Stat(resolvedPath)
+	if err != nil && filepath.Ext(resolvedPath) == "" {
+		if info, err = os.Stat(resolvedPath + ".ahoy"); err == nil {
+			resolvedPath += ".ahoy"
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import path not found: %s", importPath)
+	}
+
+	var pkg *Package
+	if info.IsDir() {
+		// Load all .ahoy files in directory
+		pkg, err = pm.LoadPackageFromDirectory(resolvedPath)
+	} else if strings.HasSuffix(resolvedPath, ".ahoy") {
+		// Load single file or package starting from this file
+		pkg, err = pm.LoadPackageFromFile(resolvedPath)
+	} else if strings.HasSuffix(resolvedPath, ".h") {
+		// C header file - create empty package (parsing handled by compiler)
+		pkg = &Package{
+			Name:  filepath.Base(resolvedPath),
+			Files: []PackageFile{},
+		}
+		err = nil
+	} else {
+		return nil, fmt.Errorf("import path must be a directory, .ahoy file, or .h file: %s", importPath)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	pm.ImportedPaths[key] = pkg
+	return pkg, nil
+}
+
+// resolveImportPath returns the file or directory an import refers to:
+// ./ and ../ paths are relative to the importing file, other relative paths
+// to the current directory
+func (pm *PackageManager) resolveImportPath(importPath string, fromFile string) string {
+	if strings.HasPrefix(importPath, "./") || strings.HasPrefix(importPath, "../") {
+		return filepath.Join(filepath.Dir(fromFile), importPath)
+	}
+	if filepath.IsAbs(importPath) {
+		return importPath
+	}
+	return filepath.Join(pm.CurrentDir, importPath)
+}
+
+// LoadPackageFromDirectory loads all .ahoy files in a directory
+// If they have the same program declaration, they're grouped together
+func (pm *PackageManager) LoadPackageFromDirectory(dirPath string) (*Package, error) {
+	files, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %v", dirPath, err)
+	}
+
+	packageFiles := make(map[string][]PackageFile) // program name -> files
+	standaloneFiles := []PackageFile{}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".ahoy") {
+			continue
+		}
+
+		filePath := filepath.Join(dirPath, file.Name())
+		pf, err := pm.LoadFile(filePath)
+		if err != nil {
+			// Skip files that fail to parse
+			fmt.Fprintf(pm.Log, "Warning: Skipping file %s due to error: %v\n", file.Name(), err)
+			continue
+		}
+
+		if pf.ProgramName == "" {
+			standaloneFiles = append(standaloneFiles, *pf)
+		} else {
+			packageFiles[pf.ProgramName] = append(packageFiles[pf.ProgramName], *pf)
+		}
+	}
+
+	// If there's only one program group, return that
+	if len(packageFiles) == 1 {
+		for name, files := range packageFiles {
+			pkg := &Package{
+				Name:  name,
+				Files: files,
+			}
+			pm.Packages[name] = pkg
+			return pkg, nil
+		}
+	}
+
+	// If multiple programs or standalone files, return error
+	if len(packageFiles) > 1 {
+		names := make([]string, 0, len(packageFiles))
+		for name := range packageFiles {
+			names = append(names, name)
+		}
+		return nil, fmt.Errorf("directory contains multiple programs: %v", names)
+	}
+
+	// Only standalone files
+	if len(standaloneFiles) > 0 {
+		return nil, fmt.Errorf("directory contains only standalone files without program declarations")
+	}
+
+	return nil, fmt.Errorf("no .ahoy files found in directory: %s", dirPath)
+}
+
+// GetAllFunctions returns all function declarations from a package
+func (pkg *Package) GetAllFunctions() []*ASTNode {
+	functions := []*ASTNode{}
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			for _, child := range file.AST.Children {
+				if child.Type == NODE_FUNCTION {
+					functions = append(functions, child)
+				}
+			}
+		}
+	}
+	return functions
+}
+
+// GetAllGlobalVariables returns all global variable declarations from a package
+func (pkg *Package) GetAllGlobalVariables() []*ASTNode {
+	variables := []*ASTNode{}
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			for _, child := range file.AST.Children {
+				if child.Type == NODE_VARIABLE_DECLARATION ||
+					child.Type == NODE_CONSTANT_DECLARATION {
+					variables = append(variables, child)
+				}
+			}
+		}
+	}
+	return variables
+}
+
+// GetAllStructs returns all struct declarations from a package
+func (pkg *Package) GetAllStructs() []*ASTNode {
+	structs := []*ASTNode{}
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			for _, child := range file.AST.Children {
+				if child.Type == NODE_STRUCT_DECLARATION {
+					structs = append(structs, child)
+				}
+			}
+		}
+	}
+	return structs
+}
+
+// GetAllEnums returns all enum declarations from a package
+func (pkg *Package) GetAllEnums() []*ASTNode {
+	enums := []*ASTNode{}
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			for _, child := range file.AST.Children {
+				if child.Type == NODE_ENUM_DECLARATION {
+					enums = append(enums, child)
+				}
+			}
+		}
+	}
+	return enums
+}
+
+// MergeAST creates a single AST from all package files, deduplicating imports
+func (pkg *Package) MergeAST() *ASTNode {
+	merged := &ASTNode{Type: NODE_PROGRAM}
+	seenImports := make(map[string]bool)
+
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			for _, child := range file.AST.Children {
+				// Skip program declarations in merged output
+				if child.Type == NODE_PROGRAM_DECLARATION {
+					continue
+				}
+
+				// Deduplicate imports
+				if child.Type == NODE_IMPORT_STATEMENT {
+					importKey := child.Value + "|" + child.DataType // path + namespace
+					if seenImports[importKey] {
+						continue
+					}
+					seenImports[importKey] = true
+				}
+
+				merged.Children = append(merged.Children, child)
+			}
+		}
+	}
+
+	return merged
+}
+
+// resolveImports recursively resolves all imports in a package
+// and merges them into a unified set of imports. Each import is resolved
+// from the file that makes it.
+func resolveImports(pkg *Package, pm *PackageManager) (map[string]*Package, error) {
+	allImports := make(map[string]*Package)
+
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			for _, child := range file.AST.Children {
+				if child.Type == NODE_IMPORT_STATEMENT {
+					importPath := child.Value
+					importedPkg, err := pm.ResolveImport(importPath, file.Path)
+					if err != nil {
+						return nil, fmt.Errorf("failed to resolve import '%s': %v", importPath, err)
+					}
+
+					// Store with namespace key
+					namespace := child.DataType
+					if namespace == "" {
+						namespace = importedPkg.Name
+					}
+					allImports[namespace] = importedPkg
+
+					// Recursively resolve imports in the imported package
+					nestedImports, err := resolveImports(importedPkg, pm)
+					if err != nil {
+						return nil, err
+					}
+
+					// Merge nested imports
+					for ns, nestedPkg := range nestedImports {
+						if _, exists := allImports[ns]; !exists {
+							allImports[ns] = nestedPkg
+						}
+					}
+				}
+			}
+		}
+	}
+	return allImports, nil
+}
+
+// MergeWithImports merges the package with all imported packages into a single AST
+func MergeWithImports(pkg *Package, imports map[string]*Package) *ASTNode {
+	merged := &ASTNode{Type: NODE_PROGRAM}
+	processedFunctions := make(map[string]bool) // Deduplicate functions
+	processedStructs := make(map[string]bool)   // Deduplicate structs
+	processedEnums := make(map[string]bool)     // Deduplicate enums
+
+	// First, add all declarations from imported packages
+	for _, importedPkg := range imports {
+		for _, file := range importedPkg.Files {
+			if file.AST != nil {
+				for _, child := range file.AST.Children {
+					// Skip program declarations and imports
+					if child.Type == NODE_PROGRAM_DECLARATION || child.Type == NODE_REEXPORT {
+						continue
+					}
+
+					// An imported package's tests are run with the package
+					if child.Type == NODE_TEST_BLOCK {
+						continue
+					}
+
+					// Keep C header imports (.h files), skip .ahoy imports
+					if child.Type == NODE_IMPORT_STATEMENT {
+						if strings.HasSuffix(child.Value, ".h") {
+							// Keep C header imports for codegen
+							merged.Children = append(merged.Children, child)
+						}
+						continue
+					}
+
+					// Deduplicate by name
+					name := child.Value
+					shouldAdd := false
+
+					switch child.Type {
+					case NODE_FUNCTION:
+						if !processedFunctions[name] {
+							processedFunctions[name] = true
+							shouldAdd = true
+						}
+					case NODE_STRUCT_DECLARATION:
+						if !processedStructs[name] {
+							processedStructs[name] = true
+							shouldAdd = true
+						}
+					case NODE_ENUM_DECLARATION:
+						if !processedEnums[name] {
+							processedEnums[name] = true
+							shouldAdd = true
+						}
+					default:
+						shouldAdd = true
+					}
+
+					if shouldAdd {
+						merged.Children = append(merged.Children, child)
+					}
+				}
+			}
+		}
+	}
+
+	// Then add declarations from the main package
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			for _, child := range file.AST.Children {
+				// Skip program declarations; re-exports only matter to importers
+				if child.Type == NODE_PROGRAM_DECLARATION || child.Type == NODE_REEXPORT {
+					continue
+				}
+
+				// Keep C header imports (.h files), skip .ahoy imports
+				if child.Type == NODE_IMPORT_STATEMENT {
+					if strings.HasSuffix(child.Value, ".h") {
+						// Keep C header imports for codegen
+						merged.Children = append(merged.Children, child)
+					}
+					continue
+				}
+
+				// Deduplicate by name
+				name := child.Value
+				shouldAdd := false
+
+				switch child.Type {
+				case NODE_FUNCTION:
+					if !processedFunctions[name] {
+						processedFunctions[name] = true
+						shouldAdd = true
+					}
+				case NODE_STRUCT_DECLARATION:
+					if !processedStructs[name] {
+						processedStructs[name] = true
+						shouldAdd = true
+					}
+				case NODE_ENUM_DECLARATION:
+					if !processedEnums[name] {
+						processedEnums[name] = true
+						shouldAdd = true
+					}
+				default:
+					shouldAdd = true
+				}
+
+				if shouldAdd {
+					merged.Children = append(merged.Children, child)
+				}
+			}
+		}
+	}
+
+	return merged
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -63,19 +65,34 @@ const (
 	NODE_OBJECT_LITERAL
 	NODE_OBJECT_PROPERTY
 	NODE_OBJECT_ACCESS
-	NODE_TYPE_PROPERTY // .type property access
+	NODE_TYPE_PROPERTY   // .type property access
+	NODE_FIXED_ARRAY     // Fixed-size stack array like int[64]
+	NODE_BYTES_LITERAL   // Byte buffer literal like b"\x00\xFF"
+	NODE_EMBED_STATEMENT // embed "path" as name
+	NODE_TEST_BLOCK      // test "name": body, run by ahoy test
+	NODE_EXTERN          // extern name |params| type from "c_name" link "lib"
+	NODE_COMPTIME        // comptime name|args|, a call evaluated while compiling
+	NODE_INLINE          // a call replaced by its function's body: bindings of the arguments, then the body
+	NODE_REEXPORT        // pub use namespace.name, a name of an imported package made part of this one
+	NODE_RECOVER         // recover name: body, which a panic in body jumps out of, setting name to its message
 )
 
 type ASTNode struct {
-	Type         NodeType
-	Value        string
-	Children     []*ASTNode
-	DataType     string
-	Line         int
-	Column       int      // Column position in source
-	DefaultValue *ASTNode // For default parameter values
-	EnumType     string   // Type of enum (int, string, color, etc.) or "" for mixed
-	IsMutable    bool     // For enum members marked as mutable
+	Type            NodeType
+	Value           string
+	Children        []*ASTNode
+	DataType        string
+	Line            int
+	Column          int          // Column position in source
+	DefaultValue    *ASTNode     // For default parameter values
+	Capacity        *ASTNode     // How many keys a dict[n] literal makes room for
+	Tag             string       // Struct field tag, like json:"hp,omitempty", or a function's inline, noinline or export annotation
+	EnumType        string       // Type of enum (int, string, color, etc.) or "" for mixed
+	IsMutable       bool         // For enum members marked as mutable
+	Span            Span         // Source text the node was parsed from
+	Comments        []string     // Comment lines directly above a statement, in order
+	TrailingComment string       // Comment ending a statement's last line
+	Header          *CHeaderInfo // What a C header import's header declares, once bound
 }
 
 type ParseError struct {
@@ -88,6 +105,8 @@ type StructField struct {
 	Name         string
 	Type         string
 	DefaultValue *ASTNode
+	Tag          string  // tags as written, like json:"hp,omitempty"
+	JSON         JSONTag // what the json tag says
 }
 
 type StructDefinition struct {
@@ -135,14 +154,16 @@ type CFunction struct {
 }
 
 type CParameter struct {
-	Name string
-	Type string
+	Name     string
+	Type     string
+	Callback *CFunction // signature of a function pointer parameter, nil otherwise
 }
 
 type CEnum struct {
 	Name       string
 	Values     map[string]int
 	ValueLines map[string]int // Line number for each enum value
+	Partial    bool           // Some members' values couldn't be worked out, so Values lacks them
 	Line       int            // Line number in header file
 }
 
@@ -168,6 +189,7 @@ type CHeaderInfo struct {
 	Enums     map[string]*CEnum
 	Defines   map[string]*CDefine
 	Structs   map[string]*CStruct
+	Callbacks map[string]*CFunction // function pointer typedefs
 }
 
 type Parser struct {
@@ -180,6 +202,7 @@ type Parser struct {
 	LintMode           bool
 	Errors             []ParseError
 	variableTypes      map[string]string             // Track variable types
+	enumVars           map[string]string             // Track the enum of variables declared with one of its members
 	constants          map[string]int                // Track constant declarations (name -> line number)
 	structs            map[string]*StructDefinition  // Track struct definitions
 	enums              map[string]*EnumDefinition    // Track enum definitions
@@ -199,15 +222,19 @@ type Parser struct {
 	hasProgramDecl     bool                          // Track if program declaration exists
 	inFunctionBody     bool                          // Track if we're inside a function body
 	sourceFilePath     string                        // Source file path for resolving relative imports
+	trailingComment    int                           // Line of the last comment attached as a trailing comment
 }
 
-func Parse(tokens []Token) *ASTNode {
-	parser := &Parser{
+// newParser returns a parser positioned at the first token with empty
+// declaration tables
+func newParser(tokens []Token, lintMode bool, sourceFilePath string) *Parser {
+	return &Parser{
 		tokens:             tokens,
 		pos:                0,
-		LintMode:           false,
+		LintMode:           lintMode,
 		Errors:             []ParseError{},
 		variableTypes:      make(map[string]string),
+		enumVars:           make(map[string]string),
 		constants:          make(map[string]int),
 		structs:            make(map[string]*StructDefinition),
 		enums:              make(map[string]*EnumDefinition),
@@ -225,97 +252,40 @@ func Parse(tokens []Token) *ASTNode {
 		functionDepth:      0,
 		hasProgramDecl:     false,
 		inFunctionBody:     false,
-		sourceFilePath:     "",
+		sourceFilePath:     sourceFilePath,
 	}
-	return parser.parseProgram()
+}
+
+func Parse(tokens []Token) *ASTNode {
+	return newParser(tokens, false, "").parseProgram()
 }
 
 func ParseWithPath(tokens []Token, sourceFilePath string) *ASTNode {
-	parser := &Parser{
-		tokens:             tokens,
-		pos:                0,
-		LintMode:           false,
-		Errors:             []ParseError{},
-		variableTypes:      make(map[string]string),
-		constants:          make(map[string]int),
-		structs:            make(map[string]*StructDefinition),
-		enums:              make(map[string]*EnumDefinition),
-		typeAliases:        make(map[string]string),
-		unionTypes:         make(map[string][]string),
-		objectLiterals:     make(map[string]map[string]bool),
-		currentFunctionRet: "",
-		functionScope:      make(map[string]string),
-		functions:          make(map[string]*FunctionSignature),
-		arrayLengths:       make(map[string]ArrayInfo),
-		cHeaders:           make(map[string]*CHeaderInfo),
-		cHeaderGlobal:      &CHeaderInfo{Functions: make(map[string]*CFunction), Enums: make(map[string]*CEnum), Defines: make(map[string]*CDefine), Structs: make(map[string]*CStruct)},
-		blockDepth:         0,
-		loopVarScopes:      make([]map[string]string, 0),
-		functionDepth:      0,
-		hasProgramDecl:     false,
-		inFunctionBody:     false,
-		sourceFilePath:     sourceFilePath,
-	}
-	return parser.parseProgram()
+	return newParser(tokens, false, sourceFilePath).parseProgram()
 }
 
 func ParseLint(tokens []Token) (*ASTNode, []ParseError) {
-	parser := &Parser{
-		tokens:             tokens,
-		pos:                0,
-		LintMode:           true,
-		Errors:             []ParseError{},
-		variableTypes:      make(map[string]string),
-		constants:          make(map[string]int),
-		structs:            make(map[string]*StructDefinition),
-		enums:              make(map[string]*EnumDefinition),
-		typeAliases:        make(map[string]string),
-		unionTypes:         make(map[string][]string),
-		objectLiterals:     make(map[string]map[string]bool),
-		currentFunctionRet: "",
-		functionScope:      make(map[string]string),
-		functions:          make(map[string]*FunctionSignature),
-		arrayLengths:       make(map[string]ArrayInfo),
-		cHeaders:           make(map[string]*CHeaderInfo),
-		cHeaderGlobal:      &CHeaderInfo{Functions: make(map[string]*CFunction), Enums: make(map[string]*CEnum), Defines: make(map[string]*CDefine), Structs: make(map[string]*CStruct)},
-		blockDepth:         0,
-		loopVarScopes:      make([]map[string]string, 0),
-		functionDepth:      0,
-		hasProgramDecl:     false,
-		inFunctionBody:     false,
-		sourceFilePath:     "",
-	}
-	ast := parser.parseProgram()
-	return ast, parser.Errors
+	return ParseLintWithPath(tokens, "")
 }
 
-func ParseLintWithPath(tokens []Token, sourceFilePath string) (*ASTNode, []ParseError) {
-	parser := &Parser{
-		tokens:             tokens,
-		pos:                0,
-		LintMode:           true,
-		Errors:             []ParseError{},
-		variableTypes:      make(map[string]string),
-		constants:          make(map[string]int),
-		structs:            make(map[string]*StructDefinition),
-		enums:              make(map[string]*EnumDefinition),
-		typeAliases:        make(map[string]string),
-		unionTypes:         make(map[string][]string),
-		objectLiterals:     make(map[string]map[string]bool),
-		currentFunctionRet: "",
-		functionScope:      make(map[string]string),
-		functions:          make(map[string]*FunctionSignature),
-		arrayLengths:       make(map[string]ArrayInfo),
-		cHeaders:           make(map[string]*CHeaderInfo),
-		cHeaderGlobal:      &CHeaderInfo{Functions: make(map[string]*CFunction), Enums: make(map[string]*CEnum), Defines: make(map[string]*CDefine), Structs: make(map[string]*CStruct)},
-		blockDepth:         0,
-		loopVarScopes:      make([]map[string]string, 0),
-		functionDepth:      0,
-		hasProgramDecl:     false,
-		inFunctionBody:     false,
-		sourceFilePath:     sourceFilePath,
-	}
-	ast := parser.parseProgram()
+// ParseLintWithPath parses like ParseWithPath but records syntax errors
+// instead of stopping at the first. A statement with a syntax error is left
+// out of the program and parsing resumes at the next statement, so the
+// result holds everything else in the file. It never panics: a parser bug
+// on malformed input comes back as an error, with an empty program.
+func ParseLintWithPath(tokens []Token, sourceFilePath string) (ast *ASTNode, errors []ParseError) {
+	parser := newParser(tokens, true, sourceFilePath)
+	defer func() {
+		if r := recover(); r != nil {
+			token := parser.current()
+			ast, errors = &ASTNode{Type: NODE_PROGRAM}, append(parser.Errors, ParseError{
+				Message: fmt.Sprintf("internal parser error: %v", r),
+				Line:    token.Line,
+				Column:  token.Column,
+			})
+		}
+	}()
+	ast = parser.parseProgram()
 	return ast, parser.Errors
 }
 
@@ -340,6 +310,14 @@ func (p *Parser) skipNewlines() {
 	}
 }
 
+// skipLineBreaks skips the line breaks inside a bracketed or piped list,
+// along with the indentation changes of the lines it continues on
+func (p *Parser) skipLineBreaks() {
+	for p.current().Type == TOKEN_NEWLINE || p.current().Type == TOKEN_INDENT || p.current().Type == TOKEN_DEDENT {
+		p.advance()
+	}
+}
+
 func (p *Parser) recordError(message string) {
 	token := p.current()
 	p.Errors = append(p.Errors, ParseError{
@@ -349,6 +327,116 @@ func (p *Parser) recordError(message string) {
 	})
 }
 
+// syntaxBailout is panicked in lint mode to abandon the statement being
+// parsed; parseStatement recovers it
+type syntaxBailout struct{}
+
+// syntaxError reports an error the current statement can't be parsed past.
+// Outside lint mode it stops parsing. In lint mode it records the error and
+// unwinds to the enclosing statement, so one mistake isn't reported again
+// by everything parsed after it.
+func (p *Parser) syntaxError(message string) {
+	if !p.LintMode {
+		panic(message)
+	}
+	p.recordError(message)
+	panic(syntaxBailout{})
+}
+
+// nesting is the parser state a statement can leave behind when it fails
+type nesting struct {
+	blockDepth         int
+	loopScopes         int
+	functionDepth      int
+	inFunctionCall     int
+	inFunctionBody     bool
+	inArrayLiteral     bool
+	inObjectLiteral    bool
+	inDictLiteral      bool
+	currentFunctionRet string
+}
+
+func (p *Parser) saveNesting() nesting {
+	return nesting{
+		blockDepth:         p.blockDepth,
+		loopScopes:         len(p.loopVarScopes),
+		functionDepth:      p.functionDepth,
+		inFunctionCall:     p.inFunctionCall,
+		inFunctionBody:     p.inFunctionBody,
+		inArrayLiteral:     p.inArrayLiteral,
+		inObjectLiteral:    p.inObjectLiteral,
+		inDictLiteral:      p.inDictLiteral,
+		currentFunctionRet: p.currentFunctionRet,
+	}
+}
+
+func (p *Parser) restoreNesting(state nesting) {
+	p.blockDepth = state.blockDepth
+	if len(p.loopVarScopes) > state.loopScopes {
+		p.loopVarScopes = p.loopVarScopes[:state.loopScopes]
+	}
+	p.functionDepth = state.functionDepth
+	p.inFunctionCall = state.inFunctionCall
+	p.inFunctionBody = state.inFunctionBody
+	p.inArrayLiteral = state.inArrayLiteral
+	p.inObjectLiteral = state.inObjectLiteral
+	p.inDictLiteral = state.inDictLiteral
+	p.currentFunctionRet = state.currentFunctionRet
+}
+
+// recoverStatement handles a panic from the statement that began at start.
+// Syntax errors are recorded and parsing resumes after the statement; any
+// other panic is a parser bug and keeps unwinding.
+func (p *Parser) recoverStatement(r any, start int, state nesting) {
+	switch r := r.(type) {
+	case syntaxBailout:
+	case string:
+		p.recordError(r)
+	default:
+		panic(r)
+	}
+	p.restoreNesting(state)
+	p.synchronize(start)
+}
+
+// synchronize skips the rest of a statement that failed to parse: to the
+// end of its line and, when the lines after it are indented, past that
+// block and the $ closing it
+func (p *Parser) synchronize(start int) {
+	if p.pos == start {
+		// Always make progress; a stray layout token is all there is to skip
+		token := p.current()
+		p.advance()
+		if isLayoutToken(token.Type) {
+			return
+		}
+	}
+	for !isLayoutToken(p.current().Type) {
+		p.advance()
+	}
+	if p.current().Type == TOKEN_NEWLINE && p.peek(1).Type == TOKEN_INDENT {
+		p.advance()
+	}
+	if p.current().Type != TOKEN_INDENT {
+		return
+	}
+	for depth := 0; p.current().Type != TOKEN_EOF; {
+		switch p.current().Type {
+		case TOKEN_INDENT:
+			depth++
+		case TOKEN_DEDENT:
+			depth--
+		}
+		p.advance()
+		if depth == 0 {
+			break
+		}
+	}
+	if p.current().Type == TOKEN_END && !strings.HasPrefix(p.current().Value, "$#") {
+		p.advance()
+	}
+}
+
 // recordErrorAtLine records an error at a specific line number
 func (p *Parser) recordErrorAtLine(message string, line int) {
 	p.Errors = append(p.Errors, ParseError{
@@ -358,6 +446,161 @@ func (p *Parser) recordErrorAtLine(message string, line int) {
 	})
 }
 
+// isLayoutToken reports whether t only marks line structure
+func isLayoutToken(t TokenType) bool {
+	return t == TOKEN_NEWLINE || t == TOKEN_INDENT || t == TOKEN_DEDENT || t == TOKEN_SEMICOLON || t == TOKEN_EOF
+}
+
+// consumed returns the indexes of the first and last tokens other than
+// layout consumed since start, or -1 if there were none
+func (p *Parser) consumed(start int) (int, int) {
+	end := min(p.pos, len(p.tokens))
+	for start < end && isLayoutToken(p.tokens[start].Type) {
+		start++
+	}
+	for end > start && isLayoutToken(p.tokens[end-1].Type) {
+		end--
+	}
+	if start == end {
+		return -1, -1
+	}
+	return start, end - 1
+}
+
+// spanned widens node's span to cover the tokens consumed since start
+func (p *Parser) spanned(node *ASTNode, start int) *ASTNode {
+	if node == nil {
+		return nil
+	}
+	if first, last := p.consumed(start); first >= 0 {
+		node.Span = joinSpans(node.Span, Span{
+			Start:       p.tokens[first].Span.Start,
+			End:         p.tokens[last].Span.End,
+			StartLine:   p.tokens[first].Span.StartLine,
+			StartColumn: p.tokens[first].Span.StartColumn,
+			EndLine:     p.tokens[last].Span.EndLine,
+			EndColumn:   p.tokens[last].Span.EndColumn,
+		})
+	}
+	return node
+}
+
+// joinSpans returns the smallest span covering a and b
+func joinSpans(a, b Span) Span {
+	if a.IsZero() {
+		return b
+	}
+	if b.IsZero() {
+		return a
+	}
+	if b.Start < a.Start {
+		a.Start, a.StartLine, a.StartColumn = b.Start, b.StartLine, b.StartColumn
+	}
+	if b.End > a.End {
+		a.End, a.EndLine, a.EndColumn = b.End, b.EndLine, b.EndColumn
+	}
+	return a
+}
+
+// fillSpans gives nodes built without going through parseStatement or
+// parseExpression, such as operators and parameters, the span of their
+// token and children. Tokens are looked up within the nearest span above.
+func (p *Parser) fillSpans(node *ASTNode, within Span) Span {
+	if node == nil {
+		return Span{}
+	}
+	if !node.Span.IsZero() {
+		within = node.Span
+	}
+	children := Span{}
+	for _, child := range node.Children {
+		children = joinSpans(children, p.fillSpans(child, within))
+	}
+	children = joinSpans(children, p.fillSpans(node.DefaultValue, within))
+	children = joinSpans(children, p.fillSpans(node.Capacity, within))
+	if node.Span.IsZero() {
+		node.Span = joinSpans(p.tokenSpan(node, within), children)
+	}
+	return node.Span
+}
+
+// tokenSpan finds the span of the token a node was made from by its line
+// and column or, failing that, its value
+func (p *Parser) tokenSpan(node *ASTNode, within Span) Span {
+	if node.Line <= 0 {
+		return Span{}
+	}
+	i := sort.Search(len(p.tokens), func(i int) bool {
+		return p.tokens[i].Line >= node.Line && p.tokens[i].Line > 0
+	})
+	var byValue Span
+	for ; i < len(p.tokens) && p.tokens[i].Line == node.Line; i++ {
+		token := p.tokens[i]
+		if isLayoutToken(token.Type) {
+			continue
+		}
+		if !within.IsZero() && (token.Span.Start < within.Start || token.Span.End > within.End) {
+			continue
+		}
+		if node.Column > 0 && token.Column == node.Column {
+			return token.Span
+		}
+		if byValue.IsZero() && node.Value != "" && token.Value == node.Value {
+			byValue = token.Span
+		}
+	}
+	return byValue
+}
+
+// leadingComments returns the comment lines directly above the current
+// token when it starts its line, if they are indented the same way
+func (p *Parser) leadingComments() []string {
+	start := p.current()
+	i := p.pos - 1
+	for i >= 0 && (p.tokens[i].Type == TOKEN_INDENT || p.tokens[i].Type == TOKEN_DEDENT) {
+		i--
+	}
+	if i >= 0 && p.tokens[i].Type != TOKEN_NEWLINE {
+		return nil
+	}
+
+	var comments []string
+	line := start.Line - 1
+	for i >= 0 {
+		token := p.tokens[i]
+		if token.Type != TOKEN_NEWLINE || token.Comment == "" || token.Line != line ||
+			token.Span.StartColumn != start.Span.StartColumn {
+			break
+		}
+		// The comment must be alone on its line
+		j := i - 1
+		for j >= 0 && (p.tokens[j].Type == TOKEN_INDENT || p.tokens[j].Type == TOKEN_DEDENT) {
+			j--
+		}
+		if j >= 0 && p.tokens[j].Type != TOKEN_NEWLINE {
+			break
+		}
+		comments = append([]string{token.Comment}, comments...)
+		line--
+		i = j
+	}
+	return comments
+}
+
+// attachTrailingComment gives stmt the comment that directly follows it on
+// its last line, unless a statement nested in it took the comment already
+func (p *Parser) attachTrailingComment(stmt *ASTNode, start int) {
+	_, last := p.consumed(start)
+	if last < 0 || last+1 >= len(p.tokens) {
+		return
+	}
+	next := p.tokens[last+1]
+	if next.Type == TOKEN_NEWLINE && next.Comment != "" && next.Line != p.trailingComment {
+		stmt.TrailingComment = next.Comment
+		p.trailingComment = next.Line
+	}
+}
+
 // validateNoGlobalFunctionCalls checks if a statement contains function calls at global scope
 func (p *Parser) validateNoGlobalFunctionCalls(node *ASTNode) {
 	if node == nil {
@@ -407,6 +650,8 @@ func (p *Parser) inferType(node *ASTNode) string {
 		return "int"
 	case NODE_STRING, NODE_F_STRING:
 		return "string"
+	case NODE_BYTES_LITERAL:
+		return "bytes"
 	case NODE_CHAR:
 		return "char"
 	case NODE_BOOLEAN:
@@ -621,7 +866,7 @@ func (p *Parser) validateArrayAccess(arrayNode *ASTNode, indexNode *ASTNode, lin
 func tokenTypeName(t TokenType) string {
 	names := map[TokenType]string{
 		TOKEN_EOF: "EOF", TOKEN_IDENTIFIER: "identifier", TOKEN_NUMBER: "number",
-		TOKEN_STRING: "string", TOKEN_CHAR: "char", TOKEN_F_STRING: "f-string",
+		TOKEN_STRING: "string", TOKEN_CHAR: "char", TOKEN_F_STRING: "f-string", TOKEN_BYTES_STRING: "bytes literal",
 		TOKEN_ASSIGN: "':'", TOKEN_IS: "'is'", TOKEN_NOT: "'not'",
 		TOKEN_OR: "'or'", TOKEN_AND: "'and'", TOKEN_THEN: "'then'",
 		TOKEN_ON: "'on'", TOKEN_IF: "'if'", TOKEN_ELSE: "'else'",
@@ -641,10 +886,11 @@ func tokenTypeName(t TokenType) string {
 		TOKEN_RANGLE: "'>'", TOKEN_COMMA: "','", TOKEN_DOT: "'.'",
 		TOKEN_SEMICOLON: "';'", TOKEN_NEWLINE: "newline", TOKEN_INDENT: "indent",
 		TOKEN_DEDENT: "dedent", TOKEN_INT_TYPE: "type 'int'", TOKEN_FLOAT_TYPE: "type 'float'",
-		TOKEN_STRING_TYPE: "type 'string'", TOKEN_BOOL_TYPE: "type 'bool'",
+		TOKEN_STRING_TYPE: "type 'string'", TOKEN_CHAR_TYPE: "type 'char'", TOKEN_BOOL_TYPE: "type 'bool'",
 		TOKEN_DICT_TYPE: "type 'dict'", TOKEN_ARRAY_TYPE: "type 'array'",
 		TOKEN_TRUE: "'true'", TOKEN_FALSE: "'false'",
 		TOKEN_ENUM: "'enum'", TOKEN_STRUCT: "'struct'", TOKEN_TYPE: "'type'",
+		TOKEN_ALIAS: "'alias'", TOKEN_UNION: "'union'",
 		TOKEN_DO: "'do'", TOKEN_HALT: "'halt'", TOKEN_NEXT: "'next'",
 		TOKEN_ASSERT: "'assert'", TOKEN_DEFER: "'defer'",
 		TOKEN_DOUBLE_COLON: "'::'", TOKEN_WALRUS: "':='", TOKEN_QUESTION: "'?'", TOKEN_TERNARY: "'??'",
@@ -652,6 +898,7 @@ func tokenTypeName(t TokenType) string {
 		TOKEN_AT: "'@'", TOKEN_END: "'$'",
 		TOKEN_PLUS_ASSIGN: "'+='", TOKEN_MINUS_ASSIGN: "'-='",
 		TOKEN_MULTIPLY_ASSIGN: "'*='", TOKEN_DIVIDE_ASSIGN: "'/='", TOKEN_MODULO_ASSIGN: "'%='",
+		TOKEN_CARET: "'^'", TOKEN_AMPERSAND: "'&'",
 	}
 	if name, ok := names[t]; ok {
 		return name
@@ -704,11 +951,14 @@ func (p *Parser) copyASTNode(node *ASTNode) *ASTNode {
 		children[i] = p.copyASTNode(child)
 	}
 
-	// Copy default value if present
-	var defaultValue *ASTNode
+	// Copy default value and capacity if present
+	var defaultValue, capacity *ASTNode
 	if node.DefaultValue != nil {
 		defaultValue = p.copyASTNode(node.DefaultValue)
 	}
+	if node.Capacity != nil {
+		capacity = p.copyASTNode(node.Capacity)
+	}
 
 	return &ASTNode{
 		Type:         node.Type,
@@ -717,6 +967,7 @@ func (p *Parser) copyASTNode(node *ASTNode) *ASTNode {
 		DataType:     node.DataType,
 		Line:         node.Line,
 		DefaultValue: defaultValue,
+		Capacity:     capacity,
 		EnumType:     node.EnumType,
 		IsMutable:    node.IsMutable,
 	}
@@ -732,20 +983,11 @@ func (p *Parser) skipWhitespace() {
 func (p *Parser) expect(tokenType TokenType) Token {
 	if p.current().Type != tokenType {
 		current := p.current()
-		errMsg := fmt.Sprintf("Expected %s, got %s at line %d:%d",
+		p.syntaxError(fmt.Sprintf("Expected %s, got %s at line %d:%d",
 			tokenTypeName(tokenType),
 			tokenTypeName(current.Type),
 			current.Line,
-			current.Column)
-		if p.LintMode {
-			p.recordError(errMsg)
-			// In lint mode, return current token and advance to continue parsing
-			token := p.current()
-			p.advance()
-			return token
-		} else {
-			panic(errMsg)
-		}
+			current.Column))
 	}
 	token := p.current()
 	p.advance()
@@ -760,37 +1002,72 @@ func (p *Parser) parseProgram() *ASTNode {
 			p.advance()
 			continue
 		}
+		p.parseTopLevelStatement(program)
+	}
 
-		// Save position to detect if we're stuck
-		oldPos := p.pos
+	for _, child := range program.Children {
+		program.Span = joinSpans(program.Span, child.Span)
+	}
+	return program
+}
 
-		stmt := p.parseStatement()
-		if stmt != nil {
-			program.Children = append(program.Children, stmt)
+// parseTopLevelStatement parses one statement of the program and adds it to
+// program's children
+func (p *Parser) parseTopLevelStatement(program *ASTNode) {
+	// Save position to detect if we're stuck
+	oldPos := p.pos
 
-			// Track if we've seen non-import statements
-			if stmt.Type != NODE_IMPORT_STATEMENT && stmt.Type != NODE_PROGRAM_DECLARATION {
-				p.seenNonImport = true
-			}
-		}
+	stmt := p.parseStatement()
+	if stmt != nil {
+		p.fillSpans(stmt, Span{})
+		program.Children = append(program.Children, stmt)
 
-		// After a statement, accept either newline or semicolon
-		if p.current().Type == TOKEN_SEMICOLON {
-			p.advance()
-			// Continue to parse next statement on same line
+		// Track if we've seen non-import statements
+		if stmt.Type != NODE_IMPORT_STATEMENT && stmt.Type != NODE_PROGRAM_DECLARATION && stmt.Type != NODE_REEXPORT {
+			p.seenNonImport = true
 		}
+	}
 
-		// Safety check: if position hasn't advanced, force advance to prevent infinite loop
-		if p.pos == oldPos && p.current().Type != TOKEN_EOF {
-			// We're stuck - skip this token to avoid infinite loop
-			p.advance()
-		}
+	// After a statement, accept either newline or semicolon
+	if p.current().Type == TOKEN_SEMICOLON {
+		p.advance()
+		// Continue to parse next statement on same line
 	}
 
-	return program
+	// Safety check: if position hasn't advanced, force advance to prevent infinite loop
+	if p.pos == oldPos && p.current().Type != TOKEN_EOF {
+		// We're stuck - skip this token to avoid infinite loop
+		p.advance()
+	}
 }
 
-func (p *Parser) parseStatement() *ASTNode {
+// parseStatement parses a statement and records its span and the comments
+// around it. In lint mode a statement with a syntax error is dropped and
+// parsing resumes at the next one.
+func (p *Parser) parseStatement() (stmt *ASTNode) {
+	start := p.pos
+	if p.LintMode {
+		state := p.saveNesting()
+		defer func() {
+			if r := recover(); r != nil {
+				p.recoverStatement(r, start, state)
+				stmt = nil
+			}
+		}()
+	}
+	comments := p.leadingComments()
+	stmt = p.spanned(p.parseStatementKind(), start)
+	if stmt == nil {
+		return nil
+	}
+	if stmt.Comments == nil {
+		stmt.Comments = comments
+	}
+	p.attachTrailingComment(stmt, start)
+	return stmt
+}
+
+func (p *Parser) parseStatementKind() *ASTNode {
 	switch p.current().Type {
 	case TOKEN_PROGRAM:
 		return p.parseProgramDeclaration()
@@ -890,6 +1167,30 @@ func (p *Parser) parseStatement() *ASTNode {
 		if p.current().Value == "json" && p.peek(1).Type == TOKEN_ASSIGN && p.peek(2).Type == TOKEN_STRUCT {
 			return p.parseJsonStructDeclaration()
 		}
+		// Check for embed "path" as name
+		if p.current().Value == "embed" && p.peek(1).Type == TOKEN_STRING {
+			return p.parseEmbedStatement()
+		}
+		// Check for test "name": body
+		if p.current().Value == "test" && p.peek(1).Type == TOKEN_STRING && p.peek(2).Type == TOKEN_ASSIGN {
+			return p.parseTestBlock()
+		}
+		// Check for from package import names
+		if p.current().Value == "from" && (p.peek(1).Type == TOKEN_IDENTIFIER || p.peek(1).Type == TOKEN_STRING) && p.peek(2).Type == TOKEN_IMPORT {
+			return p.parseFromImport()
+		}
+		// Check for recover: body and recover name: body
+		if p.current().Value == "recover" && p.isRecoverBlock() {
+			return p.parseRecoverBlock()
+		}
+		// Check for pub use namespace.name
+		if p.current().Value == "pub" && p.peek(1).Type == TOKEN_IDENTIFIER && p.peek(1).Value == "use" {
+			return p.parseReexport()
+		}
+		// Check for extern name |params| type
+		if p.current().Value == "extern" && p.peek(1).Type == TOKEN_IDENTIFIER && p.peek(2).Type == TOKEN_PIPE {
+			return p.parseExternDeclaration()
+		}
 		// Check for constant declaration (name ::)
 		nextType := p.peek(1).Type
 		if nextType == TOKEN_DOUBLE_COLON {
@@ -1208,6 +1509,13 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 	startLine := p.current().Line
 	p.expect(TOKEN_SWITCH)
 	expr := p.parseExpression()
+	subjectEnum := p.switchSubjectEnum(expr)
+
+	// switch d has: matches on the keys a dict holds
+	keyMatch := p.current().Type == TOKEN_IDENTIFIER && p.current().Value == "has"
+	if keyMatch {
+		p.advance()
+	}
 
 	// Expect ':' after switch expression
 	if p.current().Type == TOKEN_ASSIGN { // colon
@@ -1231,6 +1539,9 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 		Type:     NODE_SWITCH_STATEMENT,
 		Children: []*ASTNode{expr}, // First child is the switch expression
 	}
+	if keyMatch {
+		switchStmt.Value = "has"
+	}
 
 	// Parse cases: each case starts with 'on' keyword (except default case with '_')
 	maxSwitchIterations := 10000 // Safety limit
@@ -1272,11 +1583,10 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 		// Parse case values - could be single, list (with commas), or range (with 'to')
 		caseValues := []*ASTNode{}
 
-		if !isDefaultCase {
+		if !isDefaultCase && keyMatch {
+			caseValues = p.parseSwitchHasKeys()
+		} else if !isDefaultCase {
 			for {
-				// Save position to detect stuck loop
-				oldPos := p.pos
-
 				// Parse single case value
 				var caseValue *ASTNode
 				if p.current().Type == TOKEN_NUMBER {
@@ -1285,6 +1595,7 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 					caseValue = &ASTNode{
 						Type:  NODE_NUMBER,
 						Value: tok.Value,
+						Line:  tok.Line,
 					}
 				} else if p.current().Type == TOKEN_CHAR {
 					tok := p.current()
@@ -1292,6 +1603,7 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 					caseValue = &ASTNode{
 						Type:  NODE_CHAR,
 						Value: tok.Value,
+						Line:  tok.Line,
 					}
 				} else if p.current().Type == TOKEN_STRING {
 					tok := p.current()
@@ -1299,6 +1611,7 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 					caseValue = &ASTNode{
 						Type:  NODE_STRING,
 						Value: tok.Value,
+						Line:  tok.Line,
 					}
 				} else if p.current().Type == TOKEN_IDENTIFIER {
 					tok := p.current()
@@ -1315,10 +1628,12 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 							caseValue = &ASTNode{
 								Type:  NODE_MEMBER_ACCESS,
 								Value: member.Value, // member name
+								Line:  tok.Line,
 								Children: []*ASTNode{
 									{
 										Type:  NODE_IDENTIFIER,
 										Value: tok.Value, // enum name
+										Line:  tok.Line,
 									},
 								},
 							}
@@ -1327,6 +1642,7 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 							caseValue = &ASTNode{
 								Type:  NODE_IDENTIFIER,
 								Value: tok.Value,
+								Line:  tok.Line,
 							}
 						}
 					} else {
@@ -1334,11 +1650,12 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 						caseValue = &ASTNode{
 							Type:  NODE_IDENTIFIER,
 							Value: tok.Value,
+							Line:  tok.Line,
 						}
 
 						// Validate if this identifier is an ambiguous enum member
 						if p.LintMode {
-							p.validateEnumMemberInSwitch(tok.Value, tok.Line)
+							p.validateEnumMemberInSwitch(tok.Value, tok.Line, subjectEnum)
 						}
 					}
 				} else {
@@ -1396,11 +1713,6 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 					// Single value or end of value list
 					break
 				}
-
-				// Safety check: if position hasn't advanced, break to avoid infinite loop
-				if p.pos == oldPos {
-					break
-				}
 			}
 		} else {
 			// Default case with '_'
@@ -1556,6 +1868,48 @@ func (p *Parser) parseSwitchStatement() *ASTNode {
 	return switchStmt
 }
 
+// parseSwitchHasKeys parses the keys of a switch d has: case, "key" as name
+// type, ... A key binds its value to name, or when there's no as to the key
+// itself if it is a name, and type is the value's type in an untyped dict.
+func (p *Parser) parseSwitchHasKeys() []*ASTNode {
+	var keys []*ASTNode
+	for {
+		tok := p.current()
+		if tok.Type != TOKEN_STRING {
+			errMsg := fmt.Sprintf("Expected a string key in switch has case at line %d", tok.Line)
+			if !p.LintMode {
+				panic(errMsg)
+			}
+			p.recordError(errMsg)
+			return keys
+		}
+		p.advance()
+		key := &ASTNode{Type: NODE_STRING, Value: tok.Value, Line: tok.Line}
+		name := ""
+		if isIdentifier(tok.Value) {
+			name = tok.Value
+		}
+		if p.current().Type == TOKEN_IDENTIFIER && p.current().Value == "as" {
+			p.advance()
+			name = p.expect(TOKEN_IDENTIFIER).Value
+		}
+		binding := &ASTNode{Type: NODE_IDENTIFIER, Value: name, Line: tok.Line}
+		switch p.current().Type {
+		case TOKEN_INT_TYPE, TOKEN_FLOAT_TYPE, TOKEN_STRING_TYPE, TOKEN_BOOL_TYPE, TOKEN_CHAR_TYPE:
+			binding.DataType = p.current().Value
+			p.advance()
+		}
+		if name != "" {
+			key.Children = []*ASTNode{binding}
+		}
+		keys = append(keys, key)
+		if p.current().Type != TOKEN_COMMA {
+			return keys
+		}
+		p.advance()
+	}
+}
+
 // validateSwitchReturnTypes checks that all cases in a switch return compatible types
 func (p *Parser) validateSwitchReturnTypes(switchStmt *ASTNode, line int) {
 	p.validateSwitchReturnTypesWithExpected(switchStmt, "", line)
@@ -2221,9 +2575,7 @@ func (p *Parser) parseAhoyStatement() *ASTNode {
 	// Parse arguments until closing pipe (allow newlines for multiline calls)
 	for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 		// Skip newlines between arguments
-		for p.current().Type == TOKEN_NEWLINE {
-			p.advance()
-		}
+		p.skipLineBreaks()
 
 		if p.current().Type == TOKEN_PIPE || p.current().Type == TOKEN_EOF {
 			break
@@ -2235,14 +2587,10 @@ func (p *Parser) parseAhoyStatement() *ASTNode {
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
 			// Skip newlines after comma
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 		} else {
 			// Skip trailing newlines before closing pipe
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 			if p.current().Type != TOKEN_PIPE {
 				break
 			}
@@ -2276,9 +2624,7 @@ func (p *Parser) parsePrintStatement() *ASTNode {
 	// Parse arguments until closing pipe (allow newlines for multiline calls)
 	for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 		// Skip newlines between arguments
-		for p.current().Type == TOKEN_NEWLINE {
-			p.advance()
-		}
+		p.skipLineBreaks()
 
 		if p.current().Type == TOKEN_PIPE || p.current().Type == TOKEN_EOF {
 			break
@@ -2290,14 +2636,10 @@ func (p *Parser) parsePrintStatement() *ASTNode {
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
 			// Skip newlines after comma
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 		} else {
 			// Skip trailing newlines before closing pipe
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 			if p.current().Type != TOKEN_PIPE {
 				break
 			}
@@ -2313,15 +2655,30 @@ func (p *Parser) parsePrintStatement() *ASTNode {
 	return call
 }
 
+// logLevels are the levels of log.<level>|...|
+var logLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
 func (p *Parser) parseLogStatement() *ASTNode {
 	p.expect(TOKEN_LOG)
 
-	// log takes two arguments: message and file_path
+	// log.debug|fmt, args...| and friends log at a level; plain log takes
+	// two arguments: message and file_path
+	name := "log"
+	if p.current().Type == TOKEN_DOT {
+		p.advance()
+		level := p.current()
+		if level.Type == TOKEN_IDENTIFIER && logLevels[level.Value] {
+			name = "log." + level.Value
+		} else {
+			p.recordError(fmt.Sprintf("Unknown log level '%s': use log.debug, log.info, log.warn or log.error", level.Value))
+		}
+		p.advance()
+	}
 	p.expect(TOKEN_PIPE)
 
 	call := &ASTNode{
 		Type:  NODE_CALL,
-		Value: "log",
+		Value: name,
 		Line:  p.current().Line,
 	}
 
@@ -2331,9 +2688,7 @@ func (p *Parser) parseLogStatement() *ASTNode {
 	// Parse arguments until closing pipe (allow newlines for multiline calls)
 	for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 		// Skip newlines between arguments
-		for p.current().Type == TOKEN_NEWLINE {
-			p.advance()
-		}
+		p.skipLineBreaks()
 
 		if p.current().Type == TOKEN_PIPE || p.current().Type == TOKEN_EOF {
 			break
@@ -2345,14 +2700,10 @@ func (p *Parser) parseLogStatement() *ASTNode {
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
 			// Skip newlines after comma
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 		} else {
 			// Skip trailing newlines before closing pipe
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 			if p.current().Type != TOKEN_PIPE {
 				break
 			}
@@ -2386,9 +2737,7 @@ func (p *Parser) parsePanicStatement() *ASTNode {
 	// Parse arguments until closing pipe (allow newlines for multiline calls)
 	for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 		// Skip newlines between arguments
-		for p.current().Type == TOKEN_NEWLINE {
-			p.advance()
-		}
+		p.skipLineBreaks()
 
 		if p.current().Type == TOKEN_PIPE || p.current().Type == TOKEN_EOF {
 			break
@@ -2400,14 +2749,10 @@ func (p *Parser) parsePanicStatement() *ASTNode {
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
 			// Skip newlines after comma
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 		} else {
 			// Skip trailing newlines before closing pipe
-			for p.current().Type == TOKEN_NEWLINE {
-				p.advance()
-			}
+			p.skipLineBreaks()
 			if p.current().Type != TOKEN_PIPE {
 				break
 			}
@@ -2505,51 +2850,353 @@ func (p *Parser) parseReturnStatement() *ASTNode {
 		}
 	}
 
-	return ret
-}
-
-func (p *Parser) parseAssertStatement() *ASTNode {
-	assertToken := p.expect(TOKEN_ASSERT)
+	return ret
+}
+
+func (p *Parser) parseAssertStatement() *ASTNode {
+	assertToken := p.expect(TOKEN_ASSERT)
+
+	// Parse the condition expression, keeping its text for the failure message
+	start := p.pos
+	condition := p.parseExpression()
+
+	return &ASTNode{
+		Type:     NODE_ASSERT_STATEMENT,
+		Value:    p.sourceText(start),
+		Line:     assertToken.Line,
+		Children: []*ASTNode{condition},
+	}
+}
+
+// sourceText rebuilds the source of the tokens consumed since start. Each
+// token lands at its offset from the first, so spans of the nodes parsed
+// from them index the text too.
+func (p *Parser) sourceText(start int) string {
+	first, last := p.consumed(start)
+	if first < 0 {
+		return ""
+	}
+	base := p.tokens[first].Span.Start
+	text := []byte(strings.Repeat(" ", p.tokens[last].Span.End-base))
+	for _, token := range p.tokens[first : last+1] {
+		if isLayoutToken(token.Type) || token.Span.Start < base {
+			continue
+		}
+		value := token.Value
+		switch token.Type {
+		case TOKEN_STRING:
+			value = "\"" + value + "\""
+		case TOKEN_CHAR:
+			value = "'" + value + "'"
+		case TOKEN_F_STRING:
+			value = "f\"" + value + "\""
+		case TOKEN_BYTES_STRING:
+			value = "b\"" + value + "\""
+		}
+		copy(text[token.Span.Start-base:], value)
+	}
+	return string(text)
+}
+
+func (p *Parser) parseDeferStatement() *ASTNode {
+	deferToken := p.expect(TOKEN_DEFER)
+
+	// Parse the deferred statement
+	// Could be a function call, ahoy statement, print statement, etc.
+	var statement *ASTNode
+	if p.current().Type == TOKEN_PRINT {
+		statement = p.parsePrintStatement()
+	} else if p.current().Type == TOKEN_LOG {
+		statement = p.parseLogStatement()
+	} else if p.current().Type == TOKEN_PANIC {
+		statement = p.parsePanicStatement()
+	} else if p.current().Type == TOKEN_AHOY {
+		statement = p.parseAhoyStatement()
+	} else {
+		statement = p.parseExpression()
+	}
+
+	return &ASTNode{
+		Type:     NODE_DEFER_STATEMENT,
+		Line:     deferToken.Line,
+		Children: []*ASTNode{statement},
+	}
+}
+
+// parseEmbedStatement parses embed "path" as name, which compiles the file's
+// contents into the program as a bytes value
+func (p *Parser) parseEmbedStatement() *ASTNode {
+	embedToken := p.current()
+	p.advance()
+	path := unescapeC(p.expect(TOKEN_STRING).Value)
+
+	if p.current().Type != TOKEN_IDENTIFIER || p.current().Value != "as" {
+		errMsg := fmt.Sprintf("Expected 'as' and a name after the embedded file at line %d", embedToken.Line)
+		if p.LintMode {
+			p.recordErrorAtLine(errMsg, embedToken.Line)
+			return &ASTNode{Type: NODE_EMBED_STATEMENT, Value: path, Line: embedToken.Line}
+		}
+		panic(errMsg)
+	}
+	p.advance()
+	nameToken := p.expect(TOKEN_IDENTIFIER)
+
+	if p.inFunctionBody {
+		errMsg := fmt.Sprintf("embed must be at the top level, not inside a function, at line %d", embedToken.Line)
+		if p.LintMode {
+			p.recordErrorAtLine(errMsg, embedToken.Line)
+		} else {
+			panic(errMsg)
+		}
+	}
+
+	// Check the file exists (for linting); paths are relative to the source file
+	if p.LintMode {
+		resolvedPath := path
+		if !filepath.IsAbs(path) && p.sourceFilePath != "" {
+			resolvedPath = filepath.Join(filepath.Dir(p.sourceFilePath), path)
+		}
+		if _, err := os.Stat(resolvedPath); err != nil {
+			p.recordErrorAtLine(fmt.Sprintf("Embedded file does not exist: %s", path), embedToken.Line)
+		}
+	}
+
+	p.variableTypes[nameToken.Value] = "bytes"
+	return &ASTNode{
+		Type:  NODE_EMBED_STATEMENT,
+		Value: path,
+		Line:  embedToken.Line,
+		Children: []*ASTNode{
+			{Type: NODE_IDENTIFIER, Value: nameToken.Value, Line: nameToken.Line, Column: nameToken.Column},
+		},
+	}
+}
+
+// parseExternDeclaration parses extern name |params| type, optionally
+// followed by from "c_name" and link "library". It declares a C function by
+// its signature, for when its header is missing or can't be parsed; the
+// function is called by name and links against the C symbol c_name.
+func (p *Parser) parseExternDeclaration() *ASTNode {
+	externToken := p.current()
+	p.advance()
+	name := p.expect(TOKEN_IDENTIFIER)
+
+	if p.inFunctionBody || p.functionDepth > 0 {
+		errMsg := fmt.Sprintf("extern must be at the top level, not inside a function, at line %d", externToken.Line)
+		if p.LintMode {
+			p.recordErrorAtLine(errMsg, externToken.Line)
+		} else {
+			panic(errMsg)
+		}
+	}
+
+	p.expect(TOKEN_PIPE)
+	params := &ASTNode{Type: NODE_BLOCK}
+	for p.current().Type == TOKEN_IDENTIFIER {
+		paramName := p.current()
+		p.advance()
+		p.expect(TOKEN_ASSIGN)
+		params.Children = append(params.Children, &ASTNode{
+			Type:     NODE_IDENTIFIER,
+			Value:    paramName.Value,
+			DataType: p.parseExternType(),
+			Line:     paramName.Line,
+			Column:   paramName.Column,
+		})
+		if p.current().Type != TOKEN_COMMA {
+			break
+		}
+		p.advance()
+	}
+	p.expect(TOKEN_PIPE)
+
+	returnType := "void"
+	if p.current().Type == TOKEN_VOID {
+		p.advance()
+	} else if p.current().Type != TOKEN_NEWLINE && p.current().Type != TOKEN_EOF &&
+		!(p.current().Value == "from" || p.current().Value == "link") {
+		returnType = p.parseExternType()
+	}
+
+	cName := &ASTNode{Type: NODE_STRING, Value: name.Value, Line: name.Line}
+	library := &ASTNode{Type: NODE_STRING, Line: name.Line}
+	for p.current().Type == TOKEN_IDENTIFIER && p.peek(1).Type == TOKEN_STRING {
+		switch p.current().Value {
+		case "from":
+			p.advance()
+			cName.Value = unescapeC(p.expect(TOKEN_STRING).Value)
+		case "link":
+			p.advance()
+			library.Value = unescapeC(p.expect(TOKEN_STRING).Value)
+		default:
+			errMsg := fmt.Sprintf("Expected from or link after extern '%s', got '%s' at line %d",
+				name.Value, p.current().Value, p.current().Line)
+			if !p.LintMode {
+				panic(errMsg)
+			}
+			p.recordError(errMsg)
+			p.advance()
+			p.advance()
+		}
+	}
+
+	extern := &ASTNode{
+		Type:     NODE_EXTERN,
+		Value:    name.Value,
+		DataType: returnType,
+		Line:     externToken.Line,
+		Children: []*ASTNode{params, cName, library},
+	}
+
+	if p.LintMode {
+		if existing, exists := p.functions[name.Value]; exists {
+			p.recordErrorAtLine(fmt.Sprintf("Redeclaration of function '%s' (previously declared at line %d)", name.Value, existing.Line), name.Line)
+		}
+		paramInfos := []ParameterInfo{}
+		for _, param := range params.Children {
+			paramInfos = append(paramInfos, ParameterInfo{Name: param.Value, Type: param.DataType})
+		}
+		returnTypes := []string{}
+		if returnType != "void" {
+			returnTypes = append(returnTypes, returnType)
+		}
+		p.functions[name.Value] = &FunctionSignature{
+			Name:         name.Value,
+			Parameters:   paramInfos,
+			ReturnTypes:  returnTypes,
+			FunctionNode: extern,
+			Line:         name.Line,
+		}
+	}
+	return extern
+}
+
+// parseExternType parses the type of an extern parameter or result: an Ahoy
+// type or a C type name, with a * for each level of pointer
+func (p *Parser) parseExternType() string {
+	var externType string
+	if p.isTypeToken(p.current().Type) || p.current().Type == TOKEN_CHAR_TYPE {
+		externType = p.parseComplexReturnType()
+	} else {
+		errMsg := fmt.Sprintf("Expected a type in extern declaration, got %s at line %d",
+			tokenTypeName(p.current().Type), p.current().Line)
+		if !p.LintMode {
+			panic(errMsg)
+		}
+		p.recordError(errMsg)
+		return "int"
+	}
+	for p.current().Type == TOKEN_MULTIPLY {
+		p.advance()
+		externType += "*"
+	}
+	return externType
+}
+
+// parseTestBlock parses test "name": followed by an indented body closed
+// with $, or by a single statement on the same line
+func (p *Parser) parseTestBlock() *ASTNode {
+	testToken := p.current()
+	p.advance()
+	name := p.expect(TOKEN_STRING).Value
+	p.expect(TOKEN_ASSIGN)
+
+	if p.inFunctionBody || p.functionDepth > 0 {
+		errMsg := fmt.Sprintf("test must be at the top level, not inside a function, at line %d", testToken.Line)
+		if p.LintMode {
+			p.recordErrorAtLine(errMsg, testToken.Line)
+		} else {
+			panic(errMsg)
+		}
+	}
+
+	// The body is checked like a function's
+	var savedFunctionScope map[string]string
+	var savedInFunctionBody bool
+	if p.LintMode {
+		savedFunctionScope = p.functionScope
+		savedInFunctionBody = p.inFunctionBody
+		p.functionScope = make(map[string]string)
+		p.inFunctionBody = true
+	}
+
+	var body *ASTNode
+	if p.current().Type == TOKEN_NEWLINE {
+		p.advance()
+		if p.current().Type == TOKEN_INDENT {
+			p.advance()
+		}
+		p.blockDepth++ // Opening a multi-line block
+		body = p.parseBlockUntilEnd("test", testToken.Line)
+	} else {
+		body = &ASTNode{Type: NODE_BLOCK}
+		if stmt := p.parseStatement(); stmt != nil {
+			body.Children = append(body.Children, stmt)
+		}
+		// The closing $ is optional, and the formatter moves it to its own line
+		if p.current().Type == TOKEN_NEWLINE && p.peek(1).Type == TOKEN_END {
+			p.advance()
+		}
+		if p.current().Type == TOKEN_END {
+			p.advance()
+		}
+	}
 
-	// Parse the condition expression
-	condition := p.parseExpression()
+	if p.LintMode {
+		p.functionScope = savedFunctionScope
+		p.inFunctionBody = savedInFunctionBody
+	}
 
 	return &ASTNode{
-		Type:     NODE_ASSERT_STATEMENT,
-		Line:     assertToken.Line,
-		Children: []*ASTNode{condition},
+		Type:     NODE_TEST_BLOCK,
+		Value:    name,
+		Line:     testToken.Line,
+		Children: []*ASTNode{body},
 	}
 }
 
-func (p *Parser) parseDeferStatement() *ASTNode {
-	deferToken := p.expect(TOKEN_DEFER)
-
-	// Parse the deferred statement
-	// Could be a function call, ahoy statement, print statement, etc.
-	var statement *ASTNode
-	if p.current().Type == TOKEN_PRINT {
-		statement = p.parsePrintStatement()
-	} else if p.current().Type == TOKEN_LOG {
-		statement = p.parseLogStatement()
-	} else if p.current().Type == TOKEN_PANIC {
-		statement = p.parsePanicStatement()
-	} else if p.current().Type == TOKEN_AHOY {
-		statement = p.parseAhoyStatement()
-	} else {
-		statement = p.parseExpression()
+// isRecoverBlock reports whether the recover at the current token starts a
+// block, recover: or recover name: at the end of a line, rather than an
+// assignment to a variable named recover
+func (p *Parser) isRecoverBlock() bool {
+	if p.peek(1).Type == TOKEN_ASSIGN {
+		return p.peek(2).Type == TOKEN_NEWLINE
 	}
+	return p.peek(1).Type == TOKEN_IDENTIFIER && p.peek(2).Type == TOKEN_ASSIGN && p.peek(3).Type == TOKEN_NEWLINE
+}
 
+// parseRecoverBlock parses recover: body $ and recover name: body $. A
+// panic in body, or in anything it calls, ends the block, and name is set
+// to the panic's message, or "" when the body finished.
+func (p *Parser) parseRecoverBlock() *ASTNode {
+	recoverToken := p.current()
+	p.advance()
+	name := ""
+	if p.current().Type == TOKEN_IDENTIFIER {
+		name = p.current().Value
+		p.advance()
+	}
+	p.expect(TOKEN_ASSIGN)
+	p.expect(TOKEN_NEWLINE)
+	if p.current().Type == TOKEN_INDENT {
+		p.advance()
+	}
+	p.blockDepth++ // Opening a multi-line block
+	body := p.parseBlockUntilEnd("recover", recoverToken.Line)
+	if name != "" {
+		p.variableTypes[name] = "string"
+	}
 	return &ASTNode{
-		Type:     NODE_DEFER_STATEMENT,
-		Line:     deferToken.Line,
-		Children: []*ASTNode{statement},
+		Type:     NODE_RECOVER,
+		Value:    name,
+		Line:     recoverToken.Line,
+		Children: []*ASTNode{body},
 	}
 }
 
-func (p *Parser) parseImportStatement() *ASTNode {
-	importToken := p.current()
-	p.expect(TOKEN_IMPORT)
-
+// checkImportPlacement reports an import after the statements that must
+// follow the imports
+func (p *Parser) checkImportPlacement(importToken Token) {
 	// Validate that import is at top level (after program declaration, before other code)
 	if p.seenNonImport {
 		errMsg := fmt.Sprintf("Import statements must be at the top of the file, after the program declaration at line %d", importToken.Line)
@@ -2559,6 +3206,73 @@ func (p *Parser) parseImportStatement() *ASTNode {
 			panic(errMsg)
 		}
 	}
+}
+
+// parseFromImport parses from package import name, name: an import of an
+// Ahoy package that brings only the names listed into scope. The package is
+// a path, or a name for the .ahoy file or directory of that name beside
+// this file. The names are the import's children.
+func (p *Parser) parseFromImport() *ASTNode {
+	fromToken := p.current()
+	p.advance()
+	p.checkImportPlacement(fromToken)
+
+	var path string
+	if p.current().Type == TOKEN_STRING {
+		path = unescapeC(p.expect(TOKEN_STRING).Value)
+	} else {
+		path = "./" + p.expect(TOKEN_IDENTIFIER).Value
+	}
+	p.expect(TOKEN_IMPORT)
+	if strings.HasSuffix(path, ".h") {
+		errMsg := fmt.Sprintf("from ... import takes an Ahoy package, not the C header %s; use import at line %d", path, fromToken.Line)
+		if !p.LintMode {
+			panic(errMsg)
+		}
+		p.recordErrorAtLine(errMsg, fromToken.Line)
+	}
+
+	node := &ASTNode{Type: NODE_IMPORT_STATEMENT, Value: path, Line: fromToken.Line}
+	for {
+		name := p.expect(TOKEN_IDENTIFIER)
+		node.Children = append(node.Children, &ASTNode{Type: NODE_IDENTIFIER, Value: name.Value, Line: name.Line, Column: name.Column})
+		if p.current().Type != TOKEN_COMMA {
+			break
+		}
+		p.advance()
+	}
+	return node
+}
+
+// parseReexport parses pub use namespace.name, which makes a name that the
+// package imported as namespace declares part of this package, so files
+// importing this one can take it with from ... import
+func (p *Parser) parseReexport() *ASTNode {
+	pubToken := p.current()
+	p.advance()
+	p.advance()
+	if p.inFunctionBody || p.functionDepth > 0 {
+		errMsg := fmt.Sprintf("pub use must be at the top level, not inside a function, at line %d", pubToken.Line)
+		if !p.LintMode {
+			panic(errMsg)
+		}
+		p.recordErrorAtLine(errMsg, pubToken.Line)
+	}
+	namespace := p.expect(TOKEN_IDENTIFIER)
+	p.expect(TOKEN_DOT)
+	name := p.expect(TOKEN_IDENTIFIER)
+	return &ASTNode{
+		Type:     NODE_REEXPORT,
+		Value:    name.Value,
+		DataType: namespace.Value,
+		Line:     pubToken.Line,
+	}
+}
+
+func (p *Parser) parseImportStatement() *ASTNode {
+	importToken := p.current()
+	p.expect(TOKEN_IMPORT)
+	p.checkImportPlacement(importToken)
 
 	// Check if there's an identifier (namespace) before the string path
 	var namespace string
@@ -2567,9 +3281,9 @@ func (p *Parser) parseImportStatement() *ASTNode {
 	if p.current().Type == TOKEN_IDENTIFIER {
 		namespace = p.current().Value
 		p.advance()
-		path = p.expect(TOKEN_STRING).Value
+		path = unescapeC(p.expect(TOKEN_STRING).Value)
 	} else if p.current().Type == TOKEN_STRING {
-		path = p.expect(TOKEN_STRING).Value
+		path = unescapeC(p.expect(TOKEN_STRING).Value)
 		namespace = "" // No namespace means import all into global scope
 	} else {
 		if p.LintMode {
@@ -2751,7 +3465,8 @@ func (p *Parser) parseAssignmentOrExpression() *ASTNode {
 		}
 	}
 
-	// Check for object property assignment: obj{'prop'}: value
+	// Check for object property assignment: obj{'prop'}: value, or
+	// obj{'prop'}.field: value for a struct stored in a dict
 	if p.pos+2 < len(p.tokens) && p.tokens[p.pos+1].Type == TOKEN_LBRACE {
 		// Check if this is obj{'prop'}: pattern
 		savedPos := p.pos
@@ -2767,9 +3482,37 @@ func (p *Parser) parseAssignmentOrExpression() *ASTNode {
 			}
 			p.advance()
 		}
+		// Skip the field names
+		isField := false
+		for p.current().Type == TOKEN_DOT && p.peek(1).Type == TOKEN_IDENTIFIER {
+			p.advance()
+			p.advance()
+			isField = true
+		}
 		isAssignment := p.current().Type == TOKEN_ASSIGN
+		isCompoundAssignment := isField && p.isCompoundAssignOp(p.current().Type)
 		p.pos = savedPos // restore position
 
+		if isCompoundAssignment {
+			target := p.parsePrimaryExpression() // This will parse obj{'prop'}.field
+			opToken := p.current()
+			p.advance() // consume compound operator
+			value := p.parseExpression()
+
+			// Convert to: target: target op value
+			binaryOp := &ASTNode{
+				Type:     NODE_BINARY_OP,
+				Value:    p.getCompoundAssignOp(opToken.Type),
+				Children: []*ASTNode{p.copyASTNode(target), value},
+				Line:     target.Line,
+			}
+			return &ASTNode{
+				Type:     NODE_ASSIGNMENT,
+				Children: []*ASTNode{target, binaryOp},
+				Line:     target.Line,
+			}
+		}
+
 		if isAssignment {
 			// Parse as object property assignment
 			target := p.parsePrimaryExpression() // This will parse obj{'prop'}
@@ -3146,9 +3889,11 @@ func (p *Parser) parseAssignmentOrExpression() *ASTNode {
 				possibleType := p.current().Value
 
 				// Check for typed collections: array[type]= or dict[key,value]= or dict<key,value>=
+				// dict[64] without a comma is a dict with room for 64 keys, not a type
 				if (p.current().Type == TOKEN_ARRAY_TYPE || p.current().Type == TOKEN_DICT_TYPE ||
 					(p.current().Type == TOKEN_IDENTIFIER && p.current().Value == "dict")) &&
-					(p.peek(1).Type == TOKEN_LBRACKET || p.peek(1).Type == TOKEN_LANGLE) {
+					(p.peek(1).Type == TOKEN_LBRACKET || p.peek(1).Type == TOKEN_LANGLE) &&
+					!(p.current().Type == TOKEN_DICT_TYPE && p.peek(1).Type == TOKEN_LBRACKET && p.peek(3).Type != TOKEN_COMMA) {
 					baseType := possibleType
 					isDict := p.current().Type == TOKEN_DICT_TYPE || p.current().Value == "dict"
 					bracketType := p.peek(1).Type
@@ -3339,6 +4084,14 @@ func (p *Parser) parseAssignmentOrExpression() *ASTNode {
 							p.trackObjectLiteralProperties(varName, value)
 						}
 					}
+
+					// Remember the enum of a variable declared with one of its
+					// members, so switches on it can use bare member names
+					if value.Type == NODE_MEMBER_ACCESS && len(value.Children) > 0 && value.Children[0].Type == NODE_IDENTIFIER {
+						if _, isEnum := p.enums[value.Children[0].Value]; isEnum {
+							p.enumVars[varName] = value.Children[0].Value
+						}
+					}
 				}
 
 				// Validate property assignment for struct reassignments
@@ -3562,7 +4315,8 @@ func (p *Parser) parseBlockUntilEnd(constructName string, startLine int) *ASTNod
 }
 
 func (p *Parser) parseExpression() *ASTNode {
-	return p.parseTernaryExpression()
+	start := p.pos
+	return p.spanned(p.parseTernaryExpression(), start)
 }
 
 func (p *Parser) parseTernaryExpression() *ASTNode {
@@ -3659,12 +4413,24 @@ func (p *Parser) parseAndExpression() *ASTNode {
 func (p *Parser) parseEqualityExpression() *ASTNode {
 	left := p.parseRelationalExpression()
 
-	for p.current().Type == TOKEN_IS {
+	for p.current().Type == TOKEN_IS || p.current().Type == TOKEN_IN {
 		op := p.current()
 		p.advance()
 
+		// Membership: value in array, key in dict
+		if op.Type == TOKEN_IN {
+			right := p.parseRelationalExpression()
+			left = &ASTNode{
+				Type:     NODE_BINARY_OP,
+				Value:    "in",
+				Children: []*ASTNode{left, right},
+				Line:     op.Line,
+			}
+			continue
+		}
+
 		// Check for "is not" pattern
-		isNegated := false
+		isNegated := op.Value == "!="
 		if p.current().Type == TOKEN_NOT {
 			isNegated = true
 			p.advance()
@@ -3674,7 +4440,7 @@ func (p *Parser) parseEqualityExpression() *ASTNode {
 
 		comparison := &ASTNode{
 			Type:     NODE_BINARY_OP,
-			Value:    op.Value,
+			Value:    "is",
 			Children: []*ASTNode{left, right},
 		}
 
@@ -3796,23 +4562,41 @@ func (p *Parser) parseMultiplicativeExpression() *ASTNode {
 	return left
 }
 
+// endsOperand reports whether a token can follow a complete operand rather
+// than start one
+func endsOperand(tokenType TokenType) bool {
+	switch tokenType {
+	case TOKEN_NEWLINE, TOKEN_EOF, TOKEN_PIPE, TOKEN_COMMA, TOKEN_RPAREN, TOKEN_RBRACKET, TOKEN_RBRACE,
+		TOKEN_END, TOKEN_MULTIPLY, TOKEN_DIVIDE, TOKEN_PLUS, TOKEN_TIMES_WORD, TOKEN_DIV_WORD,
+		TOKEN_PLUS_WORD, TOKEN_MOD_WORD:
+		return true
+	}
+	return false
+}
+
 func (p *Parser) parseUnaryExpression() *ASTNode {
 	if p.current().Type == TOKEN_NOT || p.current().Type == TOKEN_MINUS ||
 		p.current().Type == TOKEN_CARET || p.current().Type == TOKEN_AMPERSAND {
+		start := p.pos
 		op := p.current()
 		p.advance()
 		expr := p.parseUnaryExpression()
-		return &ASTNode{
+		return p.spanned(&ASTNode{
 			Type:     NODE_UNARY_OP,
 			Value:    op.Value,
 			Children: []*ASTNode{expr},
-		}
+		}, start)
 	}
 
 	return p.parsePrimaryExpression()
 }
 
 func (p *Parser) parsePrimaryExpression() *ASTNode {
+	start := p.pos
+	return p.spanned(p.parsePrimary(), start)
+}
+
+func (p *Parser) parsePrimary() *ASTNode {
 	switch p.current().Type {
 	case TOKEN_NUMBER:
 		token := p.current()
@@ -3860,6 +4644,21 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 		}
 		return node
 
+	case TOKEN_BYTES_STRING:
+		token := p.current()
+		p.advance()
+		node := &ASTNode{
+			Type:     NODE_BYTES_LITERAL,
+			Value:    token.Value,
+			DataType: "bytes",
+			Line:     token.Line,
+		}
+		// Check for method call on bytes literal
+		if p.current().Type == TOKEN_DOT {
+			return p.parseMemberAccessChain(node)
+		}
+		return node
+
 	case TOKEN_CHAR:
 		token := p.current()
 		p.advance()
@@ -3894,6 +4693,20 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 		token := p.current()
 		p.advance()
 
+		// Check for comptime name|args|, parsed as a call of its own even
+		// as another call's argument
+		if token.Value == "comptime" && p.current().Type == TOKEN_IDENTIFIER && p.peek(1).Type == TOKEN_PIPE {
+			depth := p.inFunctionCall
+			p.inFunctionCall = 0
+			call := p.parsePrimary()
+			p.inFunctionCall = depth
+			return &ASTNode{
+				Type:     NODE_COMPTIME,
+				Children: []*ASTNode{call},
+				Line:     token.Line,
+			}
+		}
+
 		// Check for array access identifier[index]
 		if p.current().Type == TOKEN_LBRACKET {
 			p.advance()
@@ -3936,6 +4749,7 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 		// Check for object instantiation identifier{...} or object property access identifier{'key'}
 		if p.current().Type == TOKEN_LBRACE {
 			p.advance()
+			p.skipLineBreaks()
 
 			// Check for empty object instantiation identifier{}
 			if p.current().Type == TOKEN_RBRACE {
@@ -4127,9 +4941,7 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 				// Parse arguments until closing pipe (allow newlines for multiline calls)
 				for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 					// Skip newlines between arguments
-					for p.current().Type == TOKEN_NEWLINE {
-						p.advance()
-					}
+					p.skipLineBreaks()
 
 					if p.current().Type == TOKEN_PIPE || p.current().Type == TOKEN_EOF {
 						break
@@ -4141,14 +4953,10 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 					if p.current().Type == TOKEN_COMMA {
 						p.advance()
 						// Skip newlines after comma
-						for p.current().Type == TOKEN_NEWLINE {
-							p.advance()
-						}
+						p.skipLineBreaks()
 					} else {
 						// Skip trailing newlines before closing pipe
-						for p.current().Type == TOKEN_NEWLINE {
-							p.advance()
-						}
+						p.skipLineBreaks()
 						if p.current().Type != TOKEN_PIPE {
 							break
 						}
@@ -4268,14 +5076,54 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 		// Switch expression (can be used in assignments)
 		return p.parseSwitchStatement()
 
+	// A dict with room for a number of keys before it grows: dict[64], or
+	// dict[64]<"a": 1> with its first keys
+	case TOKEN_DICT_TYPE:
+		token := p.current()
+		if p.peek(1).Type != TOKEN_LBRACKET {
+			p.syntaxError(fmt.Sprintf("Unexpected type keyword '%s' at line %d:%d",
+				token.Value, token.Line, token.Column))
+		}
+		p.advance() // consume dict
+		p.advance() // consume [
+		capacity := p.parseExpression()
+		p.expect(TOKEN_RBRACKET)
+
+		if p.current().Type != TOKEN_LANGLE && p.current().Type != TOKEN_LBRACE {
+			return &ASTNode{
+				Type:     NODE_DICT_LITERAL,
+				DataType: "dict",
+				Capacity: capacity,
+				Line:     token.Line,
+			}
+		}
+		literal := p.parseDictLiteral()
+		// A method call on the literal wraps it; the hint goes on the literal
+		dict := literal
+		for dict.Type != NODE_DICT_LITERAL && len(dict.Children) > 0 {
+			dict = dict.Children[0]
+		}
+		dict.Capacity = capacity
+		return literal
+
 	// Type casts: int(value), float(value), char(value), string(value)
 	case TOKEN_INT_TYPE, TOKEN_FLOAT_TYPE, TOKEN_CHAR_TYPE, TOKEN_STRING_TYPE:
 		token := p.current()
 		p.advance()
 
+		// A bare type name, as in size_of|int|, ends its call's arguments
+		if p.current().Type == TOKEN_PIPE && endsOperand(p.peek(1).Type) {
+			return &ASTNode{
+				Type:  NODE_IDENTIFIER,
+				Value: token.Value,
+				Line:  token.Line,
+			}
+		}
+
 		// Check if this is object instantiation with {}
 		if p.current().Type == TOKEN_LBRACE {
 			p.advance()
+			p.skipLineBreaks()
 
 			// Check for empty object: vector2{}
 			if p.current().Type == TOKEN_RBRACE {
@@ -4299,7 +5147,20 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 			}
 
 			// Otherwise error - unexpected syntax
-			panic(fmt.Sprintf("Unexpected token in object literal at line %d", p.current().Line))
+			p.syntaxError(fmt.Sprintf("Unexpected token in object literal at line %d", p.current().Line))
+		}
+
+		// Check if this is a fixed-size array: int[64]
+		if p.current().Type == TOKEN_LBRACKET {
+			p.advance() // consume '['
+			size := p.parseExpression()
+			p.expect(TOKEN_RBRACKET)
+			return &ASTNode{
+				Type:     NODE_FIXED_ARRAY,
+				Value:    token.Value,
+				Children: []*ASTNode{size},
+				Line:     token.Line,
+			}
 		}
 
 		// Check if this is old dict literal syntax with <>
@@ -4339,8 +5200,10 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 
 		// Check if this is a cast (followed by parenthesis or pipe)
 		if p.current().Type == TOKEN_PIPE {
-			// vector2|x,y| syntax
+			// vector2|x,y| syntax; inside the pipes int|n| takes n as a value,
+			// not the start of a call
 			p.advance() // consume |
+			p.inFunctionCall++
 			args := []*ASTNode{}
 			for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 				arg := p.parseAdditiveExpression()
@@ -4352,6 +5215,7 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 					break
 				}
 			}
+			p.inFunctionCall--
 			p.expect(TOKEN_PIPE)
 
 			return &ASTNode{
@@ -4364,15 +5228,8 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 
 		if p.current().Type != TOKEN_LPAREN {
 			// Not a cast or instantiation - treat as unexpected
-			errMsg := fmt.Sprintf("Unexpected type keyword '%s' at line %d:%d",
-				token.Value, token.Line, token.Column)
-			if p.LintMode {
-				p.recordError(errMsg)
-				p.advance()
-				return &ASTNode{Type: NODE_IDENTIFIER, Value: "error"}
-			} else {
-				panic(errMsg)
-			}
+			p.syntaxError(fmt.Sprintf("Unexpected type keyword '%s' at line %d:%d",
+				token.Value, token.Line, token.Column))
 		}
 
 		// It's a cast - parse the argument in parentheses
@@ -4391,19 +5248,9 @@ func (p *Parser) parsePrimaryExpression() *ASTNode {
 
 	default:
 		current := p.current()
-		errMsg := fmt.Sprintf("Unexpected token %s at line %d:%d",
-			tokenTypeName(current.Type), current.Line, current.Column)
-		if p.LintMode {
-			p.recordError(errMsg)
-			// Return a dummy node to continue parsing
-			p.advance()
-			return &ASTNode{
-				Type:  NODE_IDENTIFIER,
-				Value: "error",
-			}
-		} else {
-			panic(errMsg)
-		}
+		p.syntaxError(fmt.Sprintf("Unexpected token %s at line %d:%d",
+			tokenTypeName(current.Type), current.Line, current.Column))
+		return nil
 	}
 }
 
@@ -4424,12 +5271,15 @@ func (p *Parser) parseArrayLiteral() *ASTNode {
 
 	p.inArrayLiteral = true
 
+	p.skipLineBreaks()
 	for p.current().Type != TOKEN_RANGLE {
 		element := p.parseCallArgument() // Use call argument parser to avoid consuming >
 		array.Children = append(array.Children, element)
 
+		p.skipLineBreaks()
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
+			p.skipLineBreaks()
 		} else if p.current().Type != TOKEN_RANGLE {
 			break
 		}
@@ -4457,6 +5307,7 @@ func (p *Parser) parseObjectLiteral() *ASTNode {
 
 	p.inObjectLiteral = true
 
+	p.skipLineBreaks()
 	for p.current().Type != TOKEN_RBRACE && p.current().Type != TOKEN_EOF {
 		// Parse property name (can be identifier or string)
 		if p.current().Type != TOKEN_IDENTIFIER && p.current().Type != TOKEN_STRING {
@@ -4496,8 +5347,10 @@ func (p *Parser) parseObjectLiteral() *ASTNode {
 		object.Children = append(object.Children, prop)
 
 		// Check for comma or end
+		p.skipLineBreaks()
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
+			p.skipLineBreaks()
 		} else if p.current().Type != TOKEN_RBRACE {
 			if p.LintMode {
 				p.recordError(fmt.Sprintf("Expected ',' or '}' in object literal at line %d", p.current().Line))
@@ -4606,6 +5459,7 @@ func (p *Parser) parseDictLiteral() *ASTNode {
 
 	p.inDictLiteral = true
 
+	p.skipLineBreaks()
 	for p.current().Type != endToken && p.current().Type != TOKEN_EOF {
 		// Parse key (can be string or identifier)
 		key := p.parseCallArgument()
@@ -4615,8 +5469,10 @@ func (p *Parser) parseDictLiteral() *ASTNode {
 		// Store key-value pair as two consecutive children
 		dict.Children = append(dict.Children, key, value)
 
+		p.skipLineBreaks()
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
+			p.skipLineBreaks()
 		} else if p.current().Type != endToken {
 			break
 		}
@@ -4734,10 +5590,15 @@ func (p *Parser) parseEnumDeclaration() *ASTNode {
 
 		// Parse value expression first (if present)
 		// Value can be: number, string, bool, array, dict, color, vector2
-		if p.current().Type == TOKEN_NUMBER {
+		if p.current().Type == TOKEN_NUMBER || (p.current().Type == TOKEN_MINUS && p.peek(1).Type == TOKEN_NUMBER) {
+			sign := ""
+			if p.current().Type == TOKEN_MINUS {
+				sign = "-"
+				p.advance()
+			}
 			valueNode = &ASTNode{
 				Type:  NODE_NUMBER,
-				Value: p.current().Value,
+				Value: sign + p.current().Value,
 				Line:  p.current().Line,
 			}
 			p.advance()
@@ -4810,12 +5671,14 @@ func (p *Parser) parseEnumDeclaration() *ASTNode {
 		memberName = p.current().Value
 		p.advance()
 
-		// Check for :mutable modifier
+		// Check for :mutable modifier or a constant expression value (two: one plus 1)
 		if p.current().Type == TOKEN_ASSIGN {
 			p.advance() // consume ':'
 			if p.current().Type == TOKEN_IDENTIFIER && p.current().Value == "mutable" {
 				isMutable = true
 				p.advance()
+			} else if valueNode == nil && p.current().Type != TOKEN_NEWLINE && p.current().Type != TOKEN_END {
+				valueNode = p.parseExpression()
 			} else {
 				errMsg := fmt.Sprintf("Expected 'mutable' after ':' in enum member at line %d", p.current().Line)
 				if p.LintMode {
@@ -4868,6 +5731,14 @@ func (p *Parser) parseEnumDeclaration() *ASTNode {
 		}
 	}
 
+	// Flags enum members must be single bits (or zero for an empty set);
+	// builds check the same in generateEnum
+	if p.LintMode && enumType == "flags" {
+		for _, member := range nonFlagMembers(enum) {
+			p.recordErrorAtLine(nonFlagMessage(member), member.Line)
+		}
+	}
+
 	// Register enum definition in lint mode for validation
 	if p.LintMode {
 		members := make([]*ASTNode, len(enum.Children))
@@ -4970,6 +5841,14 @@ func (p *Parser) parseFunctionDeclaration() *ASTNode {
 		p.recordErrorAtLine(errMsg, startLine)
 	}
 
+	// @inline and @noinline ask for and rule out inlining the function, and
+	// @export makes it part of a library's C API
+	annotation := ""
+	if (p.current().Value == "inline" || p.current().Value == "noinline" || p.current().Value == "export") && p.peek(1).Type == TOKEN_IDENTIFIER {
+		annotation = p.current().Value
+		p.advance()
+	}
+
 	name := p.expect(TOKEN_IDENTIFIER)
 
 	// Double colon is now optional
@@ -4981,6 +5860,7 @@ func (p *Parser) parseFunctionDeclaration() *ASTNode {
 	defer func() { p.functionDepth-- }() // Exiting function definition
 
 	result := p.parseFunctionWithDoubleColon(name)
+	result.Tag = annotation
 	return result
 }
 
@@ -4999,6 +5879,7 @@ func (p *Parser) parseFunctionWithDoubleColon(name Token) *ASTNode {
 	params := &ASTNode{Type: NODE_BLOCK}
 	hasDefaultParam := false // Track if we've seen a default parameter
 
+	p.skipLineBreaks()
 	for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 		// Safety check: if current token is not an identifier, break to avoid infinite loop
 		if p.current().Type != TOKEN_IDENTIFIER {
@@ -5065,8 +5946,10 @@ func (p *Parser) parseFunctionWithDoubleColon(name Token) *ASTNode {
 			p.functionScope[paramName.Value] = paramType
 		}
 
+		p.skipLineBreaks()
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
+			p.skipLineBreaks()
 		} else if p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_EOF {
 			// If we're not at a comma, pipe, or EOF, something is wrong - break to avoid infinite loop
 			break
@@ -5714,6 +6597,7 @@ func (p *Parser) parseStructDeclaration() *ASTNode {
 							DataType:     fieldType,
 							Line:         fieldName.Line,
 							DefaultValue: defaultValue,
+							Tag:          p.parseFieldTag(),
 						}
 						nestedType.Children = append(nestedType.Children, field)
 
@@ -5880,6 +6764,7 @@ func (p *Parser) parseStructDeclaration() *ASTNode {
 				DataType:     fieldType,
 				Line:         fieldName.Line,
 				DefaultValue: defaultValue,
+				Tag:          p.parseFieldTag(),
 			}
 			struc.Children = append(struc.Children, field)
 		}
@@ -5931,6 +6816,50 @@ func (p *Parser) parseStructDeclaration() *ASTNode {
 	return struc
 }
 
+// parseFieldTag reads the tags after a struct field's type, like
+// json:"hp,omitempty", and returns them space separated as in Go
+func (p *Parser) parseFieldTag() string {
+	var tags []string
+	for p.current().Type == TOKEN_IDENTIFIER && p.peek(1).Type == TOKEN_ASSIGN && p.peek(2).Type == TOKEN_STRING {
+		tags = append(tags, p.current().Value+":\""+p.peek(2).Value+"\"")
+		p.advance()
+		p.advance()
+		p.advance()
+	}
+	return strings.Join(tags, " ")
+}
+
+// JSONTag is the json tag of a struct field: the key it is written under,
+// whether it is left out when empty, and whether it is skipped ("-")
+type JSONTag struct {
+	Key       string
+	OmitEmpty bool
+	Skip      bool
+}
+
+// parseJSONTag reads the json tag of field name from tag; without one the
+// key is the field name
+func parseJSONTag(name, tag string) JSONTag {
+	value, ok := reflect.StructTag(tag).Lookup("json")
+	if !ok {
+		return JSONTag{Key: name}
+	}
+	if value == "-" {
+		return JSONTag{Key: name, Skip: true}
+	}
+	key, options, _ := strings.Cut(value, ",")
+	if key == "" {
+		key = name
+	}
+	fieldTag := JSONTag{Key: key}
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" {
+			fieldTag.OmitEmpty = true
+		}
+	}
+	return fieldTag
+}
+
 // Store struct definition for later validation
 func (p *Parser) storeStructDefinition(struc *ASTNode, startLine int) {
 	structName := struc.Value
@@ -5985,6 +6914,8 @@ func (p *Parser) storeStructDefinition(struc *ASTNode, startLine int) {
 					Name:         field.Value,
 					Type:         field.DataType,
 					DefaultValue: field.DefaultValue,
+					Tag:          field.Tag,
+					JSON:         parseJSONTag(field.Value, field.Tag),
 				})
 			}
 
@@ -5995,6 +6926,8 @@ func (p *Parser) storeStructDefinition(struc *ASTNode, startLine int) {
 				Name:         child.Value,
 				Type:         child.DataType,
 				DefaultValue: child.DefaultValue,
+				Tag:          child.Tag,
+				JSON:         parseJSONTag(child.Value, child.Tag),
 			})
 		}
 	}
@@ -6325,14 +7258,12 @@ func (p *Parser) inferMemberAccessType(node *ASTNode) string {
 			}
 		}
 
-		// Check for built-in types with fields (vector2, color)
-		if objType == "vector2" {
-			if node.Value == "x" || node.Value == "y" {
-				return "float"
-			}
-		} else if objType == "color" {
-			if node.Value == "r" || node.Value == "g" || node.Value == "b" || node.Value == "a" {
-				return "int"
+		// Check for built-in types with fields (vector2, color, rectangle, matrix)
+		if builtin, ok := builtinStructs[objType]; ok {
+			for _, field := range builtin.fields {
+				if field.Name == node.Value {
+					return field.Type
+				}
 			}
 		}
 	}
@@ -6378,6 +7309,11 @@ func (p *Parser) validateMemberAccess(object *ASTNode, memberName string, line i
 		// We'll handle this when the literal is assigned to a variable
 	}
 
+	// obj.fn calls fn with obj when a tuple assignment takes its values
+	if _, isFunction := p.functions[memberName]; isFunction {
+		return
+	}
+
 	if objectType == "" {
 		return // Can't validate without type info
 	}
@@ -6385,20 +7321,16 @@ func (p *Parser) validateMemberAccess(object *ASTNode, memberName string, line i
 	// Normalize struct type
 	objectType = strings.TrimPrefix(objectType, "struct:")
 
-	// Check for built-in types with fields (vector2, color)
-	if objectType == "vector2" {
-		if memberName != "x" && memberName != "y" {
-			errMsg := fmt.Sprintf("Property not found: '%s' does not exist on type 'vector2' (line %d)",
-				memberName, line)
-			p.recordError(errMsg)
-		}
-		return
-	} else if objectType == "color" {
-		if memberName != "r" && memberName != "g" && memberName != "b" && memberName != "a" {
-			errMsg := fmt.Sprintf("Property not found: '%s' does not exist on type 'color' (line %d)",
-				memberName, line)
-			p.recordError(errMsg)
+	// Check for built-in types with fields (vector2, color, rectangle, matrix)
+	if builtin, ok := builtinStructs[objectType]; ok {
+		for _, field := range builtin.fields {
+			if field.Name == memberName {
+				return
+			}
 		}
+		errMsg := fmt.Sprintf("Property not found: '%s' does not exist on type '%s' (line %d)",
+			memberName, objectType, line)
+		p.recordError(errMsg)
 		return
 	}
 
@@ -6432,12 +7364,15 @@ func (p *Parser) parseArrayLiteralBracket() *ASTNode {
 
 	p.inArrayLiteral = true
 
+	p.skipLineBreaks()
 	for p.current().Type != TOKEN_RBRACKET {
 		element := p.parseExpression()
 		array.Children = append(array.Children, element)
 
+		p.skipLineBreaks()
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
+			p.skipLineBreaks()
 		} else if p.current().Type != TOKEN_RBRACKET {
 			break
 		}
@@ -6906,13 +7841,15 @@ func (p *Parser) parseCallWithName(funcName Token) *ASTNode {
 	// Increment depth to allow nested function calls
 	p.inFunctionCall++
 
-	// Parse arguments until closing pipe
+	// Parse arguments until closing pipe; after a comma they can go on to
+	// the next line
 	for p.current().Type != TOKEN_PIPE && p.current().Type != TOKEN_NEWLINE && p.current().Type != TOKEN_EOF {
 		arg := p.parseCallArgument()
 		call.Children = append(call.Children, arg)
 
 		if p.current().Type == TOKEN_COMMA {
 			p.advance()
+			p.skipLineBreaks()
 		} else {
 			break
 		}
@@ -7037,19 +7974,49 @@ func isScreamingSnakeCase(s string) bool {
 	return hasUpper
 }
 
+// switchSubjectEnum returns the Ahoy enum a switch subject belongs to when
+// the parser can tell: enum.member, or a variable declared with the enum type
+// or with one of its members
+func (p *Parser) switchSubjectEnum(expr *ASTNode) string {
+	if expr == nil {
+		return ""
+	}
+	name := ""
+	if expr.Type == NODE_MEMBER_ACCESS && len(expr.Children) > 0 && expr.Children[0].Type == NODE_IDENTIFIER {
+		name = expr.Children[0].Value
+	} else if expr.Type == NODE_IDENTIFIER {
+		name = p.variableTypes[expr.Value]
+		if enumName, exists := p.enumVars[expr.Value]; exists {
+			name = enumName
+		}
+	}
+	if _, isEnum := p.enums[name]; isEnum {
+		return name
+	}
+	return ""
+}
+
 // validateEnumMemberInSwitch checks if an enum member name is ambiguous (exists in multiple enums)
-// and returns true if the member needs to be prefixed with enum name
-func (p *Parser) validateEnumMemberInSwitch(memberName string, line int) bool {
+// and returns true if the member needs to be prefixed with enum name. A member
+// of the switch subject's enum is never ambiguous.
+func (p *Parser) validateEnumMemberInSwitch(memberName string, line int, subjectEnum string) bool {
 	if !p.LintMode {
 		return false
 	}
+	if enumDef := p.enums[subjectEnum]; enumDef != nil {
+		for _, member := range enumDef.Members {
+			if member.Value == memberName {
+				return false
+			}
+		}
+	}
 
 	// Check C header enums first
 	foundInEnums := []string{}
 
 	// Check global C header enums
 	for enumName, cEnum := range p.cHeaderGlobal.Enums {
-		if _, exists := cEnum.Values[memberName]; exists {
+		if _, exists := cEnum.ValueLines[memberName]; exists {
 			foundInEnums = append(foundInEnums, enumName)
 		}
 	}
@@ -7057,7 +8024,7 @@ func (p *Parser) validateEnumMemberInSwitch(memberName string, line int) bool {
 	// Check namespaced C header enums
 	for _, headerInfo := range p.cHeaders {
 		for enumName, cEnum := range headerInfo.Enums {
-			if _, exists := cEnum.Values[memberName]; exists {
+			if _, exists := cEnum.ValueLines[memberName]; exists {
 				foundInEnums = append(foundInEnums, enumName)
 			}
 		}
@@ -7075,6 +8042,7 @@ func (p *Parser) validateEnumMemberInSwitch(memberName string, line int) bool {
 
 	// If found in multiple enums, it's ambiguous
 	if len(foundInEnums) > 1 {
+		sort.Strings(foundInEnums)
 		errMsg := fmt.Sprintf("Ambiguous enum member '%s' - found in multiple enums: %s. Please prefix with enum name (e.g., %s.%s)",
 			memberName, strings.Join(foundInEnums, ", "), foundInEnums[0], memberName)
 		p.recordErrorAtLine(errMsg, line)
@@ -0,0 +1,270 @@
+package ahoy
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNodeSpans(t *testing.T) {
+	source := "@ area |w:int, h:int| int:\n    return w * h\n$\nsize: area|2, 3| + 1\n"
+	ast, errors := ParseLint(Tokenize(source))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+
+	var check func(node *ASTNode)
+	check = func(node *ASTNode) {
+		if node == nil {
+			return
+		}
+		if node.Span.IsZero() {
+			t.Errorf("node %d %q has no span", node.Type, node.Value)
+		}
+		for _, child := range node.Children {
+			check(child)
+		}
+		check(node.DefaultValue)
+	}
+	check(ast)
+
+	text := func(node *ASTNode) string {
+		return source[node.Span.Start:node.Span.End]
+	}
+	function, assignment := ast.Children[0], ast.Children[1]
+	if got := text(function); got != "@ area |w:int, h:int| int:\n    return w * h\n$" {
+		t.Errorf("function span covers %q", got)
+	}
+	if span := function.Span; span.StartLine != 1 || span.EndLine != 3 || span.EndColumn != 2 {
+		t.Errorf("unexpected function span %+v", span)
+	}
+	sum := assignment.Children[0]
+	if got := text(sum); got != "area|2, 3| + 1" {
+		t.Errorf("sum span covers %q", got)
+	}
+	if span := sum.Span; span.StartLine != 4 || span.StartColumn != 7 {
+		t.Errorf("unexpected sum span %+v", span)
+	}
+}
+
+func TestStatementComments(t *testing.T) {
+	source := `? Keeps score
+? for both players
+score: 0 ? starts empty
+
+? Adds one
+@ bump |n:int| int:
+    ? one more
+    return n + 1 ? never negative
+$
+`
+	ast, errors := ParseLint(Tokenize(source))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+	score, bump := ast.Children[0], ast.Children[1]
+	if !slices.Equal(score.Comments, []string{"? Keeps score", "? for both players"}) {
+		t.Errorf("unexpected leading comments %q", score.Comments)
+	}
+	if score.TrailingComment != "? starts empty" {
+		t.Errorf("unexpected trailing comment %q", score.TrailingComment)
+	}
+	if !slices.Equal(bump.Comments, []string{"? Adds one"}) {
+		t.Errorf("unexpected function comments %q", bump.Comments)
+	}
+
+	ret := bump.Children[1].Children[0]
+	if !slices.Equal(ret.Comments, []string{"? one more"}) || ret.TrailingComment != "? never negative" {
+		t.Errorf("unexpected return comments %q %q", ret.Comments, ret.TrailingComment)
+	}
+	if bump.TrailingComment != "" {
+		t.Errorf("the function should not take its body's comment, got %q", bump.TrailingComment)
+	}
+}
+
+func TestStructFieldTags(t *testing.T) {
+	source := "struct player:\n    health: int json:\"hp\"\n    speed: float json:\",omitempty\"\n    secret: string json:\"-\"\n    name: string\n$\n"
+	ast, errors := ParseLint(Tokenize(source))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+	expected := []struct {
+		name, tag string
+		json      JSONTag
+	}{
+		{"health", `json:"hp"`, JSONTag{Key: "hp"}},
+		{"speed", `json:",omitempty"`, JSONTag{Key: "speed", OmitEmpty: true}},
+		{"secret", `json:"-"`, JSONTag{Key: "secret", Skip: true}},
+		{"name", "", JSONTag{Key: "name"}},
+	}
+	fields := ast.Children[0].Children
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(fields))
+	}
+	for i, want := range expected {
+		if fields[i].Value != want.name || fields[i].Tag != want.tag {
+			t.Errorf("field %d: expected %s with tag %q, got %s with %q", i, want.name, want.tag, fields[i].Value, fields[i].Tag)
+		}
+		if got := parseJSONTag(fields[i].Value, fields[i].Tag); got != want.json {
+			t.Errorf("field %s: expected %+v, got %+v", want.name, want.json, got)
+		}
+	}
+}
+
+func TestSwitchEnumMemberAmbiguity(t *testing.T) {
+	enums := "enum paint:\n\tred\n\tblue\n$\nenum light:\n\tred\n\tamber\n$\n"
+	_, errors := ParseLint(Tokenize(enums + "shade: paint.blue\nswitch shade:\n\ton red: print|\"red\"|\n$\n"))
+	if len(errors) > 0 {
+		t.Errorf("expected the subject's enum to settle red, got %v", errors)
+	}
+
+	_, errors = ParseLint(Tokenize(enums + "n: 1\nswitch n:\n\ton red: print|\"red\"|\n$\n"))
+	if len(errors) != 1 || errors[0].Message != "Ambiguous enum member 'red' - found in multiple enums: light, paint. Please prefix with enum name (e.g., light.red)" {
+		t.Errorf("expected an ambiguity error listing both enums, got %v", errors)
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		source string
+		value  string
+	}{
+		{`'say "hi"'`, `say \"hi\"`},
+		{`"tab:\t \"q\" back\\slash"`, `tab:\t \"q\" back\\slash`},
+		{`"\x41BC"`, `ABC`},
+		{`"\x01" `, `\001`},
+		{`"caf\u00e9 \u{1F600}"`, "café 😀"},
+		{`'it\'s'`, `it's`},
+		{`"C:\dir"`, `C:\\dir`},
+	}
+	for _, test := range tests {
+		source := "s: " + test.source + "\n"
+		ast, errors := ParseLint(Tokenize(source))
+		if len(errors) > 0 {
+			t.Fatalf("%s: parse errors: %v", test.source, errors)
+		}
+		literal := ast.Children[0].Children[0]
+		if literal.Value != test.value {
+			t.Errorf("%s: expected %q, got %q", test.source, test.value, literal.Value)
+		}
+		if got := source[literal.Span.Start:literal.Span.End]; got != strings.TrimSpace(test.source) {
+			t.Errorf("%s: span covers %q", test.source, got)
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	source := "sql: \"\"\"SELECT \"a\\n\"\n  FROM t\"\"\" ? query\ncount: 1\n"
+	ast, errors := ParseLint(Tokenize(source))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+	literal := ast.Children[0].Children[0]
+	if want := `SELECT \"a\\n\"\n  FROM t`; literal.Value != want {
+		t.Errorf("expected %q, got %q", want, literal.Value)
+	}
+	if span := literal.Span; span.StartLine != 1 || span.EndLine != 2 || span.EndColumn != 12 {
+		t.Errorf("unexpected raw string span %+v", span)
+	}
+	if count := ast.Children[1]; count.Value != "count" || count.Span.StartLine != 3 {
+		t.Errorf("expected count on line 3, got %q %+v", count.Value, count.Span)
+	}
+}
+
+func TestParseLintRecovery(t *testing.T) {
+	source := `first: (1 +
+second: 2
+@ add |a:int, b:int| int:
+    total: a + * b
+    if a > then
+        print|a|
+    $
+    return a + b
+$
+loop i to do
+    print|i|
+$
+last: add|second, 3|
+`
+	ast, errors := ParseLint(Tokenize(source))
+
+	var lines []int
+	for _, err := range errors {
+		lines = append(lines, err.Line)
+	}
+	if !slices.Equal(lines, []int{1, 4, 5, 10}) {
+		t.Errorf("expected one error on each broken line, got %v", errors)
+	}
+
+	var names []string
+	for _, stmt := range ast.Children {
+		names = append(names, stmt.Value)
+	}
+	if !slices.Equal(names, []string{"second", "add", "last"}) {
+		t.Fatalf("expected the statements around the errors, got %v", names)
+	}
+	body := ast.Children[1].Children[1]
+	if len(body.Children) != 1 || body.Children[0].Type != NODE_RETURN_STATEMENT {
+		t.Errorf("expected the function body to keep its return, got %d statements", len(body.Children))
+	}
+}
+
+func TestMultilineLiteralsAndTrailingCommas(t *testing.T) {
+	source := `@ add |
+    a:int,
+    b:int,
+| int:
+    return a + b
+$
+nums: [
+    1,
+    2,
+]
+ages: {
+    "ann": 31,
+    "bob": 42,
+}
+origin: point{
+    x: 0,
+    y: 0,
+}
+total: add|
+    1,
+    2,
+|
+pair: add|1, 2,|
+after: 1
+`
+	ast, errors := ParseLint(Tokenize(source))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+	if len(ast.Children) != 7 {
+		t.Fatalf("expected 7 statements, got %d", len(ast.Children))
+	}
+	if params := ast.Children[0].Children[0].Children; len(params) != 2 {
+		t.Errorf("expected 2 parameters, got %d", len(params))
+	}
+	counts := map[string]int{"nums": 2, "ages": 4, "origin": 2, "total": 2, "pair": 2}
+	for _, stmt := range ast.Children[1:6] {
+		if got := len(stmt.Children[0].Children); got != counts[stmt.Value] {
+			t.Errorf("expected %d entries in %s, got %d", counts[stmt.Value], stmt.Value, got)
+		}
+	}
+	if last := ast.Children[6]; last.Value != "after" || last.Span.StartLine != 24 {
+		t.Errorf("expected after on line 24, got %q %+v", last.Value, last.Span)
+	}
+}
+
+func TestDictCapacityHint(t *testing.T) {
+	ast, errors := ParseLint(Tokenize("seen: dict[1000]\nports: dict[4]<\"http\": 80>\n"))
+	if len(errors) > 0 {
+		t.Fatalf("parse errors: %v", errors)
+	}
+	for i, want := range []string{"1000", "4"} {
+		dict := ast.Children[i].Children[0]
+		if dict.Type != NODE_DICT_LITERAL || dict.Capacity == nil || dict.Capacity.Value != want || dict.DefaultValue != nil {
+			t.Errorf("statement %d: expected a dict literal with capacity %s, got %+v", i, want, dict)
+		}
+	}
+}
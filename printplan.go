@@ -0,0 +1,176 @@
+package ahoy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatOperand is an argument a formatStep passes: C code written as it
+// is, or a node generated in its place
+type formatOperand struct {
+	code string
+	node *ASTNode
+}
+
+// formatStep is how print shows one value: the printf conversion it takes
+// and what is passed for it, which is the value itself when helper is empty
+// and otherwise helper called with the operands
+type formatStep struct {
+	spec     string
+	helper   string
+	operands []formatOperand
+}
+
+// formatPlan is how print shows a list of values, worked out from their
+// types before any C is written, so print, format, print_err, log and
+// panic all show a value the same way
+type formatPlan []formatStep
+
+// planFormat decides how each of args is shown. The helpers the plan calls
+// are marked as used.
+func (gen *CodeGenerator) planFormat(args []*ASTNode) formatPlan {
+	plan := make(formatPlan, len(args))
+	for i, arg := range args {
+		plan[i] = gen.planFormatStep(arg)
+	}
+	return plan
+}
+
+// planFormatStep decides how print shows arg. Containers, JSON, bytes,
+// ranges and structs go through the helper that turns them into text, and
+// values read out of untyped dicts through format_dict_value, which goes by
+// the type the dict stored them with.
+func (gen *CodeGenerator) planFormatStep(arg *ASTNode) formatStep {
+	argType := gen.inferType(arg)
+	value := []formatOperand{{node: arg}}
+	text := func(helper string, operands ...formatOperand) formatStep {
+		return formatStep{spec: "%s", helper: helper, operands: operands}
+	}
+
+	switch {
+	case arg.Type == NODE_IDENTIFIER && gen.isEnumType(arg.Value):
+		// The whole enum, listed by its print function
+		return text("print_" + arg.Value)
+	case gen.printedEnum(arg) != "":
+		return text("ahoy_enum_"+gen.printedEnum(arg)+"_name", value...)
+	case argType == "array" || strings.HasPrefix(argType, "array["):
+		if arg.Type == NODE_IDENTIFIER {
+			if elemType := gen.arrayElementTypes[arg.Value]; elemType == "char*" || elemType == "string" {
+				gen.arrayMethods["print_string_array"] = true
+				return text("print_string_array_helper", value...)
+			}
+		}
+		gen.arrayMethods["print_array"] = true
+		return text("print_array_helper", value...)
+	case argType == "dict" || strings.HasPrefix(argType, "dict["):
+		gen.dictMethods["print_dict"] = true
+		return text("print_dict_helper", value...)
+	case argType == "AhoyJSON*" || argType == "json":
+		return text("ahoy_json_stringify", value...)
+	case argType == "bytes":
+		// As a b"..." literal
+		return text("ahoy_bytes_format", value...)
+	case argType == "range":
+		// As the range|...| call that makes it
+		return text("ahoy_range_format", value...)
+	case argType == "struct" || gen.structs[argType] != nil || gen.structs[strings.ToLower(argType)] != nil:
+		// Struct helpers go by the Ahoy name: Vector2 prints with vector2's
+		gen.arrayMethods["print_struct"] = true
+		return text("print_struct_helper_"+strings.ToLower(argType), value...)
+	}
+
+	switch arg.Type {
+	case NODE_DICT_ACCESS:
+		// A float to the type checker, but the dict may hold any type
+		dict := arg.Value
+		if argType == "float" && gen.lookupVarType(arg.Value) == "generic" {
+			dict = "(HashMap*)" + dict
+		}
+		return text("format_dict_value", formatOperand{code: dict}, formatOperand{node: arg.Children[0]})
+	case NODE_IDENTIFIER:
+		if dictName, isDictSourced := gen.dictSourcedVars[arg.Value]; isDictSourced {
+			if key, hasKey := gen.dictSourcedKeys[arg.Value]; hasKey {
+				return text("format_dict_value", formatOperand{code: dictName}, formatOperand{code: fmt.Sprintf("\"%s\"", key)})
+			}
+			return formatStep{spec: "%s", operands: value}
+		}
+	case NODE_OBJECT_ACCESS:
+		if len(arg.Children) > 0 && (gen.variables[arg.Value] == "dict" || gen.functionVars[arg.Value] == "dict") {
+			// dict{"key"}, by the stored type tag
+			return text("format_dict_value", formatOperand{code: arg.Value}, formatOperand{node: arg.Children[0]})
+		}
+	case NODE_MEMBER_ACCESS:
+		if len(arg.Children) > 0 {
+			if objType := gen.inferType(arg.Children[0]); objType == "HashMap*" || objType == "dict" {
+				return text("format_dict_value", formatOperand{node: arg.Children[0]}, formatOperand{code: fmt.Sprintf("\"%s\"", arg.Value)})
+			}
+		}
+	}
+	return formatStep{spec: printSpec(argType), operands: value}
+}
+
+// printedEnum returns the int enum whose member names print shows the
+// variable arg with, or "" when arg isn't a variable holding one
+func (gen *CodeGenerator) printedEnum(arg *ASTNode) string {
+	if arg.Type != NODE_IDENTIFIER || (!gen.isVariable(arg.Value) && gen.lookupVarType(arg.Value) == "") {
+		return ""
+	}
+	if enumName := gen.valueEnum(arg); gen.enumTypes[enumName] == "int" {
+		return enumName
+	}
+	return ""
+}
+
+// lookupVarType returns the type a variable was declared with, or ""
+func (gen *CodeGenerator) lookupVarType(name string) string {
+	if varType, exists := gen.variables[name]; exists {
+		return varType
+	}
+	return gen.functionVars[name]
+}
+
+// printSpec returns the printf conversion print shows a value of a scalar
+// type with; bools show as 0 or 1
+func printSpec(valueType string) string {
+	switch valueType {
+	case "string", "char*", "const char*":
+		return "%s"
+	case "intptr_t":
+		return "%ld"
+	case "float", "double":
+		return "%g"
+	case "char":
+		return "%c"
+	}
+	return "%d"
+}
+
+// writeFormatPlan writes the printf arguments of plan: its conversions
+// joined by separator and followed by end as the format string, then the
+// values
+func (gen *CodeGenerator) writeFormatPlan(plan formatPlan, separator string, end string) {
+	specs := make([]string, len(plan))
+	for i, step := range plan {
+		specs[i] = step.spec
+	}
+	gen.output.WriteString(fmt.Sprintf("\"%s%s\"", strings.Join(specs, separator), end))
+	for _, step := range plan {
+		gen.output.WriteString(", ")
+		if step.helper != "" {
+			gen.output.WriteString(step.helper + "(")
+		}
+		for i, operand := range step.operands {
+			if i > 0 {
+				gen.output.WriteString(", ")
+			}
+			if operand.node != nil {
+				gen.generateNode(operand.node)
+			} else {
+				gen.output.WriteString(operand.code)
+			}
+		}
+		if step.helper != "" {
+			gen.output.WriteString(")")
+		}
+	}
+}
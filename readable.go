@@ -0,0 +1,134 @@
+package ahoy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultCLineWidth is the longest generated C line -readable-c leaves
+// whole when no width is given
+const DefaultCLineWidth = 100
+
+// commentStatement writes the Ahoy line a statement came from above its C
+// code when building readable C
+func (gen *CodeGenerator) commentStatement(statement *ASTNode) {
+	if gen.sourceLines == nil {
+		return
+	}
+	line := gen.statementLine(statement)
+	if line == 0 {
+		return
+	}
+	text := ""
+	if lines := gen.sourceLines[gen.currentFile]; line <= len(lines) {
+		// A trailing backslash would continue the comment onto the code
+		text = strings.TrimRight(strings.TrimSpace(lines[line-1]), "\\")
+	}
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("// %s:%d: %s\n", filepath.Base(gen.currentFile), line, text))
+}
+
+// readableC splits the lines of code longer than width at their braces and
+// statements, so the temporaries of statement expressions and the checks
+// around them read one per line. Preprocessor lines and comments are left
+// as they are.
+func readableC(code string, width int) string {
+	if width <= 0 {
+		width = DefaultCLineWidth
+	}
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(code, "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		trimmed := strings.TrimSpace(text)
+		if len(text) <= width || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") ||
+			strings.HasPrefix(trimmed, "/*") || strings.HasSuffix(trimmed, "\\") {
+			out.WriteString(line)
+			continue
+		}
+		out.WriteString(splitCLine(text))
+		out.WriteString(line[len(text):])
+	}
+	return out.String()
+}
+
+// splitCLine breaks a line of C after each opening brace and each statement
+// of a block, indenting the pieces by how many braces enclose them
+func splitCLine(line string) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	var lines []string
+	var current strings.Builder
+	depth, lineDepth := 0, 0
+	parens := 0
+	blockParens := []int{0} // paren depth where each open brace's statements end
+	flush := func() {
+		if text := strings.TrimRight(current.String(), " "); text != "" {
+			lines = append(lines, indent+strings.Repeat("    ", lineDepth)+text)
+		}
+		current.Reset()
+		lineDepth = depth
+	}
+	// rest is what follows position i, ignoring spaces
+	rest := func(i int) string {
+		return strings.TrimLeft(line[i:], " ")
+	}
+
+	for i := len(indent); i < len(line); i++ {
+		c := line[i]
+		if c == ' ' && current.Len() == 0 {
+			continue
+		}
+		switch {
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(line) && line[end] != c {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end, len(line)-1)
+			current.WriteString(line[i : end+1])
+			i = end
+		case strings.HasPrefix(line[i:], "//") || strings.HasPrefix(line[i:], "/*"):
+			// Comments run to the end of generated lines
+			current.WriteString(line[i:])
+			i = len(line)
+		case c == '(':
+			parens++
+			current.WriteByte(c)
+		case c == ')':
+			parens--
+			current.WriteByte(c)
+		case c == '{':
+			current.WriteByte(c)
+			depth++
+			blockParens = append(blockParens, parens)
+			flush()
+		case c == '}':
+			flush()
+			if depth > 0 {
+				depth--
+				blockParens = blockParens[:len(blockParens)-1]
+			}
+			lineDepth = depth
+			current.WriteByte(c)
+			// A statement expression's close, a statement's semicolon and an
+			// else stay with the brace
+			next := rest(i + 1)
+			if !strings.HasPrefix(next, ")") && !strings.HasPrefix(next, ";") && !strings.HasPrefix(next, ",") &&
+				!strings.HasPrefix(next, "else") {
+				flush()
+			}
+		case c == ';':
+			current.WriteByte(c)
+			if parens == blockParens[len(blockParens)-1] {
+				flush()
+			}
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,224 @@
+package ahoy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// generatePanic generates panic|message| or panic|"format", args|: a jump
+// to the innermost recover block running, or when there's none, the
+// message, where it was raised and the C stack on stderr, and exit status 1
+func (gen *CodeGenerator) generatePanic(node *ASTNode) {
+	gen.usePanic = true
+	gen.output.WriteString("ahoy_panic(")
+	args := node.Children
+	switch {
+	case len(args) == 0:
+		gen.output.WriteString(`""`)
+	case len(args) == 1 && args[0].Type == NODE_STRING:
+		gen.output.WriteString(fmt.Sprintf("\"%s\"", args[0].Value))
+	case args[0].Type == NODE_STRING && hasFormatPlaceholders(args[0].Value):
+		gen.markFormatUsed()
+		format, formatArgs := gen.processFormatString(args[0].Value, args[1:])
+		gen.output.WriteString(fmt.Sprintf("ahoy_format(\"%s\"", format))
+		for _, arg := range formatArgs {
+			gen.output.WriteString(", ")
+			gen.generateNode(arg)
+		}
+		gen.output.WriteString(")")
+	default:
+		// The values, separated by spaces
+		gen.markFormatUsed()
+		gen.output.WriteString("ahoy_format(")
+		gen.writeFormatPlan(gen.planFormat(args), " ", "")
+		gen.output.WriteString(")")
+	}
+	gen.output.WriteString(fmt.Sprintf(", %s, %d)", strconv.Quote(gen.sourceFilename), node.Line))
+}
+
+// generateRecover generates recover name: body. The block pushes a jump
+// target that panics in body longjmp to, and pops it when body finishes, a
+// panic lands, or a return, halt or next leaves it early.
+func (gen *CodeGenerator) generateRecover(node *ASTNode) {
+	gen.usePanic = true
+	frame := fmt.Sprintf("__recover_%d", gen.varCounter)
+	gen.varCounter++
+	gen.markRecoverExits(node.Children[0], frame, false)
+
+	if name := node.Value; name != "" {
+		gen.writeIndent()
+		if gen.currentFunction != "" && !gen.declaredFunctionVars[name] {
+			gen.writeVariableType("char*", name)
+			gen.functionVars[name] = "string"
+			gen.declaredFunctionVars[name] = true
+		} else if gen.currentFunction == "" && !gen.declaredGlobalVars[name] {
+			gen.writeVariableType("char*", name)
+			gen.variables[name] = "string"
+			gen.declaredGlobalVars[name] = true
+		}
+		gen.output.WriteString(name + " = \"\";\n")
+	}
+	gen.writeIndent()
+	gen.output.WriteString("{\n")
+	gen.indent++
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("AhoyRecover %s;\n", frame))
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("%s.outer = ahoy_recover_top;\n", frame))
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("ahoy_recover_top = &%s;\n", frame))
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("if (setjmp(%s.jump) == 0) {\n", frame))
+	gen.indent++
+	gen.generateNodeInternal(node.Children[0], false)
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("ahoy_recover_top = %s.outer;\n", frame))
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("} else {\n")
+	gen.indent++
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("ahoy_recover_top = %s.outer;\n", frame))
+	if node.Value != "" {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s = (char*)ahoy_panic_message;\n", node.Value))
+	}
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
+
+// markRecoverExits records the statements in a recover block's body that
+// leave it without finishing it: returns, and halts and nexts outside the
+// loops in the body. An exit already marked by an enclosing block leaves
+// that one too, so it keeps the outer frame.
+func (gen *CodeGenerator) markRecoverExits(node *ASTNode, frame string, inLoop bool) {
+	if node == nil || node.Type == NODE_LAMBDA {
+		return
+	}
+	switch node.Type {
+	case NODE_RETURN_STATEMENT:
+		if gen.recoverExits[node] == "" {
+			gen.recoverExits[node] = frame
+		}
+	case NODE_HALT, NODE_NEXT:
+		if !inLoop && gen.recoverExits[node] == "" {
+			gen.recoverExits[node] = frame
+		}
+	case NODE_WHILE_LOOP, NODE_FOR_LOOP, NODE_FOR_RANGE_LOOP, NODE_FOR_COUNT_LOOP,
+		NODE_FOR_IN_ARRAY_LOOP, NODE_FOR_IN_DICT_LOOP, NODE_SWITCH_STATEMENT:
+		inLoop = true
+	}
+	for _, child := range node.Children {
+		gen.markRecoverExits(child, frame, inLoop)
+	}
+}
+
+// recoverAssigned returns the variables assigned in the recover blocks in
+// body. A panic longjmps out of the block, after which a local the compiler
+// kept in a register may have lost what the block assigned to it.
+func recoverAssigned(body *ASTNode) map[string]bool {
+	assigned := map[string]bool{}
+	walkAST(body, func(node *ASTNode) bool {
+		if node.Type == NODE_LAMBDA {
+			return false
+		}
+		if node.Type != NODE_RECOVER {
+			return true
+		}
+		walkAST(node.Children[0], func(inner *ASTNode) bool {
+			switch inner.Type {
+			case NODE_LAMBDA:
+				return false
+			case NODE_VARIABLE_DECLARATION, NODE_ASSIGNMENT:
+				if inner.Value != "" {
+					assigned[inner.Value] = true
+				}
+			case NODE_TUPLE_ASSIGNMENT:
+				for _, target := range inner.Children[0].Children {
+					assigned[target.Value] = true
+				}
+			}
+			return true
+		})
+		return false
+	})
+	return assigned
+}
+
+// localType is the C type a local variable is declared with: volatile when
+// a recover block assigns it, so it keeps its value across the longjmp
+func (gen *CodeGenerator) localType(cType string, name string) string {
+	if gen.recoverVars[name] {
+		return cType + " volatile"
+	}
+	return cType
+}
+
+// leaveRecover pops the recover blocks a return, halt or next leaves
+func (gen *CodeGenerator) leaveRecover(node *ASTNode) {
+	if frame := gen.recoverExits[node]; frame != "" {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("ahoy_recover_top = %s.outer;\n", frame))
+	}
+}
+
+// writePanicHelperFunctions generates the recover stack and ahoy_panic.
+// Under ahoy test a panic no recover block catches fails the test it is in.
+func (gen *CodeGenerator) writePanicHelperFunctions() {
+	if !gen.usePanic {
+		return
+	}
+	for _, include := range []string{"setjmp.h", "execinfo.h"} {
+		if !gen.includes[include] {
+			gen.includes[include] = true
+			gen.orderedIncludes = append(gen.orderedIncludes, include)
+		}
+	}
+
+	gen.funcReturnStructs.WriteString("typedef struct AhoyRecover {\n    jmp_buf jump;\n    struct AhoyRecover* outer;\n} AhoyRecover;\n\n")
+	gen.funcReturnStructs.WriteString("extern AhoyRecover* ahoy_recover_top;\n")
+	gen.funcReturnStructs.WriteString("extern const char* ahoy_panic_message;\n")
+	gen.funcReturnStructs.WriteString("_Noreturn void ahoy_panic(const char* message, const char* file, int line);\n\n")
+
+	gen.funcDecls.WriteString("\n// panic and recover\n")
+	gen.funcDecls.WriteString("AhoyRecover* ahoy_recover_top = NULL;\n")
+	gen.funcDecls.WriteString("const char* ahoy_panic_message = \"\";\n\n")
+	gen.funcDecls.WriteString("_Noreturn void ahoy_panic(const char* message, const char* file, int line) {\n")
+	gen.funcDecls.WriteString("    if (ahoy_recover_top != NULL) {\n")
+	gen.funcDecls.WriteString("        ahoy_panic_message = message;\n")
+	gen.funcDecls.WriteString("        longjmp(ahoy_recover_top->jump, 1);\n")
+	gen.funcDecls.WriteString("    }\n")
+	gen.funcDecls.WriteString("    fflush(stdout);\n")
+	gen.funcDecls.WriteString("    fprintf(stderr, \"PANIC: %s\\n  at %s:%d\\n\", message, file, line);\n")
+	if gen.test {
+		gen.funcDecls.WriteString("    ahoy_test_failed();\n")
+		gen.funcDecls.WriteString("    exit(1);\n")
+	} else {
+		gen.funcDecls.WriteString("    void* frames[64];\n")
+		gen.funcDecls.WriteString("    int count = backtrace(frames, 64);\n")
+		gen.funcDecls.WriteString("    fprintf(stderr, \"stack:\\n\");\n")
+		gen.funcDecls.WriteString("    backtrace_symbols_fd(frames + 1, count - 1, 2);\n")
+		gen.funcDecls.WriteString("    exit(1);\n")
+	}
+	gen.funcDecls.WriteString("}\n\n")
+}
+
+// callsPanic reports whether node contains a panic call
+func callsPanic(node *ASTNode) bool {
+	if node == nil {
+		return false
+	}
+	if node.Type == NODE_CALL && node.Value == "panic" {
+		return true
+	}
+	for _, child := range node.Children {
+		if callsPanic(child) {
+			return true
+		}
+	}
+	return false
+}
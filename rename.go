@@ -0,0 +1,117 @@
+package ahoy
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Position is a place in a file. Line and Column are 1-based, with the
+// column counted in bytes from the start of the line.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// RenameEdit replaces the text at Span of File with NewText
+type RenameEdit struct {
+	File    string
+	Span    Span
+	NewText string
+}
+
+// Rename returns the edits that rename the symbol named at pos, and every
+// reference to it in the package's files, to newName. References resolve
+// the way Definition does, so a local in one function doesn't rename a
+// namesake in another and names reached through an import namespace are
+// left alone. It fails when newName would clash with a name already in
+// scope at any of the places it's written.
+func Rename(pkg *Package, pos Position, newName string) ([]RenameEdit, error) {
+	index := newSymbolIndex(pkg)
+	file := index.file(pos.File)
+	if file == nil {
+		return nil, fmt.Errorf("%s isn't a file of package %s", pos.File, pkg.Name)
+	}
+	at := tokenAt(file.tokens, pos.Line, pos.Column)
+	if at < 0 {
+		return nil, fmt.Errorf("no name at %s:%d:%d", pos.File, pos.Line, pos.Column)
+	}
+	target, ok := index.definition(file, at)
+	if !ok {
+		return nil, fmt.Errorf("can't find the declaration of %s", file.tokens[at].Value)
+	}
+	if !isIdentifier(newName) {
+		return nil, fmt.Errorf("%q isn't a valid name", newName)
+	}
+	if newName == target.Name {
+		return nil, nil
+	}
+
+	edits := []RenameEdit{}
+	for _, other := range index.files {
+		for i, token := range other.tokens {
+			if token.Type != TOKEN_IDENTIFIER || token.Value != target.Name {
+				continue
+			}
+			symbol, ok := index.definition(other, i)
+			if !ok || symbol.File != target.File || symbol.NameSpan != target.NameSpan {
+				continue
+			}
+			if clash, ok := index.clash(other, i, target, newName); ok {
+				return nil, fmt.Errorf("renaming %s to %s clashes with %s %s declared at %s:%d",
+					target.Name, newName, clash.Kind, clash.Name, clash.File, clash.NameSpan.StartLine)
+			}
+			edits = append(edits, RenameEdit{File: other.path, Span: token.Span, NewText: newName})
+		}
+	}
+	return edits, nil
+}
+
+// clash returns what newName already means where the reference to target
+// at index of file is written, if anything
+func (index *symbolIndex) clash(file *symbolFile, at int, target Symbol, newName string) (Symbol, bool) {
+	if target.Kind == SymbolField || target.Kind == SymbolEnumMember {
+		// Members only clash with the other members of their struct or enum
+		for _, other := range index.files {
+			for _, symbol := range other.symbols() {
+				for _, child := range symbol.Children {
+					if child.File == target.File && child.NameSpan == target.NameSpan {
+						return member(symbol, newName)
+					}
+				}
+			}
+		}
+		return Symbol{}, false
+	}
+	return index.resolve(file, newName, file.tokens[at].Span.Start)
+}
+
+// isIdentifier reports whether name tokenizes as a single identifier
+func isIdentifier(name string) bool {
+	tokens := Tokenize(name)
+	if len(tokens) == 0 || tokens[0].Type != TOKEN_IDENTIFIER || tokens[0].Value != name {
+		return false
+	}
+	for _, token := range tokens[1:] {
+		if !isLayoutToken(token.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyRenameEdits applies the edits for file to its source
+func ApplyRenameEdits(source string, file string, edits []RenameEdit) string {
+	var own []RenameEdit
+	for _, edit := range edits {
+		if edit.File == file {
+			own = append(own, edit)
+		}
+	}
+	// Apply from the end so earlier offsets stay valid
+	sort.Slice(own, func(i, j int) bool { return own[i].Span.Start > own[j].Span.Start })
+	for _, edit := range own {
+		source = source[:edit.Span.Start] + edit.NewText + source[edit.Span.End:]
+	}
+	return source
+}
@@ -0,0 +1,48 @@
+package ahoy
+
+import "testing"
+
+func TestRename(t *testing.T) {
+	sources := map[string]string{
+		"main.ahoy":   "total: 0\n@ add |n:int| int:\n    step: n + 1\n    total: total + step\n    return geo.step|total|\n$\n@ sub |n:int| int:\n    step: n - 1\n    return step\n$\n",
+		"shapes.ahoy": "enum side:\n    left\n    right\n$\nway: side.left\n",
+	}
+	pkg := symbolPackage(t, sources)
+
+	renamed := func(pos Position, newName string) map[string]string {
+		edits, err := Rename(pkg, pos, newName)
+		if err != nil {
+			t.Fatalf("Rename to %s: %v", newName, err)
+		}
+		return map[string]string{
+			"main.ahoy":   ApplyRenameEdits(sources["main.ahoy"], "main.ahoy", edits),
+			"shapes.ahoy": ApplyRenameEdits(sources["shapes.ahoy"], "shapes.ahoy", edits),
+		}
+	}
+
+	// A local renames in its own function only, and not through a namespace
+	got := renamed(Position{File: "main.ahoy", Line: 4, Column: 20}, "inc")
+	if expected := "total: 0\n@ add |n:int| int:\n    inc: n + 1\n    total: total + inc\n    return geo.step|total|\n$\n@ sub |n:int| int:\n    step: n - 1\n    return step\n$\n"; got["main.ahoy"] != expected {
+		t.Errorf("renaming a local gave\n%s", got["main.ahoy"])
+	}
+
+	got = renamed(Position{File: "shapes.ahoy", Line: 2, Column: 5}, "west")
+	if expected := "enum side:\n    west\n    right\n$\nway: side.west\n"; got["shapes.ahoy"] != expected {
+		t.Errorf("renaming an enum member gave\n%s", got["shapes.ahoy"])
+	}
+
+	got = renamed(Position{File: "main.ahoy", Line: 1, Column: 1}, "sum")
+	if expected := "sum: 0\n@ add |n:int| int:\n    step: n + 1\n    sum: sum + step\n    return geo.step|sum|\n$\n@ sub |n:int| int:\n    step: n - 1\n    return step\n$\n"; got["main.ahoy"] != expected {
+		t.Errorf("renaming a global gave\n%s", got["main.ahoy"])
+	}
+
+	if _, err := Rename(pkg, Position{File: "main.ahoy", Line: 1, Column: 1}, "n"); err == nil {
+		t.Errorf("expected renaming total to a parameter's name to fail")
+	}
+	if _, err := Rename(pkg, Position{File: "shapes.ahoy", Line: 2, Column: 5}, "right"); err == nil {
+		t.Errorf("expected renaming left to another member's name to fail")
+	}
+	if _, err := Rename(pkg, Position{File: "main.ahoy", Line: 1, Column: 1}, "loop"); err == nil {
+		t.Errorf("expected a keyword to be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+package ahoy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BuildReport is what BuildOptions.Report writes to build-report.json, for
+// CI dashboards that follow generated code size over time
+type BuildReport struct {
+	Package       string       `json:"package"`
+	Files         []FileReport `json:"files"`
+	CFile         string       `json:"c_file"`
+	CBytes        int          `json:"c_bytes"`
+	CLines        int          `json:"c_lines"`
+	Helpers       []string     `json:"helpers"` // runtime helper functions in the C code
+	CodegenMS     float64      `json:"codegen_ms"`
+	CompileMS     float64      `json:"compile_ms,omitempty"`
+	LinkMS        float64      `json:"link_ms,omitempty"`
+	Warnings      []string     `json:"warnings"`                 // diagnostics and gcc warnings
+	RuntimeCached bool         `json:"runtime_cached,omitempty"` // the compiled runtime came from BuildOptions.RuntimeCache
+}
+
+// FileReport is a source file in a BuildReport
+type FileReport struct {
+	Path  string `json:"path"`
+	Lines int    `json:"lines"`
+}
+
+// ReportFile is the name of the report written next to the C file
+const ReportFile = "build-report.json"
+
+// cFunctionDefinition matches the first line of a C function definition
+var cFunctionDefinition = regexp.MustCompile(`^[A-Za-z_][\w \*]*?\b([A-Za-z_]\w*)\([^;]*\)\s*\{\s*$`)
+
+// newBuildReport describes the sources of a build; the rest is filled in as
+// the build goes
+func newBuildReport(pkg *Package, imports map[string]*Package) *BuildReport {
+	report := &BuildReport{Package: pkg.Name, Files: []FileReport{}, Helpers: []string{}, Warnings: []string{}}
+	seen := map[string]bool{}
+	addFiles := func(pkg *Package) {
+		for _, file := range pkg.Files {
+			if seen[file.Path] {
+				continue
+			}
+			seen[file.Path] = true
+			lines := strings.Count(file.Content, "\n")
+			if file.Content != "" && !strings.HasSuffix(file.Content, "\n") {
+				lines++
+			}
+			report.Files = append(report.Files, FileReport{Path: file.Path, Lines: lines})
+		}
+	}
+	addFiles(pkg)
+	for _, imported := range imports {
+		addFiles(imported)
+	}
+	sort.Slice(report.Files[len(pkg.Files):], func(i, j int) bool {
+		rest := report.Files[len(pkg.Files):]
+		return rest[i].Path < rest[j].Path
+	})
+	return report
+}
+
+// addCode records the size of the C code and the helpers in it, which are
+// the functions the program didn't define itself
+func (report *BuildReport) addCode(cCode string, ast *ASTNode) {
+	report.CBytes = len(cCode)
+	report.CLines = strings.Count(cCode, "\n")
+
+	userFunctions := map[string]bool{"main": true, "ahoy_main": true}
+	for _, child := range ast.Children {
+		if child.Type == NODE_FUNCTION {
+			userFunctions[child.Value] = true
+		}
+	}
+	for _, line := range strings.Split(cCode, "\n") {
+		if match := cFunctionDefinition.FindStringSubmatch(line); match != nil && !userFunctions[match[1]] {
+			report.Helpers = append(report.Helpers, match[1])
+		}
+	}
+}
+
+// addDiagnostics records diagnostics as warnings
+func (report *BuildReport) addDiagnostics(diagnostics []Diagnostic) {
+	for _, d := range diagnostics {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s:%d: %s: %s", d.File, d.Line, d.Severity, d.Message))
+	}
+}
+
+// addCompilerOutput records the warnings in gcc's output
+func (report *BuildReport) addCompilerOutput(output []byte) {
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.Contains(line, "warning:") {
+			report.Warnings = append(report.Warnings, strings.TrimSpace(line))
+		}
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func (report *BuildReport) write(path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
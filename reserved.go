@@ -0,0 +1,91 @@
+package ahoy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cKeywords can't name anything in the C a program compiles to
+var cKeywords = setOf(
+	"auto", "break", "case", "char", "const", "continue", "default", "do",
+	"double", "else", "enum", "extern", "float", "for", "goto", "if",
+	"inline", "int", "long", "register", "restrict", "return", "short",
+	"signed", "sizeof", "static", "struct", "switch", "typedef", "union",
+	"unsigned", "void", "volatile", "while", "bool", "true", "false", "NULL",
+)
+
+// cLibraryNames are the C library functions from the headers every program
+// may include; the runtime calls many of them
+var cLibraryNames = setOf(
+	// stdio.h
+	"printf", "fprintf", "sprintf", "snprintf", "vprintf", "vsnprintf",
+	"puts", "fputs", "putchar", "fputc", "getchar", "fgets", "getline",
+	"scanf", "sscanf", "fscanf", "fopen", "fclose", "fread", "fwrite",
+	"fflush", "fseek", "ftell", "rewind", "remove", "rename", "perror",
+	// stdlib.h
+	"malloc", "calloc", "realloc", "free", "exit", "abort", "atexit",
+	"atoi", "atof", "atol", "strtol", "strtoll", "strtoul", "strtod",
+	"rand", "srand", "qsort", "bsearch", "getenv", "setenv", "system",
+	"abs", "labs",
+	// string.h
+	"strlen", "strcmp", "strncmp", "strcpy", "strncpy", "strcat", "strncat",
+	"strdup", "strndup", "strchr", "strrchr", "strstr", "strtok", "strspn",
+	"strcspn", "strerror", "memcpy", "memmove", "memset", "memcmp", "memchr",
+	// math.h
+	"sqrt", "pow", "floor", "ceil", "round", "trunc", "fabs", "fmod",
+	"fmin", "fmax", "hypot", "sin", "cos", "tan", "asin", "acos", "atan",
+	"atan2", "exp", "log", "log2", "log10",
+	// time.h, ctype.h, signal.h, errno.h and regex.h
+	"time", "clock", "clock_gettime", "nanosleep", "difftime", "localtime",
+	"gmtime", "mktime", "strftime",
+	"isalpha", "isdigit", "isalnum", "isspace", "isupper", "islower",
+	"ispunct", "toupper", "tolower",
+	"signal", "raise", "errno", "regcomp", "regexec", "regfree",
+)
+
+// runtimeNames are the runtime's helpers that don't start with ahoy_
+var runtimeNames = setOf(
+	"hash", "createHashMap", "freeHashMap", "hashMapGet", "hashMapPut",
+	"hashMapGetTyped", "hashMapPutTyped", "hashMapGetValue", "hashMapPutValue",
+	"hashMapGetDouble", "hashMapResize", "createArray", "freeArray",
+	"format_dict_value", "format_hashmap_value", "print_dict_helper",
+	"print_array_helper",
+)
+
+func setOf(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// checkDeclaredName reports a function or enum whose name the generated C
+// already uses. The names of Ahoy's builtins are free to take: a function
+// the program declares is called in place of the builtin.
+func (gen *CodeGenerator) checkDeclaredName(node *ASTNode, kind string) {
+	name := node.Value
+	reason := ""
+	switch {
+	case cKeywords[name]:
+		reason = "a C keyword"
+	case cLibraryNames[name]:
+		reason = "a C library function"
+	case runtimeNames[name], strings.HasPrefix(name, "ahoy_") && !gen.isInlineTest(name):
+		reason = "a name the Ahoy runtime uses"
+	default:
+		return
+	}
+	gen.reportError(node.Line, fmt.Sprintf("can't name %s '%s': it's %s", kind, name, reason),
+		"The compiled C program already uses the name; pick another one")
+}
+
+// isInlineTest reports whether name is the function a test block became
+func (gen *CodeGenerator) isInlineTest(name string) bool {
+	for _, test := range gen.tests {
+		if test.function == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,373 @@
+package ahoy
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RuntimeFile and RuntimeHeader are the files BuildOptions.SplitRuntime
+// writes the runtime helpers to, next to the program's C file
+const (
+	RuntimeFile   = "ahoy_runtime.c"
+	RuntimeHeader = "ahoy_runtime.h"
+)
+
+// Runtime helpers are fenced with these comments while the program is
+// generated, so they can be pruned and split out once it's whole
+const (
+	runtimeBegin = "// ahoy:runtime\n"
+	runtimeEnd   = "// ahoy:end-runtime\n"
+)
+
+// cProgram is generated C: the program and, when the runtime is split out,
+// the runtime's header and source
+type cProgram struct {
+	code    string
+	header  string
+	runtime string
+
+	// A library's C header and its cgo and ctypes bindings
+	libraryHeader  string
+	goBindings     string
+	pythonBindings string
+}
+
+// writeRuntime runs a writer of runtime helpers, fencing what it writes
+func (gen *CodeGenerator) writeRuntime(write func()) {
+	for _, builder := range []*strings.Builder{&gen.funcDecls, &gen.funcReturnStructs} {
+		beginRuntime(builder)
+	}
+	write()
+	for _, builder := range []*strings.Builder{&gen.funcDecls, &gen.funcReturnStructs} {
+		endRuntime(builder)
+	}
+}
+
+func beginRuntime(builder *strings.Builder) {
+	if builder.Len() > 0 && !strings.HasSuffix(builder.String(), "\n") {
+		builder.WriteString("\n")
+	}
+	builder.WriteString(runtimeBegin)
+}
+
+func endRuntime(builder *strings.Builder) {
+	if builder.Len() > 0 && !strings.HasSuffix(builder.String(), "\n") {
+		builder.WriteString("\n")
+	}
+	builder.WriteString(runtimeEnd)
+}
+
+// cItemKind is what a top-level piece of C is
+type cItemKind int
+
+const (
+	cOther cItemKind = iota // comments and blank lines
+	cDirective
+	cType
+	cPrototype
+	cVariable
+	cFunction
+)
+
+// cItem is a top-level declaration or definition with the comments and
+// blank lines before it
+type cItem struct {
+	kind    cItemKind
+	name    string // the function's name, for functions and prototypes
+	first   string // the first line of code
+	text    string
+	runtime bool // fenced as a runtime helper
+}
+
+var cIdentifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// splitRuntime prunes the runtime helpers nothing calls from generated C
+// and removes their fences. With split set, the helpers move to a header
+// of their types and prototypes and a source file of their definitions.
+func splitRuntime(code string, split bool) cProgram {
+	items := cItems(code)
+
+	// Everything outside the runtime is used, as is what it and the
+	// runtime's macros call
+	used := map[string]bool{}
+	addUses := func(text string) {
+		for _, name := range cIdentifier.FindAllString(text, -1) {
+			used[name] = true
+		}
+	}
+	for _, item := range items {
+		if item.kind == cVariable || item.kind == cOther || item.kind == cDirective || (item.kind == cFunction && !item.runtime) {
+			addUses(item.text)
+		}
+	}
+	kept := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, item := range items {
+			if item.kind == cFunction && item.runtime && used[item.name] && !kept[item.name] {
+				kept[item.name] = true
+				addUses(item.text)
+				changed = true
+			}
+		}
+	}
+	pruned := map[string]bool{}
+	for _, item := range items {
+		if item.kind == cFunction && item.runtime && !kept[item.name] {
+			pruned[item.name] = true
+		}
+	}
+
+	var program, header, runtime strings.Builder
+	var early map[string]bool
+	if !split {
+		early = calledBeforeDefined(items, pruned)
+	}
+	declared := false
+	if split {
+		header.WriteString("// Ahoy runtime helpers used by the program\n")
+		header.WriteString("#ifndef AHOY_RUNTIME_H\n#define AHOY_RUNTIME_H\n\n")
+		runtime.WriteString("#include \"" + RuntimeHeader + "\"\n")
+		program.WriteString("#include \"" + RuntimeHeader + "\"\n")
+	}
+	for _, item := range items {
+		if (item.kind == cFunction || item.kind == cPrototype) && item.runtime && pruned[item.name] {
+			continue
+		}
+		if item.kind == cFunction && !item.runtime && !declared && len(early) > 0 {
+			// Functions called ahead of their definitions are declared
+			// before the program's first, after every type
+			declared = true
+			program.WriteString("\n")
+			for _, other := range items {
+				if other.kind == cFunction && early[other.name] {
+					program.WriteString(prototype(other) + "\n")
+				}
+			}
+		}
+		if !split || !item.runtime {
+			program.WriteString(item.text)
+			continue
+		}
+		switch item.kind {
+		case cFunction:
+			runtime.WriteString(item.text)
+			if !strings.HasPrefix(item.first, "static ") {
+				header.WriteString(prototype(item) + "\n")
+			}
+		case cVariable:
+			runtime.WriteString(item.text)
+			if !strings.HasPrefix(item.first, "static ") {
+				declaration, _, _ := strings.Cut(item.first, "=")
+				header.WriteString("extern " + strings.TrimSuffix(strings.TrimSpace(declaration), ";") + ";\n")
+			}
+		case cOther:
+			runtime.WriteString(item.text)
+		default:
+			header.WriteString(item.text)
+		}
+	}
+	if !split {
+		return cProgram{code: program.String()}
+	}
+	header.WriteString("\n#endif\n")
+	return cProgram{code: program.String(), header: header.String(), runtime: runtime.String()}
+}
+
+// prototype returns the declaration of a function item
+func prototype(item cItem) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(item.first), "{")) + ";"
+}
+
+// calledBeforeDefined returns the functions that are named in the C before
+// they are defined or declared, which are the runtime helpers the
+// program's own functions call
+func calledBeforeDefined(items []cItem, pruned map[string]bool) map[string]bool {
+	functions := map[string]bool{}
+	for _, item := range items {
+		if item.kind == cFunction && !pruned[item.name] {
+			functions[item.name] = true
+		}
+	}
+	early := map[string]bool{}
+	known := map[string]bool{}
+	for _, item := range items {
+		if item.kind == cFunction || item.kind == cPrototype {
+			known[item.name] = true
+		}
+		if item.kind != cFunction || pruned[item.name] {
+			continue
+		}
+		for _, name := range cIdentifier.FindAllString(item.text, -1) {
+			if functions[name] && !known[name] {
+				early[name] = true
+			}
+		}
+	}
+	return early
+}
+
+// cItems splits generated C into its top-level items, marking those
+// between runtime fences
+func cItems(code string) []cItem {
+	var items []cItem
+	var text strings.Builder
+	first := ""
+	depth := 0
+	inComment := false
+	runtime := false
+	finish := func(kind cItemKind) {
+		item := cItem{kind: kind, first: first, text: text.String(), runtime: runtime}
+		if kind == cFunction || kind == cPrototype {
+			if open := strings.Index(first, "("); open > 0 {
+				names := cIdentifier.FindAllString(first[:open], -1)
+				if len(names) > 0 {
+					item.name = names[len(names)-1]
+				}
+			}
+		}
+		items = append(items, item)
+		text.Reset()
+		first = ""
+	}
+
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if line == runtimeBegin || line == runtimeEnd {
+			if text.Len() > 0 && first == "" {
+				finish(cOther)
+			}
+			runtime = line == runtimeBegin
+			continue
+		}
+		text.WriteString(line)
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 && first == "" {
+			if inComment || trimmed == "" || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") {
+				inComment = cCommentOpen(line, inComment)
+				continue
+			}
+			first = line
+			if strings.HasPrefix(trimmed, "#") {
+				finish(cDirective)
+				continue
+			}
+		}
+		opened := depth > 0
+		depth, inComment = cBraceDepth(line, depth, inComment)
+		if depth > 0 {
+			continue
+		}
+		firstLine := strings.TrimSpace(first)
+		switch {
+		case strings.HasSuffix(trimmed, "}") && (opened || strings.Contains(trimmed, "{")) && isFunctionHead(firstLine):
+			finish(cFunction)
+		case !strings.HasSuffix(trimmed, ";"):
+			// A function's opening line, or a declaration that goes on
+		case strings.HasPrefix(firstLine, "typedef") || strings.HasPrefix(firstLine, "struct") ||
+			strings.HasPrefix(firstLine, "enum") || strings.HasPrefix(firstLine, "union"):
+			finish(cType)
+		case strings.Contains(firstLine, "(") && !strings.Contains(firstLine, "=") && !strings.Contains(firstLine, "{"):
+			finish(cPrototype)
+		default:
+			finish(cVariable)
+		}
+	}
+	if text.Len() > 0 {
+		finish(cOther)
+	}
+	return items
+}
+
+// isFunctionHead reports whether a top-level line starts a function
+// definition rather than an initialized variable
+func isFunctionHead(line string) bool {
+	open := strings.Index(line, "(")
+	return open > 0 && !strings.Contains(line[:open], "=") && !strings.HasPrefix(line, "typedef")
+}
+
+// cBraceDepth returns the brace depth after line, skipping braces in
+// literals and comments
+func cBraceDepth(line string, depth int, inComment bool) (int, bool) {
+	for i := 0; i < len(line); i++ {
+		if inComment {
+			if strings.HasPrefix(line[i:], "*/") {
+				inComment = false
+				i++
+			}
+			continue
+		}
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], "//"):
+			return depth, false
+		case strings.HasPrefix(line[i:], "/*"):
+			inComment = true
+			i++
+		case c == '"' || c == '\'':
+			for i++; i < len(line) && line[i] != c; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		}
+	}
+	return depth, inComment
+}
+
+// cCommentOpen reports whether a block comment is still open after line
+func cCommentOpen(line string, inComment bool) bool {
+	_, inComment = cBraceDepth(line, 0, inComment)
+	return inComment
+}
+
+// runtimeCompileArgs are the gcc arguments compiling the runtime at source
+// to object with flags
+func runtimeCompileArgs(flags []string, object string, source string) []string {
+	return append(slices.Clone(flags), "-o", object, source)
+}
+
+// cachedRuntime returns the compiled runtime at source from cacheDir,
+// compiling it there when no build has yet. Objects are keyed by the
+// compiler's version, the flags and the runtime's header and code, as each
+// program keeps only the helpers it uses.
+func cachedRuntime(cacheDir string, flags []string, source string, program cProgram) (object string, cached bool, output []byte, err error) {
+	version, err := exec.Command("gcc", "--version").Output()
+	if err != nil {
+		return "", false, nil, fmt.Errorf("gcc --version: %v", err)
+	}
+	hash := sha256.New()
+	for _, part := range []string{string(version), strings.Join(flags, " "), program.header, program.runtime} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	object = filepath.Join(cacheDir, fmt.Sprintf("runtime-%x.o", hash.Sum(nil)[:12]))
+	if _, err := os.Stat(object); err == nil {
+		return object, true, nil, nil
+	}
+
+	// Compiled beside its name and renamed, so builds running at the same
+	// time never link half an object
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", false, nil, err
+	}
+	temp, err := os.CreateTemp(cacheDir, "runtime-*.tmp")
+	if err != nil {
+		return "", false, nil, err
+	}
+	temp.Close()
+	output, err = exec.Command("gcc", runtimeCompileArgs(flags, temp.Name(), source)...).CombinedOutput()
+	if err != nil {
+		os.Remove(temp.Name())
+		return "", false, output, err
+	}
+	return object, false, output, os.Rename(temp.Name(), object)
+}
@@ -0,0 +1,168 @@
+package ahoy
+
+// Semantic token kinds, named like the LSP's token types
+const (
+	SemanticKeyword    = "keyword"
+	SemanticOperator   = "operator"
+	SemanticType       = "type"
+	SemanticFunction   = "function"
+	SemanticParameter  = "parameter"
+	SemanticVariable   = "variable"
+	SemanticProperty   = "property"
+	SemanticEnumMember = "enumMember"
+	SemanticConstant   = "constant"
+	SemanticNamespace  = "namespace"
+	SemanticString     = "string"
+	SemanticNumber     = "number"
+	SemanticComment    = "comment"
+)
+
+// SemanticToken is a classified range of source for highlighting.
+// Declaration marks the name in a declaration, as opposed to a use.
+type SemanticToken struct {
+	Span        Span   `json:"span"`
+	Kind        string `json:"kind"`
+	Declaration bool   `json:"declaration,omitempty"`
+}
+
+// tokenKinds classifies the tokens whose kind doesn't depend on context
+var tokenKinds = map[TokenType]string{
+	TOKEN_STRING: SemanticString, TOKEN_CHAR: SemanticString, TOKEN_F_STRING: SemanticString, TOKEN_BYTES_STRING: SemanticString,
+	TOKEN_NUMBER: SemanticNumber,
+
+	TOKEN_THEN: SemanticKeyword, TOKEN_ON: SemanticKeyword, TOKEN_IF: SemanticKeyword, TOKEN_ELSE: SemanticKeyword,
+	TOKEN_ELSEIF: SemanticKeyword, TOKEN_ANIF: SemanticKeyword, TOKEN_SWITCH: SemanticKeyword, TOKEN_LOOP: SemanticKeyword,
+	TOKEN_IN: SemanticKeyword, TOKEN_TO: SemanticKeyword, TOKEN_TILL: SemanticKeyword, TOKEN_FUNC: SemanticKeyword,
+	TOKEN_RETURN: SemanticKeyword, TOKEN_IMPORT: SemanticKeyword, TOKEN_PROGRAM: SemanticKeyword, TOKEN_WHEN: SemanticKeyword,
+	TOKEN_TRUE: SemanticKeyword, TOKEN_FALSE: SemanticKeyword, TOKEN_ENUM: SemanticKeyword, TOKEN_STRUCT: SemanticKeyword,
+	TOKEN_TYPE: SemanticKeyword, TOKEN_ALIAS: SemanticKeyword, TOKEN_UNION: SemanticKeyword, TOKEN_DO: SemanticKeyword,
+	TOKEN_HALT: SemanticKeyword, TOKEN_NEXT: SemanticKeyword, TOKEN_ASSERT: SemanticKeyword, TOKEN_DEFER: SemanticKeyword,
+	TOKEN_END: SemanticKeyword, TOKEN_AT: SemanticKeyword,
+
+	TOKEN_AHOY: SemanticFunction, TOKEN_PRINT: SemanticFunction, TOKEN_LOG: SemanticFunction, TOKEN_PANIC: SemanticFunction,
+
+	TOKEN_IS: SemanticOperator, TOKEN_NOT: SemanticOperator, TOKEN_OR: SemanticOperator, TOKEN_AND: SemanticOperator,
+	TOKEN_PLUS_WORD: SemanticOperator, TOKEN_MINUS_WORD: SemanticOperator, TOKEN_TIMES_WORD: SemanticOperator,
+	TOKEN_DIV_WORD: SemanticOperator, TOKEN_MOD_WORD: SemanticOperator, TOKEN_LESSER_WORD: SemanticOperator,
+	TOKEN_GREATER_WORD: SemanticOperator,
+
+	TOKEN_INT_TYPE: SemanticType, TOKEN_FLOAT_TYPE: SemanticType, TOKEN_STRING_TYPE: SemanticType, TOKEN_CHAR_TYPE: SemanticType,
+	TOKEN_BOOL_TYPE: SemanticType, TOKEN_DICT_TYPE: SemanticType, TOKEN_ARRAY_TYPE: SemanticType, TOKEN_INFER: SemanticType,
+	TOKEN_VOID: SemanticType,
+}
+
+// symbolKinds gives the semantic kind of a name from what it declares
+var symbolKinds = map[SymbolKind]string{
+	SymbolFunction:   SemanticFunction,
+	SymbolStruct:     SemanticType,
+	SymbolField:      SemanticProperty,
+	SymbolEnum:       SemanticType,
+	SymbolEnumMember: SemanticEnumMember,
+	SymbolConstant:   SemanticConstant,
+	SymbolVariable:   SemanticVariable,
+	SymbolType:       SemanticType,
+	SymbolParameter:  SemanticParameter,
+}
+
+// contextualKeywords are identifiers that are keywords in the statements
+// they start, like embed "path" as name
+var contextualKeywords = map[NodeType][]string{
+	NODE_EMBED_STATEMENT:    {"embed", "as"},
+	NODE_EXTERN:             {"extern", "from", "link"},
+	NODE_IMPORT_STATEMENT:   {"from"},
+	NODE_REEXPORT:           {"pub", "use"},
+	NODE_RECOVER:            {"recover"},
+	NODE_TEST_BLOCK:         {"test"},
+	NODE_STRUCT_DECLARATION: {"json"},
+	NODE_COMPTIME:           {"comptime"},
+	NODE_FUNCTION:           {"inline", "noinline", "export"},
+}
+
+// SemanticTokens classifies the tokens and comments of a parsed file for
+// highlighting, in source order. Names are classified by what they resolve
+// to the way Definition resolves them, so color.red is an enum member
+// while point.x is a property; names declared in other files fall back to
+// function when called and variable otherwise. Punctuation is left out.
+func SemanticTokens(ast *ASTNode, source string) []SemanticToken {
+	file := newSymbolFile(ast, source, "")
+	index := &symbolIndex{files: []*symbolFile{file}}
+	keywords := contextualKeywordSpans(ast, file.tokens)
+	namespaces := map[string]bool{}
+	if ast != nil {
+		for _, node := range ast.Children {
+			if node.Type == NODE_IMPORT_STATEMENT && node.DataType != "" {
+				namespaces[node.DataType] = true
+			}
+		}
+	}
+
+	semanticTokens := []SemanticToken{}
+	for i, token := range file.tokens {
+		if token.Type == TOKEN_NEWLINE {
+			if token.Comment != "" {
+				semanticTokens = append(semanticTokens, SemanticToken{Span: token.Span, Kind: SemanticComment})
+			}
+			continue
+		}
+		if kind, ok := tokenKinds[token.Type]; ok {
+			semanticTokens = append(semanticTokens, SemanticToken{Span: token.Span, Kind: kind})
+			continue
+		}
+		if token.Type != TOKEN_IDENTIFIER {
+			continue
+		}
+		if keywords[token.Span.Start] {
+			semanticTokens = append(semanticTokens, SemanticToken{Span: token.Span, Kind: SemanticKeyword})
+			continue
+		}
+
+		semantic := SemanticToken{Span: token.Span, Kind: SemanticVariable}
+		called := i+1 < len(file.tokens) && file.tokens[i+1].Type == TOKEN_PIPE
+		member := i > 0 && file.tokens[i-1].Type == TOKEN_DOT
+		if symbol, ok := index.definition(file, i); ok {
+			semantic.Kind = symbolKinds[symbol.Kind]
+			semantic.Declaration = symbol.NameSpan == token.Span
+		} else if called {
+			semantic.Kind = SemanticFunction
+		} else if member {
+			semantic.Kind = SemanticProperty
+		} else if namespaces[token.Value] && i > 0 && file.tokens[i-1].Type == TOKEN_IMPORT {
+			semantic.Kind = SemanticNamespace
+			semantic.Declaration = true
+		} else if namespaces[token.Value] && i+1 < len(file.tokens) && file.tokens[i+1].Type == TOKEN_DOT {
+			semantic.Kind = SemanticNamespace
+		}
+		semanticTokens = append(semanticTokens, semantic)
+	}
+	return semanticTokens
+}
+
+// contextualKeywordSpans returns the start offsets of the identifiers that
+// act as keywords in the statements of ast
+func contextualKeywordSpans(ast *ASTNode, tokens []Token) map[int]bool {
+	starts := map[int]bool{}
+	walkAST(ast, func(node *ASTNode) bool {
+		words := contextualKeywords[node.Type]
+		if len(words) == 0 || node.Span.IsZero() {
+			return true
+		}
+		for _, token := range tokens {
+			// Only the statement's first line holds its keywords
+			if token.Span.Start < node.Span.Start || token.Type == TOKEN_NEWLINE {
+				if token.Span.Start >= node.Span.Start {
+					break
+				}
+				continue
+			}
+			if token.Type == TOKEN_IDENTIFIER && token.Value != node.Value {
+				for _, word := range words {
+					if token.Value == word {
+						starts[token.Span.Start] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+	return starts
+}
@@ -0,0 +1,52 @@
+package ahoy
+
+import "testing"
+
+func TestSemanticTokens(t *testing.T) {
+	source := `import rl "raylib.h"
+? Colors
+enum color:
+    red
+$
+struct point:
+    x: int
+$
+p: point{x: 1}
+@ paint |c:color| int:
+    if c is color.red then
+        rl.DrawPixel|p.x, 0| ? one dot
+    $
+    return helper|1|
+$
+`
+	ast, _ := ParseLint(Tokenize(source))
+	var got []string
+	for _, token := range SemanticTokens(ast, source) {
+		text := source[token.Span.Start:token.Span.End]
+		if token.Declaration {
+			text += "*"
+		}
+		got = append(got, text+" "+token.Kind)
+	}
+	expected := []string{
+		"import keyword", "rl* namespace", `"raylib.h" string`,
+		"? Colors comment",
+		"enum keyword", "color* type", "red* enumMember", "$ keyword",
+		"struct keyword", "point* type", "x* property", "int type", "$ keyword",
+		"p* variable", "point type", "x property", "1 number",
+		"@ keyword", "paint* function", "c* parameter", "color type", "int type",
+		"if keyword", "c parameter", "is operator", "color type", "red enumMember", "then keyword",
+		"rl namespace", "DrawPixel function", "p variable", "x property", "0 number", "? one dot comment",
+		"$ keyword",
+		"return keyword", "helper function", "1 number",
+		"$ keyword",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %q", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("token %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+}
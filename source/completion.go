@@ -0,0 +1,311 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command is a subcommand, run as "ahoy <name> [args]"
+type command struct {
+	name    string
+	args    string // argument synopsis; a|b|c lists the choices
+	summary string
+	flags   *flag.FlagSet // nil when the command takes no flags
+}
+
+// commands are the subcommands; the compile flags are defined in main
+var commands = []command{
+	{"completion", "bash|zsh|fish", "Print a shell completion script", nil},
+	{"doctor", "", "Check the build environment and suggest fixes", nil},
+	{"emit", "-f file -fn function", "Print the generated C of one function and the runtime helpers it calls", emitFlags},
+	{"fuzz", "tokenize|parse|lint", "Fuzz the tokenizer and parser (needs Go and the compiler source)", fuzzFlags},
+	{"graph", "-f file [-calls] [-dot]", "Print the import graph or the call graph of a program", graphFlags},
+	{"man", "", "Print the man page", nil},
+	{"rename", "-f file -line n -col n", "Rename a symbol across the files of its package", renameFlags},
+	{"run", "-f file [-leak-check]", "Build and run a program, optionally checking it for leaks", runFlags},
+	{"test", "-f file", "Build and run the test blocks of a program, or -programs dir against recorded output", testFlags},
+}
+
+// choices returns the values a command's argument can take, if it lists them
+func (c command) choices() []string {
+	if !strings.Contains(c.args, "|") {
+		return nil
+	}
+	return strings.Split(c.args, "|")
+}
+
+// flagsOf returns the flags of a set in the order flag prints them; the
+// compile flags when set is nil
+func flagsOf(set *flag.FlagSet) []*flag.Flag {
+	if set == nil {
+		set = flag.CommandLine
+	}
+	var flags []*flag.Flag
+	set.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// flagNames returns each flag with its dash
+func flagNames(flags []*flag.Flag) string {
+	var names []string
+	for _, f := range flags {
+		names = append(names, "-"+f.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+// takesValue reports whether a flag needs an argument
+func takesValue(f *flag.Flag) bool {
+	name, _ := flag.UnquoteUsage(f)
+	return name != ""
+}
+
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy completion bash|zsh|fish")
+		return 1
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		writeZshCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown shell '%s', expected bash, zsh or fish\n", args[0])
+		return 1
+	}
+	return 0
+}
+
+// writeBashCompletion writes a script to source from .bashrc or to install
+// under bash-completion's completions directory
+func writeBashCompletion(w io.Writer) {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+
+	fmt.Fprintln(w, "# bash completion for ahoy")
+	fmt.Fprintln(w, "_ahoy() {")
+	fmt.Fprintln(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"")
+	fmt.Fprintln(w, "    case \"$prev\" in")
+	fmt.Fprintln(w, "        -f)")
+	fmt.Fprintln(w, "            COMPREPLY=($(compgen -o plusdirs -f -X '!*.ahoy' -- \"$cur\"))")
+	fmt.Fprintln(w, "            return ;;")
+	for _, c := range commands {
+		if choices := c.choices(); choices != nil {
+			fmt.Fprintf(w, "        %s)\n", c.name)
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(choices, " "))
+			fmt.Fprintln(w, "            return ;;")
+		}
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "    case \"${COMP_WORDS[1]}\" in")
+	for _, c := range commands {
+		if c.flags != nil {
+			fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", c.name, flagNames(flagsOf(c.flags)))
+		} else {
+			fmt.Fprintf(w, "        %s) return ;;\n", c.name)
+		}
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "    if [ \"$COMP_CWORD\" -eq 1 ]; then")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s %s\" -- \"$cur\"))\n", strings.Join(names, " "), flagNames(flagsOf(nil)))
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", flagNames(flagsOf(nil)))
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o filenames -F _ahoy ahoy")
+}
+
+// writeZshCompletion writes a script to save as _ahoy on $fpath or to
+// source from .zshrc
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef ahoy")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_ahoy() {")
+	fmt.Fprintln(w, "    local -a commands")
+	fmt.Fprintln(w, "    commands=(")
+	for _, c := range commands {
+		fmt.Fprintf(w, "        '%s:%s'\n", c.name, zshEscape(c.summary))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "    if (( CURRENT == 2 )) && [[ $PREFIX != -* ]]; then")
+	fmt.Fprintln(w, "        _describe 'command' commands")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "    case $words[2] in")
+	for _, c := range commands {
+		switch choices := c.choices(); {
+		case choices != nil:
+			fmt.Fprintf(w, "        %s) (( CURRENT == 3 )) && _values '%s' %s; return ;;\n", c.name, c.name, strings.Join(choices, " "))
+		case c.flags != nil:
+			fmt.Fprintf(w, "        %s)\n", c.name)
+			writeZshArguments(w, flagsOf(c.flags), "            ")
+			fmt.Fprintln(w, "            return ;;")
+		default:
+			fmt.Fprintf(w, "        %s) return ;;\n", c.name)
+		}
+	}
+	fmt.Fprintln(w, "    esac")
+	writeZshArguments(w, flagsOf(nil), "    ")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "if [[ $zsh_eval_context[-1] == loadautofunc ]]; then")
+	fmt.Fprintln(w, "    _ahoy \"$@\"")
+	fmt.Fprintln(w, "else")
+	fmt.Fprintln(w, "    compdef _ahoy ahoy")
+	fmt.Fprintln(w, "fi")
+}
+
+// writeZshArguments writes an _arguments call completing flags
+func writeZshArguments(w io.Writer, flags []*flag.Flag, indent string) {
+	fmt.Fprintf(w, "%s_arguments", indent)
+	for _, f := range flags {
+		name, usage := flag.UnquoteUsage(f)
+		spec := fmt.Sprintf("-%s[%s]", f.Name, zshEscape(usage))
+		if f.Name == "f" {
+			spec += fmt.Sprintf(":%s:_files -g \"*.ahoy\"", name)
+		} else if takesValue(f) {
+			spec += fmt.Sprintf(":%s:", name)
+		}
+		fmt.Fprintf(w, " \\\n%s    '%s'", indent, spec)
+	}
+	fmt.Fprintln(w)
+}
+
+// zshEscape makes text safe inside a single-quoted _arguments spec
+func zshEscape(text string) string {
+	return strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]").Replace(text)
+}
+
+// writeFishCompletion writes a script to save as ahoy.fish in fish's
+// completions directory
+func writeFishCompletion(w io.Writer) {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+
+	fmt.Fprintln(w, "# fish completion for ahoy")
+	fmt.Fprintln(w, "complete -c ahoy -f")
+	for _, c := range commands {
+		fmt.Fprintf(w, "complete -c ahoy -n 'not __fish_seen_subcommand_from %s' -a %s -d %s\n",
+			strings.Join(names, " "), c.name, fishQuote(c.summary))
+		condition := "-n '__fish_seen_subcommand_from " + c.name + "' "
+		if choices := c.choices(); choices != nil {
+			fmt.Fprintf(w, "complete -c ahoy %s-a '%s'\n", condition, strings.Join(choices, " "))
+		}
+		if c.flags != nil {
+			writeFishFlags(w, flagsOf(c.flags), condition)
+		}
+	}
+	writeFishFlags(w, flagsOf(nil), "")
+}
+
+// writeFishFlags writes a complete line for each flag, under a condition
+// when one is given
+func writeFishFlags(w io.Writer, flags []*flag.Flag, condition string) {
+	for _, f := range flags {
+		_, usage := flag.UnquoteUsage(f)
+		switch {
+		case f.Name == "f":
+			fmt.Fprintf(w, "complete -c ahoy %s-o f -r -a '(__fish_complete_suffix .ahoy)' -d %s\n", condition, fishQuote(usage))
+		case takesValue(f):
+			fmt.Fprintf(w, "complete -c ahoy %s-o %s -r -d %s\n", condition, f.Name, fishQuote(usage))
+		default:
+			fmt.Fprintf(w, "complete -c ahoy %s-o %s -d %s\n", condition, f.Name, fishQuote(usage))
+		}
+	}
+}
+
+// fishQuote single-quotes text for fish
+func fishQuote(text string) string {
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(text) + "'"
+}
+
+func runMan(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy man")
+		return 1
+	}
+	writeManPage(os.Stdout)
+	return 0
+}
+
+// writeManPage writes the ahoy(1) man page in roff
+func writeManPage(w io.Writer) {
+	fmt.Fprintln(w, ".TH AHOY 1")
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintln(w, "ahoy \\- compiler for the Ahoy programming language")
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintln(w, ".B ahoy")
+	fmt.Fprintln(w, "\\fB\\-f\\fR \\fIfile\\fR [\\fIoptions\\fR]")
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, ".B ahoy")
+	fmt.Fprintln(w, "\\fIcommand\\fR [\\fIargs\\fR]")
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, ".B ahoy")
+	fmt.Fprintln(w, "compiles an Ahoy program to C and, with \\fB\\-r\\fR, builds it with gcc and runs it.")
+	fmt.Fprintln(w, "The C file and executable are written to the \\fIoutput\\fR directory.")
+	fmt.Fprintln(w, ".SH OPTIONS")
+	writeManFlags(w, flagsOf(nil))
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, c := range commands {
+		fmt.Fprintln(w, ".TP")
+		if c.args != "" {
+			fmt.Fprintf(w, "\\fB%s\\fR \\fI%s\\fR\n", c.name, manEscape(c.args))
+		} else {
+			fmt.Fprintf(w, "\\fB%s\\fR\n", c.name)
+		}
+		fmt.Fprintln(w, manEscape(c.summary))
+		if c.flags != nil {
+			fmt.Fprintln(w, ".RS")
+			writeManFlags(w, flagsOf(c.flags))
+			fmt.Fprintln(w, ".RE")
+		}
+	}
+	fmt.Fprintln(w, ".SH ENVIRONMENT")
+	fmt.Fprintln(w, ".TP")
+	fmt.Fprintln(w, ".B AHOY_LOG_LEVEL")
+	fmt.Fprintln(w, "Quietest level of log.debug, log.info, log.warn and log.error shown by compiled programs, or \\fIoff\\fR.")
+	fmt.Fprintln(w, ".SH EXIT STATUS")
+	fmt.Fprintln(w, "0 on success and 1 when the source has errors.")
+	fmt.Fprintln(w, "With \\fB\\-r\\fR, a program that fails passes its own exit status on.")
+	fmt.Fprintln(w, ".SH EXAMPLES")
+	fmt.Fprintln(w, ".nf")
+	fmt.Fprintln(w, "ahoy \\-f main.ahoy \\-r")
+	fmt.Fprintln(w, "ahoy completion bash > /etc/bash_completion.d/ahoy")
+	fmt.Fprintln(w, "ahoy man > /usr/local/share/man/man1/ahoy.1")
+	fmt.Fprintln(w, ".fi")
+}
+
+// writeManFlags writes a tagged paragraph for each flag
+func writeManFlags(w io.Writer, flags []*flag.Flag) {
+	for _, f := range flags {
+		name, usage := flag.UnquoteUsage(f)
+		fmt.Fprintln(w, ".TP")
+		if name != "" {
+			fmt.Fprintf(w, "\\fB\\-%s\\fR \\fI%s\\fR\n", manEscape(f.Name), name)
+		} else {
+			fmt.Fprintf(w, "\\fB\\-%s\\fR\n", manEscape(f.Name))
+		}
+		fmt.Fprintln(w, manEscape(usage))
+	}
+}
+
+// manEscape keeps text from being read as roff requests or escapes
+func manEscape(text string) string {
+	text = strings.NewReplacer("\\", "\\e", "-", "\\-").Replace(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = "\\&" + text
+	}
+	return text
+}
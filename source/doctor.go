@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// doctorCheck is one thing ahoy doctor looks at. Programs can't be built
+// without the required ones; the rest are needed by some features.
+type doctorCheck struct {
+	name     string
+	required bool
+	run      func() (found bool, detail string, hint string)
+}
+
+var doctorChecks = []doctorCheck{
+	{"C compiler", true, checkCCompiler},
+	{"output directory", true, checkOutputDir},
+	{"pkg-config", false, checkPkgConfig},
+	{"raylib", false, checkRaylib},
+	{"ahoy-lsp", false, checkLSP},
+	{"emscripten", false, checkEmscripten},
+}
+
+func runDoctor(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy doctor")
+		return 1
+	}
+
+	failed := 0
+	for _, check := range doctorChecks {
+		found, detail, hint := check.run()
+		mark := "✓"
+		if !found {
+			mark = "!"
+			if check.required {
+				mark = "✗"
+				failed++
+			}
+		}
+		fmt.Printf("%s %-17s %s\n", mark, check.name, detail)
+		if !found && hint != "" {
+			fmt.Printf("  %s\n", hint)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d required check(s) failed; programs can't be built until they're fixed\n", failed)
+		return 1
+	}
+	fmt.Println("\nReady to build Ahoy programs")
+	return 0
+}
+
+// commandVersion returns the first line a tool prints for --version
+func commandVersion(name string) (string, bool) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", false
+	}
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return path, true
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return version, true
+}
+
+func checkCCompiler() (bool, string, string) {
+	var found []string
+	for _, compiler := range []string{"gcc", "clang"} {
+		if version, ok := commandVersion(compiler); ok {
+			found = append(found, version)
+		}
+	}
+	if len(found) == 0 {
+		return false, "gcc and clang not found", "Install gcc: sudo apt install gcc (Debian/Ubuntu), xcode-select --install (macOS) or sudo dnf install gcc (Fedora)"
+	}
+	if _, err := exec.LookPath("gcc"); err != nil {
+		return false, found[0], "ahoy -r compiles with gcc; install it or link clang as gcc"
+	}
+	return true, strings.Join(found, "; "), ""
+}
+
+func checkOutputDir() (bool, string, string) {
+	dir := "output"
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		// It is created on the first build, so its parent must be writable
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, ".ahoy-doctor-*")
+	if err != nil {
+		absDir, _ := filepath.Abs(dir)
+		return false, absDir + " is not writable", "Run ahoy from a directory you can write to, or fix its permissions"
+	}
+	file.Close()
+	os.Remove(file.Name())
+	absDir, _ := filepath.Abs("output")
+	return true, absDir, ""
+}
+
+func checkPkgConfig() (bool, string, string) {
+	version, ok := commandVersion("pkg-config")
+	if !ok {
+		return false, "not found", "Install pkg-config so raylib and other C libraries can be located"
+	}
+	return true, version, ""
+}
+
+func checkRaylib() (bool, string, string) {
+	if output, err := exec.Command("pkg-config", "--cflags", "raylib").Output(); err == nil {
+		version, _ := exec.Command("pkg-config", "--modversion", "raylib").Output()
+		detail := strings.TrimSpace(string(version))
+		if cflags := strings.TrimSpace(string(output)); cflags != "" {
+			detail += " (" + cflags + ")"
+		}
+		return true, detail, ""
+	}
+	for _, dir := range []string{"/usr/include", "/usr/local/include", "/opt/homebrew/include"} {
+		header := filepath.Join(dir, "raylib.h")
+		if _, err := os.Stat(header); err == nil {
+			return true, header, ""
+		}
+	}
+	return false, "raylib.h not found", "Install raylib (https://www.raylib.com) for games, or import raylib.h by its full path"
+}
+
+func checkLSP() (bool, string, string) {
+	path, err := exec.LookPath("ahoy-lsp")
+	if err != nil {
+		return false, "not in PATH", "Run ./install_lsp.sh for editor diagnostics and completion"
+	}
+	return true, path, ""
+}
+
+func checkEmscripten() (bool, string, string) {
+	version, ok := commandVersion("emcc")
+	if !ok {
+		return false, "emcc not found", "Install the Emscripten SDK (https://emscripten.org) to build for the web"
+	}
+	return true, version, ""
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"ahoy"
+)
+
+var emitFlags = flag.NewFlagSet("emit", flag.ExitOnError)
+
+var (
+	emitFileFlag     = emitFlags.String("f", "", "Input .ahoy source `file`")
+	emitFunctionFlag = emitFlags.String("fn", "", "The `function` to print the generated C of")
+)
+
+// runEmit prints the C one function of a program becomes, with the runtime
+// helpers it calls
+func runEmit(args []string) int {
+	emitFlags.Parse(args)
+	if *emitFileFlag == "" || *emitFunctionFlag == "" {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy emit -f file -fn function")
+		return 1
+	}
+
+	code, diagnostics, err := ahoy.EmitFunction(ahoy.BuildOptions{Source: *emitFileFlag}, *emitFunctionFlag)
+	if err != nil {
+		if err == ahoy.ErrCodeGeneration {
+			for _, diagnostic := range diagnostics {
+				fmt.Fprintf(os.Stderr, "  Line %d: %s\n", diagnostic.Line, diagnostic.Message)
+			}
+			fmt.Fprintln(os.Stderr, "✗ Code generation failed due to errors")
+		} else {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		}
+		return 1
+	}
+	fmt.Print(code)
+	return 0
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var fuzzFlags = flag.NewFlagSet("fuzz", flag.ExitOnError)
+
+var (
+	fuzzTimeFlag = fuzzFlags.String("time", "30s", "How long to fuzz each target, as a `duration` or a count like 1000x")
+	fuzzDirFlag  = fuzzFlags.String("dir", "", "The ahoy module `directory`; found from the working directory when empty")
+)
+
+// fuzzTargets maps the names ahoy fuzz takes to the fuzz tests of the ahoy package
+var fuzzTargets = map[string]string{
+	"tokenize": "FuzzTokenize",
+	"parse":    "FuzzParse",
+	"lint":     "FuzzParseLint",
+}
+
+// runFuzz runs the parser fuzz targets with go test -fuzz, one after the
+// other. It's a development command: it needs Go and the compiler source.
+func runFuzz(args []string) int {
+	fuzzFlags.Parse(args)
+	targets := fuzzFlags.Args()
+	if len(targets) == 0 {
+		targets = []string{"tokenize", "parse", "lint"}
+	}
+	for _, target := range targets {
+		if _, ok := fuzzTargets[target]; !ok {
+			fmt.Fprintln(os.Stderr, "Usage: ahoy fuzz [-time duration] [-dir dir] [tokenize|parse|lint ...]")
+			return 1
+		}
+	}
+
+	if _, err := exec.LookPath("go"); err != nil {
+		fmt.Println("Error go isn't on PATH; ahoy fuzz needs the Go toolchain")
+		return 1
+	}
+	dir := *fuzzDirFlag
+	if dir == "" {
+		workDir, _ := os.Getwd()
+		dir = findAhoyModule(workDir)
+		if dir == "" {
+			fmt.Println("Error couldn't find the ahoy module; run from the compiler source or pass -dir")
+			return 1
+		}
+	}
+
+	for _, target := range targets {
+		name := fuzzTargets[target]
+		fmt.Printf("Fuzzing %s for %s\n", name, *fuzzTimeFlag)
+		cmd := exec.Command("go", "test", "-run", "^$", "-fuzz", "^"+name+"$", "-fuzztime", *fuzzTimeFlag, ".")
+		cmd.Dir = dir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			// go test saves the failing input under testdata/fuzz
+			fmt.Printf("✗ %s found a failing input; rerun it with go test -run %s in %s\n", name, name, dir)
+			return 1
+		}
+	}
+	return 0
+}
+
+// findAhoyModule returns the nearest directory at or above dir whose go.mod
+// declares the ahoy module, or "" when there is none
+func findAhoyModule(dir string) string {
+	for {
+		if content, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			firstLine, _, _ := strings.Cut(string(content), "\n")
+			if strings.TrimSpace(firstLine) == "module ahoy" {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
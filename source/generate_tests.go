@@ -2,9 +2,6 @@
 // +build ignore
 
 // This file is meant to be run with `go run generate_tests.go <ahoy_file>`.
-// When run this way, Go compiles all .go files in the package together,
-// making formatSource and generateC available from formatter.go and codegen.go.
-// The gopls errors about undefined functions can be ignored.
 
 package main
 
@@ -28,12 +25,12 @@ func captureOutput(ahoyFile string) (string, error) {
 	}
 
 	// Format, tokenize, and parse
-	formattedContent := formatSource(string(content))
+	formattedContent := ahoy.Format(string(content))
 	tokens := ahoy.Tokenize(formattedContent)
 	ast := ahoy.Parse(tokens)
 
 	// Generate C code
-	cCode := generateC(ast)
+	cCode := ahoy.GenerateCWithFilename(ast, ahoyFile)
 
 	// Determine output paths
 	baseName := strings.TrimSuffix(filepath.Base(ahoyFile), ".ahoy")
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"ahoy"
+)
+
+var graphFlags = flag.NewFlagSet("graph", flag.ExitOnError)
+
+var (
+	graphFileFlag  = graphFlags.String("f", "", "Input .ahoy source `file`")
+	graphDotFlag   = graphFlags.Bool("dot", false, "Print Graphviz DOT instead of a tree")
+	graphCallsFlag = graphFlags.Bool("calls", false, "Print which functions call which instead of the imports")
+	graphLibFlag   = graphFlags.Bool("lib", false, "With -calls, start from the @export functions rather than main")
+)
+
+func runGraph(args []string) int {
+	graphFlags.Parse(args)
+	if *graphFileFlag == "" {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy graph -f file [-calls [-lib]] [-dot]")
+		return 1
+	}
+
+	if *graphCallsFlag {
+		graph, err := ahoy.LoadCallGraph(*graphFileFlag, *graphLibFlag)
+		if err != nil {
+			fmt.Printf("Error %v\n", err)
+			return 1
+		}
+		if *graphDotFlag {
+			graph.WriteDOT(os.Stdout)
+		} else {
+			graph.WriteTree(os.Stdout)
+		}
+		return 0
+	}
+
+	graph, err := ahoy.LoadImportGraph(*graphFileFlag)
+	if err != nil {
+		fmt.Printf("Error %v\n", err)
+		return 1
+	}
+	if *graphDotFlag {
+		graph.WriteDOT(os.Stdout)
+	} else {
+		graph.WriteTree(os.Stdout)
+	}
+	return 0
+}
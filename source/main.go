@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -13,11 +14,68 @@ import (
 
 func main() {
 	// Define CLI flags
-	fileFlag := flag.String("f", "", "Input .ahoy source file")
+	fileFlag := flag.String("f", "", "Input .ahoy source `file`")
 	runFlag := flag.Bool("r", false, "Run the compiled C program after compilation")
 	formatFlag := flag.Bool("format", false, "Format the source file")
 	lintFlag := flag.Bool("lint", false, "Run linter to check for errors without compiling")
+	fixFlag := flag.Bool("fix", false, "With -lint, apply automatic fixes to the source file")
+	releaseFlag := flag.Bool("release", false, "Release build: strip log.debug calls")
+	softAssertFlag := flag.Bool("soft-assert", false, "Report failed asserts and keep running, exiting with status 1")
+	safeFlag := flag.Bool("safe", false, "Stop with an error when a loop's array or dict is modified inside the loop")
+	growArraysFlag := flag.Bool("grow-arrays", false, "Grow an array written past its end, filling the gap with zeros, instead of stopping")
+	strictCallsFlag := flag.Bool("strict-calls", false, "Report calls to unknown functions instead of guessing a PascalCase C name")
+	deterministicFlag := flag.Bool("deterministic", false, "Seed random numbers with AHOY_SEED, run time on a virtual clock and record input to AHOY_RECORD or replay it from AHOY_REPLAY")
+	optimizeFlag := flag.Bool("O", false, "Optimize the generated C, turning enum switches of constants into table lookups")
+	readableCFlag := flag.Bool("readable-c", false, "Comment the generated C with each statement's Ahoy line and split long lines")
+	cWidthFlag := flag.Int("c-width", ahoy.DefaultCLineWidth, "With -readable-c, split generated C lines longer than `n` characters")
+	splitRuntimeFlag := flag.Bool("split-runtime", false, "Write the runtime helpers the program uses to ahoy_runtime.c and ahoy_runtime.h")
+	libFlag := flag.Bool("lib", false, "Build a shared library of the program's @export functions, with a C header for them")
+	bindingsFlag := flag.String("bindings", "", "With -lib, also write bindings for `langs`: go, python or both, comma-separated")
+	minimalRuntimeFlag := flag.Bool("minimal-runtime", false, "Include only the C headers and runtime types the program uses, without the crash handler")
+	cacheRuntimeFlag := flag.Bool("cache-runtime", false, "Split out the runtime helpers and reuse their compiled object between builds")
+	werrorCFlag := flag.Bool("werror-c", false, "Fail the build on any gcc warning for the generated C (-Wall -Wextra and strict prototypes)")
+	reportFlag := flag.Bool("report", false, "Write build-report.json with sizes, helpers and timings to the output directory")
+	tokensJSONFlag := flag.Bool("tokens-json", false, "Print the file's classified tokens as JSON, for debugging editor highlighting")
+	dumpTokensFlag := flag.Bool("dump-tokens", false, "Print the tokens the file is split into as JSON, with their spans")
+	dumpASTFlag := flag.String("dump-ast", "", "Print the file's parsed tree with spans, as `json` or sexp")
+	bannerFlag := flag.Bool("banner", false, "Start the generated C with a comment naming the Ahoy version and source, and a provenance line")
+	bannerTimeFlag := flag.Bool("banner-time", false, "With -banner, also record when the C was generated")
+	licenseFlag := flag.String("license", "", "Start the generated C with the text of `file`, such as its license; implies -banner")
+	pruneFlag := flag.Bool("prune", false, "Leave out the functions nothing reachable from main, or from the exports with -lib, uses")
 	helpFlag := flag.Bool("h", false, "Show help")
+	buildSettings := map[string]string{}
+	flag.Func("set", "Set `name=value` for the program to read as build.name: version, commit or date", func(setting string) error {
+		name, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return fmt.Errorf("expected name=value, got %q", setting)
+		}
+		buildSettings[name] = value
+		return nil
+	})
+
+	// Subcommands come before any flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "completion":
+			os.Exit(runCompletion(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "emit":
+			os.Exit(runEmit(os.Args[2:]))
+		case "fuzz":
+			os.Exit(runFuzz(os.Args[2:]))
+		case "graph":
+			os.Exit(runGraph(os.Args[2:]))
+		case "man":
+			os.Exit(runMan(os.Args[2:]))
+		case "rename":
+			os.Exit(runRename(os.Args[2:]))
+		case "run":
+			os.Exit(runRun(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		}
+	}
 
 	flag.Parse()
 
@@ -41,9 +99,41 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Classify the file as written, since editors highlight that text
+	if *tokensJSONFlag {
+		ast, _ := ahoy.ParseLintWithPath(ahoy.Tokenize(string(content)), sourceFile)
+		output, _ := json.MarshalIndent(ahoy.SemanticTokens(ast, string(content)), "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	// Dump the tokens or tree of the file as written, since that's what
+	// the build parses
+	if *dumpTokensFlag {
+		fmt.Print(ahoy.DumpTokens(ahoy.Tokenize(string(content))))
+		return
+	}
+	if *dumpASTFlag != "" {
+		ast, errors := ahoy.ParseLintWithPath(ahoy.Tokenize(string(content)), sourceFile)
+		dump, err := ahoy.DumpAST(ast, *dumpASTFlag)
+		if err != nil {
+			fmt.Printf("Error %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(dump)
+		// The tree of a file with syntax errors leaves out their statements
+		for _, err := range errors {
+			fmt.Fprintf(os.Stderr, "Line %d, Column %d: %s\n", err.Line, err.Column, err.Message)
+		}
+		if len(errors) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Format if requested
 	if *formatFlag {
-		formatted := formatSource(string(content))
+		formatted := ahoy.Format(string(content))
 		err = os.WriteFile(sourceFile, []byte(formatted), 0644)
 		if err != nil {
 			fmt.Printf("Error writing formatted file: %v\n", err)
@@ -53,16 +143,13 @@ func main() {
 		return
 	}
 
-	// Format source before compiling (tabs to spaces, etc)
-	formattedContent := formatSource(string(content))
-
-	// Tokenize
-	tokens := ahoy.Tokenize(formattedContent)
-
-	// Lint mode
+	// Lint mode checks the file as written, since the formatted copy
+	// reflows lines and respaces ahoy:ignore comments
 	if *lintFlag {
+		original := string(content)
+
 		// Parse the code to check for C imports
-		ast, errors := ahoy.ParseLintWithPath(tokens, sourceFile)
+		ast, errors := ahoy.ParseLintWithPath(ahoy.Tokenize(original), sourceFile)
 
 		// Check syntax errors
 		if len(errors) > 0 {
@@ -73,6 +160,75 @@ func main() {
 			os.Exit(1)
 		}
 
+		// Run lint rules with the nearest .ahoylint, if any
+		config := ahoy.DefaultLintConfig()
+		if absPath, err := filepath.Abs(sourceFile); err == nil {
+			if configPath := ahoy.FindLintConfig(filepath.Dir(absPath)); configPath != "" {
+				config, err = ahoy.LoadLintConfig(configPath)
+				if err != nil {
+					fmt.Printf("Error reading lint config: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		findings := ahoy.Lint(ast, original, config)
+
+		// Fix what can be fixed, then lint what remains
+		if *fixFlag {
+			fixedContent, fixed := ahoy.ApplyLintEdits(original, findings)
+			if fixed > 0 {
+				if err := os.WriteFile(sourceFile, []byte(fixedContent), 0644); err != nil {
+					fmt.Printf("Error writing fixed file: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Fixed %d issue(s) in %s\n", fixed, sourceFile)
+
+				ast, errors = ahoy.ParseLintWithPath(ahoy.Tokenize(fixedContent), sourceFile)
+				if len(errors) > 0 {
+					fmt.Printf("Found %d syntax error(s) in %s after fixing:\n", len(errors), sourceFile)
+					for _, err := range errors {
+						fmt.Printf("  Line %d, Column %d: %s\n", err.Line, err.Column, err.Message)
+					}
+					os.Exit(1)
+				}
+				findings = ahoy.Lint(ast, fixedContent, config)
+			}
+		}
+
+		var lintErrors, warnings []ahoy.LintWarning
+		for _, finding := range findings {
+			if finding.Severity == ahoy.SeverityError {
+				lintErrors = append(lintErrors, finding)
+			} else {
+				warnings = append(warnings, finding)
+			}
+		}
+
+		// Warnings don't fail the lint
+		if len(warnings) > 0 {
+			fmt.Printf("Found %d warning(s) in %s:\n", len(warnings), sourceFile)
+			for _, warning := range warnings {
+				fmt.Printf("  Line %d: %s [%s]\n", warning.Line, warning.Message, warning.Rule)
+			}
+		}
+		fixable := 0
+		for _, finding := range findings {
+			if len(finding.Edits) > 0 {
+				fixable++
+			}
+		}
+		if fixable > 0 && !*fixFlag {
+			fmt.Printf("  %d issue(s) can be fixed automatically with -lint -fix\n", fixable)
+		}
+		if len(lintErrors) > 0 {
+			fmt.Printf("Found %d lint error(s) in %s:\n", len(lintErrors), sourceFile)
+			for _, lintError := range lintErrors {
+				fmt.Printf("  Line %d: %s [%s]\n", lintError.Line, lintError.Message, lintError.Rule)
+			}
+			os.Exit(1)
+		}
+
 		// Check if file has C header imports
 		hasCImports := false
 		if ast != nil {
@@ -102,281 +258,86 @@ func main() {
 		return
 	}
 
-	// Get absolute path for source file
-	absPath, err := filepath.Abs(sourceFile)
-	if err != nil {
-		fmt.Printf("Error resolving file path: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Initialize package manager
-	pm := NewPackageManager(filepath.Dir(absPath))
-
-	// Load the package
-	pkg, err := pm.LoadPackageFromFile(absPath)
-	if err != nil {
-		fmt.Printf("Error loading package: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Resolve imports recursively
-	imports, err := resolveImports(pkg, pm, absPath)
-	if err != nil {
-		fmt.Printf("Error resolving imports: %v\n", err)
-		os.Exit(1)
+	runtimeCache := ""
+	if *cacheRuntimeFlag {
+		runtimeCache = ahoy.DefaultRuntimeCache()
 	}
-
-	// Merge package with all imports into one AST
-	ast := MergeWithImports(pkg, imports)
-
-	// Generate C code with source filename for better error messages
-	cCode := generateC(ast, sourceFile)
-
-	// Check if code generation failed
-	if cCode == "" {
-		fmt.Println("✗ Code generation failed due to errors")
-		os.Exit(1)
+	var bindings []string
+	if *bindingsFlag != "" {
+		bindings = strings.Split(*bindingsFlag, ",")
 	}
-
-	// Determine output file name
-	baseName := filepath.Base(sourceFile)
-	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
-
-	// Determine output directory based on source file location
-	outputDir := "output"
-	sourceDir := filepath.Dir(sourceFile)
-	if strings.Contains(sourceDir, "test/input") || strings.Contains(sourceDir, "test\\input") {
-		// If source is in test/input, output to test/output
-		outputDir = filepath.Join(filepath.Dir(filepath.Dir(sourceDir)), "test", "output")
+	license := ""
+	if *licenseFlag != "" {
+		text, err := os.ReadFile(*licenseFlag)
+		if err != nil {
+			fmt.Printf("Error reading license: %v\n", err)
+			os.Exit(1)
+		}
+		license = string(text)
 	}
-
-	outputFile := filepath.Join(outputDir, baseName+".c")
-	executable := filepath.Join(outputDir, baseName)
-
-	// Create output directory if it doesn't exist
-	os.MkdirAll(outputDir, 0755)
-
-	// Write C file
-	err = os.WriteFile(outputFile, []byte(cCode), 0644)
+	artifacts, _, err := ahoy.Build(ahoy.BuildOptions{
+		Source:         sourceFile,
+		Compile:        *runFlag || *libFlag,
+		SoftAssert:     *softAssertFlag,
+		Release:        *releaseFlag,
+		Safe:           *safeFlag,
+		GrowArrays:     *growArraysFlag,
+		StrictCalls:    *strictCallsFlag,
+		Deterministic:  *deterministicFlag,
+		Optimize:       *optimizeFlag,
+		ReadableC:      *readableCFlag,
+		CLineWidth:     *cWidthFlag,
+		SplitRuntime:   *splitRuntimeFlag,
+		MinimalRuntime: *minimalRuntimeFlag,
+		Library:        *libFlag,
+		Bindings:       bindings,
+		RuntimeCache:   runtimeCache,
+		WerrorC:        *werrorCFlag,
+		Report:         *reportFlag,
+		Set:            buildSettings,
+		Banner:         *bannerFlag,
+		BannerTime:     *bannerTimeFlag,
+		License:        license,
+		Prune:          *pruneFlag,
+		Log:            os.Stdout,
+	})
 	if err != nil {
-		fmt.Printf("Error writing C file: %v\n", err)
+		if err == ahoy.ErrCodeGeneration {
+			fmt.Println("✗ Code generation failed due to errors")
+		} else {
+			fmt.Printf("Error %v\n", err)
+		}
 		os.Exit(1)
 	}
 
-	if len(pkg.Files) > 1 {
-		fmt.Printf("✓ Compiled package '%s' (%d files) to %s\n", pkg.Name, len(pkg.Files), outputFile)
-	} else {
-		fmt.Printf("✓ Compiled %s to %s\n", sourceFile, outputFile)
+	// A library has no program to run
+	if *libFlag {
+		fmt.Printf("✓ Wrote %s\n", artifacts.Header)
+		for _, binding := range artifacts.Bindings {
+			fmt.Printf("✓ Wrote %s\n", binding)
+		}
+		return
 	}
 
-	// Compile C code if run flag is set
+	// Run the compiled program if requested
 	if *runFlag {
-		fmt.Println("Compiling C code...")
-
-		// Build compilation arguments
-		compileArgs := []string{"-o", executable, outputFile}
-
-		// Check if raylib is imported
-		hasRaylib := false
-		raylibPath := ""
-		for _, file := range pkg.Files {
-			if file.AST != nil {
-				for _, child := range file.AST.Children {
-					if child.Type == ahoy.NODE_IMPORT_STATEMENT && strings.Contains(child.Value, "raylib.h") {
-						hasRaylib = true
-						raylibPath = filepath.Dir(child.Value)
-						break
-					}
-				}
-			}
-			if hasRaylib {
-				break
-			}
-		}
-
-		// Add raylib linking flags if needed
-		if hasRaylib {
-			if raylibPath != "" {
-				compileArgs = append(compileArgs, "-L"+raylibPath)
-			}
-			compileArgs = append(compileArgs, "-lraylib", "-lm", "-lpthread", "-ldl", "-lrt", "-lX11")
-		} else {
-			compileArgs = append(compileArgs, "-lm")
-		}
-
-		cmd := exec.Command("gcc", compileArgs...)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			fmt.Printf("Error compiling C code:\n%s\n", output)
-			os.Exit(1)
-		}
-
-		fmt.Printf("✓ Compiled C code to %s\n", executable)
 		fmt.Println("Running program:")
 		fmt.Println("==================")
 
-		// Run the executable
-		runCmd := exec.Command(executable)
+		runCmd := exec.Command(artifacts.Executable)
 		runCmd.Stdout = os.Stdout
 		runCmd.Stderr = os.Stderr
 		err = runCmd.Run()
 		fmt.Println("==================")
 		if err != nil {
 			fmt.Printf("Program exited with error: %v\n", err)
-			os.Exit(1)
-		}
-	}
-}
-
-// resolveImports recursively resolves all imports in a package
-// and merges them into a unified set of imports
-func resolveImports(pkg *Package, pm *PackageManager, fromFile string) (map[string]*Package, error) {
-	allImports := make(map[string]*Package)
-
-	for _, file := range pkg.Files {
-		if file.AST != nil {
-			for _, child := range file.AST.Children {
-				if child.Type == ahoy.NODE_IMPORT_STATEMENT {
-					importPath := child.Value
-					importedPkg, err := pm.ResolveImport(importPath, fromFile)
-					if err != nil {
-						return nil, fmt.Errorf("failed to resolve import '%s': %v", importPath, err)
-					}
-
-					// Store with namespace key
-					namespace := child.DataType
-					if namespace == "" {
-						namespace = importedPkg.Name
-					}
-					allImports[namespace] = importedPkg
-
-					// Recursively resolve imports in the imported package
-					nestedImports, err := resolveImports(importedPkg, pm, file.Path)
-					if err != nil {
-						return nil, err
-					}
-
-					// Merge nested imports
-					for ns, nestedPkg := range nestedImports {
-						if _, exists := allImports[ns]; !exists {
-							allImports[ns] = nestedPkg
-						}
-					}
-				}
-			}
-		}
-	}
-	return allImports, nil
-}
-
-// MergeWithImports merges the package with all imported packages into a single AST
-func MergeWithImports(pkg *Package, imports map[string]*Package) *ahoy.ASTNode {
-	merged := &ahoy.ASTNode{Type: ahoy.NODE_PROGRAM}
-	processedFunctions := make(map[string]bool) // Deduplicate functions
-	processedStructs := make(map[string]bool)   // Deduplicate structs
-	processedEnums := make(map[string]bool)     // Deduplicate enums
-
-	// First, add all declarations from imported packages
-	for _, importedPkg := range imports {
-		for _, file := range importedPkg.Files {
-			if file.AST != nil {
-				for _, child := range file.AST.Children {
-					// Skip program declarations and imports
-					if child.Type == ahoy.NODE_PROGRAM_DECLARATION {
-						continue
-					}
-
-					// Keep C header imports (.h files), skip .ahoy imports
-					if child.Type == ahoy.NODE_IMPORT_STATEMENT {
-						if strings.HasSuffix(child.Value, ".h") {
-							// Keep C header imports for codegen
-							merged.Children = append(merged.Children, child)
-						}
-						continue
-					}
-
-					// Deduplicate by name
-					name := child.Value
-					shouldAdd := false
-
-					switch child.Type {
-					case ahoy.NODE_FUNCTION:
-						if !processedFunctions[name] {
-							processedFunctions[name] = true
-							shouldAdd = true
-						}
-					case ahoy.NODE_STRUCT_DECLARATION:
-						if !processedStructs[name] {
-							processedStructs[name] = true
-							shouldAdd = true
-						}
-					case ahoy.NODE_ENUM_DECLARATION:
-						if !processedEnums[name] {
-							processedEnums[name] = true
-							shouldAdd = true
-						}
-					default:
-						shouldAdd = true
-					}
-
-					if shouldAdd {
-						merged.Children = append(merged.Children, child)
-					}
-				}
-			}
-		}
-	}
-
-	// Then add declarations from the main package
-	for _, file := range pkg.Files {
-		if file.AST != nil {
-			for _, child := range file.AST.Children {
-				// Skip program declarations
-				if child.Type == ahoy.NODE_PROGRAM_DECLARATION {
-					continue
-				}
-
-				// Keep C header imports (.h files), skip .ahoy imports
-				if child.Type == ahoy.NODE_IMPORT_STATEMENT {
-					if strings.HasSuffix(child.Value, ".h") {
-						// Keep C header imports for codegen
-						merged.Children = append(merged.Children, child)
-					}
-					continue
-				}
-
-				// Deduplicate by name
-				name := child.Value
-				shouldAdd := false
-
-				switch child.Type {
-				case ahoy.NODE_FUNCTION:
-					if !processedFunctions[name] {
-						processedFunctions[name] = true
-						shouldAdd = true
-					}
-				case ahoy.NODE_STRUCT_DECLARATION:
-					if !processedStructs[name] {
-						processedStructs[name] = true
-						shouldAdd = true
-					}
-				case ahoy.NODE_ENUM_DECLARATION:
-					if !processedEnums[name] {
-						processedEnums[name] = true
-						shouldAdd = true
-					}
-				default:
-					shouldAdd = true
-				}
-
-				if shouldAdd {
-					merged.Children = append(merged.Children, child)
-				}
+			// Pass the program's own exit status on to the shell
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+				os.Exit(exitErr.ExitCode())
 			}
+			os.Exit(1)
 		}
 	}
-
-	return merged
 }
 
 func showHelp() {
@@ -391,11 +352,42 @@ func showHelp() {
 	fmt.Println("  -r            Run the compiled C program")
 	fmt.Println("  -format       Format the source file")
 	fmt.Println("  -lint         Check for syntax errors without compiling")
+	fmt.Println("  -fix          With -lint, apply automatic fixes to the file")
+	fmt.Println("  -soft-assert  Report failed asserts and keep running")
+	fmt.Println("  -release      Strip log.debug calls from the program")
+	fmt.Println("  -safe         Stop when a loop's array or dict is modified inside it")
+	fmt.Println("  -grow-arrays  Grow an array written past its end instead of stopping")
+	fmt.Println("  -strict-calls Report calls to unknown functions instead of guessing C names")
+	fmt.Println("  -deterministic Seed random numbers, run a virtual clock and record or replay input")
+	fmt.Println("  -O            Optimize the generated C, like table lookups for enum switches and inlining")
+	fmt.Println("  -readable-c   Comment the C with each statement's Ahoy line and split long lines")
+	fmt.Println("  -c-width <n>  With -readable-c, split C lines longer than n (default 100)")
+	fmt.Println("  -split-runtime Write the runtime helpers to ahoy_runtime.c/.h beside the program")
+	fmt.Println("  -cache-runtime Like -split-runtime, compiling the runtime once for later builds")
+	fmt.Println("  -lib          Build a shared library of the @export functions, with a C header")
+	fmt.Println("  -bindings <l> With -lib, also write go and/or python bindings, like -bindings go,python")
+	fmt.Println("  -minimal-runtime Include only the headers and runtime the program uses")
+	fmt.Println("  -werror-c     Fail the build on any gcc warning for the generated C")
+	fmt.Println("  -report       Write build-report.json to the output directory")
+	fmt.Println("  -set <n=v>    Set build.version, build.commit or build.date for the program")
+	fmt.Println("  -banner       Start the C with the Ahoy version, source and a provenance line")
+	fmt.Println("  -banner-time  With -banner, also record when the C was generated")
+	fmt.Println("  -license <f>  Start the C with the text of file f, such as a license; implies -banner")
+	fmt.Println("  -prune        Leave out functions nothing reachable from main (or the exports) uses")
+	fmt.Println("  -tokens-json  Print the file's classified tokens as JSON")
+	fmt.Println("  -dump-tokens  Print the tokens the file is split into as JSON")
+	fmt.Println("  -dump-ast <f> Print the file's parsed tree with spans, as json or sexp")
 	fmt.Println("  -h            Show this help message")
 	fmt.Println()
+	fmt.Println("Commands:")
+	for _, c := range commands {
+		fmt.Printf("  %-30s %s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  go run main.go -f input/main.ahoy")
 	fmt.Println("  go run main.go -f input/main.ahoy -r")
 	fmt.Println("  go run main.go -f input/main.ahoy -format")
 	fmt.Println("  go run main.go -f input/main.ahoy -lint")
+	fmt.Println("  go run main.go completion bash > /etc/bash_completion.d/ahoy")
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ahoy"
+)
+
+// programTimeout is how long a program from the corpus may run before it
+// counts as failed
+const programTimeout = 10 * time.Second
+
+// maxDiffLines is how many changed lines a failing program shows
+const maxDiffLines = 20
+
+// runProgramTests builds and runs every program in dir and compares what it
+// prints and its exit status with the .txt file of the same name in
+// expectedDir. With record set, it writes those files instead. The status
+// is 1 when any program fails to build or differs.
+func runProgramTests(dir string, expectedDir string, record bool) int {
+	sources, err := filepath.Glob(filepath.Join(dir, "*.ahoy"))
+	if err != nil || len(sources) == 0 {
+		fmt.Fprintf(os.Stderr, "No .ahoy programs in %s\n", dir)
+		return 1
+	}
+	outputDir, err := os.MkdirTemp("", "ahoy-programs-")
+	if err != nil {
+		fmt.Printf("Error %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(outputDir)
+	if record {
+		if err := os.MkdirAll(expectedDir, 0755); err != nil {
+			fmt.Printf("Error %v\n", err)
+			return 1
+		}
+	}
+
+	passed, failed := 0, 0
+	for _, source := range sources {
+		name := strings.TrimSuffix(filepath.Base(source), ".ahoy")
+		expectedFile := filepath.Join(expectedDir, name+".txt")
+
+		got, problem := runProgram(source, filepath.Join(outputDir, name))
+		if problem != "" {
+			failed++
+			fmt.Printf("FAIL  %s: %s\n", name, problem)
+			continue
+		}
+		if record {
+			if err := os.WriteFile(expectedFile, []byte(got), 0644); err != nil {
+				failed++
+				fmt.Printf("FAIL  %s: %v\n", name, err)
+				continue
+			}
+			passed++
+			fmt.Printf("wrote %s\n", expectedFile)
+			continue
+		}
+
+		want, err := os.ReadFile(expectedFile)
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL  %s: no %s; -record writes it\n", name, expectedFile)
+			continue
+		}
+		if string(want) == got {
+			passed++
+			fmt.Printf("ok    %s\n", name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL  %s: output differs from %s\n", name, expectedFile)
+		for _, line := range diffLines(splitOutput(string(want)), splitOutput(got)) {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runProgram builds a program into outputDir and runs it, returning what
+// it printed to stdout followed, when it exits with another status than 0,
+// by an [exit status N] line. problem says why when it couldn't be built or
+// didn't finish.
+func runProgram(source string, outputDir string) (output string, problem string) {
+	artifacts, diagnostics, err := ahoy.Build(ahoy.BuildOptions{
+		Source:    source,
+		OutputDir: outputDir,
+		Compile:   true,
+	})
+	if err != nil {
+		if err == ahoy.ErrCodeGeneration && len(diagnostics) > 0 {
+			return "", fmt.Sprintf("line %d: %s", diagnostics[0].Line, diagnostics[0].Message)
+		}
+		return "", firstErrorLine(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), programTimeout)
+	defer cancel()
+	var stdout bytes.Buffer
+	runCmd := exec.CommandContext(ctx, artifacts.Executable)
+	runCmd.Stdout = &stdout
+	err = runCmd.Run()
+	if ctx.Err() != nil {
+		return "", fmt.Sprintf("still running after %s", programTimeout)
+	}
+	output = stdout.String()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		if output != "" && !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+		output += fmt.Sprintf("[exit status %d]\n", exitErr.ExitCode())
+	} else if err != nil {
+		return "", err.Error()
+	}
+	return output, ""
+}
+
+// firstErrorLine returns the first line of a build error, or the first line
+// of gcc's that says error when the build failed there
+func firstErrorLine(message string) string {
+	lines := strings.Split(message, "\n")
+	for _, line := range lines[1:] {
+		if strings.Contains(line, "error:") {
+			return strings.TrimSuffix(lines[0], ":") + ": " + strings.TrimSpace(line)
+		}
+	}
+	return lines[0]
+}
+
+func splitOutput(output string) []string {
+	if output == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+}
+
+// diffLines returns the lines only want has, marked -, and the lines only
+// got has, marked +, in order, leaving out the lines they share
+func diffLines(want []string, got []string) []string {
+	// common[i][j] is the longest common subsequence of want[i:] and got[j:]
+	common := make([][]int, len(want)+1)
+	for i := range common {
+		common[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			i++
+			j++
+			continue
+		case j == len(got) || i < len(want) && common[i+1][j] >= common[i][j+1]:
+			diff = append(diff, "- "+want[i])
+			i++
+		default:
+			diff = append(diff, "+ "+got[j])
+			j++
+		}
+		if len(diff) == maxDiffLines {
+			return append(diff, "...")
+		}
+	}
+	return diff
+}
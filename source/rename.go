@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ahoy"
+)
+
+var renameFlags = flag.NewFlagSet("rename", flag.ExitOnError)
+
+var (
+	renameFileFlag   = renameFlags.String("f", "", "Input .ahoy source `file` naming the symbol")
+	renameLineFlag   = renameFlags.Int("line", 0, "`line` of the name to rename")
+	renameColumnFlag = renameFlags.Int("col", 0, "`column` of the name to rename, counted in bytes")
+	renameToFlag     = renameFlags.String("to", "", "The new `name`")
+	renameWriteFlag  = renameFlags.Bool("w", false, "Write the renamed files instead of listing the edits")
+)
+
+// runRename renames a symbol across the files of its package, listing the
+// edits or, with -w, rewriting the files
+func runRename(args []string) int {
+	renameFlags.Parse(args)
+	if *renameFileFlag == "" || *renameLineFlag == 0 || *renameColumnFlag == 0 || *renameToFlag == "" {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy rename -f file -line n -col n -to name [-w]")
+		return 1
+	}
+
+	absPath, err := filepath.Abs(*renameFileFlag)
+	if err != nil {
+		fmt.Printf("Error resolving file path: %v\n", err)
+		return 1
+	}
+	pm := ahoy.NewPackageManager(filepath.Dir(absPath))
+	pm.Log = os.Stderr
+	pkg, err := pm.LoadPackageFromFile(absPath)
+	if err != nil {
+		fmt.Printf("Error loading package: %v\n", err)
+		return 1
+	}
+
+	edits, err := ahoy.Rename(pkg, ahoy.Position{File: absPath, Line: *renameLineFlag, Column: *renameColumnFlag}, *renameToFlag)
+	if err != nil {
+		fmt.Printf("Error %v\n", err)
+		return 1
+	}
+
+	workDir, _ := os.Getwd()
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(workDir, path); err == nil {
+			return rel
+		}
+		return path
+	}
+	if !*renameWriteFlag {
+		for _, edit := range edits {
+			fmt.Printf("%s:%d:%d: %s\n", relative(edit.File), edit.Span.StartLine, edit.Span.StartColumn, edit.NewText)
+		}
+		return 0
+	}
+
+	changed := 0
+	for _, file := range pkg.Files {
+		renamed := ahoy.ApplyRenameEdits(file.Content, file.Path, edits)
+		if renamed == file.Content {
+			continue
+		}
+		if err := os.WriteFile(file.Path, []byte(renamed), 0644); err != nil {
+			fmt.Printf("Error writing %s: %v\n", relative(file.Path), err)
+			return 1
+		}
+		changed++
+	}
+	fmt.Printf("Renamed %d place(s) in %d file(s)\n", len(edits), changed)
+	return 0
+}
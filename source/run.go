@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"ahoy"
+)
+
+var runFlags = flag.NewFlagSet("run", flag.ExitOnError)
+
+var runFileFlag = runFlags.String("f", "", "Input .ahoy source `file`")
+var runLeakCheckFlag = runFlags.Bool("leak-check", false, "Run the program under valgrind and report the memory Ahoy code leaked")
+
+// runRun builds a program and runs it, exiting with its status. With
+// -leak-check the program runs under valgrind, and the status is 1 when
+// Ahoy code leaked memory.
+func runRun(args []string) int {
+	runFlags.Parse(args)
+	if *runFileFlag == "" {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy run -f file [-leak-check]")
+		return 1
+	}
+
+	valgrind := ""
+	if *runLeakCheckFlag {
+		path, err := exec.LookPath("valgrind")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: valgrind isn't installed, so the program runs without a leak check")
+		}
+		valgrind = path
+	}
+
+	artifacts, diagnostics, err := ahoy.Build(ahoy.BuildOptions{
+		Source:  *runFileFlag,
+		Compile: true,
+		// Leaks are traced back through the statement comments of readable C
+		ReadableC: valgrind != "",
+		Debug:     valgrind != "",
+	})
+	if err != nil {
+		if err == ahoy.ErrCodeGeneration {
+			for _, diagnostic := range diagnostics {
+				fmt.Printf("  Line %d: %s\n", diagnostic.Line, diagnostic.Message)
+			}
+			fmt.Println("✗ Code generation failed due to errors")
+		} else {
+			fmt.Printf("Error %v\n", err)
+		}
+		return 1
+	}
+
+	runCmd := exec.Command(artifacts.Executable)
+	logFile := ""
+	if valgrind != "" {
+		logFile = filepath.Join(filepath.Dir(artifacts.Executable), "valgrind.log")
+		defer os.Remove(logFile)
+		runCmd = exec.Command(valgrind, "--leak-check=full", "--show-leak-kinds=definite,indirect,possible",
+			"--log-file="+logFile, artifacts.Executable)
+	}
+	runCmd.Stdin = os.Stdin
+	runCmd.Stdout = os.Stdout
+	runCmd.Stderr = os.Stderr
+	status := 0
+	if err := runCmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+			status = exitErr.ExitCode()
+		} else {
+			fmt.Printf("Program exited with error: %v\n", err)
+			status = 1
+		}
+	}
+
+	if logFile != "" && reportLeaks(logFile, artifacts) && status == 0 {
+		status = 1
+	}
+	return status
+}
+
+// reportLeaks prints the leaks valgrind found in Ahoy code, with the
+// statement and construct that allocated each, and reports whether there
+// were any
+func reportLeaks(logFile string, artifacts ahoy.Artifacts) bool {
+	report, err := os.ReadFile(logFile)
+	if err != nil {
+		fmt.Printf("Error reading the valgrind report: %v\n", err)
+		return true
+	}
+	records := ahoy.ParseLeakReport(string(report))
+	leaks := ahoy.SummarizeLeaks(records, artifacts.CFile, artifacts.CCode)
+
+	fmt.Println()
+	if len(leaks) == 0 {
+		fmt.Println("✓ No leaks from Ahoy code")
+	}
+	total := 0
+	for _, leak := range leaks {
+		where := "unknown line"
+		if leak.Line > 0 {
+			where = fmt.Sprintf("%s:%d", leak.File, leak.Line)
+		}
+		what := leak.Construct
+		if what == "" {
+			what = leak.Helper
+		}
+		fmt.Printf("%-20s %-26s %d bytes in %d blocks %s\n", where, what, leak.Bytes, leak.Blocks, leak.Kind)
+		total += leak.Bytes
+	}
+	if len(leaks) > 0 {
+		fmt.Printf("%d bytes leaked by Ahoy code in %d records\n", total, len(leaks))
+	}
+	if other := len(records) - len(leaks); other > 0 {
+		fmt.Printf("(%d records from C libraries left out)\n", other)
+	}
+	return len(leaks) > 0
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"ahoy"
+)
+
+var testFlags = flag.NewFlagSet("test", flag.ExitOnError)
+
+var testFileFlag = testFlags.String("f", "", "Input .ahoy source `file`")
+var testCoverFlag = testFlags.Bool("cover", false, "Report the share of each file's lines the tests ran")
+var testCoverHTMLFlag = testFlags.String("cover-html", "", "With -cover, also write an HTML `file` marking the lines the tests missed")
+var testProgramsFlag = testFlags.String("programs", "", "Build and run every program in `dir` and compare their output with -expected")
+var testExpectedFlag = testFlags.String("expected", "", "With -programs, the `dir` of expected output files (default: expected beside the programs)")
+var testRecordFlag = testFlags.Bool("record", false, "With -programs, write the expected output files instead of comparing")
+
+// runTest builds the test blocks of a program into a runner and runs it;
+// the exit status is 1 when any test fails. With -programs it runs a
+// directory of programs against their recorded output instead.
+func runTest(args []string) int {
+	testFlags.Parse(args)
+	if *testProgramsFlag != "" {
+		expectedDir := *testExpectedFlag
+		if expectedDir == "" {
+			expectedDir = filepath.Join(filepath.Dir(filepath.Clean(*testProgramsFlag)), "expected")
+		}
+		return runProgramTests(*testProgramsFlag, expectedDir, *testRecordFlag)
+	}
+	if *testFileFlag == "" {
+		fmt.Fprintln(os.Stderr, "Usage: ahoy test -f file [-cover] [-cover-html file]")
+		fmt.Fprintln(os.Stderr, "       ahoy test -programs dir [-expected dir] [-record]")
+		return 1
+	}
+
+	artifacts, diagnostics, err := ahoy.Build(ahoy.BuildOptions{
+		Source:  *testFileFlag,
+		Compile: true,
+		Test:    true,
+		Cover:   *testCoverFlag || *testCoverHTMLFlag != "",
+	})
+	if err != nil {
+		if err == ahoy.ErrCodeGeneration {
+			for _, diagnostic := range diagnostics {
+				fmt.Printf("  Line %d: %s\n", diagnostic.Line, diagnostic.Message)
+			}
+			fmt.Println("✗ Code generation failed due to errors")
+		} else {
+			fmt.Printf("Error %v\n", err)
+		}
+		return 1
+	}
+
+	if artifacts.Coverage != "" {
+		os.Remove(artifacts.Coverage)
+	}
+	status := 0
+	runCmd := exec.Command(artifacts.Executable)
+	runCmd.Stdout = os.Stdout
+	runCmd.Stderr = os.Stderr
+	if err := runCmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+			status = exitErr.ExitCode()
+		} else {
+			fmt.Printf("Tests exited with error: %v\n", err)
+			status = 1
+		}
+	}
+
+	// Failing tests still report the coverage of what ran
+	if artifacts.Coverage != "" && reportCoverage(artifacts.Coverage, *testCoverHTMLFlag) != nil {
+		return 1
+	}
+	return status
+}
+
+// reportCoverage prints each file's coverage from the profile the tests
+// wrote and, when htmlFile is set, writes the HTML report
+func reportCoverage(profile string, htmlFile string) error {
+	coverage, err := ahoy.ReadCoverage(profile)
+	if err != nil {
+		fmt.Printf("Error reading coverage: %v\n", err)
+		return err
+	}
+	fmt.Println()
+	workDir, _ := os.Getwd()
+	for _, file := range coverage.Files() {
+		name := file.File
+		if relative, err := filepath.Rel(workDir, name); err == nil && !strings.HasPrefix(relative, "..") {
+			name = relative
+		}
+		fmt.Printf("%-30s %5.1f%% of %d lines\n", name, file.Percent(), file.Lines)
+	}
+	total := coverage.Total()
+	fmt.Printf("coverage: %.1f%% of %d lines\n", total.Percent(), total.Lines)
+
+	if htmlFile == "" {
+		return nil
+	}
+	file, err := os.Create(htmlFile)
+	if err != nil {
+		fmt.Printf("Error writing coverage report: %v\n", err)
+		return err
+	}
+	defer file.Close()
+	if err := ahoy.WriteCoverageHTML(file, coverage); err != nil {
+		fmt.Printf("Error writing coverage report: %v\n", err)
+		return err
+	}
+	fmt.Printf("Wrote %s\n", htmlFile)
+	return nil
+}
@@ -0,0 +1,153 @@
+package ahoy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// generateSwitchHas generates switch d has:, whose cases list keys. The
+// first case whose keys are all in the dict runs, with the value of each
+// key bound to its name; _ runs when no case's keys are there. The dict is
+// worked out once and each key looked up once.
+func (gen *CodeGenerator) generateSwitchHas(node *ASTNode) {
+	subject := node.Children[0]
+	subjectType := gen.inferType(subject)
+	if !strings.HasPrefix(subjectType, "dict") && subjectType != "HashMap*" && subjectType != "generic" {
+		gen.reportError(node.Line, fmt.Sprintf("switch has matches on the keys of a dict, not a %s", subjectType))
+		return
+	}
+	valueType := dictValueType(gen.declaredType(subject))
+
+	gen.dictMethods["get"] = true
+	dict := fmt.Sprintf("__has_%d", gen.varCounter)
+	gen.varCounter++
+	keyCount := 0
+	for _, caseNode := range node.Children[1:] {
+		keyCount += len(switchHasKeys(caseNode))
+	}
+
+	gen.writeIndent()
+	gen.output.WriteString("{\n")
+	gen.indent++
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("HashMap* %s = (HashMap*)", dict))
+	gen.generateNode(subject)
+	gen.output.WriteString(";\n")
+	if keyCount > 0 {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("AhoyValue %s_values[%d];\n", dict, keyCount))
+	}
+
+	first := true
+	var defaultBody *ASTNode
+	slot := 0
+	for _, caseNode := range node.Children[1:] {
+		if caseNode.Type != NODE_SWITCH_CASE {
+			continue
+		}
+		if isDefaultCase(caseNode.Children[0]) {
+			defaultBody = caseNode.Children[1]
+			continue
+		}
+		keys := switchHasKeys(caseNode)
+		gen.writeIndent()
+		if !first {
+			gen.output.WriteString("} else ")
+		}
+		first = false
+		gen.output.WriteString("if (")
+		for i, key := range keys {
+			if i > 0 {
+				gen.output.WriteString(" && ")
+			}
+			gen.output.WriteString(fmt.Sprintf("ahoy_dict_get(%s, \"%s\", &%s_values[%d])", dict, key.Value, dict, slot+i))
+		}
+		gen.output.WriteString(") {\n")
+		gen.indent++
+
+		// Bind the values the body reads, keeping the types of any
+		// variables they shadow for afterwards
+		body := caseNode.Children[1]
+		restore := map[string]string{}
+		for i, key := range keys {
+			if len(key.Children) == 0 || !mentions(body, key.Children[0].Value) {
+				continue
+			}
+			binding := key.Children[0]
+			bindingType := valueType
+			switch {
+			case bindingType == "":
+				bindingType = binding.DataType
+				if bindingType == "" {
+					bindingType = "float"
+				}
+			case binding.DataType != "" && binding.DataType != bindingType:
+				gen.reportError(binding.Line, fmt.Sprintf("switch has key \"%s\" can't be a %s; the dict holds %s values", key.Value, binding.DataType, bindingType))
+			}
+			if _, saved := restore[binding.Value]; !saved {
+				restore[binding.Value] = gen.variables[binding.Value]
+			}
+			gen.writeIndent()
+			gen.output.WriteString(fmt.Sprintf("%s %s = %s;\n", gen.mapType(bindingType), binding.Value,
+				gen.dictValueFrom(fmt.Sprintf("%s_values[%d]", dict, slot+i), bindingType)))
+			gen.variables[binding.Value] = bindingType
+		}
+		slot += len(keys)
+		gen.generateNodeInternal(body, true)
+		for name, previous := range restore {
+			if previous != "" {
+				gen.variables[name] = previous
+			} else {
+				delete(gen.variables, name)
+			}
+		}
+		gen.indent--
+	}
+	if defaultBody != nil {
+		gen.writeIndent()
+		if first {
+			gen.output.WriteString("{\n")
+		} else {
+			gen.output.WriteString("} else {\n")
+		}
+		gen.indent++
+		gen.generateNodeInternal(defaultBody, true)
+		gen.indent--
+		first = false
+	}
+	if !first {
+		gen.writeIndent()
+		gen.output.WriteString("}\n")
+	}
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
+
+// switchHasKeys returns the keys a switch has case lists
+func switchHasKeys(caseNode *ASTNode) []*ASTNode {
+	if caseNode.Type != NODE_SWITCH_CASE || isDefaultCase(caseNode.Children[0]) {
+		return nil
+	}
+	if caseNode.Children[0].Type == NODE_SWITCH_CASE_LIST {
+		return caseNode.Children[0].Children
+	}
+	return []*ASTNode{caseNode.Children[0]}
+}
+
+// mentions reports whether node names name anywhere, counting the words of
+// its strings as unusedLocals does, since a string can interpolate it
+func mentions(node *ASTNode, name string) bool {
+	found := false
+	walkAST(node, func(child *ASTNode) bool {
+		switch child.Type {
+		case NODE_STRING, NODE_F_STRING:
+			found = found || slices.Contains(identifierPattern.FindAllString(child.Value, -1), name)
+		case NODE_IDENTIFIER:
+			found = found || child.Value == name
+		}
+		return !found
+	})
+	return found
+}
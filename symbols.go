@@ -0,0 +1,414 @@
+package ahoy
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SymbolKind says what a Symbol declares
+type SymbolKind int
+
+const (
+	SymbolFunction SymbolKind = iota
+	SymbolStruct
+	SymbolField
+	SymbolEnum
+	SymbolEnumMember
+	SymbolConstant
+	SymbolVariable
+	SymbolType // alias or union
+	SymbolParameter
+)
+
+var symbolKindNames = [...]string{"function", "struct", "field", "enum", "enum member", "constant", "variable", "type", "parameter"}
+
+func (kind SymbolKind) String() string {
+	return symbolKindNames[kind]
+}
+
+// Symbol is a named declaration. Span covers the whole declaration and
+// NameSpan just the name, which is where go-to-definition lands.
+type Symbol struct {
+	Name     string
+	Kind     SymbolKind
+	Detail   string // a function's |params| and return type, or the type of anything else, if known
+	File     string
+	Span     Span
+	NameSpan Span
+	Children []Symbol // fields of a struct, members of an enum
+}
+
+// symbolFile is a file's AST with the tokens its spans refer to
+type symbolFile struct {
+	path     string
+	ast      *ASTNode
+	tokens   []Token
+	declared []Symbol // top-level symbols, once listed
+}
+
+func newSymbolFile(ast *ASTNode, source string, path string) *symbolFile {
+	return &symbolFile{path: path, ast: ast, tokens: Tokenize(source)}
+}
+
+// FileSymbols returns the top-level declarations of a parsed file in source
+// order: functions, structs, enums, constants, aliases, unions and global
+// variables. source is the text ast was parsed from and path is recorded in
+// each symbol.
+func FileSymbols(ast *ASTNode, source string, path string) []Symbol {
+	return newSymbolFile(ast, source, path).symbols()
+}
+
+// Symbols returns the top-level declarations of every file in the package
+func (pkg *Package) Symbols() []Symbol {
+	symbols := []Symbol{}
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			symbols = append(symbols, FileSymbols(file.AST, file.Content, file.Path)...)
+		}
+	}
+	return symbols
+}
+
+func (file *symbolFile) symbols() []Symbol {
+	if file.declared == nil {
+		file.declared = file.listSymbols()
+	}
+	return file.declared
+}
+
+func (file *symbolFile) listSymbols() []Symbol {
+	symbols := []Symbol{}
+	if file.ast == nil {
+		return symbols
+	}
+	declared := map[string]bool{}
+	for _, node := range file.ast.Children {
+		switch node.Type {
+		case NODE_FUNCTION, NODE_EXTERN:
+			symbols = append(symbols, file.symbol(node, node.Value, SymbolFunction, functionDetail(node)))
+		case NODE_STRUCT_DECLARATION:
+			symbol := file.symbol(node, node.Value, SymbolStruct, "")
+			for _, field := range node.Children {
+				if field.Type == NODE_IDENTIFIER {
+					symbol.Children = append(symbol.Children, file.symbol(field, field.Value, SymbolField, field.DataType))
+				}
+			}
+			symbols = append(symbols, symbol)
+		case NODE_ENUM_DECLARATION:
+			symbol := file.symbol(node, node.Value, SymbolEnum, node.EnumType)
+			for _, member := range node.Children {
+				if member.Type == NODE_IDENTIFIER {
+					symbol.Children = append(symbol.Children, file.symbol(member, member.Value, SymbolEnumMember, ""))
+				}
+			}
+			symbols = append(symbols, symbol)
+		case NODE_CONSTANT_DECLARATION:
+			symbols = append(symbols, file.symbol(node, node.Value, SymbolConstant, valueType(node)))
+		case NODE_ALIAS_DECLARATION, NODE_UNION_DECLARATION:
+			symbols = append(symbols, file.symbol(node, node.Value, SymbolType, node.DataType))
+		case NODE_EMBED_STATEMENT:
+			if len(node.Children) > 0 {
+				symbols = append(symbols, file.symbol(node.Children[0], node.Children[0].Value, SymbolVariable, "bytes"))
+			}
+		case NODE_ASSIGNMENT, NODE_VARIABLE_DECLARATION:
+			// The first assignment to a name declares it
+			if node.Value != "" && !declared[node.Value] {
+				declared[node.Value] = true
+				symbols = append(symbols, file.symbol(node, node.Value, SymbolVariable, valueType(node)))
+			}
+		case NODE_TUPLE_ASSIGNMENT:
+			if len(node.Children) > 0 {
+				for _, target := range node.Children[0].Children {
+					if target.Type == NODE_IDENTIFIER && !declared[target.Value] {
+						declared[target.Value] = true
+						symbols = append(symbols, file.symbol(target, target.Value, SymbolVariable, ""))
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+func (file *symbolFile) symbol(node *ASTNode, name string, kind SymbolKind, detail string) Symbol {
+	return Symbol{
+		Name:     name,
+		Kind:     kind,
+		Detail:   detail,
+		File:     file.path,
+		Span:     node.Span,
+		NameSpan: file.nameSpan(node.Span, name),
+	}
+}
+
+// nameSpan finds name among the tokens of a declaration, falling back to the
+// whole declaration
+func (file *symbolFile) nameSpan(span Span, name string) Span {
+	for _, token := range file.tokens {
+		if token.Span.Start >= span.End {
+			break
+		}
+		if token.Span.Start >= span.Start && token.Type == TOKEN_IDENTIFIER && token.Value == name {
+			return token.Span
+		}
+	}
+	return span
+}
+
+// functionDetail writes a function's parameters and return type as they're
+// declared, like |a:int, b:int| int
+func functionDetail(fn *ASTNode) string {
+	var params []string
+	if len(fn.Children) > 0 {
+		for _, param := range fn.Children[0].Children {
+			if param.DataType != "" {
+				params = append(params, param.Value+":"+param.DataType)
+			} else {
+				params = append(params, param.Value)
+			}
+		}
+	}
+	detail := "|" + strings.Join(params, ", ") + "|"
+	if fn.DataType != "" {
+		detail += " " + fn.DataType
+	}
+	return detail
+}
+
+// valueType returns the declared type of a variable or constant, or the
+// type of its value when that's plain from the literal
+func valueType(node *ASTNode) string {
+	if node.DataType != "" {
+		return node.DataType
+	}
+	if len(node.Children) == 0 {
+		return ""
+	}
+	value := node.Children[0]
+	if value.Type == NODE_OBJECT_LITERAL && value.Value != "" {
+		return value.Value
+	}
+	return value.DataType
+}
+
+// Definition returns the declaration of the identifier at line:column
+// (1-based, columns in bytes) of one of the package's files. Names resolve
+// to the parameters of the enclosing function first, then to the package's
+// top-level symbols, then to the function's locals; enum.member and struct
+// fields accessed through a value of a known struct type resolve to the
+// member or field.
+func (pkg *Package) Definition(path string, line, column int) (Symbol, bool) {
+	index := newSymbolIndex(pkg)
+	file := index.file(path)
+	if file == nil {
+		return Symbol{}, false
+	}
+	at := tokenAt(file.tokens, line, column)
+	if at < 0 {
+		return Symbol{}, false
+	}
+	return index.definition(file, at)
+}
+
+// symbolIndex holds the files of a package for resolving names, so each is
+// tokenized once
+type symbolIndex struct {
+	files []*symbolFile
+}
+
+func newSymbolIndex(pkg *Package) *symbolIndex {
+	index := &symbolIndex{}
+	for _, file := range pkg.Files {
+		if file.AST != nil {
+			index.files = append(index.files, newSymbolFile(file.AST, file.Content, file.Path))
+		}
+	}
+	return index
+}
+
+// file finds a file of the package by path
+func (index *symbolIndex) file(path string) *symbolFile {
+	absPath, _ := filepath.Abs(path)
+	for _, file := range index.files {
+		if filePath, _ := filepath.Abs(file.path); filePath == absPath {
+			return file
+		}
+	}
+	return nil
+}
+
+// tokenAt returns the index of the identifier at line:column, or -1. A
+// column just past the end of a name still counts as on it.
+func tokenAt(tokens []Token, line, column int) int {
+	for i, token := range tokens {
+		span := token.Span
+		if token.Type == TOKEN_IDENTIFIER && span.StartLine == line && span.EndLine == line &&
+			column >= span.StartColumn && column <= span.EndColumn {
+			return i
+		}
+	}
+	return -1
+}
+
+// definition resolves the identifier token at index of file
+func (index *symbolIndex) definition(file *symbolFile, at int) (Symbol, bool) {
+	tokens := file.tokens
+	token := tokens[at]
+
+	// A declaration's own name
+	for _, symbol := range file.symbols() {
+		if symbol.NameSpan == token.Span {
+			return symbol, true
+		}
+		for _, child := range symbol.Children {
+			if child.NameSpan == token.Span {
+				return child, true
+			}
+		}
+	}
+
+	if at >= 2 && tokens[at-1].Type == TOKEN_DOT && tokens[at-2].Type == TOKEN_IDENTIFIER {
+		return index.memberDefinition(file, tokens[at-2], token.Value)
+	}
+	if typeName := objectLiteralType(tokens, at); typeName != "" {
+		if owner, ok := index.topLevel(file, typeName); ok && owner.Kind == SymbolStruct {
+			return member(owner, token.Value)
+		}
+		return Symbol{}, false
+	}
+	return index.resolve(file, token.Value, token.Span.Start)
+}
+
+// objectLiteralType returns the struct named before the braces when the
+// token at index is a property name in an object literal, like width in
+// box{width: 2}, or ""
+func objectLiteralType(tokens []Token, at int) string {
+	if at+1 >= len(tokens) || tokens[at+1].Type != TOKEN_ASSIGN {
+		return ""
+	}
+	depth := 0
+	for i := at - 1; i > 0; i-- {
+		switch tokens[i].Type {
+		case TOKEN_RBRACE, TOKEN_RPAREN, TOKEN_RBRACKET:
+			depth++
+		case TOKEN_LPAREN, TOKEN_LBRACKET:
+			depth--
+		case TOKEN_LBRACE:
+			if depth == 0 {
+				if tokens[i-1].Type == TOKEN_IDENTIFIER {
+					return tokens[i-1].Value
+				}
+				return ""
+			}
+			depth--
+		case TOKEN_NEWLINE, TOKEN_EOF:
+			return ""
+		}
+		if depth < 0 {
+			return ""
+		}
+	}
+	return ""
+}
+
+// resolve finds what name means at offset in file. Assigning to a global
+// inside a function doesn't declare a local, so globals come before locals.
+// A bare name no declaration has can still be a member of one enum.
+func (index *symbolIndex) resolve(file *symbolFile, name string, offset int) (Symbol, bool) {
+	fn := file.enclosingFunction(offset)
+	if fn != nil && len(fn.Children) > 0 {
+		for _, param := range fn.Children[0].Children {
+			if param.Value == name {
+				return file.symbol(param, name, SymbolParameter, param.DataType), true
+			}
+		}
+	}
+	if symbol, ok := index.topLevel(file, name); ok {
+		return symbol, true
+	}
+	if fn != nil {
+		var found *ASTNode
+		walkAST(fn, func(node *ASTNode) bool {
+			if found == nil && (node.Type == NODE_ASSIGNMENT || node.Type == NODE_VARIABLE_DECLARATION) && node.Value == name &&
+				!node.Span.IsZero() && node.Span.Start <= offset {
+				found = node
+			}
+			return found == nil
+		})
+		if found != nil {
+			return file.symbol(found, name, SymbolVariable, valueType(found)), true
+		}
+	}
+
+	var members []Symbol
+	for _, other := range index.files {
+		for _, symbol := range other.symbols() {
+			if symbol.Kind != SymbolEnum {
+				continue
+			}
+			if found, ok := member(symbol, name); ok {
+				members = append(members, found)
+			}
+		}
+	}
+	if len(members) == 1 {
+		return members[0], true
+	}
+	return Symbol{}, false
+}
+
+// enclosingFunction returns the top-level function containing offset
+func (file *symbolFile) enclosingFunction(offset int) *ASTNode {
+	for _, node := range file.ast.Children {
+		if node.Type == NODE_FUNCTION && offset >= node.Span.Start && offset < node.Span.End {
+			return node
+		}
+	}
+	return nil
+}
+
+// topLevel finds a top-level symbol, preferring the given file's
+func (index *symbolIndex) topLevel(file *symbolFile, name string) (Symbol, bool) {
+	for _, symbol := range file.symbols() {
+		if symbol.Name == name {
+			return symbol, true
+		}
+	}
+	for _, other := range index.files {
+		for _, symbol := range other.symbols() {
+			if symbol.Name == name {
+				return symbol, true
+			}
+		}
+	}
+	return Symbol{}, false
+}
+
+// memberDefinition resolves qualifier.name: a member of an enum, or a field
+// of a value whose struct type is known. An import namespace isn't a
+// symbol, so names reached through one resolve to nothing here.
+func (index *symbolIndex) memberDefinition(file *symbolFile, qualifier Token, name string) (Symbol, bool) {
+	owner, ok := index.resolve(file, qualifier.Value, qualifier.Span.Start)
+	if !ok {
+		return Symbol{}, false
+	}
+	if owner.Kind != SymbolEnum && owner.Kind != SymbolStruct {
+		if owner.Detail == "" {
+			return Symbol{}, false
+		}
+		if owner, ok = index.topLevel(file, owner.Detail); !ok || owner.Kind != SymbolStruct {
+			return Symbol{}, false
+		}
+	}
+	return member(owner, name)
+}
+
+// member finds a field or enum member of owner by name
+func member(owner Symbol, name string) (Symbol, bool) {
+	for _, child := range owner.Children {
+		if child.Name == name {
+			return child, true
+		}
+	}
+	return Symbol{}, false
+}
@@ -0,0 +1,87 @@
+package ahoy
+
+import "testing"
+
+// symbolPackage parses main.ahoy and shapes.ahoy from sources into a package
+func symbolPackage(t *testing.T, sources map[string]string) *Package {
+	pkg := &Package{Name: "demo"}
+	for _, path := range []string{"main.ahoy", "shapes.ahoy"} {
+		ast, errors := ParseLint(Tokenize(sources[path]))
+		if len(errors) > 0 {
+			t.Fatalf("parse errors in %s: %v", path, errors)
+		}
+		pkg.Files = append(pkg.Files, PackageFile{Path: path, AST: ast, Content: sources[path]})
+	}
+	return pkg
+}
+
+func TestPackageSymbols(t *testing.T) {
+	pkg := symbolPackage(t, map[string]string{
+		"main.ahoy":   "LIMIT :: 10\nscore: 0\n@ grow |by:int| int:\n    score: score + by\n    return score\n$\nscore: grow|2|\n",
+		"shapes.ahoy": "enum side:\n    left\n    right\n$\nstruct box:\n    width: int\n$\n",
+	})
+
+	symbols := pkg.Symbols()
+	var got []string
+	for _, symbol := range symbols {
+		got = append(got, symbol.Kind.String()+" "+symbol.Name+" "+symbol.Detail)
+	}
+	expected := []string{"constant LIMIT int", "variable score int", "function grow |by:int| int", "enum side ", "struct box "}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("symbol %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+
+	grow := symbols[2]
+	if span := grow.NameSpan; span.StartLine != 3 || span.StartColumn != 3 || span.EndColumn != 7 {
+		t.Errorf("unexpected name span for grow: %+v", span)
+	}
+	if span := grow.Span; span.StartLine != 3 || span.EndLine != 6 {
+		t.Errorf("unexpected span for grow: %+v", span)
+	}
+	if side := symbols[3]; len(side.Children) != 2 || side.Children[1].Name != "right" || side.Children[1].File != "shapes.ahoy" {
+		t.Errorf("expected side's members from shapes.ahoy, got %+v", side.Children)
+	}
+}
+
+func TestPackageDefinition(t *testing.T) {
+	pkg := symbolPackage(t, map[string]string{
+		"main.ahoy":   "crate: box{width: 2}\n@ fit |b:box| int:\n    room: b.width\n    way: side.left\n    print|way.name|\n    return room + crate.width\n$\n",
+		"shapes.ahoy": "enum side:\n    left\n    right\n$\nstruct box:\n    width: int\n$\n",
+	})
+
+	tests := []struct {
+		line, column int
+		name         string
+		kind         SymbolKind
+		file         string
+		declLine     int
+	}{
+		{1, 1, "crate", SymbolVariable, "main.ahoy", 1},
+		{1, 8, "box", SymbolStruct, "shapes.ahoy", 5},
+		{3, 11, "b", SymbolParameter, "main.ahoy", 2},
+		{3, 14, "width", SymbolField, "shapes.ahoy", 6},
+		{4, 15, "left", SymbolEnumMember, "shapes.ahoy", 2},
+		{6, 12, "room", SymbolVariable, "main.ahoy", 3},
+		{6, 25, "width", SymbolField, "shapes.ahoy", 6},
+	}
+	for _, test := range tests {
+		symbol, ok := pkg.Definition("main.ahoy", test.line, test.column)
+		if !ok {
+			t.Errorf("%d:%d: expected %s, found nothing", test.line, test.column, test.name)
+			continue
+		}
+		if symbol.Name != test.name || symbol.Kind != test.kind || symbol.File != test.file || symbol.NameSpan.StartLine != test.declLine {
+			t.Errorf("%d:%d: expected %s %s at %s:%d, got %s %s at %s:%d", test.line, test.column,
+				test.kind, test.name, test.file, test.declLine, symbol.Kind, symbol.Name, symbol.File, symbol.NameSpan.StartLine)
+		}
+	}
+
+	if symbol, ok := pkg.Definition("main.ahoy", 5, 15); ok {
+		t.Errorf("expected no definition for a field of an untyped value, got %+v", symbol)
+	}
+}
@@ -0,0 +1,125 @@
+package ahoy
+
+import (
+	"fmt"
+	"slices"
+)
+
+// tailCallLabel starts the body of a function that tail calls itself
+const tailCallLabel = "__tail_call"
+
+// findTailCalls returns the calls fn makes to itself in tail position, with
+// the argument for each parameter: the
+// value of a return and, when fn returns nothing, the last statement of its
+// body or of the blocks of an if ending it. generateTailCall turns them into
+// a jump back to the start of fn, so deep recursion runs as a loop. Other
+// recursive calls in a return's value, and tail calls in a function that
+// defers statements, are reported as warnings, as they still use the stack.
+func (gen *CodeGenerator) findTailCalls(fn *ASTNode, void bool) map[*ASTNode][]*ASTNode {
+	if len(fn.Children) < 2 {
+		return nil
+	}
+	calls := map[*ASTNode][]*ASTNode{}
+	tail := func(node *ASTNode) {
+		if node != nil && node.Type == NODE_CALL && node.Value == fn.Value {
+			if args := bindArguments(node, fn.Children[0].Children); args != nil {
+				calls[node] = args
+			}
+		}
+	}
+	var lastStatement func(block *ASTNode)
+	lastStatement = func(block *ASTNode) {
+		if block == nil || len(block.Children) == 0 {
+			return
+		}
+		last := block.Children[len(block.Children)-1]
+		switch last.Type {
+		case NODE_CALL:
+			tail(last)
+		case NODE_IF_STATEMENT:
+			for _, child := range last.Children {
+				if child.Type == NODE_BLOCK {
+					lastStatement(child)
+				}
+			}
+		}
+	}
+
+	defers := false
+	var returns []*ASTNode
+	walkAST(fn.Children[1], func(node *ASTNode) bool {
+		switch node.Type {
+		case NODE_DEFER_STATEMENT:
+			defers = true
+		case NODE_RETURN_STATEMENT:
+			returns = append(returns, node)
+			if len(node.Children) == 1 {
+				tail(node.Children[0])
+			}
+		}
+		return true
+	})
+	if void {
+		lastStatement(fn.Children[1])
+	}
+
+	if defers && len(calls) > 0 {
+		lines := []int{}
+		for call := range calls {
+			lines = append(lines, call.Line)
+		}
+		slices.Sort(lines)
+		gen.reportWarning(lines[0], fmt.Sprintf("tail call to %s isn't turned into a loop, as %s defers statements", fn.Value, fn.Value))
+		return nil
+	}
+	for _, ret := range returns {
+		recursive := false
+		for _, child := range ret.Children {
+			walkAST(child, func(node *ASTNode) bool {
+				recursive = recursive || (node.Type == NODE_CALL && node.Value == fn.Value && calls[node] == nil)
+				return !recursive
+			})
+		}
+		if recursive {
+			gen.reportWarning(nodeLine(ret), fmt.Sprintf("recursive call to %s isn't a tail call, so it isn't turned into a loop", fn.Value))
+		}
+	}
+	return calls
+}
+
+// generateTailCall writes a tail call of the current function: its
+// arguments, evaluated before any parameter changes, become the parameters
+// and the body starts over
+func (gen *CodeGenerator) generateTailCall(call *ASTNode) {
+	params := gen.functionParamNames[call.Value]
+	types := gen.functionParamTypes[call.Value]
+	args := gen.tailCalls[call]
+	prefix := fmt.Sprintf("__tail_%d_", gen.varCounter)
+	gen.varCounter++
+
+	gen.writeIndent()
+	gen.output.WriteString("{\n")
+	gen.indent++
+	for i, param := range params {
+		paramType := "intptr_t"
+		if types[i] != "generic" {
+			paramType = gen.mapType(types[i])
+		}
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s %s%s = ", paramType, prefix, param))
+		if paramType == "intptr_t" {
+			gen.output.WriteString("(intptr_t)")
+		}
+		gen.generateNode(args[i])
+		gen.output.WriteString(";\n")
+	}
+	for _, param := range params {
+		gen.writeIndent()
+		gen.output.WriteString(fmt.Sprintf("%s = %s%s;\n", param, prefix, param))
+	}
+	gen.writeIndent()
+	gen.output.WriteString("goto " + tailCallLabel + ";\n")
+	gen.indent--
+	gen.writeIndent()
+	gen.output.WriteString("}\n")
+}
@@ -10,18 +10,32 @@ import (
 	"testing"
 )
 
-// TestLinting runs the compiler's lint flag on all test files to catch linting errors
-func TestLinting(t *testing.T) {
-	// Build the compiler first
-	compilerPath := "../ahoy-bin"
-	if _, err := os.Stat(compilerPath); os.IsNotExist(err) {
-		// Try to build it
-		cmd := exec.Command("go", "build", "-o", compilerPath, "../source")
-		if err := cmd.Run(); err != nil {
-			t.Fatalf("Failed to build compiler: %v", err)
-		}
+// compilerPath is the compiler TestMain builds from ../source, so the tests
+// always run the current code rather than a stale ahoy-bin
+var compilerPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ahoy-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create build directory: %v\n", err)
+		os.Exit(1)
+	}
+	compilerPath = filepath.Join(dir, "ahoy")
+	cmd := exec.Command("go", "build", "-o", compilerPath, ".")
+	cmd.Dir = "../source"
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		fmt.Fprintf(os.Stderr, "Failed to build compiler: %v\n", err)
+		os.Exit(1)
 	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
 
+// TestLinting runs the compiler's lint flag on all test files to catch linting errors
+func TestLinting(t *testing.T) {
 	// Discover all .ahoy files in input directory
 	files, err := filepath.Glob("input/*.ahoy")
 	if err != nil {
@@ -60,6 +74,36 @@ func TestLinting(t *testing.T) {
 	}
 }
 
+// TestLintIgnoreComment runs -lint on a file with a trailing ahoy:ignore
+// comment, which must silence its line as written
+func TestLintIgnoreComment(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".ahoylint"), []byte("magic-number: warning\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := "width: 3\ntotal: width * 12 ? ahoy:ignore magic-number\nother: width * 13\nprint|total + other|\n"
+	file := filepath.Join(dir, "ignore.ahoy")
+	if err := os.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(compilerPath, "-lint", "-f", file)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("lint failed: %v\n%s", err, output.String())
+	}
+
+	got := output.String()
+	if strings.Contains(got, "magic number 12") {
+		t.Errorf("ignored magic number reported:\n%s", got)
+	}
+	if !strings.Contains(got, "Line 3: magic number 13") {
+		t.Errorf("expected magic number 13 on line 3:\n%s", got)
+	}
+}
+
 // TestLSPDiagnostics runs LSP validation on all test files to catch semantic errors
 func TestLSPDiagnostics(t *testing.T) {
 	lspPath := "../../ahoy-lsp/ahoy-lsp"
@@ -127,16 +171,6 @@ func TestLSPDiagnostics(t *testing.T) {
 
 // TestConsolidatedFiles tests all consolidated test files
 func TestConsolidatedFiles(t *testing.T) {
-	// Build the compiler first
-	compilerPath := "../ahoy-bin"
-	if _, err := os.Stat(compilerPath); os.IsNotExist(err) {
-		// Try to build it
-		cmd := exec.Command("go", "build", "-o", compilerPath, "../source")
-		if err := cmd.Run(); err != nil {
-			t.Fatalf("Failed to build compiler: %v", err)
-		}
-	}
-
 	// Discover all .ahoy files in input directory
 	files, err := filepath.Glob("input/*.ahoy")
 	if err != nil {
@@ -2,8 +2,10 @@ package ahoy
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type TokenType int
@@ -106,13 +108,33 @@ const (
 	TOKEN_MODULO_ASSIGN   // %=
 	TOKEN_CARET           // ^ (pointer dereference, Pascal-style)
 	TOKEN_AMPERSAND       // & (address-of, Pascal-style)
+	TOKEN_BYTES_STRING    // b"\x00\xFF" byte buffer literal
 )
 
 type Token struct {
-	Type   TokenType
-	Value  string
-	Line   int
-	Column int
+	Type    TokenType
+	Value   string
+	Line    int
+	Column  int    // 1-based, counted from the first non-blank character of the line
+	Span    Span   // exact source range
+	Comment string // on NEWLINE tokens, the comment ending the line, if any
+}
+
+// Span is a range of source text. Offsets are bytes from the start of the
+// source and End is exclusive; lines and columns are 1-based, with columns
+// counted in bytes from the start of the line.
+type Span struct {
+	Start       int `json:"start"`
+	End         int `json:"end"`
+	StartLine   int `json:"start_line"`
+	StartColumn int `json:"start_column"`
+	EndLine     int `json:"end_line"`
+	EndColumn   int `json:"end_column"`
+}
+
+// IsZero reports whether the span was never set
+func (s Span) IsZero() bool {
+	return s == Span{}
 }
 
 func Tokenize(input string) []Token {
@@ -176,10 +198,29 @@ func Tokenize(input string) []Token {
 		"void":         TOKEN_VOID,
 	}
 
+	offset := 0 // byte offset of the current line
+	skip := 0   // lines already tokenized as part of a raw string
 	for lineNum, line := range lines {
+		lineStart := offset
+		offset += len(line) + 1
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		// A raw string left open takes in the lines up to the one closing
+		// it, which are tokenized with this one
+		open := rawStringOpen(line, false)
+		for next := lineNum + 1; open && next < len(lines); next++ {
+			line += "\n" + lines[next]
+			open = rawStringOpen(lines[next], true)
+			skip++
+		}
+
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
+		lineTokens := len(tokens)
 
 		// Handle indentation
 		indent := 0
@@ -207,10 +248,14 @@ func Tokenize(input string) []Token {
 		content := strings.TrimSpace(line)
 		i := 0
 
+		lead := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+		comment := -1 // index of the comment in content
+
 		// Check if line starts with comment
 		if len(content) > 0 && content[0] == '?' {
 			// Skip this line - it's a comment
-			tokens = append(tokens, Token{Type: TOKEN_NEWLINE, Line: lineNum + 1})
+			tokens = append(tokens, Token{Type: TOKEN_NEWLINE, Line: lineNum + 1, Comment: content})
+			setTokenSpans(tokens[lineTokens:], lineNum+1, lineStart, lead, content, 0)
 			continue
 		}
 
@@ -235,6 +280,7 @@ func Tokenize(input string) []Token {
 
 			// Check for inline comment (?) - skip rest of line
 			if content[i] == '?' {
+				comment = i
 				break // Skip rest of line
 			}
 
@@ -253,6 +299,23 @@ func Tokenize(input string) []Token {
 				continue
 			}
 
+			// Raw strings keep newlines and backslashes as written
+			if strings.HasPrefix(content[i:], `"""`) {
+				end := strings.Index(content[i+3:], `"""`)
+				if end < 0 {
+					i = len(content)
+					continue
+				}
+				tokens = append(tokens, Token{
+					Type:   TOKEN_STRING,
+					Value:  escapeC(content[i+3 : i+3+end]),
+					Line:   lineNum + 1,
+					Column: i + 1,
+				})
+				i += end + 6
+				continue
+			}
+
 			// Strings and chars (including f-strings)
 			if content[i] == '"' || content[i] == '\'' {
 				// Check for f-string prefix
@@ -265,6 +328,16 @@ func Tokenize(input string) []Token {
 					}
 				}
 
+				// Check for bytes literal prefix (b"...")
+				isBytes := false
+				if content[i] == '"' && i > 0 && content[i-1] == 'b' {
+					// Remove the previously added 'b' identifier token
+					if len(tokens) > 0 && tokens[len(tokens)-1].Type == TOKEN_IDENTIFIER && tokens[len(tokens)-1].Value == "b" {
+						tokens = tokens[:len(tokens)-1]
+						isBytes = true
+					}
+				}
+
 				quote := content[i]
 				i++
 				start := i
@@ -280,6 +353,13 @@ func Tokenize(input string) []Token {
 
 					if isFString {
 						tokenType = TOKEN_F_STRING
+					} else if isBytes {
+						tokenType = TOKEN_BYTES_STRING
+					}
+					if isFString {
+						value = escapeFString(value)
+					} else if !isBytes {
+						value = escapeC(decodeEscapes(value))
 					}
 					// Note: Removed automatic CHAR conversion to fix dictionary keys
 					// Single-character strings remain as STRING tokens
@@ -332,6 +412,11 @@ func Tokenize(input string) []Token {
 					tokens = append(tokens, Token{Type: TOKEN_DOUBLE_COLON, Value: "::", Line: lineNum + 1, Column: i + 1})
 					i += 2
 					continue
+				case "==", "!=":
+					// C-style spellings of is / is not; -lint suggests the words
+					tokens = append(tokens, Token{Type: TOKEN_IS, Value: twoChar, Line: lineNum + 1, Column: i + 1})
+					i += 2
+					continue
 				case "<=":
 					tokens = append(tokens, Token{Type: TOKEN_LESS_EQUAL, Value: "<=", Line: lineNum + 1, Column: i + 1})
 					i += 2
@@ -437,15 +522,235 @@ func Tokenize(input string) []Token {
 			i++
 		}
 
-		tokens = append(tokens, Token{Type: TOKEN_NEWLINE, Line: lineNum + 1})
+		newline := Token{Type: TOKEN_NEWLINE, Line: lineNum + 1}
+		if comment >= 0 {
+			newline.Comment = content[comment:]
+		}
+		tokens = append(tokens, newline)
+		setTokenSpans(tokens[lineTokens:], lineNum+1, lineStart, lead, content, comment)
 	}
 
 	// Add final dedents
+	lastLine := lines[len(lines)-1]
+	end := Span{Start: len(input), End: len(input), StartLine: len(lines), StartColumn: len(lastLine) + 1, EndLine: len(lines), EndColumn: len(lastLine) + 1}
 	for len(indentStack) > 1 {
 		indentStack = indentStack[:len(indentStack)-1]
-		tokens = append(tokens, Token{Type: TOKEN_DEDENT})
+		tokens = append(tokens, Token{Type: TOKEN_DEDENT, Span: end})
 	}
 
-	tokens = append(tokens, Token{Type: TOKEN_EOF})
+	tokens = append(tokens, Token{Type: TOKEN_EOF, Span: end})
 	return tokens
 }
+
+// setTokenSpans fills in the spans of one line's tokens from their columns.
+// content is the line without its lead bytes of indentation, and comment is
+// where its comment starts in content, or -1. A raw string can carry content
+// over several lines; positions after a newline count from its line's start.
+func setTokenSpans(tokens []Token, line int, lineStart int, lead int, content string, comment int) {
+	position := func(index int) (int, int) {
+		newline := strings.LastIndexByte(content[:min(index, len(content))], '\n')
+		if newline < 0 {
+			return line, lead + index + 1
+		}
+		return line + strings.Count(content[:index], "\n"), index - newline
+	}
+	for i := range tokens {
+		token := &tokens[i]
+		start, end := token.Column-1, token.Column-1+len(token.Value)
+		switch token.Type {
+		case TOKEN_INDENT, TOKEN_DEDENT:
+			start, end = 0, 0
+		case TOKEN_NEWLINE:
+			start, end = len(content), len(content)
+			if comment >= 0 {
+				start = comment
+			}
+		case TOKEN_STRING, TOKEN_CHAR:
+			end = literalEnd(content, start)
+		case TOKEN_F_STRING, TOKEN_BYTES_STRING:
+			end = literalEnd(content, start)
+			start-- // prefix
+		}
+		startLine, startColumn := position(start)
+		endLine, endColumn := position(end)
+		token.Line = startLine
+		token.Span = Span{
+			Start:       lineStart + lead + start,
+			End:         lineStart + lead + end,
+			StartLine:   startLine,
+			StartColumn: startColumn,
+			EndLine:     endLine,
+			EndColumn:   endColumn,
+		}
+	}
+}
+
+// literalEnd returns where the string literal whose opening quote is at
+// start in content ends, after its closing quote. Escaped literals are held
+// in their C form, so the source rather than the value gives the length.
+func literalEnd(content string, start int) int {
+	if start < 0 || start >= len(content) {
+		return start
+	}
+	if strings.HasPrefix(content[start:], `"""`) {
+		if end := strings.Index(content[start+3:], `"""`); end >= 0 {
+			return start + end + 6
+		}
+		return len(content)
+	}
+	quote := content[start]
+	i := start + 1
+	for i < len(content) && content[i] != quote {
+		if content[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	return min(i+1, len(content))
+}
+
+// rawStringOpen reports whether a raw string is open at the end of line,
+// given whether one was open at its start. Quotes in ordinary strings and
+// comments don't count.
+func rawStringOpen(line string, open bool) bool {
+	for i := 0; i < len(line); i++ {
+		if strings.HasPrefix(line[i:], `"""`) {
+			open = !open
+			i += 2
+			continue
+		}
+		if open {
+			continue
+		}
+		switch line[i] {
+		case '"', '\'':
+			quote := line[i]
+			for i++; i < len(line) && line[i] != quote; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case '?':
+			if i+1 < len(line) && line[i+1] == '?' {
+				i++
+				continue
+			}
+			return false
+		}
+	}
+	return open
+}
+
+// decodeEscapes resolves the escapes of a string literal: \n, \t, \r, \0,
+// \\, \", \', \xNN and \uXXXX or \u{X...}. A backslash before anything else
+// is kept, so "C:\dir" means what it says.
+func decodeEscapes(raw string) string {
+	if !strings.Contains(raw, "\\") {
+		return raw
+	}
+	var text strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 >= len(raw) {
+			text.WriteByte(raw[i])
+			continue
+		}
+		i++
+		switch raw[i] {
+		case 'n':
+			text.WriteByte('\n')
+		case 't':
+			text.WriteByte('\t')
+		case 'r':
+			text.WriteByte('\r')
+		case '0':
+			text.WriteByte(0)
+		case '\\', '"', '\'':
+			text.WriteByte(raw[i])
+		case 'x':
+			// Exactly two hex digits
+			if i+2 < len(raw) {
+				if val, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+					text.WriteByte(byte(val))
+					i += 2
+					continue
+				}
+			}
+			text.WriteString("\\x")
+		case 'u':
+			digits, size := "", 0
+			if i+1 < len(raw) && raw[i+1] == '{' {
+				if end := strings.IndexByte(raw[i:], '}'); end > 0 {
+					digits, size = raw[i+2:i+end], end
+				}
+			} else if i+4 < len(raw) {
+				digits, size = raw[i+1:i+5], 4
+			}
+			if val, err := strconv.ParseUint(digits, 16, 32); err == nil && utf8.ValidRune(rune(val)) {
+				text.WriteRune(rune(val))
+				i += size
+				continue
+			}
+			text.WriteString("\\u")
+		default:
+			text.WriteByte('\\')
+			text.WriteByte(raw[i])
+		}
+	}
+	return text.String()
+}
+
+// escapeC writes text as the inside of a C string literal. Quotes,
+// backslashes and control characters are escaped, the latter in octal so no
+// character after one can extend it; valid UTF-8 is left as it is.
+func escapeC(text string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '"' || r == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteRune(r)
+		case r == '\n':
+			escaped.WriteString("\\n")
+		case r == '\t':
+			escaped.WriteString("\\t")
+		case r == '\r':
+			escaped.WriteString("\\r")
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f:
+			escaped.WriteString(fmt.Sprintf("\\%03o", text[i]))
+		default:
+			escaped.WriteString(text[i : i+size])
+		}
+		i += size
+	}
+	return escaped.String()
+}
+
+// escapeFString puts the text of an f-string in C form, leaving the
+// expressions in braces as written
+func escapeFString(raw string) string {
+	var escaped strings.Builder
+	for {
+		open := strings.IndexByte(raw, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(raw[open:], '}')
+		if end < 0 {
+			break
+		}
+		escaped.WriteString(escapeC(decodeEscapes(raw[:open])))
+		escaped.WriteString(raw[open : open+end+1])
+		raw = raw[open+end+1:]
+	}
+	escaped.WriteString(escapeC(decodeEscapes(raw)))
+	return escaped.String()
+}
+
+// unescapeC returns the text of a literal held in the C form escapeC gives
+func unescapeC(value string) string {
+	if text, err := strconv.Unquote("\"" + value + "\""); err == nil {
+		return text
+	}
+	return value
+}
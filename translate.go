@@ -0,0 +1,198 @@
+package ahoy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// StringsExt is the extension of the string tables tr|key| reads from. The
+// tables beside a program's main file are compiled into it.
+const StringsExt = ".ahoy-strings"
+
+// LanguageEnv is the environment variable a program picks the language of
+// tr|key| from; LANG is read when it isn't set
+const LanguageEnv = "AHOY_LANG"
+
+// stringTable is the text of a program in each language it's translated to.
+// The first language is the default: every key is in it, and the other
+// languages fall back to it for keys they don't have.
+type stringTable struct {
+	languages []string
+	keys      []string                     // in the order the default language has them
+	text      map[string]map[string]string // language, then key
+}
+
+// loadStringTables reads the string tables in dir, in file name order. It
+// returns nil when there are none.
+func loadStringTables(dir string) (*stringTable, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+StringsExt))
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+	slices.Sort(paths)
+	table := &stringTable{text: map[string]map[string]string{}}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := table.parse(filepath.Base(path), string(content)); err != nil {
+			return nil, err
+		}
+	}
+	for _, language := range table.languages[1:] {
+		for key := range table.text[language] {
+			if _, ok := table.text[table.languages[0]][key]; !ok {
+				return nil, fmt.Errorf("'%s' is translated to %s but isn't in %s, the default language", key, language, table.languages[0])
+			}
+		}
+	}
+	return table, nil
+}
+
+// parse adds the text of a string table file. A [language] line starts each
+// language, followed by a key: "text" line for each of its strings; lines
+// starting with ? or # are comments.
+func (table *stringTable) parse(name, content string) error {
+	language := ""
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("%s:%d: %s", name, i+1, fmt.Sprintf(format, args...))
+		}
+		switch {
+		case line == "" || strings.HasPrefix(line, "?") || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			language = strings.TrimSpace(line[1 : len(line)-1])
+			if language == "" {
+				return fail("a language needs a name, like [en]")
+			}
+			if _, ok := table.text[language]; !ok {
+				table.languages = append(table.languages, language)
+				table.text[language] = map[string]string{}
+			}
+		default:
+			key, value, ok := strings.Cut(line, ":")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok || key == "" || len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+				return fail(`expected key: "text", got %s`, line)
+			}
+			if language == "" {
+				return fail("'%s' comes before any [language] line", key)
+			}
+			if _, ok := table.text[language][key]; ok {
+				return fail("'%s' is already in %s", key, language)
+			}
+			table.text[language][key] = decodeEscapes(value[1 : len(value)-1])
+			if language == table.languages[0] {
+				table.keys = append(table.keys, key)
+			}
+		}
+	}
+	return nil
+}
+
+// generateTr generates tr|key|, the text of key in the language the program
+// runs in
+func (gen *CodeGenerator) generateTr(node *ASTNode) {
+	if len(node.Children) != 1 {
+		gen.reportError(node.Line, fmt.Sprintf("tr takes the key of a string, got %d arguments", len(node.Children)))
+		return
+	}
+	table := gen.stringTable
+	if table == nil {
+		gen.reportError(node.Line, "tr needs a string table, and there's no "+StringsExt+" file beside the program",
+			"Write one with a [language] line before each language's key: \"text\" lines")
+		return
+	}
+	if key := node.Children[0]; key.Type == NODE_STRING {
+		if _, ok := table.text[table.languages[0]][unescapeC(key.Value)]; !ok {
+			gen.reportError(node.Line, fmt.Sprintf("there's no string '%s' in %s, the default language", unescapeC(key.Value), table.languages[0]))
+			return
+		}
+	}
+	gen.useTr = true
+
+	gen.output.WriteString("ahoy_tr(")
+	gen.generateNode(node.Children[0])
+	gen.output.WriteString(")")
+}
+
+// writeTrHelperFunctions generates the program's string table and the lookup
+// behind tr. The language is picked on the first lookup: LanguageEnv or
+// LANG naming one exactly, like pt_BR, or by its first part, like fr in
+// fr_FR.UTF-8. Otherwise the default language is used.
+func (gen *CodeGenerator) writeTrHelperFunctions() {
+	if !gen.useTr {
+		return
+	}
+	table := gen.stringTable
+	quote := func(text string) string {
+		return "\"" + escapeC(text) + "\""
+	}
+	gen.funcReturnStructs.WriteString("char* ahoy_tr(const char* key);\n\n")
+
+	gen.funcDecls.WriteString("\n// tr string table, NULL where a language lacks a string\n")
+	gen.funcDecls.WriteString(fmt.Sprintf("#define AHOY_TR_LANGUAGES %d\n", len(table.languages)))
+	gen.funcDecls.WriteString(fmt.Sprintf("#define AHOY_TR_KEYS %d\n", len(table.keys)))
+	var languages, keys []string
+	for _, language := range table.languages {
+		languages = append(languages, quote(language))
+	}
+	for _, key := range table.keys {
+		keys = append(keys, quote(key))
+	}
+	gen.funcDecls.WriteString("static const char* const ahoy_tr_languages[AHOY_TR_LANGUAGES] = {" + strings.Join(languages, ", ") + "};\n")
+	gen.funcDecls.WriteString("static const char* const ahoy_tr_keys[AHOY_TR_KEYS] = {" + strings.Join(keys, ", ") + "};\n")
+	gen.funcDecls.WriteString("static const char* const ahoy_tr_text[AHOY_TR_LANGUAGES][AHOY_TR_KEYS] = {\n")
+	for _, language := range table.languages {
+		var row []string
+		for _, key := range table.keys {
+			if text, ok := table.text[language][key]; ok {
+				row = append(row, quote(text))
+			} else {
+				row = append(row, "NULL")
+			}
+		}
+		gen.funcDecls.WriteString("    {" + strings.Join(row, ", ") + "},\n")
+	}
+	gen.funcDecls.WriteString("};\n\n")
+	gen.funcDecls.WriteString(fmt.Sprintf(`static int ahoy_tr_find_language(const char* name, size_t length) {
+    for (int i = 0; i < AHOY_TR_LANGUAGES; i++) {
+        if (strlen(ahoy_tr_languages[i]) == length && strncmp(name, ahoy_tr_languages[i], length) == 0) return i;
+    }
+    return -1;
+}
+
+static int ahoy_tr_language(void) {
+    static int language = -1;
+    if (language >= 0) return language;
+    language = 0;
+    const char* names[] = {getenv("%s"), getenv("LANG")};
+    for (int n = 0; n < 2; n++) {
+        if (names[n] == NULL || names[n][0] == '\0') continue;
+        int found = ahoy_tr_find_language(names[n], strcspn(names[n], ".@"));
+        if (found < 0) found = ahoy_tr_find_language(names[n], strcspn(names[n], "_.@"));
+        if (found >= 0) {
+            language = found;
+            break;
+        }
+    }
+    return language;
+}
+
+char* ahoy_tr(const char* key) {
+    for (int i = 0; i < AHOY_TR_KEYS; i++) {
+        if (strcmp(key, ahoy_tr_keys[i]) == 0) {
+            const char* text = ahoy_tr_text[ahoy_tr_language()][i];
+            return (char*)(text != NULL ? text : ahoy_tr_text[0][i]);
+        }
+    }
+    // A key made at runtime that isn't in the table shows as itself
+    return (char*)key;
+}
+`, LanguageEnv))
+}
@@ -0,0 +1,68 @@
+package ahoy
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// unusedLocals returns the names a function body mentions only once, where
+// they are declared. Every node's value and every word of its strings
+// counts as a mention, so a variable is only called unused when nothing
+// could be reading it.
+func unusedLocals(body *ASTNode) map[string]bool {
+	mentions := map[string]int{}
+	walkAST(body, func(node *ASTNode) bool {
+		switch node.Type {
+		case NODE_STRING, NODE_F_STRING:
+			for _, word := range identifierPattern.FindAllString(node.Value, -1) {
+				mentions[word]++
+			}
+		default:
+			if node.Value != "" {
+				mentions[node.Value]++
+			}
+		}
+		return true
+	})
+	unused := map[string]bool{}
+	for name, count := range mentions {
+		if count == 1 {
+			unused[name] = true
+		}
+	}
+	return unused
+}
+
+// markUnused writes (void)name after the declaration of a variable the
+// function never uses, which keeps gcc's -Wunused-variable quiet
+func (gen *CodeGenerator) markUnused(name string) {
+	if !gen.unusedVars[name] {
+		return
+	}
+	gen.writeIndent()
+	gen.output.WriteString(fmt.Sprintf("(void)%s;\n", name))
+}
+
+// markUnusedDeclarations marks the variables a statement declared that are
+// never used
+func (gen *CodeGenerator) markUnusedDeclarations(statement *ASTNode) {
+	var names []string
+	switch statement.Type {
+	case NODE_VARIABLE_DECLARATION, NODE_ASSIGNMENT:
+		// A first assignment declares its variable
+		names = append(names, statement.Value)
+	case NODE_TUPLE_ASSIGNMENT:
+		for _, target := range statement.Children[0].Children {
+			names = append(names, target.Value)
+		}
+	}
+	for _, name := range names {
+		_, local := gen.functionVars[name]
+		_, scoped := gen.variables[name]
+		if local || scoped {
+			gen.markUnused(name)
+		}
+	}
+}